@@ -0,0 +1,7 @@
+module Data.User exposing (..)
+
+import Util
+
+
+name =
+    Util.format "Ada"
@@ -0,0 +1,8 @@
+module Main exposing (..)
+
+import Data.User
+import View.Page
+
+
+main =
+    View.Page.page Data.User.name
@@ -0,0 +1,8 @@
+module Util exposing (..)
+
+import View.Html
+
+
+format : a -> a
+format s =
+    View.Html.text s
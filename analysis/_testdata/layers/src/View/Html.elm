@@ -0,0 +1,6 @@
+module View.Html exposing (..)
+
+
+text : a -> a
+text s =
+    s
@@ -0,0 +1,8 @@
+module View.Page exposing (..)
+
+import View.Html
+
+
+page : a -> a
+page s =
+    View.Html.text s
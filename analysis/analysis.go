@@ -0,0 +1,232 @@
+// Package analysis is a driver for the checks that programs write about
+// the Elm code of their organization, such as the rules of its
+// architecture, in the style of golang.org/x/tools/go/analysis. A package
+// is loaded once, parsed, resolved and type checked, and every analyzer
+// runs on every module in the order of their imports, so the facts an
+// analyzer learns about a module, such as the layer it belongs to, are
+// known when it runs on the modules that import it.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Analyzer is a check or an analysis of the modules.
+type Analyzer struct {
+	// Name of the analyzer, which is unique among the analyzers run
+	// together and is shown with the problems it finds.
+	Name string
+	// Doc describes what the analyzer finds.
+	Doc string
+	// Requires are the analyzers whose results the analyzer uses, which run
+	// on every module before it.
+	Requires []*Analyzer
+	// Run analyzes the module of the pass and returns its result for the
+	// analyzers that require it. It only returns an error if it could not
+	// analyze the module.
+	Run func(p *Pass) (interface{}, error)
+}
+
+// Diagnostic is a problem found by an analyzer.
+type Diagnostic struct {
+	// Analyzer is the name of the analyzer that found the problem.
+	Analyzer string
+	// Module is the name of the module with the problem, and Path the path
+	// to its file.
+	Module string
+	Path   string
+	// Pos and End are the positions where the problematic code starts and
+	// ends.
+	Pos token.Pos
+	End token.Pos
+	// Message describes the problem.
+	Message string
+}
+
+// Pass is the run of an analyzer on a module.
+type Pass struct {
+	// Analyzer is the analyzer being run.
+	Analyzer *Analyzer
+	// Module is the module being analyzed, which has been resolved and has
+	// its comments.
+	Module *ast.Module
+	// Package is the loaded package, with all the modules.
+	Package *ast.Package
+	// Info is the type information of the package.
+	Info *types.Info
+	// ResultOf are the results of the analyzers required by the analyzer
+	// on the module.
+	ResultOf map[*Analyzer]interface{}
+
+	facts       map[factKey]interface{}
+	diagnostics []Diagnostic
+}
+
+// Report reports a problem with the given node.
+func (p *Pass) Report(node ast.Node, format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Analyzer: p.Analyzer.Name,
+		Module:   p.Module.Name,
+		Path:     p.Module.Path,
+		Pos:      node.Pos(),
+		End:      node.End(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ExportModuleFact sets the fact of the analyzer about the module being
+// analyzed, which the passes of the analyzer on the modules that import it
+// get with ModuleFact.
+func (p *Pass) ExportModuleFact(fact interface{}) {
+	p.facts[factKey{p.Analyzer, p.Module.Name, ""}] = fact
+}
+
+// ModuleFact returns the fact of the analyzer about the given module, if
+// it exported one. Only the facts of the modules analyzed before are
+// known, which are all the modules the module being analyzed imports,
+// directly or not.
+func (p *Pass) ModuleFact(module string) (interface{}, bool) {
+	fact, ok := p.facts[factKey{p.Analyzer, module, ""}]
+	return fact, ok
+}
+
+// ExportDefFact sets the fact of the analyzer about the top-level
+// definition of the module being analyzed with the given name.
+func (p *Pass) ExportDefFact(name string, fact interface{}) {
+	p.facts[factKey{p.Analyzer, p.Module.Name, name}] = fact
+}
+
+// DefFact returns the fact of the analyzer about the top-level definition
+// of the given module with the given name, if it exported one.
+func (p *Pass) DefFact(module, name string) (interface{}, bool) {
+	fact, ok := p.facts[factKey{p.Analyzer, module, name}]
+	return fact, ok
+}
+
+type factKey struct {
+	analyzer *Analyzer
+	module   string
+	def      string
+}
+
+// Program is a loaded package, which the analyzers run on.
+type Program struct {
+	// Package is the parsed and resolved package.
+	Package *ast.Package
+	// Info is the type information of the package.
+	Info *types.Info
+}
+
+// Load parses, resolves and type checks the module at the given path with
+// all the modules it imports, directly or not, with their comments.
+func Load(path string) (*Program, error) {
+	pkg, err := parser.Parse(path, parser.FullParse|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkg.Order) == 0 {
+		return nil, fmt.Errorf("analysis: there are no modules in %s", path)
+	}
+
+	info := types.NewInfo()
+	var conf types.Config
+	if err := conf.Check(pkg, info); err != nil {
+		return nil, err
+	}
+	return &Program{pkg, info}, nil
+}
+
+// Run runs the given analyzers, and the analyzers they require, on every
+// module of the program, in the order of their imports. It returns the
+// problems they found in the order of the modules and then in the order
+// they appear in the source code. It fails if the requirements of the
+// analyzers have a cycle, if two of them have the same name or if any of
+// them fails.
+func (prog *Program) Run(analyzers ...*Analyzer) ([]Diagnostic, error) {
+	order, err := sortAnalyzers(analyzers)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Diagnostic
+	var facts = make(map[factKey]interface{})
+	for _, name := range prog.Package.Order {
+		mod := prog.Package.Modules[name]
+		var results = make(map[*Analyzer]interface{})
+		var diagnostics []Diagnostic
+		for _, a := range order {
+			p := &Pass{
+				Analyzer: a,
+				Module:   mod,
+				Package:  prog.Package,
+				Info:     prog.Info,
+				ResultOf: make(map[*Analyzer]interface{}),
+				facts:    facts,
+			}
+			for _, r := range a.Requires {
+				p.ResultOf[r] = results[r]
+			}
+
+			result, err := a.Run(p)
+			if err != nil {
+				return nil, fmt.Errorf("analysis: %s failed on module %s: %s", a.Name, name, err)
+			}
+			results[a] = result
+			diagnostics = append(diagnostics, p.diagnostics...)
+		}
+
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Pos < diagnostics[j].Pos
+		})
+		all = append(all, diagnostics...)
+	}
+	return all, nil
+}
+
+// sortAnalyzers returns the analyzers and the ones they require, so that
+// every analyzer is after the analyzers it requires.
+func sortAnalyzers(analyzers []*Analyzer) ([]*Analyzer, error) {
+	var order []*Analyzer
+	var names = make(map[string]*Analyzer)
+	var visiting = make(map[*Analyzer]bool)
+	var done = make(map[*Analyzer]bool)
+	var visit func(a *Analyzer) error
+	visit = func(a *Analyzer) error {
+		if done[a] {
+			return nil
+		}
+
+		if visiting[a] {
+			return fmt.Errorf("analysis: analyzer %s requires itself", a.Name)
+		}
+
+		if other, ok := names[a.Name]; ok && other != a {
+			return fmt.Errorf("analysis: there are two analyzers named %s", a.Name)
+		}
+		names[a.Name] = a
+
+		visiting[a] = true
+		for _, r := range a.Requires {
+			if err := visit(r); err != nil {
+				return err
+			}
+		}
+		done[a] = true
+		order = append(order, a)
+		return nil
+	}
+
+	for _, a := range analyzers {
+		if err := visit(a); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const fixture = "_testdata/layers/src/Main.elm"
+
+// imports returns the names of the modules imported by every module.
+var imports = &Analyzer{
+	Name: "imports",
+	Doc:  "find the imported modules",
+	Run: func(p *Pass) (interface{}, error) {
+		var names []string
+		for _, imp := range p.Module.Imports {
+			names = append(names, imp.ModuleName())
+		}
+		return names, nil
+	},
+}
+
+// layers reports the modules of the data layer that depend on the view
+// layer, directly or not. The fact of every module is the set of layers it
+// depends on.
+var layers = &Analyzer{
+	Name:     "layers",
+	Doc:      "check that data does not depend on views",
+	Requires: []*Analyzer{imports},
+	Run: func(p *Pass) (interface{}, error) {
+		var reached = map[string]bool{layer(p.Module.Name): true}
+		for i, name := range p.ResultOf[imports].([]string) {
+			fact, ok := p.ModuleFact(name)
+			if !ok {
+				return nil, errors.New("no fact of " + name)
+			}
+
+			for l := range fact.(map[string]bool) {
+				reached[l] = true
+				if layer(p.Module.Name) == "Data" && l == "View" {
+					p.Report(p.Module.Imports[i], "module %s depends on the views through %s", p.Module.Name, name)
+				}
+			}
+		}
+		p.ExportModuleFact(reached)
+		return nil, nil
+	},
+}
+
+func layer(module string) string {
+	return strings.Split(module, ".")[0]
+}
+
+func TestRun(t *testing.T) {
+	require := require.New(t)
+
+	prog, err := Load(fixture)
+	require.NoError(err)
+
+	diagnostics, err := prog.Run(layers)
+	require.NoError(err)
+	require.Len(diagnostics, 1)
+	require.Equal("layers", diagnostics[0].Analyzer)
+	require.Equal("Data.User", diagnostics[0].Module)
+	require.Equal("module Data.User depends on the views through Util", diagnostics[0].Message)
+	require.True(diagnostics[0].End > diagnostics[0].Pos)
+
+	var order []string
+	var defs = &Analyzer{
+		Name: "defs",
+		Run: func(p *Pass) (interface{}, error) {
+			order = append(order, p.Module.Name)
+			p.ExportDefFact("size", len(p.Module.Decls))
+			if n, ok := p.DefFact("View.Html", "size"); ok && p.Module.Name == "Main" {
+				p.Report(p.Module.Module, "View.Html has %d declarations", n)
+			}
+			return nil, nil
+		},
+	}
+
+	diagnostics, err = prog.Run(defs)
+	require.NoError(err)
+	require.Len(diagnostics, 1)
+	require.Equal("View.Html has 1 declarations", diagnostics[0].Message)
+	require.Equal("Main", order[len(order)-1])
+
+	sorted := append([]string(nil), order...)
+	sort.Strings(sorted)
+	require.Equal([]string{"Data.User", "Main", "Util", "View.Html", "View.Page"}, sorted)
+}
+
+func TestRunErrors(t *testing.T) {
+	require := require.New(t)
+
+	prog, err := Load(fixture)
+	require.NoError(err)
+
+	var a, b Analyzer
+	a = Analyzer{Name: "a", Requires: []*Analyzer{&b}}
+	b = Analyzer{Name: "b", Requires: []*Analyzer{&a}}
+	_, err = prog.Run(&a)
+	require.EqualError(err, "analysis: analyzer a requires itself")
+
+	_, err = prog.Run(imports, &Analyzer{Name: "imports"})
+	require.EqualError(err, "analysis: there are two analyzers named imports")
+
+	broken := &Analyzer{Name: "broken", Run: func(*Pass) (interface{}, error) {
+		return nil, errors.New("oops")
+	}}
+	_, err = prog.Run(broken)
+	require.Error(err)
+	require.Contains(err.Error(), "analysis: broken failed on module ")
+}
+
+func TestRunMain(t *testing.T) {
+	require := require.New(t)
+
+	var out bytes.Buffer
+	require.Equal(1, run("layers", []string{fixture}, &out, []*Analyzer{layers}))
+	require.Contains(out.String(), "module Data.User depends on the views through Util (layers)")
+
+	out.Reset()
+	require.Equal(0, run("layers", []string{fixture}, &out, []*Analyzer{imports}))
+	require.Empty(out.String())
+
+	out.Reset()
+	require.Equal(2, run("layers", nil, &out, []*Analyzer{layers}))
+	require.Contains(out.String(), "Usage: layers <path to module>")
+	require.Contains(out.String(), "check that data does not depend on views")
+
+	out.Reset()
+	require.Equal(3, run("layers", []string{"_testdata/missing/Main.elm"}, &out, []*Analyzer{layers}))
+}
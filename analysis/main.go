@@ -0,0 +1,119 @@
+package analysis
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Main is the main function of a program that runs the given analyzers on
+// the module at the path in its arguments, such as:
+//
+//	func main() {
+//		analysis.Main(layers.Analyzer, naming.Analyzer)
+//	}
+//
+// The problems found in the modules of the package are written as
+// warnings to the standard error, but not the ones in its dependencies.
+// The program exits with the same codes as elmo: 1 if there are any
+// problems, 2 if the arguments are not valid and 3 if the modules could
+// not be analyzed.
+func Main(analyzers ...*Analyzer) {
+	os.Exit(run(os.Args[0], os.Args[1:], os.Stderr, analyzers))
+}
+
+func run(name string, args []string, stderr io.Writer, analyzers []*Analyzer) int {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s <path to module>\n\nThe analyzers are:\n\n", filepath.Base(name))
+		for _, a := range analyzers {
+			fmt.Fprintf(stderr, "\t%s\n\t\t%s\n", a.Name, a.Doc)
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	p, err := pkg.Load(filepath.Dir(path))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", filepath.Base(name), err)
+		return 3
+	}
+
+	prog, err := Load(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 3
+	}
+
+	diagnostics, err := prog.Run(analyzers...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 3
+	}
+
+	cm := source.NewCodeMap(source.NewFsLoader(p))
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, report.Writer(stderr, true, stderr == os.Stderr))
+	var problems int
+	for _, d := range diagnostics {
+		if isDependency(p, d.Path) {
+			continue
+		}
+
+		if err := cm.Add(d.Path); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", filepath.Base(name), err)
+			return 3
+		}
+
+		msg := fmt.Sprintf("%s (%s)", d.Message, d.Analyzer)
+		loc := token.Location{File: d.Path, Span: token.Span{Start: d.Pos, End: d.End}}
+		region := &report.Region{Start: d.Pos, End: d.End}
+		reporter.Report(report.NewBaseReport(report.Warning, loc, msg, region))
+		problems++
+	}
+
+	if err := reporter.Emit(); err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", filepath.Base(name), err)
+		return 3
+	}
+
+	if problems > 0 {
+		return 1
+	}
+	return 0
+}
+
+// isDependency reports whether the file at the given path belongs to a
+// dependency of the package.
+func isDependency(p *pkg.Package, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	root, err := filepath.Abs(p.Root())
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && strings.HasPrefix(filepath.ToSlash(rel), "elm-stuff/")
+}
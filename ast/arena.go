@@ -0,0 +1,90 @@
+package ast
+
+import "github.com/elm-tangram/tangram/token"
+
+// arenaChunk is the number of nodes of each type allocated at once.
+const arenaChunk = 256
+
+// Arena allocates the most common nodes of the syntax tree of a module,
+// such as identifiers, literals and applications, in chunks of many nodes
+// of the same type. A module with thousands of nodes is then only a few
+// dozen objects for the garbage collector, which are freed all together
+// once the module is no longer used.
+//
+// The methods of a nil arena allocate every node on its own, so the
+// parser can use the same code with or without an arena.
+type Arena struct {
+	idents    []Ident
+	selectors []SelectorExpr
+	lits      []BasicLit
+	apps      []FuncApp
+}
+
+// NewArena creates a new empty arena.
+func NewArena() *Arena {
+	return new(Arena)
+}
+
+// NewIdent creates a new identifier with the given name and position.
+func (a *Arena) NewIdent(name string, pos token.Pos) *Ident {
+	if a == nil {
+		return NewIdent(name, pos)
+	}
+
+	if len(a.idents) == cap(a.idents) {
+		a.idents = make([]Ident, 0, arenaChunk)
+	}
+	a.idents = append(a.idents, Ident{pos, name, nil})
+	return &a.idents[len(a.idents)-1]
+}
+
+// NewSelectorExpr creates a new selector expression from a list of
+// identifiers, like the NewSelectorExpr function.
+func (a *Arena) NewSelectorExpr(idents ...*Ident) *SelectorExpr {
+	if a == nil {
+		return NewSelectorExpr(idents...)
+	}
+
+	if len(idents) < 2 {
+		return nil
+	}
+
+	var expr Expr = idents[1]
+	if len(idents) > 2 {
+		expr = a.NewSelectorExpr(idents[1:]...)
+	}
+
+	if len(a.selectors) == cap(a.selectors) {
+		a.selectors = make([]SelectorExpr, 0, arenaChunk)
+	}
+	a.selectors = append(a.selectors, SelectorExpr{expr, idents[0]})
+	return &a.selectors[len(a.selectors)-1]
+}
+
+// NewBasicLit creates a new literal of the given type, with the given
+// position and value.
+func (a *Arena) NewBasicLit(typ BasicLitType, pos token.Pos, value string) *BasicLit {
+	if a == nil {
+		return &BasicLit{pos, typ, value}
+	}
+
+	if len(a.lits) == cap(a.lits) {
+		a.lits = make([]BasicLit, 0, arenaChunk)
+	}
+	a.lits = append(a.lits, BasicLit{pos, typ, value})
+	return &a.lits[len(a.lits)-1]
+}
+
+// NewFuncApp creates a new application of the given function to the given
+// arguments.
+func (a *Arena) NewFuncApp(fn Expr, args ...Expr) *FuncApp {
+	if a == nil {
+		return &FuncApp{fn, args}
+	}
+
+	if len(a.apps) == cap(a.apps) {
+		a.apps = make([]FuncApp, 0, arenaChunk)
+	}
+	a.apps = append(a.apps, FuncApp{fn, args})
+	return &a.apps[len(a.apps)-1]
+}
@@ -0,0 +1,31 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArena(t *testing.T) {
+	require := require.New(t)
+
+	for _, a := range []*Arena{nil, NewArena()} {
+		var idents []*Ident
+		for i := 0; i < 2*arenaChunk; i++ {
+			idents = append(idents, a.NewIdent("x", 0))
+		}
+		idents[0].Name = "foo"
+		require.Equal("x", idents[1].Name)
+		require.Equal("x", idents[arenaChunk].Name)
+
+		sel := a.NewSelectorExpr(idents[0], a.NewIdent("bar", 0), a.NewIdent("baz", 0))
+		require.Equal("foo.bar.baz", sel.String())
+		require.Nil(a.NewSelectorExpr(idents[0]))
+
+		lit := a.NewBasicLit(Int, 3, "42")
+		require.Equal(&BasicLit{3, Int, "42"}, lit)
+
+		app := a.NewFuncApp(idents[0], lit)
+		require.Equal(&FuncApp{idents[0], []Expr{lit}}, app)
+	}
+}
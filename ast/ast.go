@@ -1,6 +1,11 @@
 package ast
 
-import "github.com/elm-tangram/tangram/token"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/token"
+)
 
 // Node is a node in the AST.
 type Node interface {
@@ -21,6 +26,10 @@ type Module struct {
 	NativeImports []string
 	Decls         []Decl
 	Scope         *ModuleScope
+	// Comments contains all the comments of the module in the order they
+	// appear in the source code. It is only set if the module was parsed
+	// with comments.
+	Comments []*Comment
 }
 
 func (f *Module) Pos() token.Pos { return f.Module.Pos() }
@@ -36,6 +45,24 @@ func (f *Module) End() token.Pos {
 	return f.Module.End()
 }
 
+// Comment is a comment in the source code, either a line comment or a
+// block comment.
+type Comment struct {
+	// Position of the comment.
+	Position token.Pos
+	// Text of the comment, including its delimiters.
+	Text string
+}
+
+func (c *Comment) Pos() token.Pos { return c.Position }
+func (c *Comment) End() token.Pos { return c.Position + token.Pos(len(c.Text)) }
+
+// IsLine reports whether the comment is a line comment, which starts with
+// "--" and ends at the end of the line.
+func (c *Comment) IsLine() bool {
+	return strings.HasPrefix(c.Text, "--")
+}
+
 // Package is the set of modules with a certain order of resolution that
 // conform a package.
 type Package struct {
@@ -44,4 +71,80 @@ type Package struct {
 	// Modules is a mapping between a module name and its module AST
 	// representation.
 	Modules map[string]*Module
+	// Natives are the native modules imported by the modules, by their
+	// name.
+	Natives map[string]*NativeModule
+	// Operators are the operators with a fixity available in every module,
+	// defined or imported, by the name of the module and their name.
+	Operators map[string]map[string]*Operator
+	// Partial reports whether the parse was interrupted before all the
+	// modules were parsed, by a cancellation or the cap of errors. Only
+	// the modules that were parsed without errors, and whose imports were
+	// too, are in a partial package.
+	Partial bool
+}
+
+// Operator returns the operator with the given name available in the
+// module, or nil if it is not available or it has no fixity.
+func (p *Package) Operator(module, name string) *Operator {
+	return p.Operators[module][name]
+}
+
+// Operator is an operator with a fixity, which is the same in every module
+// it is available in.
+type Operator struct {
+	// Name is the name of the operator, such as "+".
+	Name string
+	// Module is the name of the module that declares its fixity.
+	Module string
+	// Assoc is the associativity of the operator.
+	Assoc Associativity
+	// Precedence is the precedence of the operator, from 0 to 9.
+	Precedence uint
+	// Decl is the declaration of the fixity.
+	Decl *InfixDecl
+	// Doc is the text of the doc comment of the definition of the operator,
+	// without its delimiters, if the module was parsed with comments.
+	Doc string
+}
+
+// Fixity returns the fixity of the operator as it is declared, but without
+// its name, such as "infixl 6".
+func (o *Operator) Fixity() string {
+	return fmt.Sprintf("%s %d", o.Assoc.Keyword(), o.Precedence)
+}
+
+// NativeModule is a native module, whose values are implemented in Go, or in
+// JavaScript for a kernel module, with the declarations of their types,
+// which are written in the Elm syntax of type annotations in a file next to
+// the Go file:
+//
+//	cons : a -> List a -> List a
+//
+//	empty : List a
+//
+// The types are resolved in the scope of the first module that imports the
+// native module.
+type NativeModule struct {
+	// Name of the module, such as `Native.List`.
+	Name string
+	// Path is the path to the Go file of the module, or to its JavaScript
+	// file if it is a kernel module without a Go file.
+	Path string
+	// DeclPath is the path to the file of the declarations, which is empty
+	// if the module has no such file.
+	DeclPath string
+	// Decls are the declarations of the values of the module.
+	Decls []*TypeAnnotation
+}
+
+// Lookup returns the declaration of the value with the given name, or nil if
+// it is not declared.
+func (m *NativeModule) Lookup(name string) *TypeAnnotation {
+	for _, d := range m.Decls {
+		if d.Name.Name == name {
+			return d
+		}
+	}
+	return nil
 }
@@ -70,16 +70,55 @@ func (e *ExposedUnion) End() token.Pos { return e.Ctors.End() }
 type ModuleDecl struct {
 	// Name of the module.
 	Name Expr
+	// Effect is the position of the "effect" keyword of effect modules.
+	Effect token.Pos
+	// Port is the position of the "port" keyword of port modules.
+	Port token.Pos
+	// PortModule reports whether the module is a port module, which are
+	// the only modules that can declare ports.
+	PortModule bool
 	// Module is the position of the "module" keyword.
 	Module token.Pos
+	// Where is the position of the "where" keyword of effect modules. It
+	// is NoPos if the module is not an effect module.
+	Where token.Pos
+	// Manager contains the types of the commands and subscriptions managed
+	// by an effect module, assigned to the "command" and "subscription"
+	// fields, respectively.
+	Manager []*FieldAssign
 	// Exposing is the list of exposed identifiers, if any.
 	Exposing ExposedList
 }
 
-func (d *ModuleDecl) Pos() token.Pos { return d.Module }
+func (d *ModuleDecl) Pos() token.Pos {
+	switch {
+	case d.IsEffect():
+		return d.Effect
+	case d.PortModule:
+		return d.Port
+	}
+	return d.Module
+}
+
 func (d *ModuleDecl) End() token.Pos { return d.Exposing.End() }
 func (d *ModuleDecl) isDecl()        {}
 
+// IsEffect reports whether the module is an effect module.
+func (d *ModuleDecl) IsEffect() bool {
+	return d.Where != token.NoPos
+}
+
+// ManagedType returns the name of the type assigned to the given field of
+// the effect manager, or nil if there is none.
+func (d *ModuleDecl) ManagedType(field string) Expr {
+	for _, f := range d.Manager {
+		if f.Field.Name == field {
+			return f.Expr
+		}
+	}
+	return nil
+}
+
 // ModuleName returns the name of the module.
 func (d *ModuleDecl) ModuleName() string {
 	if stringer, ok := d.Name.(fmt.Stringer); ok {
@@ -148,6 +187,18 @@ const (
 	Right
 )
 
+// Keyword returns the keyword of the fixity declarations of operators with
+// the associativity: infix, infixl or infixr.
+func (a Associativity) Keyword() string {
+	switch a {
+	case Left:
+		return "infixl"
+	case Right:
+		return "infixr"
+	}
+	return "infix"
+}
+
 func (InfixDecl) isDecl()          {}
 func (d InfixDecl) Pos() token.Pos { return d.InfixPos }
 func (d InfixDecl) End() token.Pos { return d.Op.End() }
@@ -223,6 +274,23 @@ func (c Constructor) End() token.Pos {
 	return c.Name.End()
 }
 
+// PortDecl is a node representing the declaration of a port, which is
+// used to send values to JavaScript or receive values from it.
+type PortDecl struct {
+	// Port is the position of the "port" keyword.
+	Port token.Pos
+	// Name of the port.
+	Name *Ident
+	// Colon is the position of the ":" token.
+	Colon token.Pos
+	// Type of the port.
+	Type Type
+}
+
+func (*PortDecl) isDecl()          {}
+func (d *PortDecl) Pos() token.Pos { return d.Port }
+func (d *PortDecl) End() token.Pos { return d.Type.End() }
+
 // DestructuringAssignment represents a declaration using pattern matching on
 // the expression.
 type DestructuringAssignment struct {
@@ -0,0 +1,80 @@
+package ast
+
+import "github.com/elm-tangram/tangram/token"
+
+// LazyExpr is the body of a top-level definition that is not parsed until it
+// is needed, in the modules parsed with the LazyBodies mode of the parser.
+// The tools that only need the signatures of the definitions never parse
+// the bodies, while walking the node or calling ParseLazy parses them.
+// It is not safe to parse the same body from several goroutines.
+type LazyExpr struct {
+	// Start is the position of the first token of the body, and Stop the
+	// end of its last token.
+	Start token.Pos
+	Stop  token.Pos
+	// Scope is the scope of the definition the body belongs to, which is
+	// set when the module is resolved so the body is resolved with it once
+	// it is parsed.
+	Scope *NodeScope
+
+	parse  func(*LazyExpr) (Expr, error)
+	expr   Expr
+	err    error
+	parsed bool
+}
+
+// NewLazyExpr creates a new lazy expression between the given positions,
+// which is parsed with the given function.
+func NewLazyExpr(start, stop token.Pos, parse func(*LazyExpr) (Expr, error)) *LazyExpr {
+	return &LazyExpr{Start: start, Stop: stop, parse: parse}
+}
+
+func (e *LazyExpr) Pos() token.Pos { return e.Start }
+func (e *LazyExpr) End() token.Pos { return e.Stop }
+func (*LazyExpr) isExpr()          {}
+
+// Expr returns the body, which is parsed the first time. A body that could
+// not be parsed is a BadExpr, and Err returns why.
+func (e *LazyExpr) Expr() Expr {
+	if !e.parsed {
+		e.parsed = true
+		e.expr, e.err = e.parse(e)
+		if e.expr == nil {
+			e.expr = &BadExpr{StartPos: e.Start, EndPos: e.Stop}
+		}
+	}
+	return e.expr
+}
+
+// Err returns the problems found parsing and resolving the body, if it was
+// parsed.
+func (e *LazyExpr) Err() error {
+	return e.err
+}
+
+// Parsed reports whether the body has been parsed.
+func (e *LazyExpr) Parsed() bool {
+	return e.parsed
+}
+
+// ParseLazy parses the lazy bodies of the top-level definitions of the
+// module and replaces them with the parsed expressions, so the module is
+// the same as if it had been fully parsed. It returns the first problem
+// found in the bodies, if any.
+func ParseLazy(mod *Module) error {
+	var err error
+	for _, d := range mod.Decls {
+		def, ok := d.(*Definition)
+		if !ok {
+			continue
+		}
+
+		if lazy, ok := def.Body.(*LazyExpr); ok {
+			def.Body = lazy.Expr()
+			if err == nil {
+				err = lazy.Err()
+			}
+		}
+	}
+	return err
+}
@@ -56,6 +56,10 @@ func Walk(v Visitor, node Node) {
 	// Decls
 	case *ModuleDecl:
 		Walk(v, node.Name)
+		for _, f := range node.Manager {
+			Walk(v, f)
+		}
+
 		if node.Exposing != nil {
 			Walk(v, node.Exposing)
 		}
@@ -113,6 +117,10 @@ func Walk(v Visitor, node Node) {
 			Walk(v, a)
 		}
 
+	case *PortDecl:
+		Walk(v, node.Name)
+		Walk(v, node.Type)
+
 	case *DestructuringAssignment:
 		Walk(v, node.Pattern)
 		Walk(v, node.Expr)
@@ -267,6 +275,12 @@ func Walk(v Visitor, node Node) {
 	case *ParensExpr:
 		Walk(v, node.Expr)
 
+	case *LazyExpr:
+		Walk(v, node.Expr())
+
+	case *BadExpr:
+		// nothing to do
+
 	default:
 		panic(fmt.Errorf("walk: unable to walk node of type %T", node))
 	}
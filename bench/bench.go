@@ -0,0 +1,209 @@
+// Package bench generates the synthetic packages of the benchmarks of the
+// compiler, which stress the phases of the compiler in different ways: a
+// main module that imports many modules, expressions nested very deeply and
+// case expressions with many branches. The benchmarks measure scanning,
+// parsing, resolving and type checking them, and are run with:
+//
+//	go test -run NONE -bench . -benchmem ./bench
+//
+// The results of two commits are compared with benchstat to find out
+// whether a change makes the compiler slower.
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Package is a synthetic package.
+type Package struct {
+	// Name of the package, which names its benchmarks.
+	Name string
+	// Modules is the source code of every module by its name. The main
+	// module is Main.
+	Modules map[string]string
+}
+
+// Packages returns the packages of the benchmarks.
+func Packages() []*Package {
+	return []*Package{
+		Wide(100, 20),
+		Deep(200),
+		Cases(2000),
+	}
+}
+
+// Names returns the names of the modules of the package, sorted.
+func (p *Package) Names() []string {
+	var names = make([]string, 0, len(p.Modules))
+	for name := range p.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// manifest is the elm-package.json of the packages, which are core so their
+// modules have no default imports.
+const manifest = `{
+    "version": "1.0.0",
+    "summary": "synthetic package of the benchmarks",
+    "repository": "https://github.com/elm-lang/core.git",
+    "license": "BSD3",
+    "source-directories": [
+        "src"
+    ],
+    "exposed-modules": [],
+    "dependencies": {},
+    "elm-version": "0.18.0 <= v < 0.19.0"
+}
+`
+
+// Write writes the package to the given directory and returns the path to
+// the file of its main module.
+func (p *Package) Write(dir string) (string, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "elm-package.json"), []byte(manifest), 0644); err != nil {
+		return "", err
+	}
+
+	for name, src := range p.Modules {
+		if err := ioutil.WriteFile(filepath.Join(dir, "src", name+".elm"), []byte(src), 0644); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "src", "Main.elm"), nil
+}
+
+// basics is the module with the operators of the packages.
+const basics = `module Basics exposing ((+), (*), identity)
+
+infixl 6 +
+infixl 7 *
+
+
+(+) : number -> number -> number
+(+) a b =
+    a
+
+
+(*) : number -> number -> number
+(*) a b =
+    b
+
+
+identity : a -> a
+identity a =
+    a
+`
+
+// newPackage creates a package with the Basics module.
+func newPackage(name string) *Package {
+	return &Package{name, map[string]string{"Basics": basics}}
+}
+
+// Wide returns a package whose main module imports the given number of
+// modules, with the given number of definitions each.
+func Wide(modules, defs int) *Package {
+	p := newPackage(fmt.Sprintf("wide-%d", modules))
+	var main bytes.Buffer
+	main.WriteString("module Main exposing (..)\n\nimport Basics exposing (..)\n")
+	for i := 0; i < modules; i++ {
+		name := fmt.Sprintf("Module%03d", i)
+		fmt.Fprintf(&main, "import %s\n", name)
+
+		var mod bytes.Buffer
+		fmt.Fprintf(&mod, "module %s exposing (..)\n\nimport Basics exposing (..)\n", name)
+		for j := 0; j < defs; j++ {
+			fmt.Fprintf(&mod, "\n\ndef%d : Int -> Int\ndef%d x =\n", j, j)
+			if j == 0 {
+				mod.WriteString("    identity x\n")
+			} else {
+				fmt.Fprintf(&mod, "    def%d x + x * %d\n", j-1, j)
+			}
+		}
+		p.Modules[name] = mod.String()
+	}
+
+	main.WriteString("\n\nmain : Int\nmain =\n    0")
+	for i := 0; i < modules; i++ {
+		fmt.Fprintf(&main, "\n        + Module%03d.def%d %d", i, defs-1, i)
+	}
+	main.WriteString("\n")
+	p.Modules["Main"] = main.String()
+	return p
+}
+
+// Deep returns a package whose main module has expressions nested up to
+// the given depth: parenthesized operations, applications and lets.
+func Deep(depth int) *Package {
+	p := newPackage(fmt.Sprintf("deep-%d", depth))
+	var main bytes.Buffer
+	main.WriteString("module Main exposing (..)\n\nimport Basics exposing (..)\n")
+
+	main.WriteString("\n\nparens : Int -> Int\nparens x =\n    ")
+	for i := 0; i < depth; i++ {
+		if i%2 == 0 {
+			main.WriteString("x + (")
+		} else {
+			main.WriteString("x * (")
+		}
+	}
+	main.WriteString("x")
+	for i := 0; i < depth; i++ {
+		main.WriteString(")")
+	}
+
+	main.WriteString("\n\n\napps : Int -> Int\napps x =\n    ")
+	for i := 0; i < depth; i++ {
+		main.WriteString("identity (")
+	}
+	main.WriteString("x")
+	for i := 0; i < depth; i++ {
+		main.WriteString(")")
+	}
+
+	main.WriteString("\n\n\nlets : Int -> Int\nlets x0 =\n")
+	for i := 0; i < depth; i++ {
+		indent := bytes.Repeat([]byte("    "), i+1)
+		fmt.Fprintf(&main, "%slet\n%s    x%d =\n%s        x%d + %d\n%sin\n", indent, indent, i+1, indent, i, i, indent)
+	}
+	fmt.Fprintf(&main, "%sx%d\n", bytes.Repeat([]byte("    "), depth+1), depth)
+	p.Modules["Main"] = main.String()
+	return p
+}
+
+// Cases returns a package whose main module has a union type with the
+// given number of constructors and case expressions on it.
+func Cases(branches int) *Package {
+	p := newPackage(fmt.Sprintf("cases-%d", branches))
+	var main bytes.Buffer
+	main.WriteString("module Main exposing (..)\n\nimport Basics exposing (..)\n\n\ntype Value\n")
+	for i := 0; i < branches; i++ {
+		var sep = "|"
+		if i == 0 {
+			sep = "="
+		}
+		fmt.Fprintf(&main, "    %s Value%d Int\n", sep, i)
+	}
+
+	main.WriteString("\n\nvalue : Value -> Int\nvalue v =\n    case v of\n")
+	for i := 0; i < branches; i++ {
+		fmt.Fprintf(&main, "        Value%d n ->\n            n + %d\n\n", i, i)
+	}
+
+	main.WriteString("\nnumber : Int -> Int\nnumber n =\n    case n of\n")
+	for i := 0; i < branches; i++ {
+		fmt.Fprintf(&main, "        %d ->\n            value (Value%d n)\n\n", i, i)
+	}
+	main.WriteString("        _ ->\n            n\n")
+	p.Modules["Main"] = main.String()
+	return p
+}
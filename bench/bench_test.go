@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/profile"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/types"
+	"github.com/stretchr/testify/require"
+)
+
+// write writes the package to a temporary directory and returns the path to
+// its main module and a function to remove it.
+func write(t testing.TB, p *Package) (string, func()) {
+	dir, err := ioutil.TempDir("", "bench")
+	require.NoError(t, err)
+
+	path, err := p.Write(dir)
+	require.NoError(t, err)
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestPackages(t *testing.T) {
+	for _, p := range Packages() {
+		t.Run(p.Name, func(t *testing.T) {
+			require := require.New(t)
+			path, remove := write(t, p)
+			defer remove()
+
+			pkg, err := parser.Parse(path, parser.FullParse)
+			require.NoError(err)
+			require.Len(pkg.Order, len(p.Modules))
+
+			var conf types.Config
+			require.NoError(conf.Check(pkg, types.NewInfo()))
+		})
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	for _, p := range Packages() {
+		b.Run(p.Name, func(b *testing.B) {
+			var size int64
+			for _, src := range p.Modules {
+				size += int64(len(src))
+			}
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for name, src := range p.Modules {
+					scanner.New(name, strings.NewReader(src)).Run()
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, p := range Packages() {
+		b.Run(p.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for name, src := range p.Modules {
+					if _, err := parser.ParseFrom(name+".elm", strings.NewReader(src), parser.FullParse); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkResolve parses the whole packages, and reports the time spent
+// resolving them apart from the rest.
+func BenchmarkResolve(b *testing.B) {
+	for _, p := range Packages() {
+		b.Run(p.Name, func(b *testing.B) {
+			path, remove := write(b, p)
+			defer remove()
+
+			var resolve time.Duration
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				prof := profile.New()
+				if _, err := parser.ParseWith(path, parser.FullParse, report.Errors(false), prof); err != nil {
+					b.Fatal(err)
+				}
+
+				for _, e := range prof.Entries() {
+					if e.Phase == profile.Resolve {
+						resolve += e.Duration
+					}
+				}
+			}
+			b.ReportMetric(float64(resolve.Nanoseconds())/float64(b.N), "resolve-ns/op")
+		})
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	for _, p := range Packages() {
+		b.Run(p.Name, func(b *testing.B) {
+			path, remove := write(b, p)
+			defer remove()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				pkg, err := parser.Parse(path, parser.FullParse)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				var conf types.Config
+				if err := conf.Check(pkg, types.NewInfo()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package host is the API a Go host exposes to its Elm programs.
+package host
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ID identifies a user.
+type ID int
+
+// User is a user of the host.
+type User struct {
+	ID     ID       `json:"id"`
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags,omitempty"`
+	Team   *Team    `json:"team"`
+	Score  float32
+	secret string
+	Hidden bool `json:"-"`
+}
+
+// Team is a team of users.
+type Team struct {
+	Name string `json:"name"`
+}
+
+// Node is recursive, so it has no type alias.
+type Node struct {
+	Children []Node
+}
+
+// Event has a time, which has no Elm type.
+type Event struct {
+	At time.Time
+}
+
+// Greet greets the user.
+func Greet(u User) string {
+	return "Hello, " + u.Name
+}
+
+// Find finds the user with the given id.
+func Find(id ID) (*User, error) {
+	if id == 0 {
+		return nil, errors.New("no user")
+	}
+	return &User{ID: id}, nil
+}
+
+// Names returns the names of the users.
+func Names(users []User) []string {
+	var names []string
+	for _, u := range users {
+		names = append(names, strings.ToUpper(u.Name))
+	}
+	return names
+}
+
+// Count counts until n.
+func Count(n int8) int64 {
+	return int64(n)
+}
+
+// Reset resets the host.
+func Reset() {}
+
+// Flush writes everything.
+func Flush() error {
+	return nil
+}
+
+// Now returns the current time.
+func Now() time.Time {
+	return time.Now()
+}
+
+// Join joins the words.
+func Join(words ...string) string {
+	return strings.Join(words, " ")
+}
+
+func private() {}
@@ -0,0 +1,444 @@
+// Package bindgen generates the Elm bindings of a Go package, so a Go host
+// can expose its API to the programs built for the go target without
+// writing both sides by hand. The bindings of the package are an Elm module,
+// such as `Host`, with:
+//
+//   - a type alias for every exported struct, with a JSON decoder and
+//     encoder that follow the json tags of its fields;
+//   - a function for every exported function, which calls the native
+//     module `Native.Host`.
+//
+// The native module is generated too, its declarations and the Go file
+// that converts the Elm values to Go values, calls the functions of the
+// package and converts their results back. The Go types that have an Elm
+// type are the booleans, the strings, the numbers, the slices, which are
+// lists, the pointers, which are Maybe values, and the exported structs of
+// the package. A function that returns an error as its last result returns
+// a Result in Elm, and a function without parameters takes `()`. The
+// structs and functions with other types are skipped.
+package bindgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Bindings are the generated files of the bindings of a Go package.
+type Bindings struct {
+	// Module is the name of the Elm module, such as `Host`.
+	Module string
+	// Elm is the source of the Elm module.
+	Elm []byte
+	// Decls are the declarations of the values of the native module.
+	Decls []byte
+	// Go is the source of the Go file of the native module.
+	Go []byte
+	// Skipped are the reasons why the structs and the functions that have
+	// no bindings were skipped, in the order of their names.
+	Skipped []string
+}
+
+// Files returns the paths of the files of the bindings, relative to the
+// source directory they are written to, and their contents.
+func (b *Bindings) Files() map[string][]byte {
+	path := filepath.Join(strings.Split(b.Module, ".")...)
+	native := filepath.Join("Native", strings.Replace(b.Module, ".", "", -1))
+	return map[string][]byte{
+		path + ".elm":    b.Elm,
+		native + ".decl": b.Decls,
+		native + ".go":   b.Go,
+	}
+}
+
+// Write writes the files of the bindings to the given source directory.
+func (b *Bindings) Write(dir string) error {
+	for path, content := range b.Files() {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load type checks the Go package in the given directory, with the packages
+// it imports read from their source. The test files are left out.
+func Load(dir string) (*types.Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("bindgen: there must be a single Go package in %s, but there are %d", dir, len(pkgs))
+	}
+
+	var files []*ast.File
+	var path string
+	for name, pkg := range pkgs {
+		path = name
+		var names []string
+		for name := range pkg.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, pkg.Files[name])
+		}
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		path = importPath(abs, path)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(path, fset, files, nil)
+}
+
+// importPath returns the import path of the package in the given directory,
+// which is relative to the src directory of the GOPATH it is in, or the name
+// of the package if it is not in any.
+func importPath(dir, name string) string {
+	for _, gopath := range filepath.SplitList(os.Getenv("GOPATH")) {
+		src := filepath.Join(gopath, "src") + string(filepath.Separator)
+		if strings.HasPrefix(dir, src) {
+			return filepath.ToSlash(strings.TrimPrefix(dir, src))
+		}
+	}
+	return name
+}
+
+// Generate returns the bindings of the given Go package as the Elm module
+// with the given name.
+func Generate(pkg *types.Package, module string) (*Bindings, error) {
+	if module == "" || !unicode.IsUpper([]rune(module)[0]) {
+		return nil, fmt.Errorf("bindgen: %q is not a valid module name", module)
+	}
+
+	g := newGenerator(pkg, module)
+	g.bind()
+	if len(g.aliases) == 0 && len(g.funcs) == 0 {
+		return nil, fmt.Errorf("bindgen: package %s has no structs or functions with bindings", pkg.Path())
+	}
+
+	elm, err := g.elm()
+	if err != nil {
+		return nil, err
+	}
+
+	goSrc, err := g.golang()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bindings{
+		Module:  module,
+		Elm:     elm,
+		Decls:   g.decls(),
+		Go:      goSrc,
+		Skipped: g.skipped,
+	}, nil
+}
+
+// structState is the state of the binding of a struct.
+type structState byte
+
+const (
+	// binding means that the fields of the struct are being bound.
+	binding structState = iota + 1
+	// bound means that the struct has a type alias.
+	bound
+	// unbound means that the struct cannot have a type alias.
+	unbound
+)
+
+// generator generates the bindings of a package.
+type generator struct {
+	pkg    *types.Package
+	module string
+	// native is the name of the native module, such as `Native.Host`.
+	native string
+
+	structs map[*types.Named]structState
+	// aliases are the structs with a type alias, and funcs the functions
+	// with bindings, in the order of their names.
+	aliases []*alias
+	funcs   []*function
+	skipped []string
+}
+
+// alias is the type alias of a struct.
+type alias struct {
+	named  *types.Named
+	name   string
+	fields []*field
+}
+
+// field is a field of a struct with a type alias.
+type field struct {
+	// goName is the name of the field in Go, name the name in Elm, and
+	// json the name in JSON.
+	goName, name, json string
+	typ                types.Type
+}
+
+// function is an exported function with bindings.
+type function struct {
+	fn *types.Func
+	// name is the name of the function in Elm.
+	name   string
+	params []types.Type
+	// result is the type of the result, which is nil if it has none, and
+	// fallible reports whether it returns an error too.
+	result   types.Type
+	fallible bool
+}
+
+func newGenerator(pkg *types.Package, module string) *generator {
+	return &generator{
+		pkg:     pkg,
+		module:  module,
+		native:  "Native." + strings.Replace(module, ".", "", -1),
+		structs: make(map[*types.Named]structState),
+	}
+}
+
+// bind finds the structs and the functions of the package that have
+// bindings.
+func (g *generator) bind() {
+	scope := g.pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() {
+			continue
+		}
+
+		if named, ok := obj.Type().(*types.Named); ok {
+			if _, ok := named.Underlying().(*types.Struct); ok {
+				if err := g.bindStruct(named); err != nil {
+					g.skip(err)
+				}
+			}
+		}
+	}
+
+	for _, name := range scope.Names() {
+		fn, ok := scope.Lookup(name).(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+
+		if err := g.bindFunc(fn); err != nil {
+			g.skip(err)
+		}
+	}
+	sort.Slice(g.aliases, func(i, j int) bool { return g.aliases[i].name < g.aliases[j].name })
+}
+
+func (g *generator) skip(err error) {
+	for _, s := range g.skipped {
+		if s == err.Error() {
+			return
+		}
+	}
+	g.skipped = append(g.skipped, err.Error())
+}
+
+// bindStruct gives the struct a type alias, if all of its fields have Elm
+// types.
+func (g *generator) bindStruct(named *types.Named) error {
+	switch g.structs[named] {
+	case bound:
+		return nil
+	case binding:
+		return fmt.Errorf("struct %s is recursive, and type aliases cannot be", named.Obj().Name())
+	case unbound:
+		return fmt.Errorf("struct %s has no type alias", named.Obj().Name())
+	}
+
+	g.structs[named] = binding
+	a := &alias{named: named, name: named.Obj().Name()}
+	st := named.Underlying().(*types.Struct)
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() {
+			continue
+		}
+
+		json := strings.Split(reflect.StructTag(st.Tag(i)).Get("json"), ",")[0]
+		if json == "-" {
+			continue
+		}
+
+		if v.Anonymous() {
+			g.structs[named] = unbound
+			return fmt.Errorf("struct %s embeds %s, which is not supported", a.name, v.Name())
+		}
+
+		if json == "" {
+			json = v.Name()
+		}
+
+		if _, err := g.elmType(v.Type()); err != nil {
+			g.structs[named] = unbound
+			return fmt.Errorf("struct %s: field %s %s", a.name, v.Name(), err)
+		}
+		a.fields = append(a.fields, &field{v.Name(), elmName(v.Name()), json, v.Type()})
+	}
+
+	g.structs[named] = bound
+	g.aliases = append(g.aliases, a)
+	return nil
+}
+
+// bindFunc gives the function bindings, if all of its parameters and
+// results have Elm types.
+func (g *generator) bindFunc(fn *types.Func) error {
+	sig := fn.Type().(*types.Signature)
+	if sig.Variadic() {
+		return fmt.Errorf("func %s is variadic", fn.Name())
+	}
+
+	f := &function{fn: fn, name: elmName(fn.Name())}
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		if _, err := g.elmType(param.Type()); err != nil {
+			return fmt.Errorf("func %s: parameter %s %s", fn.Name(), param.Name(), err)
+		}
+		f.params = append(f.params, param.Type())
+	}
+
+	results := sig.Results()
+	if n := results.Len(); n > 0 && isError(results.At(n-1).Type()) {
+		f.fallible = true
+		results = types.NewTuple(tupleVars(results)[:n-1]...)
+	}
+
+	switch results.Len() {
+	case 0:
+	case 1:
+		if _, err := g.elmType(results.At(0).Type()); err != nil {
+			return fmt.Errorf("func %s: result %s", fn.Name(), err)
+		}
+		f.result = results.At(0).Type()
+	default:
+		return fmt.Errorf("func %s returns more than one value", fn.Name())
+	}
+
+	g.funcs = append(g.funcs, f)
+	return nil
+}
+
+// elmType returns the Elm type of the Go type, or an error if it has none.
+func (g *generator) elmType(t types.Type) (string, error) {
+	switch t := t.(type) {
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			return "Bool", nil
+		case t.Info()&types.IsString != 0:
+			return "String", nil
+		case t.Info()&types.IsInteger != 0:
+			return "Int", nil
+		case t.Info()&types.IsFloat != 0:
+			return "Float", nil
+		}
+	case *types.Slice:
+		elem, err := g.elmType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "List " + parens(elem), nil
+	case *types.Pointer:
+		elem, err := g.elmType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "Maybe " + parens(elem), nil
+	case *types.Named:
+		switch t.Underlying().(type) {
+		case *types.Basic:
+			return g.elmType(t.Underlying())
+		case *types.Struct:
+			if t.Obj().Pkg() != g.pkg || !t.Obj().Exported() {
+				break
+			}
+
+			if err := g.bindStruct(t); err != nil {
+				return "", fmt.Errorf("has type %s: %s", t.Obj().Name(), err)
+			}
+			return t.Obj().Name(), nil
+		}
+	}
+	return "", fmt.Errorf("has type %s, which has no Elm type", types.TypeString(t, types.RelativeTo(g.pkg)))
+}
+
+// isError reports whether the type is the error interface.
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func tupleVars(t *types.Tuple) []*types.Var {
+	var vars = make([]*types.Var, t.Len())
+	for i := range vars {
+		vars[i] = t.At(i)
+	}
+	return vars
+}
+
+// parens returns the type in parentheses if it has arguments.
+func parens(typ string) string {
+	if strings.Contains(typ, " ") {
+		return "(" + typ + ")"
+	}
+	return typ
+}
+
+// keywords are the reserved words of Elm, which cannot be names.
+var keywords = map[string]bool{
+	"if": true, "then": true, "else": true, "case": true, "of": true,
+	"let": true, "in": true, "type": true, "alias": true, "module": true,
+	"where": true, "import": true, "exposing": true, "as": true,
+	"port": true, "infix": true, "infixl": true, "infixr": true,
+}
+
+// elmName returns the name in Elm of a Go name, whose leading upper case
+// letters are lower case, such as `urlPath` for `URLPath`. A keyword is
+// followed by an underscore.
+func elmName(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+
+		next := i + 1
+		if i > 0 && next < len(runes) && unicode.IsLower(runes[next]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	if name = string(runes); keywords[name] {
+		return name + "_"
+	}
+	return name
+}
@@ -0,0 +1,116 @@
+package bindgen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := Load("_testdata/host")
+	require.NoError(err)
+
+	b, err := Generate(pkg, "Host")
+	require.NoError(err)
+
+	elm := string(b.Elm)
+	require.Contains(elm, "module Host exposing (Team, decodeTeam, encodeTeam, User, decodeUser, encodeUser, count, find, flush, greet, names, reset)\n")
+	require.Contains(elm, `type alias User =
+    { id : Int
+    , name : String
+    , tags : List String
+    , team : Maybe Team
+    , score : Float
+    }
+`)
+	require.Contains(elm, `        |> andMap (Decode.field "team" (Decode.nullable decodeTeam))
+        |> andMap (Decode.field "Score" Decode.float)
+`)
+	require.Contains(elm, `        , ( "tags", (Encode.list << List.map Encode.string) value.tags )
+`)
+	require.Contains(elm, "find : Int -> Result String (Maybe User)\nfind =\n    Native.Host.find\n")
+	require.Contains(elm, "reset : () -> ()\n")
+
+	require.Equal(`count : Int -> Int
+
+find : Int -> Result String (Maybe User)
+
+flush : () -> Result String ()
+
+greet : User -> String
+
+names : List User -> List String
+
+reset : () -> ()
+`, string(b.Decls))
+
+	goSrc := string(b.Go)
+	require.Contains(goSrc, "\t\"host\"\n")
+	require.Contains(goSrc, `func HostFind(a0 rt.Value) rt.Value {
+	result, err := host.Find(host.ID(a0.(int)))
+	if err != nil {
+		return rt.C(1, err.Error())
+	}
+	return rt.C(0, fromMaybeUser(result))
+}`)
+	require.Contains(goSrc, "func HostCount(a0 rt.Value) rt.Value {\n\treturn int(host.Count(int8(a0.(int))))\n}")
+	require.Contains(goSrc, "func HostReset(_ rt.Value) rt.Value {\n\thost.Reset()\n\treturn rt.Tuple{}\n}")
+	require.Contains(goSrc, "\t\tScore: float32(r[\"score\"].(float64)),\n")
+	require.Contains(goSrc, "\t\t\"id\":    int(x.ID),\n")
+
+	require.Equal([]string{
+		"struct Event: field At has type time.Time, which has no Elm type",
+		"struct Node: field Children has type Node: struct Node is recursive, and type aliases cannot be",
+		"func Join is variadic",
+		"func Now: result has type time.Time, which has no Elm type",
+	}, b.Skipped)
+
+	require.Equal([]string{"Host.elm", "Native/Host.decl", "Native/Host.go"}, sortedKeys(b.Files()))
+
+	_, err = Generate(pkg, "host")
+	require.EqualError(err, `bindgen: "host" is not a valid module name`)
+}
+
+func TestGenerateNested(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "api.go", "package api\n\nfunc Ping() {}\n", 0)
+	require.NoError(err)
+	pkg, err := new(types.Config).Check("example.com/go-api", fset, []*ast.File{file}, nil)
+	require.NoError(err)
+
+	b, err := Generate(pkg, "Api.Client")
+	require.NoError(err)
+	require.Contains(string(b.Elm), "import Native.ApiClient\n")
+	require.Contains(string(b.Go), "\tapi \"example.com/go-api\"\n")
+	require.Equal([]string{"Api/Client.elm", "Native/ApiClient.decl", "Native/ApiClient.go"}, sortedKeys(b.Files()))
+
+	_, err = Generate(types.NewPackage("example.com/empty", "empty"), "Empty")
+	require.EqualError(err, "bindgen: package example.com/empty has no structs or functions with bindings")
+}
+
+func TestElmName(t *testing.T) {
+	require := require.New(t)
+	require.Equal("name", elmName("Name"))
+	require.Equal("id", elmName("ID"))
+	require.Equal("urlPath", elmName("URLPath"))
+	require.Equal("type_", elmName("Type"))
+	require.Equal("x", elmName("x"))
+}
+
+func sortedKeys(files map[string][]byte) []string {
+	var keys []string
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
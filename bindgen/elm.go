@@ -0,0 +1,152 @@
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+
+	"github.com/elm-tangram/tangram/format"
+)
+
+// elm returns the source of the Elm module, formatted.
+func (g *generator) elm() ([]byte, error) {
+	var exposed []string
+	for _, a := range g.aliases {
+		exposed = append(exposed, a.name, "decode"+a.name, "encode"+a.name)
+	}
+	for _, f := range g.funcs {
+		exposed = append(exposed, f.name)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s exposing (%s)\n\n", g.module, strings.Join(exposed, ", "))
+	fmt.Fprintf(&buf, "{-| The bindings of the Go package `%s`, generated by `elmo bind`.\n-}\n\n", g.pkg.Path())
+	if len(g.aliases) > 0 {
+		buf.WriteString("import Json.Decode as Decode\nimport Json.Encode as Encode\n")
+	}
+	fmt.Fprintf(&buf, "import %s\n", g.native)
+
+	for _, a := range g.aliases {
+		g.elmAlias(&buf, a)
+	}
+
+	for _, f := range g.funcs {
+		annotation := g.annotation(f)
+		fmt.Fprintf(&buf, "\n\n%s : %s\n%s =\n    %s.%s\n", f.name, annotation, f.name, g.native, f.name)
+	}
+
+	if len(g.aliases) > 0 {
+		buf.WriteString("\n\nandMap : Decode.Decoder a -> Decode.Decoder (a -> b) -> Decode.Decoder b\nandMap =\n    Decode.map2 (|>)\n")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("bindgen: the Elm module is not valid: %s", err)
+	}
+	return src, nil
+}
+
+// elmAlias writes the type alias of the struct, with its decoder and its
+// encoder.
+func (g *generator) elmAlias(buf *bytes.Buffer, a *alias) {
+	fmt.Fprintf(buf, "\n\ntype alias %s =\n    {", a.name)
+	for i, f := range a.fields {
+		if i > 0 {
+			buf.WriteString("\n    ,")
+		}
+		fmt.Fprintf(buf, " %s : %s", f.name, g.mustElmType(f.typ))
+	}
+	buf.WriteString("\n    }\n")
+
+	fmt.Fprintf(buf, "\n\ndecode%s : Decode.Decoder %s\ndecode%s =\n    Decode.succeed %s", a.name, a.name, a.name, a.name)
+	for _, f := range a.fields {
+		fmt.Fprintf(buf, "\n        |> andMap (Decode.field %q %s)", f.json, g.decoder(f.typ))
+	}
+	buf.WriteString("\n")
+
+	fmt.Fprintf(buf, "\n\nencode%s : %s -> Encode.Value\nencode%s value =\n    Encode.object\n        [", a.name, a.name, a.name)
+	for i, f := range a.fields {
+		if i > 0 {
+			buf.WriteString("\n        ,")
+		}
+		fmt.Fprintf(buf, " ( %q, %s value.%s )", f.json, g.encoder(f.typ), f.name)
+	}
+	if len(a.fields) == 0 {
+		buf.WriteString("\n       ")
+	}
+	buf.WriteString(" ]\n")
+}
+
+// annotation returns the type of the Elm function of the Go function.
+func (g *generator) annotation(f *function) string {
+	var args []string
+	for _, p := range f.params {
+		args = append(args, g.mustElmType(p))
+	}
+	if len(args) == 0 {
+		args = []string{"()"}
+	}
+
+	result := "()"
+	if f.result != nil {
+		result = g.mustElmType(f.result)
+	}
+	if f.fallible {
+		result = "Result String " + parens(result)
+	}
+	return strings.Join(append(args, result), " -> ")
+}
+
+// mustElmType returns the Elm type of a Go type that is known to have one.
+func (g *generator) mustElmType(t types.Type) string {
+	typ, err := g.elmType(t)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// decoder returns the JSON decoder of the Go type.
+func (g *generator) decoder(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Slice:
+		return "(Decode.list " + g.decoder(t.Elem()) + ")"
+	case *types.Pointer:
+		return "(Decode.nullable " + g.decoder(t.Elem()) + ")"
+	case *types.Named:
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return "decode" + t.Obj().Name()
+		}
+		return g.decoder(t.Underlying())
+	}
+	return "Decode." + strings.ToLower(g.mustElmType(t))
+}
+
+// encoder returns the JSON encoder of the Go type.
+func (g *generator) encoder(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Slice:
+		return "(Encode.list << List.map " + g.encoder(t.Elem()) + ")"
+	case *types.Pointer:
+		return "(Maybe.withDefault Encode.null << Maybe.map " + g.encoder(t.Elem()) + ")"
+	case *types.Named:
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return "encode" + t.Obj().Name()
+		}
+		return g.encoder(t.Underlying())
+	}
+	return "Encode." + strings.ToLower(g.mustElmType(t))
+}
+
+// decls returns the declarations of the values of the native module.
+func (g *generator) decls() []byte {
+	var buf bytes.Buffer
+	for i, f := range g.funcs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "%s : %s\n", f.name, g.annotation(f))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,241 @@
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ffi"
+)
+
+// The tags of the constructors of Maybe and Result.
+const (
+	justTag    = 0
+	nothingTag = 1
+	okTag      = 0
+	errTag     = 1
+)
+
+// golang returns the source of the Go file of the native module, formatted.
+func (g *generator) golang() ([]byte, error) {
+	c := &converters{g: g, funcs: make(map[string]string)}
+
+	var funcs bytes.Buffer
+	for _, f := range g.funcs {
+		c.native(&funcs, f)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by elmo bind. DO NOT EDIT.\n\n")
+	buf.WriteString("package native\n\n")
+	imp := fmt.Sprintf("%q", g.pkg.Path())
+	if path.Base(g.pkg.Path()) != g.pkg.Name() {
+		imp = g.pkg.Name() + " " + imp
+	}
+	fmt.Fprintf(&buf, "import (\n\t\"github.com/elm-tangram/tangram/codegen/golang/rt\"\n\n\t%s\n)\n", imp)
+	buf.Write(funcs.Bytes())
+
+	var names []string
+	for name := range c.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		buf.WriteString(c.funcs[name])
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("bindgen: the Go file is not valid: %s", err)
+	}
+	return src, nil
+}
+
+// converters are the functions of the Go file that convert values between
+// Elm and Go, by their name.
+type converters struct {
+	g     *generator
+	funcs map[string]string
+}
+
+// native writes the function of the native module of the Go function.
+func (c *converters) native(buf *bytes.Buffer, f *function) {
+	var params, args []string
+	for i, p := range f.params {
+		params = append(params, fmt.Sprintf("a%d", i))
+		args = append(args, c.toGo(p, fmt.Sprintf("a%d", i)))
+	}
+	if len(params) == 0 {
+		params = []string{"_"}
+	}
+
+	name := ffi.GoName(c.g.native, f.name)
+	call := fmt.Sprintf("%s.%s(%s)", c.g.pkg.Name(), f.fn.Name(), strings.Join(args, ", "))
+	fmt.Fprintf(buf, "\nfunc %s(%s rt.Value) rt.Value {\n", name, strings.Join(params, ", "))
+
+	result := "rt.Tuple{}"
+	switch {
+	case f.result != nil && f.fallible:
+		fmt.Fprintf(buf, "\tresult, err := %s\n", call)
+		result = c.fromGo(f.result, "result")
+	case f.result != nil:
+		fmt.Fprintf(buf, "\treturn %s\n}\n", c.fromGo(f.result, call))
+		return
+	case f.fallible:
+		fmt.Fprintf(buf, "\terr := %s\n", call)
+	default:
+		fmt.Fprintf(buf, "\t%s\n\treturn rt.Tuple{}\n}\n", call)
+		return
+	}
+
+	fmt.Fprintf(buf, "\tif err != nil {\n\t\treturn rt.C(%d, err.Error())\n\t}\n", errTag)
+	fmt.Fprintf(buf, "\treturn rt.C(%d, %s)\n}\n", okTag, result)
+}
+
+// goType returns the Go type as it is written in the Go file.
+func (c *converters) goType(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string { return pkg.Name() })
+}
+
+// toGo returns the expression that converts the Elm value of x to the Go
+// type.
+func (c *converters) toGo(t types.Type, x string) string {
+	switch t := t.(type) {
+	case *types.Basic:
+		elm := elmBasic(t)
+		if t.Name() == elm {
+			return x + ".(" + elm + ")"
+		}
+		return t.Name() + "(" + x + ".(" + elm + "))"
+	case *types.Named:
+		if basic, ok := t.Underlying().(*types.Basic); ok {
+			return c.goType(t) + "(" + x + ".(" + elmBasic(basic) + "))"
+		}
+	}
+	return c.converter("to", t, x)
+}
+
+// fromGo returns the expression that converts the Go value of x to an Elm
+// value.
+func (c *converters) fromGo(t types.Type, x string) string {
+	switch t := t.(type) {
+	case *types.Basic:
+		if elm := elmBasic(t); t.Name() != elm {
+			return elm + "(" + x + ")"
+		}
+		return x
+	case *types.Named:
+		if basic, ok := t.Underlying().(*types.Basic); ok {
+			return elmBasic(basic) + "(" + x + ")"
+		}
+	}
+	return c.converter("from", t, x)
+}
+
+// elmBasic returns the Go type of the Elm values of the basic type, which
+// is bool, string, int or float64.
+func elmBasic(t *types.Basic) string {
+	switch {
+	case t.Info()&types.IsBoolean != 0:
+		return "bool"
+	case t.Info()&types.IsString != 0:
+		return "string"
+	case t.Info()&types.IsInteger != 0:
+		return "int"
+	}
+	return "float64"
+}
+
+// converter returns the call to the function that converts x between Elm
+// and Go in the given direction, "to" or "from" Go, adding the function if
+// it is not there yet.
+func (c *converters) converter(dir string, t types.Type, x string) string {
+	name := dir + c.typeName(t)
+	if _, ok := c.funcs[name]; !ok {
+		c.funcs[name] = ""
+		if dir == "to" {
+			c.funcs[name] = c.toGoFunc(name, t)
+		} else {
+			c.funcs[name] = c.fromGoFunc(name, t)
+		}
+	}
+	return name + "(" + x + ")"
+}
+
+// typeName returns the name of a type in the names of its converters, such
+// as `ListUser` for `[]User`.
+func (c *converters) typeName(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Slice:
+		return "List" + c.typeName(t.Elem())
+	case *types.Pointer:
+		return "Maybe" + c.typeName(t.Elem())
+	case *types.Named:
+		return t.Obj().Name()
+	case *types.Basic:
+		return strings.Title(t.Name())
+	}
+	return ""
+}
+
+// toGoFunc returns the function that converts Elm values to the Go type.
+func (c *converters) toGoFunc(name string, t types.Type) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\nfunc %s(v rt.Value) %s {\n", name, c.goType(t))
+	switch t := t.(type) {
+	case *types.Slice:
+		fmt.Fprintf(&buf, "\tvar result %s\n", c.goType(t))
+		fmt.Fprintf(&buf, "\tfor _, e := range rt.ToSlice(v) {\n\t\tresult = append(result, %s)\n\t}\n", c.toGo(t.Elem(), "e"))
+		buf.WriteString("\treturn result\n")
+	case *types.Pointer:
+		fmt.Fprintf(&buf, "\tc := v.(*rt.Ctor)\n\tif c.Tag != %d {\n\t\treturn nil\n\t}\n\n", justTag)
+		fmt.Fprintf(&buf, "\tx := %s\n\treturn &x\n", c.toGo(t.Elem(), "c.Args[0]"))
+	case *types.Named:
+		buf.WriteString("\tr := v.(rt.Record)\n")
+		fmt.Fprintf(&buf, "\treturn %s{\n", c.goType(t))
+		for _, f := range c.g.alias(t).fields {
+			fmt.Fprintf(&buf, "\t\t%s: %s,\n", f.goName, c.toGo(f.typ, fmt.Sprintf("r[%q]", f.name)))
+		}
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// fromGoFunc returns the function that converts values of the Go type to
+// Elm values.
+func (c *converters) fromGoFunc(name string, t types.Type) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\nfunc %s(x %s) rt.Value {\n", name, c.goType(t))
+	switch t := t.(type) {
+	case *types.Slice:
+		buf.WriteString("\tvar elems = make([]rt.Value, len(x))\n")
+		fmt.Fprintf(&buf, "\tfor i, e := range x {\n\t\telems[i] = %s\n\t}\n", c.fromGo(t.Elem(), "e"))
+		buf.WriteString("\treturn rt.List(elems...)\n")
+	case *types.Pointer:
+		fmt.Fprintf(&buf, "\tif x == nil {\n\t\treturn rt.C(%d)\n\t}\n", nothingTag)
+		fmt.Fprintf(&buf, "\treturn rt.C(%d, %s)\n", justTag, c.fromGo(t.Elem(), "*x"))
+	case *types.Named:
+		buf.WriteString("\treturn rt.Record{\n")
+		for _, f := range c.g.alias(t).fields {
+			fmt.Fprintf(&buf, "\t\t%q: %s,\n", f.name, c.fromGo(f.typ, "x."+f.goName))
+		}
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// alias returns the type alias of the struct.
+func (g *generator) alias(named *types.Named) *alias {
+	for _, a := range g.aliases {
+		if a.named == named {
+			return a
+		}
+	}
+	return nil
+}
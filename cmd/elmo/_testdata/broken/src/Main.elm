@@ -0,0 +1,27 @@
+port module Main exposing (..)
+
+import Basics exposing (..)
+import Platform exposing (..)
+import Platform.Cmd as Cmd exposing (..)
+import Platform.Sub as Sub exposing (..)
+
+
+port total : Int -> Cmd msg
+
+
+type Msg
+    = Reset
+
+
+update : Msg -> Int -> ( Int, Cmd Msg )
+update msg model =
+    ( 0, total "zero" )
+
+
+main : Program Never Int Msg
+main =
+    program
+        { init = ( 0, Cmd.none )
+        , update = update
+        , subscriptions = \_ -> Sub.batch []
+        }
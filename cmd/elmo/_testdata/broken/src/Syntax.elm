@@ -0,0 +1,5 @@
+module Syntax exposing (..)
+
+
+answer =
+    (42
@@ -0,0 +1,15 @@
+module Basics exposing (..)
+
+import Native.Basics
+
+
+type Never
+    = JustOneMore Never
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Basics.add
+
+
+infixl 6 +
@@ -0,0 +1,32 @@
+port module Main exposing (..)
+
+import Basics exposing (..)
+import Platform exposing (..)
+import Platform.Cmd as Cmd exposing (..)
+import Platform.Sub as Sub exposing (..)
+
+
+port increment : (Int -> msg) -> Sub msg
+
+
+port total : Int -> Cmd msg
+
+
+type Msg
+    = Increment Int
+
+
+update : Msg -> Int -> ( Int, Cmd Msg )
+update msg model =
+    case msg of
+        Increment n ->
+            ( model + n, total (model + n) )
+
+
+main : Program Never Int Msg
+main =
+    program
+        { init = ( 0, Cmd.none )
+        , update = update
+        , subscriptions = \_ -> increment Increment
+        }
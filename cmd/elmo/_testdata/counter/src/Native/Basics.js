@@ -0,0 +1,3 @@
+var Native$Basics = {
+	add: _F(2, function(a, b) { return a + b; })
+};
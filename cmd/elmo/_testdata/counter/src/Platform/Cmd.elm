@@ -0,0 +1,17 @@
+module Platform.Cmd exposing (..)
+
+import Native.Platform
+
+
+type Cmd msg
+    = Cmd
+
+
+none : Cmd msg
+none =
+    Native.Platform.none
+
+
+batch : List (Cmd msg) -> Cmd msg
+batch =
+    Native.Platform.batch
@@ -0,0 +1,12 @@
+module Platform.Sub exposing (..)
+
+import Native.Platform
+
+
+type Sub msg
+    = Sub
+
+
+batch : List (Sub msg) -> Sub msg
+batch =
+    Native.Platform.batch
@@ -0,0 +1,3 @@
+module Basics exposing (..)
+
+placeholder = "foo"
@@ -0,0 +1,3 @@
+module Debug exposing (..)
+
+placeholder = "foo"
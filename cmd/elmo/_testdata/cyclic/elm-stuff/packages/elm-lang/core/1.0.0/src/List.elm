@@ -0,0 +1,3 @@
+module List exposing (..)
+
+placeholder = "foo"
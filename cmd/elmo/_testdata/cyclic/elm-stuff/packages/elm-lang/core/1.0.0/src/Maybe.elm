@@ -0,0 +1,3 @@
+module Maybe exposing (..)
+
+placeholder = "foo"
@@ -0,0 +1,3 @@
+module Result exposing (..)
+
+placeholder = "foo"
@@ -0,0 +1,3 @@
+module String exposing (..)
+
+placeholder = "foo"
@@ -0,0 +1,3 @@
+module Tuple exposing (..)
+
+placeholder = "foo"
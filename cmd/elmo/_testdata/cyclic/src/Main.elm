@@ -0,0 +1,8 @@
+module Main exposing (..)
+
+import Model
+import View
+
+
+main =
+    View.view Model.initial
@@ -0,0 +1,7 @@
+module Model exposing (..)
+
+import Update
+
+
+initial =
+    Update.reset
@@ -0,0 +1,11 @@
+module Update exposing (..)
+
+import Model
+
+
+reset =
+    0
+
+
+update model =
+    Model.initial
@@ -0,0 +1,7 @@
+module View exposing (..)
+
+import Model
+
+
+view model =
+    model
@@ -0,0 +1,21 @@
+module Greeting exposing (Greeting(..), hello)
+
+{-| Greetings for everyone.
+
+@docs Greeting, hello
+
+-}
+
+
+{-| The kinds of greetings.
+-}
+type Greeting
+    = Formal
+    | Casual
+
+
+{-| Greets someone.
+-}
+hello : Greeting -> String -> String
+hello greeting name =
+    name
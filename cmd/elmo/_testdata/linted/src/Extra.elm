@@ -0,0 +1,6 @@
+module Extra exposing (one)
+
+
+one : Int
+one =
+    1
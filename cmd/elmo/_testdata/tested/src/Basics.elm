@@ -0,0 +1,63 @@
+module Basics exposing (..)
+
+import Native.Basics
+
+
+type Never
+    = JustOneMore Never
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Basics.add
+
+
+(-) : number -> number -> number
+(-) =
+    Native.Basics.sub
+
+
+(*) : number -> number -> number
+(*) =
+    Native.Basics.mul
+
+
+(==) : a -> a -> Bool
+(==) =
+    Native.Basics.eq
+
+
+(<) : comparable -> comparable -> Bool
+(<) =
+    Native.Basics.lt
+
+
+(++) : String -> String -> String
+(++) =
+    Native.Basics.append
+
+
+toString : a -> String
+toString =
+    Native.Basics.toString
+
+
+negate : number -> number
+negate n =
+    0 - n
+
+
+not : Bool -> Bool
+not b =
+    if b then
+        False
+    else
+        True
+
+
+infixl 6 +
+infixl 6 -
+infixl 7 *
+infix 4 ==
+infix 4 <
+infixr 5 ++
@@ -0,0 +1,45 @@
+module Math exposing (double, sign)
+
+import Basics exposing (..)
+
+
+{-| Doubles a number.
+
+```elm
+double 2 ==> 4
+
+four =
+    double 2
+
+double four
+    ==> 8
+```
+
+-}
+double : number -> number
+double n =
+    n * 2
+
+
+positive : Int -> Int
+positive n =
+    if n == 0 then
+        0
+    else
+        1
+
+
+{-| The sign of a number, whose example is wrong.
+
+```elm
+sign (-3) ==> -1
+sign 0 ==> 1
+```
+
+-}
+sign : Int -> Int
+sign n =
+    if n < 0 then
+        -1
+    else
+        positive n
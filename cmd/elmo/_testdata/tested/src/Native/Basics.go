@@ -0,0 +1,60 @@
+package native
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/elm-tangram/tangram/codegen/golang/rt"
+)
+
+func BasicsAdd(a, b rt.Value) rt.Value {
+	if a, ok := a.(float64); ok {
+		return a + b.(float64)
+	}
+	return a.(int) + b.(int)
+}
+
+func BasicsSub(a, b rt.Value) rt.Value {
+	if a, ok := a.(float64); ok {
+		return a - b.(float64)
+	}
+	return a.(int) - b.(int)
+}
+
+func BasicsMul(a, b rt.Value) rt.Value {
+	if a, ok := a.(float64); ok {
+		return a * b.(float64)
+	}
+	return a.(int) * b.(int)
+}
+
+func BasicsEq(a, b rt.Value) rt.Value {
+	return a == b
+}
+
+func BasicsLt(a, b rt.Value) rt.Value {
+	switch a := a.(type) {
+	case float64:
+		return a < b.(float64)
+	case string:
+		return a < b.(string)
+	}
+	return a.(int) < b.(int)
+}
+
+func BasicsAppend(a, b rt.Value) rt.Value {
+	return a.(string) + b.(string)
+}
+
+func BasicsToString(a rt.Value) rt.Value {
+	switch a := a.(type) {
+	case string:
+		return strconv.Quote(a)
+	case bool:
+		if a {
+			return "True"
+		}
+		return "False"
+	}
+	return fmt.Sprint(a)
+}
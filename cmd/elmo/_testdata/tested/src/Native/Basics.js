@@ -0,0 +1,9 @@
+var Native$Basics = {
+	add: _F(2, function(a, b) { return a + b; }),
+	sub: _F(2, function(a, b) { return a - b; }),
+	mul: _F(2, function(a, b) { return a * b; }),
+	eq: _F(2, function(a, b) { return a === b; }),
+	lt: _F(2, function(a, b) { return a < b; }),
+	append: _F(2, function(a, b) { return a + b; }),
+	toString: function(a) { return _toString(a); }
+};
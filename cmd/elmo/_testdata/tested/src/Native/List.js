@@ -0,0 +1,3 @@
+var Native$List = {
+	cons: _F(2, _cons)
+};
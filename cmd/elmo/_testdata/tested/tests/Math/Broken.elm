@@ -0,0 +1,16 @@
+module Math.Broken exposing (..)
+
+import Basics exposing (..)
+import Math exposing (double)
+
+
+testDoubleIsSquare : Int -> Bool
+testDoubleIsSquare n =
+    double n == (n * n)
+
+
+testSomeWrong : List Bool
+testSomeWrong =
+    [ double 2 == 4
+    , double 3 == 9
+    ]
@@ -0,0 +1,23 @@
+module Math.Expected exposing (..)
+
+import Basics exposing (..)
+import Expect exposing (Expectation)
+import Math exposing (double, sign)
+
+
+testDoubleTwo : Expectation
+testDoubleTwo =
+    Expect.equal 4 (double 2)
+
+
+testSigns : List Expectation
+testSigns =
+    [ Expect.equal 1 (sign 5)
+    , Expect.equal 0 (sign 1)
+    , Expect.all [ Expect.lessThan 0, Expect.notEqual (-2) ] (sign (-3))
+    ]
+
+
+testDoubleGrows : Int -> Expectation
+testDoubleGrows n =
+    Expect.greaterThan n (double n)
@@ -0,0 +1,32 @@
+module MathTest exposing (..)
+
+import Basics exposing (..)
+import Math exposing (double, sign)
+
+
+testDouble : Bool
+testDouble =
+    double 21 == 42
+
+
+testSign : List Bool
+testSign =
+    [ sign (-5) == -1
+    , sign 0 == 0
+    , sign 3 == 1
+    ]
+
+
+testDoubleSum : Int -> Int -> Bool
+testDoubleSum a b =
+    double (a + b) == double a + double b
+
+
+testAppend : String -> Bool
+testAppend s =
+    s ++ "" == s
+
+
+helper : Int
+helper =
+    1
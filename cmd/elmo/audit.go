@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+)
+
+const auditUsage = `Usage: elmo audit [flags]
+
+Audit checks the exact versions of the dependencies of the package in the
+current directory, the ones saved in elm-stuff/exact-dependencies.json or in
+the elm.json of Elm 0.19, against the advisory feed of the registry. It
+reports the versions with a known problem, such as a vulnerability, the
+versions yanked by their authors and the packages that are no longer
+maintained.
+
+The versions with an advisory and the yanked versions are known to be bad,
+so the command fails if there is any, which can fail a CI build. The
+unmaintained packages are only warnings.
+
+The flags are:
+
+`
+
+// auditOptions are the flags of the audit command.
+type auditOptions struct {
+	dir        string
+	registry   string
+	advisories string
+	ignore     string
+}
+
+func runAudit(args []string, stdout, stderr io.Writer) int {
+	var opts auditOptions
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.dir, "dir", ".", "directory of the package")
+	flags.StringVar(&opts.registry, "registry", pkg.DefaultRegistry, "URL of the package registry")
+	flags.StringVar(&opts.advisories, "advisories", "", "URL of the advisory feed, instead of the one of the registry")
+	flags.StringVar(&opts.ignore, "ignore", "", "comma-separated IDs of the advisories that are ignored")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, auditUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	p, err := pkg.Load(opts.dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if p.ExactDependencies == nil && len(p.Dependencies) > 0 {
+		out.errorf("the dependencies of the package are not installed, run elmo install first")
+		return exitInternal
+	}
+
+	r := pkg.NewRegistry(opts.registry, archives)
+	advisories, err := r.Advisories(opts.advisories)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var ignore []string
+	for _, id := range strings.Split(opts.ignore, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ignore = append(ignore, id)
+		}
+	}
+
+	var bad = make(map[string]bool)
+	for _, f := range advisories.Audit(p.ExactDependencies, ignore...) {
+		data := fields{"package": f.Package, "version": f.Version, "kind": f.Kind}
+		if f.Advisory != nil {
+			data["advisory"] = f.Advisory
+		}
+
+		if !f.Bad() {
+			data["reason"] = f.Reason
+			out.progress("audit", data, fmt.Sprintf("warning: %s\n", f))
+			continue
+		}
+
+		bad[f.Package] = true
+		out.event("audit", data, f.String()+"\n")
+	}
+
+	if len(bad) > 0 {
+		out.text(fmt.Sprintf("%d of the %d dependencies have versions known to be bad\n", len(bad), len(p.ExactDependencies)))
+		return exitProblems
+	}
+
+	out.note(fmt.Sprintf("there are no known bad versions in the %d dependencies\n", len(p.ExactDependencies)))
+	return exitOK
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	srv := newRegistryServer(t)
+	defer srv.Close()
+	defer func(url string) { archives = url }(archives)
+	archives = srv.URL
+
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elm-package.json"), []byte(emptyManifest), 0644))
+
+	code, out := runElmo(t, "audit", "--dir", dir, "--registry", srv.URL)
+	require.Equal(0, code, out)
+	require.Equal("there are no known bad versions in the 0 dependencies\n", out)
+
+	code, out = runElmo(t, "install", "--dir", dir, "--registry", srv.URL, "elmo/greeting")
+	require.Equal(0, code, out)
+
+	code, out = runElmo(t, "audit", "--dir", dir, "--registry", srv.URL)
+	require.Equal(1, code, out)
+	require.Equal(strings.Join([]string{
+		"elmo/greeting 1.1.0 is affected by ELM-7 (high): Greets strangers",
+		"warning: elmo/greeting is no longer maintained: use elmo/hello instead",
+		"1 of the 1 dependencies have versions known to be bad",
+	}, "\n")+"\n", out)
+
+	code, out = runElmo(t, "audit", "--dir", dir, "--registry", srv.URL, "--ignore", "ELM-1, ELM-7", "--quiet")
+	require.Equal(0, code, out)
+	require.Equal("", out)
+
+	code, out = runElmo(t, "audit", "--dir", dir, "--registry", srv.URL, "--report", "json")
+	require.Equal(1, code, out)
+	require.Contains(out, `{"advisory":{"id":"ELM-7","package":"elmo/greeting","versions":"1.1.0 <= v < 1.2.0","severity":"high","summary":"Greets strangers"},"event":"audit","kind":"advisory","package":"elmo/greeting","version":"1.1.0"}`)
+	require.Contains(out, `{"event":"audit","kind":"unmaintained","package":"elmo/greeting","reason":"use elmo/hello instead","version":"1.1.0"}`)
+}
+
+func TestAuditErrors(t *testing.T) {
+	srv := newRegistryServer(t)
+	defer srv.Close()
+
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	manifest := strings.Replace(emptyManifest, `"dependencies": {}`, `"dependencies": {"elmo/greeting": "1.0.0 <= v < 2.0.0"}`, 1)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elm-package.json"), []byte(manifest), 0644))
+
+	code, out := runElmo(t, "audit", "--dir", dir, "--registry", srv.URL)
+	require.Equal(3, code)
+	require.Equal("elmo: the dependencies of the package are not installed, run elmo install first\n", out)
+
+	require.NoError(os.MkdirAll(filepath.Join(dir, "elm-stuff"), 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elm-stuff", "exact-dependencies.json"), []byte(`{"elmo/greeting": "1.0.0"}`), 0644))
+	code, out = runElmo(t, "audit", "--dir", dir, "--advisories", srv.URL+"/unknown")
+	require.Equal(3, code)
+	require.Contains(out, "elmo: pkg: can't get the advisories of the registry: unexpected status 404")
+
+	code, out = runElmo(t, "audit", "--dir", dir, "--registry", srv.URL)
+	require.Equal(0, code, out)
+	require.Equal("warning: elmo/greeting is no longer maintained: use elmo/hello instead\nthere are no known bad versions in the 1 dependencies\n", out)
+
+	code, out = runElmo(t, "audit", "extra")
+	require.Equal(2, code)
+	require.Contains(out, "Usage: elmo audit")
+}
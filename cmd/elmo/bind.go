@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/bindgen"
+)
+
+const bindUsage = `Usage: elmo bind [flags] dir
+
+Bind generates the Elm bindings of the Go package in the given directory, so
+the programs built for the go target can use its API. The bindings are an Elm
+module with a type alias, a JSON decoder and a JSON encoder for every
+exported struct and a function for every exported function, which calls the
+native module generated next to it, with its declarations and the Go code
+that converts the values between Elm and Go. The structs and functions whose
+types have no Elm type are skipped.
+
+The module is named after the Go package by default, such as Host for the
+package host, and its files are written to the src directory of the current
+package.
+
+The flags are:
+
+`
+
+// bindOptions are the flags of the bind command.
+type bindOptions struct {
+	module string
+	output string
+}
+
+func runBind(args []string, stdout, stderr io.Writer) int {
+	var opts bindOptions
+	flags := flag.NewFlagSet("bind", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.module, "module", "", "name of the Elm module of the bindings (default the name of the Go package, capitalized)")
+	flags.StringVar(&opts.output, "output", "src", "source directory the files of the bindings are written to")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, bindUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	pkg, err := bindgen.Load(flags.Arg(0))
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	module := opts.module
+	if module == "" {
+		module = strings.Title(pkg.Name())
+	}
+
+	bindings, err := bindgen.Generate(pkg, module)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if err := bindings.Write(opts.output); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	for _, s := range bindings.Skipped {
+		out.note(fmt.Sprintf("skipped %s\n", s))
+	}
+
+	var files []string
+	for path := range bindings.Files() {
+		files = append(files, filepath.Join(opts.output, path))
+	}
+	sort.Strings(files)
+	out.event("bind", fields{"module": module, "files": files, "skipped": bindings.Skipped}, fmt.Sprintf("bound %s in %s\n", pkg.Path(), strings.Join(files, ", ")))
+	return exitOK
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const hostPackage = "../../bindgen/_testdata/host"
+
+func TestBind(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	code, out := runElmo(t, "bind", "--output", dir, hostPackage)
+	require.Equal(0, code, out)
+	require.Contains(out, "skipped func Join is variadic\n")
+	require.Contains(out, "bound host in "+filepath.Join(dir, "Host.elm")+", ")
+	require.Contains(readFile(t, filepath.Join(dir, "Host.elm")), "module Host exposing (")
+	require.Contains(readFile(t, filepath.Join(dir, "Native", "Host.decl")), "greet : User -> String\n")
+	require.Contains(readFile(t, filepath.Join(dir, "Native", "Host.go")), "func HostGreet(a0 rt.Value) rt.Value {\n")
+
+	code, out = runElmo(t, "bind", "--output", dir, "--module", "Api.Host", "--report", "json", hostPackage)
+	require.Equal(0, code, out)
+	require.Contains(out, `{"event":"bind","files":["`+filepath.Join(dir, "Api", "Host.elm")+`",`)
+	require.NotContains(out, "skipped func")
+	require.FileExists(filepath.Join(dir, "Native", "ApiHost.go"))
+
+	code, out = runElmo(t, "bind", "--module", "host", hostPackage)
+	require.Equal(exitInternal, code)
+	require.Equal("elmo: bindgen: \"host\" is not a valid module name\n", out)
+
+	code, _ = runElmo(t, "bind")
+	require.Equal(exitUsage, code)
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/codegen/golang"
+	"github.com/elm-tangram/tangram/codegen/js"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/embed"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/profile"
+)
+
+// runtimeNatives are the native modules provided by the JavaScript runtime,
+// which do not need a JavaScript source in the package.
+var runtimeNatives = map[string]bool{
+	"Native.Platform": true,
+	"Native.Debug":    true,
+}
+
+const buildUsage = `Usage: elmo build [flags] [path]
+
+Build compiles the main module at the given path, which is either the file
+of the module or the directory of a package whose main module is Main, and
+writes the generated code to the output file. The path is the current
+directory by default.
+
+The target is the kind of file that is built: js, html, node, webworker, go
+or wasm. By default, it is html for .html output files, go for .go output
+files, wasm for .wasm output files and js for the rest. All the targets but
+js run the main of the program, and they use the custom template of the
+package for the target, if any.
+
+The node and webworker targets run headless worker programs: the node
+script sends the lines of JSON of its standard input to the incoming ports
+and writes the values of the outgoing ones to its standard output, and the
+script of the Web Worker starts the program with the flags of its first
+message, {"flags": ...}, and then does the same with the messages it
+receives and posts, {"port": "input", "value": 2}.
+
+The wasm target is the Go program of the go target built for GOOS=js and
+GOARCH=wasm, with go or, with --tinygo, with TinyGo, whose modules are
+smaller. The JavaScript that loads the module is written next to it, such as
+elm.js for elm.wasm, and Elm.load("elm.wasm") returns a promise of the Elm
+object with the main program, whose worker is started as the one of the
+bundle is.
+
+With --watch, the program is built again every time the Elm files of the
+source directories change, until elmo is interrupted. The problems of every
+build are reported, and only the modules affected by the changes are
+compiled again, except for the go target and optimized builds, which are
+always compiled as a whole.
+
+With --embed, the output file is written to the directory of a Go package,
+next to the Go file that embeds it with a go:embed directive and serves it
+with the http.Handler named Handler, so a Go service can ship the program in
+a single binary. The package is named after the directory, which is
+frontend by default, and the output file is index.html for the html target.
+Only the js and html targets can be embedded.
+
+With --source-map, the source map of the JavaScript is written next to the
+output file, such as elm.js.map for elm.js, and the output links it, so the
+browsers and node show the Elm source code in the stack traces and the
+debuggers. Only the js, node and webworker targets have source maps.
+
+With --reproducible, the output is the same for the same source code, no
+matter where or when it is built: the line directives of --debug have the
+paths relative to the root of the package, with forward slashes, and the
+wasm target is built with -trimpath and without the version control
+information of go, which TinyGo cannot do. The rest of the output never
+depends on where it is built.
+
+Without --target and --output, the target is the one of the elmo.toml or
+.elmorc project configuration of the current directory, if it has one.
+
+The flags are:
+
+`
+
+// buildOptions are the flags of the build command.
+type buildOptions struct {
+	output   string
+	target   string
+	natives  string
+	optimize bool
+	debug    bool
+	watch    bool
+	embed    bool
+	tinygo   bool
+	// sourceMap reports whether the source map of the JavaScript is written
+	// next to the output.
+	sourceMap bool
+	// reproducible reports whether the output only depends on the source
+	// code, and not on where it is built.
+	reproducible bool
+}
+
+func runBuild(args []string, stdout, stderr io.Writer) int {
+	var opts buildOptions
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.output, "output", "", "path of the output file (default elm.js, or elm plus the extension of the target)")
+	flags.StringVar(&opts.target, "target", "", "kind of file that is built: js, html, node, webworker, go or wasm")
+	flags.StringVar(&opts.natives, "natives", "", "import path of the Go package of the native modules, for the go and wasm targets")
+	flags.BoolVar(&opts.tinygo, "tinygo", false, "build the wasm target with TinyGo instead of go")
+	flags.BoolVar(&opts.optimize, "optimize", false, "eliminate the dead code and minify the JavaScript output")
+	flags.BoolVar(&opts.debug, "debug", false, "keep all the code and report where crashes happen in the Elm source")
+	flags.BoolVar(&opts.watch, "watch", false, "build the program again every time its source code changes")
+	flags.BoolVar(&opts.embed, "embed", false, "write the output to a Go package that embeds and serves it")
+	flags.BoolVar(&opts.reproducible, "reproducible", false, "build the same output for the same source code, no matter where it is built")
+	flags.BoolVar(&opts.sourceMap, "source-map", false, "write the source map of the JavaScript output next to it, with the .map extension")
+	outOpts := outputFlags(flags)
+	prof := profileFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, buildUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	stop, ok := prof.start(out)
+	if !ok {
+		return exitInternal
+	}
+	defer stop()
+
+	if opts.optimize && opts.debug {
+		out.errorf("--optimize and --debug cannot be used together")
+		return exitUsage
+	}
+
+	// the target of the project configuration is the default one
+	if opts.target == "" && opts.output == "" {
+		opts.target = out.config.Target
+	}
+
+	t, err := buildTarget(&opts)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitUsage
+	}
+
+	if opts.watch {
+		done := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		defer signal.Stop(signals)
+		go func() {
+			<-signals
+			close(done)
+		}()
+		return watchBuild(flags.Arg(0), t, opts, done, out)
+	}
+
+	prog := loadFor(flags.Arg(0), t, out)
+	if prog == nil {
+		out.event("build", fields{"ok": false}, "")
+		return out.failure()
+	}
+
+	if err := build(prog, t, opts, nil); err != nil {
+		out.errorf("%s", err)
+		out.event("build", fields{"ok": false}, "")
+		// without an import path for the natives, the build can only
+		// succeed with another invocation
+		if err == golang.ErrNoNatives {
+			return exitUsage
+		}
+		return exitInternal
+	}
+
+	out.event("build", fields{"ok": true, "output": opts.output, "target": t}, "")
+	return exitOK
+}
+
+// buildTarget returns the target of the build and sets the default output
+// file if there is none.
+func buildTarget(opts *buildOptions) (target.Target, error) {
+	var t = target.JS
+	switch {
+	case opts.target != "":
+		var err error
+		if t, err = target.Parse(opts.target); err != nil {
+			return "", err
+		}
+	case filepath.Ext(opts.output) == target.HTML.Ext():
+		t = target.HTML
+	case filepath.Ext(opts.output) == target.Go.Ext():
+		t = target.Go
+	case filepath.Ext(opts.output) == target.WASM.Ext():
+		t = target.WASM
+	}
+
+	if opts.tinygo && t != target.WASM {
+		return "", fmt.Errorf("--tinygo can only be used with the wasm target")
+	}
+
+	if opts.tinygo && opts.reproducible {
+		return "", fmt.Errorf("--reproducible cannot be used with --tinygo")
+	}
+
+	if opts.sourceMap && t != target.JS && t != target.Node && t != target.WebWorker {
+		return "", fmt.Errorf("--source-map can only be used with the js, node and webworker targets")
+	}
+
+	if opts.embed && t != target.JS && t != target.HTML && t != target.WebWorker {
+		return "", fmt.Errorf("the %s target cannot be embedded, only the js, html and webworker targets can", t)
+	}
+
+	switch {
+	case opts.output != "":
+	case opts.embed && t == target.HTML:
+		opts.output = filepath.Join("frontend", embed.Index)
+	case opts.embed:
+		opts.output = filepath.Join("frontend", "elm"+t.Ext())
+	default:
+		opts.output = "elm" + t.Ext()
+	}
+	return t, nil
+}
+
+// objects are the JavaScript objects of the modules compiled by previous
+// builds, which are linked again until their modules are affected by a
+// change.
+type objects struct {
+	modules map[string]*js.Object
+	// compiled is the number of modules compiled by the last build.
+	compiled int
+}
+
+func newObjects() *objects {
+	return &objects{modules: make(map[string]*js.Object)}
+}
+
+// forget removes the objects of the given modules, so they are compiled
+// again.
+func (o *objects) forget(modules []string) {
+	for _, m := range modules {
+		delete(o.modules, m)
+	}
+}
+
+// build generates the code of the program for the given target and writes
+// it to the output file. The JavaScript objects of the modules are reused
+// and kept in objs, if it is not nil.
+func build(prog *program, t target.Target, opts buildOptions, objs *objects) error {
+	defer prog.profile.Start().Stop(profile.Codegen, profile.Program)
+	lowered := ir.Lower(prog.ast, prog.info)
+
+	// libraries are built without a main, so everything they expose is
+	// kept
+	var main string
+	var roots = lowered.Exposed()
+	if mod := lowered.Lookup(prog.main); mod != nil && mod.Lookup("main") != nil {
+		main = prog.main
+		roots = []*ir.Global{{Module: main, Name: "main", Typ: mod.Lookup("main").Type}}
+	}
+
+	if main == "" && t != target.JS {
+		return fmt.Errorf("module %s has no main, so it cannot be built for the %s target", prog.main, t)
+	}
+
+	var optOpts = ir.Options{NoFold: opts.debug, NoInline: opts.debug}
+	if opts.optimize {
+		optOpts.Roots = roots
+	}
+	ir.Optimize(lowered, optOpts)
+	if err := prog.runLowered(lowered); err != nil {
+		return err
+	}
+
+	// the line directives of reproducible builds are relative to the root
+	var trimPath string
+	if opts.reproducible {
+		trimPath = prog.pkg.Root()
+	}
+
+	var code, sourceMap bytes.Buffer
+	switch t.Backend() {
+	case "go":
+		if err := prog.checkNatives(); err != nil {
+			return err
+		}
+
+		err := golang.Generate(&code, lowered, golang.Options{
+			Main:           main,
+			Natives:        opts.natives,
+			LineDirectives: opts.debug,
+			TrimPath:       trimPath,
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		natives, err := jsNatives(prog.pkg, lowered)
+		if err != nil {
+			return err
+		}
+
+		jsOpts := js.Options{
+			Main:    main,
+			Natives: natives,
+			Debug:   opts.debug,
+			Minify:  opts.optimize,
+			Loader:  prog.loader,
+		}
+
+		if opts.sourceMap {
+			jsOpts.SourceMap = &sourceMap
+			jsOpts.SourceMapURL = filepath.Base(opts.output) + ".map"
+		}
+
+		if objs == nil {
+			err = js.Generate(&code, lowered, jsOpts)
+		} else {
+			err = js.Link(&code, lowered, objs.compile(lowered, jsOpts, prog.profile), jsOpts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	tmpl, err := target.Load(t, prog.pkg.Template(string(t)))
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, target.Data{Main: main, Code: code.String()}); err != nil {
+		return err
+	}
+	prog.profile.Event(profile.OutputEvent, profile.Program, map[string]interface{}{"target": t, "bytes": out.Len()})
+
+	if t == target.WASM {
+		compiler := goWasm
+		if opts.tinygo {
+			compiler = tinyGoWasm
+		}
+		return buildWasm(prog.pkg.Root(), out.Bytes(), opts.output, compiler, opts.reproducible)
+	}
+
+	var files = map[string][]byte{filepath.Base(opts.output): out.Bytes()}
+	if opts.sourceMap {
+		files[filepath.Base(opts.output)+".map"] = sourceMap.Bytes()
+	}
+
+	if opts.embed {
+		return embed.Write(filepath.Dir(opts.output), files)
+	}
+
+	if opts.sourceMap {
+		if err := writeOutput(opts.output+".map", sourceMap.Bytes()); err != nil {
+			return err
+		}
+	}
+	return writeOutput(opts.output, out.Bytes())
+}
+
+// compile returns the objects of the modules of the package, compiling the
+// ones that have no object yet. Whether they had one is an event of prof.
+func (o *objects) compile(pkg *ir.Package, opts js.Options, prof *profile.Profile) []*js.Object {
+	o.compiled = 0
+	var result = make([]*js.Object, len(pkg.Modules))
+	for i, mod := range pkg.Modules {
+		obj, ok := o.modules[mod.Name]
+		prof.Event(profile.CacheEvent, mod.Name, map[string]interface{}{"hit": ok})
+		if !ok {
+			obj = js.Compile(mod, "", opts)
+			o.modules[mod.Name] = obj
+			o.compiled++
+			prof.Event(profile.OutputEvent, mod.Name, map[string]interface{}{"bytes": len(obj.Code)})
+		}
+		result[i] = obj
+	}
+	return result
+}
+
+// jsNatives returns the JavaScript source of the native modules used by the
+// package, which is in a file with the same name as the Go file of the
+// native module and the .js extension, such as `src/Native/List.js`.
+func jsNatives(p *pkg.Package, lowered *ir.Package) (map[string]string, error) {
+	var natives = make(map[string]string)
+	var err error
+	for _, m := range lowered.Modules {
+		for _, d := range m.Defs {
+			ir.Inspect(d.Expr, func(e ir.Expr) bool {
+				g, ok := e.(*ir.Global)
+				if !ok || !strings.HasPrefix(g.Module, "Native.") || err != nil {
+					return err == nil
+				}
+
+				if _, ok := natives[g.Module]; !ok {
+					natives[g.Module], err = nativeSource(p, g.Module)
+				}
+				return err == nil
+			})
+		}
+	}
+
+	for name, src := range natives {
+		if src == "" {
+			delete(natives, name)
+		}
+	}
+	return natives, err
+}
+
+// nativeSource returns the JavaScript source of the given native module, or
+// an empty string if it is provided by the runtime and the package does not
+// have its own.
+func nativeSource(p *pkg.Package, module string) (string, error) {
+	path, err := p.FindModule(module)
+	if err != nil {
+		return "", fmt.Errorf("can't find native module %s: %s", module, err)
+	}
+
+	jsPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".js"
+	src, err := ioutil.ReadFile(jsPath)
+	switch {
+	case os.IsNotExist(err) && runtimeNatives[module]:
+		return "", nil
+	case os.IsNotExist(err):
+		return "", fmt.Errorf("there is no JavaScript source for %s, it must be at %s", module, jsPath)
+	case err != nil:
+		return "", err
+	}
+
+	kernel, err := js.Kernel(module, string(src))
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", jsPath, err)
+	}
+	return kernel, nil
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const fixture = "_testdata/counter"
+
+func runElmo(t *testing.T, args ...string) (int, string) {
+	var out bytes.Buffer
+	code := run(args, &out, &out)
+	return code, out.String()
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "elmo")
+	require.NoError(t, err)
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestBuild(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "main.js")
+	code, out := runElmo(t, "build", "--output", output, fixture)
+	require.Equal(0, code, out)
+
+	bundle := readFile(t, output)
+	require.Contains(bundle, "var Native$Basics = {")
+	require.Contains(bundle, `_export(scope, "Main", { worker: _worker($Main$main, null) });`)
+
+	code, out = runElmo(t, "build", "--output", output, filepath.Join(fixture, "src", "Main.elm"))
+	require.Equal(0, code, out)
+	require.Equal(bundle, readFile(t, output))
+}
+
+func TestBuildJSON(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "main.js")
+	code, out := runElmo(t, "build", "--report=json", "--output", output, fixture)
+	require.Equal(0, code, out)
+	require.Equal([]fields{{"event": "build", "ok": true, "output": output, "target": "js"}}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "build", "--report=json", "--output", output, "_testdata/broken")
+	require.Equal(1, code)
+	events := jsonEvents(t, out)
+	require.Equal("diagnostic", events[0]["event"])
+	require.Equal(fields{"event": "build", "ok": false}, events[len(events)-1])
+
+	code, out = runElmo(t, "build", "--report=json", "--optimize", "--debug", fixture)
+	require.Equal(2, code)
+	require.Equal([]fields{{"event": "error", "message": "--optimize and --debug cannot be used together"}}, jsonEvents(t, out))
+}
+
+func TestBuildTargets(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	html := filepath.Join(dir, "index.html")
+	code, out := runElmo(t, "build", "--output", html, fixture)
+	require.Equal(0, code, out)
+	require.True(strings.HasPrefix(readFile(t, html), "<!DOCTYPE html>"))
+	require.Contains(readFile(t, html), "var app = Elm.Main.worker();")
+
+	program := filepath.Join(dir, "main.go")
+	code, out = runElmo(t, "build", "--output", program, "--natives", "example.com/natives", fixture)
+	require.Equal(0, code, out)
+	require.True(strings.HasPrefix(readFile(t, program), "// Code generated by tangram. DO NOT EDIT."))
+	require.Contains(readFile(t, program), "rt.Main(Worker())")
+
+	worker := filepath.Join(dir, "main.worker.js")
+	code, out = runElmo(t, "build", "--target", "webworker", "--output", worker, fixture)
+	require.Equal(0, code, out)
+	require.Contains(readFile(t, worker), "self.onmessage = function(event) {")
+
+	script := filepath.Join(dir, "main.node.js")
+	code, out = runElmo(t, "build", "--target", "node", "--output", script, fixture)
+	require.Equal(0, code, out)
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	cmd := exec.Command(node, script)
+	cmd.Stdin = strings.NewReader(`{"port": "increment", "value": 2}` + "\n" + `{"port": "increment", "value": 3}` + "\n")
+	result, err := cmd.CombinedOutput()
+	require.NoError(err, string(result))
+	require.Equal("{\"port\":\"total\",\"value\":2}\n{\"port\":\"total\",\"value\":5}\n", string(result))
+
+	// the Web Worker runs in a context with the API of the workers
+	harness := filepath.Join(dir, "worker.js")
+	require.NoError(ioutil.WriteFile(harness, []byte(`var vm = require('vm');
+var context = { setTimeout: setTimeout, postMessage: function(msg) { console.log(JSON.stringify(msg)); } };
+context.self = context;
+vm.createContext(context);
+vm.runInContext(require('fs').readFileSync(process.argv[2], 'utf8'), context);
+context.onmessage({ data: {} });
+setTimeout(function() {
+	context.onmessage({ data: { port: 'increment', value: 2 } });
+	context.onmessage({ data: { port: 'increment', value: 3 } });
+}, 0);
+`), 0644))
+
+	result, err = exec.Command(node, harness, worker).CombinedOutput()
+	require.NoError(err, string(result))
+	require.Equal("{\"port\":\"total\",\"value\":2}\n{\"port\":\"total\",\"value\":5}\n", string(result))
+}
+
+func TestBuildConfig(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	main, err := filepath.Abs(fixture)
+	require.NoError(err)
+	wd, err := os.Getwd()
+	require.NoError(err)
+	require.NoError(os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	require.NoError(ioutil.WriteFile("elmo.toml", []byte("target = \"html\"\n"), 0644))
+	code, out := runElmo(t, "build", main)
+	require.Equal(0, code, out)
+	require.True(strings.HasPrefix(readFile(t, filepath.Join(dir, "elm.html")), "<!DOCTYPE html>"))
+
+	code, out = runElmo(t, "build", "--target", "js", main)
+	require.Equal(0, code, out)
+	require.NotContains(readFile(t, filepath.Join(dir, "elm.js")), "<!DOCTYPE html>")
+}
+
+func TestBuildEmbed(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	html := filepath.Join(dir, "frontend", "index.html")
+	code, out := runElmo(t, "build", "--embed", "--output", html, fixture)
+	require.Equal(0, code, out)
+	require.Contains(readFile(t, html), "var app = Elm.Main.worker();")
+
+	src := readFile(t, filepath.Join(dir, "frontend", "embed.go"))
+	require.Contains(src, "package frontend\n")
+	require.Contains(src, "//go:embed index.html\n")
+
+	code, out = runElmo(t, "build", "--embed", "--target", "go", fixture)
+	require.Equal(exitUsage, code)
+	require.Equal("elmo: the go target cannot be embedded, only the js, html and webworker targets can\n", out)
+}
+
+const counterNatives = "github.com/elm-tangram/tangram/cmd/elmo/_testdata/counter/src/Native"
+
+func TestBuildWasm(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "build", "--tinygo", "--output", "main.js", fixture)
+	require.Equal(exitUsage, code)
+	require.Equal("elmo: --tinygo can only be used with the wasm target\n", out)
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	if err := exec.Command(goBin, "list", counterNatives).Run(); err != nil {
+		t.Skip("natives of the counter package cannot be imported")
+	}
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	module := filepath.Join(dir, "main.wasm")
+	code, out = runElmo(t, "build", "--output", module, "--natives", counterNatives, fixture)
+	require.Equal(0, code, out)
+	require.FileExists(module)
+	require.Contains(readFile(t, filepath.Join(dir, "main.js")), "scope.Elm.load = function(source) {")
+	_, err = os.Stat(filepath.Join(fixture, "elm-stuff"))
+	require.True(os.IsNotExist(err))
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	script := filepath.Join(dir, "run.js")
+	require.NoError(ioutil.WriteFile(script, []byte(`require('./main.js');
+Elm.load(require('fs').readFileSync(__dirname + '/main.wasm')).then(function(Elm) {
+	var app = Elm.Main.worker();
+	app.ports.total.subscribe(function(value) { console.log(value); });
+	app.ports.increment.send(2);
+	app.ports.increment.send(3);
+	process.exit(0);
+});
+`), 0644))
+
+	result, err := exec.Command(node, script).CombinedOutput()
+	require.NoError(err, string(result))
+	require.Equal("2\n5\n", string(result))
+}
+
+func TestBuildReproducible(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// the same package is built from two directories
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second", "counter")
+	copyDir(t, fixture, first)
+	copyDir(t, fixture, second)
+
+	build := func(pkg, output string, args ...string) string {
+		path := filepath.Join(dir, output)
+		code, out := runElmo(t, append(append([]string{"build", "--output", path}, args...), pkg)...)
+		require.Equal(0, code, out)
+		return readFile(t, path)
+	}
+
+	cases := [][]string{
+		{"--target", "js"},
+		{"--target", "html", "--optimize"},
+		{"--target", "webworker", "--debug"},
+		{"--target", "go", "--debug", "--natives", counterNatives},
+	}
+	for _, args := range cases {
+		args = append(args, "--reproducible")
+		out := build(first, "first.out", args...)
+		require.Equal(out, build(first, "again.out", args...), "%v", args)
+		require.Equal(out, build(second, "second.out", args...), "%v", args)
+	}
+
+	out := build(first, "main.go", "--target", "go", "--debug", "--natives", counterNatives, "--reproducible")
+	require.Contains(out, "/*line src/Main.elm:")
+	require.NotContains(out, dir)
+
+	out = build(first, "main.go", "--target", "go", "--debug", "--natives", counterNatives)
+	require.Contains(out, "/*line "+first)
+
+	code, out := runElmo(t, "build", "--tinygo", "--reproducible", "--output", filepath.Join(dir, "main.wasm"), fixture)
+	require.Equal(exitUsage, code)
+	require.Equal("elmo: --reproducible cannot be used with --tinygo\n", out)
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	if err := exec.Command(goBin, "list", counterNatives).Run(); err != nil {
+		t.Skip("natives of the counter package cannot be imported")
+	}
+
+	args := []string{"--natives", counterNatives, "--reproducible"}
+	require.Equal(build(fixture, "first.wasm", args...), build(fixture, "second.wasm", args...))
+}
+
+func TestBuildOptimize(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	plain := filepath.Join(dir, "plain.js")
+	code, out := runElmo(t, "build", "--output", plain, fixture)
+	require.Equal(0, code, out)
+
+	optimized := filepath.Join(dir, "optimized.js")
+	code, out = runElmo(t, "build", "--optimize", "--output", optimized, fixture)
+	require.Equal(0, code, out)
+	require.True(len(readFile(t, optimized)) < len(readFile(t, plain)))
+	require.NotContains(readFile(t, optimized), "$Main$update")
+
+	debug := filepath.Join(dir, "debug.js")
+	code, out = runElmo(t, "build", "--debug", "--output", debug, fixture)
+	require.Equal(0, code, out)
+	require.Contains(readFile(t, debug), `"Increment"`)
+}
+
+func TestBuildSourceMap(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "main.js")
+	code, out := runElmo(t, "build", "--source-map", "--output", output, fixture)
+	require.Equal(0, code, out)
+	require.True(strings.HasSuffix(readFile(t, output), "//# sourceMappingURL=main.js.map\n"))
+
+	var m struct {
+		Version  int
+		Sources  []string
+		Mappings string
+	}
+	require.NoError(json.Unmarshal([]byte(readFile(t, output+".map")), &m))
+	require.Equal(3, m.Version)
+	require.Contains(m.Sources, filepath.Join(fixture, "src", "Main.elm"))
+	require.NotEmpty(m.Mappings)
+
+	code, out = runElmo(t, "build", "--source-map", "--output", filepath.Join(dir, "main.html"), fixture)
+	require.Equal(exitUsage, code)
+	require.Equal("elmo: --source-map can only be used with the js, node and webworker targets\n", out)
+}
+
+func TestBuildErrors(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "main.js")
+
+	code, out := runElmo(t, "build", "--optimize", "--debug", "--output", output, fixture)
+	require.Equal(2, code)
+	require.Equal("elmo: --optimize and --debug cannot be used together\n", out)
+
+	code, out = runElmo(t, "build", "--target", "exe", fixture)
+	require.Equal(2, code)
+	require.Equal("elmo: target: unknown target \"exe\"\n", out)
+
+	code, out = runElmo(t, "build", "--output", output, filepath.Join(fixture, "Missing.elm"))
+	require.Equal(3, code)
+	require.Contains(out, "elmo: ")
+
+	code, _ = runElmo(t, "build", "--output", output, fixture, fixture)
+	require.Equal(2, code)
+
+	code, out = runElmo(t, "build", "--target", "go", "--output", filepath.Join(dir, "main.go"), fixture)
+	require.Equal(2, code)
+	require.Equal("elmo: golang: natives are used, but there is no import path for them\n", out)
+
+	code, out = runElmo(t, "deploy")
+	require.Equal(2, code)
+	require.Contains(out, `elmo: unknown command "deploy"`)
+
+	_, err := os.Stat(output)
+	require.True(os.IsNotExist(err))
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/elm-tangram/tangram/doc"
+	"github.com/elm-tangram/tangram/package"
+)
+
+const bumpUsage = `Usage: elmo bump [flags] [path]
+
+Bump sets the version of the package at the given path, which is the current
+directory by default, to the next one after the changes of its exposed API
+since the version it has, which must be published in the registry. The
+version is major if anything was removed or changed, minor if anything was
+only added and patch otherwise. The version of a package that was never
+published is set to 1.0.0.
+
+The flags are:
+
+`
+
+// bumpOptions are the flags of the bump command.
+type bumpOptions struct {
+	registry string
+}
+
+func runBump(args []string, stdout, stderr io.Writer) int {
+	var opts bumpOptions
+	flags := flag.NewFlagSet("bump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.registry, "registry", pkg.DefaultRegistry, "URL of the package registry")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, bumpUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	path := flags.Arg(0)
+	if path == "" {
+		path = "."
+	}
+
+	p, name, ok := loadRelease(path, out)
+	if !ok {
+		return exitInternal
+	}
+
+	r := pkg.NewRegistry(opts.registry, archives)
+	versions, err := publishedVersions(r, name)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	prev := p.Version
+	var changes = new(doc.Changes)
+	if len(versions) == 0 {
+		p.Version = pkg.Version{1, 0, 0}
+	} else {
+		if !isPublished(versions, p.Version) {
+			out.errorf("the version %s of %s is not published, only published versions can be bumped", p.Version, name)
+			return exitInternal
+		}
+
+		var ok bool
+		if _, changes, ok = apiChanges(p, r, name, p.Version, out); !ok {
+			return out.failure()
+		}
+
+		p.Version = p.Version.Bump(changes.Magnitude())
+		if isPublished(versions, p.Version) {
+			out.errorf("the next version of %s, %s, is already published", name, p.Version)
+			return exitInternal
+		}
+	}
+
+	if p.Version == prev {
+		out.note(fmt.Sprintf("the version of %s is already %s\n", name, p.Version))
+		return exitOK
+	}
+
+	if err := p.Save(); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	text := fmt.Sprintf("bumped the version of %s from %s to %s\n", name, prev, p.Version)
+	if len(versions) > 0 {
+		text = fmt.Sprintf("bumped the version of %s from %s to %s, a %s change\n%s", name, prev, p.Version, changes.Magnitude(), changesText(changes))
+	}
+	out.event("bump", fields{
+		"package":   name,
+		"from":      prev,
+		"to":        p.Version,
+		"magnitude": changes.Magnitude(),
+		"changes":   changes,
+	}, text)
+	return exitOK
+}
+
+// loadRelease loads the package at the given path, which is going to be
+// released, and returns it with its name. It reports false if it is not a
+// package that can be published.
+func loadRelease(path string, out *output) (*pkg.Package, string, bool) {
+	p, err := pkg.Load(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return nil, "", false
+	}
+
+	name := packageName(p)
+	if !isPackageName(name) {
+		out.errorf("the repository of the package must have the form https://github.com/user/project.git, not %q", p.Repository)
+		return nil, "", false
+	}
+
+	if len(p.ExposedModules) == 0 {
+		out.errorf("the package at %s exposes no modules", p.Root())
+		return nil, "", false
+	}
+	return p, name, true
+}
+
+// publishedVersions returns the versions of the package published in the
+// registry, from the newest to the oldest, which are none if it was never
+// published.
+func publishedVersions(r *pkg.Registry, name string) ([]pkg.Version, error) {
+	versions, err := r.Versions(name)
+	if _, ok := err.(*pkg.NotFoundError); ok {
+		return nil, nil
+	}
+	return versions, err
+}
+
+// isPublished reports whether the version is one of the published ones.
+func isPublished(versions []pkg.Version, v pkg.Version) bool {
+	for _, published := range versions {
+		if published == v {
+			return true
+		}
+	}
+	return false
+}
+
+// apiChanges returns the documentation of the exposed modules of the
+// package and the changes of its API since the given published version.
+// The diagnostics are written to out, and it reports false if there are
+// any errors.
+func apiChanges(p *pkg.Package, r *pkg.Registry, name string, v pkg.Version, out *output) ([]*doc.Module, *doc.Changes, bool) {
+	data, err := r.Documentation(name, v)
+	if err != nil {
+		out.errorf("%s", err)
+		return nil, nil, false
+	}
+
+	old, err := doc.Read(bytes.NewReader(data))
+	if err != nil {
+		out.errorf("%s", err)
+		return nil, nil, false
+	}
+
+	mods, ok := packageDocs(p, out)
+	if !ok {
+		return nil, nil, false
+	}
+	return mods, doc.Diff(old, mods), true
+}
+
+// changesText describes the changes of the API, one per line.
+func changesText(c *doc.Changes) string {
+	var buf bytes.Buffer
+	for _, name := range c.Added {
+		fmt.Fprintf(&buf, "\tadded module %s\n", name)
+	}
+
+	for _, name := range c.Removed {
+		fmt.Fprintf(&buf, "\tremoved module %s\n", name)
+	}
+
+	for _, m := range c.Modules {
+		for _, change := range []struct {
+			verb  string
+			names []string
+		}{{"added", m.Added}, {"changed", m.Changed}, {"removed", m.Removed}} {
+			for _, name := range change.names {
+				fmt.Fprintf(&buf, "\t%s %s.%s\n", change.verb, m.Name, name)
+			}
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// oldGreetingDocs is the documentation of the version 1.0.0 of the
+// documented package, which did not have hello yet.
+const oldGreetingDocs = `[{
+    "name": "Greeting",
+    "comment": "",
+    "aliases": [],
+    "types": [{"name": "Greeting", "comment": "", "args": [], "cases": [["Formal", []], ["Casual", []]]}],
+    "values": [],
+    "generated-with-elm-version": "0.18.0"
+}]`
+
+// releaseRegistry is a registry where the documented package, whose name
+// is elm-lang/core, has the given versions published.
+type releaseRegistry struct {
+	*httptest.Server
+	versions []string
+	// docs is the documentation of every version, which is oldGreetingDocs
+	// for the ones that are not in it.
+	docs map[string]string
+	// published are the versions published by the tests.
+	published []string
+}
+
+func newReleaseRegistry(t *testing.T, versions ...string) *releaseRegistry {
+	r := &releaseRegistry{versions: versions, docs: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/all-packages", func(w http.ResponseWriter, req *http.Request) {
+		if len(r.versions) == 0 {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"name": "elm-lang/core", "versions": ["` + strings.Join(r.versions, `", "`) + `"]}]`))
+	})
+
+	mux.HandleFunc("/documentation", func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "elm-lang/core", req.FormValue("name"))
+		docs, ok := r.docs[req.FormValue("version")]
+		if !ok {
+			docs = oldGreetingDocs
+		}
+		w.Write([]byte(docs))
+	})
+
+	mux.HandleFunc("/register", func(w http.ResponseWriter, req *http.Request) {
+		f, _, err := req.FormFile("documentation")
+		require.NoError(t, err)
+		docs, err := ioutil.ReadAll(f)
+		require.NoError(t, err)
+		require.Contains(t, string(docs), `"name": "hello"`)
+
+		r.published = append(r.published, req.FormValue("name")+" "+req.FormValue("version"))
+	})
+
+	r.Server = httptest.NewServer(mux)
+	return r
+}
+
+// releasePackage returns a copy of the documented package in a temporary
+// directory.
+func releasePackage(t *testing.T) string {
+	dir := tempDir(t)
+	copyDir(t, documented, dir)
+	return dir
+}
+
+func TestBump(t *testing.T) {
+	srv := newReleaseRegistry(t, "1.0.0")
+	defer srv.Close()
+
+	require := require.New(t)
+	dir := releasePackage(t)
+	defer os.RemoveAll(dir)
+
+	code, out := runElmo(t, "bump", "--registry", srv.URL, dir)
+	require.Equal(0, code, out)
+	require.Equal("bumped the version of elm-lang/core from 1.0.0 to 1.1.0, a MINOR change\n\tadded Greeting.hello\n", out)
+	require.Contains(readFile(t, filepath.Join(dir, "elm-package.json")), `"version": "1.1.0"`)
+
+	code, out = runElmo(t, "bump", "--registry", srv.URL, dir)
+	require.Equal(3, code)
+	require.Equal("elmo: the version 1.1.0 of elm-lang/core is not published, only published versions can be bumped\n", out)
+
+	output := filepath.Join(dir, "docs.json")
+	code, out = runElmo(t, "doc", "--output", output, dir)
+	require.Equal(0, code, out)
+	srv.versions = []string{"1.0.0", "1.1.0"}
+	srv.docs["1.1.0"] = readFile(t, output)
+	code, out = runElmo(t, "bump", "--registry", srv.URL, "--report=json", dir)
+	require.Equal(0, code, out)
+	events := jsonEvents(t, out)
+	require.Len(events, 1)
+	require.Equal("bump", events[0]["event"])
+	require.Equal("1.1.0", events[0]["from"])
+	require.Equal("1.1.1", events[0]["to"])
+	require.Equal("PATCH", events[0]["magnitude"])
+}
+
+func TestBumpUnpublished(t *testing.T) {
+	srv := newReleaseRegistry(t)
+	defer srv.Close()
+
+	require := require.New(t)
+	dir := releasePackage(t)
+	defer os.RemoveAll(dir)
+
+	code, out := runElmo(t, "bump", "--registry", srv.URL, dir)
+	require.Equal(0, code, out)
+	require.Equal("the version of elm-lang/core is already 1.0.0\n", out)
+
+	code, out = runElmo(t, "bump", "--registry", srv.URL, fixture)
+	require.Equal(3, code)
+	require.Equal("elmo: the package at "+fixture+" exposes no modules\n", out)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+const checkUsage = `Usage: elmo check [path]
+
+Check parses, resolves and type checks the main module at the given path and
+all the modules it imports, without generating any code. The path is either
+the file of the module or the directory of a package whose main module is
+Main, and it is the current directory by default.
+
+The problems found are written to the standard error, and the exit code is
+not zero if any of them is an error.
+
+The flags are:
+
+`
+
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	outOpts := outputFlags(flags)
+	prof := profileFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, checkUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	stop, ok := prof.start(out)
+	if !ok {
+		return exitInternal
+	}
+	defer stop()
+
+	if load(flags.Arg(0), out) == nil {
+		out.event("check", fields{"ok": false}, "")
+		return out.failure()
+	}
+
+	out.event("check", fields{"ok": true}, "")
+	return exitOK
+}
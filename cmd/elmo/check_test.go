@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "check", fixture)
+	require.Equal(0, code, out)
+	require.Equal("", out)
+
+	code, out = runElmo(t, "check", filepath.Join(fixture, "src", "Main.elm"))
+	require.Equal(0, code, out)
+}
+
+func TestCheckErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "check", "_testdata/broken")
+	require.Equal(1, code)
+	require.Contains(out, "type error: I was expecting this to be of type:")
+	require.Contains(out, "at _testdata/broken/src/Main.elm:18:16")
+
+	code, out = runElmo(t, "check", "_testdata/broken/src/Syntax.elm")
+	require.Equal(1, code)
+	require.Contains(out, "syntax error: Unexpected end of file.")
+
+	code, _ = runElmo(t, "check", fixture, fixture)
+	require.Equal(2, code)
+}
+
+func TestCheckJSON(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "check", "--report=json", fixture)
+	require.Equal(0, code, out)
+	require.Equal([]fields{{"event": "check", "ok": true}}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "check", "--report=json", "_testdata/broken")
+	require.Equal(1, code)
+	events := jsonEvents(t, out)
+	require.True(len(events) > 1, out)
+
+	d := events[0]
+	require.Equal("diagnostic", d["event"])
+	require.Equal("type error", d["type"])
+	require.Equal("_testdata/broken/src/Main.elm", d["file"])
+	require.Equal(float64(18), d["line"])
+	require.Equal(float64(16), d["col"])
+	require.Contains(d["message"], "I was expecting this to be of type:")
+	require.Equal(fields{"event": "check", "ok": false}, events[len(events)-1])
+
+	code, out = runElmo(t, "check", "--report=json", "_testdata/broken/src/Syntax.elm")
+	require.Equal(1, code)
+	events = jsonEvents(t, out)
+	require.Equal("syntax error", events[0]["type"])
+	require.Equal("Unexpected end of file.", events[0]["message"])
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/elmo"
+	"github.com/elm-tangram/tangram/report"
+)
+
+const compileUsage = `Usage: elmo compile --stdin-protocol
+
+Compile compiles a package that is given on the standard input instead of
+read from the file system, so the build tools that are not written in Go,
+such as plugins of webpack or vite or rules of bazel, run elmo as a
+subprocess without writing the files to a temporary directory.
+
+The request is a JSON object with the files of the package by their paths,
+relative to its root and with its elm-package.json among them, the path of
+the entry file and the options of the compilation:
+
+	{
+	    "files": {"elm-package.json": "...", "src/Main.elm": "..."},
+	    "entry": "src/Main.elm",
+	    "options": {"target": "html", "output": "index.html"}
+	}
+
+The target is js by default, and the output is the path of the generated
+code, elm with the extension of the target by default. The modules of the
+dependencies must be among the files, because they are not installed, and
+the wasm target is not supported, since the module is not built.
+
+The response written to the standard output is a JSON object with all the
+diagnostics found, warnings included, and the generated code, which is only
+there if there are no errors:
+
+	{
+	    "ok": true,
+	    "diagnostics": [{"file": "src/Main.elm", "type": "warning", ...}],
+	    "artifacts": [{"path": "index.html", "content": "..."}]
+	}
+
+A request that is not valid is a response with just an error.
+
+The flags are:
+
+`
+
+// compileRequest is the request of the compile command.
+type compileRequest struct {
+	Files   map[string]string `json:"files"`
+	Entry   string            `json:"entry"`
+	Options compileOptions    `json:"options"`
+}
+
+// compileOptions are the options of a compile request.
+type compileOptions struct {
+	Target string `json:"target"`
+	Output string `json:"output"`
+}
+
+// compileResponse is the response of the compile command.
+type compileResponse struct {
+	OK          bool              `json:"ok"`
+	Error       string            `json:"error,omitempty"`
+	Diagnostics []elmo.Diagnostic `json:"diagnostics"`
+	Artifacts   []compileArtifact `json:"artifacts"`
+}
+
+// compileArtifact is a file generated by the compile command.
+type compileArtifact struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+func runCompile(args []string, stdout, stderr io.Writer) int {
+	var stdinProtocol bool
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&stdinProtocol, "stdin-protocol", false, "read the JSON request from stdin and write the JSON response to stdout")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, compileUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if !stdinProtocol || flags.NArg() > 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	var (
+		req  compileRequest
+		resp compileResponse
+		code = exitUsage
+	)
+	if err := decodeRequest(stdin, &req); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp, code = compileFiles(req, elmo.Limits{}, false)
+	}
+
+	if err := json.NewEncoder(stdout).Encode(resp.normalize()); err != nil {
+		fmt.Fprintf(stderr, "elmo: %s\n", err)
+		return exitInternal
+	}
+	return code
+}
+
+// decodeRequest decodes the JSON request read from r into v, which can not
+// have fields that are not known.
+func decodeRequest(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request: %s", err)
+	}
+	return nil
+}
+
+// compileFiles compiles the request with the given limits, or only checks
+// it if check is true, and returns the response and the exit code of the
+// command. The response of a check has no artifacts.
+func compileFiles(req compileRequest, limits elmo.Limits, check bool) (compileResponse, int) {
+	t := target.JS
+	if req.Options.Target != "" {
+		var err error
+		if t, err = target.Parse(req.Options.Target); err != nil {
+			return compileResponse{Error: err.Error()}, exitUsage
+		}
+	}
+
+	switch {
+	case t == target.WASM && !check:
+		return compileResponse{Error: "the wasm target cannot be compiled in memory"}, exitUsage
+	case req.Entry == "":
+		return compileResponse{Error: "the request has no entry file"}, exitUsage
+	}
+
+	if check {
+		diags := elmo.CheckStringWith(req.Files, req.Entry, t, limits)
+		resp := compileResponse{OK: !hasErrors(diags), Diagnostics: diags}
+		if !resp.OK {
+			return resp, exitProblems
+		}
+		return resp, exitOK
+	}
+
+	output := req.Options.Output
+	if output == "" {
+		output = "elm" + t.Ext()
+	}
+
+	out, diags := elmo.CompileStringWith(req.Files, req.Entry, t, limits)
+	resp := compileResponse{OK: !hasErrors(diags), Diagnostics: diags}
+	if !resp.OK {
+		return resp, exitProblems
+	}
+	resp.Artifacts = []compileArtifact{{Path: output, Content: out.Code}}
+	return resp, exitOK
+}
+
+// normalize returns the response with empty lists instead of nil ones, so
+// they are encoded as [] instead of null.
+func (r compileResponse) normalize() compileResponse {
+	if r.Diagnostics == nil {
+		r.Diagnostics = []elmo.Diagnostic{}
+	}
+	if r.Artifacts == nil {
+		r.Artifacts = []compileArtifact{}
+	}
+	return r
+}
+
+// hasErrors reports whether any of the diagnostics is not a warning.
+func hasErrors(diags []elmo.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Type != report.Warning.String() {
+			return true
+		}
+	}
+	return false
+}
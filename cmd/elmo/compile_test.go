@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// packageFiles returns the files of the package in the given directory by
+// their paths relative to it.
+func packageFiles(t *testing.T, dir string) map[string]string {
+	var files = make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func compileRequestOf(t *testing.T, files map[string]string, options string) io.Reader {
+	content, err := json.Marshal(files)
+	require.NoError(t, err)
+	return strings.NewReader(`{"files": ` + string(content) + `, "entry": "src/Main.elm", "options": ` + options + `}`)
+}
+
+func TestCompileStdinProtocol(t *testing.T) {
+	require := require.New(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	files := packageFiles(t, fixture)
+	stdin = compileRequestOf(t, files, `{"target": "html", "output": "index.html"}`)
+	code, out := runElmo(t, "compile", "--stdin-protocol")
+	require.Equal(0, code, out)
+
+	var resp compileResponse
+	require.NoError(json.Unmarshal([]byte(out), &resp))
+	require.True(resp.OK)
+	require.Empty(resp.Diagnostics)
+	require.Len(resp.Artifacts, 1)
+	require.Equal("index.html", resp.Artifacts[0].Path)
+	require.Contains(resp.Artifacts[0].Content, "<html")
+
+	stdin = compileRequestOf(t, files, `{}`)
+	code, out = runElmo(t, "compile", "--stdin-protocol")
+	require.Equal(0, code, out)
+	require.NoError(json.Unmarshal([]byte(out), &resp))
+	require.Equal("elm.js", resp.Artifacts[0].Path)
+
+	files["src/Main.elm"] = strings.Replace(files["src/Main.elm"], "main", "main =", 1)
+	stdin = compileRequestOf(t, files, `{}`)
+	code, out = runElmo(t, "compile", "--stdin-protocol")
+	require.Equal(1, code, out)
+	resp = compileResponse{}
+	require.NoError(json.Unmarshal([]byte(out), &resp))
+	require.False(resp.OK)
+	require.NotEmpty(resp.Diagnostics)
+	require.Equal("src/Main.elm", resp.Diagnostics[0].File)
+	require.Empty(resp.Artifacts)
+}
+
+func TestCompileStdinProtocolInvalid(t *testing.T) {
+	require := require.New(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	code, _ := runElmo(t, "compile")
+	require.Equal(2, code)
+
+	cases := []struct {
+		request string
+		err     string
+	}{
+		{`{"files": 1}`, "invalid request"},
+		{`{"files": {}, "entry": "Main.elm", "options": {"target": "wasm"}}`, "the wasm target cannot be compiled in memory"},
+		{`{"files": {}, "entry": "Main.elm", "options": {"target": "c"}}`, "unknown target"},
+		{`{"files": {}}`, "the request has no entry file"},
+	}
+
+	for _, c := range cases {
+		stdin = strings.NewReader(c.request)
+		code, out := runElmo(t, "compile", "--stdin-protocol")
+		require.Equal(2, code, c.request)
+
+		var resp compileResponse
+		require.NoError(json.Unmarshal([]byte(out), &resp))
+		require.Contains(resp.Error, c.err, c.request)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const completionUsage = `Usage: elmo completion [flags] bash|zsh|fish
+
+Completion prints the script that completes the commands of elmo and their
+flags in the given shell. The plugin commands installed on the PATH are
+completed as well, although not their flags. For example, to load the
+completions in every bash session:
+
+	echo 'source <(elmo completion bash)' >> ~/.bashrc
+
+The flags are:
+
+`
+
+// completionShells are the functions that write the completion script of
+// every shell.
+var completionShells = map[string]func(io.Writer, []commandFlags){
+	"bash": writeBash,
+	"zsh":  writeZsh,
+	"fish": writeFish,
+}
+
+func runCompletion(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("completion", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, completionUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	write, ok := completionShells[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "elmo: unknown shell %q, it must be bash, zsh or fish\n", flags.Arg(0))
+		return exitUsage
+	}
+
+	var cmds []commandFlags
+	for _, cmd := range commands {
+		cmds = append(cmds, commandFlags{cmd, flagsOf(cmd)})
+	}
+	write(stdout, cmds)
+	return exitOK
+}
+
+// commandFlags is a command with its flags.
+type commandFlags struct {
+	*command
+	flags []flagInfo
+}
+
+// flagInfo describes a flag of a command.
+type flagInfo struct {
+	name  string
+	usage string
+	// value reports whether the flag takes a value, which is the case of
+	// all the flags that are not booleans.
+	value bool
+}
+
+// flagsOf returns the flags of the command. The flag sets of the commands
+// are local to them, so the flags are read from the defaults they print in
+// their usage: every flag is on a line that starts with two spaces and its
+// name with a dash, followed by the type of its value if it is not a
+// boolean, and its usage is on the next lines, indented with four spaces
+// and a tab.
+func flagsOf(cmd *command) []flagInfo {
+	var buf bytes.Buffer
+	cmd.run([]string{"-h"}, ioutil.Discard, &buf)
+
+	var flags []flagInfo
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "  -"):
+			parts := strings.Fields(line)
+			flags = append(flags, flagInfo{
+				name:  strings.TrimPrefix(parts[0], "-"),
+				value: len(parts) > 1,
+			})
+		case strings.HasPrefix(line, "    \t") && len(flags) > 0:
+			f := &flags[len(flags)-1]
+			f.usage = strings.TrimSpace(f.usage + " " + strings.TrimSpace(line))
+		}
+	}
+	return flags
+}
+
+func writeBash(w io.Writer, cmds []commandFlags) {
+	var names []string
+	for _, cmd := range cmds {
+		names = append(names, cmd.name)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# bash completion for elmo, generated by `elmo completion bash`.\n\n")
+	buf.WriteString("_elmo() {\n")
+	buf.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	buf.WriteString("\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	buf.WriteString("\t\tlocal plugins=$(compgen -c elmo- | sed 's/^elmo-//')\n")
+	fmt.Fprintf(&buf, "\t\tCOMPREPLY=($(compgen -W \"%s $plugins\" -- \"$cur\"))\n", strings.Join(names, " "))
+	buf.WriteString("\t\treturn\n\tfi\n\n")
+	buf.WriteString("\tif [[ \"$cur\" != -* ]]; then\n\t\treturn\n\tfi\n\n")
+	buf.WriteString("\tcase \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range cmds {
+		var flags []string
+		for _, f := range cmd.flags {
+			flags = append(flags, "--"+f.name)
+		}
+		fmt.Fprintf(&buf, "\t%s)\n\t\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n\t\t;;\n", cmd.name, strings.Join(flags, " "))
+	}
+	buf.WriteString("\tesac\n}\n\n")
+	buf.WriteString("complete -o default -F _elmo elmo\n")
+	w.Write(buf.Bytes())
+}
+
+// zshQuote quotes the text to be used in a single-quoted zsh string and in
+// the description of a zsh completion.
+var zshQuote = strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+
+func writeZsh(w io.Writer, cmds []commandFlags) {
+	var buf bytes.Buffer
+	buf.WriteString("#compdef elmo\n")
+	buf.WriteString("# zsh completion for elmo, generated by `elmo completion zsh`.\n\n")
+	buf.WriteString("_elmo() {\n")
+	buf.WriteString("\tlocal -a cmds\n\tcmds=(\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&buf, "\t\t'%s:%s'\n", cmd.name, zshQuote.Replace(cmd.summary))
+	}
+	buf.WriteString("\t\t${${(k)commands[(I)elmo-*]}#elmo-}\n\t)\n\n")
+	buf.WriteString("\tif (( CURRENT == 2 )); then\n\t\t_describe 'command' cmds\n\t\treturn\n\tfi\n\n")
+	buf.WriteString("\tshift words\n\t(( CURRENT-- ))\n")
+	buf.WriteString("\tcase $words[1] in\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&buf, "\t%s)\n\t\t_arguments \\\n", cmd.name)
+		for _, f := range cmd.flags {
+			var value string
+			if f.value {
+				value = ":value:_files"
+			}
+			fmt.Fprintf(&buf, "\t\t\t'--%s[%s]%s' \\\n", f.name, zshQuote.Replace(f.usage), value)
+		}
+		buf.WriteString("\t\t\t'*:file:_files'\n\t\t;;\n")
+	}
+	buf.WriteString("\t*)\n\t\t_files\n\t\t;;\n")
+	buf.WriteString("\tesac\n}\n\n")
+	buf.WriteString("compdef _elmo elmo\n")
+	w.Write(buf.Bytes())
+}
+
+// fishQuote quotes the text to be used in a single-quoted fish string.
+var fishQuote = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+func writeFish(w io.Writer, cmds []commandFlags) {
+	var buf bytes.Buffer
+	buf.WriteString("# fish completion for elmo, generated by `elmo completion fish`.\n\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&buf, "complete -c elmo -f -n __fish_use_subcommand -a %s -d '%s'\n", cmd.name, fishQuote.Replace(cmd.summary))
+	}
+	buf.WriteString("complete -c elmo -f -n __fish_use_subcommand -a \"(complete -C 'elmo-' | string replace -r '^elmo-([^\\t]*).*' '$1')\"\n")
+
+	for _, cmd := range cmds {
+		buf.WriteString("\n")
+		for _, f := range cmd.flags {
+			var value string
+			if f.value {
+				value = " -r"
+			}
+			fmt.Fprintf(&buf, "complete -c elmo -n '__fish_seen_subcommand_from %s' -l %s%s -d '%s'\n", cmd.name, f.name, value, fishQuote.Replace(f.usage))
+		}
+	}
+	w.Write(buf.Bytes())
+}
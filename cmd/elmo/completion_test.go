@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			require := require.New(t)
+
+			code, out := runElmo(t, "completion", shell)
+			require.Equal(exitOK, code, out)
+			for _, cmd := range commands {
+				require.Contains(out, cmd.name)
+			}
+			require.Contains(out, "elmo-")
+		})
+	}
+}
+
+func TestCompletionFlags(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "completion", "bash")
+	require.Equal(exitOK, code, out)
+	require.Contains(out, "--output")
+	require.Contains(out, "--report")
+
+	code, out = runElmo(t, "completion", "zsh")
+	require.Equal(exitOK, code, out)
+	require.Contains(out, "'--output[")
+	require.Contains(out, "]:value:_files'")
+
+	code, out = runElmo(t, "completion", "fish")
+	require.Equal(exitOK, code, out)
+	require.Contains(out, "complete -c elmo -n '__fish_seen_subcommand_from build' -l output -r")
+	require.Contains(out, "complete -c elmo -n '__fish_seen_subcommand_from build' -l quiet -d")
+}
+
+func TestCompletionErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, _ := runElmo(t, "completion")
+	require.Equal(exitUsage, code)
+
+	code, out := runElmo(t, "completion", "powershell")
+	require.Equal(exitUsage, code)
+	require.Contains(out, `elmo: unknown shell "powershell"`)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/derive"
+	"github.com/elm-tangram/tangram/parser"
+)
+
+const deriveUsage = `Usage: elmo derive [flags] file.elm
+
+Derive generates the JSON decoders and encoders of the type aliases and the
+union types exposed by the module in the given file, in a new module named
+after it, such as Model.Json for Model. A record is an object, a tuple or a
+list is an array, a Maybe value is null or the value, and a value of a union
+type is an object with the name of its constructor in the "tag" field and
+its arguments in the "args" array.
+
+The module is written next to the file by default, such as src/Model/Json.elm
+for src/Model.elm.
+
+The flags are:
+
+`
+
+// deriveOptions are the flags of the derive command.
+type deriveOptions struct {
+	types  string
+	output string
+}
+
+func runDerive(args []string, stdout, stderr io.Writer) int {
+	var opts deriveOptions
+	flags := flag.NewFlagSet("derive", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.types, "types", "", "comma-separated names of the types to derive (default all the exposed types)")
+	flags.StringVar(&opts.output, "output", "", "file the module is written to, or - for the standard output")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, deriveUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	path := flags.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	defer f.Close()
+
+	mod, err := parser.ParseFrom(path, f, parser.SkipWarnings)
+	if err != nil {
+		out.problem(err)
+		return exitProblems
+	}
+
+	var names []string
+	if opts.types != "" {
+		names = strings.Split(opts.types, ",")
+	}
+
+	src, err := derive.JSON(mod, names...)
+	if err != nil {
+		out.problem(err)
+		return exitProblems
+	}
+
+	module := derive.Module(mod.Name)
+	if opts.output == "-" {
+		out.event("derived", fields{"module": module, "code": string(src)}, string(src))
+		return exitOK
+	}
+
+	output := opts.output
+	if output == "" {
+		output = filepath.Join(strings.TrimSuffix(path, filepath.Ext(path)), "Json.elm")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	out.event("derived", fields{"module": module, "file": output}, fmt.Sprintf("derived %s in %s\n", module, output))
+	return exitOK
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDerive(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Model.elm")
+	require.NoError(ioutil.WriteFile(path, []byte("module Model exposing (..)\n\ntype alias Point =\n    { x : Float\n    , y : Float\n    }\n\ntype alias Handler =\n    { run : Int -> Int }\n"), 0644))
+
+	code, out := runElmo(t, "derive", "--types", "Point", path)
+	require.Equal(0, code, out)
+	output := filepath.Join(dir, "Model", "Json.elm")
+	require.Equal("derived Model.Json in "+output+"\n", out)
+	require.Contains(readFile(t, output), "module Model.Json exposing (decodePoint, encodePoint)\n")
+
+	code, out = runElmo(t, "derive", "--types", "Point", "--output", "-", path)
+	require.Equal(0, code, out)
+	require.Contains(out, "decodePoint : Decode.Decoder Point\n")
+
+	code, out = runElmo(t, "derive", path)
+	require.Equal(exitProblems, code)
+	require.Equal("derive: Handler: functions have no JSON representation\n", out)
+
+	code, _ = runElmo(t, "derive")
+	require.Equal(exitUsage, code)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/symbol"
+)
+
+const describeUsage = `Usage: elmo describe [flags] Module.name [path]
+
+Describe prints what a top-level value, type or constructor of a module is,
+in the package at the given path, which is the current directory by
+default: how it is declared, with its type, the module that declares it
+and its doc comment, in markdown. For example:
+
+	elmo describe Util.identity
+
+A module name alone describes the module. The modules of the dependencies
+of the package can be described as well.
+
+The flags are:
+
+`
+
+func runDescribe(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("describe", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, describeUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	dir := flags.Arg(1)
+	if dir == "" {
+		dir = "."
+	}
+
+	p, err := pkg.Load(dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	module, name, path, err := findDeclaration(p, flags.Arg(0))
+	if err == pkg.ErrModuleNotFound {
+		out.errorf("there is no module %s in the package or its dependencies", module)
+		return exitUsage
+	} else if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	prog := loadMode(path, parser.FullParse|parser.ParseComments, out)
+	if prog == nil {
+		return out.failure()
+	}
+
+	desc := symbol.DescribeName(prog.ast, prog.info, module, name)
+	if desc == nil {
+		out.errorf("there is no declaration %s.%s in the package", module, name)
+		return exitUsage
+	}
+
+	out.event("describe", fields{
+		"name":   flags.Arg(0),
+		"code":   desc.Code,
+		"doc":    desc.Doc,
+		"module": desc.Module,
+	}, desc.Markdown()+"\n")
+	return exitOK
+}
+
+// findModule returns the path to the file of the module with the given
+// name in the package or in its dependencies, if they are installed.
+func findModule(p *pkg.Package, module string) (string, error) {
+	path, err := p.FindModule(module)
+	if err == pkg.ErrDepsNotInstalled {
+		return "", pkg.ErrModuleNotFound
+	}
+	return path, err
+}
+
+// findDeclaration returns the module and the name of the declaration with
+// the given qualified name, and the path to the file of the module. The
+// name is empty if the qualified name is the name of a module.
+func findDeclaration(p *pkg.Package, qualified string) (string, string, string, error) {
+	if path, err := findModule(p, qualified); err == nil {
+		return qualified, "", path, nil
+	} else if err != pkg.ErrModuleNotFound {
+		return "", "", "", err
+	}
+
+	i := strings.LastIndex(qualified, ".")
+	if i <= 0 {
+		return qualified, "", "", pkg.ErrModuleNotFound
+	}
+
+	module, name := qualified[:i], qualified[i+1:]
+	path, err := findModule(p, module)
+	return module, name, path, err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "describe", "Greeting.hello", documented)
+	require.Equal(exitOK, code, out)
+	require.Equal("```elm\nhello : Greeting.Greeting -> String -> String\n```\n\nDefined in `Greeting`.\n\nGreets someone.\n", out)
+
+	code, out = runElmo(t, "describe", "Greeting", documented)
+	require.Equal(exitOK, code, out)
+	require.Contains(out, "```elm\nmodule Greeting\n```\n\nGreetings for everyone.")
+
+	code, out = runElmo(t, "describe", "--report=json", "Greeting.Formal", documented)
+	require.Equal(exitOK, code, out)
+	events := jsonEvents(t, out)
+	require.Equal("describe", events[0]["event"])
+	require.Equal("Formal : Greeting.Greeting", events[0]["code"])
+	require.Equal("Greeting", events[0]["module"])
+}
+
+func TestDescribeErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "describe", "Greeting.missing", documented)
+	require.Equal(exitUsage, code, out)
+	require.Contains(out, "there is no declaration Greeting.missing in the package")
+
+	code, out = runElmo(t, "describe", "Missing.value", documented)
+	require.Equal(exitUsage, code, out)
+	require.Contains(out, "there is no module Missing in the package or its dependencies")
+
+	code, _ = runElmo(t, "describe")
+	require.Equal(exitUsage, code)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/doc"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+)
+
+const docUsage = `Usage: elmo doc [flags] [path]
+
+Doc generates the documentation of the package at the given path, which is
+the current directory by default, from the doc comments and the exposed
+declarations of the modules listed in the exposed-modules of the package.
+The documentation is written to the output file in the format of the
+docs.json files of the package registry, and it is also rendered as an HTML
+page if there is a path for it.
+
+The exposed modules tagged example in the module-tags of the manifest are
+documented too, but the ones tagged test are left out, as they are when
+the API of the package is compared by bump and publish.
+
+The flags are:
+
+`
+
+// docOptions are the flags of the doc command.
+type docOptions struct {
+	output string
+	html   string
+}
+
+func runDoc(args []string, stdout, stderr io.Writer) int {
+	var opts docOptions
+	flags := flag.NewFlagSet("doc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.output, "output", "docs.json", "path of the documentation file")
+	flags.StringVar(&opts.html, "html", "", "path of the HTML page of the documentation, if any")
+	outOpts := outputFlags(flags)
+	prof := profileFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, docUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	stop, ok := prof.start(out)
+	if !ok {
+		return exitInternal
+	}
+	defer stop()
+
+	path := flags.Arg(0)
+	if path == "" {
+		path = "."
+	}
+
+	p, err := pkg.Load(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if len(p.ExposedModules) == 0 {
+		out.errorf("the package at %s exposes no modules", p.Root())
+		return exitInternal
+	}
+
+	p = p.WithTags(pkg.ExampleTag)
+	mods, ok := packageDocs(p, out)
+	if !ok {
+		return out.failure()
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf, mods); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if err := writeOutput(opts.output, buf.Bytes()); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if opts.html == "" {
+		out.event("doc", fields{"output": opts.output, "modules": p.ExposedModules}, "")
+		return exitOK
+	}
+
+	buf.Reset()
+	if err := doc.HTML(&buf, packageTitle(p), mods); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if err := writeOutput(opts.html, buf.Bytes()); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	out.event("doc", fields{"output": opts.output, "html": opts.html, "modules": p.ExposedModules}, "")
+	return exitOK
+}
+
+// packageDocs returns the documentation of the exposed modules of the
+// package, in the order they are listed in, except the ones with a tag the
+// package does not include. The diagnostics are written to out, and it
+// reports false if there are any errors.
+func packageDocs(p *pkg.Package, out *output) ([]*doc.Module, bool) {
+	var mods []*doc.Module
+	for _, name := range p.ExposedModules {
+		path, err := p.FindSourceModule(name)
+		if _, ok := err.(*pkg.ExcludedModuleError); ok {
+			continue
+		} else if err != nil {
+			out.errorf("can't find exposed module %s: %s", name, err)
+			return nil, false
+		}
+
+		prog := loadMode(path, parser.FullParse|parser.ParseComments, out)
+		if prog == nil {
+			return nil, false
+		}
+		mods = append(mods, doc.New(prog.ast.Modules[prog.main], prog.info))
+	}
+	return mods, true
+}
+
+// packageTitle returns the name of the package and its version.
+func packageTitle(p *pkg.Package) string {
+	return fmt.Sprintf("%s %s", packageName(p), p.Version)
+}
+
+// packageName returns the name of the package, which is the repository
+// without the host and the extension.
+func packageName(p *pkg.Package) string {
+	name := strings.TrimSuffix(p.Repository, ".git")
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+len("://"):]
+	}
+
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// writeOutput writes the content to the file at the given path, creating
+// its directory if needed.
+func writeOutput(path string, content []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
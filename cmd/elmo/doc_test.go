@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/doc"
+
+	"github.com/stretchr/testify/require"
+)
+
+const documented = "_testdata/documented"
+
+func TestDoc(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "docs", "docs.json")
+	page := filepath.Join(dir, "docs", "index.html")
+	code, out := runElmo(t, "doc", "--output", output, "--html", page, documented)
+	require.Equal(0, code, out)
+
+	f, err := os.Open(output)
+	require.NoError(err)
+	defer f.Close()
+
+	mods, err := doc.Read(f)
+	require.NoError(err)
+	require.Len(mods, 1)
+	require.Equal("Greeting", mods[0].Name)
+	require.Equal(" Greetings for everyone.\n\n@docs Greeting, hello\n\n", mods[0].Comment)
+	require.Equal([]doc.Value{{Name: "hello", Comment: " Greets someone.\n", Type: "Greeting -> String -> String"}}, mods[0].Values)
+	require.Equal([]doc.Case{{"Formal", []string{}}, {"Casual", []string{}}}, mods[0].Types[0].Cases)
+
+	html := readFile(t, page)
+	require.Contains(html, "<title>elm-lang/core 1.0.0</title>")
+	require.Contains(html, "<p>Greets someone.</p>")
+}
+
+func TestDocModuleTags(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, documented, dir)
+
+	manifest := readFile(t, filepath.Join(dir, "elm-package.json"))
+	manifest = strings.Replace(manifest, `"Greeting"
+    ],`, `"Greeting",
+        "Examples.Formal",
+        "Fixtures"
+    ],
+    "module-tags": {"test": ["Fixtures"], "example": ["Examples.*"]},`, 1)
+	files := map[string]string{
+		"elm-package.json":        manifest,
+		"src/Examples/Formal.elm": "module Examples.Formal exposing (formal)\n\nimport Examples.Names\nimport Greeting\n\n\nformal : String\nformal =\n    Greeting.hello Greeting.Formal Examples.Names.name\n",
+		"src/Examples/Names.elm":  "module Examples.Names exposing (name)\n\n\nname : String\nname =\n    \"Alice\"\n",
+		"src/Fixtures.elm":        "module Fixtures exposing (name)\n\n\nname : String\nname =\n    \"Bob\"\n",
+	}
+	require.NoError(os.MkdirAll(filepath.Join(dir, "src", "Examples"), 0755))
+	for path, content := range files {
+		require.NoError(ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+
+	output := filepath.Join(dir, "docs.json")
+	code, out := runElmo(t, "doc", "--output", output, dir)
+	require.Equal(0, code, out)
+
+	f, err := os.Open(output)
+	require.NoError(err)
+	defer f.Close()
+
+	mods, err := doc.Read(f)
+	require.NoError(err)
+	require.Len(mods, 2)
+	require.Equal("Greeting", mods[0].Name)
+	require.Equal("Examples.Formal", mods[1].Name)
+}
+
+func TestDocErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "doc", fixture)
+	require.Equal(3, code)
+	require.Equal("elmo: the package at "+fixture+" exposes no modules\n", out)
+
+	code, out = runElmo(t, "doc", documented, "other")
+	require.Equal(2, code)
+	require.Contains(out, "Usage: elmo doc")
+}
@@ -0,0 +1,145 @@
+package main
+
+// expectModule is the module of the assertions with messages of the tests,
+// which elmo test compiles with them. It only needs the operators and
+// toString of Basics, so it works in any package, even in the core one.
+const expectModule = `module Expect
+    exposing
+        ( Expectation(..)
+        , pass
+        , fail
+        , onFail
+        , equal
+        , notEqual
+        , lessThan
+        , greaterThan
+        , true
+        , false
+        , all
+        )
+
+{-| The assertions of the tests, which tell why they failed.
+
+A test of type Expectation or List Expectation passes if every
+expectation passes, and every failure is reported with its message. Fuzz
+tests can return an Expectation instead of a Bool.
+
+@docs Expectation, pass, fail, onFail, equal, notEqual, lessThan, greaterThan, true, false, all
+-}
+
+import Basics exposing (..)
+import List exposing ((::))
+
+
+{-| Whether an assertion passed, or the message of why it failed.
+-}
+type Expectation
+    = Pass
+    | Fail String
+
+
+{-| An expectation that always passes.
+-}
+pass : Expectation
+pass =
+    Pass
+
+
+{-| An expectation that always fails with the given message.
+-}
+fail : String -> Expectation
+fail message =
+    Fail message
+
+
+{-| Replaces the message of the expectation if it failed.
+-}
+onFail : String -> Expectation -> Expectation
+onFail message expectation =
+    case expectation of
+        Pass ->
+            Pass
+
+        Fail _ ->
+            Fail message
+
+
+{-| Passes if the actual value, the second one, is equal to the expected
+one.
+-}
+equal : a -> a -> Expectation
+equal expected actual =
+    if expected == actual then
+        Pass
+    else
+        Fail ("Expected " ++ toString expected ++ ", but got " ++ toString actual)
+
+
+{-| Passes if the actual value, the second one, is not equal to the first
+one.
+-}
+notEqual : a -> a -> Expectation
+notEqual unexpected actual =
+    if unexpected == actual then
+        Fail ("Expected anything but " ++ toString unexpected)
+    else
+        Pass
+
+
+{-| Passes if the actual value, the second one, is less than the bound.
+-}
+lessThan : comparable -> comparable -> Expectation
+lessThan bound actual =
+    if actual < bound then
+        Pass
+    else
+        Fail ("Expected less than " ++ toString bound ++ ", but got " ++ toString actual)
+
+
+{-| Passes if the actual value, the second one, is greater than the bound.
+-}
+greaterThan : comparable -> comparable -> Expectation
+greaterThan bound actual =
+    if bound < actual then
+        Pass
+    else
+        Fail ("Expected greater than " ++ toString bound ++ ", but got " ++ toString actual)
+
+
+{-| Passes if the value is True, or fails with the given message.
+-}
+true : String -> Bool -> Expectation
+true message value =
+    if value then
+        Pass
+    else
+        Fail message
+
+
+{-| Passes if the value is False, or fails with the given message.
+-}
+false : String -> Bool -> Expectation
+false message value =
+    if value then
+        Fail message
+    else
+        Pass
+
+
+{-| Passes if all the expectations pass for the subject, or fails with the
+message of the first failure.
+-}
+all : List (subject -> Expectation) -> subject -> Expectation
+all expectations subject =
+    case expectations of
+        [] ->
+            Pass
+
+        expect :: rest ->
+            case expect subject of
+                Pass ->
+                    all rest subject
+
+                failure ->
+                    failure
+`
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/refactor"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/symbol"
+	"github.com/elm-tangram/tangram/token"
+)
+
+const exploreUsage = `Usage: elmo explore [flags] [path]
+
+Explore serves a web page to explore the main module at the given path and
+all the modules it imports, until elmo is interrupted. The path is either
+the file of the module or the directory of a package whose main module is
+Main, and it is the current directory by default.
+
+The page shows the graph of the imports of the modules, the syntax tree of
+every module, and the declaration and the references of every top-level
+name. The data is served as JSON as well:
+
+	/graph                       the modules and their imports
+	/ast?module=Main             the syntax tree of a module
+	/symbol?module=Main&name=x   the description and references of a name
+
+The flags are:
+
+`
+
+func runExplore(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("explore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "localhost:6060", "address where the page is served")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, exploreUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	prog := loadMode(flags.Arg(0), parser.FullParse|parser.ParseComments, out)
+	if prog == nil {
+		return out.failure()
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	url := "http://" + l.Addr().String()
+	out.progress("explore", fields{"url": url}, fmt.Sprintf("exploring %s at %s\n", prog.main, url))
+
+	srv := &http.Server{Handler: newExplorer(prog)}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		srv.Close()
+	}()
+
+	if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	return exitOK
+}
+
+// explorer serves the page and the data of the explore command.
+type explorer struct {
+	prog *program
+	cm   *source.CodeMap
+}
+
+func newExplorer(prog *program) http.Handler {
+	e := &explorer{prog, source.NewCodeMap(source.NewFsLoader(prog.pkg))}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", e.page)
+	mux.HandleFunc("/graph", e.graph)
+	mux.HandleFunc("/ast", e.ast)
+	mux.HandleFunc("/symbol", e.symbol)
+	return mux
+}
+
+// location is a position in the source code of a module.
+type location struct {
+	Module string `json:"module"`
+	Line   int    `json:"line"`
+	Col    int    `json:"col"`
+}
+
+func (e *explorer) location(mod *ast.Module, pos token.Pos) location {
+	var loc = location{Module: mod.Name}
+	if err := e.cm.Add(mod.Path); err != nil {
+		return loc
+	}
+
+	if lp, err := e.cm.Source(mod.Path).LinePos(pos); err == nil {
+		loc.Line, loc.Col = lp.Line, lp.Col
+	}
+	return loc
+}
+
+func (e *explorer) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, explorePage)
+}
+
+// exploreModule is a module of the graph of the explore command.
+type exploreModule struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Imports []string `json:"imports"`
+}
+
+func (e *explorer) graph(w http.ResponseWriter, r *http.Request) {
+	var modules = []exploreModule{}
+	for _, name := range e.prog.ast.Order {
+		mod := e.prog.ast.Modules[name]
+		var imports = []string{}
+		for _, imp := range mod.Imports {
+			imports = append(imports, imp.ModuleName())
+		}
+		sort.Strings(imports)
+		modules = append(modules, exploreModule{name, mod.Path, imports})
+	}
+	writeJSON(w, fields{"main": e.prog.main, "modules": modules})
+}
+
+// astNode is a node of the syntax tree of a module.
+type astNode struct {
+	Type     string     `json:"type"`
+	Name     string     `json:"name,omitempty"`
+	Pos      location   `json:"pos"`
+	Children []*astNode `json:"children,omitempty"`
+}
+
+func (e *explorer) ast(w http.ResponseWriter, r *http.Request) {
+	mod := e.module(w, r)
+	if mod == nil {
+		return
+	}
+
+	var root = &astNode{Children: []*astNode{}}
+	var stack = []*astNode{root}
+	ast.WalkFunc(mod, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+
+		node := &astNode{
+			Type: strings.TrimPrefix(reflect.TypeOf(n).String(), "*ast."),
+			Pos:  e.location(mod, n.Pos()),
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			node.Name = id.Name
+		}
+
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, node)
+		return true
+	})
+	writeJSON(w, root.Children[0])
+}
+
+func (e *explorer) symbol(w http.ResponseWriter, r *http.Request) {
+	mod := e.module(w, r)
+	if mod == nil {
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	obj := refactor.Lookup(e.prog.ast, mod.Name, name)
+	if obj == nil {
+		http.Error(w, fmt.Sprintf("there is no declaration %s.%s", mod.Name, name), http.StatusNotFound)
+		return
+	}
+
+	var desc string
+	if d := symbol.DescribeName(e.prog.ast, e.prog.info, mod.Name, name); d != nil {
+		desc = d.Markdown()
+	}
+
+	def := symbol.NameOf(obj.Node)
+	var refs = []location{}
+	for _, n := range e.prog.ast.Order {
+		m := e.prog.ast.Modules[n]
+		ast.WalkFunc(m, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Obj == obj && id != def && id.NamePos != token.NoPos {
+				refs = append(refs, e.location(m, id.Pos()))
+			}
+			return n != nil
+		})
+	}
+
+	var declared = location{Module: mod.Name}
+	if def != nil {
+		declared = e.location(mod, def.Pos())
+	}
+	writeJSON(w, fields{"description": desc, "declaration": declared, "references": refs})
+}
+
+// module returns the module of the request, or writes an error and
+// returns nil if there is no such module.
+func (e *explorer) module(w http.ResponseWriter, r *http.Request) *ast.Module {
+	name := r.URL.Query().Get("module")
+	mod := e.prog.ast.Modules[name]
+	if mod == nil {
+		http.Error(w, fmt.Sprintf("there is no module %q", name), http.StatusNotFound)
+	}
+	return mod
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// explorePage is the page of the explore command, which gets its data from
+// the JSON endpoints.
+const explorePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>elmo explore</title>
+<style>
+body { font-family: sans-serif; display: flex; margin: 0; height: 100vh; }
+nav { width: 16em; overflow: auto; border-right: 1px solid #ccc; padding: 1em; }
+main { flex: 1; overflow: auto; padding: 1em; }
+a { color: #1565c0; cursor: pointer; }
+ul.tree { list-style: none; padding-left: 1.2em; }
+.pos { color: #888; font-size: small; }
+pre { background: #f5f5f5; padding: 1em; }
+</style>
+</head>
+<body>
+<nav><h3>Modules</h3><ul id="modules"></ul></nav>
+<main id="main"></main>
+<script>
+function get(url) { return fetch(url).then(function (r) { return r.json(); }); }
+function el(tag, text) { var e = document.createElement(tag); if (text) e.textContent = text; return e; }
+function link(text, fn) { var a = el("a", text); a.onclick = fn; return a; }
+
+function tree(node, module) {
+	var li = el("li");
+	li.appendChild(el("span", node.type + (node.name ? " " + node.name : "") + " "));
+	li.appendChild(el("span", node.pos.line + ":" + node.pos.col)).className = "pos";
+	if (node.type === "Ident" && node.name) {
+		li.appendChild(el("span", " "));
+		li.appendChild(link("references", function () { showSymbol(module, node.name); }));
+	}
+	if (node.children) {
+		var ul = el("ul"); ul.className = "tree";
+		node.children.forEach(function (c) { ul.appendChild(tree(c, module)); });
+		li.appendChild(ul);
+	}
+	return li;
+}
+
+function showModule(m) {
+	var main = document.getElementById("main");
+	main.innerHTML = "";
+	main.appendChild(el("h2", m.name));
+	main.appendChild(el("p", m.path));
+	var imports = el("p", "Imports: ");
+	m.imports.forEach(function (i) { imports.appendChild(link(i + " ", function () { showModule(modules[i]); })); });
+	main.appendChild(imports);
+	var importers = el("p", "Imported by: ");
+	Object.keys(modules).forEach(function (n) {
+		if (modules[n].imports.indexOf(m.name) >= 0) importers.appendChild(link(n + " ", function () { showModule(modules[n]); }));
+	});
+	main.appendChild(importers);
+	get("/ast?module=" + encodeURIComponent(m.name)).then(function (root) {
+		var ul = el("ul"); ul.className = "tree";
+		ul.appendChild(tree(root, m.name));
+		main.appendChild(el("h3", "Syntax tree"));
+		main.appendChild(ul);
+	});
+}
+
+function showSymbol(module, name) {
+	fetch("/symbol?module=" + encodeURIComponent(module) + "&name=" + encodeURIComponent(name)).then(function (r) {
+		if (!r.ok) return;
+		r.json().then(function (s) {
+			var main = document.getElementById("main");
+			main.innerHTML = "";
+			main.appendChild(el("h2", module + "." + name));
+			main.appendChild(el("pre", s.description));
+			main.appendChild(el("p", "Declared in " + s.declaration.module + " at " + s.declaration.line + ":" + s.declaration.col));
+			var ul = el("ul");
+			s.references.forEach(function (l) { ul.appendChild(el("li", l.module + " " + l.line + ":" + l.col)); });
+			main.appendChild(el("h3", "References"));
+			main.appendChild(ul);
+		});
+	});
+}
+
+var modules = {};
+get("/graph").then(function (g) {
+	var list = document.getElementById("modules");
+	g.modules.forEach(function (m) {
+		modules[m.name] = m;
+		var li = el("li");
+		li.appendChild(link(m.name, function () { showModule(m); }));
+		list.appendChild(li);
+	});
+	showModule(modules[g.main]);
+});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func explore(t *testing.T, url string) (int, map[string]interface{}) {
+	var out bytes.Buffer
+	o, _ := newOutput(&outputOptions{report: "text"}, &out, &out)
+	prog := loadMode(fixture, parser.FullParse|parser.ParseComments, o)
+	require.NotNil(t, prog, out.String())
+
+	rec := httptest.NewRecorder()
+	newExplorer(prog).ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		return rec.Code, map[string]interface{}{"body": rec.Body.String()}
+	}
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+	return rec.Code, data
+}
+
+func TestExplore(t *testing.T) {
+	require := require.New(t)
+
+	code, data := explore(t, "/")
+	require.Equal(http.StatusOK, code)
+	require.Contains(data["body"], "<title>elmo explore</title>")
+
+	code, data = explore(t, "/graph")
+	require.Equal(http.StatusOK, code)
+	require.Equal("Main", data["main"])
+	modules := data["modules"].([]interface{})
+	main := modules[len(modules)-1].(map[string]interface{})
+	require.Equal("Main", main["name"])
+	require.Equal([]interface{}{"Basics", "Platform", "Platform.Cmd", "Platform.Sub"}, main["imports"])
+
+	code, data = explore(t, "/ast?module=Main")
+	require.Equal(http.StatusOK, code)
+	require.Equal("Module", data["type"])
+	require.Equal(map[string]interface{}{"module": "Main", "line": float64(1), "col": float64(1)}, data["pos"])
+	require.NotEmpty(data["children"])
+
+	code, data = explore(t, "/symbol?module=Main&name=update")
+	require.Equal(http.StatusOK, code)
+	require.Contains(data["description"], "update : Main.Msg -> Int -> (Int, Platform.Cmd.Cmd Main.Msg)")
+	require.Equal(map[string]interface{}{"module": "Main", "line": float64(20), "col": float64(1)}, data["declaration"])
+	require.Len(data["references"], 1)
+
+	code, data = explore(t, "/ast?module=Nope")
+	require.Equal(http.StatusNotFound, code)
+	require.Contains(data["body"], `there is no module "Nope"`)
+
+	code, _ = explore(t, "/symbol?module=Main&name=nope")
+	require.Equal(http.StatusNotFound, code)
+
+	code, _ = explore(t, "/nope")
+	require.Equal(http.StatusNotFound, code)
+}
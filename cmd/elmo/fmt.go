@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elm-tangram/tangram/format"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/refactor"
+)
+
+const fmtUsage = `Usage: elmo fmt [flags] [path ...]
+
+Fmt formats the Elm files at the given paths, which are either files or
+directories whose .elm files are formatted recursively, except the ones in
+elm-stuff. By default, the formatted files are written to the standard
+output. Without paths, the standard input is formatted.
+
+With --imports, the imports of the modules are organized as well: they are
+sorted by module, the imports of the same module are merged, and the ones
+that are not used are removed, as well as the exposed names that are not
+used. The files must be in a package, whose modules are parsed to know
+which imports are used. The imports are organized by default if the elmo.toml
+or .elmorc project configuration of the current directory has:
+
+	[format]
+	imports = true
+
+The flags are:
+
+`
+
+// fmtOptions are the flags of the fmt command.
+type fmtOptions struct {
+	write   bool
+	check   bool
+	imports bool
+}
+
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	var opts fmtOptions
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&opts.write, "write", false, "write the formatted code to the files instead of the standard output")
+	flags.BoolVar(&opts.check, "check", false, "list the files that are not formatted, and fail if there are any")
+	flags.BoolVar(&opts.imports, "imports", false, "organize the imports as well: sort and merge them, and remove the unused ones")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, fmtUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	// --imports is enabled by default by the project configuration
+	if imports := out.config.Format.Imports; imports != nil && !isSet(flags, "imports") {
+		opts.imports = *imports
+	}
+
+	if opts.write && opts.check {
+		out.errorf("--write and --check cannot be used together")
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if opts.write {
+			out.errorf("--write cannot be used with the standard input")
+			return exitUsage
+		}
+
+		if opts.imports {
+			out.errorf("--imports cannot be used with the standard input")
+			return exitUsage
+		}
+		return fmtInput("<stdin>", stdin, &opts, out)
+	}
+
+	files, err := elmFiles(flags.Args())
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	code = exitOK
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			out.errorf("%s", err)
+			code = exitInternal
+			continue
+		}
+
+		if c := fmtInput(path, f, &opts, out); c > code {
+			code = c
+		}
+		f.Close()
+	}
+	return code
+}
+
+// fmtInput formats the code read from the given input, named after path,
+// and returns the exit code.
+func fmtInput(path string, r io.Reader, opts *fmtOptions, out *output) int {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	input := src
+	if opts.imports {
+		var code int
+		if input, code = organizeImports(path, src, out); code != exitOK {
+			return code
+		}
+	}
+
+	formatted, err := format.File(path, input)
+	if err != nil {
+		out.problem(err)
+		return exitProblems
+	}
+
+	switch {
+	case opts.check:
+		if !bytes.Equal(src, formatted) {
+			out.event("unformatted", fields{"file": path}, path+"\n")
+			return exitProblems
+		}
+	case opts.write:
+		if bytes.Equal(src, formatted) {
+			return exitOK
+		}
+
+		if err := ioutil.WriteFile(path, formatted, 0644); err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+		out.event("formatted", fields{"file": path}, "")
+	default:
+		out.event("formatted", fields{"file": path, "code": string(formatted)}, string(formatted))
+	}
+	return exitOK
+}
+
+// organizeImports returns the source of the module in the file at the given
+// path with its imports organized, and the exit code. The module is parsed
+// and resolved with the package it belongs to, to know which imports are
+// used.
+func organizeImports(path string, src []byte, out *output) ([]byte, int) {
+	tree, err := parser.ParseWith(path, parser.FullParse|parser.ParseComments, out.emitter(), nil)
+	if err == parser.ErrProblems {
+		return nil, exitProblems
+	} else if err != nil {
+		out.errorf("%s", err)
+		return nil, exitInternal
+	}
+
+	for _, mod := range tree.Modules {
+		if absPath(mod.Path) != absPath(path) {
+			continue
+		}
+
+		edits, err := refactor.OrganizeImports(mod)
+		if err != nil {
+			out.errorf("%s", err)
+			return nil, exitInternal
+		}
+
+		if src, err = refactor.Apply(src, edits); err != nil {
+			out.errorf("%s", err)
+			return nil, exitInternal
+		}
+	}
+	return src, exitOK
+}
+
+// elmFiles returns the Elm files at the given paths, looking for them
+// recursively in the directories.
+func elmFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() && info.Name() == "elm-stuff" {
+				return filepath.SkipDir
+			}
+
+			if !info.IsDir() && filepath.Ext(path) == ".elm" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
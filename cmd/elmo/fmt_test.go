@@ -0,0 +1,161 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const unformatted = `module Main exposing (..)
+answer = 42
+`
+
+const formatted = `module Main exposing (..)
+
+
+answer =
+    42
+`
+
+func TestFmt(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Main.elm")
+	require.NoError(ioutil.WriteFile(path, []byte(unformatted), 0644))
+	stuff := filepath.Join(dir, "elm-stuff")
+	require.NoError(os.Mkdir(stuff, 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(stuff, "Dep.elm"), []byte(unformatted), 0644))
+
+	code, out := runElmo(t, "fmt", path)
+	require.Equal(0, code, out)
+	require.Equal(formatted, out)
+
+	code, out = runElmo(t, "fmt", "--check", dir)
+	require.Equal(1, code)
+	require.Equal(path+"\n", out)
+
+	code, out = runElmo(t, "fmt", "--write", dir)
+	require.Equal(0, code, out)
+	require.Equal(formatted, readFile(t, path))
+	require.Equal(unformatted, readFile(t, filepath.Join(stuff, "Dep.elm")))
+
+	code, out = runElmo(t, "fmt", "--check", dir)
+	require.Equal(0, code, out)
+	require.Equal("", out)
+
+	code, out = runElmo(t, "fmt", "--check", fixture)
+	require.Equal(0, code, out)
+}
+
+func TestFmtStdin(t *testing.T) {
+	require := require.New(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(unformatted)
+
+	code, out := runElmo(t, "fmt")
+	require.Equal(0, code, out)
+	require.Equal(formatted, out)
+}
+
+func TestFmtJSON(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Main.elm")
+	require.NoError(ioutil.WriteFile(path, []byte(unformatted), 0644))
+
+	code, out := runElmo(t, "fmt", "--report=json", path)
+	require.Equal(0, code, out)
+	require.Equal([]fields{{"event": "formatted", "file": path, "code": formatted}}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "fmt", "--report=json", "--check", dir)
+	require.Equal(1, code)
+	require.Equal([]fields{{"event": "unformatted", "file": path}}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "fmt", "--report=json", "--write", dir)
+	require.Equal(0, code, out)
+	require.Equal([]fields{{"event": "formatted", "file": path}}, jsonEvents(t, out))
+}
+
+func TestFmtImports(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, linted, dir)
+
+	path := filepath.Join(dir, "src", "Main.elm")
+	code, out := runElmo(t, "fmt", "--imports", path)
+	require.Equal(0, code, out)
+	require.Contains(out, "import Util exposing (identity)\n\n\nview")
+	require.NotContains(out, "import Extra")
+
+	code, out = runElmo(t, "fmt", "--check", "--imports", path)
+	require.Equal(1, code, out)
+
+	code, out = runElmo(t, "fmt", "--write", "--imports", path)
+	require.Equal(0, code, out)
+	require.NotContains(readFile(t, path), "import Extra")
+
+	code, out = runElmo(t, "fmt", "--check", "--imports", path)
+	require.Equal(0, code, out)
+
+	code, out = runElmo(t, "fmt", "--imports")
+	require.Equal(exitUsage, code)
+	require.Contains(out, "--imports cannot be used with the standard input")
+}
+
+func TestFmtConfig(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, linted, dir)
+
+	wd, err := os.Getwd()
+	require.NoError(err)
+	require.NoError(os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	require.NoError(ioutil.WriteFile("elmo.toml", []byte("[format]\nimports = true\n"), 0644))
+	path := filepath.Join(dir, "src", "Main.elm")
+	code, out := runElmo(t, "fmt", path)
+	require.Equal(0, code, out)
+	require.NotContains(out, "import Extra")
+
+	code, out = runElmo(t, "fmt", "--imports=false", path)
+	require.Equal(0, code, out)
+	require.Contains(out, "import Extra")
+
+	require.NoError(ioutil.WriteFile(".elmorc", []byte(`{"format": {"imports": "yes"}}`), 0644))
+	code, out = runElmo(t, "fmt", path)
+	require.Equal(exitInternal, code)
+	require.Contains(out, "elmo: "+filepath.Join(dir, ".elmorc")+": config: can't decode configuration")
+}
+
+func TestFmtErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "fmt", "_testdata/broken/src/Syntax.elm")
+	require.Equal(1, code)
+	require.Contains(out, "syntax error: Unexpected end of file.")
+	require.Contains(out, "at _testdata/broken/src/Syntax.elm:5:8")
+
+	code, out = runElmo(t, "fmt", "--write", "--check", fixture)
+	require.Equal(2, code)
+	require.Equal("elmo: --write and --check cannot be used together\n", out)
+
+	code, out = runElmo(t, "fmt", "--write")
+	require.Equal(2, code)
+	require.Equal("elmo: --write cannot be used with the standard input\n", out)
+
+	code, out = runElmo(t, "fmt", "_testdata/missing")
+	require.Equal(3, code)
+	require.Contains(out, "elmo: ")
+}
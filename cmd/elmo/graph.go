@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+)
+
+const graphUsage = `Usage: elmo graph [flags] [path]
+
+Graph prints the dependency graph of the main module at the given path and
+all the modules it imports. The path is either the file of the module or the
+directory of a package whose main module is Main, and it is the current
+directory by default.
+
+The graph is printed in the DOT format of Graphviz, as JSON or as a mermaid
+flowchart. The modules can be collapsed into the packages they belong to,
+and the modules that depend on each other can be highlighted. The circular
+dependencies are always listed in the JSON format. With --report=json, the
+graph is the "graph" field of a graph event.
+
+The flags are:
+
+`
+
+// graphOptions are the flags of the graph command.
+type graphOptions struct {
+	format   string
+	collapse bool
+	cycles   bool
+}
+
+// graphFormats are the functions that print a graph in each format.
+var graphFormats = map[string]func(io.Writer, *depGraph, bool) error{
+	"dot":     writeDot,
+	"json":    writeGraphJSON,
+	"mermaid": writeMermaid,
+}
+
+func runGraph(args []string, stdout, stderr io.Writer) int {
+	var opts graphOptions
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.format, "format", "dot", "format of the graph: dot, json or mermaid")
+	flags.BoolVar(&opts.collapse, "collapse", false, "collapse the modules into their packages")
+	flags.BoolVar(&opts.cycles, "cycles", false, "highlight the circular dependencies")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, graphUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	write, ok := graphFormats[opts.format]
+	if !ok {
+		out.errorf("unknown graph format %q", opts.format)
+		return exitUsage
+	}
+
+	g, ok := moduleGraph(flags.Arg(0), out)
+	if !ok {
+		return out.failure()
+	}
+
+	if opts.collapse {
+		g = g.collapse()
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, g, opts.cycles); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var graph interface{} = buf.String()
+	if opts.format == "json" {
+		graph = json.RawMessage(buf.Bytes())
+	}
+	out.event("graph", fields{"format": opts.format, "graph": graph}, buf.String())
+	return exitOK
+}
+
+// depGraph is a dependency graph whose nodes are either modules or
+// packages.
+type depGraph struct {
+	*pkg.Graph
+	// kind is what the nodes are: modules or packages.
+	kind string
+	// packages are the packages of the modules by their name, if the
+	// nodes are modules.
+	packages map[string]string
+}
+
+// moduleGraph returns the dependency graph of the main module at the given
+// path. The diagnostics are written to out, and it reports false if there
+// are any errors.
+func moduleGraph(path string, out *output) (*depGraph, bool) {
+	path, err := mainPath(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return nil, false
+	}
+
+	p, err := pkg.Load(filepath.Dir(path))
+	if err != nil {
+		out.errorf("%s", err)
+		return nil, false
+	}
+
+	g, err := parser.GraphWith(path, out.emitter())
+	if err == parser.ErrProblems {
+		return nil, false
+	} else if err != nil {
+		out.errorf("%s", err)
+		return nil, false
+	}
+
+	packages := make(map[string]string)
+	for _, m := range g.Modules() {
+		file := path
+		if m != g.Root() {
+			if file, err = p.FindModule(m); err != nil {
+				out.errorf("can't find module %s: %s", m, err)
+				return nil, false
+			}
+		}
+		packages[m] = modulePackage(p, file)
+	}
+
+	return &depGraph{g, "modules", packages}, true
+}
+
+// modulePackage returns the name of the package the module in the given
+// file belongs to, which is either the package p or one of its
+// dependencies.
+func modulePackage(p *pkg.Package, file string) string {
+	deps := filepath.Join(p.Root(), "elm-stuff", "packages")
+	if rel, err := filepath.Rel(deps, file); err == nil && !strings.HasPrefix(rel, "..") {
+		if parts := strings.Split(filepath.ToSlash(rel), "/"); len(parts) > 2 {
+			return parts[0] + "/" + parts[1]
+		}
+	}
+	return packageName(p)
+}
+
+// collapse returns the graph of the packages of the modules, in which a
+// package depends on another if any of its modules imports one of the
+// other.
+func (g *depGraph) collapse() *depGraph {
+	c := pkg.NewGraph(g.packages[g.Root()])
+	for _, m := range g.Modules() {
+		for _, imp := range g.Imports(m) {
+			if from, to := g.packages[m], g.packages[imp]; from != to {
+				c.Add(to, from)
+			}
+		}
+	}
+	return &depGraph{Graph: c, kind: "packages"}
+}
+
+// cycleOf returns the index of the cycle each node belongs to, starting at
+// one. The nodes that are not in any cycle are not in the result.
+func (g *depGraph) cycleOf() map[string]int {
+	var cycles = make(map[string]int)
+	for i, c := range g.Cycles() {
+		for _, n := range c {
+			cycles[n] = i + 1
+		}
+	}
+	return cycles
+}
+
+// inCycle reports whether the edge between the given nodes is part of a
+// cycle.
+func inCycle(cycles map[string]int, from, to string) bool {
+	return cycles[from] != 0 && cycles[from] == cycles[to]
+}
+
+func writeDot(w io.Writer, g *depGraph, highlight bool) error {
+	cycles := g.cycleOf()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %s {\n", g.kind)
+	for _, n := range g.Modules() {
+		if highlight && cycles[n] != 0 {
+			fmt.Fprintf(&buf, "\t%q [color=red];\n", n)
+		} else {
+			fmt.Fprintf(&buf, "\t%q;\n", n)
+		}
+	}
+
+	for _, n := range g.Modules() {
+		for _, imp := range g.Imports(n) {
+			if highlight && inCycle(cycles, n, imp) {
+				fmt.Fprintf(&buf, "\t%q -> %q [color=red];\n", n, imp)
+			} else {
+				fmt.Fprintf(&buf, "\t%q -> %q;\n", n, imp)
+			}
+		}
+	}
+
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeMermaid(w io.Writer, g *depGraph, highlight bool) error {
+	cycles := g.cycleOf()
+	var buf bytes.Buffer
+	var ids = make(map[string]string)
+	var inCycles []string
+	buf.WriteString("graph LR\n")
+	for i, n := range g.Modules() {
+		ids[n] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&buf, "\t%s[%q]\n", ids[n], n)
+		if cycles[n] != 0 {
+			inCycles = append(inCycles, ids[n])
+		}
+	}
+
+	// the links are styled by their index, in the order they are written
+	var links []string
+	var link int
+	for _, n := range g.Modules() {
+		for _, imp := range g.Imports(n) {
+			if inCycle(cycles, n, imp) {
+				links = append(links, fmt.Sprint(link))
+			}
+			fmt.Fprintf(&buf, "\t%s --> %s\n", ids[n], ids[imp])
+			link++
+		}
+	}
+
+	if highlight && len(inCycles) > 0 {
+		buf.WriteString("\tclassDef cycle stroke:red\n")
+		fmt.Fprintf(&buf, "\tclass %s cycle\n", strings.Join(inCycles, ","))
+		fmt.Fprintf(&buf, "\tlinkStyle %s stroke:red\n", strings.Join(links, ","))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// graphJSON is the JSON encoding of a dependency graph.
+type graphJSON struct {
+	Root   string     `json:"root"`
+	Nodes  []nodeJSON `json:"nodes"`
+	Cycles [][]string `json:"cycles"`
+}
+
+// nodeJSON is the JSON encoding of a node of a dependency graph.
+type nodeJSON struct {
+	Name    string   `json:"name"`
+	Package string   `json:"package,omitempty"`
+	Imports []string `json:"imports"`
+}
+
+func writeGraphJSON(w io.Writer, g *depGraph, _ bool) error {
+	data := graphJSON{Root: g.Root(), Nodes: []nodeJSON{}, Cycles: g.Cycles()}
+	if data.Cycles == nil {
+		data.Cycles = [][]string{}
+	}
+
+	for _, n := range g.Modules() {
+		imports := g.Imports(n)
+		if imports == nil {
+			imports = []string{}
+		}
+		data.Nodes = append(data.Nodes, nodeJSON{Name: n, Package: g.packages[n], Imports: imports})
+	}
+
+	content, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
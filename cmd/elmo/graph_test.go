@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const cyclic = "_testdata/cyclic"
+
+func TestGraph(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "graph", fixture)
+	require.Equal(0, code, out)
+	require.Equal(`digraph modules {
+	"Basics";
+	"Main";
+	"Platform";
+	"Platform.Cmd";
+	"Platform.Sub";
+	"Main" -> "Basics";
+	"Main" -> "Platform";
+	"Main" -> "Platform.Cmd";
+	"Main" -> "Platform.Sub";
+	"Platform" -> "Basics";
+	"Platform" -> "Platform.Cmd";
+	"Platform" -> "Platform.Sub";
+}
+`, out)
+}
+
+func TestGraphCycles(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "graph", "--cycles", cyclic)
+	require.Equal(0, code, out)
+	require.Contains(out, "\t\"Main\";\n")
+	require.Contains(out, "\t\"Model\" [color=red];\n")
+	require.Contains(out, "\t\"Model\" -> \"Update\" [color=red];\n")
+	require.Contains(out, "\t\"Update\" -> \"Model\" [color=red];\n")
+	require.Contains(out, "\t\"View\" -> \"Model\";\n")
+	require.Contains(out, "\t\"Main\" -> \"Basics\";\n")
+
+	code, out = runElmo(t, "graph", "--format", "mermaid", "--cycles", cyclic)
+	require.Equal(0, code, out)
+	require.Contains(out, "graph LR\n\tn0[\"Basics\"]\n")
+	require.Contains(out, "\tn5 --> n9\n")
+	require.Contains(out, "\tclass n5,n9 cycle\n")
+	require.Contains(out, "\tlinkStyle 16,24 stroke:red\n")
+}
+
+func TestGraphJSON(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "graph", "--format", "json", cyclic)
+	require.Equal(0, code, out)
+
+	var g graphJSON
+	require.NoError(json.Unmarshal([]byte(out), &g))
+	require.Equal("Main", g.Root)
+	require.Equal([][]string{{"Model", "Update"}}, g.Cycles)
+	require.Len(g.Nodes, 11)
+	require.Equal(nodeJSON{"Basics", "elm-lang/core", []string{}}, g.Nodes[0])
+	require.Equal("elmo/cyclic", g.Nodes[5].Package)
+	require.Equal("Model", g.Nodes[5].Name)
+	require.Contains(g.Nodes[5].Imports, "Update")
+}
+
+func TestGraphCollapse(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "graph", "--collapse", cyclic)
+	require.Equal(0, code, out)
+	require.Equal(`digraph packages {
+	"elm-lang/core";
+	"elmo/cyclic";
+	"elmo/cyclic" -> "elm-lang/core";
+}
+`, out)
+}
+
+func TestGraphErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "graph", "--format", "svg", fixture)
+	require.Equal(2, code)
+	require.Equal("elmo: unknown graph format \"svg\"\n", out)
+
+	code, out = runElmo(t, "graph", fixture, "other")
+	require.Equal(2, code)
+	require.Contains(out, "Usage: elmo graph")
+
+	code, out = runElmo(t, "graph", "_testdata/missing")
+	require.Equal(3, code)
+	require.Contains(out, "elmo: ")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+)
+
+const installUsage = `Usage: elmo install [flags] [package]
+
+Install adds the given package, whose name has the form user/project, to the
+dependencies of the package in the current directory, with the newest
+version that is compatible with the rest of them. Then the exact versions of
+all the dependencies are resolved and saved in
+elm-stuff/exact-dependencies.json, and the ones that are not installed yet
+are downloaded into elm-stuff/packages.
+
+Without a package, it just installs the dependencies, keeping the exact
+versions that are already saved if they are still valid.
+
+The flags are:
+
+`
+
+// archives is the URL the archives of the packages are downloaded from,
+// which is replaced in the tests.
+var archives = pkg.DefaultArchives
+
+// installOptions are the flags of the install command.
+type installOptions struct {
+	dir      string
+	registry string
+}
+
+func runInstall(args []string, stdout, stderr io.Writer) int {
+	var opts installOptions
+	flags := flag.NewFlagSet("install", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.dir, "dir", ".", "directory of the package")
+	flags.StringVar(&opts.registry, "registry", pkg.DefaultRegistry, "URL of the package registry")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, installUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	name := flags.Arg(0)
+	if name != "" && !isPackageName(name) {
+		out.errorf("%q is not a package name of the form user/project", name)
+		return exitUsage
+	}
+
+	p, err := pkg.Load(opts.dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	r := pkg.NewRegistry(opts.registry, archives)
+	if name != "" {
+		v, err := p.AddDependency(r, name)
+		if err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+
+		if err := p.Save(); err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+		vr := v.UntilNextMajor()
+		out.progress("added", fields{"package": name, "range": vr}, fmt.Sprintf("added %s %s to the dependencies\n", name, vr))
+	}
+
+	downloaded, err := p.Install(r)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if len(downloaded) == 0 {
+		out.note("the dependencies are up to date\n")
+	}
+
+	for _, dep := range downloaded {
+		v := p.ExactDependencies[dep]
+		out.progress("installed", fields{"package": dep, "version": v}, fmt.Sprintf("installed %s %s\n", dep, v))
+	}
+	return exitOK
+}
+
+// isPackageName reports whether the name has the form user/project.
+func isPackageName(name string) bool {
+	parts := strings.Split(name, "/")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
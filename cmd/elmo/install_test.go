@@ -0,0 +1,121 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const emptyManifest = `{
+    "version": "1.0.0",
+    "summary": "elmo test package",
+    "repository": "https://github.com/elmo/app.git",
+    "license": "BSD3",
+    "source-directories": [
+        "src"
+    ],
+    "exposed-modules": [],
+    "dependencies": {},
+    "elm-version": "0.18.0 <= v < 0.19.0"
+}
+`
+
+// newRegistryServer returns a server of a registry with the versions 1.0.0
+// and 1.1.0 of the package elmo/greeting, whose version 1.1.0 has an
+// advisory.
+func newRegistryServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/all-packages", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name": "elmo/greeting", "versions": ["1.0.0", "1.1.0"]}]`))
+	})
+
+	mux.HandleFunc("/description", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"version":            r.FormValue("version"),
+			"source-directories": []string{"src"},
+			"dependencies":       map[string]string{},
+		})
+	})
+
+	mux.HandleFunc("/advisories", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"advisories": [{"id": "ELM-7", "package": "elmo/greeting", "versions": "1.1.0 <= v < 1.2.0", "severity": "high", "summary": "Greets strangers"}],
+			"unmaintained": {"elmo/greeting": "use elmo/hello instead"}
+		}`))
+	})
+
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		v := v
+		mux.HandleFunc("/elmo/greeting/zipball/"+v+"/", func(w http.ResponseWriter, r *http.Request) {
+			zw := zip.NewWriter(w)
+			f, err := zw.Create("elmo-greeting-abcdef/src/Greeting.elm")
+			require.NoError(t, err)
+			f.Write([]byte("module Greeting exposing (..)\n\nversion =\n    \"" + v + "\"\n"))
+			require.NoError(t, zw.Close())
+		})
+	}
+
+	return httptest.NewServer(mux)
+}
+
+func TestInstall(t *testing.T) {
+	srv := newRegistryServer(t)
+	defer srv.Close()
+	defer func(url string) { archives = url }(archives)
+	archives = srv.URL
+
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elm-package.json"), []byte(emptyManifest), 0644))
+
+	code, out := runElmo(t, "install", "--dir", dir, "--registry", srv.URL, "elmo/greeting")
+	require.Equal(0, code, out)
+	require.Equal("added elmo/greeting 1.1.0 <= v < 2.0.0 to the dependencies\ninstalled elmo/greeting 1.1.0\n", out)
+
+	require.Contains(readFile(t, filepath.Join(dir, "elm-package.json")), `"elmo/greeting": "1.1.0 <= v < 2.0.0"`)
+	require.Equal("{\n    \"elmo/greeting\": \"1.1.0\"\n}\n", readFile(t, filepath.Join(dir, "elm-stuff", "exact-dependencies.json")))
+	module := filepath.Join(dir, "elm-stuff", "packages", "elmo", "greeting", "1.1.0", "src", "Greeting.elm")
+	require.Contains(readFile(t, module), `"1.1.0"`)
+
+	code, out = runElmo(t, "install", "--dir", dir, "--registry", srv.URL)
+	require.Equal(0, code, out)
+	require.Equal("the dependencies are up to date\n", out)
+
+	require.NoError(os.RemoveAll(filepath.Join(dir, "elm-stuff", "packages")))
+	code, out = runElmo(t, "install", "--dir", dir, "--registry", srv.URL)
+	require.Equal(0, code, out)
+	require.Equal("installed elmo/greeting 1.1.0\n", out)
+	require.Contains(readFile(t, module), `"1.1.0"`)
+}
+
+func TestInstallErrors(t *testing.T) {
+	srv := newRegistryServer(t)
+	defer srv.Close()
+	defer func(url string) { archives = url }(archives)
+	archives = srv.URL
+
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elm-package.json"), []byte(emptyManifest), 0644))
+
+	code, out := runElmo(t, "install", "--dir", dir, "greeting")
+	require.Equal(2, code)
+	require.Equal("elmo: \"greeting\" is not a package name of the form user/project\n", out)
+
+	code, out = runElmo(t, "install", "--dir", dir, "--registry", srv.URL, "elmo/unknown")
+	require.Equal(3, code)
+	require.Equal("elmo: pkg: there is no package elmo/unknown in the registry\n", out)
+
+	code, out = runElmo(t, "install", "--dir", dir, "a/b", "c/d")
+	require.Equal(2, code)
+	require.Contains(out, "Usage: elmo install")
+}
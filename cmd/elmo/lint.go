@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/lint"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+const lintUsage = `Usage: elmo lint [flags] [path]
+
+Lint reports the code of a package that is valid but is likely to be a
+mistake or to be hard to maintain. The path is either the file of a module,
+which is linted with the modules of the package it imports, or the directory
+of a package, whose modules in the source directories are all linted. It is
+the current directory by default.
+
+The problems are reported as warnings followed by the name of the rule that
+found them, and the exit code is not zero if there are any. The rules are
+configured in the elmo-lint.json file of the package, such as:
+
+	{
+	    "rules": {
+	        "todo": {"disabled": true},
+	        "long-case": {"max": 20},
+	        "exposing-all": {"enabled": true}
+	    }
+	}
+
+The opt-in rules are only run if they are enabled.
+
+The rules are:
+
+%s
+The flags are:
+
+`
+
+// lintOptions are the flags of the lint command.
+type lintOptions struct {
+	config string
+}
+
+func runLint(args []string, stdout, stderr io.Writer) int {
+	var opts lintOptions
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.config, "config", "", "path of the configuration file (default elmo-lint.json in the package)")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, lintUsage, ruleList())
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	path := flags.Arg(0)
+	if path == "" {
+		path = "."
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	dir := path
+	if !fi.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	p, err := pkg.Load(dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	conf, err := lintConfig(p, opts.config)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	l, err := lint.New(conf)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var files = []string{path}
+	if fi.IsDir() {
+		var dirs []string
+		for _, dir := range p.SourceDirectories {
+			dirs = append(dirs, filepath.Join(p.Root(), dir))
+		}
+
+		if files, err = elmFiles(dirs); err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+	}
+
+	issues, ok := lintFiles(p, l, files, out)
+	if !ok {
+		return out.failure()
+	}
+
+	if !reportIssues(p, issues, out) {
+		return exitInternal
+	}
+
+	out.event("lint", fields{"issues": len(issues)}, "")
+	if len(issues) > 0 {
+		return exitProblems
+	}
+	return exitOK
+}
+
+// ruleList returns the names of the built-in rules and what they report,
+// one per line.
+func ruleList() string {
+	var buf bytes.Buffer
+	for _, r := range lint.Rules {
+		fmt.Fprintf(&buf, "\t%s\n\t\t%s", r.Name, r.Doc)
+		if r.Max > 0 {
+			fmt.Fprintf(&buf, " (default max %d)", r.Max)
+		}
+		if r.OptIn {
+			buf.WriteString(" (opt-in)")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// lintConfig loads the configuration of the rules from the given file, or
+// from the configuration file of the package if there is none.
+func lintConfig(p *pkg.Package, path string) (*lint.Config, error) {
+	if path == "" {
+		return lint.LoadConfig(p.Root())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return lint.ReadConfig(f)
+}
+
+// lintFiles lints the modules in the given files and the modules of the
+// package they import. Every module is only linted once, even if it is
+// imported by many of them. The diagnostics of the modules are written to
+// out, and it reports false if there are any errors.
+func lintFiles(p *pkg.Package, l *lint.Linter, files []string, out *output) ([]lint.Issue, bool) {
+	var linted = make(map[string]bool)
+	var issues []lint.Issue
+	for _, file := range files {
+		if linted[absPath(file)] {
+			continue
+		}
+
+		prog := loadMode(file, parser.FullParse|parser.ParseComments, out)
+		if prog == nil {
+			return nil, false
+		}
+
+		for _, name := range prog.ast.Order {
+			mod := prog.ast.Modules[name]
+			path := absPath(mod.Path)
+			if linted[path] || !inPackage(p, path) {
+				continue
+			}
+
+			linted[path] = true
+			issues = append(issues, l.Lint(mod, prog.info)...)
+		}
+	}
+	return issues, true
+}
+
+// absPath returns the absolute path of the given path, or the path itself
+// if it cannot be made absolute.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// inPackage reports whether the file at the given absolute path belongs to
+// the package and not to one of its dependencies.
+func inPackage(p *pkg.Package, path string) bool {
+	rel, err := filepath.Rel(absPath(p.Root()), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return !strings.HasPrefix(filepath.ToSlash(rel), "elm-stuff/")
+}
+
+// issueReport is the report of a lint issue, with its fixes.
+type issueReport struct {
+	report.BaseReport
+	report.FixList
+}
+
+// reportIssues reports the issues as warnings. It reports false if they
+// could not be written.
+func reportIssues(p *pkg.Package, issues []lint.Issue, out *output) bool {
+	cm := source.NewCodeMap(source.NewFsLoader(p))
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, out.emitter())
+	for _, issue := range issues {
+		if err := cm.Add(issue.Path); err != nil {
+			out.errorf("%s", err)
+			return false
+		}
+
+		msg := fmt.Sprintf("%s (%s)", issue.Message, issue.Rule)
+		loc := token.Location{File: issue.Path, Span: token.Span{Start: issue.Pos, End: issue.End}}
+		region := &report.Region{Start: issue.Pos, End: issue.End}
+		r := &issueReport{BaseReport: report.NewBaseReport(report.Warning, loc, msg, region)}
+		for _, fix := range issue.Fixes {
+			r.AddFix(fix)
+		}
+		reporter.Report(r)
+	}
+
+	if err := reporter.Emit(); err != nil {
+		out.errorf("%s", err)
+		return false
+	}
+	return true
+}
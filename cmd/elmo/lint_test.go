@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const linted = "_testdata/linted"
+
+func TestLint(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "lint", linted)
+	require.Equal(1, code, out)
+	for _, msg := range []string{
+		"warning: module Extra is imported but it is not used (unused-import)",
+		"warning: there is a TODO comment: annotate this (todo)",
+		"warning: update is exposed but it has no type annotation (missing-signature)",
+		"warning: unused is defined but it is not used (unused-value)",
+		"warning: this case expression has 4 branches, more than 3 (long-case)",
+		"warning: helper is defined but it is not used (unused-value)",
+		"at _testdata/linted/src/Main.elm:4:1",
+	} {
+		require.Contains(out, msg)
+	}
+
+	code, out = runElmo(t, "lint", filepath.Join(linted, "src", "Util.elm"))
+	require.Equal(0, code, out)
+	require.Equal("", out)
+
+	code, _ = runElmo(t, "lint", linted, linted)
+	require.Equal(2, code)
+}
+
+func TestLintConfig(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "lint.json")
+	content := `{"rules": {"todo": {"disabled": true}, "unused-value": {"disabled": true}}}`
+	require.NoError(ioutil.WriteFile(config, []byte(content), 0644))
+
+	code, out := runElmo(t, "lint", "--config", config, linted)
+	require.Equal(1, code, out)
+	require.Contains(out, "(unused-import)")
+	require.Contains(out, "(missing-signature)")
+	require.NotContains(out, "(todo)")
+	require.NotContains(out, "(unused-value)")
+	require.NotContains(out, "(long-case)")
+
+	require.NoError(ioutil.WriteFile(config, []byte(`{"rules": {"unknown": {}}}`), 0644))
+	code, out = runElmo(t, "lint", "--config", config, linted)
+	require.Equal(3, code)
+	require.Contains(out, `elmo: lint: unknown rule "unknown" in the configuration`)
+}
+
+func TestLintExposingAll(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	manifest := `{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["src"]}`
+	require.NoError(os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elm-package.json"), []byte(manifest), 0644))
+	src := "module Main exposing (..)\n\n\nanswer : Int\nanswer =\n    42\n"
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "src", "Main.elm"), []byte(src), 0644))
+
+	code, out := runElmo(t, "lint", dir)
+	require.Equal(0, code, out)
+
+	config := `{"rules": {"exposing-all": {"enabled": true}}}`
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elmo-lint.json"), []byte(config), 0644))
+	code, out = runElmo(t, "lint", dir)
+	require.Equal(1, code, out)
+	require.Contains(out, "warning: module Main exposes all of its names (exposing-all)")
+	require.Contains(out, "fix: Expose the names explicitly: (answer)")
+}
+
+func TestLintJSON(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "lint", "--report=json", linted)
+	require.Equal(1, code, out)
+	events := jsonEvents(t, out)
+	require.Len(events, 7)
+	require.Equal("diagnostic", events[0]["event"])
+	require.Equal("warning", events[0]["type"])
+	require.Equal(fields{"event": "lint", "issues": float64(6)}, events[6])
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/crash"
+	"github.com/elm-tangram/tangram/ffi"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/pipeline"
+	"github.com/elm-tangram/tangram/profile"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// program is a main module parsed and type checked with all the modules it
+// imports.
+type program struct {
+	// pkg is the package of the main module.
+	pkg *pkg.Package
+	// main is the name of the main module.
+	main string
+	ast  *ast.Package
+	info *types.Info
+	// loader loads the files of the program, with the same sources the
+	// parser had.
+	loader source.Loader
+	// profile measures the phases of the program, if it is not nil.
+	profile *profile.Profile
+	// passes are the registered passes, which run on unit, and emitter
+	// emits the problems they find.
+	passes  *pipeline.Pipeline
+	unit    *pipeline.Unit
+	emitter report.Emitter
+}
+
+// sources are the sources of the files loaded by the commands, so the watch
+// command only scans again the files that changed every time it loads the
+// program again.
+var sources = source.NewCache()
+
+// mainPath returns the path to the file of the main module given in the
+// command line. It can be the file itself or the directory of a package,
+// whose main module is Main. An empty path is the current directory.
+func mainPath(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !fi.IsDir() {
+		return path, nil
+	}
+
+	p, err := pkg.Load(path)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := p.FindSourceModule("Main")
+	if err == pkg.ErrModuleNotFound {
+		return "", fmt.Errorf("there is no Main module in the source directories of %s", p.Root())
+	}
+	return file, err
+}
+
+// load parses and type checks the main module at the given path. The
+// diagnostics are written to out, and the program is nil if there are any
+// errors.
+func load(path string, out *output) *program {
+	return loadMode(path, parser.FullParse, out)
+}
+
+// loadFor is like load, but the modules are parsed for the given target, so
+// the declarations guarded by target pragmas for other targets are left out.
+// They are parsed for the js target otherwise.
+func loadFor(path string, t target.Target, out *output) *program {
+	return loadModeFor(path, parser.FullParse, t, out)
+}
+
+// loadMode is like load, but the modules are parsed with the given mode.
+func loadMode(path string, mode parser.ParseMode, out *output) *program {
+	return loadModeFor(path, mode, target.JS, out)
+}
+
+// loadModeFor is like load, but the modules are parsed with the given mode
+// for the given target.
+func loadModeFor(path string, mode parser.ParseMode, t target.Target, out *output) *program {
+	path, err := mainPath(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return nil
+	}
+
+	p, err := pkg.Load(filepath.Dir(path))
+	if err != nil {
+		out.errorf("%s", err)
+		return nil
+	}
+	return loadIn(path, p, mode, t, out)
+}
+
+// loadIn is like loadModeFor, but the package of the main module at the
+// given path is already loaded, and the modules are parsed with the given
+// options too.
+func loadIn(path string, p *pkg.Package, mode parser.ParseMode, t target.Target, out *output, opts ...parser.Option) *program {
+	passes, err := pipeline.Default()
+	if err != nil {
+		out.errorf("%s", err)
+		return nil
+	}
+
+	parsed, err := parser.Parse(path, append([]parser.Option{
+		mode,
+		parser.WithEmitter(out.emitter()),
+		parser.WithProfile(out.profile),
+		parser.WithCache(sources),
+		parser.WithTarget(t),
+	}, opts...)...)
+	if err == parser.ErrProblems {
+		return nil
+	} else if err != nil {
+		out.errorf("%s", err)
+		return nil
+	}
+
+	if parsed == nil || len(parsed.Order) == 0 {
+		out.errorf("there are no modules in %s", path)
+		return nil
+	}
+
+	prog := &program{
+		pkg:     p,
+		main:    parsed.Order[len(parsed.Order)-1],
+		ast:     parsed,
+		info:    types.NewInfo(),
+		loader:  sources.Loader(source.NewFsLoader(p)),
+		profile: out.profile,
+		passes:  passes,
+		emitter: out.emitter(),
+	}
+	prog.unit = &pipeline.Unit{AST: parsed, Info: prog.info}
+
+	cm := source.NewCodeMap(source.NewFsLoader(p))
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, out.emitter())
+	conf := types.Config{
+		Main:    prog.main,
+		Profile: out.profile,
+		Error: func(err *types.Error) {
+			if err := cm.Add(err.Path); err != nil {
+				out.errorf("%s", err)
+				return
+			}
+			reporter.Report(report.NewTypeError(err))
+		},
+	}
+
+	checkErr := conf.Check(parsed, prog.info)
+	if crashErr, ok := checkErr.(*crash.Error); ok {
+		crashErr.Locate(cm)
+		out.errorf("%s", crashErr)
+		return nil
+	}
+
+	var passErr error
+	if checkErr == nil {
+		prog.unit.CodeMap, prog.unit.Reporter = cm, reporter
+		passErr = passes.Run(prog.unit, pipeline.Checked)
+	}
+
+	if err := reporter.Emit(); err != nil {
+		out.errorf("%s", err)
+		return nil
+	}
+
+	if passErr != nil {
+		out.errorf("%s", passErr)
+		return nil
+	}
+
+	if checkErr != nil || reporter.HasErrors() {
+		return nil
+	}
+	return prog
+}
+
+// runLowered runs the registered passes of the lowered stage on the lowered
+// program and emits the problems they find. It fails if any pass fails or
+// reports errors.
+func (p *program) runLowered(lowered *ir.Package) error {
+	if len(p.passes.Passes()) == 0 {
+		return nil
+	}
+
+	cm := source.NewCodeMap(source.NewFsLoader(p.pkg))
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, p.emitter)
+	p.unit.IR, p.unit.CodeMap, p.unit.Reporter = lowered, cm, reporter
+	passErr := p.passes.Run(p.unit, pipeline.Lowered)
+	if err := reporter.Emit(); err != nil {
+		return err
+	}
+
+	if passErr != nil {
+		return passErr
+	}
+
+	if reporter.HasErrors() {
+		return fmt.Errorf("the passes found errors in module %s", p.main)
+	}
+	return nil
+}
+
+// checkNatives reports the native modules of the program whose Go files do
+// not implement their declarations, before the program is built for the go
+// target.
+func (p *program) checkNatives() error {
+	loader := source.NewFsLoader(p.pkg)
+	errs, err := ffi.Check(p.ast, loader)
+	if err != nil || len(errs) == 0 {
+		return err
+	}
+
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, p.emitter)
+	for _, e := range errs {
+		if err := cm.Add(e.Path); err != nil {
+			return err
+		}
+		reporter.Report(report.NewBaseReport(report.TypeError, token.At(e.Path, e.Pos), e.Message, nil))
+	}
+	if err := reporter.Emit(); err != nil {
+		return err
+	}
+	return fmt.Errorf("the native modules of module %s do not match their declarations", p.main)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/elm-tangram/tangram/elmo"
+	"github.com/elm-tangram/tangram/lsp"
+)
+
+const lspUsage = `Usage: elmo lsp [flags]
+
+Lsp runs a server of the Language Server Protocol on the standard input and
+output, to be started by editors. It reports the problems of the modules
+being edited as they change, even before they are saved, and it shows the
+types of the values on hover, goes to the definitions of the names, finds
+their references, lists the declarations of the modules and completes the
+names in scope. The version of elmo and what it supports, the JSON object
+of elmo version --json, are sent to the editor when it initializes the
+server, in the buildInfo of the experimental capabilities.
+
+The errors of the server are logged to the standard error.
+
+The flags are:
+
+`
+
+func runLsp(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	logFile := flags.String("log", "", "log the errors of the server to this file instead of the standard error")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, lspUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	var logw = stderr
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(stderr, "elmo: %s\n", err)
+			return exitInternal
+		}
+		defer f.Close()
+		logw = f
+	}
+
+	info := elmo.BuildInfo()
+	s := lsp.NewServer()
+	s.Log = log.New(logw, "elmo lsp: ", log.LstdFlags)
+	s.Version, s.BuildInfo = info.Version, info
+	if err := s.Serve(stdin, stdout); err != nil {
+		s.Log.Print(err)
+		return exitInternal
+	}
+	return exitOK
+}
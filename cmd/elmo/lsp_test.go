@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lspMessages frames the given messages of a client.
+func lspMessages(msgs ...string) string {
+	var buf strings.Builder
+	for _, msg := range msgs {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	return buf.String()
+}
+
+func TestLsp(t *testing.T) {
+	require := require.New(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(lspMessages(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"processId":null,"rootUri":null}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	))
+
+	code, out := runElmo(t, "lsp")
+	require.Equal(exitOK, code, out)
+	require.Contains(out, `"hoverProvider":true`)
+	require.Contains(out, `{"jsonrpc":"2.0","id":2,"result":null}`)
+}
+
+func TestLspExitWithoutShutdown(t *testing.T) {
+	require := require.New(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(lspMessages(`{"jsonrpc":"2.0","method":"exit"}`))
+
+	code, out := runElmo(t, "lsp")
+	require.Equal(exitInternal, code, out)
+	require.Contains(out, "exit without shutdown")
+}
@@ -0,0 +1,185 @@
+// Command elmo compiles Elm packages.
+//
+// Usage:
+//
+//	elmo <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	audit       check the dependencies against the advisories of the registry
+//	bind        generate the Elm bindings of a Go package
+//	build       compile a program or a library
+//	bump        set the version of a package after the changes of its API
+//	check       report the problems of a program without compiling it
+//	compile     compile a package given as JSON on the standard input
+//	completion  print the shell completion script of elmo
+//	derive      derive the JSON decoders and encoders of the types of a module
+//	describe    describe a declaration of a package
+//	doc         generate the documentation of a package
+//	explore     serve a web page to explore the modules of a program
+//	fmt         format Elm source code
+//	graph       print the dependency graph of a program
+//	install     install the dependencies of a package
+//	lint        report the code of a package that is likely to be a mistake
+//	lsp         run a language server for editors
+//	publish     publish the version of a package in the registry
+//	rename      rename a declaration in all the modules of a package
+//	repl        evaluate Elm code interactively
+//	serve       serve the compiler as an HTTP API
+//	test        run the tests of a package
+//	usage       print how much the declarations of a package are used
+//	version     print the version of elmo and what it supports
+//
+// Any other command, such as `elmo deploy`, runs the plugin executable
+// elmo-deploy found on the PATH with the rest of the arguments, the way git
+// does, and exits with its exit code. The ELMO environment variable of the
+// plugin is the path to elmo.
+//
+// Run `elmo <command> -help` to see the flags of a command. Every command
+// but compile, completion, lsp and version has a --report=json flag that
+// writes the diagnostics and the events of the command to the standard
+// output as JSON, one object per line.
+//
+// The commands that compile programs, build, check, doc and test, have a
+// --profile flag that prints the time and the memory spent in every phase
+// of every module, an --events flag that writes the events of the
+// compilation to a file as JSON lines, and --cpuprofile and --memprofile
+// flags that write pprof profiles of elmo.
+//
+// The commands with a --report flag have a --quiet flag as well, which
+// leaves out the warnings and the messages about their progress, and a
+// project configuration, the elmo.toml or .elmorc files of the current
+// directory and its parents, can hide the warnings of all of them, set the
+// default target of build and the formatting of fmt, and replace the source
+// directories of the packages. Every command exits with one of these codes:
+//
+//	0  the command succeeded
+//	1  there are problems in the Elm code, such as compile errors, failing
+//	   tests, unformatted files, lint issues, a package that is not
+//	   ready to be published or dependencies known to be bad
+//	2  the arguments are not valid
+//	3  elmo failed for another reason, such as a file that cannot be read
+//	   or written, an invalid package, a network error or a crash
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/elm-tangram/tangram/crash"
+)
+
+// The exit codes of the commands.
+const (
+	// exitOK means that the command succeeded.
+	exitOK = 0
+	// exitProblems means that there are problems in the Elm code.
+	exitProblems = 1
+	// exitUsage means that the arguments of the command are not valid.
+	exitUsage = 2
+	// exitInternal means that elmo failed for a reason other than the Elm
+	// code, including crashes.
+	exitInternal = 3
+)
+
+// command is a subcommand of elmo.
+type command struct {
+	name    string
+	summary string
+	// run runs the command with the given arguments, which do not include
+	// the name of the command, and returns the exit code.
+	run func(args []string, stdout, stderr io.Writer) int
+}
+
+// stdin is the standard input of the commands, which is replaced in the
+// tests.
+var stdin io.Reader = os.Stdin
+
+// commands are the built-in commands. They are set in init because the
+// completion command lists them.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"audit", "check the dependencies against the advisories of the registry", runAudit},
+		{"bind", "generate the Elm bindings of a Go package", runBind},
+		{"build", "compile a program or a library", runBuild},
+		{"bump", "set the version of a package after the changes of its API", runBump},
+		{"check", "report the problems of a program without compiling it", runCheck},
+		{"compile", "compile a package given as JSON on the standard input", runCompile},
+		{"completion", "print the shell completion script of elmo", runCompletion},
+		{"derive", "derive the JSON decoders and encoders of the types of a module", runDerive},
+		{"describe", "describe a declaration of a package", runDescribe},
+		{"doc", "generate the documentation of a package", runDoc},
+		{"explore", "serve a web page to explore the modules of a program", runExplore},
+		{"fmt", "format Elm source code", runFmt},
+		{"graph", "print the dependency graph of a program", runGraph},
+		{"install", "install the dependencies of a package", runInstall},
+		{"lint", "report the code of a package that is likely to be a mistake", runLint},
+		{"lsp", "run a language server for editors", runLsp},
+		{"publish", "publish the version of a package in the registry", runPublish},
+		{"rename", "rename a declaration in all the modules of a package", runRename},
+		{"repl", "evaluate Elm code interactively", runRepl},
+		{"serve", "serve the compiler as an HTTP API", runServe},
+		{"test", "run the tests of a package", runTest},
+		{"usage", "print how much the declarations of a package are used", runUsage},
+		{"version", "print the version of elmo and what it supports", runVersion},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command in the given arguments and returns the exit code.
+// A crash of the command is reported as an internal compiler error of the
+// phase named after the command.
+func run(args []string, stdout, stderr io.Writer) (code int) {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(stderr, "elmo: %s", crash.New(args[0], r))
+			code = exitInternal
+		}
+	}()
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+
+	if path, ok := findPlugin(args[0]); ok {
+		return runPlugin(path, args[1:], stdout, stderr)
+	}
+
+	fmt.Fprintf(stderr, "elmo: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n\n\telmo <command> [flags] [arguments]\n\nThe commands are:\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "\t%-10s %s\n", cmd.name, cmd.summary)
+	}
+
+	if names := plugins(); len(names) > 0 {
+		fmt.Fprintf(w, "\nThe plugin commands on the PATH are:\n\n")
+		for _, name := range names {
+			fmt.Fprintf(w, "\t%s\n", name)
+		}
+	}
+	fmt.Fprintf(w, "\nRun `elmo <command> -help` to see the flags of a command.\n")
+}
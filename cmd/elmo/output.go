@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/elm-tangram/tangram/config"
+	"github.com/elm-tangram/tangram/profile"
+	"github.com/elm-tangram/tangram/report"
+)
+
+// output is where a command writes what it does and the problems it finds.
+// As text, the results are written to the standard output and the problems
+// to the standard error. With --report=json, everything is written to the
+// standard output instead as JSON events, one per line, so that editors and
+// build tools can follow what elmo does. Every event has an "event" field
+// with its kind.
+type output struct {
+	stdout io.Writer
+	stderr io.Writer
+	json   bool
+	// quiet reports whether the warnings and the progress of the command
+	// are left out.
+	quiet bool
+	// internal reports whether an error of elmo has been reported.
+	internal bool
+	// profile measures the phases of the programs loaded and built by the
+	// command, if it is not nil.
+	profile *profile.Profile
+	// config is the project configuration of the current directory.
+	config *config.Config
+}
+
+// fields are the data of an event.
+type fields map[string]interface{}
+
+// outputOptions are the flags of every command about its output.
+type outputOptions struct {
+	report string
+	quiet  bool
+}
+
+// outputFlags defines the --report and --quiet flags of a command.
+func outputFlags(flags *flag.FlagSet) *outputOptions {
+	var opts outputOptions
+	flags.StringVar(&opts.report, "report", "text", "format of the output: text, or json for a stream of JSON events")
+	flags.BoolVar(&opts.quiet, "quiet", false, "leave out the warnings and the progress of the command")
+	return &opts
+}
+
+// isSet reports whether the flag with the given name was given in the
+// command line.
+func isSet(flags *flag.FlagSet, name string) bool {
+	var set bool
+	flags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// newOutput returns the output of a command with the given flags and the
+// project configuration of the current directory, whose warnings are left
+// out too if the configuration hides them. It returns nil and writes the
+// error to stderr if the report format or the configuration is not valid,
+// with the exit code of the command, which is exitUsage for the format and
+// exitInternal for the configuration.
+func newOutput(opts *outputOptions, stdout, stderr io.Writer) (*output, int) {
+	if opts.report != "text" && opts.report != "json" {
+		fmt.Fprintf(stderr, "elmo: unknown report format %q, it must be text or json\n", opts.report)
+		return nil, exitUsage
+	}
+
+	conf, err := config.Load(".")
+	if err != nil {
+		fmt.Fprintf(stderr, "elmo: %s\n", err)
+		return nil, exitInternal
+	}
+
+	return &output{
+		stdout: stdout,
+		stderr: stderr,
+		json:   opts.report == "json",
+		quiet:  opts.quiet || conf.Warnings == config.HideWarnings,
+		config: conf,
+	}, exitOK
+}
+
+// failure returns the exit code of a command that failed, which is
+// exitInternal if an error of elmo has been reported and exitProblems
+// otherwise.
+func (o *output) failure() int {
+	if o.internal {
+		return exitInternal
+	}
+	return exitProblems
+}
+
+// errorf reports an error of elmo, which is not a diagnostic of the Elm
+// code, as an error event.
+func (o *output) errorf(format string, args ...interface{}) {
+	o.internal = true
+	msg := fmt.Sprintf(format, args...)
+	if o.json {
+		o.encode("error", fields{"message": msg})
+		return
+	}
+	fmt.Fprintf(o.stderr, "elmo: %s\n", msg)
+}
+
+// problem reports an error whose message is already written for humans,
+// such as the problems found by the parser, as an error event.
+func (o *output) problem(err error) {
+	if o.json {
+		o.encode("error", fields{"message": err.Error()})
+		return
+	}
+	fmt.Fprintln(o.stderr, err)
+}
+
+// event reports an event of the given kind. As text, only the given text is
+// written.
+func (o *output) event(kind string, data fields, text string) {
+	if o.json {
+		o.encode(kind, data)
+		return
+	}
+	io.WriteString(o.stdout, text)
+}
+
+// progress reports an event about the progress of the command, whose text
+// is not written in quiet mode.
+func (o *output) progress(kind string, data fields, text string) {
+	if o.quiet {
+		text = ""
+	}
+	o.event(kind, data, text)
+}
+
+// text writes text that is only meant for humans, which is not written as
+// JSON.
+func (o *output) text(text string) {
+	if !o.json {
+		io.WriteString(o.stdout, text)
+	}
+}
+
+// note writes text about the progress of the command that is only meant for
+// humans, which is not written as JSON nor in quiet mode.
+func (o *output) note(text string) {
+	if !o.quiet {
+		o.text(text)
+	}
+}
+
+// emitter returns the emitter of the diagnostics of the Elm code, which are
+// diagnostic events. The warnings are left out in quiet mode.
+func (o *output) emitter() report.Emitter {
+	if o.json {
+		return report.JSON(o.stdout, !o.quiet)
+	}
+	return report.Writer(o.stderr, !o.quiet, o.stderr == os.Stderr)
+}
+
+func (o *output) encode(kind string, data fields) {
+	var event = fields{"event": kind}
+	for k, v := range data {
+		event[k] = v
+	}
+
+	enc := json.NewEncoder(o.stdout)
+	enc.SetEscapeHTML(false)
+	enc.Encode(event)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// jsonEvents decodes the JSON events of the output of a command.
+func jsonEvents(t *testing.T, out string) []fields {
+	var events []fields
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var e fields
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestReportJSON(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "check", "--report=json", "_testdata/missing")
+	require.Equal(3, code)
+	events := jsonEvents(t, out)
+	require.Len(events, 2)
+	require.Equal("error", events[0]["event"])
+	require.Contains(events[0]["message"], "_testdata/missing")
+	require.Equal(fields{"event": "check", "ok": false}, events[1])
+
+	code, out = runElmo(t, "graph", "--report", "json", "--collapse", cyclic)
+	require.Equal(0, code, out)
+	require.Equal([]fields{{
+		"event":  "graph",
+		"format": "dot",
+		"graph":  "digraph packages {\n\t\"elm-lang/core\";\n\t\"elmo/cyclic\";\n\t\"elmo/cyclic\" -> \"elm-lang/core\";\n}\n",
+	}}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "graph", "--report", "json", "--format", "json", "--collapse", cyclic)
+	require.Equal(0, code, out)
+	events = jsonEvents(t, out)
+	require.Equal("elmo/cyclic", events[0]["graph"].(map[string]interface{})["root"])
+
+	code, out = runElmo(t, "build", "--report=xml", fixture)
+	require.Equal(2, code)
+	require.Equal("elmo: unknown report format \"xml\", it must be text or json\n", out)
+}
+
+func TestQuiet(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "lint", "--quiet", linted)
+	require.Equal(1, code, out)
+	require.Equal("", out)
+
+	code, out = runElmo(t, "lint", "--quiet", "--report=json", linted)
+	require.Equal(1, code, out)
+	require.Equal([]fields{{"event": "lint", "issues": float64(6)}}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "check", "--quiet", "_testdata/broken")
+	require.Equal(1, code)
+	require.Contains(out, "type error: ")
+}
+
+func TestExitCodes(t *testing.T) {
+	require := require.New(t)
+
+	code, _ := runElmo(t, "check", fixture)
+	require.Equal(exitOK, code)
+
+	code, _ = runElmo(t, "check", "_testdata/broken")
+	require.Equal(exitProblems, code)
+
+	code, _ = runElmo(t, "check", "--unknown")
+	require.Equal(exitUsage, code)
+
+	code, _ = runElmo(t, "check", "_testdata/missing")
+	require.Equal(exitInternal, code)
+
+	commands = append(commands, &command{"crash", "crash", func([]string, io.Writer, io.Writer) int {
+		panic("boom")
+	}})
+	defer func() { commands = commands[:len(commands)-1] }()
+
+	code, out := runElmo(t, "crash")
+	require.Equal(exitInternal, code)
+	require.Contains(out, "elmo: internal compiler error: boom\n\nphase:  crash\n")
+	require.Contains(out, "This is a bug in the compiler")
+}
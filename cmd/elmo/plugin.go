@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pluginPrefix is the prefix of the names of the plugin executables.
+const pluginPrefix = "elmo-"
+
+// findPlugin returns the path to the executable of the plugin command with
+// the given name on the PATH, if there is one.
+func findPlugin(name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+
+	path, err := exec.LookPath(pluginPrefix + name)
+	return path, err == nil
+}
+
+// runPlugin runs the plugin executable at the given path with the arguments
+// and returns its exit code.
+func runPlugin(path string, args []string, stdout, stderr io.Writer) int {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = os.Environ()
+	if elmo, err := os.Executable(); err == nil {
+		cmd.Env = append(cmd.Env, "ELMO="+elmo)
+	}
+
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() >= 0 {
+		return exit.ExitCode()
+	} else if err != nil {
+		fmt.Fprintf(stderr, "elmo: can't run plugin %s: %s\n", path, err)
+		return exitInternal
+	}
+	return exitOK
+}
+
+// plugins returns the names of the plugin commands on the PATH, sorted,
+// without the ones that have the name of a built-in command.
+func plugins() []string {
+	var builtin = make(map[string]bool)
+	for _, cmd := range commands {
+		builtin[cmd.name] = true
+	}
+
+	var seen = make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, f := range files {
+			name := strings.TrimPrefix(f.Name(), pluginPrefix)
+			if name == f.Name() || name == "" || f.IsDir() || f.Mode()&0111 == 0 {
+				continue
+			}
+
+			if !builtin[name] && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
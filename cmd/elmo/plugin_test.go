@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const helloPlugin = `#!/bin/sh
+echo "hello $@"
+echo "elmo is $ELMO" >&2
+exit 4
+`
+
+func TestPlugin(t *testing.T) {
+	require := require.New(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elmo-hello"), []byte(helloPlugin), 0755))
+	// plugins with the name of a built-in command are not run nor listed
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "elmo-build"), []byte(helloPlugin), 0755))
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	require.NoError(os.Setenv("PATH", dir))
+
+	code, out := runElmo(t, "hello", "--name", "world")
+	require.Equal(4, code, out)
+	require.Contains(out, "hello --name world\n")
+	require.Contains(out, "elmo is /")
+
+	code, out = runElmo(t, "help")
+	require.Equal(exitOK, code)
+	require.Contains(out, "The plugin commands on the PATH are:\n\n\thello\n")
+	require.NotContains(out, "\tbuild\n")
+
+	code, out = runElmo(t, "goodbye")
+	require.Equal(exitUsage, code)
+	require.Contains(out, `elmo: unknown command "goodbye"`)
+
+	code, _ = runElmo(t, "../hello")
+	require.Equal(exitUsage, code)
+}
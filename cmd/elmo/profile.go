@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/elm-tangram/tangram/profile"
+)
+
+// profileOptions are the profiling flags of the commands that compile
+// programs.
+type profileOptions struct {
+	phases bool
+	events string
+	cpu    string
+	mem    string
+}
+
+// profileFlags defines the profiling flags of a command.
+func profileFlags(flags *flag.FlagSet) *profileOptions {
+	var opts profileOptions
+	flags.BoolVar(&opts.phases, "profile", false, "print the time and memory spent in every phase of every module")
+	flags.StringVar(&opts.events, "events", "", "write the events of the compilation as JSON lines to this file")
+	flags.StringVar(&opts.cpu, "cpuprofile", "", "write a CPU profile of elmo in the pprof format to this file")
+	flags.StringVar(&opts.mem, "memprofile", "", "write a memory profile of elmo in the pprof format to this file")
+	return &opts
+}
+
+// start starts the profiles of the command and returns the function that
+// stops them, which writes them. The phases are measured in the profile of
+// out. It reports false if the profiles could not be started.
+func (o *profileOptions) start(out *output) (stop func(), ok bool) {
+	if o.phases || o.events != "" {
+		out.profile = profile.New()
+	}
+
+	var events *os.File
+	if o.events != "" {
+		var err error
+		if events, err = os.Create(o.events); err != nil {
+			out.errorf("can't create the events file: %s", err)
+			return nil, false
+		}
+		out.profile.WriteEvents(events)
+	}
+
+	var cpu *os.File
+	if o.cpu != "" {
+		var err error
+		if cpu, err = os.Create(o.cpu); err != nil {
+			out.errorf("can't create the CPU profile: %s", err)
+			return nil, false
+		}
+
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			if events != nil {
+				events.Close()
+			}
+			out.errorf("can't start the CPU profile: %s", err)
+			return nil, false
+		}
+	}
+
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				out.errorf("can't write the CPU profile: %s", err)
+			}
+		}
+
+		if o.mem != "" {
+			if err := writeMemProfile(o.mem); err != nil {
+				out.errorf("can't write the memory profile: %s", err)
+			}
+		}
+
+		if events != nil {
+			if err := events.Close(); err != nil {
+				out.errorf("can't write the events file: %s", err)
+			}
+		}
+
+		if o.phases {
+			var buf bytes.Buffer
+			out.profile.Write(&buf)
+			out.event("profile", fields{"phases": out.profile.Entries()}, buf.String())
+		}
+	}, true
+}
+
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	// the garbage collection updates the statistics of the heap
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "elm.js")
+	cpu := filepath.Join(dir, "cpu.prof")
+	mem := filepath.Join(dir, "mem.prof")
+	code, out := runElmo(t, "build", "--profile", "--cpuprofile", cpu, "--memprofile", mem, "--output", output, fixture)
+	require.Equal(0, code, out)
+	require.Contains(out, "PHASE")
+	for _, line := range []string{"load     Main", "scan     Basics", "parse    (total)", "resolve  Platform", "check    (program)", "codegen  (program)"} {
+		require.Contains(out, line)
+	}
+
+	for _, path := range []string{cpu, mem} {
+		fi, err := os.Stat(path)
+		require.NoError(err)
+		require.NotZero(fi.Size())
+	}
+
+	code, out = runElmo(t, "check", "--cpuprofile", filepath.Join(dir, "missing", "cpu.prof"), fixture)
+	require.Equal(3, code)
+	require.Contains(out, "elmo: can't create the CPU profile")
+}
+
+func TestProfileJSON(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "check", "--profile", "--report=json", fixture)
+	require.Equal(0, code, out)
+	events := jsonEvents(t, out)
+	require.Len(events, 2)
+	require.Equal("profile", events[1]["event"])
+
+	var phases = make(map[interface{}]bool)
+	for _, e := range events[1]["phases"].([]interface{}) {
+		entry := e.(map[string]interface{})
+		require.Contains(entry, "module")
+		require.Contains(entry, "duration")
+		require.Contains(entry, "alloc")
+		phases[entry["phase"]] = true
+	}
+	require.Equal(map[interface{}]bool{"load": true, "scan": true, "parse": true, "resolve": true, "check": true}, phases)
+}
+
+func TestProfileEvents(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+	code, out := runElmo(t, "build", "--events", path, "--output", filepath.Join(dir, "elm.js"), fixture)
+	require.Equal(0, code, out)
+	require.NotContains(out, "PHASE")
+
+	f, err := os.Open(path)
+	require.NoError(err)
+	defer f.Close()
+
+	var kinds = make(map[interface{}]bool)
+	var modules = make(map[interface{}]bool)
+	var output map[string]interface{}
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var e map[string]interface{}
+		require.NoError(dec.Decode(&e))
+		require.Contains(e, "time")
+		kinds[e["event"]] = true
+		if e["event"] == "module" {
+			modules[e["module"]] = true
+		}
+		if e["event"] == "output" {
+			output = e
+		}
+	}
+
+	require.Equal(map[interface{}]bool{"module": true, "load": true, "scan": true, "parse": true, "resolve": true, "check": true, "codegen": true, "output": true}, kinds)
+	require.True(modules["Main"])
+	require.Equal("js", output["target"])
+	require.NotZero(output["bytes"])
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/doc"
+	"github.com/elm-tangram/tangram/package"
+)
+
+const publishUsage = `Usage: elmo publish [flags] [path]
+
+Publish publishes the version of the package at the given path, which is the
+current directory by default, in the registry. First it checks that the
+package is ready to be published:
+
+	- it has a summary of less than 80 characters, a license, exposed
+	  modules and a README.md
+	- its version is not published yet, and it is 1.0.0 if the package was
+	  never published or the one elmo bump gives after the previous
+	  published version otherwise
+	- all the changes of its git repository are committed
+
+Then the version is tagged in the git repository, the tag is pushed to the
+remote, where the registry downloads the code from, and the version is
+uploaded to the registry with its documentation.
+
+The flags are:
+
+`
+
+// defaultSummary is the summary of the packages created by elm-package,
+// which must be replaced before publishing them.
+const defaultSummary = "helpful summary of your project, less than 80 characters"
+
+// maxSummary is the maximum length of the summary of a package.
+const maxSummary = 80
+
+// publishOptions are the flags of the publish command.
+type publishOptions struct {
+	registry string
+	remote   string
+	dryRun   bool
+}
+
+func runPublish(args []string, stdout, stderr io.Writer) int {
+	var opts publishOptions
+	flags := flag.NewFlagSet("publish", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.registry, "registry", pkg.DefaultRegistry, "URL of the package registry")
+	flags.StringVar(&opts.remote, "remote", "origin", "git remote the tag of the version is pushed to")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "only check that the package is ready to be published")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, publishUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	path := flags.Arg(0)
+	if path == "" {
+		path = "."
+	}
+
+	p, name, ok := loadRelease(path, out)
+	if !ok {
+		return exitInternal
+	}
+
+	readme, err := ioutil.ReadFile(filepath.Join(p.Root(), "README.md"))
+	if err != nil && !os.IsNotExist(err) {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	problems := manifestProblems(p, readme)
+	if status, err := git(p.Root(), "status", "--porcelain"); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	} else if status != "" {
+		problems = append(problems, "there are changes in the git repository that are not committed")
+	}
+
+	if len(problems) > 0 {
+		reportProblems(name, p.Version, problems, out)
+		return exitProblems
+	}
+
+	r := pkg.NewRegistry(opts.registry, archives)
+	mods, problem, ok := releaseDocs(p, r, name, out)
+	if !ok {
+		return out.failure()
+	} else if problem != "" {
+		reportProblems(name, p.Version, []string{problem}, out)
+		return exitProblems
+	}
+
+	tag := p.Version.String()
+	tagged, problem, err := isTagged(p.Root(), tag)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	} else if problem != "" {
+		reportProblems(name, p.Version, []string{problem}, out)
+		return exitProblems
+	}
+
+	if opts.dryRun {
+		out.event("publish", fields{"package": name, "version": p.Version, "dry-run": true}, fmt.Sprintf("%s %s is ready to be published\n", name, p.Version))
+		return exitOK
+	}
+
+	if !tagged {
+		if _, err := git(p.Root(), "tag", "-a", tag, "-m", tag); err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+		out.progress("tagged", fields{"tag": tag}, fmt.Sprintf("tagged the version %s\n", tag))
+	}
+
+	if _, err := git(p.Root(), "push", opts.remote, tag); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	out.progress("pushed", fields{"tag": tag, "remote": opts.remote}, fmt.Sprintf("pushed the tag %s to %s\n", tag, opts.remote))
+
+	rel, err := newRelease(p, name, mods, readme)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if err := r.Publish(rel); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	out.event("publish", fields{"package": name, "version": p.Version, "dry-run": false}, fmt.Sprintf("published %s %s\n", name, p.Version))
+	return exitOK
+}
+
+// manifestProblems returns the reasons why the package with the given
+// README cannot be published, which are none if it is ready.
+func manifestProblems(p *pkg.Package, readme []byte) []string {
+	var problems []string
+	switch summary := strings.TrimSpace(p.Summary); {
+	case summary == "" || summary == defaultSummary:
+		problems = append(problems, "the package has no summary")
+	case len(summary) >= maxSummary:
+		problems = append(problems, fmt.Sprintf("the summary of the package must have less than %d characters", maxSummary))
+	}
+
+	if strings.TrimSpace(p.License) == "" {
+		problems = append(problems, "the package has no license")
+	}
+
+	if len(bytes.TrimSpace(readme)) == 0 {
+		problems = append(problems, "the package has no README.md")
+	}
+	return problems
+}
+
+// reportProblems reports the reasons why a version of a package cannot be
+// published.
+func reportProblems(name string, v pkg.Version, problems []string, out *output) {
+	for _, problem := range problems {
+		out.problem(fmt.Errorf("%s %s can't be published: %s", name, v, problem))
+	}
+}
+
+// releaseDocs returns the documentation of the version of the package to be
+// published. It checks that the version is the right one after the
+// published ones, and returns why it is not if it is not. The diagnostics
+// are written to out, and it reports false if there are any errors.
+func releaseDocs(p *pkg.Package, r *pkg.Registry, name string, out *output) ([]*doc.Module, string, bool) {
+	versions, err := publishedVersions(r, name)
+	if err != nil {
+		out.errorf("%s", err)
+		return nil, "", false
+	}
+
+	if isPublished(versions, p.Version) {
+		return nil, "the version is already published", true
+	}
+
+	if len(versions) == 0 {
+		if p.Version != (pkg.Version{1, 0, 0}) {
+			return nil, "the first version of a package must be 1.0.0", true
+		}
+
+		mods, ok := packageDocs(p, out)
+		return mods, "", ok
+	}
+
+	var prev *pkg.Version
+	for i := range versions {
+		if versions[i].Less(p.Version) {
+			prev = &versions[i]
+			break
+		}
+	}
+
+	if prev == nil {
+		return nil, fmt.Sprintf("the version is older than all the published ones, the oldest is %s", versions[len(versions)-1]), true
+	}
+
+	mods, changes, ok := apiChanges(p, r, name, *prev, out)
+	if !ok {
+		return nil, "", false
+	}
+
+	if next := prev.Bump(changes.Magnitude()); next != p.Version {
+		return nil, fmt.Sprintf("the API has %s changes since %s, so the version must be %s, which elmo bump sets", changes.Magnitude(), prev, next), true
+	}
+	return mods, "", true
+}
+
+// isTagged reports whether the version of the package is already tagged in
+// its git repository. It returns why it cannot be published if the tag is
+// not the current commit.
+func isTagged(dir, tag string) (bool, string, error) {
+	if _, err := git(dir, "rev-parse", "-q", "--verify", "refs/tags/"+tag); err != nil {
+		return false, "", nil
+	}
+
+	tagged, err := git(dir, "rev-list", "-n", "1", tag)
+	if err != nil {
+		return false, "", err
+	}
+
+	head, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, "", err
+	}
+
+	if tagged != head {
+		return true, fmt.Sprintf("the tag %s is not the current commit", tag), nil
+	}
+	return true, "", nil
+}
+
+// newRelease returns the release of the package with the given
+// documentation and README.
+func newRelease(p *pkg.Package, name string, mods []*doc.Module, readme []byte) (*pkg.Release, error) {
+	manifest, err := ioutil.ReadFile(filepath.Join(p.Root(), "elm-package.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs bytes.Buffer
+	if err := doc.Write(&docs, mods); err != nil {
+		return nil, err
+	}
+
+	return &pkg.Release{
+		Name:          name,
+		Version:       p.Version,
+		Manifest:      manifest,
+		Documentation: docs.Bytes(),
+		Readme:        readme,
+	}, nil
+}
+
+// git runs the git command with the given arguments in the directory and
+// returns its output without the trailing spaces.
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepo makes the directory a git repository with all its files
+// committed, whose origin remote is a new bare repository in remote.
+func gitRepo(t *testing.T, dir, remote string) {
+	_, err := git(remote, "init", "--bare", "-q")
+	require.NoError(t, err)
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "elmo"},
+		{"config", "user.email", "elmo@example.com"},
+		{"remote", "add", "origin", remote},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "initial commit"},
+	} {
+		_, err := git(dir, args...)
+		require.NoError(t, err)
+	}
+}
+
+func setVersion(t *testing.T, dir, version string) {
+	path := filepath.Join(dir, "elm-package.json")
+	manifest := strings.Replace(readFile(t, path), `"version": "1.0.0"`, `"version": "`+version+`"`, 1)
+	require.NoError(t, ioutil.WriteFile(path, []byte(manifest), 0644))
+}
+
+func TestPublish(t *testing.T) {
+	srv := newReleaseRegistry(t, "1.0.0")
+	defer srv.Close()
+
+	require := require.New(t)
+	dir := releasePackage(t)
+	defer os.RemoveAll(dir)
+	remote := tempDir(t)
+	defer os.RemoveAll(remote)
+
+	setVersion(t, dir, "1.1.0")
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# Greeting\n"), 0644))
+	gitRepo(t, dir, remote)
+
+	code, out := runElmo(t, "publish", "--registry", srv.URL, "--dry-run", dir)
+	require.Equal(0, code, out)
+	require.Equal("elm-lang/core 1.1.0 is ready to be published\n", out)
+	require.Empty(srv.published)
+
+	code, out = runElmo(t, "publish", "--registry", srv.URL, dir)
+	require.Equal(0, code, out)
+	require.Equal("tagged the version 1.1.0\npushed the tag 1.1.0 to origin\npublished elm-lang/core 1.1.0\n", out)
+	require.Equal([]string{"elm-lang/core 1.1.0"}, srv.published)
+
+	tags, err := git(remote, "tag")
+	require.NoError(err)
+	require.Equal("1.1.0", tags)
+
+	srv.versions = []string{"1.0.0", "1.1.0"}
+	code, out = runElmo(t, "publish", "--registry", srv.URL, dir)
+	require.Equal(1, code)
+	require.Equal("elm-lang/core 1.1.0 can't be published: the version is already published\n", out)
+}
+
+func TestPublishProblems(t *testing.T) {
+	srv := newReleaseRegistry(t, "1.0.0")
+	defer srv.Close()
+
+	require := require.New(t)
+	dir := releasePackage(t)
+	defer os.RemoveAll(dir)
+	remote := tempDir(t)
+	defer os.RemoveAll(remote)
+
+	setVersion(t, dir, "2.0.0")
+	gitRepo(t, dir, remote)
+
+	code, out := runElmo(t, "publish", "--registry", srv.URL, dir)
+	require.Equal(1, code)
+	require.Equal("elm-lang/core 2.0.0 can't be published: the package has no README.md\n", out)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# Greeting\n"), 0644))
+	code, out = runElmo(t, "publish", "--registry", srv.URL, dir)
+	require.Equal(1, code)
+	require.Equal("elm-lang/core 2.0.0 can't be published: there are changes in the git repository that are not committed\n", out)
+
+	_, err := git(dir, "add", "-A")
+	require.NoError(err)
+	_, err = git(dir, "commit", "-q", "-m", "add README")
+	require.NoError(err)
+
+	code, out = runElmo(t, "publish", "--registry", srv.URL, "--dry-run", dir)
+	require.Equal(1, code)
+	require.Equal("elm-lang/core 2.0.0 can't be published: the API has MINOR changes since 1.0.0, so the version must be 1.1.0, which elmo bump sets\n", out)
+
+	srv.versions = nil
+	code, out = runElmo(t, "publish", "--registry", srv.URL, "--dry-run", dir)
+	require.Equal(1, code)
+	require.Equal("elm-lang/core 2.0.0 can't be published: the first version of a package must be 1.0.0\n", out)
+
+	code, out = runElmo(t, "publish", "--registry", srv.URL, fixture)
+	require.Equal(3, code)
+	require.Equal("elmo: the package at "+fixture+" exposes no modules\n", out)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/refactor"
+)
+
+const renameUsage = `Usage: elmo rename [flags] Module.name newname [path]
+
+Rename renames a top-level value, type or constructor of a module of the
+package at the given path, which is the current directory by default. Its
+declaration, its type annotation, all the references to it in the modules
+of the package, the exposing lists and the @docs of the module comment are
+changed, and the files are written. For example:
+
+	elmo rename Util.identity id
+
+It fails without changing anything if the new name is not valid, or if it
+is already used where the declaration is visible.
+
+The flags are:
+
+`
+
+// renameOptions are the flags of the rename command.
+type renameOptions struct {
+	dryRun bool
+}
+
+func runRename(args []string, stdout, stderr io.Writer) int {
+	var opts renameOptions
+	flags := flag.NewFlagSet("rename", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "list the files that would change without writing them")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, renameUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() < 2 || flags.NArg() > 3 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	i := strings.LastIndex(flags.Arg(0), ".")
+	if i <= 0 {
+		out.errorf("%q is not a qualified name, such as Module.name", flags.Arg(0))
+		return exitUsage
+	}
+	module, name, to := flags.Arg(0)[:i], flags.Arg(0)[i+1:], flags.Arg(1)
+
+	dir := flags.Arg(2)
+	if dir == "" {
+		dir = "."
+	}
+
+	p, err := pkg.Load(dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var dirs []string
+	for _, dir := range p.SourceDirectories {
+		dirs = append(dirs, filepath.Join(p.Root(), dir))
+	}
+
+	files, err := elmFiles(dirs)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	edits, code := renameFiles(p, files, module, name, to, out)
+	if code != exitOK {
+		return code
+	}
+
+	var byPath = make(map[string][]refactor.Edit)
+	var paths []string
+	for _, e := range edits {
+		if _, ok := byPath[e.Path]; !ok {
+			paths = append(paths, e.Path)
+		}
+		byPath[e.Path] = append(byPath[e.Path], e)
+	}
+
+	for _, path := range paths {
+		if !opts.dryRun {
+			if err := applyEdits(path, byPath[path]); err != nil {
+				out.errorf("%s", err)
+				return exitInternal
+			}
+		}
+		out.progress("renamed", fields{"file": path, "edits": len(byPath[path])}, path+"\n")
+	}
+
+	out.event("rename", fields{"from": flags.Arg(0), "to": to, "files": len(paths), "dry-run": opts.dryRun}, "")
+	return exitOK
+}
+
+// renameFiles returns the edits that rename the declaration in the modules
+// of the package, which are parsed from the given files and the modules
+// they import, and the exit code.
+func renameFiles(p *pkg.Package, files []string, module, name, to string, out *output) ([]refactor.Edit, int) {
+	var parsed = make(map[string]bool)
+	var found bool
+	var edits [][]refactor.Edit
+	for _, file := range files {
+		if parsed[absPath(file)] {
+			continue
+		}
+
+		tree, err := parser.ParseWith(file, parser.FullParse|parser.ParseComments, out.emitter(), nil)
+		if err == parser.ErrProblems {
+			return nil, out.failure()
+		} else if err != nil {
+			out.errorf("%s", err)
+			return nil, exitInternal
+		}
+
+		for _, mod := range tree.Modules {
+			parsed[absPath(mod.Path)] = true
+		}
+
+		obj := refactor.Lookup(tree, module, name)
+		if obj == nil {
+			continue
+		}
+
+		if !inPackage(p, absPath(tree.Modules[module].Path)) {
+			out.errorf("%s.%s is not defined in the package, but in a dependency", module, name)
+			return nil, exitUsage
+		}
+		found = true
+
+		e, err := refactor.Rename(tree, obj, to)
+		if err != nil {
+			out.errorf("%s", err)
+			return nil, exitUsage
+		}
+		edits = append(edits, e)
+	}
+
+	if !found {
+		out.errorf("there is no declaration %s.%s in the package", module, name)
+		return nil, exitUsage
+	}
+	return refactor.Merge(edits...), exitOK
+}
+
+// applyEdits writes the content of the file with the given edits.
+func applyEdits(path string, edits []refactor.Edit) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if content, err = refactor.Apply(content, edits); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRename(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, linted, dir)
+
+	code, out := runElmo(t, "rename", "Util.identity", "id", dir)
+	require.Equal(exitOK, code, out)
+	require.Contains(out, filepath.Join("src", "Main.elm"))
+	require.Contains(out, filepath.Join("src", "Util.elm"))
+
+	require.Equal("module Util exposing (id)\n\n\nid : a -> a\nid x =\n    x\n", readFile(t, filepath.Join(dir, "src", "Util.elm")))
+	main := readFile(t, filepath.Join(dir, "src", "Main.elm"))
+	require.Contains(main, "import Util exposing (id)\n")
+	require.Contains(main, "    id x\n")
+	require.NotContains(main, "identity")
+}
+
+func TestRenameDryRun(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, linted, dir)
+
+	code, out := runElmo(t, "rename", "--dry-run", "--report=json", "Util.identity", "id", dir)
+	require.Equal(exitOK, code, out)
+
+	events := jsonEvents(t, out)
+	last := events[len(events)-1]
+	require.Equal("rename", last["event"])
+	require.Equal(float64(2), last["files"])
+	require.Equal(true, last["dry-run"])
+	require.Contains(readFile(t, filepath.Join(dir, "src", "Util.elm")), "identity")
+}
+
+func TestRenameErrors(t *testing.T) {
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, linted, dir)
+
+	code, out := runElmo(t, "rename", "Util.identity", "view", dir)
+	require.Equal(exitUsage, code, out)
+	require.Contains(out, "view is already defined in module Main")
+
+	code, out = runElmo(t, "rename", "Util.missing", "other", dir)
+	require.Equal(exitUsage, code, out)
+	require.Contains(out, "there is no declaration Util.missing in the package")
+
+	code, out = runElmo(t, "rename", "identity", "other", dir)
+	require.Equal(exitUsage, code, out)
+	require.Contains(out, "is not a qualified name")
+
+	code, _ = runElmo(t, "rename", "Util.identity")
+	require.Equal(exitUsage, code)
+	require.Contains(readFile(t, filepath.Join(dir, "src", "Util.elm")), "identity")
+}
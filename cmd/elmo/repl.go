@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/js"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/types"
+)
+
+const replUsage = `Usage: elmo repl [path]
+
+Repl reads Elm expressions, declarations and imports from the standard input
+and evaluates them in the package at the given path, which is the current
+directory by default. The value and the type of every expression and
+definition are shown, and the declarations and imports are kept for the
+rest of the session, replacing the previous declarations with the same name.
+The values are computed with the JavaScript backend, so node must be
+installed.
+
+A line that ends with a backslash continues in the next one. The commands
+of the session are:
+
+	:help    show this message
+	:reset   forget the declarations and imports of the session
+	:exit    end the session
+
+With --report=json, the values are value events and there is no prompt.
+`
+
+// replModule is the name of the module the session is compiled to, and
+// replValue is the definition the expressions are bound to in it.
+const (
+	replModule = "Repl"
+	replValue  = "repl_value"
+)
+
+// declStart matches the inputs that look like declarations, which are
+// parsed as such. Everything else is an expression.
+var declStart = regexp.MustCompile(`^(type|port|infixl|infixr|infix)\s|^([a-z_][A-Za-z0-9_']*|\([^\s\w()]+\))(\s+[^=]*[^=<>/!])?\s*(=($|[^=])|:($|[^:]))`)
+
+// replDecl is a declaration of the session.
+type replDecl struct {
+	// names are the names declared.
+	names []string
+	// value is the definition whose value is shown, if the declaration is
+	// a single definition.
+	value string
+	src   string
+}
+
+// session is a REPL session, which is compiled each time to a module in
+// the elm-stuff directory of the package.
+type session struct {
+	dir     string
+	node    string
+	imports []string
+	decls   []replDecl
+	out     *output
+}
+
+func runRepl(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, replUsage)
+		fmt.Fprint(stderr, "\nThe flags are:\n\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		out.errorf("node is needed to evaluate the expressions, but it is not installed")
+		return exitInternal
+	}
+
+	s, err := newSession(flags.Arg(0), node, out)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	defer s.close()
+
+	in := bufio.NewScanner(stdin)
+	for {
+		input, ok := readInput(in, out)
+		if !ok {
+			out.text("\n")
+			return exitOK
+		}
+
+		switch strings.TrimSpace(input) {
+		case ":exit", ":quit":
+			return exitOK
+		case ":help":
+			out.event("help", fields{"text": replUsage}, replUsage)
+		case ":reset":
+			s.imports, s.decls = nil, nil
+		default:
+			s.eval(input)
+		}
+	}
+}
+
+// readInput reads the next input of the session, joining the lines that
+// end with a backslash. It reports false at the end of the input.
+func readInput(in *bufio.Scanner, out *output) (string, bool) {
+	var lines []string
+	prompt := "> "
+	for {
+		out.text(prompt)
+		if !in.Scan() {
+			return strings.Join(lines, "\n"), len(lines) > 0
+		}
+
+		line := in.Text()
+		if !strings.HasSuffix(line, "\\") {
+			return strings.Join(append(lines, line), "\n"), true
+		}
+
+		lines = append(lines, strings.TrimSuffix(line, "\\"))
+		prompt = "| "
+	}
+}
+
+func newSession(path, node string, out *output) (*session, error) {
+	if path == "" {
+		path = "."
+	}
+
+	p, err := pkg.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(p.Root(), "elm-stuff", "repl")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &session{dir: dir, node: node, out: out}, nil
+}
+
+// close removes the files of the session, and the elm-stuff directory if
+// it was created for the session.
+func (s *session) close() {
+	os.RemoveAll(s.dir)
+	os.Remove(filepath.Dir(s.dir))
+}
+
+// eval evaluates the given input and adds it to the session if it is an
+// import or a declaration without errors.
+func (s *session) eval(input string) {
+	trimmed := strings.TrimSpace(input)
+	switch {
+	case trimmed == "":
+	case strings.HasPrefix(trimmed, "import "):
+		imports := append(s.imports[:len(s.imports):len(s.imports)], trimmed)
+		if s.run(imports, s.decls, "") {
+			s.imports = imports
+		}
+	case declStart.MatchString(trimmed):
+		decl, ok := parseReplDecl(input, s.out)
+		if !ok {
+			return
+		}
+
+		decls := append(withoutNames(s.decls, decl.names), decl)
+		if s.run(s.imports, decls, decl.value) {
+			s.decls = decls
+		}
+	default:
+		expr := replDecl{
+			names: []string{replValue},
+			value: replValue,
+			src:   replValue + " =\n" + indent(input),
+		}
+		s.run(s.imports, append(withoutNames(s.decls, expr.names), expr), expr.value)
+	}
+}
+
+// parseReplDecl parses the declarations in the input and returns them as
+// a declaration of the session.
+func parseReplDecl(input string, out *output) (replDecl, bool) {
+	src := fmt.Sprintf("module %s exposing (..)\n\n%s\n", replModule, input)
+	mod, err := parser.ParseFrom(replModule+".elm", strings.NewReader(src), parser.SkipWarnings)
+	if err != nil {
+		out.problem(err)
+		return replDecl{}, false
+	}
+
+	var decl = replDecl{src: input}
+	for _, d := range mod.Decls {
+		switch d := d.(type) {
+		case *ast.Definition:
+			decl.names = append(decl.names, d.Name.Name)
+		case *ast.AliasDecl:
+			decl.names = append(decl.names, d.Name.Name)
+		case *ast.UnionDecl:
+			decl.names = append(decl.names, d.Name.Name)
+		case *ast.PortDecl:
+			decl.names = append(decl.names, d.Name.Name)
+		}
+	}
+
+	if len(mod.Decls) == 1 {
+		if def, ok := mod.Decls[0].(*ast.Definition); ok {
+			decl.value = def.Name.Name
+		}
+	}
+	return decl, true
+}
+
+// withoutNames returns the declarations that declare none of the names.
+func withoutNames(decls []replDecl, names []string) []replDecl {
+	var result []replDecl
+	for _, d := range decls {
+		var found bool
+		for _, n := range d.names {
+			for _, name := range names {
+				found = found || n == name
+			}
+		}
+
+		if !found {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// indent indents every line of the source code, so it can be the body of a
+// definition.
+func indent(src string) string {
+	lines := strings.Split(src, "\n")
+	for i, l := range lines {
+		lines[i] = "    " + l
+	}
+	return strings.Join(lines, "\n")
+}
+
+// run compiles the session with the given imports and declarations, and
+// shows the value and the type of the given definition, if any. It reports
+// whether there were no errors.
+func (s *session) run(imports []string, decls []replDecl, show string) bool {
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "module %s exposing (..)\n\n", replModule)
+	for _, imp := range imports {
+		fmt.Fprintln(&src, imp)
+	}
+	for _, d := range decls {
+		fmt.Fprintf(&src, "\n\n%s\n", d.src)
+	}
+
+	path := filepath.Join(s.dir, replModule+".elm")
+	if err := ioutil.WriteFile(path, src.Bytes(), 0644); err != nil {
+		s.out.errorf("%s", err)
+		return false
+	}
+
+	prog := load(path, s.out)
+	if prog == nil {
+		return false
+	}
+
+	if show == "" {
+		return true
+	}
+
+	lowered := ir.Lower(prog.ast, prog.info)
+	def := lowered.Lookup(replModule).Lookup(show)
+	if def == nil {
+		return true
+	}
+
+	// the tail calls are loops, as in the programs that are built, so the
+	// recursive functions do not exhaust the stack
+	ir.Optimize(lowered, ir.Options{})
+
+	value, err := s.value(prog, lowered, show)
+	if err != nil {
+		s.out.errorf("%s", err)
+		return false
+	}
+
+	typ := types.TypeString(def.Type)
+	s.out.event("value", fields{"value": value, "type": typ}, fmt.Sprintf("%s : %s\n", value, typ))
+	return true
+}
+
+// value evaluates the given definition of the session with node and
+// returns how it is shown. What the program logs is written to the
+// standard output of the session.
+func (s *session) value(prog *program, lowered *ir.Package, name string) (string, error) {
+	natives, err := jsNatives(prog.pkg, lowered)
+	if err != nil {
+		return "", err
+	}
+
+	var code bytes.Buffer
+	err = js.Generate(&code, lowered, js.Options{
+		Natives: natives,
+		Debug:   true,
+		Show:    []*ir.Global{{Module: replModule, Name: name}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.node)
+	cmd.Stdin = &code
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s", jsError(stderr.String(), err))
+	}
+
+	// the value is the last line, after the logs
+	out := strings.TrimSuffix(stdout.String(), "\n")
+	if i := strings.LastIndexByte(out, '\n'); i >= 0 {
+		s.out.event("log", fields{"text": out[:i]}, out[:i]+"\n")
+		out = out[i+1:]
+	}
+	return out, nil
+}
+
+// jsError returns the message of the error thrown by the script, which node
+// writes after the code where it was thrown.
+func jsError(stderr string, err error) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if i := strings.Index(line, "Error: "); i >= 0 && !strings.HasPrefix(strings.TrimSpace(line), "at ") {
+			return line[i+len("Error: "):]
+		}
+	}
+
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return stderr
+	}
+	return err.Error()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func replSession(t *testing.T, input string) (int, string) {
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(input)
+	return runElmo(t, "repl", fixture)
+}
+
+func TestRepl(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	code, out := replSession(t, strings.Join([]string{
+		"import Basics exposing (..)",
+		"1 + 2",
+		"add a b = a + b",
+		"add 2 3",
+		"type Color = Red | Green Int",
+		"Green (add 1 2)",
+		`{ name = "elmo", size = 1.5 }`,
+		`x : Int\`,
+		"x = 4",
+		"x + 1",
+		"x = 10",
+		"x",
+		":exit",
+		"x",
+	}, "\n"))
+	require.Equal(0, code, out)
+	require.Equal(strings.Join([]string{
+		"> > 3 : number",
+		"> <function> : number -> number -> number",
+		"> 5 : number",
+		"> > Green 3 : Repl.Color",
+		`> { name = "elmo", size = 1.5 } : { name : String, size : Float }`,
+		"> | 4 : Int",
+		"> 5 : Int",
+		"> 10 : number",
+		"> 10 : number",
+		"> ",
+	}, "\n"), out)
+
+	_, err := os.Stat(filepath.Join(fixture, "elm-stuff"))
+	require.True(os.IsNotExist(err))
+}
+
+func TestReplTailCalls(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	code, out := replSession(t, strings.Join([]string{
+		"import Basics exposing (..)",
+		`sumTo acc n = case n of\`,
+		`    100001 -> acc\`,
+		"    _ -> sumTo (acc + n) (n + 1)",
+		"sumTo 0 1",
+	}, "\n"))
+	require.Equal(0, code, out)
+	require.Equal(strings.Join([]string{
+		"> > | | <function> : number -> number -> number",
+		"> 5000050000 : number",
+		"> \n",
+	}, "\n"), out)
+}
+
+func TestReplErrors(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	code, out := replSession(t, strings.Join([]string{
+		"import Basics exposing (..)",
+		"x = 1",
+		"x + y",
+		`x = 1 + "two"`,
+		"x",
+		":reset",
+		"x",
+	}, "\n"))
+	require.Equal(0, code, out)
+	require.Contains(out, `name error: I could not find any definition for "y".`)
+	require.Contains(out, "type error: I was expecting this to be of type:")
+	require.Contains(out, "> 1 : number\n> ")
+	require.Contains(out, `name error: I could not find any definition for "x".`)
+
+	code, _ = runElmo(t, "repl", fixture, fixture)
+	require.Equal(2, code)
+}
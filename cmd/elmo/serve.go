@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/elm-tangram/tangram/elmo"
+)
+
+const serveUsage = `Usage: elmo serve --compiler-api [flags]
+
+Serve serves the compiler as an HTTP API until elmo is interrupted, for the
+online editors and the remote build agents that compile code sent to them.
+The code of a request is only in memory, it is never written to or read
+from the file system, and its compilation is bounded by the limits of the
+elmo package and the timeout, so the code can come from anyone.
+
+Every endpoint takes a JSON object with POST and responds with another:
+
+	/compile      compiles a package, with the request and response of
+	              elmo compile --stdin-protocol
+	/check        type checks a package, with the same request and the
+	              same response, but without artifacts
+	/format       formats a module: {"path": "src/Main.elm", "source": "..."}
+	              is {"ok": true, "formatted": "...", "diagnostics": []}
+	/diagnostics  reports the syntax errors of every module on its own,
+	              which is fast enough to do as it is typed: {"files": {...}}
+	              is {"ok": true, "diagnostics": []}
+
+The status of a response is 200 even if the code has problems, and 400 with
+just an error if the request is not valid.
+
+The flags are:
+
+`
+
+// maxRequestSize is the maximum size of the body of a request to the
+// compiler API, which is larger than the maximum size of the files because
+// they are escaped in JSON.
+const maxRequestSize = 4 << 20
+
+func runServe(args []string, stdout, stderr io.Writer) int {
+	var compilerAPI bool
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&compilerAPI, "compiler-api", false, "serve the compiler API")
+	addr := flags.String("addr", "localhost:6070", "address where the API is served")
+	timeout := flags.Duration("timeout", elmo.DefaultLimits.Timeout, "maximum time spent in a request")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, serveUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if !compilerAPI || flags.NArg() > 0 || *timeout <= 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	url := "http://" + l.Addr().String()
+	out.progress("serve", fields{"url": url}, fmt.Sprintf("serving the compiler API at %s\n", url))
+
+	limits := elmo.DefaultLimits
+	limits.Timeout = *timeout
+	srv := &http.Server{
+		Handler:      newCompilerAPI(limits),
+		ReadTimeout:  *timeout,
+		WriteTimeout: 2 * *timeout,
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		srv.Close()
+	}()
+
+	if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	return exitOK
+}
+
+// compilerAPI serves the endpoints of the serve command.
+type compilerAPI struct {
+	limits elmo.Limits
+}
+
+func newCompilerAPI(limits elmo.Limits) http.Handler {
+	a := &compilerAPI{limits}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compile", a.compile)
+	mux.HandleFunc("/check", a.check)
+	mux.Handle("/format", a.withTimeout(a.format))
+	mux.Handle("/diagnostics", a.withTimeout(a.diagnostics))
+	return mux
+}
+
+// withTimeout bounds the time of the handlers whose work has no limits of
+// its own, unlike the compilations.
+func (a *compilerAPI) withTimeout(h http.HandlerFunc) http.Handler {
+	msg := fmt.Sprintf(`{"ok": false, "error": "the request took longer than the limit of %s"}`, a.limits.Timeout)
+	return http.TimeoutHandler(h, a.limits.Timeout, msg)
+}
+
+// formatRequest is the request of the format endpoint.
+type formatRequest struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`
+}
+
+// formatResponse is the response of the format endpoint.
+type formatResponse struct {
+	OK          bool              `json:"ok"`
+	Error       string            `json:"error,omitempty"`
+	Formatted   string            `json:"formatted"`
+	Diagnostics []elmo.Diagnostic `json:"diagnostics"`
+}
+
+// diagnosticsRequest is the request of the diagnostics endpoint.
+type diagnosticsRequest struct {
+	Files map[string]string `json:"files"`
+}
+
+// diagnosticsResponse is the response of the diagnostics endpoint.
+type diagnosticsResponse struct {
+	OK          bool              `json:"ok"`
+	Error       string            `json:"error,omitempty"`
+	Diagnostics []elmo.Diagnostic `json:"diagnostics"`
+}
+
+func (a *compilerAPI) compile(w http.ResponseWriter, r *http.Request) {
+	a.compileFiles(w, r, false)
+}
+
+func (a *compilerAPI) check(w http.ResponseWriter, r *http.Request) {
+	a.compileFiles(w, r, true)
+}
+
+func (a *compilerAPI) compileFiles(w http.ResponseWriter, r *http.Request, check bool) {
+	if !allowPost(w, r) {
+		return
+	}
+
+	var req compileRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeJSONStatus(w, http.StatusBadRequest, compileResponse{Error: err.Error()}.normalize())
+		return
+	}
+
+	resp, code := compileFiles(req, a.limits, check)
+	if code == exitUsage {
+		writeJSONStatus(w, http.StatusBadRequest, resp.normalize())
+		return
+	}
+	writeJSON(w, resp.normalize())
+}
+
+func (a *compilerAPI) format(w http.ResponseWriter, r *http.Request) {
+	if !allowPost(w, r) {
+		return
+	}
+
+	var req formatRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeJSONStatus(w, http.StatusBadRequest, formatResponse{Error: err.Error(), Diagnostics: []elmo.Diagnostic{}})
+		return
+	}
+
+	formatted, diags := elmo.FormatString(req.Path, req.Source)
+	if diags == nil {
+		diags = []elmo.Diagnostic{}
+	}
+	writeJSON(w, formatResponse{OK: !hasErrors(diags), Formatted: formatted, Diagnostics: diags})
+}
+
+func (a *compilerAPI) diagnostics(w http.ResponseWriter, r *http.Request) {
+	if !allowPost(w, r) {
+		return
+	}
+
+	var req diagnosticsRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeJSONStatus(w, http.StatusBadRequest, diagnosticsResponse{Error: err.Error(), Diagnostics: []elmo.Diagnostic{}})
+		return
+	}
+
+	if a.limits.MaxFiles > 0 && len(req.Files) > a.limits.MaxFiles {
+		msg := fmt.Sprintf("there are %d files, but the limit is %d", len(req.Files), a.limits.MaxFiles)
+		writeJSONStatus(w, http.StatusBadRequest, diagnosticsResponse{Error: msg, Diagnostics: []elmo.Diagnostic{}})
+		return
+	}
+
+	diags := elmo.ParseString(req.Files)
+	if diags == nil {
+		diags = []elmo.Diagnostic{}
+	}
+	writeJSON(w, diagnosticsResponse{OK: !hasErrors(diags), Diagnostics: diags})
+}
+
+// allowPost reports whether the request is a POST, and responds with an
+// error if it is not.
+func allowPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodPost {
+		return true
+	}
+
+	w.Header().Set("Allow", http.MethodPost)
+	http.Error(w, "the endpoints of the compiler API only accept POST", http.StatusMethodNotAllowed)
+	return false
+}
+
+// readRequest reads the JSON body of the request into v, up to the
+// maxRequestSize.
+func readRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return decodeRequest(http.MaxBytesReader(w, r.Body, maxRequestSize), v)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elm-tangram/tangram/elmo"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, url string, body io.Reader, resp interface{}) int {
+	rec := httptest.NewRecorder()
+	newCompilerAPI(elmo.Limits{MaxFiles: 64, Timeout: 10 * time.Second}).ServeHTTP(rec, httptest.NewRequest("POST", url, body))
+	if rec.Header().Get("Content-Type") == "application/json" {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), resp), rec.Body.String())
+	}
+	return rec.Code
+}
+
+func TestServeCompile(t *testing.T) {
+	require := require.New(t)
+
+	files := packageFiles(t, fixture)
+	var resp compileResponse
+	code := serve(t, "/compile", compileRequestOf(t, files, `{"target": "html", "output": "index.html"}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.True(resp.OK, resp.Error)
+	require.Len(resp.Artifacts, 1)
+	require.Contains(resp.Artifacts[0].Content, "<html")
+
+	resp = compileResponse{}
+	code = serve(t, "/check", compileRequestOf(t, files, `{"target": "wasm"}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.True(resp.OK, resp.Error)
+	require.Empty(resp.Artifacts)
+
+	files["src/Main.elm"] = strings.Replace(files["src/Main.elm"], "main", "main =", 1)
+	resp = compileResponse{}
+	code = serve(t, "/check", compileRequestOf(t, files, `{}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.False(resp.OK)
+	require.Equal("src/Main.elm", resp.Diagnostics[0].File)
+
+	resp = compileResponse{}
+	code = serve(t, "/compile", strings.NewReader(`{"files": {}}`), &resp)
+	require.Equal(http.StatusBadRequest, code)
+	require.Equal("the request has no entry file", resp.Error)
+
+	resp = compileResponse{}
+	code = serve(t, "/compile", strings.NewReader(`{"files": {}, "entry": "Main.elm", "extra": 1}`), &resp)
+	require.Equal(http.StatusBadRequest, code)
+	require.Contains(resp.Error, "invalid request")
+}
+
+func TestServeFormat(t *testing.T) {
+	require := require.New(t)
+
+	var resp formatResponse
+	code := serve(t, "/format", strings.NewReader(`{"path": "src/Main.elm", "source": "module Main exposing (..)\nanswer = 42\n"}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.True(resp.OK)
+	require.Equal("module Main exposing (..)\n\n\nanswer =\n    42\n", resp.Formatted)
+
+	resp = formatResponse{}
+	code = serve(t, "/format", strings.NewReader(`{"path": "src/Main.elm", "source": "module Main exposing (..)\nanswer =\n"}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.False(resp.OK)
+	require.Empty(resp.Formatted)
+	require.Equal("syntax error", resp.Diagnostics[0].Type)
+}
+
+func TestServeDiagnostics(t *testing.T) {
+	require := require.New(t)
+
+	var resp diagnosticsResponse
+	code := serve(t, "/diagnostics", strings.NewReader(`{"files": {"src/Main.elm": "module Main exposing (..)\n\nimport Missing\n"}}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.True(resp.OK)
+	require.Empty(resp.Diagnostics)
+
+	resp = diagnosticsResponse{}
+	code = serve(t, "/diagnostics", strings.NewReader(`{"files": {"src/Main.elm": "module Main exposing (..)\n\n\nanswer =\n    (1\n"}}`), &resp)
+	require.Equal(http.StatusOK, code)
+	require.False(resp.OK)
+	require.Len(resp.Diagnostics, 1)
+	require.Equal("src/Main.elm", resp.Diagnostics[0].File)
+}
+
+func TestServeInvalid(t *testing.T) {
+	require := require.New(t)
+
+	rec := httptest.NewRecorder()
+	newCompilerAPI(elmo.DefaultLimits).ServeHTTP(rec, httptest.NewRequest("GET", "/compile", nil))
+	require.Equal(http.StatusMethodNotAllowed, rec.Code)
+	require.Equal("POST", rec.Header().Get("Allow"))
+
+	var resp diagnosticsResponse
+	big := `{"files": {"Main.elm": "` + strings.Repeat(" ", maxRequestSize) + `"}}`
+	code := serve(t, "/diagnostics", strings.NewReader(big), &resp)
+	require.Equal(http.StatusBadRequest, code)
+	require.Contains(resp.Error, "invalid request")
+
+	code, _ = runElmo(t, "serve")
+	require.Equal(exitUsage, code)
+}
@@ -0,0 +1,1167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/golang"
+	"github.com/elm-tangram/tangram/codegen/js"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/coverage"
+	"github.com/elm-tangram/tangram/doc"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+const testUsage = `Usage: elmo test [flags] [path]
+
+Test runs the tests of the package at the given path, which is the current
+directory by default. The tests are in the tests directory of the package,
+which is a package itself, and every module in it is a test module.
+
+The tests are the exposed values of the test modules whose names start with
+test. A test of type Bool is an assertion that passes if it is True, and a
+test of type List Bool has an assertion for every element of the list. The
+Expect module, which is compiled with the tests, has assertions of type
+Expectation that tell why they failed, such as Expect.equal 4 (double 2),
+and a test can be an Expectation or a List Expectation as well.
+
+A test that is a function of Int, Float, Bool and String arguments returning
+Bool or Expectation is a fuzz test, which is called with random arguments
+generated from the seed. The arguments of every failing call are shrunk,
+running it again with smaller ones, to report the simplest call that fails.
+
+The tests are compiled with the backend of the target, js or go, and run
+with node or go, which must be installed. The position of every failing
+assertion is reported, with its message if it is an expectation.
+
+With --coverage, the function definitions and the branches of the case and
+if expressions of the modules of the package that the tests evaluate are
+recorded. The percentage of them evaluated in every module is reported,
+and the report is written to the given file in the lcov format.
+
+With --doc, the code examples in the doc comments of the exposed modules of
+the package are tested as well, in the fenced code blocks whose language is
+elm or none. They are compiled against the exposed API of their module, and
+every line such as double 2 ==> 4 is an assertion that the expression equals
+the value after the arrow, which is reported at its position in the doc
+comment. The lines that are not indented start the definitions the
+assertions of the example use, or its imports, and the indented lines
+continue them.
+
+The tests can import the modules tagged test in the module-tags of the
+manifest, such as fixtures and helpers, which the programs of the package
+cannot, and the tagged modules are left out of the coverage.
+
+The flags are:
+
+`
+
+// testRunner is the name of the module that runs the tests, which is
+// compiled in the elm-stuff directory of the tests.
+const testRunner = "TestRunner"
+
+// maxShrinks is the number of times the arguments of a failing fuzz call
+// are shrunk at most.
+const maxShrinks = 100
+
+// testOptions are the flags of the test command.
+type testOptions struct {
+	target   string
+	natives  string
+	filter   string
+	seed     int64
+	fuzz     int
+	coverage string
+	doc      bool
+}
+
+// assertion is a boolean of a test, which passes if it is True.
+type assertion struct {
+	// name identifies the assertion in the report.
+	name string
+	// pos is the position of the assertion in the source code.
+	pos string
+	// call is the call of the fuzz test of the assertion, if it is one.
+	call *fuzzCall
+}
+
+// testCase is a test value, whose assertions are run together.
+type testCase struct {
+	// expr is the Elm code of the failures of the assertions in the runner
+	// module, which is a List (List String).
+	expr       string
+	assertions []assertion
+}
+
+// failure is the result of an assertion: empty if it passed, or with the
+// message of why it failed, which is empty for the ones of type Bool.
+type failure []string
+
+func (f failure) failed() bool {
+	return len(f) > 0
+}
+
+func (f failure) message() string {
+	if len(f) == 0 {
+		return ""
+	}
+	return f[0]
+}
+
+func runTest(args []string, stdout, stderr io.Writer) int {
+	var opts testOptions
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.target, "target", "js", "backend the tests are compiled with: js or go")
+	flags.StringVar(&opts.natives, "natives", "", "import path of the Go package of the native modules, for the go target")
+	flags.StringVar(&opts.filter, "filter", "", "regular expression the names of the tests, such as Module.testName, must match to be run")
+	flags.Int64Var(&opts.seed, "seed", 0, "seed of the random arguments of the fuzz tests (default random)")
+	flags.IntVar(&opts.fuzz, "fuzz", 100, "number of times every fuzz test is called")
+	flags.StringVar(&opts.coverage, "coverage", "", "file the lcov report of the coverage of the package by the tests is written to")
+	flags.BoolVar(&opts.doc, "doc", false, "test the examples in the doc comments of the exposed modules too")
+	outOpts := outputFlags(flags)
+	prof := profileFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, testUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	stop, ok := prof.start(out)
+	if !ok {
+		return exitInternal
+	}
+	defer stop()
+
+	t, err := target.Parse(opts.target)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitUsage
+	}
+
+	if t != target.JS && t != target.Go {
+		out.errorf("tests cannot be run with the %s target", t)
+		return exitUsage
+	}
+
+	filter, err := regexp.Compile(opts.filter)
+	if err != nil {
+		out.errorf("invalid filter: %s", err)
+		return exitUsage
+	}
+
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+
+	path := flags.Arg(0)
+	if path == "" {
+		path = "."
+	}
+
+	p, err := pkg.Load(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	dir := filepath.Join(p.Root(), "tests")
+	modules, err := testModules(dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if len(modules) == 0 {
+		out.errorf("there are no test modules in %s", dir)
+		return exitInternal
+	}
+
+	stuff := filepath.Join(dir, "elm-stuff", "test")
+	if err := os.MkdirAll(stuff, 0755); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	defer func() {
+		os.RemoveAll(stuff)
+		os.Remove(filepath.Dir(stuff))
+	}()
+
+	tp, err := pkg.Load(dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if err := writeTestPackage(tp, stuff); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var docs map[string]*doc.TestModule
+	if opts.doc {
+		if docs, ok = writeDocTests(p, stuff, out); !ok {
+			return out.failure()
+		}
+
+		for _, m := range docs {
+			modules = append(modules, m.Name)
+		}
+		sort.Strings(modules)
+	}
+
+	runner := filepath.Join(stuff, testRunner+".elm")
+	tests, err := findTests(runner, modules, docs, filter, opts, out)
+	if err != nil {
+		if err != errReported {
+			out.errorf("%s", err)
+		}
+		return out.failure()
+	}
+
+	if len(tests) == 0 {
+		out.progress("result", fields{"passed": 0, "failed": 0, "seed": opts.seed}, "no tests to run\n")
+		return exitOK
+	}
+
+	results, cov, err := runTests(runner, modules, tests, t, opts, out)
+	if err != nil {
+		if err != errReported {
+			out.errorf("%s", err)
+		}
+		return out.failure()
+	}
+
+	if err := shrinkFailures(runner, modules, tests, results, t, opts, out); err != nil {
+		if err != errReported {
+			out.errorf("%s", err)
+		}
+		return out.failure()
+	}
+
+	var total, failed int
+	for i, tc := range tests {
+		for j, a := range tc.assertions {
+			total++
+			if results[i][j].failed() {
+				failed++
+				reportFailure(a, results[i][j], out)
+			}
+		}
+	}
+
+	if cov != nil {
+		if err := writeCoverage(p, cov, opts.coverage, out); err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+	}
+
+	result := fields{"passed": total - failed, "failed": failed, "seed": opts.seed}
+	if failed > 0 {
+		out.event("result", result, fmt.Sprintf("FAIL: %d of %d assertions failed (seed %d)\n", failed, total, opts.seed))
+		return exitProblems
+	}
+
+	out.progress("result", result, fmt.Sprintf("ok: %d assertions passed (seed %d)\n", total, opts.seed))
+	return exitOK
+}
+
+// reportFailure reports the failed assertion, with its message if it has
+// one. The fuzz calls are the shrunk ones, which say the original call.
+func reportFailure(a assertion, f failure, out *output) {
+	ev := fields{"test": a.name, "pos": a.pos}
+	text := fmt.Sprintf("--- FAIL: %s at %s", a.name, a.pos)
+	if a.call != nil && a.call.original != "" {
+		ev["shrunk"] = a.call.original
+		text += fmt.Sprintf(" (shrunk from %s)", a.call.original)
+	}
+	text += "\n"
+
+	if msg := f.message(); msg != "" {
+		ev["message"] = msg
+		text += "    " + strings.Replace(msg, "\n", "\n    ", -1) + "\n"
+	}
+	out.event("fail", ev, text)
+}
+
+// errReported is returned when the errors were already written to the
+// output.
+var errReported = fmt.Errorf("errors were reported")
+
+// testModules returns the names of the modules in the tests directory,
+// sorted. The elm-stuff directory is skipped.
+func testModules(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("there is no tests directory in %s", filepath.Dir(dir))
+	}
+
+	files, err := elmFiles([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []string
+	for _, f := range files {
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return nil, err
+		}
+
+		rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+		modules = append(modules, strings.Replace(rel, string(filepath.Separator), ".", -1))
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// writeTestPackage writes the package the runner is compiled in to the
+// given directory: the Expect module and a manifest like the one of the tests,
+// with the same dependencies, whose source directories are the directory
+// itself and the ones of the tests.
+func writeTestPackage(tests *pkg.Package, dir string) error {
+	manifest := *tests
+	// the runner imports every module of the tests, whatever their tags
+	manifest.ModuleTags = nil
+	manifest.SourceDirectories = []string{"."}
+	for _, src := range tests.SourceDirectories {
+		if !filepath.IsAbs(src) {
+			rel, err := filepath.Rel(dir, filepath.Join(tests.Root(), src))
+			if err != nil {
+				return err
+			}
+			src = rel
+		}
+		manifest.SourceDirectories = append(manifest.SourceDirectories, src)
+	}
+
+	if err := writeManifest(filepath.Join(dir, "elm-package.json"), &manifest); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "Expect.elm"), []byte(expectModule), 0644); err != nil {
+		return err
+	}
+
+	if tests.ExactDependencies == nil {
+		return nil
+	}
+
+	// the dependencies are found in the elm-stuff directory of the root
+	stuff := filepath.Join(dir, "elm-stuff")
+	if err := os.MkdirAll(stuff, 0755); err != nil {
+		return err
+	}
+
+	if err := writeManifest(filepath.Join(stuff, "exact-dependencies.json"), tests.ExactDependencies); err != nil {
+		return err
+	}
+
+	packages, err := filepath.Abs(filepath.Join(tests.Root(), "elm-stuff", "packages"))
+	if err != nil {
+		return err
+	}
+	return os.Symlink(packages, filepath.Join(stuff, "packages"))
+}
+
+// writeManifest writes the value as indented JSON, the way elm-package.json
+// and exact-dependencies.json are written.
+func writeManifest(path string, v interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writeRunner writes the runner module, which imports the test modules and
+// has a definition of type List (List String) for every test, with the
+// failures of its assertions.
+func writeRunner(path string, modules []string, tests []testCase) error {
+	var src bytes.Buffer
+	// Basics and List are imported for the negative arguments of the fuzz
+	// tests and the lists of failures, even in the core package, which has
+	// no default imports
+	fmt.Fprintf(&src, "module %s exposing (..)\n\nimport Basics exposing (..)\nimport List exposing ((::))\nimport Expect\n", testRunner)
+	for _, m := range modules {
+		fmt.Fprintf(&src, "import %s\n", m)
+	}
+	src.WriteString(runnerHelpers)
+
+	for i, tc := range tests {
+		fmt.Fprintf(&src, "\n\n%s : List (List String)\n%s =\n    %s\n", runnerValue(i), runnerValue(i), tc.expr)
+	}
+	return ioutil.WriteFile(path, src.Bytes(), 0644)
+}
+
+// runnerHelpers are the definitions of the runner that turn the assertions
+// into their failures.
+const runnerHelpers = `
+
+fromBool : Bool -> List String
+fromBool passed =
+    if passed then
+        []
+    else
+        [ "" ]
+
+
+fromExpectation : Expect.Expectation -> List String
+fromExpectation expectation =
+    case expectation of
+        Expect.Pass ->
+            []
+
+        Expect.Fail message ->
+            [ message ]
+
+
+fromBools : List Bool -> List (List String)
+fromBools list =
+    case list of
+        [] ->
+            []
+
+        passed :: rest ->
+            fromBool passed :: fromBools rest
+
+
+fromExpectations : List Expect.Expectation -> List (List String)
+fromExpectations list =
+    case list of
+        [] ->
+            []
+
+        expectation :: rest ->
+            fromExpectation expectation :: fromExpectations rest
+`
+
+// runnerValue returns the name of the definition of the runner with the
+// assertions of the test with the given index.
+func runnerValue(i int) string {
+	return fmt.Sprintf("test_%d", i)
+}
+
+// writeDocTests writes the test modules of the examples of the exposed
+// modules of the package to the given directory, and returns them by their
+// name. The modules without examples have none.
+func writeDocTests(p *pkg.Package, dir string, out *output) (map[string]*doc.TestModule, bool) {
+	var docs = make(map[string]*doc.TestModule)
+	for _, name := range p.ExposedModules {
+		path, err := p.FindSourceModule(name)
+		if _, ok := err.(*pkg.ExcludedModuleError); ok {
+			continue
+		} else if err != nil {
+			out.errorf("can't find exposed module %s: %s", name, err)
+			return nil, false
+		}
+
+		prog := loadMode(path, parser.FullParse|parser.ParseComments, out)
+		if prog == nil {
+			return nil, false
+		}
+
+		mod := prog.ast.Modules[prog.main]
+		examples := doc.Examples(mod)
+		if len(examples) == 0 {
+			continue
+		}
+
+		m := doc.NewTestModule(mod, examples)
+		path = filepath.Join(dir, strings.Replace(m.Name, ".", string(filepath.Separator), -1)+".elm")
+		if err := writeOutput(path, m.Source); err != nil {
+			out.errorf("%s", err)
+			return nil, false
+		}
+		docs[m.Name] = m
+	}
+	return docs, true
+}
+
+// findTests compiles a runner that imports the test modules, and returns
+// the tests in them that match the filter. The assertions of the test
+// modules of the examples are at the positions of their expectations in
+// the doc comments.
+func findTests(runner string, modules []string, docs map[string]*doc.TestModule, filter *regexp.Regexp, opts testOptions, out *output) ([]testCase, error) {
+	if err := writeRunner(runner, modules, nil); err != nil {
+		return nil, err
+	}
+
+	prog := loadFor(runner, target.Target(opts.target), out)
+	if prog == nil {
+		return nil, errReported
+	}
+
+	cm := source.NewCodeMap(source.NewFsLoader(prog.pkg))
+	defer cm.Close()
+
+	lowered := ir.Lower(prog.ast, prog.info)
+	var tests []testCase
+	var rng = rand.New(rand.NewSource(opts.seed))
+	for _, m := range modules {
+		mod := prog.ast.Modules[m]
+		for _, d := range mod.Decls {
+			def, ok := d.(*ast.Definition)
+			if !ok || !strings.HasPrefix(def.Name.Name, "test") {
+				continue
+			}
+
+			value := lowered.Lookup(m).Lookup(def.Name.Name)
+			if value == nil || !value.Exposed {
+				continue
+			}
+
+			name := m + "." + def.Name.Name
+			if !filter.MatchString(name) {
+				continue
+			}
+
+			pos := func(p token.Pos) string {
+				return position(cm, mod.Path, p)
+			}
+
+			if dt, ok := docs[m]; ok {
+				expectation := dt.Tests[def.Name.Name]
+				pos = func(token.Pos) string {
+					return position(cm, dt.Path, expectation)
+				}
+			}
+
+			tc, err := newTestCase(name, value.Type, def, pos, rng, opts.fuzz)
+			if err != nil {
+				// the type of the test is a problem of the Elm code, not
+				// of elmo
+				out.problem(err)
+				return nil, errReported
+			}
+			tests = append(tests, tc)
+		}
+	}
+	return tests, nil
+}
+
+// position returns the position in the source code as path:line:column, or
+// just the path if it cannot be found.
+func position(cm *source.CodeMap, path string, pos token.Pos) string {
+	if err := cm.Add(path); err != nil {
+		return path
+	}
+
+	lp, err := cm.Source(path).LinePos(pos)
+	if err != nil {
+		return path
+	}
+	return fmt.Sprintf("%s:%d:%d", path, lp.Line, lp.Col)
+}
+
+// newTestCase returns the test of the given definition with its
+// assertions, depending on its type.
+func newTestCase(name string, typ types.Type, def *ast.Definition, pos func(token.Pos) string, rng *rand.Rand, fuzz int) (testCase, error) {
+	var tc testCase
+	switch {
+	case isBool(typ), isExpectation(typ):
+		tc.expr = "[ " + resultOf(typ) + " " + name + " ]"
+		tc.assertions = []assertion{{name: name, pos: pos(def.Body.Pos())}}
+	case isList(typ, isBool), isList(typ, isExpectation):
+		list, ok := def.Body.(*ast.ListLit)
+		if !ok {
+			return tc, fmt.Errorf("%s: %s must be a list literal to be a test of type %s", pos(def.Pos()), name, types.TypeString(typ))
+		}
+
+		tc.expr = "fromBools " + name
+		if isList(typ, isExpectation) {
+			tc.expr = "fromExpectations " + name
+		}
+
+		for i, e := range list.Elems {
+			tc.assertions = append(tc.assertions, assertion{
+				name: fmt.Sprintf("%s[%d]", name, i),
+				pos:  pos(e.Pos()),
+			})
+		}
+	default:
+		args, result, ok := fuzzArgs(typ)
+		if !ok {
+			return tc, fmt.Errorf("%s: %s has type %s, but tests must be Bool, Expectation, lists of them or functions of Int, Float, Bool and String that return them", pos(def.Pos()), name, types.TypeString(typ))
+		}
+
+		for i := 0; i < fuzz; i++ {
+			call := &fuzzCall{fn: name, result: resultOf(result)}
+			for _, arg := range args {
+				call.args = append(call.args, randomValue(arg, rng))
+			}
+			tc.assertions = append(tc.assertions, assertion{name: call.String(), pos: pos(def.Body.Pos()), call: call})
+		}
+		tc.expr = callsExpr(tc.assertions)
+	}
+	return tc, nil
+}
+
+// resultOf returns the name of the function of the runner that turns an
+// assertion of the given type into its failures.
+func resultOf(t types.Type) string {
+	if isExpectation(t) {
+		return "fromExpectation"
+	}
+	return "fromBool"
+}
+
+// callsExpr returns the Elm code of the failures of the fuzz calls of the
+// assertions.
+func callsExpr(assertions []assertion) string {
+	var calls = make([]string, len(assertions))
+	for i, a := range assertions {
+		calls[i] = a.call.result + " (" + a.call.String() + ")"
+	}
+	return "[ " + strings.Join(calls, ", ") + " ]"
+}
+
+// isBool reports whether the type is Bool.
+func isBool(t types.Type) bool {
+	named, ok := types.Resolve(t).(*types.Named)
+	return ok && named.Module == "" && named.Name == "Bool"
+}
+
+// isExpectation reports whether the type is the Expectation of the Expect
+// module.
+func isExpectation(t types.Type) bool {
+	named, ok := types.Resolve(t).(*types.Named)
+	return ok && named.Module == "Expect" && named.Name == "Expectation"
+}
+
+// isList reports whether the type is a list of elements for which elem
+// reports true.
+func isList(t types.Type, elem func(types.Type) bool) bool {
+	named, ok := types.Resolve(t).(*types.Named)
+	return ok && named.Module == "" && named.Name == "List" && len(named.Args) == 1 && elem(named.Args[0])
+}
+
+// fuzzArgs returns the names of the argument types of a fuzz test and its
+// result type, and reports whether the type is the one of a fuzz test.
+func fuzzArgs(t types.Type) ([]string, types.Type, bool) {
+	var args []string
+	for {
+		fn, ok := types.Resolve(t).(*types.Func)
+		if !ok {
+			return args, t, len(args) > 0 && (isBool(t) || isExpectation(t))
+		}
+
+		named, ok := types.Resolve(fn.Arg).(*types.Named)
+		if !ok || named.Module != "" {
+			return nil, nil, false
+		}
+
+		switch named.Name {
+		case "Int", "Float", "Bool", "String":
+			args = append(args, named.Name)
+		default:
+			return nil, nil, false
+		}
+		t = fn.Result
+	}
+}
+
+// fuzzCall is a call of a fuzz test with some arguments, which are int,
+// float64, bool or string values.
+type fuzzCall struct {
+	fn   string
+	args []interface{}
+	// result is the function of the runner that turns the result of the
+	// call into its failures.
+	result string
+	// original is the first call that failed, if this one was shrunk from
+	// it.
+	original string
+}
+
+// String returns the Elm code of the call.
+func (c *fuzzCall) String() string {
+	var call = c.fn
+	for _, arg := range c.args {
+		call += " " + elmValue(arg)
+	}
+	return call
+}
+
+// shrink returns the calls with the arguments shrunk, one at a time, from
+// the simplest to the most similar to the call.
+func (c *fuzzCall) shrink() []*fuzzCall {
+	var calls []*fuzzCall
+	for i, arg := range c.args {
+		for _, v := range shrinkValue(arg) {
+			call := &fuzzCall{fn: c.fn, result: c.result, args: make([]interface{}, len(c.args))}
+			copy(call.args, c.args)
+			call.args[i] = v
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// fuzzChars are the characters of the random strings of the fuzz tests.
+const fuzzChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+// randomValue returns a random value of the given type.
+func randomValue(typ string, rng *rand.Rand) interface{} {
+	switch typ {
+	case "Int":
+		return rng.Intn(2001) - 1000
+	case "Float":
+		return rng.Float64()*2000 - 1000
+	case "Bool":
+		return rng.Intn(2) == 0
+	default:
+		var chars = make([]byte, rng.Intn(11))
+		for i := range chars {
+			chars[i] = fuzzChars[rng.Intn(len(fuzzChars))]
+		}
+		return string(chars)
+	}
+}
+
+// shrinkValue returns the values simpler than the given one, from the
+// simplest: the numbers closer to zero, False and the shorter strings.
+func shrinkValue(v interface{}) []interface{} {
+	var values []interface{}
+	switch v := v.(type) {
+	case int:
+		if v == 0 {
+			return nil
+		}
+
+		values = append(values, 0)
+		if v < 0 {
+			values = append(values, -v)
+		}
+
+		for _, n := range []int{v / 2, v - v/abs(v)} {
+			if !containsValue(values, n) {
+				values = append(values, n)
+			}
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+
+		values = append(values, 0.0)
+		if v < 0 {
+			values = append(values, -v)
+		}
+
+		if f := math.Trunc(v); f != v && !containsValue(values, f) {
+			values = append(values, f)
+		}
+
+		// the halves of the fractions would never end
+		if f := v / 2; math.Abs(v) >= 1 && !containsValue(values, f) {
+			values = append(values, f)
+		}
+	case bool:
+		if v {
+			values = append(values, false)
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+
+		values = append(values, "")
+		half := len(v) / 2
+		for _, s := range []string{v[:half], v[half:]} {
+			if s != v && !containsValue(values, s) {
+				values = append(values, s)
+			}
+		}
+
+		for i := range v {
+			if s := v[:i] + v[i+1:]; !containsValue(values, s) {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// elmValue returns the Elm code of the value of a fuzz call, which is
+// parenthesized if it is negative.
+func elmValue(value interface{}) string {
+	var v string
+	switch value := value.(type) {
+	case int:
+		v = strconv.Itoa(value)
+	case float64:
+		v = strconv.FormatFloat(value, 'f', -1, 64)
+		if !strings.Contains(v, ".") {
+			v += ".0"
+		}
+	case bool:
+		if v = "False"; value {
+			v = "True"
+		}
+	case string:
+		return strconv.Quote(value)
+	}
+
+	if strings.HasPrefix(v, "-") {
+		return "(" + v + ")"
+	}
+	return v
+}
+
+// shrinkFailures shrinks the arguments of the failing fuzz calls of the
+// tests until none of the simpler calls fails, and replaces them and
+// their results with the simplest calls that still fail. The simpler
+// calls of all of them are run together, so there is a run per step.
+func shrinkFailures(runner string, modules []string, tests []testCase, results [][]failure, t target.Target, opts testOptions, out *output) error {
+	type shrinking struct {
+		test, assertion int
+		candidates      []*fuzzCall
+	}
+
+	var pending []*shrinking
+	for i, tc := range tests {
+		for j, a := range tc.assertions {
+			if a.call != nil && results[i][j].failed() {
+				pending = append(pending, &shrinking{test: i, assertion: j})
+			}
+		}
+	}
+
+	for step := 0; step < maxShrinks && len(pending) > 0; step++ {
+		var candidates []testCase
+		var shrinkings []*shrinking
+		for _, s := range pending {
+			s.candidates = tests[s.test].assertions[s.assertion].call.shrink()
+			if len(s.candidates) == 0 {
+				continue
+			}
+
+			var tc testCase
+			for _, c := range s.candidates {
+				tc.assertions = append(tc.assertions, assertion{call: c})
+			}
+			tc.expr = callsExpr(tc.assertions)
+			candidates = append(candidates, tc)
+			shrinkings = append(shrinkings, s)
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		shrunk, _, err := runTests(runner, modules, candidates, t, opts, out)
+		if err != nil {
+			return err
+		}
+
+		pending = nil
+		for i, s := range shrinkings {
+			for j, c := range s.candidates {
+				if !shrunk[i][j].failed() {
+					continue
+				}
+
+				a := &tests[s.test].assertions[s.assertion]
+				if c.original = a.call.original; c.original == "" {
+					c.original = a.call.String()
+				}
+				a.call = c
+				a.name = c.String()
+				results[s.test][s.assertion] = shrunk[i][j]
+				pending = append(pending, s)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// testCoverage is the coverage of a run of the tests: the points of the
+// instrumented program and the counts of their probes.
+type testCoverage struct {
+	points []*ir.Point
+	counts []int
+}
+
+// runTests compiles the runner with the tests for the target, runs it and
+// returns the failures of every assertion of every test. The program is
+// instrumented if the coverage is measured, and its coverage is returned
+// too.
+func runTests(runner string, modules []string, tests []testCase, t target.Target, opts testOptions, out *output) ([][]failure, *testCoverage, error) {
+	if err := writeRunner(runner, modules, tests); err != nil {
+		return nil, nil, err
+	}
+
+	prog := loadFor(runner, t, out)
+	if prog == nil {
+		return nil, nil, errReported
+	}
+
+	var lowered *ir.Package
+	if opts.coverage != "" {
+		lowered = ir.Instrument(prog.ast, prog.info)
+	} else {
+		lowered = ir.Lower(prog.ast, prog.info)
+	}
+	ir.Optimize(lowered, ir.Options{})
+
+	var show = make([]*ir.Global, len(tests))
+	for i := range tests {
+		show[i] = &ir.Global{Module: testRunner, Name: runnerValue(i), Typ: types.NewList(types.NewList(types.NewNamed("", "String")))}
+	}
+
+	var logs string
+	var err error
+	if t == target.Go {
+		if err := prog.checkNatives(); err != nil {
+			return nil, nil, err
+		}
+		logs, err = runGoTests(filepath.Dir(runner), lowered, show, opts)
+	} else {
+		logs, err = runJSTests(prog.pkg, lowered, show)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the results are the last lines, after the logs, and the counts of
+	// the probes are after them
+	var outputs = len(tests)
+	if len(lowered.Points) > 0 {
+		outputs++
+	}
+
+	lines := strings.Split(strings.TrimSuffix(logs, "\n"), "\n")
+	if len(lines) < outputs {
+		return nil, nil, fmt.Errorf("the tests did not output their results:\n%s", logs)
+	}
+
+	lines = lines[len(lines)-outputs:]
+	var results = make([][]failure, len(tests))
+	for i := range tests {
+		if err := json.Unmarshal([]byte(lines[i]), &results[i]); err != nil || len(results[i]) != len(tests[i].assertions) {
+			return nil, nil, fmt.Errorf("expecting %d results for %s, but got %q", len(tests[i].assertions), runnerValue(i), lines[i])
+		}
+	}
+
+	if len(lowered.Points) == 0 {
+		return results, nil, nil
+	}
+
+	cov := &testCoverage{points: lowered.Points}
+	if err := json.Unmarshal([]byte(lines[len(tests)]), &cov.counts); err != nil {
+		return nil, nil, fmt.Errorf("the tests did not output their coverage: %q", lines[len(tests)])
+	}
+	return results, cov, nil
+}
+
+// writeCoverage writes the lcov report of the coverage of the modules of
+// the package, not the ones of the tests or the dependencies, to the file
+// at the given path, and reports the percentage of every module.
+func writeCoverage(p *pkg.Package, cov *testCoverage, path string, out *output) error {
+	report, err := coverage.New(cov.points, cov.counts, func(module string) bool {
+		return isSourceModule(p, module, cov.points)
+	})
+	if err != nil {
+		return err
+	}
+
+	cm := source.NewCodeMap(source.NewFsLoader(p))
+	defer cm.Close()
+
+	var buf bytes.Buffer
+	if err := report.WriteLcov(&buf, cm); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	for _, m := range report.Modules {
+		covered, total := m.Covered()
+		out.event(
+			"coverage",
+			fields{"module": m.Name, "covered": covered, "total": total, "percent": m.Percent()},
+			fmt.Sprintf("coverage: %.1f%% of %s (%d of %d)\n", m.Percent(), m.Name, covered, total),
+		)
+	}
+	return nil
+}
+
+// isSourceModule reports whether the module of the points is in the source
+// directories of the package.
+func isSourceModule(p *pkg.Package, module string, points []*ir.Point) bool {
+	path, err := p.FindSourceModule(module)
+	if err != nil {
+		return false
+	}
+
+	for _, point := range points {
+		if point.Module == module {
+			return filepath.Clean(point.Path) == filepath.Clean(path)
+		}
+	}
+	return false
+}
+
+// runJSTests runs the tests with node, which shows the lists of failures as
+// Elm values, such as [[],[""]], which are JSON as well.
+func runJSTests(p *pkg.Package, lowered *ir.Package, show []*ir.Global) (string, error) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		return "", fmt.Errorf("node is needed to run the tests, but it is not installed")
+	}
+
+	natives, err := jsNatives(p, lowered)
+	if err != nil {
+		return "", err
+	}
+
+	var code bytes.Buffer
+	err = js.Generate(&code, lowered, js.Options{Natives: natives, Debug: true, Show: show, Coverage: true})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(node)
+	cmd.Stdin = &code
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("the tests crashed: %s", jsError(stderr.String(), err))
+	}
+	return stdout.String(), nil
+}
+
+// runGoTests runs the tests with go, which shows the lists of failures as
+// JSON, such as [[],[""]]. The program is built in the given directory,
+// so the natives and the runtime can be imported from it.
+func runGoTests(dir string, lowered *ir.Package, show []*ir.Global, opts testOptions) (string, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return "", fmt.Errorf("go is needed to run the tests, but it is not installed")
+	}
+
+	var code bytes.Buffer
+	err = golang.Generate(&code, lowered, golang.Options{Natives: opts.natives, Show: show})
+	if err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "elm.go"), code.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	main := goTestMain
+	if len(lowered.Points) > 0 {
+		main = goCoverageMain
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(main), 0644); err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(goBin, "run", ".")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("the tests crashed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// goTestMain is the main of the Go program of the tests.
+const goTestMain = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := Show(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+// goCoverageMain is the main of the Go program of the instrumented tests,
+// which writes the counts of the probes after the shown values.
+const goCoverageMain = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := Show(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(Coverage); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testedFixture = "_testdata/tested"
+
+const testedNatives = "github.com/elm-tangram/tangram/cmd/elmo/_testdata/tested/src/Native"
+
+var testFailures = []string{
+	"--- FAIL: Math.Broken.testDoubleIsSquare 1 at " + filepath.Join(testedFixture, "tests/Math/Broken.elm") + ":9:5 (shrunk from Math.Broken.testDoubleIsSquare 157)",
+	"--- FAIL: Math.Broken.testDoubleIsSquare 1 at " + filepath.Join(testedFixture, "tests/Math/Broken.elm") + ":9:5 (shrunk from Math.Broken.testDoubleIsSquare (-472))",
+	"--- FAIL: Math.Broken.testDoubleIsSquare 1 at " + filepath.Join(testedFixture, "tests/Math/Broken.elm") + ":9:5 (shrunk from Math.Broken.testDoubleIsSquare (-362))",
+	"--- FAIL: Math.Broken.testSomeWrong[1] at " + filepath.Join(testedFixture, "tests/Math/Broken.elm") + ":15:7",
+	"--- FAIL: Math.Expected.testSigns[1] at " + filepath.Join(testedFixture, "tests/Math/Expected.elm") + ":16:7\n    Expected 0, but got 1",
+	"--- FAIL: Math.Expected.testDoubleGrows 0 at " + filepath.Join(testedFixture, "tests/Math/Expected.elm") + ":23:5 (shrunk from Math.Expected.testDoubleGrows (-698))\n    Expected greater than 0, but got 0",
+	"--- FAIL: Math.Expected.testDoubleGrows 0 at " + filepath.Join(testedFixture, "tests/Math/Expected.elm") + ":23:5 (shrunk from Math.Expected.testDoubleGrows (-642))\n    Expected greater than 0, but got 0",
+	"--- FAIL: Math.Expected.testDoubleGrows 0 at " + filepath.Join(testedFixture, "tests/Math/Expected.elm") + ":23:5 (shrunk from Math.Expected.testDoubleGrows (-784))\n    Expected greater than 0, but got 0",
+	"FAIL: 8 of 22 assertions failed (seed 1)",
+}
+
+func TestTest(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	code, out := runElmo(t, "test", "--seed", "1", "--fuzz", "3", testedFixture)
+	require.Equal(1, code, out)
+	require.Equal(strings.Join(testFailures, "\n")+"\n", out)
+
+	code, out = runElmo(t, "test", "--seed", "2", "--filter", "^MathTest\\.", testedFixture)
+	require.Equal(0, code, out)
+	require.Equal("ok: 204 assertions passed (seed 2)\n", out)
+
+	code, out = runElmo(t, "test", "--seed", "1", "--fuzz", "0", "--filter", "Broken", testedFixture)
+	require.Equal(1, code, out)
+	require.Equal(testFailures[3]+"\nFAIL: 1 of 2 assertions failed (seed 1)\n", out)
+
+	code, out = runElmo(t, "test", "--filter", "testNothing", testedFixture)
+	require.Equal(0, code, out)
+	require.Equal("no tests to run\n", out)
+
+	_, err := os.Stat(filepath.Join(testedFixture, "tests", "elm-stuff"))
+	require.True(os.IsNotExist(err))
+}
+
+func TestTestJSON(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	code, out := runElmo(t, "test", "--report=json", "--seed", "1", "--fuzz", "0", "--filter", "Broken", testedFixture)
+	require.Equal(1, code, out)
+	require.Equal([]fields{
+		{"event": "fail", "test": "Math.Broken.testSomeWrong[1]", "pos": filepath.Join(testedFixture, "tests/Math/Broken.elm") + ":15:7"},
+		{"event": "result", "passed": float64(1), "failed": float64(1), "seed": float64(1)},
+	}, jsonEvents(t, out))
+
+	code, out = runElmo(t, "test", "--report=json", "--seed", "1", "--fuzz", "2", "--filter", "Expected", testedFixture)
+	require.Equal(1, code, out)
+	require.Equal([]fields{
+		{"event": "fail", "test": "Math.Expected.testSigns[1]", "pos": filepath.Join(testedFixture, "tests/Math/Expected.elm") + ":16:7", "message": "Expected 0, but got 1"},
+		{"event": "fail", "test": "Math.Expected.testDoubleGrows 0", "pos": filepath.Join(testedFixture, "tests/Math/Expected.elm") + ":23:5", "message": "Expected greater than 0, but got 0", "shrunk": "Math.Expected.testDoubleGrows (-472)"},
+		{"event": "result", "passed": float64(4), "failed": float64(2), "seed": float64(1)},
+	}, jsonEvents(t, out))
+}
+
+func TestTestGo(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	if err := exec.Command(goBin, "list", testedNatives).Run(); err != nil {
+		t.Skip("natives of the test package cannot be imported")
+	}
+
+	code, out := runElmo(t, "test", "--target", "go", "--natives", testedNatives, "--seed", "1", "--fuzz", "3", testedFixture)
+	require.Equal(t, 1, code, out)
+	require.Equal(t, strings.Join(testFailures, "\n")+"\n", out)
+}
+
+func TestTestCoverage(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	path := filepath.Join(tempDir(t), "lcov.info")
+	code, out := runElmo(t, "test", "--seed", "2", "--fuzz", "5", "--filter", "^MathTest\\.", "--coverage", path, testedFixture)
+	require.Equal(0, code, out)
+	require.Equal("coverage: 25.0% of Basics (1 of 4)\ncoverage: 100.0% of Math (7 of 7)\nok: 14 assertions passed (seed 2)\n", out)
+
+	lcov, err := ioutil.ReadFile(path)
+	require.NoError(err)
+	require.Contains(string(lcov), "SF:"+filepath.Join(testedFixture, "src/Math.elm")+"\n")
+	require.Contains(string(lcov), "FNDA:19,double\n")
+	require.Contains(string(lcov), "FNDA:0,not\n")
+	require.NotContains(string(lcov), "Expect.elm")
+}
+
+func TestTestDoc(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	require := require.New(t)
+
+	code, out := runElmo(t, "test", "--doc", "--seed", "1", "--fuzz", "0", "--filter", "^DocTests\\.", testedFixture)
+	require.Equal(1, code, out)
+	require.Equal("--- FAIL: DocTests.Math.testExample4 at "+filepath.Join(testedFixture, "src/Math.elm")+":36:1\n    Expected 1, but got 0\nFAIL: 1 of 4 assertions failed (seed 1)\n", out)
+
+	// the examples are only tested with --doc
+	code, out = runElmo(t, "test", "--seed", "1", "--fuzz", "0", "--filter", "^DocTests\\.", testedFixture)
+	require.Equal(0, code, out)
+	require.Equal("no tests to run\n", out)
+
+	_, err := os.Stat(filepath.Join(testedFixture, "tests", "elm-stuff"))
+	require.True(os.IsNotExist(err))
+}
+
+func TestTestErrors(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "test", "--target", "html", testedFixture)
+	require.Equal(2, code)
+	require.Equal("elmo: tests cannot be run with the html target\n", out)
+
+	code, out = runElmo(t, "test", "--filter", "(", testedFixture)
+	require.Equal(2, code)
+	require.Contains(out, "elmo: invalid filter: ")
+
+	code, out = runElmo(t, "test", fixture)
+	require.Equal(3, code)
+	require.Equal("elmo: there is no tests directory in "+fixture+"\n", out)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, testedFixture, dir)
+	wrong := filepath.Join(dir, "tests", "Math", "Wrong.elm")
+	require.NoError(ioutil.WriteFile(wrong, []byte("module Math.Wrong exposing (..)\n\n\ntestAnswer : Int\ntestAnswer =\n    42\n"), 0644))
+
+	code, out = runElmo(t, "test", "--filter", "Wrong", dir)
+	require.Equal(1, code)
+	require.Equal(wrong+":4:1: Math.Wrong.testAnswer has type Int, but tests must be Bool, Expectation, lists of them or functions of Int, Float, Bool and String that return them\n", out)
+}
+
+func TestShrinkValue(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal([]interface{}{0, 78, 156}, shrinkValue(157))
+	require.Equal([]interface{}{0, 3, -1, -2}, shrinkValue(-3))
+	require.Equal([]interface{}{0.0, 2.0, 1.25}, shrinkValue(2.5))
+	require.Equal([]interface{}{0.0}, shrinkValue(0.5))
+	require.Equal([]interface{}{false}, shrinkValue(true))
+	require.Equal([]interface{}{"", "a", "bc", "ac", "ab"}, shrinkValue("abc"))
+	require.Empty(shrinkValue(0))
+	require.Empty(shrinkValue(false))
+	require.Empty(shrinkValue(""))
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/symbol"
+)
+
+const usageUsage = `Usage: elmo usage [flags] [path]
+
+Usage prints how much the top-level declarations of the modules of the
+package at the given path, and the constructors of their types, are used by
+all the modules of the package. The path is the directory of the package,
+and it is the current directory by default.
+
+For every declaration, it prints whether its module exposes it and whether
+it is public, which is exposed by one of the exposed modules of the package,
+the number of references to it outside of its own declaration, the number of
+modules with any of them and the number of declarations that use it,
+directly or through other declarations. The declarations that are not
+reachable, which are neither public nor a main definition nor used by them,
+directly or not, are dead code, and the public ones that are not used
+anywhere in the package may be dead API.
+
+The usage is printed as CSV, with a header, or as JSON. With --report=json,
+it is the "usage" field of a usage event.
+
+The flags are:
+
+`
+
+// usageRoot is the name of the synthetic module the package is loaded
+// from, which imports all its modules.
+const usageRoot = "ElmoUsage"
+
+// usageOptions are the flags of the usage command.
+type usageOptions struct {
+	format string
+}
+
+// usageFormats are the functions that print the usage in each format.
+var usageFormats = map[string]func(io.Writer, []usageJSON) error{
+	"csv":  writeUsageCSV,
+	"json": writeUsageJSON,
+}
+
+func runUsage(args []string, stdout, stderr io.Writer) int {
+	var opts usageOptions
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.format, "format", "csv", "format of the usage: csv or json")
+	outOpts := outputFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usageUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	out, code := newOutput(outOpts, stdout, stderr)
+	if out == nil {
+		return code
+	}
+
+	write, ok := usageFormats[opts.format]
+	if !ok {
+		out.errorf("unknown usage format %q", opts.format)
+		return exitUsage
+	}
+
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+
+	p, err := pkg.Load(dir)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	modules, err := packageModules(p)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	if len(modules) == 0 {
+		out.errorf("there are no modules in the source directories of %s", p.Root())
+		return exitInternal
+	}
+
+	// the tagged modules use the rest of the package too
+	p = p.WithTags(pkg.TestTag, pkg.ExampleTag)
+	if err := p.Provide(pkg.MemoryModules{usageRoot: usageRootSource(modules)}); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	prog := loadIn(p.SyntheticPath(usageRoot), p, parser.FullParse, target.JS, out, parser.WithPackage(p))
+	if prog == nil {
+		return out.failure()
+	}
+
+	cm := source.NewCodeMap(source.NewFsLoader(p))
+	defer cm.Close()
+
+	var usages []usageJSON
+	for _, u := range symbol.Usages(prog.ast, modules, p.ExposedModules) {
+		usages = append(usages, usageJSON{
+			Module:     u.Module,
+			Name:       u.Name,
+			Kind:       u.Kind.String(),
+			Pos:        position(cm, u.Path, u.Ident.Pos()),
+			Exposed:    u.Exposed,
+			Public:     u.Public,
+			Uses:       u.Uses,
+			Modules:    u.Modules,
+			Dependents: u.Dependents,
+			Reachable:  u.Reachable,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, usages); err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var usage interface{} = buf.String()
+	if opts.format == "json" {
+		usage = json.RawMessage(buf.Bytes())
+	}
+	out.event("usage", fields{"format": opts.format, "usage": usage}, buf.String())
+	return exitOK
+}
+
+// packageModules returns the names of the modules in the source
+// directories of the package, sorted.
+func packageModules(p *pkg.Package) ([]string, error) {
+	var names = make(map[string]bool)
+	for _, dir := range p.SourceDirectories {
+		dir = filepath.Join(p.Root(), dir)
+		files, err := elmFiles([]string{dir})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			rel, err := filepath.Rel(dir, f)
+			if err != nil {
+				return nil, err
+			}
+
+			rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+			names[strings.Replace(rel, string(filepath.Separator), ".", -1)] = true
+		}
+	}
+
+	var modules []string
+	for name := range names {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// usageRootSource returns the source code of the root module, which only
+// imports the modules.
+func usageRootSource(modules []string) []byte {
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "module %s exposing (..)\n\n", usageRoot)
+	for _, m := range modules {
+		fmt.Fprintf(&src, "import %s\n", m)
+	}
+	return src.Bytes()
+}
+
+// usageJSON is the usage of a declaration.
+type usageJSON struct {
+	Module     string `json:"module"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Pos        string `json:"pos"`
+	Exposed    bool   `json:"exposed"`
+	Public     bool   `json:"public"`
+	Uses       int    `json:"uses"`
+	Modules    int    `json:"modules"`
+	Dependents int    `json:"dependents"`
+	Reachable  bool   `json:"reachable"`
+}
+
+func writeUsageCSV(w io.Writer, usages []usageJSON) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"module", "name", "kind", "pos", "exposed", "public", "uses", "modules", "dependents", "reachable"})
+	for _, u := range usages {
+		cw.Write([]string{
+			u.Module, u.Name, u.Kind, u.Pos,
+			strconv.FormatBool(u.Exposed), strconv.FormatBool(u.Public),
+			strconv.Itoa(u.Uses), strconv.Itoa(u.Modules), strconv.Itoa(u.Dependents),
+			strconv.FormatBool(u.Reachable),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeUsageJSON(w io.Writer, usages []usageJSON) error {
+	if usages == nil {
+		usages = []usageJSON{}
+	}
+
+	content, err := json.MarshalIndent(usages, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
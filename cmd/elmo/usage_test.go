@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	require := require.New(t)
+	linted := filepath.Join("_testdata", "linted")
+	pos := func(file string, line int) string {
+		return filepath.Join(linted, "src", file) + ":" + strconv.Itoa(line) + ":1"
+	}
+
+	code, out := runElmo(t, "usage", linted)
+	require.Equal(0, code, out)
+	require.Equal(strings.Join([]string{
+		"module,name,kind,pos,exposed,public,uses,modules,dependents,reachable",
+		"Extra,one,value," + pos("Extra.elm", 5) + ",true,false,0,0,0,false",
+		"Main,view,function," + pos("Main.elm", 8) + ",true,true,0,0,0,true",
+		"Main,update,function," + pos("Main.elm", 16) + ",true,true,0,0,0,true",
+		"Main,count,function," + pos("Main.elm", 28) + ",true,true,0,0,0,true",
+		"Main,helper,value," + pos("Main.elm", 43) + ",false,false,0,0,0,false",
+		"Util,identity,function," + pos("Util.elm", 5) + ",true,false,1,1,1,true",
+	}, "\n")+"\n", out)
+
+	code, out = runElmo(t, "usage", "--format", "json", linted)
+	require.Equal(0, code, out)
+	var usages []usageJSON
+	require.NoError(json.Unmarshal([]byte(out), &usages))
+	require.Len(usages, 6)
+	require.Equal(usageJSON{
+		Module:     "Util",
+		Name:       "identity",
+		Kind:       "function",
+		Pos:        pos("Util.elm", 5),
+		Exposed:    true,
+		Uses:       1,
+		Modules:    1,
+		Dependents: 1,
+		Reachable:  true,
+	}, usages[5])
+
+	code, out = runElmo(t, "usage", "--format", "xml", linted)
+	require.Equal(2, code)
+	require.Equal("elmo: unknown usage format \"xml\"\n", out)
+}
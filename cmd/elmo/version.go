@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/elmo"
+)
+
+const versionUsage = `Usage: elmo version [flags]
+
+Version prints the version of elmo and what it supports: the dialects of
+Elm it compiles, the backends with their targets and its features. With
+--json, they are printed as a JSON object with the fields version, go,
+dialects, backends and features, so the build tools and the editors can
+check what they can use instead of guessing from the version.
+
+The flags are:
+
+`
+
+func runVersion(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the version and what elmo supports as JSON")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, versionUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	info := elmo.BuildInfo()
+	if *asJSON {
+		content, err := json.MarshalIndent(info, "", "    ")
+		if err != nil {
+			fmt.Fprintf(stderr, "elmo: %s\n", err)
+			return exitInternal
+		}
+
+		stdout.Write(append(content, '\n'))
+		return exitOK
+	}
+
+	var backends []string
+	for _, b := range info.Backends {
+		backends = append(backends, fmt.Sprintf("%s (%s)", b.Name, strings.Join(b.Targets, ", ")))
+	}
+
+	fmt.Fprintf(stdout, "elmo %s %s\n", info.Version, info.Go)
+	fmt.Fprintf(stdout, "dialects: %s\n", strings.Join(info.Dialects, ", "))
+	fmt.Fprintf(stdout, "backends: %s\n", strings.Join(backends, ", "))
+	fmt.Fprintf(stdout, "features: %s\n", strings.Join(info.Features, ", "))
+	return exitOK
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/elm-tangram/tangram/elmo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	require := require.New(t)
+
+	code, out := runElmo(t, "version")
+	require.Equal(exitOK, code, out)
+	require.Contains(out, "elmo ")
+	require.Contains(out, "dialects: elm-0.18, ")
+	require.Contains(out, "backends: go (go, wasm), js (html, js, node, webworker)\n")
+
+	code, out = runElmo(t, "version", "--json")
+	require.Equal(exitOK, code, out)
+
+	var info elmo.Info
+	require.NoError(json.Unmarshal([]byte(out), &info))
+	require.Equal(elmo.BuildInfo(), info)
+	require.Contains(info.Features, "lsp")
+
+	code, _ = runElmo(t, "version", "extra")
+	require.Equal(exitUsage, code)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// wasmGlue is the JavaScript that loads the WebAssembly module of the wasm
+// target, after the wasm_exec.js of the compiler, which runs the Go
+// programs built for GOOS=js and GOARCH=wasm. Elm.load takes the URL or the
+// bytes of the module and returns a promise of the Elm object, once the
+// module has exported its main program to it.
+const wasmGlue = `
+(function(scope) {
+	scope.Elm = scope.Elm || {};
+	scope.Elm.load = function(source) {
+		var go = new Go();
+		var result = typeof source === 'string'
+			? WebAssembly.instantiateStreaming(fetch(source), go.importObject)
+			: WebAssembly.instantiate(source, go.importObject);
+		return result.then(function(result) {
+			go.run(result.instance);
+			return scope.Elm;
+		});
+	};
+}(typeof globalThis !== 'undefined' ? globalThis : this));
+`
+
+// wasmCompiler is a compiler of Go programs to WebAssembly.
+type wasmCompiler struct {
+	// name of the command.
+	name string
+	// args are the arguments that build the package in the current
+	// directory to the output file, whose first one is the subcommand.
+	args func(output string) []string
+	// reproducible are the flags of the subcommand that build the same
+	// module no matter where and when it is built.
+	reproducible []string
+	// env are the environment variables of the build.
+	env []string
+	// root is the argument of the env subcommand of the compiler that
+	// prints its root directory.
+	root string
+	// execPaths are the paths to wasm_exec.js in the root, in order.
+	execPaths []string
+}
+
+var (
+	goWasm = &wasmCompiler{
+		name:         "go",
+		args:         func(output string) []string { return []string{"build", "-o", output, "."} },
+		reproducible: []string{"-trimpath", "-buildvcs=false"},
+		env:          []string{"GOOS=js", "GOARCH=wasm"},
+		root:         "GOROOT",
+		// wasm_exec.js was moved to lib/wasm in Go 1.24
+		execPaths: []string{"lib/wasm/wasm_exec.js", "misc/wasm/wasm_exec.js"},
+	}
+	tinyGoWasm = &wasmCompiler{
+		name:      "tinygo",
+		args:      func(output string) []string { return []string{"build", "-o", output, "-target", "wasm", "."} },
+		root:      "TINYGOROOT",
+		execPaths: []string{"targets/wasm_exec.js"},
+	}
+)
+
+// buildWasm builds the Go program of the wasm target in the elm-stuff
+// directory of the package at root and writes the WebAssembly module to the
+// output file, and the JavaScript that loads it next to it, with the .js
+// extension. If reproducible is true, the module does not depend on the
+// directories of the build.
+func buildWasm(root string, program []byte, output string, c *wasmCompiler, reproducible bool) error {
+	bin, err := exec.LookPath(c.name)
+	if err != nil {
+		return fmt.Errorf("%s is needed to build the wasm target, but it is not installed", c.name)
+	}
+
+	output, err = filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	// the directories created for the build are removed afterwards
+	stuff := filepath.Join(root, "elm-stuff")
+	if _, err := os.Stat(stuff); os.IsNotExist(err) {
+		defer os.Remove(stuff)
+	}
+
+	dir := filepath.Join(stuff, "wasm")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), program, 0644); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	args := c.args(output)
+	if reproducible {
+		args = append(append([]string{args[0]}, c.reproducible...), args[1:]...)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), c.env...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("can't build the wasm target with %s: %s", c.name, strings.TrimSpace(stderr.String()))
+	}
+
+	wasmExec, err := c.wasmExec(bin)
+	if err != nil {
+		return err
+	}
+
+	glue := strings.TrimSuffix(output, filepath.Ext(output)) + ".js"
+	return ioutil.WriteFile(glue, append(wasmExec, wasmGlue...), 0644)
+}
+
+// wasmExec returns the wasm_exec.js of the compiler, which is the
+// JavaScript support of the programs it builds.
+func (c *wasmCompiler) wasmExec(bin string) ([]byte, error) {
+	out, err := exec.Command(bin, "env", c.root).Output()
+	if err != nil {
+		return nil, fmt.Errorf("can't find the root of %s: %s", c.name, err)
+	}
+
+	root := strings.TrimSpace(string(out))
+	for _, path := range c.execPaths {
+		content, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err == nil {
+			return content, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("there is no wasm_exec.js in %s", root)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/watch"
+)
+
+// watchInterval is the time between two polls of the source files in watch
+// mode, which is shorter in the tests.
+var watchInterval = watch.DefaultInterval
+
+// watchBuild builds the program at the given path and builds it again every
+// time its source code changes, until done is closed. Only the modules
+// affected by the changes since the last successful build are compiled
+// again, unless the whole program must be compiled for the target.
+func watchBuild(path string, t target.Target, opts buildOptions, done <-chan struct{}, out *output) int {
+	file, err := mainPath(path)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	p, err := pkg.Load(filepath.Dir(file))
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+
+	var dirs = []string{filepath.Dir(file)}
+	for _, dir := range p.SourceDirectories {
+		dirs = append(dirs, filepath.Join(p.Root(), dir))
+	}
+
+	w, err := watch.New(dirs...)
+	if err != nil {
+		out.errorf("%s", err)
+		return exitInternal
+	}
+	w.Interval = watchInterval
+
+	var objs *objects
+	if t.Backend() != "go" && !opts.optimize {
+		objs = newObjects()
+	}
+
+	var last *program
+	var rebuild = true
+	for {
+		if rebuild {
+			if prog := watchStep(file, t, opts, objs, out); prog != nil {
+				last = prog
+				rebuild = false
+			}
+		}
+
+		changed, err := w.Wait(done)
+		if err != nil {
+			out.errorf("%s", err)
+			return exitInternal
+		}
+
+		if len(changed) == 0 {
+			return exitOK
+		}
+
+		if last != nil {
+			affected := watch.Affected(last.ast, changed)
+			if objs != nil {
+				objs.forget(affected)
+			}
+			rebuild = rebuild || len(affected) > 0
+		}
+	}
+}
+
+// watchStep builds the program once in watch mode and returns it, or nil if
+// it could not be built.
+func watchStep(file string, t target.Target, opts buildOptions, objs *objects, out *output) *program {
+	prog := loadFor(file, t, out)
+	if prog == nil {
+		out.event("build", fields{"ok": false}, "")
+		return nil
+	}
+
+	if err := build(prog, t, opts, objs); err != nil {
+		out.errorf("%s", err)
+		out.event("build", fields{"ok": false}, "")
+		return nil
+	}
+
+	data := fields{"ok": true, "output": opts.output, "target": t, "modules": len(prog.ast.Order)}
+	if objs != nil {
+		data["compiled"] = objs.compiled
+		out.progress("build", data, fmt.Sprintf("built %s, compiled %d of %d modules\n", opts.output, objs.compiled, len(prog.ast.Order)))
+	} else {
+		out.progress("build", data, fmt.Sprintf("built %s\n", opts.output))
+	}
+	return prog
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a buffer that can be written and read at the same time.
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.String()
+}
+
+// waitFor waits until the buffer contains the given text.
+func waitFor(t *testing.T, b *syncBuffer, text string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(b.String(), text) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expecting %q in the output, but got:\n%s", text, b.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func copyDir(t *testing.T, src, dst string) {
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), content, 0644)
+	})
+	require.NoError(t, err)
+}
+
+func TestWatchBuild(t *testing.T) {
+	defer func(d time.Duration) { watchInterval = d }(watchInterval)
+	watchInterval = 10 * time.Millisecond
+
+	require := require.New(t)
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, fixture, dir)
+
+	var stdout, stderr syncBuffer
+	out := &output{stdout: &stdout, stderr: &stderr}
+	output := filepath.Join(dir, "main.js")
+	done := make(chan struct{})
+	exited := make(chan int)
+	go func() {
+		exited <- watchBuild(dir, target.JS, buildOptions{output: output}, done, out)
+	}()
+
+	waitFor(t, &stdout, "built "+output+", compiled 5 of 5 modules\n")
+	first := readFile(t, output)
+
+	main := filepath.Join(dir, "src", "Main.elm")
+	src := readFile(t, main)
+	require.NoError(ioutil.WriteFile(main, []byte(src+"\n\nanswer : Int\nanswer =\n    42\n"), 0644))
+	waitFor(t, &stdout, "built "+output+", compiled 1 of 5 modules\n")
+	require.Contains(readFile(t, output), "$Main$answer")
+
+	require.NoError(ioutil.WriteFile(main, []byte(src+"\n\nanswer : Int\nanswer =\n    unknown\n"), 0644))
+	waitFor(t, &stderr, `I could not find any definition for "unknown"`)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "src", "Basics.elm"), []byte(readFile(t, filepath.Join(fixture, "src", "Basics.elm"))+"\n"), 0644))
+	require.NoError(ioutil.WriteFile(main, []byte(src), 0644))
+	waitFor(t, &stdout, "built "+output+", compiled 5 of 5 modules\nbuilt "+output+", compiled 1 of 5 modules\nbuilt "+output+", compiled ")
+	require.Equal(first, readFile(t, output))
+
+	close(done)
+	require.Equal(0, <-exited)
+	require.Equal(3, strings.Count(stdout.String(), "built"))
+}
+
+func TestWatchBuildGo(t *testing.T) {
+	defer func(d time.Duration) { watchInterval = d }(watchInterval)
+	watchInterval = 10 * time.Millisecond
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	copyDir(t, fixture, dir)
+
+	var stdout, stderr syncBuffer
+	out := &output{stdout: &stdout, stderr: &stderr}
+	output := filepath.Join(dir, "main.go")
+	done := make(chan struct{})
+	exited := make(chan int)
+	go func() {
+		exited <- watchBuild(dir, target.Go, buildOptions{output: output, natives: "example.com/natives"}, done, out)
+	}()
+	waitFor(t, &stdout, "built "+output+"\n")
+
+	close(done)
+	require.Equal(t, 0, <-exited, stderr.String())
+}
@@ -0,0 +1,106 @@
+// Package codegentest implements a harness to test the backends of the
+// compiler with golden files.
+//
+// The fixtures are Elm packages with a Main module in their src directory.
+// The code a backend generates for a fixture is compared with the golden
+// file `<backend>.golden` of the fixture and, if the fixture has an
+// `input.jsonl` file and the backend can run its code, the output of
+// running the main program with that input is compared with the golden
+// file `<backend>.output.golden`. Input and output are lines of JSON with
+// the values of the ports, such as `{"port": "input", "value": 2}`, just
+// like in the node and Go targets.
+//
+// Running the tests with the -update flag writes the golden files instead,
+// so a new case only needs a fixture, and the golden files of its expected
+// output are reviewed like any other change.
+package codegentest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/parser/parsertest"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	pkgFile   = "elm-package.json"
+	inputFile = "input.jsonl"
+)
+
+// Backend is a backend tested by the harness.
+type Backend struct {
+	// Name of the backend, which is the name of its golden files.
+	Name string
+	// Generate returns the code of the given package.
+	Generate func(pkg *ir.Package) ([]byte, error)
+	// Run runs the generated code with the given input and returns its
+	// output. It is nil if the code of the backend cannot be run, and it
+	// may skip the test if the tools to run it are not installed.
+	Run func(t *testing.T, code, input []byte) ([]byte, error)
+}
+
+// Lower parses, checks, lowers and optimizes the Main module of the
+// fixture in the given directory.
+func Lower(t *testing.T, dir string) *ir.Package {
+	pkg, err := parser.Parse(filepath.Join(dir, "src", "Main.elm"), parser.FullParse)
+	require.NoError(t, err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(t, conf.Check(pkg, info))
+
+	lowered := ir.Lower(pkg, info)
+	ir.Optimize(lowered, ir.Options{})
+	return lowered
+}
+
+// Compare fails the test if got is not the content of the golden file at
+// the given path, or writes it to the file if the tests are run with the
+// -update flag, like parsertest.Compare.
+func Compare(t *testing.T, path string, got []byte) {
+	parsertest.Compare(t, path, got)
+}
+
+// Test tests the backend with all the fixtures in the directories that
+// match the given pattern, such as `_testdata/*`. Directories that are not
+// Elm packages are ignored.
+func Test(t *testing.T, pattern string, b Backend) {
+	dirs, err := filepath.Glob(pattern)
+	require.NoError(t, err)
+
+	var fixtures int
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, pkgFile)); err != nil {
+			continue
+		}
+
+		fixtures++
+		dir := dir
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			code, err := b.Generate(Lower(t, dir))
+			require.NoError(t, err)
+			Compare(t, filepath.Join(dir, b.Name+".golden"), code)
+
+			input, err := ioutil.ReadFile(filepath.Join(dir, inputFile))
+			if os.IsNotExist(err) || b.Run == nil {
+				return
+			}
+			require.NoError(t, err)
+
+			output, err := b.Run(t, code, input)
+			require.NoError(t, err, string(output))
+			Compare(t, filepath.Join(dir, b.Name+".output.golden"), output)
+		})
+	}
+
+	if fixtures == 0 {
+		t.Fatalf("there are no fixtures in %s", pattern)
+	}
+}
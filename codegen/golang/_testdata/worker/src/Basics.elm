@@ -0,0 +1,27 @@
+module Basics exposing (..)
+
+import Native.Basics
+
+
+type Never
+    = JustOneMore Never
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Basics.add
+
+
+(-) : number -> number -> number
+(-) =
+    Native.Basics.sub
+
+
+(==) : a -> a -> Bool
+(==) =
+    Native.Basics.eq
+
+
+infixl 6 +
+infixl 6 -
+infix 4 ==
@@ -0,0 +1,11 @@
+module List exposing (..)
+
+import Native.List
+
+
+(::) : a -> List a -> List a
+(::) =
+    Native.List.cons
+
+
+infixr 5 ::
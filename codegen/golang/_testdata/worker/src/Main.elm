@@ -0,0 +1,71 @@
+port module Main exposing (..)
+
+import Basics exposing (..)
+import List exposing (..)
+import Platform exposing (..)
+import Platform.Cmd as Cmd exposing (..)
+import Platform.Sub as Sub exposing (..)
+
+
+port increment : (Int -> msg) -> Sub msg
+
+
+port total : Int -> Cmd msg
+
+
+port history : List Int -> Cmd msg
+
+
+port sum : (Int -> msg) -> Sub msg
+
+
+type alias Model =
+    { total : Int
+    , history : List Int
+    }
+
+
+type Msg
+    = Increment Int
+    | Reset
+    | Sum Int
+
+
+initial : Model
+initial =
+    { total = 0, history = [] }
+
+
+sumTo : Int -> Int -> Int
+sumTo n acc =
+    if n == 0 then
+        acc
+    else
+        sumTo (n - 1) (acc + n)
+
+
+update : Msg -> Model -> ( Model, Cmd Msg )
+update msg model =
+    case msg of
+        Increment n ->
+            let
+                next =
+                    { model | total = model.total + n, history = (model.total + n) :: model.history }
+            in
+                ( next, Cmd.batch [ total next.total, history next.history ] )
+
+        Reset ->
+            ( initial, Cmd.none )
+
+        Sum n ->
+            ( { model | total = sumTo n 0 }, total (sumTo n 0) )
+
+
+main : Program Never Model Msg
+main =
+    program
+        { init = ( initial, Cmd.none )
+        , update = update
+        , subscriptions = \_ -> Sub.batch [ increment Increment, sum Sum ]
+        }
+
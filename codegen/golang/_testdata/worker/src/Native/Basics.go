@@ -0,0 +1,21 @@
+package native
+
+import "github.com/elm-tangram/tangram/codegen/golang/rt"
+
+func BasicsAdd(a, b rt.Value) rt.Value {
+	if a, ok := a.(float64); ok {
+		return a + b.(float64)
+	}
+	return a.(int) + b.(int)
+}
+
+func BasicsSub(a, b rt.Value) rt.Value {
+	if a, ok := a.(float64); ok {
+		return a - b.(float64)
+	}
+	return a.(int) - b.(int)
+}
+
+func BasicsEq(a, b rt.Value) rt.Value {
+	return a == b
+}
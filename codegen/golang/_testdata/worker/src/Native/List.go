@@ -0,0 +1,7 @@
+package native
+
+import "github.com/elm-tangram/tangram/codegen/golang/rt"
+
+func ListCons(head, tail rt.Value) rt.Value {
+	return rt.Cons(head, tail)
+}
@@ -0,0 +1,20 @@
+module Platform exposing (..)
+
+import Basics exposing (..)
+import Platform.Cmd exposing (..)
+import Platform.Sub exposing (..)
+import Native.Platform
+
+
+type Program flags model msg
+    = Program
+
+
+program :
+    { init : ( model, Cmd msg )
+    , update : msg -> model -> ( model, Cmd msg )
+    , subscriptions : model -> Sub msg
+    }
+    -> Program Never model msg
+program =
+    Native.Platform.program
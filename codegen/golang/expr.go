@@ -0,0 +1,303 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// expr returns the Go expression of the given expression. Let and case
+// expressions are compiled to statements, so they are wrapped in a function
+// that is called immediately.
+func (g *generator) expr(e ir.Expr) string {
+	switch e := e.(type) {
+	case *ir.Local:
+		return localName(e)
+	case *ir.Global:
+		return g.global(e)
+	case *ir.Lit:
+		return literal(e)
+	case *ir.Ctor:
+		return ctor(e)
+	case *ir.Lambda:
+		return g.directive(e.Pos) + g.lambda(e)
+	case *ir.App:
+		return g.directive(e.Pos) + g.app(e)
+	case *ir.Call:
+		return fmt.Sprintf("%srt.Call(%s, %s)", g.directive(e.Pos), g.expr(e.Func), g.exprs(e.Args))
+	case *ir.Let, *ir.Case, *ir.Probe:
+		return fmt.Sprintf("func() rt.Value {\n%s}()", g.block(e))
+	case *ir.Tuple:
+		return "rt.Tuple{" + g.exprs(e.Elems) + "}"
+	case *ir.Index:
+		return fmt.Sprintf("%s.(rt.Tuple)[%d]", g.expr(e.Expr), e.Index)
+	case *ir.Record:
+		return "rt.Record{" + g.fields(e.Fields) + "}"
+	case *ir.Access:
+		return fmt.Sprintf("%s.(rt.Record)[%q]", g.expr(e.Expr), e.Field)
+	case *ir.Update:
+		return fmt.Sprintf("rt.Update(%s, rt.Record{%s})", g.expr(e.Record), g.fields(e.Fields))
+	case *ir.List:
+		if len(e.Elems) == 0 {
+			return "rt.Nil"
+		}
+		return "rt.List(" + g.exprs(e.Elems) + ")"
+	case *ir.Fail:
+		return fmt.Sprintf("rt.Crash(%q, %q)", e.Module, e.Msg)
+	}
+	return fmt.Sprintf("rt.Crash(%q, %q)", "", fmt.Sprintf("unsupported expression %T", e))
+}
+
+func (g *generator) exprs(exprs []ir.Expr) string {
+	var result = make([]string, len(exprs))
+	for i, e := range exprs {
+		result[i] = g.expr(e)
+	}
+	return joinArgs(result)
+}
+
+func (g *generator) fields(fields []*ir.Field) string {
+	var result = make([]string, len(fields))
+	for i, f := range fields {
+		result[i] = fmt.Sprintf("%q: %s", f.Name, g.expr(f.Expr))
+	}
+	return joinArgs(result)
+}
+
+// global returns a reference to a global. Natives that are functions are
+// wrapped, so they can be used as any other function.
+func (g *generator) global(e *ir.Global) string {
+	if !strings.HasPrefix(e.Module, "Native.") {
+		return globalName(e.Module, e.Name)
+	}
+
+	name := g.native(e)
+	arity := len(funcArgs(e.Typ))
+	if arity == 0 {
+		return name
+	}
+
+	var args = make([]string, arity)
+	for i := range args {
+		args[i] = fmt.Sprintf("args[%d]", i)
+	}
+	return fmt.Sprintf(
+		"rt.F(%d, func(args []rt.Value) rt.Value { return %s(%s) })",
+		arity, name, joinArgs(args),
+	)
+}
+
+func (g *generator) native(e *ir.Global) string {
+	name := nativeName(e.Module, e.Name)
+	if strings.HasPrefix(name, "native.") {
+		g.natives = true
+	}
+	return name
+}
+
+// lambda returns a function. The body of functions with tail calls is a
+// loop, and the tail calls replace the arguments and continue with the next
+// iteration, which declares the parameters again, so the functions created
+// in every iteration do not share them.
+func (g *generator) lambda(e *ir.Lambda) string {
+	var buf bytes.Buffer
+	loop := ir.HasTailCalls(e)
+	if loop {
+		buf.WriteString("for {\n")
+	}
+
+	for i, p := range e.Params {
+		if g.used[p] {
+			fmt.Fprintf(&buf, "%s := args[%d]\n", localName(p), i)
+		}
+	}
+	buf.WriteString(g.block(e.Body))
+
+	if loop {
+		buf.WriteString("}\n")
+	}
+	return fmt.Sprintf("rt.F(%d, func(args []rt.Value) rt.Value {\n%s})", len(e.Params), buf.String())
+}
+
+func (g *generator) app(e *ir.App) string {
+	if c, ok := e.Func.(*ir.Ctor); ok && c.Arity == len(e.Args) {
+		if c.Module == "" && c.Name == "::" {
+			return fmt.Sprintf("rt.Cons(%s)", g.exprs(e.Args))
+		}
+		return fmt.Sprintf("rt.C(%d, %s)", c.Tag, g.exprs(e.Args))
+	}
+
+	// applied natives are called directly
+	if n, ok := e.Func.(*ir.Global); ok && strings.HasPrefix(n.Module, "Native.") {
+		if arity := len(funcArgs(n.Typ)); arity > 0 && arity <= len(e.Args) {
+			call := fmt.Sprintf("%s(%s)", g.native(n), g.exprs(e.Args[:arity]))
+			if arity == len(e.Args) {
+				return call
+			}
+			return fmt.Sprintf("rt.A(%s, %s)", call, g.exprs(e.Args[arity:]))
+		}
+	}
+	return fmt.Sprintf("rt.A(%s, %s)", g.expr(e.Func), g.exprs(e.Args))
+}
+
+// ctor returns the value of a constructor that is not applied to all its
+// arguments, which is a function that creates the value.
+func ctor(c *ir.Ctor) string {
+	if c.Module == "" && c.Union == "List" {
+		if c.Name == "[]" {
+			return "rt.Nil"
+		}
+		return "rt.F(2, func(args []rt.Value) rt.Value { return rt.Cons(args[0], args[1]) })"
+	}
+
+	if c.Arity == 0 {
+		return fmt.Sprintf("rt.C(%d)", c.Tag)
+	}
+	return fmt.Sprintf("rt.F(%d, func(args []rt.Value) rt.Value { return rt.C(%d, args...) })", c.Arity, c.Tag)
+}
+
+// block returns the statements that return the value of the expression.
+func (g *generator) block(e ir.Expr) string {
+	buf := g.buf
+	g.buf = new(bytes.Buffer)
+	g.stmts(e)
+	result := g.buf.String()
+	g.buf = buf
+	return result
+}
+
+func (g *generator) stmts(e ir.Expr) {
+	switch e := e.(type) {
+	case *ir.Let:
+		// all the variables are declared first, because they may refer to
+		// each other inside functions
+		binds := ir.SortBinds(e.Binds)
+		for _, b := range binds {
+			if g.used[b.Var] {
+				g.line("var %s rt.Value", localName(b.Var))
+			}
+		}
+
+		for _, b := range binds {
+			if g.used[b.Var] {
+				g.line("%s = %s", localName(b.Var), g.expr(b.Expr))
+			} else {
+				g.line("_ = %s", g.expr(b.Expr))
+			}
+		}
+		g.stmts(e.Body)
+	case *ir.Case:
+		if len(e.Alts) > 0 && e.Alts[0].Ctor != nil {
+			g.switchCase(e)
+		} else {
+			g.ifCase(e)
+		}
+	case *ir.Probe:
+		g.line("Coverage[%d]++", e.ID)
+		g.stmts(e.Expr)
+	case *ir.TailCall:
+		g.line("args = []rt.Value{%s}", g.exprs(e.Args))
+		g.line("continue")
+	default:
+		g.line("return %s", g.expr(e))
+	}
+}
+
+// switchCase writes a case expression on constructors as a switch on the
+// tag of the subject. The switch always has a default clause, so it is a
+// terminating statement.
+func (g *generator) switchCase(e *ir.Case) {
+	subject := localName(e.Subject)
+	g.line("switch %s.(*rt.Ctor).Tag {", subject)
+
+	var seen = make(map[int]bool)
+	for i, alt := range e.Alts {
+		last := e.Default == nil && i == len(e.Alts)-1
+		if seen[alt.Ctor.Tag] && !last {
+			// unreachable, and go does not allow duplicate cases
+			continue
+		}
+		seen[alt.Ctor.Tag] = true
+
+		if last {
+			g.line("default:")
+		} else {
+			g.line("case %d:", alt.Ctor.Tag)
+		}
+
+		for j, v := range alt.Vars {
+			if g.used[v] {
+				g.line("%s := %s.(*rt.Ctor).Args[%d]", localName(v), subject, j)
+			}
+		}
+		g.stmts(alt.Body)
+	}
+
+	if e.Default != nil {
+		g.line("default:")
+		g.stmts(e.Default)
+	}
+	g.line("}")
+}
+
+// ifCase writes a case expression on literals as a chain of conditions.
+func (g *generator) ifCase(e *ir.Case) {
+	subject := localName(e.Subject)
+	for i, alt := range e.Alts {
+		if e.Default == nil && i == len(e.Alts)-1 {
+			g.stmts(alt.Body)
+			return
+		}
+
+		g.line("if %s == rt.Value(%s) {", subject, literal(alt.Lit))
+		g.stmts(alt.Body)
+		g.line("}")
+	}
+
+	if e.Default != nil {
+		g.stmts(e.Default)
+	}
+}
+
+// literal returns the Go literal of the given literal. Number literals are
+// converted to the type of the value, so they have the representation of
+// Int or Float.
+func literal(lit *ir.Lit) string {
+	switch lit.Kind {
+	case ast.Bool:
+		if lit.Value == "True" {
+			return "true"
+		}
+		return "false"
+	case ast.String:
+		if strings.HasPrefix(lit.Value, `"""`) {
+			s := strings.TrimSuffix(strings.TrimPrefix(lit.Value, `"""`), `"""`)
+			return strconv.Quote(s)
+		}
+		return lit.Value
+	case ast.Int, ast.Float:
+		if n, ok := types.Prune(lit.Typ).(*types.Named); ok && n.Module == "" && n.Name == "Float" {
+			return "float64(" + lit.Value + ")"
+		}
+		return "int(" + lit.Value + ")"
+	}
+	return lit.Value
+}
+
+// funcArgs returns the types of the arguments of a function type.
+func funcArgs(t types.Type) []types.Type {
+	var args []types.Type
+	for {
+		fn, ok := types.Prune(t).(*types.Func)
+		if !ok {
+			return args
+		}
+		args = append(args, fn.Arg)
+		t = fn.Result
+	}
+}
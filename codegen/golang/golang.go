@@ -0,0 +1,304 @@
+// Package golang implements the Go backend of the compiler, which compiles
+// a package lowered to the core IR to the source of a Go package, so Elm
+// programs can be embedded into Go programs.
+//
+// All the values of the generated code are rt.Value, whose representation
+// is described in the rt package. The globals of the modules are package
+// variables named after their module and name, such as `Main_update` or
+// `Basics_op_plus` for `Basics.+`.
+//
+// Native modules are the Go files in the `Native` directory of the source
+// directory, which are a Go package imported by the generated code.
+// References to natives are direct Go calls: a native `Native.X.name` is
+// the function `XName` of that package, which takes as many rt.Value
+// parameters as arguments it is given in the type it is used with and
+// returns a rt.Value. If that type is not a function, it is a variable of
+// type rt.Value. `Native.Platform` is provided by the rt package.
+//
+// If there is a main program it can be run creating its worker with the
+// generated Worker function:
+//
+//	w := Worker()
+//	w.Subscribe("output", func(value interface{}) { ... })
+//	w.Start(flags)
+//	w.Send("input", value)
+//
+// rt.Main runs a worker with the values of the ports in the standard input
+// and output, which is what the main function of the Go target does.
+//
+// The generated code of an instrumented package has a Coverage variable
+// with the counts of its probes, by their IDs.
+package golang
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// ErrNoNatives is returned when the native modules are used, but there is
+// no import path for them.
+var ErrNoNatives = errors.New("golang: natives are used, but there is no import path for them")
+
+// rtPath is the import path of the runtime package.
+const rtPath = "github.com/elm-tangram/tangram/codegen/golang/rt"
+
+// Options are the options of the code generation.
+type Options struct {
+	// Package is the name of the generated Go package. By default, it is
+	// "main".
+	Package string
+	// Natives is the import path of the Go package of the native modules.
+	Natives string
+	// Main is the name of the module whose main program can be run with
+	// the generated Worker function, if any.
+	Main string
+	// LineDirectives reports whether the generated code has line
+	// directives with the positions of the Elm source code, so panics and
+	// debuggers point to it.
+	LineDirectives bool
+	// TrimPath is the directory whose files are in the line directives by
+	// their path relative to it, with forward slashes, instead of by their
+	// absolute path, so the generated code does not depend on where it is
+	// built, if it is not empty. Go resolves the relative paths from the
+	// directory of the generated file.
+	TrimPath string
+	// Show are the globals whose values are written as JSON by the
+	// generated Show function, one per line. They must have types that can
+	// be sent through ports.
+	Show []*ir.Global
+	// Context makes the generation stop when it is done, before the next
+	// module is generated, if it is not nil. Generate returns the error of
+	// the context then.
+	Context context.Context
+}
+
+// Generate writes the Go source of the given package to w.
+func Generate(w io.Writer, pkg *ir.Package, opts Options) error {
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+
+	g := &generator{buf: new(bytes.Buffer), trimPath: opts.TrimPath}
+	if opts.LineDirectives {
+		g.sources = source.NewCodeMap(source.NewFsLoader(nil))
+		defer g.sources.Close()
+	}
+
+	for _, mod := range pkg.Modules {
+		if opts.Context != nil && opts.Context.Err() != nil {
+			return opts.Context.Err()
+		}
+
+		g.path = mod.Path
+		for _, p := range mod.Ports {
+			g.line("%s = ports.%s(%q, %s)", globalName(mod.Name, p.Name), portKind(p.Type), p.Name, g.port(p.Type))
+		}
+
+		for _, def := range ir.SortDefs(mod) {
+			g.used = usedLocals(def.Expr)
+			expr := g.expr(def.Expr)
+			if !strings.HasPrefix(expr, "/*line ") {
+				expr = g.directive(def.Pos) + expr
+			}
+			g.line("%s = %s", globalName(mod.Name, def.Name), expr)
+		}
+	}
+	body := g.buf.String()
+	if g.err != nil {
+		return g.err
+	}
+
+	var main string
+	if opts.Main != "" {
+		var err error
+		if main, err = g.worker(pkg, opts.Main); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Show) > 0 {
+		main += g.show(opts.Show)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by tangram. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", opts.Package)
+	buf.WriteString("import (\n")
+	if len(opts.Show) > 0 {
+		buf.WriteString("\"io\"\n")
+	}
+	fmt.Fprintf(&buf, "%q\n", rtPath)
+	if g.natives {
+		if opts.Natives == "" {
+			return ErrNoNatives
+		}
+		fmt.Fprintf(&buf, "native %q\n", opts.Natives)
+	}
+	buf.WriteString(")\n\n")
+
+	buf.WriteString("var ports = rt.NewPorts()\n\n")
+	buf.WriteString("var (\n")
+	for _, mod := range pkg.Modules {
+		for _, p := range mod.Ports {
+			fmt.Fprintf(&buf, "%s rt.Value\n", globalName(mod.Name, p.Name))
+		}
+		for _, def := range mod.Defs {
+			fmt.Fprintf(&buf, "%s rt.Value\n", globalName(mod.Name, def.Name))
+		}
+	}
+	buf.WriteString(")\n\n")
+
+	if len(pkg.Points) > 0 {
+		buf.WriteString("// Coverage counts the evaluations of the points of the instrumented package.\n")
+		fmt.Fprintf(&buf, "var Coverage = make([]int, %d)\n\n", len(pkg.Points))
+	}
+
+	// globals are initialized in init, because they may refer to each other
+	// inside functions, which go considers initialization cycles.
+	buf.WriteString("func init() {\n")
+	buf.WriteString(body)
+	buf.WriteString("}\n")
+	buf.WriteString(main)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("golang: generated invalid code: %s", err)
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+type generator struct {
+	buf *bytes.Buffer
+	// used are the locals referenced in the definition being generated.
+	used map[*ir.Local]bool
+	// natives reports whether any native is referenced.
+	natives bool
+	// sources contains the source code of the modules if there are line
+	// directives, and path is the path of the module being generated.
+	sources *source.CodeMap
+	path    string
+	// trimPath is the directory the paths of the line directives are
+	// relative to, if any.
+	trimPath string
+	// err is the first error found reading the source code.
+	err error
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	fmt.Fprintf(g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+// worker returns the source of the Worker function, which runs the main
+// program of the given module.
+func (g *generator) worker(pkg *ir.Package, module string) (string, error) {
+	mod := pkg.Lookup(module)
+	if mod == nil {
+		return "", fmt.Errorf("golang: there is no module %s", module)
+	}
+
+	main := mod.Lookup("main")
+	if main == nil {
+		return "", fmt.Errorf("golang: module %s has no main", module)
+	}
+
+	program, ok := types.Prune(main.Type).(*types.Named)
+	if !ok || program.Module != "Platform" || program.Name != "Program" || len(program.Args) != 3 {
+		return "", fmt.Errorf(
+			"golang: main has type %s, but only Platform programs are supported",
+			types.TypeString(main.Type),
+		)
+	}
+
+	var flags = "nil"
+	if !isNever(program.Args[0]) {
+		flags = g.fromGo(program.Args[0])
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n// Worker returns a new worker of the main program of module %s.\n", module)
+	buf.WriteString("func Worker() *rt.Worker {\n")
+	fmt.Fprintf(&buf, "return rt.NewWorker(%s, %s, ports)\n", globalName(module, "main"), flags)
+	buf.WriteString("}\n")
+	return buf.String(), nil
+}
+
+// show returns the source of the Show function, which writes the values of
+// the given globals.
+func (g *generator) show(globals []*ir.Global) string {
+	var buf bytes.Buffer
+	buf.WriteString("\n// Show writes the values of the shown globals as JSON to w, one per line.\n")
+	buf.WriteString("func Show(w io.Writer) error {\n")
+	for _, s := range globals {
+		fmt.Fprintf(&buf, "if err := rt.Show(w, %s, %s); err != nil {\nreturn err\n}\n", globalName(s.Module, s.Name), g.toGo(s.Typ))
+	}
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// portKind returns the method of rt.Ports that declares a port of the given
+// type.
+func portKind(t types.Type) string {
+	if _, incoming := portValue(t); incoming {
+		return "Incoming"
+	}
+	return "Outgoing"
+}
+
+// port returns the converter of the values of a port of the given type.
+func (g *generator) port(t types.Type) string {
+	value, incoming := portValue(t)
+	if incoming {
+		return g.fromGo(value)
+	}
+	return g.toGo(value)
+}
+
+// portValue returns the type of the values sent by an outgoing port,
+// `a -> Cmd msg`, or received by an incoming port, `(a -> msg) -> Sub msg`.
+func portValue(t types.Type) (value types.Type, incoming bool) {
+	fn, ok := types.Prune(t).(*types.Func)
+	if !ok {
+		return nil, false
+	}
+
+	result, ok := types.Prune(fn.Result).(*types.Named)
+	if tagger, isFunc := types.Prune(fn.Arg).(*types.Func); ok && isFunc && result.Name == "Sub" {
+		return tagger.Arg, true
+	}
+	return fn.Arg, false
+}
+
+func isNever(t types.Type) bool {
+	n, ok := types.Prune(t).(*types.Named)
+	return ok && n.Module == "Basics" && n.Name == "Never"
+}
+
+// usedLocals returns the locals referenced in the given expression. Go does
+// not allow unused variables, so only these are declared.
+func usedLocals(e ir.Expr) map[*ir.Local]bool {
+	var used = make(map[*ir.Local]bool)
+	ir.Inspect(e, func(e ir.Expr) bool {
+		if l, ok := e.(*ir.Local); ok {
+			used[l] = true
+		}
+		return true
+	})
+	return used
+}
+
+func joinArgs(args []string) string {
+	return strings.Join(args, ", ")
+}
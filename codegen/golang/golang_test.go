@@ -0,0 +1,246 @@
+package golang_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/codegen/codegentest"
+	"github.com/elm-tangram/tangram/codegen/golang"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/ir"
+
+	"github.com/stretchr/testify/require"
+)
+
+const nativesPath = "github.com/elm-tangram/tangram/codegen/golang/_testdata/worker/src/Native"
+
+func generate(t *testing.T, opts golang.Options) string {
+	var buf bytes.Buffer
+	require.NoError(t, golang.Generate(&buf, codegentest.Lower(t, "_testdata/worker"), opts))
+	return buf.String()
+}
+
+func TestGenerate(t *testing.T) {
+	out := generate(t, golang.Options{Main: "Main", Natives: nativesPath})
+
+	expected := []string{
+		"package main\n",
+		`native "` + nativesPath + `"`,
+		`Main_increment = ports.Incoming("increment", rt.DecodeInt)`,
+		`Main_total = ports.Outgoing("total", rt.Identity)`,
+		`Main_history = ports.Outgoing("history", rt.ListToGo(rt.Identity))`,
+		"Basics_op_plus = rt.F(2, func(args []rt.Value) rt.Value { return native.BasicsAdd(args[0], args[1]) })",
+		"Platform_Cmd_none = rt.PlatformNone",
+		"switch msg_4.(*rt.Ctor).Tag {",
+		"Main_sumTo = rt.F(2, func(args []rt.Value) rt.Value {\n\t\tfor {",
+		"args = []rt.Value{native.BasicsSub(n_1, int(1)), native.BasicsAdd(acc_2, n_1)}\n\t\t\tcontinue",
+		"rt.A(Main_total, rt.Call(Main_sumTo, n_8, int(0)))",
+		"return rt.NewWorker(Main_main, nil, ports)",
+	}
+	for _, e := range expected {
+		require.Contains(t, out, e)
+	}
+}
+
+func TestGenerateLineDirectives(t *testing.T) {
+	out := generate(t, golang.Options{Main: "Main", Natives: nativesPath, LineDirectives: true})
+
+	path, err := filepath.Abs("_testdata/worker/src/Main.elm")
+	require.NoError(t, err)
+	require.Contains(t, out, "Main_sumTo = /*line "+path+":39*/ rt.F(2, func(")
+	require.Contains(t, out, "Main_total = ports.Outgoing(")
+
+	out = generate(t, golang.Options{Main: "Main", Natives: nativesPath, LineDirectives: true, TrimPath: "_testdata/worker"})
+	require.Contains(t, out, "Main_sumTo = /*line src/Main.elm:39*/ rt.F(2, func(")
+
+	out = generate(t, golang.Options{Main: "Main", Natives: nativesPath})
+	require.NotContains(t, out, "/*line ")
+}
+
+func TestGenerateShow(t *testing.T) {
+	initial := &ir.Global{Module: "Main", Name: "initial", Typ: codegentest.Lower(t, "_testdata/worker").Lookup("Main").Lookup("initial").Type}
+	out := generate(t, golang.Options{Natives: nativesPath, Show: []*ir.Global{initial}})
+	require.Contains(t, out, "func Show(w io.Writer) error {")
+	require.Contains(t, out, "if err := rt.Show(w, Main_initial, rt.RecordToGo(")
+
+	out = generate(t, golang.Options{Main: "Main", Natives: nativesPath})
+	require.NotContains(t, out, "func Show(")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pkg := codegentest.Lower(t, "_testdata/worker")
+
+	var buf bytes.Buffer
+	err := golang.Generate(&buf, pkg, golang.Options{})
+	require.EqualError(t, err, "golang: natives are used, but there is no import path for them")
+
+	err = golang.Generate(&buf, pkg, golang.Options{Natives: nativesPath, Main: "Foo"})
+	require.EqualError(t, err, "golang: there is no module Foo")
+
+	err = golang.Generate(&buf, pkg, golang.Options{Natives: nativesPath, Main: "Basics"})
+	require.EqualError(t, err, "golang: module Basics has no main")
+}
+
+const driver = `package main
+
+import "fmt"
+
+func main() {
+	w := Worker()
+	var output []interface{}
+	record := func(v interface{}) { output = append(output, v) }
+	if err := w.Subscribe("total", record); err != nil {
+		panic(err)
+	}
+	if err := w.Subscribe("history", record); err != nil {
+		panic(err)
+	}
+	if err := w.Start(nil); err != nil {
+		panic(err)
+	}
+	for _, n := range []int{2, 3} {
+		if err := w.Send("increment", n); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.Send("sum", 100000); err != nil {
+		panic(err)
+	}
+	fmt.Println(output)
+}
+`
+
+func TestGenerateRun(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	// the generated code can only be built if the natives can be imported
+	if err := exec.Command(goBin, "list", nativesPath).Run(); err != nil {
+		t.Skip("natives of the test package cannot be imported")
+	}
+
+	dir, err := ioutil.TempDir("_testdata", "run")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := generate(t, golang.Options{Main: "Main", Natives: nativesPath})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.elm.go"), []byte(out), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(driver), 0644))
+
+	result, err := exec.Command(goBin, "run", "./"+dir).CombinedOutput()
+	require.NoError(t, err, string(result))
+	require.Equal(t, "[2 [2] 5 [5 2] 5000050000]\n", string(result))
+}
+
+func TestGenerateRunTemplate(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	if err := exec.Command(goBin, "list", nativesPath).Run(); err != nil {
+		t.Skip("natives of the test package cannot be imported")
+	}
+
+	dir, err := ioutil.TempDir("_testdata", "run")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tmpl, err := target.Default(target.Go)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	out := generate(t, golang.Options{Main: "Main", Natives: nativesPath})
+	require.NoError(t, tmpl.Execute(&buf, target.Data{Main: "Main", Code: out}))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.elm.go"), buf.Bytes(), 0644))
+
+	cmd := exec.Command(goBin, "run", "./"+dir)
+	cmd.Stdin = strings.NewReader(`{"port": "increment", "value": 2}` + "\n" + `{"port": "sum", "value": 10}` + "\n")
+	result, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(result))
+
+	expected := []string{
+		`{"port":"total","value":2}`,
+		`{"port":"history","value":[2]}`,
+		`{"port":"total","value":55}`,
+	}
+	require.Equal(t, strings.Join(expected, "\n")+"\n", string(result))
+}
+
+const showDriver = `package main
+
+import "os"
+
+func main() {
+	if err := Show(os.Stdout); err != nil {
+		panic(err)
+	}
+}
+`
+
+func TestGenerateRunShow(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	if err := exec.Command(goBin, "list", nativesPath).Run(); err != nil {
+		t.Skip("natives of the test package cannot be imported")
+	}
+
+	dir, err := ioutil.TempDir("_testdata", "run")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	initial := &ir.Global{Module: "Main", Name: "initial", Typ: codegentest.Lower(t, "_testdata/worker").Lookup("Main").Lookup("initial").Type}
+	out := generate(t, golang.Options{Natives: nativesPath, Show: []*ir.Global{initial}})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "show.elm.go"), []byte(out), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(showDriver), 0644))
+
+	result, err := exec.Command(goBin, "run", "./"+dir).CombinedOutput()
+	require.NoError(t, err, string(result))
+	require.Equal(t, "{\"history\":[],\"total\":0}\n", string(result))
+}
+
+func TestGolden(t *testing.T) {
+	codegentest.Test(t, "_testdata/*", codegentest.Backend{
+		Name: "go",
+		Generate: func(pkg *ir.Package) ([]byte, error) {
+			var buf bytes.Buffer
+			err := golang.Generate(&buf, pkg, golang.Options{Main: "Main", Natives: nativesPath})
+			return buf.Bytes(), err
+		},
+		Run: func(t *testing.T, code, input []byte) ([]byte, error) {
+			goBin, err := exec.LookPath("go")
+			if err != nil {
+				t.Skip("go is not installed")
+			}
+
+			if err := exec.Command(goBin, "list", nativesPath).Run(); err != nil {
+				t.Skip("natives of the test package cannot be imported")
+			}
+
+			dir, err := ioutil.TempDir("_testdata", "run")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			tmpl, err := target.Default(target.Go)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, tmpl.Execute(&buf, target.Data{Main: "Main", Code: string(code)}))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.elm.go"), buf.Bytes(), 0644))
+
+			cmd := exec.Command(goBin, "run", "./"+dir)
+			cmd.Stdin = bytes.NewReader(input)
+			return cmd.CombinedOutput()
+		},
+	})
+}
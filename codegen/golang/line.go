@@ -0,0 +1,61 @@
+package golang
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/elm-tangram/tangram/token"
+)
+
+// directive returns a line directive with the given position in the module
+// being generated, or nothing if there are no line directives or no
+// position. Directives are block comments, because they are written inside
+// functions, and line comments are only directives at the start of a line,
+// which gofmt would indent. The path of the file is absolute, because go
+// resolves relative paths from the directory of the generated file, unless
+// it is trimmed.
+func (g *generator) directive(pos token.Pos) string {
+	if g.sources == nil || pos == token.NoPos || g.err != nil {
+		return ""
+	}
+
+	if err := g.sources.Add(g.path); err != nil {
+		g.err = err
+		return ""
+	}
+
+	lp, err := g.sources.Source(g.path).LinePos(pos)
+	if err != nil {
+		g.err = err
+		return ""
+	}
+
+	path, err := filepath.Abs(g.path)
+	if err != nil {
+		g.err = err
+		return ""
+	}
+
+	if g.trimPath != "" {
+		if path, err = trimPath(g.trimPath, path); err != nil {
+			g.err = err
+			return ""
+		}
+	}
+	return fmt.Sprintf("/*line %s:%d*/", path, lp.Line)
+}
+
+// trimPath returns the path relative to the given directory, with forward
+// slashes.
+func trimPath(dir, path string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,77 @@
+package golang
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ffi"
+	"github.com/elm-tangram/tangram/ir"
+)
+
+// opNames are the names used for the characters of operators in the names
+// of the Go variables of globals.
+var opNames = map[rune]string{
+	'+':  "plus",
+	'-':  "minus",
+	'*':  "times",
+	'/':  "slash",
+	'=':  "eq",
+	'<':  "lt",
+	'>':  "gt",
+	'|':  "bar",
+	'&':  "amp",
+	':':  "colon",
+	'.':  "dot",
+	'^':  "caret",
+	'%':  "percent",
+	'!':  "bang",
+	'?':  "question",
+	'~':  "tilde",
+	'#':  "hash",
+	'@':  "at",
+	'$':  "dollar",
+	'\\': "backslash",
+}
+
+// globalName returns the name of the Go variable of a global, which is
+// the name of the module, with underscores instead of dots, and the name of
+// the global, such as `Platform_Cmd_batch`. Operators are written as `op`
+// followed by the names of their characters, such as `Basics_op_plus_plus`
+// for `++`.
+func globalName(module, name string) string {
+	prefix := strings.Replace(module, ".", "_", -1) + "_"
+	if name == "" || isIdentStart(rune(name[0])) {
+		return prefix + name
+	}
+
+	var parts = []string{"op"}
+	for _, r := range name {
+		if op, ok := opNames[r]; ok {
+			parts = append(parts, op)
+		} else {
+			parts = append(parts, fmt.Sprintf("u%x", r))
+		}
+	}
+	return prefix + strings.Join(parts, "_")
+}
+
+// nativeName returns the Go name of a native of the given native module,
+// such as `native.ListCons` for `Native.List.cons`, or `rt.PlatformBatch`
+// for `Native.Platform.batch`.
+func nativeName(module, name string) string {
+	if module == "Native.Platform" {
+		return "rt." + ffi.GoName(module, name)
+	}
+	return "native." + ffi.GoName(module, name)
+}
+
+// localName returns the name of the Go variable of a local, which always
+// ends with its ID, so it never clashes with other locals, the globals or
+// with reserved words.
+func localName(v *ir.Local) string {
+	return fmt.Sprintf("%s_%d", v.Name, v.ID)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
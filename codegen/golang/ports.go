@@ -0,0 +1,70 @@
+package golang
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/types"
+)
+
+// toGo returns the rt.Converter from Elm values of the given type to the
+// Go values that are sent through outgoing ports.
+func (g *generator) toGo(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "List":
+			return fmt.Sprintf("rt.ListToGo(%s)", g.toGo(t.Args[0]))
+		case t.Module == "Maybe" && t.Name == "Maybe":
+			return fmt.Sprintf("rt.MaybeToGo(%s)", g.toGo(t.Args[0]))
+		}
+	case *types.Tuple:
+		var elems = make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = g.toGo(e)
+		}
+		return fmt.Sprintf("rt.TupleToGo(%s)", joinArgs(elems))
+	case *types.Record:
+		return fmt.Sprintf("rt.RecordToGo(%s)", fieldConverters(t, g.toGo))
+	}
+	return "rt.Identity"
+}
+
+// fromGo returns the rt.Converter from the Go values received through
+// incoming ports, or given as flags, to Elm values of the given type.
+func (g *generator) fromGo(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "Int":
+			return "rt.DecodeInt"
+		case t.Module == "" && t.Name == "Float":
+			return "rt.DecodeFloat"
+		case t.Module == "" && t.Name == "Bool":
+			return "rt.DecodeBool"
+		case t.Module == "" && t.Name == "String":
+			return "rt.DecodeString"
+		case t.Module == "" && t.Name == "List":
+			return fmt.Sprintf("rt.ListFromGo(%s)", g.fromGo(t.Args[0]))
+		case t.Module == "Maybe" && t.Name == "Maybe":
+			return fmt.Sprintf("rt.MaybeFromGo(%s)", g.fromGo(t.Args[0]))
+		}
+	case *types.Tuple:
+		var elems = make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = g.fromGo(e)
+		}
+		return fmt.Sprintf("rt.TupleFromGo(%s)", joinArgs(elems))
+	case *types.Record:
+		return fmt.Sprintf("rt.RecordFromGo(%s)", fieldConverters(t, g.fromGo))
+	}
+	return "rt.Identity"
+}
+
+func fieldConverters(t *types.Record, converter func(types.Type) string) string {
+	var fields []string
+	for _, name := range t.FieldNames() {
+		fields = append(fields, fmt.Sprintf("%q: %s", name, converter(t.Fields[name])))
+	}
+	return "map[string]rt.Converter{" + strings.Join(fields, ", ") + "}"
+}
@@ -0,0 +1,235 @@
+package rt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// Converter converts values between Elm and Go when they are sent through
+// ports or given as flags. In Go, lists and tuples are slices, Maybe values
+// are nil or the value they contain, records are maps with string keys and
+// the rest of values are the same as in Elm.
+type Converter func(Value) (Value, error)
+
+// ConvertError is returned when a Go value cannot be converted to an Elm
+// value of the expected type.
+type ConvertError struct {
+	// Expected is a description of the expected value.
+	Expected string
+	// Value that could not be converted.
+	Value interface{}
+}
+
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("rt: expecting %s but got %#v", e.Expected, e.Value)
+}
+
+// Show writes the value converted to Go as JSON to w, in its own line.
+func Show(w io.Writer, v Value, c Converter) error {
+	converted, err := c(v)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(converted)
+}
+
+// Identity is the converter that returns values as they are.
+func Identity(v Value) (Value, error) {
+	return v, nil
+}
+
+// ListToGo returns a converter from Elm lists to Go slices.
+func ListToGo(elem Converter) Converter {
+	return func(v Value) (Value, error) {
+		var result = []interface{}{}
+		for _, e := range ToSlice(v) {
+			converted, err := elem(e)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, converted)
+		}
+		return result, nil
+	}
+}
+
+// MaybeToGo returns a converter from Elm Maybe values to Go. Nothing is
+// converted to nil.
+func MaybeToGo(value Converter) Converter {
+	return func(v Value) (Value, error) {
+		if c := v.(*Ctor); c.Tag == justTag {
+			return value(c.Args[0])
+		}
+		return nil, nil
+	}
+}
+
+// TupleToGo returns a converter from Elm tuples to Go slices.
+func TupleToGo(elems ...Converter) Converter {
+	return func(v Value) (Value, error) {
+		var result = make([]interface{}, len(elems))
+		for i, e := range v.(Tuple) {
+			converted, err := elems[i](e)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	}
+}
+
+// RecordToGo returns a converter from Elm records to Go maps.
+func RecordToGo(fields map[string]Converter) Converter {
+	return func(v Value) (Value, error) {
+		var result = make(map[string]interface{})
+		for k, e := range v.(Record) {
+			converted, err := fields[k](e)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = converted
+		}
+		return result, nil
+	}
+}
+
+// DecodeInt converts any Go integer to an Elm Int.
+func DecodeInt(v Value) (Value, error) {
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(r.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(r.Uint()), nil
+	}
+	return nil, &ConvertError{"an Int", v}
+}
+
+// DecodeFloat converts any Go number to an Elm Float.
+func DecodeFloat(v Value) (Value, error) {
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), nil
+	}
+
+	if i, err := DecodeInt(v); err == nil {
+		return float64(i.(int)), nil
+	}
+	return nil, &ConvertError{"a Float", v}
+}
+
+// DecodeBool converts a Go bool to an Elm Bool.
+func DecodeBool(v Value) (Value, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return nil, &ConvertError{"a Bool", v}
+}
+
+// DecodeString converts a Go string to an Elm String.
+func DecodeString(v Value) (Value, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return nil, &ConvertError{"a String", v}
+}
+
+// ListFromGo returns a converter from any Go slice or array to Elm lists.
+func ListFromGo(elem Converter) Converter {
+	return func(v Value) (Value, error) {
+		elems, err := decodeSlice(v)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, e := range elems {
+			if elems[i], err = elem(e); err != nil {
+				return nil, err
+			}
+		}
+		return List(elems...), nil
+	}
+}
+
+// MaybeFromGo returns a converter from Go values to Elm Maybe values. nil
+// is converted to Nothing.
+func MaybeFromGo(value Converter) Converter {
+	return func(v Value) (Value, error) {
+		if v == nil {
+			return C(nothingTag), nil
+		}
+
+		converted, err := value(v)
+		if err != nil {
+			return nil, err
+		}
+		return C(justTag, converted), nil
+	}
+}
+
+// TupleFromGo returns a converter from Go slices or arrays to Elm tuples.
+func TupleFromGo(elems ...Converter) Converter {
+	return func(v Value) (Value, error) {
+		values, err := decodeSlice(v)
+		if err != nil || len(values) != len(elems) {
+			return nil, &ConvertError{fmt.Sprintf("a tuple of %d elements", len(elems)), v}
+		}
+
+		var result = make(Tuple, len(elems))
+		for i, e := range values {
+			if result[i], err = elems[i](e); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// RecordFromGo returns a converter from Go maps with string keys to Elm
+// records.
+func RecordFromGo(fields map[string]Converter) Converter {
+	return func(v Value) (Value, error) {
+		r := reflect.ValueOf(v)
+		if r.Kind() != reflect.Map || r.Type().Key().Kind() != reflect.String {
+			return nil, &ConvertError{"a map with string keys", v}
+		}
+
+		var keys []string
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var result = make(Record)
+		for _, k := range keys {
+			field := fields[k]
+			value := r.MapIndex(reflect.ValueOf(k).Convert(r.Type().Key()))
+			if !value.IsValid() {
+				return nil, &ConvertError{fmt.Sprintf("a map with the key %q", k), v}
+			}
+
+			converted, err := field(value.Interface())
+			if err != nil {
+				return nil, err
+			}
+			result[k] = converted
+		}
+		return result, nil
+	}
+}
+
+func decodeSlice(v Value) ([]Value, error) {
+	r := reflect.ValueOf(v)
+	if r.Kind() != reflect.Slice && r.Kind() != reflect.Array {
+		return nil, &ConvertError{"a slice", v}
+	}
+
+	var result = make([]Value, r.Len())
+	for i := range result {
+		result[i] = r.Index(i).Interface()
+	}
+	return result, nil
+}
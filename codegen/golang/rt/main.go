@@ -0,0 +1,113 @@
+package rt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// message is a value sent through a port, which is written as a line of
+// JSON such as `{"port": "input", "value": 2}`.
+type message struct {
+	Port  string      `json:"port"`
+	Value interface{} `json:"value"`
+}
+
+// Run starts the worker with the given flags and runs it until there is no
+// more input. Every line of the input is a message with a value for an
+// incoming port, and the values sent through the outgoing ports are written
+// to out as messages in the same format:
+//
+//	{"port": "input", "value": 2}
+func Run(w *Worker, flags interface{}, in io.Reader, out io.Writer) error {
+	var names []string
+	for name, p := range w.ports.ports {
+		if !p.incoming {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var werr error
+	enc := json.NewEncoder(out)
+	for _, name := range names {
+		name := name
+		err := w.Subscribe(name, func(value interface{}) {
+			if err := enc.Encode(message{name, value}); err != nil && werr == nil {
+				werr = err
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.Start(fromJSON(flags)); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() && werr == nil {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return fmt.Errorf("rt: can't decode message %q: %s", line, err)
+		}
+
+		if err := w.Send(msg.Port, fromJSON(msg.Value)); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return werr
+}
+
+// Main runs the worker with the standard input and output, as Run does, and
+// exits when the input ends. The first argument of the command, if any, is
+// the JSON of the flags of the program.
+func Main(w *Worker) {
+	var flags interface{}
+	if len(os.Args) > 1 {
+		if err := json.Unmarshal([]byte(os.Args[1]), &flags); err != nil {
+			fmt.Fprintf(os.Stderr, "rt: can't decode flags: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := Run(w, flags, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// fromJSON returns the decoded JSON value with its integral numbers as
+// ints, so they can be converted to Ints.
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+			return int(v)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = fromJSON(e)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = fromJSON(e)
+		}
+	}
+	return v
+}
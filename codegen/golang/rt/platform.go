@@ -0,0 +1,231 @@
+package rt
+
+import "fmt"
+
+// The tags of the constructors of Maybe, which are declared in the order
+// `Just a | Nothing` in the core package.
+const (
+	justTag    = 0
+	nothingTag = 1
+)
+
+// bag is a command or a subscription.
+type bag struct {
+	kind   bagKind
+	home   string
+	value  Value
+	tagger Value
+	bag    *bag
+	bags   []*bag
+}
+
+type bagKind byte
+
+const (
+	leafBag bagKind = iota
+	batchBag
+	mapBag
+)
+
+// leaf is a command or subscription of a port along with the functions its
+// messages must be transformed with.
+type leaf struct {
+	home    string
+	value   Value
+	taggers []Value
+}
+
+func gather(b *bag, taggers []Value, leaves []*leaf) []*leaf {
+	switch b.kind {
+	case leafBag:
+		leaves = append(leaves, &leaf{b.home, b.value, taggers})
+	case batchBag:
+		for _, b := range b.bags {
+			leaves = gather(b, taggers, leaves)
+		}
+	case mapBag:
+		leaves = gather(b.bag, append([]Value{b.tagger}, taggers...), leaves)
+	}
+	return leaves
+}
+
+// PlatformNone is the native implementation of `Cmd.none` and `Sub.none`.
+var PlatformNone Value = &bag{kind: batchBag}
+
+// PlatformBatch is the native implementation of `Cmd.batch` and
+// `Sub.batch`.
+func PlatformBatch(list Value) Value {
+	var bags []*bag
+	for _, b := range ToSlice(list) {
+		bags = append(bags, b.(*bag))
+	}
+	return &bag{kind: batchBag, bags: bags}
+}
+
+// PlatformMap is the native implementation of `Cmd.map` and `Sub.map`.
+func PlatformMap(tagger, b Value) Value {
+	return &bag{kind: mapBag, tagger: tagger, bag: b.(*bag)}
+}
+
+// Program is a worker program.
+type Program struct {
+	impl  Record
+	flags bool
+}
+
+// PlatformProgram is the native implementation of `Platform.program`.
+func PlatformProgram(impl Value) Value {
+	return &Program{impl: impl.(Record)}
+}
+
+// PlatformProgramWithFlags is the native implementation of
+// `Platform.programWithFlags`.
+func PlatformProgramWithFlags(impl Value) Value {
+	return &Program{impl: impl.(Record), flags: true}
+}
+
+type port struct {
+	incoming  bool
+	converter Converter
+}
+
+// Ports are the ports declared in the modules of a package.
+type Ports struct {
+	ports map[string]*port
+}
+
+// NewPorts returns a new empty set of ports.
+func NewPorts() *Ports {
+	return &Ports{make(map[string]*port)}
+}
+
+// Outgoing declares an outgoing port that sends values converted with the
+// given converter and returns the function that creates its commands.
+func (p *Ports) Outgoing(name string, converter Converter) Value {
+	p.ports[name] = &port{false, converter}
+	return F(1, func(args []Value) Value {
+		return &bag{kind: leafBag, home: name, value: args[0]}
+	})
+}
+
+// Incoming declares an incoming port that receives values converted with
+// the given converter and returns the function that creates its
+// subscriptions.
+func (p *Ports) Incoming(name string, converter Converter) Value {
+	p.ports[name] = &port{true, converter}
+	return F(1, func(args []Value) Value {
+		return &bag{kind: leafBag, home: name, value: args[0]}
+	})
+}
+
+// Worker runs a worker program, which communicates with Go using ports.
+// A Worker is not safe for concurrent use.
+type Worker struct {
+	program     *Program
+	flags       Converter
+	ports       *Ports
+	started     bool
+	model       Value
+	subscribers map[string][]func(interface{})
+	taggers     map[string][]*leaf
+}
+
+// NewWorker returns a new worker of the given program, which must be a
+// Program. flags is the converter of the flags of the program, if any.
+func NewWorker(program Value, flags Converter, ports *Ports) *Worker {
+	return &Worker{
+		program:     program.(*Program),
+		flags:       flags,
+		ports:       ports,
+		subscribers: make(map[string][]func(interface{})),
+		taggers:     make(map[string][]*leaf),
+	}
+}
+
+// Subscribe calls fn with all the values sent through the given outgoing
+// port.
+func (w *Worker) Subscribe(name string, fn func(interface{})) error {
+	if p, ok := w.ports.ports[name]; !ok || p.incoming {
+		return fmt.Errorf("rt: there is no outgoing port %s", name)
+	}
+
+	w.subscribers[name] = append(w.subscribers[name], fn)
+	return nil
+}
+
+// Start starts the program with the given flags, which are ignored if the
+// program has no flags. The commands of the initial state are sent to the
+// ports before it returns.
+func (w *Worker) Start(flags interface{}) error {
+	if w.started {
+		return fmt.Errorf("rt: worker has already been started")
+	}
+
+	init := w.program.impl["init"]
+	if w.program.flags {
+		if w.flags != nil {
+			converted, err := w.flags(flags)
+			if err != nil {
+				return err
+			}
+			flags = converted
+		}
+		init = A(init, flags)
+	}
+
+	w.started = true
+	return w.step(init)
+}
+
+// Send sends a value through the given incoming port.
+func (w *Worker) Send(name string, value interface{}) error {
+	p, ok := w.ports.ports[name]
+	if !ok || !p.incoming {
+		return fmt.Errorf("rt: there is no incoming port %s", name)
+	}
+
+	if !w.started {
+		return fmt.Errorf("rt: worker has not been started")
+	}
+
+	converted, err := p.converter(value)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range w.taggers[name] {
+		msg := A(l.value, converted)
+		for _, t := range l.taggers {
+			msg = A(t, msg)
+		}
+
+		if err := w.step(A(w.program.impl["update"], msg, w.model)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) step(result Value) error {
+	tuple := result.(Tuple)
+	w.model = tuple[0]
+
+	w.taggers = make(map[string][]*leaf)
+	if subs, ok := w.program.impl["subscriptions"]; ok {
+		for _, l := range gather(A(subs, w.model).(*bag), nil, nil) {
+			w.taggers[l.home] = append(w.taggers[l.home], l)
+		}
+	}
+
+	for _, l := range gather(tuple[1].(*bag), nil, nil) {
+		value, err := w.ports.ports[l.home].converter(l.value)
+		if err != nil {
+			return err
+		}
+
+		for _, fn := range w.subscribers[l.home] {
+			fn(value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package rt is the runtime of the Go code generated by the golang backend.
+// Both the generated code and the native modules written in Go use it to
+// work with Elm values.
+//
+// Elm values are represented as follows:
+//
+//	Int            int
+//	Float          float64
+//	Bool           bool
+//	String         string
+//	Char           rune
+//	functions      *Func
+//	union types    *Ctor
+//	lists          *Ctor, with the constructors [] (tag 0) and :: (tag 1)
+//	tuples         Tuple
+//	records        Record
+package rt
+
+import "fmt"
+
+// Value is an Elm value.
+type Value interface{}
+
+// Func is a curried Elm function. It keeps the arguments it has been
+// partially applied to, so it is only called when all of them are given.
+type Func struct {
+	arity int
+	args  []Value
+	fn    func(args []Value) Value
+}
+
+// F returns a function with the given arity. fn is called with exactly
+// arity arguments.
+func F(arity int, fn func(args []Value) Value) *Func {
+	return &Func{arity: arity, fn: fn}
+}
+
+// A applies the function f to the given arguments, which may be less or
+// more than the arity of f.
+func A(f Value, args ...Value) Value {
+	for len(args) > 0 {
+		fn, ok := f.(*Func)
+		if !ok {
+			panic(fmt.Errorf("rt: cannot apply %v, it is not a function", f))
+		}
+
+		missing := fn.arity - len(fn.args)
+		if len(args) < missing {
+			all := make([]Value, 0, len(fn.args)+len(args))
+			all = append(append(all, fn.args...), args...)
+			return &Func{arity: fn.arity, args: all, fn: fn.fn}
+		}
+
+		all := make([]Value, 0, fn.arity)
+		all = append(append(all, fn.args...), args[:missing]...)
+		f, args = fn.fn(all), args[missing:]
+	}
+	return f
+}
+
+// Call calls the function f with exactly as many arguments as its arity.
+// It is faster than A, because the arguments do not need to be collected,
+// but f cannot have been partially applied.
+func Call(f Value, args ...Value) Value {
+	return f.(*Func).fn(args)
+}
+
+// Ctor is a value of a union type.
+type Ctor struct {
+	// Tag is the position of the constructor in the declaration of the
+	// union type.
+	Tag int
+	// Args are the arguments of the constructor.
+	Args []Value
+	// Name of the constructor, if it is known. The generated code leaves
+	// it empty, but the interpreter of the eval package sets it, so the
+	// values can be shown.
+	Name string
+}
+
+// C returns a value of a union type with the given tag and arguments.
+func C(tag int, args ...Value) *Ctor {
+	return &Ctor{Tag: tag, Args: args}
+}
+
+// Nil is the empty list.
+var Nil Value = &Ctor{Tag: 0, Name: "[]"}
+
+// Cons returns a list with the given head and tail.
+func Cons(head, tail Value) Value {
+	return &Ctor{Tag: 1, Args: []Value{head, tail}, Name: "::"}
+}
+
+// List returns a list with the given elements.
+func List(elems ...Value) Value {
+	var list = Nil
+	for i := len(elems) - 1; i >= 0; i-- {
+		list = Cons(elems[i], list)
+	}
+	return list
+}
+
+// ToSlice returns the elements of a list.
+func ToSlice(list Value) []Value {
+	var elems []Value
+	for c := list.(*Ctor); c.Tag == 1; c = c.Args[1].(*Ctor) {
+		elems = append(elems, c.Args[0])
+	}
+	return elems
+}
+
+// Tuple is a tuple value.
+type Tuple []Value
+
+// Record is a record value.
+type Record map[string]Value
+
+// Update returns a copy of the record with the given fields replaced.
+func Update(record Value, fields Record) Record {
+	var result = make(Record)
+	for k, v := range record.(Record) {
+		result[k] = v
+	}
+	for k, v := range fields {
+		result[k] = v
+	}
+	return result
+}
+
+// CrashError is the value the code panics with when it crashes, for example
+// when a case expression is not exhaustive.
+type CrashError struct {
+	// Module where the crash happened.
+	Module string
+	// Message describing the crash.
+	Message string
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("rt: ran into a crash in module %s: %s", e.Module, e.Message)
+}
+
+// Crash panics with a CrashError.
+func Crash(module, message string) Value {
+	panic(&CrashError{module, message})
+}
@@ -0,0 +1,133 @@
+package rt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	var calls int
+	add := F(2, func(args []Value) Value {
+		calls++
+		return args[0].(int) + args[1].(int)
+	})
+	adder := F(1, func(args []Value) Value {
+		return A(add, args[0])
+	})
+
+	require.Equal(t, 3, A(add, 1, 2))
+
+	inc := A(add, 1)
+	require.Equal(t, 1, calls)
+	require.Equal(t, 5, A(inc, 4))
+	require.Equal(t, 6, A(inc, 5))
+
+	// more arguments than the arity of the function
+	require.Equal(t, 7, A(adder, 3, 4))
+	require.Equal(t, 4, calls)
+
+	require.Equal(t, 9, Call(add, 4, 5))
+	require.Equal(t, 5, calls)
+}
+
+func TestList(t *testing.T) {
+	list := List(1, 2, 3)
+	require.Equal(t, Cons(1, Cons(2, Cons(3, Nil))), list)
+	require.Equal(t, []Value{1, 2, 3}, ToSlice(list))
+	require.Nil(t, ToSlice(Nil))
+}
+
+func TestUpdate(t *testing.T) {
+	r := Record{"x": 1, "y": 2}
+	require.Equal(t, Record{"x": 3, "y": 2}, Update(r, Record{"x": 3}))
+	require.Equal(t, Record{"x": 1, "y": 2}, r)
+}
+
+func TestCrash(t *testing.T) {
+	defer func() {
+		err := recover().(*CrashError)
+		require.EqualError(t, err, "rt: ran into a crash in module Main: case expression is not exhaustive")
+	}()
+	Crash("Main", "case expression is not exhaustive")
+}
+
+func TestConverters(t *testing.T) {
+	fromGo := RecordFromGo(map[string]Converter{
+		"ids":  ListFromGo(DecodeInt),
+		"name": MaybeFromGo(DecodeString),
+		"pos":  TupleFromGo(DecodeFloat, DecodeFloat),
+	})
+	toGo := RecordToGo(map[string]Converter{
+		"ids":  ListToGo(Identity),
+		"name": MaybeToGo(Identity),
+		"pos":  TupleToGo(Identity, Identity),
+	})
+
+	v, err := fromGo(map[string]interface{}{
+		"ids":  []int64{1, 2},
+		"name": nil,
+		"pos":  []interface{}{1, 2.5},
+	})
+	require.NoError(t, err)
+	require.Equal(t, Record{
+		"ids":  List(1, 2),
+		"name": C(nothingTag),
+		"pos":  Tuple{1., 2.5},
+	}, v)
+
+	back, err := toGo(v)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"ids":  []interface{}{1, 2},
+		"name": nil,
+		"pos":  []interface{}{1., 2.5},
+	}, back)
+
+	_, err = fromGo(map[string]interface{}{"ids": []int{}})
+	require.EqualError(t, err, `rt: expecting a map with the key "name" but got map[string]interface {}{"ids":[]int{}}`)
+
+	_, err = ListFromGo(DecodeInt)([]string{"a"})
+	require.EqualError(t, err, `rt: expecting an Int but got "a"`)
+}
+
+func TestShow(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Show(&out, List(true, false), ListToGo(Identity)))
+	require.NoError(t, Show(&out, Record{"total": 2}, RecordToGo(map[string]Converter{"total": Identity})))
+	require.Equal(t, "[true,false]\n{\"total\":2}\n", out.String())
+}
+
+func TestRun(t *testing.T) {
+	ports := NewPorts()
+	total := ports.Outgoing("total", Identity)
+	add := ports.Incoming("add", DecodeInt)
+	program := PlatformProgramWithFlags(Record{
+		"init": F(1, func(args []Value) Value {
+			return Tuple{args[0], A(total, args[0])}
+		}),
+		"update": F(2, func(args []Value) Value {
+			sum := args[0].(int) + args[1].(int)
+			return Tuple{sum, A(total, sum)}
+		}),
+		"subscriptions": F(1, func(args []Value) Value {
+			return A(add, F(1, func(args []Value) Value { return args[0] }))
+		}),
+	})
+
+	var out bytes.Buffer
+	in := strings.NewReader("{\"port\": \"add\", \"value\": 2}\n\n{\"port\": \"add\", \"value\": 3}\n")
+	w := NewWorker(program, DecodeInt, ports)
+	require.NoError(t, Run(w, float64(1), in, &out))
+	require.Equal(t, "{\"port\":\"total\",\"value\":1}\n{\"port\":\"total\",\"value\":3}\n{\"port\":\"total\",\"value\":6}\n", out.String())
+
+	w = NewWorker(program, DecodeInt, ports)
+	err := Run(w, 0, strings.NewReader("{\"port\": \"sub\", \"value\": 2}\n"), &out)
+	require.EqualError(t, err, "rt: there is no incoming port sub")
+
+	w = NewWorker(program, DecodeInt, ports)
+	err = Run(w, 0, strings.NewReader("not json\n"), &out)
+	require.Error(t, err)
+}
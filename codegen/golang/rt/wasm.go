@@ -0,0 +1,110 @@
+//go:build js && wasm
+// +build js,wasm
+
+package rt
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+// Export exports the worker to JavaScript as the module with the given name
+// of the global Elm object, such as `Elm.Main`, and blocks forever so its
+// functions can be called. Like the workers of the JavaScript targets, the
+// worker is started with `Elm.Main.worker(flags)`, which returns an object
+// with the ports of the program:
+//
+//	var app = Elm.Main.worker(flags);
+//	app.ports.total.subscribe(function(value) { console.log(value); });
+//	app.ports.increment.send(2);
+//
+// The values are converted between JavaScript and Go through JSON, so they
+// are the same as the ones of Run.
+func Export(module string, w *Worker) {
+	elm := js.Global().Get("Elm")
+	if elm.IsUndefined() {
+		elm = js.Global().Get("Object").New()
+		js.Global().Set("Elm", elm)
+	}
+
+	mod := js.Global().Get("Object").New()
+	mod.Set("worker", js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		var flags interface{}
+		if len(args) > 0 {
+			flags = toGo(args[0])
+		}
+
+		ports := js.Global().Get("Object").New()
+		for name, p := range w.ports.ports {
+			ports.Set(name, exportPort(w, name, p.incoming))
+		}
+
+		if err := w.Start(fromJSON(flags)); err != nil {
+			consoleError(err)
+		}
+		return map[string]interface{}{"ports": ports}
+	}))
+	elm.Set(module, mod)
+
+	select {}
+}
+
+// exportPort returns the JavaScript object of the port with the given name,
+// which has a send function if it is incoming and a subscribe function if
+// it is outgoing.
+func exportPort(w *Worker, name string, incoming bool) js.Value {
+	port := js.Global().Get("Object").New()
+	if incoming {
+		port.Set("send", js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+			var value interface{}
+			if len(args) > 0 {
+				value = toGo(args[0])
+			}
+
+			if err := w.Send(name, fromJSON(value)); err != nil {
+				consoleError(err)
+			}
+			return nil
+		}))
+		return port
+	}
+
+	port.Set("subscribe", js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		fn := args[0]
+		w.Subscribe(name, func(value interface{}) {
+			fn.Invoke(toJS(value))
+		})
+		return nil
+	}))
+	return port
+}
+
+// toGo returns the Go value of the JavaScript value, decoded from its JSON.
+func toGo(v js.Value) interface{} {
+	if v.IsUndefined() {
+		return nil
+	}
+
+	var value interface{}
+	data := js.Global().Get("JSON").Call("stringify", v).String()
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		consoleError(err)
+	}
+	return value
+}
+
+// toJS returns the JavaScript value of the Go value, parsed from its JSON.
+func toJS(v interface{}) js.Value {
+	data, err := json.Marshal(v)
+	if err != nil {
+		consoleError(err)
+		return js.Null()
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
+
+// consoleError reports the error to the console of JavaScript, since the
+// errors of the functions called from JavaScript cannot be returned.
+func consoleError(err error) {
+	js.Global().Get("console").Call("error", err.Error())
+}
@@ -0,0 +1,18 @@
+module Debug exposing (..)
+
+import Native.Debug
+
+
+log : String -> a -> a
+log =
+    Native.Debug.log
+
+
+toString : a -> String
+toString =
+    Native.Debug.toString
+
+
+crash : String -> a
+crash =
+    Native.Debug.crash
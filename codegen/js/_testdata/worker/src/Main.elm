@@ -0,0 +1,120 @@
+port module Main exposing (..)
+
+import Basics exposing (..)
+import Debug
+import List exposing (..)
+import Platform exposing (..)
+import Platform.Cmd as Cmd exposing (..)
+import Platform.Sub as Sub exposing (..)
+
+
+port increment : (Int -> msg) -> Sub msg
+
+
+port total : Int -> Cmd msg
+
+
+port history : List Int -> Cmd msg
+
+
+port sum : (Int -> msg) -> Sub msg
+
+
+port show : (Int -> msg) -> Sub msg
+
+
+port inspect : String -> Cmd msg
+
+
+port load : (Model -> msg) -> Sub msg
+
+
+port adders : (Int -> msg) -> Sub msg
+
+
+type alias Model =
+    { total : Int
+    , history : List Int
+    }
+
+
+type Msg
+    = Increment Int
+    | Reset
+    | Sum Int
+    | Show Int
+    | Load Model
+    | Adders Int
+
+
+sumTo : Int -> Int -> Int
+sumTo n acc =
+    if n == 0 then
+        acc
+    else
+        sumTo (n - 1) (acc + n)
+
+
+update : Msg -> Model -> ( Model, Cmd Msg )
+update msg model =
+    case msg of
+        Increment n ->
+            let
+                next =
+                    { model | total = model.total + n, history = (model.total + n) :: model.history }
+            in
+                ( next, Cmd.batch [ total next.total, history next.history ] )
+
+        Reset ->
+            ( { total = 0, history = [] }, Cmd.none )
+
+        Sum n ->
+            ( { model | total = sumTo n 0 }, total (sumTo n 0) )
+
+        Show n ->
+            ( model, inspect (Debug.toString ( Increment n, Show (0 - n), model )) )
+
+        Load loaded ->
+            ( loaded, total loaded.total )
+
+        Adders n ->
+            ( model, total (applyAll (makeAdders n []) 0) )
+
+
+main : Program Never Model Msg
+main =
+    program
+        { init = ( { total = 0, history = [] }, Cmd.none )
+        , update = update
+        , subscriptions = \_ -> Sub.batch [ increment Increment, sum Sum, show Show, load Load, adders Adders ]
+        }
+
+
+shown : Msg -> Int
+shown msg =
+    case msg of
+        Show n ->
+            n
+
+
+makeAdders : Int -> List (Int -> Int) -> List (Int -> Int)
+makeAdders n acc =
+    if n == 0 then
+        acc
+    else
+        makeAdders (n - 1) ((\x -> x + n) :: acc)
+
+
+applyAll : List (Int -> Int) -> Int -> Int
+applyAll adders acc =
+    case adders of
+        [] ->
+            acc
+
+        adder :: rest ->
+            applyAll rest (adder acc)
+
+
+smiley : ( String, Int )
+smiley =
+    ( "😀", sumTo 1 0 )
@@ -0,0 +1,95 @@
+package js
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/types"
+)
+
+// toSnapshot returns a JavaScript function that converts Elm values of the
+// given type to the JSON of the state snapshots of bundles built for
+// debugging. Lists are arrays, tuples are arrays and records are objects,
+// like in the ports, and the values of union types are kept as they are,
+// with the names of their constructors, since their types are not known.
+func (g *generator) toSnapshot(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "List":
+			return fmt.Sprintf(
+				"function(v) { return _toArray(v).map(%s); }",
+				g.toSnapshot(t.Args[0]),
+			)
+		case t.Module == "" && isPrimitive(t.Name):
+			return "_identity"
+		}
+		return "_toSnapshot"
+	case *types.Tuple:
+		var elems = make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = fmt.Sprintf("(%s)(v[%d])", g.toSnapshot(e), i)
+		}
+		return fmt.Sprintf("function(v) { return [%s]; }", strings.Join(elems, ", "))
+	case *types.Record:
+		var fields []string
+		for _, name := range t.FieldNames() {
+			fields = append(fields, fmt.Sprintf("%s: (%s)(v.%s)", name, g.toSnapshot(t.Fields[name]), name))
+		}
+		return fmt.Sprintf("function(v) { return { %s }; }", strings.Join(fields, ", "))
+	case *types.Func:
+		return "_snapshotFunction"
+	}
+	return "_toSnapshot"
+}
+
+// fromSnapshot returns a JavaScript function that converts the JSON of a
+// state snapshot made by toSnapshot back to Elm values of the given type.
+// The function throws an error if the value does not have the expected
+// type, which says where the unexpected value is, so the snapshots of an
+// older version of the program are not imported by mistake.
+func (g *generator) fromSnapshot(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "List":
+			return fmt.Sprintf(
+				"function(v) { return _list(_decodeArray(v).map(function(e, i) { return _index(v, i, %s); })); }",
+				g.fromSnapshot(t.Args[0]),
+			)
+		case t.Module == "" && (t.Name == "String" || t.Name == "Char"):
+			return "_decodeString"
+		case t.Module == "" && isPrimitive(t.Name):
+			return "_decode" + t.Name
+		}
+		return "_fromSnapshot"
+	case *types.Tuple:
+		var elems = make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = fmt.Sprintf("_index(v, %d, %s)", i, g.fromSnapshot(e))
+		}
+		return fmt.Sprintf(
+			"function(v) { _decodeTuple(%d)(v); return [%s]; }",
+			len(t.Elems), strings.Join(elems, ", "),
+		)
+	case *types.Record:
+		var fields []string
+		for _, name := range t.FieldNames() {
+			fields = append(fields, fmt.Sprintf("%s: _field(v, %q, %s)", name, name, g.fromSnapshot(t.Fields[name])))
+		}
+		return fmt.Sprintf("function(v) { _decodeObject(v); return { %s }; }", strings.Join(fields, ", "))
+	case *types.Func:
+		return "_snapshotFunction"
+	}
+	return "_fromSnapshot"
+}
+
+// isPrimitive reports whether the builtin type with the given name is one
+// whose values are the same in JavaScript and in JSON.
+func isPrimitive(name string) bool {
+	switch name {
+	case "Int", "Float", "Bool", "String", "Char":
+		return true
+	}
+	return false
+}
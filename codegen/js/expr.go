@@ -0,0 +1,349 @@
+package js
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// expr returns the JavaScript expression of the given expression. Let and
+// case expressions are compiled to statements, so they are wrapped in a
+// function that is called immediately.
+func (g *generator) expr(e ir.Expr) string {
+	switch e := e.(type) {
+	case *ir.Local:
+		return localName(e)
+	case *ir.Global:
+		return globalName(e.Module, e.Name)
+	case *ir.Lit:
+		return literal(e)
+	case *ir.Ctor:
+		return g.ctor(e)
+	case *ir.Lambda:
+		return g.mark(e.Pos) + g.lambda(e)
+	case *ir.App:
+		return g.mark(e.Pos) + g.app(e)
+	case *ir.Call:
+		return g.mark(e.Pos) + g.call(e)
+	case *ir.Let, *ir.Case:
+		return fmt.Sprintf("(%s())", g.function(nil, e, false))
+	case *ir.Tuple:
+		return "[" + g.exprs(e.Elems) + "]"
+	case *ir.Index:
+		return fmt.Sprintf("%s[%d]", g.expr(e.Expr), e.Index)
+	case *ir.Record:
+		return "{ " + g.fields(e.Fields) + " }"
+	case *ir.Access:
+		return g.expr(e.Expr) + "." + e.Field
+	case *ir.Update:
+		return fmt.Sprintf("_update(%s, { %s })", g.expr(e.Record), g.fields(e.Fields))
+	case *ir.List:
+		if len(e.Elems) == 0 {
+			return "_nil"
+		}
+		return "_list([" + g.exprs(e.Elems) + "])"
+	case *ir.Probe:
+		return fmt.Sprintf("(_coverage[%d]++, %s)", e.ID, g.expr(e.Expr))
+	case *ir.Fail:
+		if g.debug && e.Pos != token.NoPos {
+			return fmt.Sprintf("_crash(%q, %q, \"%s\")", e.Module, e.Msg, g.location(e.Pos))
+		}
+		return fmt.Sprintf("_crash(%q, %q)", e.Module, e.Msg)
+	}
+	return fmt.Sprintf("_crash(%q, %q)", "", fmt.Sprintf("unsupported expression %T", e))
+}
+
+func (g *generator) exprs(exprs []ir.Expr) string {
+	var result = make([]string, len(exprs))
+	for i, e := range exprs {
+		result[i] = g.expr(e)
+	}
+	return strings.Join(result, ", ")
+}
+
+func (g *generator) fields(fields []*ir.Field) string {
+	var result = make([]string, len(fields))
+	for i, f := range fields {
+		result[i] = f.Name + ": " + g.expr(f.Expr)
+	}
+	return strings.Join(result, ", ")
+}
+
+// lambda returns a function expression. Functions with more than one
+// parameter are wrapped with _F.
+func (g *generator) lambda(e *ir.Lambda) string {
+	fn := g.function(e.Params, e.Body, ir.HasTailCalls(e))
+	if len(e.Params) == 1 {
+		return fn
+	}
+	return fmt.Sprintf("_F(%d, %s)", len(e.Params), fn)
+}
+
+// function returns a function expression with the given parameters that
+// returns the value of the given body. The body of functions with tail
+// calls is a loop, and the tail calls assign the new values of the
+// parameters and continue with the next iteration.
+//
+// The variables of JavaScript are scoped to the function, so if the body
+// has functions that may capture the parameters or the variables of an
+// iteration, every iteration is a function of its own, called with the
+// values of the parameters, and its tail calls return the arguments of the
+// next one in a _Tail.
+func (g *generator) function(params []*ir.Local, body ir.Expr, loop bool) string {
+	var names = make([]string, len(params))
+	for i, p := range params {
+		names[i] = localName(p)
+	}
+
+	buf, indent, outer, scoped := g.buf, g.indent, g.params, g.scoped
+	g.buf, g.params, g.scoped = new(bytes.Buffer), nil, false
+	g.indent++
+	if loop && hasLambdas(body) {
+		g.params, g.scoped = params, true
+		args := strings.Join(names, ", ")
+		g.line("while (true) {")
+		g.indent++
+		g.line("var $r = (function(%s) {", args)
+		g.indent++
+		g.stmts(body)
+		g.indent--
+		g.line("})(%s);", args)
+		g.line("if (!($r instanceof _Tail)) {")
+		g.line("\treturn $r;")
+		g.line("}")
+		for i, name := range names {
+			g.line("%s = $r.args[%d];", name, i)
+		}
+		g.indent--
+		g.line("}")
+	} else if loop {
+		g.params = params
+		g.line("while (true) {")
+		g.indent++
+		g.stmts(body)
+		g.indent--
+		g.line("}")
+	} else {
+		g.stmts(body)
+	}
+
+	result := fmt.Sprintf(
+		"function(%s) {\n%s%s}",
+		strings.Join(names, ", "),
+		g.buf.String(),
+		strings.Repeat("\t", indent),
+	)
+	g.buf, g.indent, g.params, g.scoped = buf, indent, outer, scoped
+	return result
+}
+
+// hasLambdas reports whether there are functions in the expression, which
+// may capture its variables.
+func hasLambdas(e ir.Expr) bool {
+	var found bool
+	ir.Inspect(e, func(e ir.Expr) bool {
+		if _, ok := e.(*ir.Lambda); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// tailCall writes a tail call of the function being generated.
+func (g *generator) tailCall(e *ir.TailCall) {
+	if g.scoped {
+		g.line("return new _Tail([%s]);", g.exprs(e.Args))
+		return
+	}
+
+	if len(e.Args) == 1 {
+		if e.Args[0] != g.params[0] {
+			g.line("%s = %s;", localName(g.params[0]), g.expr(e.Args[0]))
+		}
+		g.line("continue;")
+		return
+	}
+
+	// all the arguments are evaluated before the parameters change
+	var temps []string
+	for i, arg := range e.Args {
+		if arg != g.params[i] {
+			temps = append(temps, fmt.Sprintf("$%d = %s", i, g.expr(arg)))
+		}
+	}
+	if len(temps) > 0 {
+		g.line("var %s;", strings.Join(temps, ", "))
+	}
+	for i, arg := range e.Args {
+		if arg != g.params[i] {
+			g.line("%s = $%d;", localName(g.params[i]), i)
+		}
+	}
+	g.line("continue;")
+}
+
+func (g *generator) app(e *ir.App) string {
+	if ctor, ok := e.Func.(*ir.Ctor); ok && ctor.Arity == len(e.Args) {
+		if ctor.Module == "" && ctor.Name == "::" {
+			return fmt.Sprintf("_cons(%s)", g.exprs(e.Args))
+		}
+		return g.newCtor(ctor, g.exprs(e.Args))
+	}
+
+	fn := g.expr(e.Func)
+	if _, ok := e.Func.(*ir.Lambda); ok {
+		fn = "(" + fn + ")"
+	}
+
+	if len(e.Args) == 1 {
+		return fmt.Sprintf("%s(%s)", fn, g.expr(e.Args[0]))
+	}
+	return fmt.Sprintf("_A(%s, [%s])", fn, g.exprs(e.Args))
+}
+
+// call returns a direct call of a known function. Functions with several
+// parameters are called through the uncurried function kept by _F.
+func (g *generator) call(e *ir.Call) string {
+	fn := g.expr(e.Func)
+	if len(e.Args) == 1 {
+		return fmt.Sprintf("%s(%s)", fn, g.expr(e.Args[0]))
+	}
+	return fmt.Sprintf("%s.f(%s)", fn, g.exprs(e.Args))
+}
+
+// ctor returns the value of a constructor that is not applied to all its
+// arguments, which is a function that creates the value.
+func (g *generator) ctor(c *ir.Ctor) string {
+	if c.Module == "" && c.Union == "List" {
+		if c.Name == "[]" {
+			return "_nil"
+		}
+		return "_F(2, _cons)"
+	}
+
+	if c.Arity == 0 {
+		return g.newCtor(c, "")
+	}
+
+	var params = make([]string, c.Arity)
+	for i := range params {
+		params[i] = fmt.Sprintf("a%d", i)
+	}
+
+	args := strings.Join(params, ", ")
+	fn := fmt.Sprintf("function(%s) { return %s; }", args, g.newCtor(c, args))
+	if c.Arity == 1 {
+		return fn
+	}
+	return fmt.Sprintf("_F(%d, %s)", c.Arity, fn)
+}
+
+// newCtor returns the creation of a value of the constructor with the given
+// arguments. Values keep the name of their constructor in debug mode.
+func (g *generator) newCtor(c *ir.Ctor, args string) string {
+	if g.debug {
+		return fmt.Sprintf("_ctor(%d, [%s], %q)", c.Tag, args, c.Name)
+	}
+	return fmt.Sprintf("_ctor(%d, [%s])", c.Tag, args)
+}
+
+// stmts writes the statements that return the value of the expression.
+func (g *generator) stmts(e ir.Expr) {
+	switch e := e.(type) {
+	case *ir.TailCall:
+		g.tailCall(e)
+	case *ir.Let:
+		for _, b := range ir.SortBinds(e.Binds) {
+			g.line("var %s = %s;", localName(b.Var), g.expr(b.Expr))
+		}
+		g.stmts(e.Body)
+	case *ir.Probe:
+		g.line("_coverage[%d]++;", e.ID)
+		g.stmts(e.Expr)
+	case *ir.Case:
+		if len(e.Alts) > 0 && e.Alts[0].Ctor != nil {
+			g.switchCase(e)
+		} else {
+			g.ifCase(e)
+		}
+	default:
+		g.line("return %s;", g.expr(e))
+	}
+}
+
+// switchCase writes a case expression on constructors as a switch on the
+// tag of the subject.
+func (g *generator) switchCase(e *ir.Case) {
+	subject := localName(e.Subject)
+	g.line("switch (%s.$) {", subject)
+	g.indent++
+	for i, alt := range e.Alts {
+		if e.Default == nil && i == len(e.Alts)-1 {
+			g.line("default:")
+		} else {
+			g.line("case %d:", alt.Ctor.Tag)
+		}
+
+		g.indent++
+		for j, v := range alt.Vars {
+			g.line("var %s = %s.a[%d];", localName(v), subject, j)
+		}
+		g.stmts(alt.Body)
+		g.indent--
+	}
+
+	if e.Default != nil {
+		g.line("default:")
+		g.indent++
+		g.stmts(e.Default)
+		g.indent--
+	}
+	g.indent--
+	g.line("}")
+}
+
+// ifCase writes a case expression on literals as a chain of conditions.
+func (g *generator) ifCase(e *ir.Case) {
+	subject := localName(e.Subject)
+	for i, alt := range e.Alts {
+		if e.Default == nil && i == len(e.Alts)-1 {
+			g.stmts(alt.Body)
+			return
+		}
+
+		g.line("if (%s === %s) {", subject, literal(alt.Lit))
+		g.indent++
+		g.stmts(alt.Body)
+		g.indent--
+		g.line("}")
+	}
+
+	if e.Default != nil {
+		g.stmts(e.Default)
+	}
+}
+
+// literal returns the JavaScript literal of the given literal. Characters
+// are strings with only one character.
+func literal(lit *ir.Lit) string {
+	switch lit.Kind {
+	case ast.Bool:
+		if lit.Value == "True" {
+			return "true"
+		}
+		return "false"
+	case ast.String:
+		if strings.HasPrefix(lit.Value, `"""`) {
+			s, _ := json.Marshal(strings.TrimSuffix(strings.TrimPrefix(lit.Value, `"""`), `"""`))
+			return string(s)
+		}
+		return lit.Value
+	}
+	return lit.Value
+}
@@ -0,0 +1,210 @@
+// Package js implements the JavaScript backend of the compiler, which
+// compiles a package lowered to the core IR to a single JavaScript bundle
+// that can run in browsers and in node.
+//
+// The bundle exports the main program of a module in the `Elm` object of
+// the global scope, or of `module.exports` in node, just like the official
+// compiler does. Only worker programs, created with `Platform.program` and
+// `Platform.programWithFlags`, are supported, and they communicate with
+// JavaScript using ports:
+//
+//	var app = Elm.Main.worker(flags);
+//	app.ports.output.subscribe(function(value) { ... });
+//	app.ports.input.send(value);
+//
+// The programs of the bundles built for debugging can also export and
+// import snapshots of their state, which are JSON objects with the model,
+// for time-travel debugging and the reproductions of bug reports:
+//
+//	var snapshot = app.debug.exportState();
+//	app.debug.importState(app.debug.history()[0]);
+package js
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Options are the options of the code generation.
+type Options struct {
+	// Main is the name of the module whose main program is exported, if
+	// any.
+	Main string
+	// Natives contains the JavaScript source of the native modules by
+	// module name, such as `Native.List`. The source of a native module
+	// must assign the module to a variable with the name returned by
+	// NativeName, which Kernel makes sure of. The runtime already provides `Native.Platform` and
+	// `Native.Debug`.
+	Natives map[string]string
+	// SourceMap is where the source map of the bundle is written, if it is
+	// not nil. The sources of the map are the paths of the modules.
+	SourceMap io.Writer
+	// SourceMapURL is the URL of the source map, which is linked at the end
+	// of the bundle if it is not empty.
+	SourceMapURL string
+	// Loader loads the source code of the modules, to find the lines and
+	// columns of the positions of the source map and of the crashes. It
+	// should be the loader the package was parsed with. By default, the
+	// files are read from the disk.
+	Loader source.Loader
+	// Debug reports whether the bundle is built for debugging. The values
+	// of union types keep the names of their constructors, so
+	// `Debug.toString` and `Debug.log` can show them, and crashes report
+	// where they happened in the source code. The programs keep all their
+	// models, which can be exported and imported as snapshots.
+	Debug bool
+	// Minify reports whether the bundle is built for production, with
+	// short names for the globals and locals and without indentation.
+	// Bundles cannot be minified and built for debugging at once.
+	Minify bool
+	// Show are the globals whose values are written to the console when
+	// the bundle runs, one per line, the same way `Debug.toString` shows
+	// them. The REPL uses them to show the values of the expressions.
+	Show []*ir.Global
+	// Coverage reports whether the counts of the probes of an instrumented
+	// package are written to the console as a JSON array, by the IDs of
+	// the probes, after the shown values.
+	Coverage bool
+	// Context makes Generate stop when it is done, before the next module
+	// is compiled, if it is not nil. Generate returns the error of the
+	// context then.
+	Context context.Context
+}
+
+// Generate writes the JavaScript bundle of the given package to w. It is
+// the same as compiling every module and linking the objects.
+func Generate(w io.Writer, pkg *ir.Package, opts Options) error {
+	var objs = make([]*Object, len(pkg.Modules))
+	for i, mod := range pkg.Modules {
+		if opts.Context != nil && opts.Context.Err() != nil {
+			return opts.Context.Err()
+		}
+		objs[i] = Compile(mod, "", opts)
+	}
+	return Link(w, pkg, objs, opts)
+}
+
+type generator struct {
+	buf    *bytes.Buffer
+	indent int
+	// params are the parameters of the function being generated if its
+	// body is a loop, and scoped reports whether every iteration of the
+	// loop is a function of its own.
+	params []*ir.Local
+	scoped bool
+	// sourceMap reports whether the positions of the expressions are
+	// marked in the generated code, and marks are the marked positions.
+	sourceMap bool
+	marks     []mark
+	// debug reports whether the code is generated for debugging.
+	debug bool
+	// minifier shortens the names of the code being linked, if it is
+	// minified.
+	minifier *minifier
+	// path is the path of the module being generated.
+	path string
+}
+
+// global returns the name of a global in the code being linked, which is
+// shortened if the code is minified.
+func (g *generator) global(module, name string) string {
+	if g.minifier != nil {
+		return g.minifier.name(globalName(module, name))
+	}
+	return globalName(module, name)
+}
+
+func (g *generator) raw(s string) {
+	g.buf.WriteString(s)
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("\t", g.indent))
+	fmt.Fprintf(g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) module(mod *ir.Module) {
+	g.path = mod.Path
+	g.raw("\n")
+	for _, p := range mod.Ports {
+		arg, incoming := portValue(p.Type)
+		if incoming {
+			g.line("var %s = _incomingPort(%q, %s);", globalName(mod.Name, p.Name), p.Name, g.fromJS(arg))
+		} else {
+			g.line("var %s = _outgoingPort(%q, %s);", globalName(mod.Name, p.Name), p.Name, g.toJS(arg))
+		}
+	}
+
+	for _, def := range ir.SortDefs(mod) {
+		g.line("%svar %s = %s;", g.mark(def.Pos), globalName(mod.Name, def.Name), g.expr(def.Expr))
+	}
+}
+
+// export exports the main program of the given module.
+func (g *generator) export(pkg *ir.Package, module string) error {
+	mod := pkg.Lookup(module)
+	if mod == nil {
+		return fmt.Errorf("js: there is no module %s", module)
+	}
+
+	main := mod.Lookup("main")
+	if main == nil {
+		return fmt.Errorf("js: module %s has no main", module)
+	}
+
+	program, ok := types.Prune(main.Type).(*types.Named)
+	if !ok || program.Module != "Platform" || program.Name != "Program" || len(program.Args) != 3 {
+		return fmt.Errorf(
+			"js: main has type %s, but only Platform programs are supported",
+			types.TypeString(main.Type),
+		)
+	}
+
+	var flags = "null"
+	if !isNever(program.Args[0]) {
+		flags = g.fromJS(program.Args[0])
+	}
+
+	var snapshots string
+	if g.debug {
+		snapshots = fmt.Sprintf(
+			", { encode: %s, decode: %s }",
+			g.toSnapshot(program.Args[1]), g.fromSnapshot(program.Args[1]),
+		)
+	}
+
+	g.raw("\n")
+	g.line(
+		"_export(scope, %q, { worker: _worker(%s, %s%s) });",
+		module, g.global(module, "main"), flags, snapshots,
+	)
+	return nil
+}
+
+// portValue returns the type of the values sent by an outgoing port,
+// `a -> Cmd msg`, or received by an incoming port, `(a -> msg) -> Sub msg`.
+func portValue(t types.Type) (value types.Type, incoming bool) {
+	fn, ok := types.Prune(t).(*types.Func)
+	if !ok {
+		return nil, false
+	}
+
+	result, ok := types.Prune(fn.Result).(*types.Named)
+	if tagger, isFunc := types.Prune(fn.Arg).(*types.Func); ok && isFunc && result.Name == "Sub" {
+		return tagger.Arg, true
+	}
+	return fn.Arg, false
+}
+
+func isNever(t types.Type) bool {
+	n, ok := types.Prune(t).(*types.Named)
+	return ok && n.Module == "Basics" && n.Name == "Never"
+}
@@ -0,0 +1,544 @@
+package js_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/codegen/codegentest"
+	"github.com/elm-tangram/tangram/codegen/js"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+var natives = map[string]string{
+	"Native.Basics": `var Native$Basics = {
+	add: _F(2, function(a, b) { return a + b; }),
+	sub: _F(2, function(a, b) { return a - b; }),
+	eq: _F(2, function(a, b) { return a === b; })
+};`,
+	"Native.List": `var Native$List = {
+	cons: _F(2, _cons)
+};`,
+}
+
+func generate(t *testing.T, opts js.Options) string {
+	var buf bytes.Buffer
+	require.NoError(t, js.Generate(&buf, codegentest.Lower(t, "_testdata/worker"), opts))
+	return buf.String()
+}
+
+func TestGenerate(t *testing.T) {
+	out := generate(t, js.Options{Main: "Main", Natives: natives})
+
+	expected := []string{
+		"var Native$Basics = {",
+		`var $Main$increment = _incomingPort("increment", _decodeInt);`,
+		`var $Main$total = _outgoingPort("total", _identity);`,
+		`var $Main$history = _outgoingPort("history", function(v) { return _toArray(v).map(_identity); });`,
+		"var $Basics$$plus = Native$Basics.add;",
+		"var $Main$update = _F(2, function(msg_4, model_5) {",
+		"switch (msg_4.$) {",
+		"var $Main$sumTo = _F(2, function(n_1, acc_2) {\n\twhile (true) {",
+		"var $0 = _A(Native$Basics.sub, [n_1, 1]), $1 = _A(Native$Basics.add, [acc_2, n_1]);\n\t\tn_1 = $0;\n\t\tacc_2 = $1;\n\t\tcontinue;",
+		"$Main$total($Main$sumTo.f(n_8, 0))",
+		// the closures of an iteration capture its own parameters
+		"var $Main$makeAdders = _F(2, function(n_15, acc_16) {\n\twhile (true) {\n\t\tvar $r = (function(n_15, acc_16) {",
+		"return new _Tail([_A(Native$Basics.sub, [n_15, 1]), ",
+		"})(n_15, acc_16);\n\t\tif (!($r instanceof _Tail)) {\n\t\t\treturn $r;\n\t\t}\n\t\tn_15 = $r.args[0];\n\t\tacc_16 = $r.args[1];",
+		`_export(scope, "Main", { worker: _worker($Main$main, null) });`,
+	}
+	for _, e := range expected {
+		require.Contains(t, out, e)
+	}
+
+	// globals are declared before they are used
+	require.True(t, strings.Index(out, "var $Main$update =") < strings.Index(out, "var $Main$main ="))
+}
+
+func TestGenerateSourceMap(t *testing.T) {
+	var buf bytes.Buffer
+	out := generate(t, js.Options{
+		Main:         "Main",
+		Natives:      natives,
+		SourceMap:    &buf,
+		SourceMapURL: "bundle.js.map",
+	})
+	require.NotContains(t, out, "\x00")
+	require.True(t, strings.HasSuffix(out, "\n//# sourceMappingURL=bundle.js.map\n"))
+
+	var m struct {
+		Version  int
+		Sources  []string
+		Mappings string
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	require.Equal(t, 3, m.Version)
+	require.Contains(t, m.Sources, "_testdata/worker/src/Main.elm")
+
+	lines := strings.Split(out, "\n")
+	mappings := strings.Split(m.Mappings, ";")
+	// the comment with the URL of the source map has no mappings
+	require.Len(t, mappings, len(lines)-1)
+
+	// the definition of sumTo is mapped to its line in the source code
+	src, err := ioutil.ReadFile("_testdata/worker/src/Main.elm")
+	require.NoError(t, err)
+	sumTo := strings.Count(string(src[:strings.Index(string(src), "sumTo :")]), "\n")
+
+	var srcLine int
+	for i, l := range lines {
+		for _, seg := range strings.Split(mappings[i], ",") {
+			if seg != "" {
+				srcLine += decodeVLQ(seg)[2]
+			}
+		}
+
+		if strings.HasPrefix(l, "var $Main$sumTo =") {
+			require.NotEmpty(t, mappings[i])
+			require.Equal(t, sumTo, srcLine-lastLine(mappings[i]))
+			return
+		}
+	}
+	t.Fatal("sumTo is not defined")
+}
+
+// lastLine returns the sum of the source line deltas of the segments of a
+// line of mappings after the first one.
+func lastLine(mapping string) int {
+	var n int
+	for _, seg := range strings.Split(mapping, ",")[1:] {
+		n += decodeVLQ(seg)[2]
+	}
+	return n
+}
+
+func decodeVLQ(seg string) []int {
+	const digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	var values []int
+	var v, shift int
+	for _, c := range seg {
+		d := strings.IndexRune(digits, c)
+		v |= (d & 31) << uint(shift)
+		shift += 5
+		if d&32 != 0 {
+			continue
+		}
+
+		if v&1 != 0 {
+			values = append(values, -(v >> 1))
+		} else {
+			values = append(values, v>>1)
+		}
+		v, shift = 0, 0
+	}
+	return values
+}
+
+func TestGenerateSourceMapColumns(t *testing.T) {
+	var buf bytes.Buffer
+	out := generate(t, js.Options{Main: "Main", Natives: natives, SourceMap: &buf})
+
+	var m struct{ Mappings string }
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+
+	// the columns are counted in UTF-16 code units, and the smiley is two
+	lines := strings.Split(out, "\n")
+	mappings := strings.Split(m.Mappings, ";")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "var $Main$smiley =") {
+			require.Equal(t, `var $Main$smiley = ["😀", $Main$sumTo.f(1, 0)];`, l)
+			segments := strings.Split(mappings[i], ",")
+			require.Len(t, segments, 2)
+			require.Equal(t, len(`var $Main$smiley = ["`)+2+len(`", `), decodeVLQ(segments[1])[0])
+			return
+		}
+	}
+	t.Fatal("smiley is not defined")
+}
+
+func TestGenerateSourceMapLoader(t *testing.T) {
+	require := require.New(t)
+	pkg := codegentest.Lower(t, "_testdata/worker")
+
+	var expected bytes.Buffer
+	require.NoError(js.Generate(ioutil.Discard, pkg, js.Options{Main: "Main", Natives: natives, SourceMap: &expected}))
+
+	// the sources are the ones of the loader, not the files
+	loader := source.NewMemLoader()
+	var buf bytes.Buffer
+	err := js.Generate(ioutil.Discard, pkg, js.Options{Main: "Main", Natives: natives, SourceMap: &buf, Loader: loader})
+	require.Error(err)
+
+	paths, err := filepath.Glob("_testdata/worker/src/*.elm")
+	require.NoError(err)
+	platform, err := filepath.Glob("_testdata/worker/src/Platform/*.elm")
+	require.NoError(err)
+	for _, path := range append(paths, platform...) {
+		src, err := ioutil.ReadFile(path)
+		require.NoError(err)
+		loader.Add(path, string(src))
+	}
+
+	buf.Reset()
+	require.NoError(js.Generate(ioutil.Discard, pkg, js.Options{Main: "Main", Natives: natives, SourceMap: &buf, Loader: loader}))
+	require.Equal(expected.String(), buf.String())
+}
+
+func TestGenerateDebug(t *testing.T) {
+	out := generate(t, js.Options{Main: "Main", Natives: natives, Debug: true})
+
+	expected := []string{
+		"var Native$Debug = {",
+		`$Main$inspect(Native$Debug.toString([_ctor(0, [n_9], "Increment"), _ctor(3, [_A(Native$Basics.sub, [0, n_9])], "Show"), model_5]))`,
+		`$Main$show(function(a0) { return _ctor(3, [a0], "Show"); })`,
+		`_crash("Main", "case expression is not exhaustive", "_testdata/worker/src/Main.elm:95:5")`,
+	}
+	for _, e := range expected {
+		require.Contains(t, out, e)
+	}
+
+	out = generate(t, js.Options{Main: "Main", Natives: natives})
+	require.Contains(t, out, `_crash("Main", "case expression is not exhaustive")`)
+	require.NotContains(t, out, `"Increment"`)
+}
+
+func TestGenerateMinify(t *testing.T) {
+	out := generate(t, js.Options{Main: "Main", Natives: natives, Minify: true})
+	require.NotContains(t, out, "$Main$")
+	require.NotContains(t, out, "n_1")
+	require.Contains(t, out, "{\nwhile (true) {\nvar ")
+	require.NotContains(t, out, "\n\n\n")
+	require.Contains(t, out, "history: _A(Native$List.cons")
+	require.Regexp(t, `_export\(scope, "Main", \{ worker: _worker\(\$[a-zA-Z]+, null\) \}\);`, out)
+
+	var buf bytes.Buffer
+	err := js.Generate(&buf, codegentest.Lower(t, "_testdata/worker"), js.Options{Main: "Main", Debug: true, Minify: true})
+	require.EqualError(t, err, "js: bundles built for debugging cannot be minified")
+}
+
+func TestLink(t *testing.T) {
+	require := require.New(t)
+	root, err := ioutil.TempDir(os.TempDir(), "tangram-js")
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	pkg := codegentest.Lower(t, "_testdata/worker")
+	cache := js.NewCache(root)
+	for _, mod := range pkg.Modules {
+		_, err := cache.Load(mod.Name, "hash")
+		require.Equal(js.ErrNotFound, err)
+		require.NoError(cache.Save(js.Compile(mod, "hash", js.Options{})))
+	}
+
+	_, err = cache.Load("Main", "other")
+	require.Equal(js.ErrStale, err)
+
+	var objs []*js.Object
+	for _, mod := range pkg.Modules {
+		obj, err := cache.Load(mod.Name, "hash")
+		require.NoError(err)
+		require.Equal(js.Compile(mod, "hash", js.Options{}), obj)
+		objs = append(objs, obj)
+	}
+
+	// linking the cached objects is the same as generating the bundle
+	var expectedMap bytes.Buffer
+	opts := js.Options{Main: "Main", Natives: natives, SourceMap: &expectedMap}
+	expected := generate(t, opts)
+
+	var buf, sourceMap bytes.Buffer
+	opts.SourceMap = &sourceMap
+	require.NoError(js.Link(&buf, pkg, objs, opts))
+	require.Equal(expected, buf.String())
+	require.Equal(expectedMap.String(), sourceMap.String())
+
+	buf.Reset()
+	require.NoError(js.Link(&buf, pkg, objs, js.Options{Main: "Main", Natives: natives}))
+	require.Equal(generate(t, js.Options{Main: "Main", Natives: natives}), buf.String())
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pkg, err := parser.Parse("_testdata/worker/src/Main.elm", parser.FullParse)
+	require.NoError(t, err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(t, conf.Check(pkg, info))
+
+	var buf bytes.Buffer
+	err = js.Generate(&buf, ir.Lower(pkg, info), js.Options{Main: "Foo"})
+	require.EqualError(t, err, "js: there is no module Foo")
+
+	err = js.Generate(&buf, ir.Lower(pkg, info), js.Options{Main: "Basics"})
+	require.EqualError(t, err, "js: module Basics has no main")
+}
+
+const driver = `
+var app = require(process.argv[1]).Elm.Main.worker();
+var output = [];
+app.ports.total.subscribe(function(total) { output.push(total); });
+app.ports.history.subscribe(function(history) { output.push(history); });
+app.ports.increment.send(2);
+app.ports.increment.send(3);
+app.ports.sum.send(100000);
+console.log(JSON.stringify(output));
+`
+
+const portsDriver = `
+var app = require(process.argv[1]).Elm.Main.worker();
+function attempt(port, value) {
+	try {
+		app.ports[port].send(value);
+	} catch (e) {
+		console.log(e.message);
+	}
+}
+
+app.ports.total.subscribe(function(total) { console.log(total); });
+attempt('increment', 'a');
+attempt('load', null);
+attempt('load', { total: 1 });
+attempt('load', { total: 1, history: [1, 'b'] });
+attempt('load', { total: 3, history: [1, 2] });
+`
+
+const debugDriver = `
+var app = require(process.argv[1]).Elm.Main.worker();
+app.ports.inspect.subscribe(function(value) { console.log(value); });
+app.ports.increment.send(2);
+app.ports.show.send(3);
+`
+
+const snapshotDriver = `
+var app = require(process.argv[1]).Elm.Main.worker();
+app.ports.total.subscribe(function(total) { console.log(total); });
+app.ports.increment.send(2);
+app.ports.increment.send(3);
+console.log(JSON.stringify(app.debug.exportState()));
+console.log(JSON.stringify(app.debug.history()));
+
+app.debug.importState(JSON.parse(JSON.stringify(app.debug.history()[1])));
+app.ports.increment.send(1);
+console.log(JSON.stringify(app.debug.exportState()));
+
+try {
+	app.debug.importState({ model: { total: 'a', history: [] } });
+} catch (e) {
+	console.log(e.message);
+}
+console.log(JSON.stringify(app.debug.exportState()));
+`
+
+func TestGenerateRun(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "tangram-js")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, minify := range []bool{false, true} {
+		bundle := filepath.Join(dir, "bundle.js")
+		out := generate(t, js.Options{Main: "Main", Natives: natives, Minify: minify})
+		require.NoError(t, ioutil.WriteFile(bundle, []byte(out), 0644))
+
+		result, err := exec.Command(node, "-e", driver, bundle).CombinedOutput()
+		require.NoError(t, err, string(result))
+		require.Equal(t, "[2,[2],5,[5,2],5000050000]\n", string(result), "minify: %v", minify)
+	}
+}
+
+func TestGenerateDebugRun(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "tangram-js")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "bundle.js")
+	out := generate(t, js.Options{Main: "Main", Natives: natives, Debug: true})
+	require.NoError(t, ioutil.WriteFile(bundle, []byte(out), 0644))
+
+	result, err := exec.Command(node, "-e", debugDriver, bundle).CombinedOutput()
+	require.NoError(t, err, string(result))
+	require.Equal(t, "(Increment 3,Show (-3),{ total = 2, history = [2] })\n", string(result))
+}
+
+func TestGenerateRunSnapshots(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "tangram-js")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "bundle.js")
+	out := generate(t, js.Options{Main: "Main", Natives: natives, Debug: true})
+	require.NoError(t, ioutil.WriteFile(bundle, []byte(out), 0644))
+
+	result, err := exec.Command(node, "-e", snapshotDriver, bundle).CombinedOutput()
+	require.NoError(t, err, string(result))
+
+	expected := []string{
+		"2",
+		"5",
+		`{"model":{"history":[5,2],"total":5}}`,
+		`[{"model":{"history":[],"total":0}},{"model":{"history":[2],"total":2}},{"model":{"history":[5,2],"total":5}}]`,
+		"3",
+		`{"model":{"history":[3,2],"total":3}}`,
+		`Trying to import an unexpected snapshot: Problem with the field model: Problem with the field total: Expecting an Int but got "a"`,
+		`{"model":{"history":[3,2],"total":3}}`,
+	}
+	require.Equal(t, strings.Join(expected, "\n")+"\n", string(result))
+
+	out = generate(t, js.Options{Main: "Main", Natives: natives})
+	require.NotContains(t, out, "encode:")
+}
+
+func TestGenerateRunShow(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	out := generate(t, js.Options{Natives: natives, Debug: true, Show: []*ir.Global{
+		{Module: "Main", Name: "sumTo"},
+		{Module: "Main", Name: "main"},
+	}})
+	require.Contains(t, out, "console.log(_toString($Main$sumTo));")
+
+	cmd := exec.Command(node)
+	cmd.Stdin = strings.NewReader(out)
+	result, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(result))
+	require.Equal(t, "<function>\n{ impl = { init = ({ total = 0, history = [] },<internals>), update = <function>, subscriptions = <function> }, flags = False }\n", string(result))
+}
+
+func TestGenerateRunPorts(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "tangram-js")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "bundle.js")
+	out := generate(t, js.Options{Main: "Main", Natives: natives})
+	require.NoError(t, ioutil.WriteFile(bundle, []byte(out), 0644))
+
+	result, err := exec.Command(node, "-e", portsDriver, bundle).CombinedOutput()
+	require.NoError(t, err, string(result))
+
+	expected := []string{
+		`Trying to send an unexpected value through port increment: Expecting an Int but got "a"`,
+		`Trying to send an unexpected value through port load: Expecting an object but got null`,
+		`Trying to send an unexpected value through port load: Expecting an object with a field named history but got {"total":1}`,
+		`Trying to send an unexpected value through port load: Problem with the field history: Problem with the element at index 1: Expecting an Int but got "b"`,
+		"3",
+	}
+	require.Equal(t, strings.Join(expected, "\n")+"\n", string(result))
+}
+
+func TestGenerateRunTemplate(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "tangram-js")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tmpl, err := target.Default(target.Node)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	out := generate(t, js.Options{Main: "Main", Natives: natives})
+	require.NoError(t, tmpl.Execute(&buf, target.Data{Main: "Main", Code: out}))
+
+	script := filepath.Join(dir, "main.js")
+	require.NoError(t, ioutil.WriteFile(script, buf.Bytes(), 0644))
+
+	cmd := exec.Command(node, script)
+	cmd.Stdin = strings.NewReader(`{"port": "increment", "value": 2}` + "\n" + `{"port": "sum", "value": 10}` + "\n")
+	result, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(result))
+
+	expected := []string{
+		`{"port":"total","value":2}`,
+		`{"port":"history","value":[2]}`,
+		`{"port":"total","value":55}`,
+	}
+	require.Equal(t, strings.Join(expected, "\n")+"\n", string(result))
+}
+
+func TestGolden(t *testing.T) {
+	codegentest.Test(t, "_testdata/*", codegentest.Backend{
+		Name: "js",
+		Generate: func(pkg *ir.Package) ([]byte, error) {
+			var buf bytes.Buffer
+			err := js.Generate(&buf, pkg, js.Options{Main: "Main", Natives: natives})
+			return buf.Bytes(), err
+		},
+		Run: func(t *testing.T, code, input []byte) ([]byte, error) {
+			node, err := exec.LookPath("node")
+			if err != nil {
+				t.Skip("node is not installed")
+			}
+
+			dir, err := ioutil.TempDir("", "tangram-js")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			tmpl, err := target.Default(target.Node)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, tmpl.Execute(&buf, target.Data{Main: "Main", Code: string(code)}))
+
+			script := filepath.Join(dir, "main.js")
+			require.NoError(t, ioutil.WriteFile(script, buf.Bytes(), 0644))
+
+			cmd := exec.Command(node, script)
+			cmd.Stdin = bytes.NewReader(input)
+			return cmd.CombinedOutput()
+		},
+	})
+}
+
+func TestKernel(t *testing.T) {
+	require := require.New(t)
+
+	src := "var Native$List = { cons: _F(2, function(a, b) { return a; }) };\n"
+	kernel, err := js.Kernel("Native.List", src)
+	require.NoError(err)
+	require.Equal(src, kernel)
+
+	src = "var _elm_lang$core$Native_List = function() {\n\treturn { cons: cons };\n}();\n"
+	kernel, err = js.Kernel("Native.List", src)
+	require.NoError(err)
+	require.Equal(src+"var Native$List = _elm_lang$core$Native_List;\n", kernel)
+
+	_, err = js.Kernel("Native.List", "var _elm_lang$core$Native_String = {};\nif (Native$List == null) {}\n")
+	require.EqualError(err, "the JavaScript source of Native.List does not assign it to Native$List")
+}
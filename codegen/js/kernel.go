@@ -0,0 +1,31 @@
+package js
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kernel returns the JavaScript source of the given native module, such as
+// `Native.List`, ready to be one of the natives of Options. The source must
+// assign the module to the variable of NativeName or, like the kernel
+// modules of the Elm packages, to the one of their namespace, such as
+// `_elm_lang$core$Native_List`, which is then assigned to the variable of
+// NativeName too. It is an error if it assigns neither.
+func Kernel(module, src string) (string, error) {
+	if assignment(regexp.QuoteMeta(NativeName(module))).MatchString(src) {
+		return src, nil
+	}
+
+	namespace := `_\w+\$\w+\$` + regexp.QuoteMeta(strings.Replace(module, ".", "_", -1))
+	if m := assignment(namespace).FindStringSubmatch(src); m != nil {
+		return fmt.Sprintf("%s\nvar %s = %s;\n", strings.TrimRight(src, "\n"), NativeName(module), m[1]), nil
+	}
+	return "", fmt.Errorf("the JavaScript source of %s does not assign it to %s", module, NativeName(module))
+}
+
+// assignment returns the expression of the assignment of a variable whose
+// name matches the given pattern, which is its first group.
+func assignment(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\w$.])(` + name + `)\s*=[^=]`)
+}
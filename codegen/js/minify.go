@@ -0,0 +1,142 @@
+package js
+
+import (
+	"bytes"
+	"strings"
+)
+
+// minifier shortens the names of the globals and locals of the generated
+// code. Names are shortened when the objects are linked, so a global has
+// the same name in all the modules that use it.
+//
+// Globals are shortened to `$` and a short name and locals to a short name
+// and `$`, so they never clash with each other, with the runtime and the
+// natives or with the reserved words of JavaScript.
+type minifier struct {
+	names map[string]string
+	count int
+}
+
+func newMinifier() *minifier {
+	return &minifier{names: make(map[string]string)}
+}
+
+const (
+	nameStart = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	nameChars = nameStart + "0123456789"
+)
+
+// name returns the short name of the given global or local.
+func (m *minifier) name(long string) string {
+	if short, ok := m.names[long]; ok {
+		return short
+	}
+
+	var buf = []byte{nameStart[m.count%len(nameStart)]}
+	for n := m.count / len(nameStart); n > 0; n = (n - 1) / len(nameChars) {
+		buf = append(buf, nameChars[(n-1)%len(nameChars)])
+	}
+	m.count++
+
+	short := string(buf)
+	if long[0] == '$' {
+		short = "$" + short
+	} else {
+		short += "$"
+	}
+	m.names[long] = short
+	return short
+}
+
+// minify returns the generated code with shorter names and without
+// indentation and empty lines. Properties, such as the fields of records,
+// keep their names, because they may be used from JavaScript.
+func (m *minifier) minify(code string) string {
+	code = strings.TrimLeft(code, "\n")
+	var buf bytes.Buffer
+	var prev byte
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(code) && code[end] != c {
+				if code[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			buf.WriteString(code[i : end+1])
+			i = end + 1
+		case isIdentStart(rune(c)) || c == '$':
+			end := i + 1
+			for end < len(code) && isIdentChar(code[end]) {
+				end++
+			}
+
+			ident := code[i:end]
+			if prev != '.' && !isKey(code[end:]) && (isGlobal(ident) || isLocal(ident)) {
+				ident = m.name(ident)
+			}
+			buf.WriteString(ident)
+			i = end
+		case c == '\n':
+			buf.WriteByte(c)
+			for i++; i < len(code) && (code[i] == '\t' || code[i] == '\n'); i++ {
+			}
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+
+		if buf.Len() > 0 {
+			prev = buf.Bytes()[buf.Len()-1]
+		}
+	}
+	return buf.String()
+}
+
+// compact removes the indentation, the empty lines and the comments of the
+// runtime.
+func compact(code string) string {
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "//") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(rune(c)) || c == '$' || ('0' <= c && c <= '9')
+}
+
+// isGlobal reports whether the identifier is the name of a global, which
+// starts with `$` and the name of its module.
+func isGlobal(ident string) bool {
+	return len(ident) > 1 && ident[0] == '$' && 'A' <= ident[1] && ident[1] <= 'Z'
+}
+
+// isLocal reports whether the identifier is the name of a local, which
+// ends with `_` and its ID.
+func isLocal(ident string) bool {
+	i := strings.LastIndexByte(ident, '_')
+	if i < 0 || i == len(ident)-1 || strings.ContainsRune(ident, '$') {
+		return false
+	}
+
+	for _, c := range ident[i+1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isKey reports whether the code after an identifier makes it the key of
+// an object literal.
+func isKey(rest string) bool {
+	return strings.HasPrefix(strings.TrimLeft(rest, " "), ":")
+}
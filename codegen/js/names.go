@@ -0,0 +1,75 @@
+package js
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ir"
+)
+
+// opNames are the names used for the characters of operators in the names
+// of the JavaScript variables of globals.
+var opNames = map[rune]string{
+	'+':  "plus",
+	'-':  "minus",
+	'*':  "times",
+	'/':  "slash",
+	'=':  "eq",
+	'<':  "lt",
+	'>':  "gt",
+	'|':  "bar",
+	'&':  "amp",
+	':':  "colon",
+	'.':  "dot",
+	'^':  "caret",
+	'%':  "percent",
+	'!':  "bang",
+	'?':  "question",
+	'~':  "tilde",
+	'#':  "hash",
+	'@':  "at",
+	'$':  "dollar",
+	'\\': "backslash",
+}
+
+// NativeName returns the name of the JavaScript variable the native module
+// with the given name, such as `Native.List`, must be assigned to.
+func NativeName(module string) string {
+	return strings.Replace(module, ".", "$", -1)
+}
+
+// globalName returns the name of the JavaScript variable of a global. It
+// starts with `$`, so it never clashes with the names of locals, natives and
+// the runtime. Operators are written as `$$` and the names of their
+// characters separated with `$`, such as `$Basics$$plus$plus` for `++`.
+func globalName(module, name string) string {
+	if strings.HasPrefix(module, "Native.") {
+		return NativeName(module) + "." + name
+	}
+
+	prefix := "$" + strings.Replace(module, ".", "$", -1) + "$"
+	if name == "" || isIdentStart(rune(name[0])) {
+		return prefix + name
+	}
+
+	var parts []string
+	for _, r := range name {
+		if op, ok := opNames[r]; ok {
+			parts = append(parts, op)
+		} else {
+			parts = append(parts, fmt.Sprintf("u%x", r))
+		}
+	}
+	return prefix + "$" + strings.Join(parts, "$")
+}
+
+// localName returns the name of the JavaScript variable of a local, which
+// always ends with its ID, so it never clashes with other locals or with
+// reserved words.
+func localName(v *ir.Local) string {
+	return fmt.Sprintf("%s_%d", v.Name, v.ID)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
@@ -0,0 +1,228 @@
+package js
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/token"
+)
+
+const (
+	elmStuffDir = "elm-stuff"
+	objectsDir  = "objects"
+	objectExt   = ".js.json"
+)
+
+var (
+	// ErrNotFound is returned when there is no object for a module.
+	ErrNotFound = errors.New("js: object not found")
+	// ErrStale is returned when the object of a module was compiled from a
+	// different version of its source code.
+	ErrStale = errors.New("js: object is stale")
+)
+
+// Object is the code of a single module, compiled on its own so it can be
+// linked in the bundle again if the module does not change.
+type Object struct {
+	// Module is the name of the module.
+	Module string `json:"module"`
+	// Hash is the hash of the source code the object was compiled from.
+	Hash string `json:"hash"`
+	// Path is the path to the file of the module.
+	Path string `json:"path"`
+	// Code is the code of the definitions of the module, with the marks of
+	// the positions of the source code that go in the source maps.
+	Code string `json:"code"`
+	// Marks are the positions of the marks of the code.
+	Marks []token.Pos `json:"marks,omitempty"`
+}
+
+// Compile compiles the given module to an object with the given options,
+// although only Debug changes the code of the object. Hash is the hash of
+// the source code of the module and of every module it imports, because
+// the code of the functions of other modules may be inlined in it, and it
+// must also tell apart the objects compiled for debugging.
+func Compile(mod *ir.Module, hash string, opts Options) *Object {
+	g := &generator{buf: new(bytes.Buffer), sourceMap: true, debug: opts.Debug}
+	g.module(mod)
+
+	obj := &Object{Module: mod.Name, Hash: hash, Path: mod.Path, Code: g.buf.String()}
+	for _, m := range g.marks {
+		obj.Marks = append(obj.Marks, m.pos)
+	}
+	return obj
+}
+
+// Link writes the JavaScript bundle of the given package to w with the
+// code of the given objects, which must be in the same order as the
+// modules of the package.
+func Link(w io.Writer, pkg *ir.Package, objs []*Object, opts Options) error {
+	if opts.Debug && opts.Minify {
+		return fmt.Errorf("js: bundles built for debugging cannot be minified")
+	}
+
+	g := &generator{buf: new(bytes.Buffer), sourceMap: opts.SourceMap != nil, debug: opts.Debug}
+	g.raw("(function(scope) {\n'use strict';\n\n")
+	if opts.Minify {
+		g.minifier = newMinifier()
+		g.raw(compact(runtime))
+	} else {
+		g.raw(runtime)
+	}
+
+	if len(pkg.Points) > 0 {
+		g.raw("\nvar _coverage = [" + strings.TrimSuffix(strings.Repeat("0, ", len(pkg.Points)), ", ") + "];\n")
+	}
+
+	var natives []string
+	for name := range opts.Natives {
+		natives = append(natives, name)
+	}
+	sort.Strings(natives)
+	for _, name := range natives {
+		g.raw("\n")
+		g.raw(strings.TrimSpace(opts.Natives[name]))
+		g.raw("\n")
+	}
+
+	for _, obj := range objs {
+		g.path = obj.Path
+		if g.minifier != nil {
+			g.raw(g.minifier.minify(g.relocate(obj)))
+		} else {
+			g.raw(g.relocate(obj))
+		}
+	}
+
+	if opts.Main != "" {
+		if err := g.export(pkg, opts.Main); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Show) > 0 {
+		g.raw("\n")
+	}
+	for _, s := range opts.Show {
+		g.line("console.log(_toString(%s));", g.global(s.Module, s.Name))
+	}
+
+	if opts.Coverage && len(pkg.Points) > 0 {
+		g.line("console.log(JSON.stringify(_coverage));")
+	}
+
+	g.raw("\n}(typeof module !== 'undefined' && module.exports ? module.exports : this));\n")
+
+	// marks are only resolved if there are source maps or crash locations
+	code := g.buf.Bytes()
+	if opts.SourceMap != nil || len(g.marks) > 0 {
+		resolved, m, err := g.resolve(code, opts.Loader)
+		if err != nil {
+			return err
+		}
+		code = resolved
+
+		if opts.SourceMap != nil {
+			data, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+
+			if _, err := opts.SourceMap.Write(append(data, '\n')); err != nil {
+				return err
+			}
+
+			if opts.SourceMapURL != "" {
+				code = append(code, "//# sourceMappingURL="+opts.SourceMapURL+"\n"...)
+			}
+		}
+	}
+
+	_, err := w.Write(code)
+	return err
+}
+
+// WriteObject encodes the object to the given writer.
+func WriteObject(w io.Writer, obj *Object) error {
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		return fmt.Errorf("js: can't encode object of %s: %s", obj.Module, err)
+	}
+	return nil
+}
+
+// ReadObject decodes an object from the given reader.
+func ReadObject(r io.Reader) (*Object, error) {
+	var obj Object
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("js: can't decode object: %s", err)
+	}
+	return &obj, nil
+}
+
+// Cache stores the objects of the modules of a package in a directory.
+type Cache struct {
+	dir string
+}
+
+// NewCache creates a new cache of objects for the package at the given
+// root. Objects are stored in the elm-stuff directory of the package.
+func NewCache(root string) *Cache {
+	return &Cache{filepath.Join(root, elmStuffDir, objectsDir)}
+}
+
+func (c *Cache) path(module string) string {
+	return filepath.Join(c.dir, module+objectExt)
+}
+
+// Load returns the object of the given module if it was compiled from
+// source code with the given hash. ErrNotFound will be returned if there is
+// no object for the module and ErrStale if the object does not match the
+// hash.
+func (c *Cache) Load(module, hash string) (*Object, error) {
+	f, err := os.Open(c.path(module))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("js: can't open object of %s: %s", module, err)
+	}
+	defer f.Close()
+
+	obj, err := ReadObject(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if obj.Hash != hash {
+		return nil, ErrStale
+	}
+
+	return obj, nil
+}
+
+// Save writes the given object to the cache, replacing the previous one, if
+// any.
+func (c *Cache) Save(obj *Object) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("js: can't create objects directory: %s", err)
+	}
+
+	f, err := os.Create(c.path(obj.Module))
+	if err != nil {
+		return fmt.Errorf("js: can't create object of %s: %s", obj.Module, err)
+	}
+
+	if err := WriteObject(f, obj); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
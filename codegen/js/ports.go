@@ -0,0 +1,105 @@
+package js
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/types"
+)
+
+// The tags of the constructors of Maybe, which are declared in the order
+// `Just a | Nothing` in the core package.
+const (
+	justTag    = 0
+	nothingTag = 1
+)
+
+// toJS returns a JavaScript function that converts Elm values of the given
+// type to the JavaScript values that are sent through outgoing ports.
+func (g *generator) toJS(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "List":
+			return fmt.Sprintf(
+				"function(v) { return _toArray(v).map(%s); }",
+				g.toJS(t.Args[0]),
+			)
+		case t.Module == "Maybe" && t.Name == "Maybe":
+			return fmt.Sprintf(
+				"function(v) { return v.$ === %d ? (%s)(v.a[0]) : null; }",
+				justTag, g.toJS(t.Args[0]),
+			)
+		}
+		return "_identity"
+	case *types.Tuple:
+		if len(t.Elems) == 0 {
+			return "function(v) { return null; }"
+		}
+
+		var elems = make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = fmt.Sprintf("(%s)(v[%d])", g.toJS(e), i)
+		}
+		return fmt.Sprintf("function(v) { return [%s]; }", strings.Join(elems, ", "))
+	case *types.Record:
+		var fields []string
+		for _, name := range t.FieldNames() {
+			fields = append(fields, fmt.Sprintf("%s: (%s)(v.%s)", name, g.toJS(t.Fields[name]), name))
+		}
+		return fmt.Sprintf("function(v) { return { %s }; }", strings.Join(fields, ", "))
+	}
+	return "_identity"
+}
+
+// fromJS returns a JavaScript function that converts the JavaScript values
+// received through incoming ports, or given as flags, to Elm values of the
+// given type. The function throws an error if the value does not have the
+// expected type, which says where the unexpected value is in records,
+// tuples and lists.
+func (g *generator) fromJS(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "Int":
+			return "_decodeInt"
+		case t.Module == "" && t.Name == "Float":
+			return "_decodeFloat"
+		case t.Module == "" && t.Name == "Bool":
+			return "_decodeBool"
+		case t.Module == "" && t.Name == "String":
+			return "_decodeString"
+		case t.Module == "" && t.Name == "List":
+			return fmt.Sprintf(
+				"function(v) { return _list(_decodeArray(v).map(function(e, i) { return _index(v, i, %s); })); }",
+				g.fromJS(t.Args[0]),
+			)
+		case t.Module == "Maybe" && t.Name == "Maybe":
+			return fmt.Sprintf(
+				"function(v) { return v === null || v === undefined ? _ctor(%d, []) : _ctor(%d, [(%s)(v)]); }",
+				nothingTag, justTag, g.fromJS(t.Args[0]),
+			)
+		}
+		return "_identity"
+	case *types.Tuple:
+		if len(t.Elems) == 0 {
+			return "function(v) { return []; }"
+		}
+
+		var elems = make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = fmt.Sprintf("_index(v, %d, %s)", i, g.fromJS(e))
+		}
+		return fmt.Sprintf(
+			"function(v) { _decodeTuple(%d)(v); return [%s]; }",
+			len(t.Elems), strings.Join(elems, ", "),
+		)
+	case *types.Record:
+		var fields []string
+		for _, name := range t.FieldNames() {
+			fields = append(fields, fmt.Sprintf("%s: _field(v, %q, %s)", name, name, g.fromJS(t.Fields[name])))
+		}
+		return fmt.Sprintf("function(v) { _decodeObject(v); return { %s }; }", strings.Join(fields, ", "))
+	}
+	return "_identity"
+}
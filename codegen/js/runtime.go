@@ -0,0 +1,444 @@
+package js
+
+// runtime is the JavaScript code every bundle starts with. It contains the
+// helpers the generated code uses for functions and data, the native
+// implementation of the Platform module and the runtime of worker programs.
+//
+// Functions with more than one parameter are wrapped with _F, which makes
+// them curried while keeping the uncurried function, so _A can call it
+// directly when it is applied to all its arguments at once.
+//
+// Values of union types are objects with the tag of the constructor in `$`
+// and the arguments in `a`. Lists are made of the constructors `[]`, with
+// tag 0, and `::`, with tag 1. Tuples are arrays and records are objects.
+// The name of the constructor is in `n`, but only lists and the values
+// created by bundles built for debugging have it.
+const runtime = `function _F(arity, fun) {
+	function curry(args) {
+		return function(x) {
+			var all = args.concat([x]);
+			return all.length === arity ? fun.apply(null, all) : curry(all);
+		};
+	}
+
+	var curried = curry([]);
+	curried.a = arity;
+	curried.f = fun;
+	return curried;
+}
+
+function _A(fun, args) {
+	while (args.length > 0) {
+		var arity = fun.a;
+		if (arity === args.length) {
+			return fun.f.apply(null, args);
+		}
+
+		if (arity !== undefined && arity < args.length) {
+			fun = fun.f.apply(null, args.slice(0, arity));
+			args = args.slice(arity);
+			continue;
+		}
+
+		fun = fun(args[0]);
+		args = args.slice(1);
+	}
+	return fun;
+}
+
+function _Tail(args) {
+	this.args = args;
+}
+
+function _identity(x) {
+	return x;
+}
+
+function _ctor(tag, args, name) {
+	return { $: tag, a: args, n: name };
+}
+
+var _nil = _ctor(0, [], '[]');
+
+function _cons(head, tail) {
+	return _ctor(1, [head, tail], '::');
+}
+
+function _list(array) {
+	var list = _nil;
+	for (var i = array.length; i--; ) {
+		list = _cons(array[i], list);
+	}
+	return list;
+}
+
+function _toArray(list) {
+	var array = [];
+	while (list.$ === 1) {
+		array.push(list.a[0]);
+		list = list.a[1];
+	}
+	return array;
+}
+
+function _update(record, fields) {
+	var result = {};
+	for (var key in record) {
+		result[key] = record[key];
+	}
+	for (var key in fields) {
+		result[key] = fields[key];
+	}
+	return result;
+}
+
+function _crash(module, message, location) {
+	var where = location ? ' at ' + location : '';
+	throw new Error('Ran into a crash in module ' + module + where + ': ' + message);
+}
+
+function _toString(value) {
+	switch (typeof value) {
+		case 'function':
+			return '<function>';
+		case 'boolean':
+			return value ? 'True' : 'False';
+		case 'number':
+			return value + '';
+		case 'string':
+			return JSON.stringify(value);
+	}
+
+	if (value instanceof Array) {
+		return '(' + value.map(_toString).join(',') + ')';
+	}
+
+	if (value.n === '[]' || value.n === '::') {
+		return '[' + _toArray(value).map(_toString).join(',') + ']';
+	}
+
+	if (value.$ !== undefined && value.a === undefined) {
+		return '<internals>';
+	}
+
+	if (value.$ !== undefined) {
+		var name = value.n === undefined ? '<' + value.$ + '>' : value.n;
+		return [name].concat(value.a.map(function(arg) {
+			var s = _toString(arg);
+			var nested = arg.$ !== undefined && arg.a.length > 0 && arg.n !== '::';
+			return nested || s[0] === '-' ? '(' + s + ')' : s;
+		})).join(' ');
+	}
+
+	return '{ ' + Object.keys(value).map(function(key) {
+		return key + ' = ' + _toString(value[key]);
+	}).join(', ') + ' }';
+}
+
+var Native$Debug = {
+	log: _F(2, function(tag, value) {
+		console.log(tag + ': ' + _toString(value));
+		return value;
+	}),
+	toString: _toString,
+	crash: function(message) {
+		throw new Error(message);
+	}
+};
+
+function _expect(kind, ok) {
+	return function(value) {
+		if (!ok(value)) {
+			throw new Error('Expecting ' + kind + ' but got ' + JSON.stringify(value));
+		}
+		return value;
+	};
+}
+
+var _decodeInt = _expect('an Int', function(v) {
+	return typeof v === 'number' && v % 1 === 0;
+});
+
+var _decodeFloat = _expect('a Float', function(v) {
+	return typeof v === 'number';
+});
+
+var _decodeBool = _expect('a Bool', function(v) {
+	return typeof v === 'boolean';
+});
+
+var _decodeString = _expect('a String', function(v) {
+	return typeof v === 'string';
+});
+
+var _decodeArray = _expect('an array', function(v) {
+	return v instanceof Array;
+});
+
+var _decodeObject = _expect('an object', function(v) {
+	return typeof v === 'object' && v !== null && !(v instanceof Array);
+});
+
+function _decodeTuple(length) {
+	return _expect('an array with ' + length + ' elements', function(v) {
+		return v instanceof Array && v.length === length;
+	});
+}
+
+// _within decodes the value with the decoder, adding the context to the
+// error if it fails, so the error says where the unexpected value is.
+function _within(context, decoder, value) {
+	try {
+		return decoder(value);
+	} catch (e) {
+		throw new Error(context + ': ' + e.message);
+	}
+}
+
+function _field(object, name, decoder) {
+	if (!(name in object)) {
+		throw new Error('Expecting an object with a field named ' + name + ' but got ' + JSON.stringify(object));
+	}
+	return _within('Problem with the field ' + name, decoder, object[name]);
+}
+
+function _index(array, i, decoder) {
+	return _within('Problem with the element at index ' + i, decoder, array[i]);
+}
+
+// _toSnapshot converts a value whose type is not known to the JSON of a
+// snapshot of the state, which keeps it as it is. The values of union types
+// have the names of their constructors, because only bundles built for
+// debugging make snapshots.
+function _toSnapshot(value) {
+	if (typeof value === 'function') {
+		return _snapshotFunction();
+	}
+
+	if (value === null || typeof value !== 'object') {
+		return value;
+	}
+
+	if (value instanceof Array) {
+		return value.map(_toSnapshot);
+	}
+
+	var result = {};
+	for (var key in value) {
+		result[key] = _toSnapshot(value[key]);
+	}
+	return result;
+}
+
+// _fromSnapshot converts the JSON of a value whose type is not known back,
+// which is already the value.
+var _fromSnapshot = _identity;
+
+function _snapshotFunction() {
+	throw new Error('A snapshot of the state cannot have functions');
+}
+
+var _ports = {};
+
+function _outgoingPort(name, converter) {
+	_ports[name] = { incoming: false, converter: converter };
+	return function(value) {
+		return { $: 'leaf', home: name, value: value };
+	};
+}
+
+function _incomingPort(name, converter) {
+	_ports[name] = { incoming: true, converter: converter };
+	return function(tagger) {
+		return { $: 'leaf', home: name, value: tagger };
+	};
+}
+
+var Native$Platform = (function() {
+	var none = { $: 'batch', bags: [] };
+
+	function batch(list) {
+		return { $: 'batch', bags: _toArray(list) };
+	}
+
+	var map = _F(2, function(tagger, bag) {
+		return { $: 'map', tagger: tagger, bag: bag };
+	});
+
+	function program(impl) {
+		return { impl: impl, flags: false };
+	}
+
+	function programWithFlags(impl) {
+		return { impl: impl, flags: true };
+	}
+
+	return {
+		none: none,
+		batch: batch,
+		map: map,
+		program: program,
+		programWithFlags: programWithFlags
+	};
+}());
+
+function _gather(bag, taggers, leaves) {
+	switch (bag.$) {
+		case 'leaf':
+			leaves.push({ home: bag.home, value: bag.value, taggers: taggers });
+			break;
+		case 'batch':
+			for (var i = 0; i < bag.bags.length; i++) {
+				_gather(bag.bags[i], taggers, leaves);
+			}
+			break;
+		case 'map':
+			_gather(bag.bag, [bag.tagger].concat(taggers), leaves);
+			break;
+	}
+	return leaves;
+}
+
+// _worker returns the function that starts the program. In the bundles
+// built for debugging, snapshots are the functions that convert its model
+// to JSON and back, and every model of the program is kept, so a debugger
+// can export them, such as to attach them to a bug report, and import them
+// to travel back to any of them.
+function _worker(program, flagsConverter, snapshots) {
+	return function(flags) {
+		var impl = program.impl;
+		var subscribers = {};
+		var taggers = {};
+		var ports = {};
+		var model;
+		var models = [];
+
+		Object.keys(_ports).forEach(function(name) {
+			var port = _ports[name];
+			if (port.incoming) {
+				taggers[name] = [];
+				ports[name] = {
+					send: function(value) {
+						var converted = _within(
+							'Trying to send an unexpected value through port ' + name,
+							port.converter,
+							value
+						);
+						taggers[name].slice().forEach(function(leaf) {
+							var msg = leaf.value(converted);
+							for (var i = 0; i < leaf.taggers.length; i++) {
+								msg = leaf.taggers[i](msg);
+							}
+							dispatch(msg);
+						});
+					}
+				};
+			} else {
+				subscribers[name] = [];
+				ports[name] = {
+					subscribe: function(callback) {
+						subscribers[name].push(callback);
+					},
+					unsubscribe: function(callback) {
+						var i = subscribers[name].indexOf(callback);
+						if (i >= 0) {
+							subscribers[name].splice(i, 1);
+						}
+					}
+				};
+			}
+		});
+
+		function subscriptions() {
+			Object.keys(taggers).forEach(function(name) {
+				taggers[name] = [];
+			});
+
+			if (impl.subscriptions) {
+				_gather(impl.subscriptions(model), [], []).forEach(function(leaf) {
+					taggers[leaf.home].push(leaf);
+				});
+			}
+		}
+
+		function commands(cmd) {
+			_gather(cmd, [], []).forEach(function(leaf) {
+				var value = _ports[leaf.home].converter(leaf.value);
+				subscribers[leaf.home].slice().forEach(function(callback) {
+					callback(value);
+				});
+			});
+		}
+
+		function setModel(value) {
+			model = value;
+			if (snapshots) {
+				models.push(model);
+			}
+		}
+
+		function step(result) {
+			setModel(result[0]);
+			subscriptions();
+			commands(result[1]);
+		}
+
+		function snapshot(model) {
+			return { model: snapshots.encode(model) };
+		}
+
+		function dispatch(msg) {
+			step(_A(impl.update, [msg, model]));
+		}
+
+		var init = impl.init;
+		if (program.flags) {
+			if (flagsConverter) {
+				flags = _within('Trying to initialize the program with unexpected flags', flagsConverter, flags);
+			}
+			init = init(flags);
+		}
+
+		// commands of init are sent after returning, so they can be
+		// subscribed to
+		setModel(init[0]);
+		subscriptions();
+		setTimeout(function() {
+			commands(init[1]);
+		}, 0);
+
+		if (!snapshots) {
+			return { ports: ports };
+		}
+
+		// importing a snapshot changes the model, but it does not run any
+		// command, so the outgoing ports are not sent anything
+		var debug = {
+			exportState: function() {
+				return snapshot(model);
+			},
+			importState: function(value) {
+				setModel(_within('Trying to import an unexpected snapshot', function(v) {
+					_decodeObject(v);
+					return _field(v, 'model', snapshots.decode);
+				}, value));
+				subscriptions();
+			},
+			history: function() {
+				return models.map(snapshot);
+			}
+		};
+		return { ports: ports, debug: debug };
+	};
+}
+
+function _export(scope, module, value) {
+	var obj = scope['Elm'] || (scope['Elm'] = {});
+	module.split('.').forEach(function(name) {
+		obj = obj[name] || (obj[name] = {});
+	});
+	for (var key in value) {
+		obj[key] = value[key];
+	}
+}
+`
@@ -0,0 +1,223 @@
+package js
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// marker delimits the marks written in the generated code. The bundle is
+// generated in pieces that are nested in each other, so the marks are
+// written where the positions are and they are replaced with mappings once
+// the whole bundle is generated, when their line and column are known.
+const marker = '\x00'
+
+// mark is a position in the source code of a module. Locations are marks
+// that are replaced with the path, line and column of the position, instead
+// of being mapped.
+type mark struct {
+	path     string
+	pos      token.Pos
+	location bool
+}
+
+// mark returns a mark of the given position of the module being
+// generated, or nothing if there are no source maps or no position.
+func (g *generator) mark(pos token.Pos) string {
+	if !g.sourceMap || pos == token.NoPos {
+		return ""
+	}
+
+	g.marks = append(g.marks, mark{g.path, pos, false})
+	return string(marker) + strconv.Itoa(len(g.marks)-1) + string(marker)
+}
+
+// location returns a mark that is replaced with the location of the given
+// position of the module being generated, such as `src/Main.elm:3:5`.
+func (g *generator) location(pos token.Pos) string {
+	g.marks = append(g.marks, mark{g.path, pos, true})
+	return string(marker) + "L" + strconv.Itoa(len(g.marks)-1) + string(marker)
+}
+
+// relocate returns the code of the object with its marks renumbered after
+// the marks already written, or without them if there are no source maps.
+func (g *generator) relocate(obj *Object) string {
+	var buf bytes.Buffer
+	var code = obj.Code
+	for {
+		start := strings.IndexByte(code, marker)
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(code[start+1:], marker) + start + 1
+
+		buf.WriteString(code[:start])
+		if code[start+1] == 'L' {
+			n, _ := strconv.Atoi(code[start+2 : end])
+			buf.WriteString(g.location(obj.Marks[n]))
+		} else {
+			n, _ := strconv.Atoi(code[start+1 : end])
+			buf.WriteString(g.mark(obj.Marks[n]))
+		}
+		code = code[end+1:]
+	}
+	buf.WriteString(code)
+	return buf.String()
+}
+
+// sourceMap is a source map, revision 3.
+type sourceMap struct {
+	Version  int      `json:"version"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// segment maps a column of the generated code to a position in a source.
+type segment struct {
+	col, source, line, srcCol int
+}
+
+// resolve removes the marks from the generated code and returns it with the
+// source map of its marks, once the locations are written in their place.
+// The source code of the modules is loaded with the given loader to find the
+// lines and columns of the positions, or read from the disk if it is nil.
+// The columns of the generated code are counted in UTF-16 code units, as
+// source maps require.
+func (g *generator) resolve(code []byte, loader source.Loader) ([]byte, *sourceMap, error) {
+	if loader == nil {
+		loader = source.NewFsLoader(nil)
+	}
+
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+
+	var m = &sourceMap{Version: 3, Names: []string{}}
+	var sources = make(map[string]int)
+	var lines [][]segment
+	var out bytes.Buffer
+	var line []segment
+	var col int
+	for len(code) > 0 {
+		r, size := utf8.DecodeRune(code)
+		switch r {
+		case '\n':
+			lines = append(lines, line)
+			line, col = nil, 0
+			out.WriteRune(r)
+		case marker:
+			end := bytes.IndexByte(code[1:], marker) + 1
+			n, _ := strconv.Atoi(strings.TrimPrefix(string(code[1:end]), "L"))
+			size = end + 1
+
+			mk := g.marks[n]
+			idx, ok := sources[mk.path]
+			if !ok {
+				if err := cm.Add(mk.path); err != nil {
+					return nil, nil, err
+				}
+
+				idx = len(m.Sources)
+				sources[mk.path] = idx
+				m.Sources = append(m.Sources, mk.path)
+			}
+
+			pos, err := cm.Source(mk.path).LinePos(mk.pos)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if mk.location {
+				loc := strconv.Quote(fmt.Sprintf("%s:%d:%d", mk.path, pos.Line, pos.Col))
+				loc = loc[1 : len(loc)-1]
+				out.WriteString(loc)
+				for _, r := range loc {
+					col += utf16Units(r)
+				}
+				break
+			}
+
+			// a mark at the same column as the previous one is more
+			// specific, such as the lambda of a definition
+			seg := segment{col, idx, pos.Line - 1, pos.Col - 1}
+			if n := len(line); n > 0 && line[n-1].col == col {
+				line[n-1] = seg
+			} else {
+				line = append(line, seg)
+			}
+		default:
+			out.Write(code[:size])
+			col += utf16Units(r)
+		}
+		code = code[size:]
+	}
+	lines = append(lines, line)
+
+	m.Mappings = mappings(lines)
+	return out.Bytes(), m, nil
+}
+
+// utf16Units returns the number of UTF-16 code units of the rune, which
+// are two for the runes outside the basic multilingual plane.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// mappings encodes the segments of every line of the generated code. All
+// the values of the segments are relative to the previous segment, except
+// the columns of the generated code, which are relative to the previous
+// segment of the same line.
+func mappings(lines [][]segment) string {
+	var buf bytes.Buffer
+	var prev segment
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteByte(';')
+		}
+
+		prev.col = 0
+		for j, s := range line {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+
+			writeVLQ(&buf, s.col-prev.col)
+			writeVLQ(&buf, s.source-prev.source)
+			writeVLQ(&buf, s.line-prev.line)
+			writeVLQ(&buf, s.srcCol-prev.srcCol)
+			prev = s
+		}
+	}
+	return buf.String()
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes the number as a base 64 variable-length quantity, whose
+// first digit has the sign in its least significant bit.
+func writeVLQ(buf *bytes.Buffer, n int) {
+	var v = n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		buf.WriteByte(base64Digits[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package target
+
+// defaults are the default templates of the targets.
+//
+// The node script and the Go program run the main program with the
+// standard input and output: every line of the input is the JSON of a value
+// sent through an incoming port, such as `{"port": "input", "value": 2}`,
+// and the values of outgoing ports are written in the same format. The
+// first argument of the command, if any, is the JSON of the flags.
+//
+// The Web Worker runs the main program when it receives its first message,
+// whose flags field, if any, has the flags, such as `{"flags": 1}`. The
+// rest of the messages are values sent through incoming ports, such as
+// `{"port": "input", "value": 2}`, and the values of outgoing ports are
+// posted in the same format.
+//
+// The WebAssembly module exports the main program to JavaScript as
+// `Elm.Main`, like the bundle does, once it is loaded.
+var defaults = map[Target]string{
+	JS: `{{.Code}}`,
+	HTML: `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Main}}</title>
+</head>
+<body>
+<script>
+{{script .Code}}
+</script>
+<script>
+var app = Elm.{{.Main}}.worker();
+</script>
+</body>
+</html>
+`,
+	Node: `{{.Code}}
+(function() {
+	var flags = process.argv.length > 2 ? JSON.parse(process.argv[2]) : undefined;
+	var app = module.exports.Elm.{{.Main}}.worker(flags);
+	Object.keys(app.ports).sort().forEach(function(name) {
+		if (app.ports[name].subscribe) {
+			app.ports[name].subscribe(function(value) {
+				process.stdout.write(JSON.stringify({ port: name, value: value }) + '\n');
+			});
+		}
+	});
+
+	require('readline').createInterface({ input: process.stdin }).on('line', function(line) {
+		if (line.trim() !== '') {
+			var msg = JSON.parse(line);
+			app.ports[msg.port].send(msg.value);
+		}
+	});
+}());
+`,
+	WebWorker: `{{.Code}}
+(function() {
+	var app;
+	self.onmessage = function(event) {
+		var msg = event.data;
+		if (app) {
+			app.ports[msg.port].send(msg.value);
+			return;
+		}
+
+		app = self.Elm.{{.Main}}.worker(msg && msg.flags);
+		Object.keys(app.ports).sort().forEach(function(name) {
+			if (app.ports[name].subscribe) {
+				app.ports[name].subscribe(function(value) {
+					self.postMessage({ port: name, value: value });
+				});
+			}
+		});
+	};
+}());
+`,
+	Go: `{{.Code}}
+func main() {
+	rt.Main(Worker())
+}
+`,
+	WASM: `{{.Code}}
+func main() {
+	rt.Export("{{.Main}}", Worker())
+}
+`,
+}
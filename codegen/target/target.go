@@ -0,0 +1,140 @@
+// Package target implements the templates the generated code is injected
+// into to build artifacts that can be run directly, such as an HTML page
+// that runs a program in browsers or the main package of a Go program.
+//
+// Every target has a default template, which can be replaced with a custom
+// one. Templates are text templates executed with Data, so the generated
+// code is written with `{{.Code}}`. The `script` function escapes the code
+// so it can be written inside the script element of an HTML page.
+package target
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// Target is a kind of artifact that can be built.
+type Target string
+
+const (
+	// JS is the JavaScript bundle on its own.
+	JS Target = "js"
+	// HTML is an HTML page that runs the main program of the bundle.
+	HTML Target = "html"
+	// Node is a node script that runs the main program of the bundle.
+	Node Target = "node"
+	// WebWorker is the script of a Web Worker that runs the main program of
+	// the bundle.
+	WebWorker Target = "webworker"
+	// Go is the main package of a Go program that runs the main program.
+	Go Target = "go"
+	// WASM is a WebAssembly module that runs the main program, which is
+	// the main package of the Go target built for GOOS=js and GOARCH=wasm.
+	WASM Target = "wasm"
+)
+
+// Targets are all the targets, in alphabetical order.
+var Targets = []Target{Go, HTML, JS, Node, WASM, WebWorker}
+
+// Backend returns the name of the backend that generates the code of the
+// target, which is either `js` or `go`.
+func (t Target) Backend() string {
+	if t == Go || t == WASM {
+		return "go"
+	}
+	return "js"
+}
+
+// Ext returns the extension of the files of the target.
+func (t Target) Ext() string {
+	switch t {
+	case HTML:
+		return ".html"
+	case Go:
+		return ".go"
+	case WASM:
+		return ".wasm"
+	}
+	return ".js"
+}
+
+// Parse returns the target with the given name.
+func Parse(name string) (Target, error) {
+	if _, ok := defaults[Target(name)]; !ok {
+		return "", fmt.Errorf("target: unknown target %q", name)
+	}
+	return Target(name), nil
+}
+
+// Data is the data the templates are executed with.
+type Data struct {
+	// Main is the name of the module whose main program is run.
+	Main string
+	// Code is the generated code. It is the bundle for the JavaScript
+	// targets and a Go source file of package main with the Worker function
+	// for the Go and WASM targets.
+	Code string
+}
+
+// Template is the template of a target.
+type Template struct {
+	target Target
+	tmpl   *template.Template
+}
+
+// Default returns the default template of the given target.
+func Default(t Target) (*Template, error) {
+	src, ok := defaults[t]
+	if !ok {
+		return nil, fmt.Errorf("target: unknown target %q", t)
+	}
+	return parse(t, src)
+}
+
+// Load returns the template of the given target in the file at path, or the
+// default template of the target if path is empty.
+func Load(t Target, path string) (*Template, error) {
+	if path == "" {
+		return Default(t)
+	}
+
+	if _, ok := defaults[t]; !ok {
+		return nil, fmt.Errorf("target: unknown target %q", t)
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("target: can't read template of %s: %s", t, err)
+	}
+	return parse(t, string(src))
+}
+
+var funcs = template.FuncMap{
+	"script": func(code string) string {
+		return strings.Replace(code, "</script", `<\/script`, -1)
+	},
+}
+
+func parse(t Target, src string) (*Template, error) {
+	tmpl, err := template.New(string(t)).Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("target: invalid template of %s: %s", t, err)
+	}
+	return &Template{t, tmpl}, nil
+}
+
+// Target returns the target of the template.
+func (t *Template) Target() Target {
+	return t.target
+}
+
+// Execute writes the artifact of the template with the given data to w.
+func (t *Template) Execute(w io.Writer, data Data) error {
+	if err := t.tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("target: can't execute template of %s: %s", t.target, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package target
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func execute(t *testing.T, tmpl *Template, data Data) string {
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, data))
+	return buf.String()
+}
+
+func TestDefault(t *testing.T) {
+	require := require.New(t)
+	data := Data{Main: "Main", Code: "var x = '</script>';"}
+
+	cases := []struct {
+		target   Target
+		expected []string
+	}{
+		{JS, []string{"var x = '</script>';"}},
+		{HTML, []string{"<title>Main</title>", `var x = '<\/script>';`, "var app = Elm.Main.worker();"}},
+		{Node, []string{"var x = '</script>';\n(function() {", "module.exports.Elm.Main.worker(flags)"}},
+		{WebWorker, []string{"var x = '</script>';\n(function() {", "app = self.Elm.Main.worker(msg && msg.flags);", "self.postMessage({ port: name, value: value });"}},
+		{Go, []string{"var x = '</script>';\nfunc main() {\n\trt.Main(Worker())\n}\n"}},
+		{WASM, []string{"var x = '</script>';\nfunc main() {\n\trt.Export(\"Main\", Worker())\n}\n"}},
+	}
+
+	for _, c := range cases {
+		tmpl, err := Default(c.target)
+		require.NoError(err)
+		require.Equal(c.target, tmpl.Target())
+
+		out := execute(t, tmpl, data)
+		for _, e := range c.expected {
+			require.Contains(out, e, string(c.target))
+		}
+	}
+
+	_, err := Default("jvm")
+	require.EqualError(err, `target: unknown target "jvm"`)
+}
+
+func TestLoad(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "target")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "index.html")
+	require.NoError(ioutil.WriteFile(path, []byte("<main>{{script .Code}}</main>"), 0644))
+
+	tmpl, err := Load(HTML, path)
+	require.NoError(err)
+	require.Equal("<main>code</main>", execute(t, tmpl, Data{Code: "code"}))
+
+	tmpl, err = Load(HTML, "")
+	require.NoError(err)
+	require.Contains(execute(t, tmpl, Data{Main: "Main", Code: "code"}), "<!DOCTYPE html>")
+
+	_, err = Load(HTML, filepath.Join(dir, "missing.html"))
+	require.Error(err)
+
+	bad := filepath.Join(dir, "bad.html")
+	require.NoError(ioutil.WriteFile(bad, []byte("{{.Code"), 0644))
+	_, err = Load(HTML, bad)
+	require.Error(err)
+
+	_, err = Load("jvm", path)
+	require.EqualError(err, `target: unknown target "jvm"`)
+}
+
+func TestParse(t *testing.T) {
+	require := require.New(t)
+	for _, target := range Targets {
+		parsed, err := Parse(string(target))
+		require.NoError(err)
+		require.Equal(target, parsed)
+	}
+
+	_, err := Parse("jvm")
+	require.EqualError(err, `target: unknown target "jvm"`)
+
+	require.Equal("go", Go.Backend())
+	require.Equal("go", WASM.Backend())
+	require.Equal(".wasm", WASM.Ext())
+	require.Equal("js", Node.Backend())
+	require.Equal(".html", HTML.Ext())
+	require.Equal(".js", Node.Ext())
+	require.Equal("js", WebWorker.Backend())
+	require.Equal(".js", WebWorker.Ext())
+}
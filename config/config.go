@@ -0,0 +1,182 @@
+// Package config implements the configuration files of the projects, which
+// share the settings of elmo between the people who work on them instead of
+// repeating the same flags in every invocation. A configuration is either an
+// elmo.toml file or an .elmorc file with the same settings as JSON:
+//
+//	warnings = "hide"
+//	target = "html"
+//	source-directories = ["src", "generated"]
+//
+//	[format]
+//	imports = true
+//
+// The configuration of a directory is the one of its files merged with the
+// ones of its parent directories, so a project can have a configuration in
+// its root and override some of its settings in the directories of its
+// packages. The settings of the closest files win, and the ones of .elmorc
+// win over the ones of elmo.toml in the same directory.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// TOMLFile is the name of the configuration file written in TOML.
+	TOMLFile = "elmo.toml"
+	// RCFile is the name of the configuration file written in JSON.
+	RCFile = ".elmorc"
+)
+
+// The levels of the warnings.
+const (
+	// ShowWarnings reports the warnings, which is the default.
+	ShowWarnings = "show"
+	// HideWarnings leaves out the warnings, like the --quiet flag.
+	HideWarnings = "hide"
+)
+
+// Config is the configuration of a directory.
+type Config struct {
+	// Warnings is the level of the warnings: show or hide. It is show if
+	// it is empty.
+	Warnings string `json:"warnings,omitempty"`
+	// Target is the target of the builds whose target is not given, such
+	// as html.
+	Target string `json:"target,omitempty"`
+	// SourceDirectories replace the source directories of the packages
+	// loaded with pkg.Load, if they are not empty. They are relative to the
+	// root of every package.
+	SourceDirectories []string `json:"source-directories,omitempty"`
+	// Format are the options of the formatting.
+	Format Format `json:"format"`
+}
+
+// Format are the options of the formatting of the fmt command.
+type Format struct {
+	// Imports reports whether the imports are organized as well, as with
+	// the --imports flag, if it is not nil.
+	Imports *bool `json:"imports,omitempty"`
+}
+
+// Read decodes a configuration written in JSON.
+func Read(r io.Reader) (*Config, error) {
+	var conf Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&conf); err != nil {
+		return nil, fmt.Errorf("config: can't decode configuration: %s", err)
+	}
+
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// ReadTOML decodes a configuration written in TOML.
+func ReadTOML(r io.Reader) (*Config, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := decodeTOML(content)
+	if err != nil {
+		return nil, fmt.Errorf("config: can't decode configuration: %s", err)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return Read(bytes.NewReader(data))
+}
+
+func (c *Config) validate() error {
+	switch c.Warnings {
+	case "", ShowWarnings, HideWarnings:
+		return nil
+	}
+	return fmt.Errorf("config: the warnings must be %s or %s, not %q", ShowWarnings, HideWarnings, c.Warnings)
+}
+
+// Load returns the configuration of the given directory, which is the
+// merge of the configuration files in it and in its parent directories. If
+// there are none, the configuration is empty.
+func Load(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if parent := filepath.Dir(dir); parent != dir {
+			dir = parent
+		} else {
+			break
+		}
+	}
+
+	var conf Config
+	for i := len(dirs) - 1; i >= 0; i-- {
+		for _, file := range []string{TOMLFile, RCFile} {
+			c, err := loadFile(filepath.Join(dirs[i], file))
+			if err != nil {
+				return nil, err
+			} else if c != nil {
+				conf.merge(c)
+			}
+		}
+	}
+	return &conf, nil
+}
+
+// loadFile loads the configuration file at the given path, which is nil if
+// there is no such file.
+func loadFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("config: can't open configuration: %s", err)
+	}
+	defer f.Close()
+
+	var conf *Config
+	if filepath.Base(path) == TOMLFile {
+		conf, err = ReadTOML(f)
+	} else {
+		conf, err = Read(f)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return conf, nil
+}
+
+// merge sets the settings of the configuration to the ones of other that
+// are not empty.
+func (c *Config) merge(other *Config) {
+	if other.Warnings != "" {
+		c.Warnings = other.Warnings
+	}
+	if other.Target != "" {
+		c.Target = other.Target
+	}
+	if len(other.SourceDirectories) > 0 {
+		c.SourceDirectories = other.SourceDirectories
+	}
+	if other.Format.Imports != nil {
+		c.Format.Imports = other.Format.Imports
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTOML(t *testing.T) {
+	require := require.New(t)
+
+	conf, err := ReadTOML(strings.NewReader(`
+# the settings of the team
+warnings = "hide" # for now
+target = 'html'
+source-directories = ["src", "generated # not a comment"]
+
+[format]
+imports = true
+`))
+	require.NoError(err)
+	require.Equal(HideWarnings, conf.Warnings)
+	require.Equal("html", conf.Target)
+	require.Equal([]string{"src", "generated # not a comment"}, conf.SourceDirectories)
+	require.NotNil(conf.Format.Imports)
+	require.True(*conf.Format.Imports)
+
+	// the escaped backslashes do not escape the quotes after them
+	conf, err = ReadTOML(strings.NewReader(`
+target = "html\\" # a comment
+source-directories = ["src\\", "lib, \"generated\""]
+`))
+	require.NoError(err)
+	require.Equal(`html\`, conf.Target)
+	require.Equal([]string{`src\`, `lib, "generated"`}, conf.SourceDirectories)
+
+	cases := []struct {
+		src string
+		err string
+	}{
+		{`warnings = "loud"`, `config: the warnings must be show or hide, not "loud"`},
+		{`colors = true`, `json: unknown field "colors"`},
+		{`target`, `line 1: expected a key and a value, got target`},
+		{"target = \"a\"\ntarget = \"b\"", `line 2: target is defined twice`},
+		{`source-directories = ["src",`, `arrays must be in a single line`},
+		{`source-directories = [["src", "lib"], ["generated"]]`, `line 1: invalid array [["src", "lib"], ["generated"]], arrays cannot be nested`},
+		{`[format`, `line 1: invalid table [format`},
+		{`target = html`, `line 1: invalid value html`},
+	}
+
+	for _, c := range cases {
+		_, err := ReadTOML(strings.NewReader(c.src))
+		require.Error(err, c.src)
+		require.Contains(err.Error(), c.err, c.src)
+	}
+}
+
+func TestRead(t *testing.T) {
+	require := require.New(t)
+
+	conf, err := Read(strings.NewReader(`{"warnings": "show", "format": {"imports": false}}`))
+	require.NoError(err)
+	require.Equal(ShowWarnings, conf.Warnings)
+	require.False(*conf.Format.Imports)
+
+	_, err = Read(strings.NewReader(`{"target": 1}`))
+	require.Error(err)
+}
+
+func TestLoad(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	pkg := filepath.Join(dir, "packages", "app")
+	require.NoError(os.MkdirAll(filepath.Join(pkg, "src"), 0755))
+
+	conf, err := Load(pkg)
+	require.NoError(err)
+	require.Equal(&Config{}, conf)
+
+	writeFile(t, filepath.Join(dir, TOMLFile), "warnings = \"hide\"\ntarget = \"html\"\n\n[format]\nimports = true\n")
+	writeFile(t, filepath.Join(dir, RCFile), `{"target": "node"}`)
+	writeFile(t, filepath.Join(pkg, TOMLFile), "source-directories = [\"src\"]\n[format]\nimports = false\n")
+
+	conf, err = Load(filepath.Join(pkg, "src"))
+	require.NoError(err)
+	require.Equal(HideWarnings, conf.Warnings)
+	require.Equal("node", conf.Target)
+	require.Equal([]string{"src"}, conf.SourceDirectories)
+	require.False(*conf.Format.Imports)
+
+	conf, err = Load(dir)
+	require.NoError(err)
+	require.Nil(conf.SourceDirectories)
+	require.True(*conf.Format.Imports)
+
+	writeFile(t, filepath.Join(pkg, RCFile), `{"warnings": 1}`)
+	_, err = Load(pkg)
+	require.Error(err)
+	require.Contains(err.Error(), filepath.Join(pkg, RCFile))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeTOML decodes the subset of TOML that the configuration needs: the
+// tables, and the keys whose values are strings, booleans, integers or
+// arrays of them in a single line, which cannot be nested.
+func decodeTOML(content []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	table := root
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table %s", i+1, line)
+			}
+
+			table = root
+			for _, key := range strings.Split(line[1:len(line)-1], ".") {
+				key = strings.TrimSpace(key)
+				sub, ok := table[key].(map[string]interface{})
+				if !ok {
+					if _, exists := table[key]; exists {
+						return nil, fmt.Errorf("line %d: %s is not a table", i+1, key)
+					}
+					sub = make(map[string]interface{})
+					table[key] = sub
+				}
+				table = sub
+			}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected a key and a value, got %s", i+1, line)
+		}
+
+		key := strings.TrimSpace(line[:eq])
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+		if _, exists := table[key]; exists {
+			return nil, fmt.Errorf("line %d: %s is defined twice", i+1, key)
+		}
+
+		value, err := tomlValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		table[key] = value
+	}
+	return root, nil
+}
+
+// stripComment returns the line without its comment, if any, which starts
+// with a # that is not in a string.
+func stripComment(line string) string {
+	var q quoting
+	for i, r := range line {
+		if !q.next(r) && r == '#' {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// quoting tracks the strings of a line while it is scanned, so the #s and
+// the commas in them are not taken for comments and separators.
+type quoting struct {
+	quote rune
+	// escaped reports whether the previous rune is a backslash that
+	// escapes the current one, which can only be in a basic string.
+	escaped bool
+}
+
+// next reports whether the rune is part of a string, including its quotes.
+func (q *quoting) next(r rune) bool {
+	switch {
+	case q.escaped:
+		q.escaped = false
+	case q.quote == '"' && r == '\\':
+		q.escaped = true
+	case q.quote != 0 && r == q.quote:
+		q.quote = 0
+	case q.quote == 0 && (r == '"' || r == '\''):
+		q.quote = r
+	default:
+		return q.quote != 0
+	}
+	return true
+}
+
+// tomlValue decodes the value of a key.
+func tomlValue(s string) (interface{}, error) {
+	switch {
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("invalid string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("invalid array %s, arrays must be in a single line", s)
+		}
+		return tomlArray(s[1 : len(s)-1])
+	}
+
+	n, err := strconv.ParseInt(strings.Replace(s, "_", "", -1), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s", s)
+	}
+	return n, nil
+}
+
+// tomlArray decodes the elements of an array, which are separated by
+// commas outside of the strings.
+func tomlArray(s string) ([]interface{}, error) {
+	var (
+		values []interface{}
+		start  int
+	)
+	elem := func(end int) error {
+		item := strings.TrimSpace(s[start:end])
+		if item == "" {
+			return nil
+		}
+
+		v, err := tomlValue(item)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+		return nil
+	}
+
+	var q quoting
+	for i, r := range s {
+		if q.next(r) {
+			continue
+		}
+
+		switch r {
+		case '[':
+			return nil, fmt.Errorf("invalid array [%s], arrays cannot be nested", s)
+		case ',':
+			if err := elem(i); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		}
+	}
+
+	if err := elem(len(s)); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
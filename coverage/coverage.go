@@ -0,0 +1,197 @@
+// Package coverage reports which parts of the modules of a package were
+// evaluated by its tests: the function definitions and the branches of
+// the case and if expressions, which are the points of the probes of the
+// package instrumented by ir.Instrument. The reports are written in the
+// lcov format, which the coverage tools and the editors read.
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Report is the coverage of the modules of a package.
+type Report struct {
+	// Modules are the modules with any points, in the order of the package.
+	Modules []*Module
+}
+
+// Module is the coverage of a module.
+type Module struct {
+	// Name is the name of the module, and Path the path to its file.
+	Name, Path string
+	// Points are the points of the module in the order of the source code.
+	Points []*Point
+}
+
+// Point is a point of a module with the number of times it was evaluated.
+type Point struct {
+	*ir.Point
+	Count int
+}
+
+// New returns the report of the points of an instrumented package with the
+// counts of their probes, by their IDs. Only the modules for which include
+// reports true are in the report.
+func New(points []*ir.Point, counts []int, include func(module string) bool) (*Report, error) {
+	if len(points) != len(counts) {
+		return nil, fmt.Errorf("coverage: there are %d points, but %d counts", len(points), len(counts))
+	}
+
+	var r Report
+	var modules = make(map[string]*Module)
+	for i, p := range points {
+		if !include(p.Module) {
+			continue
+		}
+
+		m := modules[p.Module]
+		if m == nil {
+			m = &Module{Name: p.Module, Path: p.Path}
+			modules[p.Module] = m
+			r.Modules = append(r.Modules, m)
+		}
+		m.Points = append(m.Points, &Point{p, counts[i]})
+	}
+
+	for _, m := range r.Modules {
+		sort.SliceStable(m.Points, func(i, j int) bool {
+			return m.Points[i].Pos < m.Points[j].Pos
+		})
+	}
+	return &r, nil
+}
+
+// Covered returns the number of points of the module that were evaluated
+// and the number of points.
+func (m *Module) Covered() (covered, total int) {
+	for _, p := range m.Points {
+		if p.Count > 0 {
+			covered++
+		}
+	}
+	return covered, len(m.Points)
+}
+
+// Percent returns the percentage of the points of the module that were
+// evaluated.
+func (m *Module) Percent() float64 {
+	covered, total := m.Covered()
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+// WriteLcov writes the report in the lcov format to w: a record for every
+// module with its functions, its branches and the lines with points. The
+// lines are found in the source code of the code map. The branches of the
+// same case or if expression are a block.
+func (r *Report) WriteLcov(w io.Writer, cm *source.CodeMap) error {
+	for _, m := range r.Modules {
+		if err := m.writeLcov(w, cm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Module) writeLcov(w io.Writer, cm *source.CodeMap) error {
+	if err := cm.Add(m.Path); err != nil {
+		return fmt.Errorf("coverage: can't read %s: %s", m.Path, err)
+	}
+
+	src := cm.Source(m.Path)
+	line := func(pos token.Pos) (int, error) {
+		lp, err := src.LinePos(pos)
+		if err != nil {
+			return 0, fmt.Errorf("coverage: can't find position %d of %s: %s", pos, m.Path, err)
+		}
+		return lp.Line, nil
+	}
+
+	var functions, branches []*Point
+	var blocks = make(map[token.Pos]int)
+	var lines = make(map[int]int)
+	for _, p := range m.Points {
+		if p.Branch {
+			branches = append(branches, p)
+			if _, ok := blocks[p.Expr]; !ok {
+				blocks[p.Expr] = len(blocks)
+			}
+		} else {
+			functions = append(functions, p)
+		}
+
+		n, err := line(p.Pos)
+		if err != nil {
+			return err
+		}
+
+		if count, ok := lines[n]; !ok || p.Count > count {
+			lines[n] = p.Count
+		}
+	}
+
+	var records = []string{"TN:", "SF:" + m.Path}
+	var hit int
+	for _, p := range functions {
+		n, err := line(p.Pos)
+		if err != nil {
+			return err
+		}
+		records = append(records, fmt.Sprintf("FN:%d,%s", n, p.Name))
+	}
+
+	for _, p := range functions {
+		records = append(records, fmt.Sprintf("FNDA:%d,%s", p.Count, p.Name))
+		if p.Count > 0 {
+			hit++
+		}
+	}
+	records = append(records, fmt.Sprintf("FNF:%d", len(functions)), fmt.Sprintf("FNH:%d", hit))
+
+	hit = 0
+	var branchNumbers = make(map[int]int)
+	for _, p := range branches {
+		n, err := line(p.Pos)
+		if err != nil {
+			return err
+		}
+
+		block := blocks[p.Expr]
+		records = append(records, fmt.Sprintf("BRDA:%d,%d,%d,%d", n, block, branchNumbers[block], p.Count))
+		branchNumbers[block]++
+		if p.Count > 0 {
+			hit++
+		}
+	}
+	records = append(records, fmt.Sprintf("BRF:%d", len(branches)), fmt.Sprintf("BRH:%d", hit))
+
+	var numbers []int
+	for n := range lines {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	hit = 0
+	for _, n := range numbers {
+		records = append(records, fmt.Sprintf("DA:%d,%d", n, lines[n]))
+		if lines[n] > 0 {
+			hit++
+		}
+	}
+	records = append(records, fmt.Sprintf("LF:%d", len(numbers)), fmt.Sprintf("LH:%d", hit), "end_of_record")
+
+	for _, record := range records {
+		if _, err := fmt.Fprintln(w, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+
+	"github.com/stretchr/testify/require"
+)
+
+const mathSource = `module Math exposing (sign)
+
+
+sign n =
+    if n < 0 then
+        -1
+    else
+        1
+`
+
+func TestReport(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		sign   = token.Pos(30)
+		ifExpr = token.Pos(43)
+		then   = token.Pos(65)
+		els    = token.Pos(85)
+	)
+
+	points := []*ir.Point{
+		{Module: "Math", Path: "Math.elm", Name: "sign", Pos: sign},
+		{Module: "Math", Path: "Math.elm", Name: "sign", Branch: true, Pos: then, Expr: ifExpr},
+		{Module: "Math", Path: "Math.elm", Name: "sign", Branch: true, Pos: els, Expr: ifExpr},
+		{Module: "Test", Path: "Test.elm", Name: "test"},
+	}
+
+	_, err := New(points, []int{1}, func(string) bool { return true })
+	require.Error(err)
+
+	report, err := New(points, []int{3, 0, 3, 1}, func(module string) bool { return module == "Math" })
+	require.NoError(err)
+	require.Len(report.Modules, 1)
+
+	math := report.Modules[0]
+	covered, total := math.Covered()
+	require.Equal(2, covered)
+	require.Equal(3, total)
+	require.InDelta(66.7, math.Percent(), 0.1)
+
+	loader := source.NewMemLoader()
+	loader.Add("Math.elm", mathSource)
+	cm := source.NewCodeMap(loader)
+
+	var buf bytes.Buffer
+	require.NoError(report.WriteLcov(&buf, cm))
+	require.Equal(`TN:
+SF:Math.elm
+FN:4,sign
+FNDA:3,sign
+FNF:1
+FNH:1
+BRDA:6,0,0,0
+BRDA:8,0,1,3
+BRF:2
+BRH:1
+DA:4,3
+DA:6,0
+DA:8,3
+LF:3
+LH:2
+end_of_record
+`, buf.String())
+}
+
+func TestPercentWithoutPoints(t *testing.T) {
+	require.Equal(t, 100.0, (&Module{Name: "Empty"}).Percent())
+}
@@ -0,0 +1,135 @@
+// Package crash turns the panics of the compiler into internal compiler
+// errors, which have what is needed to report the bug that caused them.
+package crash
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// bugsURL is where the internal compiler errors are reported.
+const bugsURL = "https://github.com/elm-tangram/tangram/issues/new"
+
+// maxSnippetWidth is the maximum number of columns of the line of code of
+// an internal error.
+const maxSnippetWidth = 80
+
+// Error is an internal compiler error: a panic in one of the phases
+// of the compiler because of a bug in it, which is caught and returned as an
+// error instead of crashing the program that runs the compiler. It has what
+// is needed to report the bug: the phase, the module and the code that was
+// being compiled, and where the compiler panicked.
+type Error struct {
+	// Phase is the phase of the compiler that panicked, such as "parse" or
+	// "type check".
+	Phase string
+	// Module is the name of the module being compiled, and Path the path
+	// to its file, if they are known.
+	Module string
+	Path   string
+	// Pos is the position of the code being compiled in the file, if it is
+	// known.
+	Pos token.Pos
+	// Line and Col are the line and column of the position, and Snippet is
+	// the line of code, which are only set once the error is located.
+	Line, Col int
+	Snippet   string
+	// Value is the value of the panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine where it panicked.
+	Stack []byte
+}
+
+// New creates a new internal compiler error of the given phase, with the
+// value of a panic and the stack trace of the goroutine, so it must be called
+// while recovering from the panic.
+func New(phase string, value interface{}) *Error {
+	return &Error{Phase: phase, Value: value, Stack: debug.Stack()}
+}
+
+// Locate finds the line, column and code of the position of the error in the
+// file of the code map with its path, which is added to it if needed. The
+// error is left as it is if the file can not be read.
+func (e *Error) Locate(cm *source.CodeMap) {
+	if cm == nil || e.Path == "" || e.Pos == token.NoPos || e.Line > 0 {
+		return
+	}
+
+	if err := cm.Add(e.Path); err != nil {
+		return
+	}
+
+	src := cm.Source(e.Path)
+	pos, err := src.LinePos(e.Pos)
+	if err != nil {
+		return
+	}
+
+	line, err := src.Line(e.Pos)
+	if err != nil || len(line.Lines) == 0 {
+		return
+	}
+	e.Line, e.Col = pos.Line, pos.Col
+	e.Snippet = line.Lines[0]
+}
+
+func (e *Error) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "internal compiler error: %v\n\n", e.Value)
+	fmt.Fprintf(&buf, "phase:  %s\n", e.Phase)
+	if e.Module != "" {
+		fmt.Fprintf(&buf, "module: %s\n", e.Module)
+	}
+
+	switch {
+	case e.Line > 0:
+		fmt.Fprintf(&buf, "file:   %s:%d:%d\n", e.Path, e.Line, e.Col)
+	case e.Path != "" && e.Pos != token.NoPos:
+		fmt.Fprintf(&buf, "file:   %s, offset %d\n", e.Path, e.Pos)
+	case e.Path != "":
+		fmt.Fprintf(&buf, "file:   %s\n", e.Path)
+	}
+
+	if e.Line > 0 {
+		line, col := minimize(e.Snippet, e.Col)
+		prefix := fmt.Sprintf("%d| ", e.Line)
+		fmt.Fprintf(&buf, "\n%s%s\n%s^\n", prefix, line, strings.Repeat(" ", len(prefix)+col-1))
+	}
+
+	fmt.Fprintf(&buf, "\nThis is a bug in the compiler, not in your code. Please report it at\n%s\nwith this whole message and, if you can, the code of the module.\n\n", bugsURL)
+	buf.Write(e.Stack)
+	return buf.String()
+}
+
+// minimize returns the part of the line around the given column that fits
+// in maxSnippetWidth columns, and the column in it.
+func minimize(line string, col int) (string, int) {
+	runes := []rune(line)
+	if len(runes) <= maxSnippetWidth {
+		return line, col
+	}
+
+	start := col - 1 - maxSnippetWidth/2
+	if start < 0 {
+		start = 0
+	} else if start > len(runes)-maxSnippetWidth {
+		start = len(runes) - maxSnippetWidth
+	}
+
+	minimized := string(runes[start : start+maxSnippetWidth])
+	col -= start
+	if start > 0 {
+		minimized = "…" + minimized
+		col++
+	}
+
+	if start+maxSnippetWidth < len(runes) {
+		minimized += "…"
+	}
+	return minimized, col
+}
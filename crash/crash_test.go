@@ -0,0 +1,60 @@
+package crash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/source"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	require := require.New(t)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nmain =\n    foo bar\n")
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+
+	var e *Error
+	func() {
+		defer func() { e = New("type check", recover()) }()
+		panic("boom")
+	}()
+	require.Contains(string(e.Stack), "TestError")
+
+	e.Module, e.Path, e.Pos = "Main", "Main.elm", 42
+	require.Contains(e.Error(), "file:   Main.elm, offset 42\n")
+
+	e.Locate(cm)
+	require.Equal(4, e.Line)
+	require.Equal(9, e.Col)
+	require.Equal("    foo bar", e.Snippet)
+	require.True(strings.HasPrefix(e.Error(), `internal compiler error: boom
+
+phase:  type check
+module: Main
+file:   Main.elm:4:9
+
+4|     foo bar
+           ^
+
+This is a bug in the compiler, not in your code.`))
+}
+
+func TestMinimize(t *testing.T) {
+	require := require.New(t)
+
+	line, col := minimize("foo bar", 5)
+	require.Equal("foo bar", line)
+	require.Equal(5, col)
+
+	long := strings.Repeat("a", 100) + "X" + strings.Repeat("b", 100)
+	line, col = minimize(long, 101)
+	require.Equal("…"+strings.Repeat("a", 40)+"X"+strings.Repeat("b", 39)+"…", line)
+	require.Equal("X", string([]rune(line)[col-1]))
+
+	line, col = minimize(long, 1)
+	require.Equal(strings.Repeat("a", 80)+"…", line)
+	require.Equal(1, col)
+}
@@ -0,0 +1,37 @@
+module Model exposing (Shape(..), Tree(..), User, Pair, Settings)
+
+import Dict exposing (Dict)
+
+
+type alias User =
+    { id : Int
+    , name : String
+    , email : Maybe String
+    , tags : List String
+    , shape : Shape
+    }
+
+
+type Shape
+    = Circle Float
+    | Rect Float Float
+    | Empty
+
+
+type Tree a
+    = Leaf
+    | Node (Tree a) a (Tree a)
+
+
+type alias Pair a =
+    ( a, List ( Int, a ) )
+
+
+type alias Settings =
+    { flags : Dict String Bool
+    , point : { x : Float, y : Float }
+    }
+
+
+type Secret
+    = Secret String
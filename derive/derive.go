@@ -0,0 +1,547 @@
+// Package derive derives the JSON decoders and encoders of the type aliases
+// and the union types of a module, which are the most tedious code to write
+// by hand in Elm. They are written to a new module, such as `Model.Json` for
+// the types of `Model`, whose decoder and encoder of a type `User` are
+// `decodeUser` and `encodeUser`.
+//
+// A record is an object with the same fields, a tuple or a list is an array,
+// a Maybe value is null or the value, and a Dict with String keys is an
+// object. A value of a union type is an object with the name of its
+// constructor in the "tag" field and its arguments in the "args" array,
+// such as `{"tag": "Circle", "args": [1.5]}`. The decoders and encoders of a
+// type with arguments take the decoders and encoders of its arguments, in
+// the same order.
+package derive
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/format"
+)
+
+// Error is the error of a type whose decoder and encoder cannot be derived.
+type Error struct {
+	// Type is the name of the type.
+	Type string
+	// Node is the type, or the part of it, which has no JSON
+	// representation.
+	Node ast.Node
+	// Message describes the problem.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("derive: %s: %s", e.Type, e.Message)
+}
+
+// Module returns the name of the module with the decoders and encoders of
+// the types of the given module, such as `Model.Json` for `Model`.
+func Module(module string) string {
+	return module + ".Json"
+}
+
+// JSON returns the source of the module with the JSON decoders and encoders
+// of the types of the module with the given names, or of all the types the
+// module exposes if there are no names. The types must be exposed by the
+// module, the union types with their constructors.
+func JSON(mod *ast.Module, names ...string) ([]byte, error) {
+	d := &deriver{mod: mod, types: make(map[string]ast.Decl)}
+	for _, decl := range mod.Decls {
+		switch decl := decl.(type) {
+		case *ast.AliasDecl:
+			d.types[decl.Name.Name] = decl
+			d.order = append(d.order, decl.Name.Name)
+		case *ast.UnionDecl:
+			d.types[decl.Name.Name] = decl
+			d.order = append(d.order, decl.Name.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		for _, name := range d.order {
+			_, union := d.types[name].(*ast.UnionDecl)
+			if d.isExposed(name, union) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		decl, ok := d.types[name]
+		if !ok {
+			return nil, &Error{name, nil, fmt.Sprintf("there is no type %s in module %s", name, mod.Name)}
+		}
+
+		if err := d.derive(decl); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(d.exposed) == 0 {
+		return nil, fmt.Errorf("derive: module %s has no types", mod.Name)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s exposing (%s)\n\n", Module(mod.Name), strings.Join(d.exposed, ", "))
+	fmt.Fprintf(&buf, "{-| The JSON decoders and encoders of the types of `%s`, derived by `elmo derive`.\n-}\n\n", mod.Name)
+	if d.array {
+		buf.WriteString("import Array\n")
+	}
+	if d.dict {
+		buf.WriteString("import Dict\n")
+	}
+	buf.WriteString("import Json.Decode as Decode\nimport Json.Encode as Encode\n")
+	fmt.Fprintf(&buf, "import %s exposing (..)\n", mod.Name)
+	buf.Write(d.buf.Bytes())
+
+	if d.andMap {
+		buf.WriteString("\n\nandMap : Decode.Decoder a -> Decode.Decoder (a -> b) -> Decode.Decoder b\nandMap =\n    Decode.map2 (|>)\n")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("derive: the derived module is not valid: %s", err)
+	}
+	return src, nil
+}
+
+// basics are the basic types, whose decoders and encoders are the ones of
+// the Json.Decode and Json.Encode modules with the same name.
+var basics = map[string]bool{
+	"Int":    true,
+	"Float":  true,
+	"String": true,
+	"Bool":   true,
+}
+
+// deriver derives the decoders and encoders of the types of a module.
+type deriver struct {
+	mod *ast.Module
+	// types are the aliases and unions of the module by name, in order.
+	types map[string]ast.Decl
+	order []string
+
+	buf     bytes.Buffer
+	exposed []string
+	// name is the name of the type being derived.
+	name string
+	// andMap, array and dict report whether the derived code uses the
+	// andMap helper and the Array and Dict modules.
+	andMap, array, dict bool
+}
+
+// derive writes the decoder and the encoder of the type.
+func (d *deriver) derive(decl ast.Decl) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+
+	switch decl := decl.(type) {
+	case *ast.AliasDecl:
+		d.name = decl.Name.Name
+		if !d.isExposed(d.name, false) {
+			d.fail(decl.Name, "it is not exposed by module %s", d.mod.Name)
+		}
+		d.alias(decl)
+	case *ast.UnionDecl:
+		d.name = decl.Name.Name
+		if !d.isExposed(d.name, true) {
+			d.fail(decl.Name, "it is not exposed with its constructors by module %s", d.mod.Name)
+		}
+		d.union(decl)
+	}
+	d.exposed = append(d.exposed, "decode"+d.name, "encode"+d.name)
+	return nil
+}
+
+func (d *deriver) fail(node ast.Node, msg string, args ...interface{}) {
+	panic(&Error{d.name, node, fmt.Sprintf(msg, args...)})
+}
+
+// isExposed reports whether the module exposes the type, with all of its
+// constructors if ctors is true.
+func (d *deriver) isExposed(name string, ctors bool) bool {
+	switch list := d.mod.Module.Exposing.(type) {
+	case *ast.OpenList:
+		return true
+	case *ast.ClosedList:
+		for _, e := range list.Exposed {
+			switch e := e.(type) {
+			case *ast.ExposedUnion:
+				if e.Type.Name == name {
+					_, open := e.Ctors.(*ast.OpenList)
+					return open || !ctors
+				}
+			case *ast.ExposedVar:
+				if e.Name == name {
+					return !ctors
+				}
+			}
+		}
+	}
+	return false
+}
+
+// alias writes the decoder and the encoder of a type alias.
+func (d *deriver) alias(decl *ast.AliasDecl) {
+	d.writeDecoder(decl.Args, func() string {
+		if record, ok := decl.Type.(*ast.RecordType); ok && len(decl.Args) == 0 {
+			return d.recordDecoder(record, decl.Name.Name, false)
+		}
+		return unparens(d.decoder(decl.Type, false))
+	})
+	d.writeEncoder(decl.Args, func() string {
+		return d.encoder(decl.Type, "value", 1)
+	})
+}
+
+// union writes the decoder and the encoder of a union type.
+func (d *deriver) union(decl *ast.UnionDecl) {
+	d.writeDecoder(decl.Args, func() string {
+		var cases []string
+		for _, c := range decl.Ctors {
+			var args []string
+			for i, a := range c.Args {
+				args = append(args, fmt.Sprintf("(Decode.index %d %s)", i, d.decoder(a, true)))
+			}
+
+			body := "Decode.succeed " + c.Name.Name
+			if len(args) > 0 {
+				body = "Decode.field \"args\" " + parens(d.apply(c.Name.Name, args))
+			}
+			cases = append(cases, fmt.Sprintf("%q ->\n    %s", c.Name.Name, body))
+		}
+		cases = append(cases, "_ ->\n    Decode.fail (\"unknown constructor \" ++ tag)")
+		return "Decode.field \"tag\" Decode.string\n    |> Decode.andThen\n        (\\tag ->\n            case tag of\n" + indent(strings.Join(cases, "\n\n"), 16) + "\n        )"
+	})
+
+	d.writeEncoder(decl.Args, func() string {
+		var cases []string
+		for _, c := range decl.Ctors {
+			pattern := c.Name.Name
+			var args []string
+			for i, a := range c.Args {
+				arg := fmt.Sprintf("a%d", i+1)
+				pattern += " " + arg
+				args = append(args, d.encoder(a, arg, 1))
+			}
+
+			cases = append(cases, fmt.Sprintf("%s ->\n    Encode.object\n        [ ( \"tag\", Encode.string %q )\n        , ( \"args\", Encode.list [ %s ] )\n        ]", pattern, c.Name.Name, strings.Join(args, ", ")))
+		}
+		return "case value of\n" + indent(strings.Join(cases, "\n\n"), 4)
+	})
+}
+
+// writeDecoder writes the decoder of a type with the given arguments, whose
+// body is returned by body.
+func (d *deriver) writeDecoder(args []*ast.Ident, body func() string) {
+	typ := typeWithArgs(d.name, args)
+	var sig []string
+	var params = []string{"decode" + d.name}
+	for _, a := range args {
+		sig = append(sig, "Decode.Decoder "+a.Name)
+		params = append(params, varDecoder(a.Name))
+	}
+	sig = append(sig, "Decode.Decoder "+parens(typ))
+
+	fmt.Fprintf(&d.buf, "\n\ndecode%s : %s\n%s =\n%s\n", d.name, strings.Join(sig, " -> "), strings.Join(params, " "), indent(body(), 4))
+}
+
+// writeEncoder writes the encoder of a type with the given arguments, whose
+// body, which encodes `value`, is returned by body.
+func (d *deriver) writeEncoder(args []*ast.Ident, body func() string) {
+	typ := typeWithArgs(d.name, args)
+	var sig []string
+	var params = []string{"encode" + d.name}
+	for _, a := range args {
+		sig = append(sig, "("+a.Name+" -> Encode.Value)")
+		params = append(params, varEncoder(a.Name))
+	}
+	sig = append(sig, parens(typ), "Encode.Value")
+	params = append(params, "value")
+
+	fmt.Fprintf(&d.buf, "\n\nencode%s : %s\n%s =\n%s\n", d.name, strings.Join(sig, " -> "), strings.Join(params, " "), indent(body(), 4))
+}
+
+// decoder returns the decoder of the type. The decoders of the types of the
+// module are lazy if lazy is true, so the decoders of recursive types do
+// not refer to themselves before they are defined.
+func (d *deriver) decoder(typ ast.Type, lazy bool) string {
+	switch t := typ.(type) {
+	case *ast.VarType:
+		return varDecoder(t.Name)
+	case *ast.TupleType:
+		if len(t.Elems) == 0 {
+			return "(Decode.succeed ())"
+		}
+
+		var args, vars []string
+		for i, e := range t.Elems {
+			args = append(args, fmt.Sprintf("(Decode.index %d %s)", i, d.decoder(e, lazy)))
+			vars = append(vars, fmt.Sprintf("t%d", i+1))
+		}
+		ctor := fmt.Sprintf("(\\%s -> ( %s ))", strings.Join(vars, " "), strings.Join(vars, ", "))
+		return parens(d.apply(ctor, args))
+	case *ast.RecordType:
+		return parens(d.recordDecoder(t, "", lazy))
+	case *ast.NamedType:
+		name := typeName(t)
+		switch {
+		case basics[name]:
+			d.args(t, 0)
+			return "Decode." + strings.ToLower(name)
+		case name == "List", name == "Array":
+			d.args(t, 1)
+			return fmt.Sprintf("(Decode.%s %s)", strings.ToLower(name), d.decoder(t.Args[0], lazy))
+		case name == "Maybe":
+			d.args(t, 1)
+			return fmt.Sprintf("(Decode.nullable %s)", d.decoder(t.Args[0], lazy))
+		case name == "Dict":
+			d.args(t, 2)
+			d.stringKeys(t)
+			return fmt.Sprintf("(Decode.dict %s)", d.decoder(t.Args[1], lazy))
+		case d.types[name] != nil:
+			d.args(t, len(d.declArgs(name)))
+			var args = []string{"decode" + name}
+			for _, a := range t.Args {
+				args = append(args, d.decoder(a, lazy))
+			}
+			if lazy {
+				return "(Decode.lazy (\\_ -> " + strings.Join(args, " ") + "))"
+			}
+			return parens(strings.Join(args, " "))
+		}
+		d.fail(t, "there is no decoder of %s, which is not a type of module %s", name, d.mod.Name)
+	}
+	d.fail(typ, "functions have no JSON representation")
+	return ""
+}
+
+// recordDecoder returns the decoder of the record, which is built with the
+// given constructor, or with a function that builds the record if it is
+// empty.
+func (d *deriver) recordDecoder(record *ast.RecordType, ctor string, lazy bool) string {
+	if ctor == "" {
+		var vars, fields []string
+		for _, f := range record.Fields {
+			vars = append(vars, f.Name.Name)
+			fields = append(fields, f.Name.Name+" = "+f.Name.Name)
+		}
+		if len(fields) == 0 {
+			return "Decode.succeed {}"
+		}
+		ctor = fmt.Sprintf("(\\%s -> { %s })", strings.Join(vars, " "), strings.Join(fields, ", "))
+	}
+
+	var args []string
+	for _, f := range record.Fields {
+		args = append(args, fmt.Sprintf("(Decode.field %q %s)", f.Name.Name, d.decoder(f.Type, lazy)))
+	}
+	return d.apply(ctor, args)
+}
+
+// apply returns the decoder that applies the function to the values of the
+// decoders, which is one of the mapN functions if there are up to 8 of
+// them.
+func (d *deriver) apply(fn string, decoders []string) string {
+	switch n := len(decoders); {
+	case n == 0:
+		return "Decode.succeed " + fn
+	case n == 1:
+		return "Decode.map " + fn + " " + decoders[0]
+	case n <= 8:
+		return fmt.Sprintf("Decode.map%d %s %s", n, fn, strings.Join(decoders, " "))
+	}
+
+	d.andMap = true
+	return "Decode.succeed " + fn + "\n    |> andMap " + strings.Join(decoders, "\n    |> andMap ")
+}
+
+// encoder returns the expression that encodes the value of the given
+// expression, with the names of the variables of the lambdas numbered from
+// depth.
+func (d *deriver) encoder(typ ast.Type, value string, depth int) string {
+	switch t := typ.(type) {
+	case *ast.VarType:
+		return varEncoder(t.Name) + " " + value
+	case *ast.TupleType:
+		if len(t.Elems) == 0 {
+			return "Encode.null"
+		}
+
+		var vars, elems []string
+		for i, e := range t.Elems {
+			v := fmt.Sprintf("t%d_%d", depth, i+1)
+			vars = append(vars, v)
+			elems = append(elems, d.encoder(e, v, depth+1))
+		}
+		return fmt.Sprintf("(\\( %s ) -> Encode.list [ %s ]) %s", strings.Join(vars, ", "), strings.Join(elems, ", "), value)
+	case *ast.RecordType:
+		var fields []string
+		for _, f := range t.Fields {
+			fields = append(fields, fmt.Sprintf("( %q, %s )", f.Name.Name, d.encoder(f.Type, value+"."+f.Name.Name, depth)))
+		}
+		if len(fields) == 0 {
+			return "Encode.object []"
+		}
+		return "Encode.object [ " + strings.Join(fields, ", ") + " ]"
+	case *ast.NamedType:
+		name := typeName(t)
+		switch {
+		case basics[name]:
+			d.args(t, 0)
+			return "Encode." + strings.ToLower(name) + " " + value
+		case name == "List":
+			d.args(t, 1)
+			return fmt.Sprintf("Encode.list (List.map %s %s)", d.encoderFunc(t.Args[0], depth), value)
+		case name == "Array":
+			d.args(t, 1)
+			d.array = true
+			return fmt.Sprintf("Encode.array (Array.map %s %s)", d.encoderFunc(t.Args[0], depth), value)
+		case name == "Maybe":
+			d.args(t, 1)
+			return fmt.Sprintf("Maybe.withDefault Encode.null (Maybe.map %s %s)", d.encoderFunc(t.Args[0], depth), value)
+		case name == "Dict":
+			d.args(t, 2)
+			d.stringKeys(t)
+			d.dict = true
+			v := fmt.Sprintf("d%d", depth)
+			return fmt.Sprintf("Encode.object (List.map (\\( k%d, %s ) -> ( k%d, %s )) (Dict.toList %s))", depth, v, depth, d.encoder(t.Args[1], v, depth+1), value)
+		case d.types[name] != nil:
+			d.args(t, len(d.declArgs(name)))
+			var args = []string{"encode" + name}
+			for _, a := range t.Args {
+				args = append(args, d.encoderFunc(a, depth))
+			}
+			return strings.Join(append(args, value), " ")
+		}
+		d.fail(t, "there is no encoder of %s, which is not a type of module %s", name, d.mod.Name)
+	}
+	d.fail(typ, "functions have no JSON representation")
+	return ""
+}
+
+// encoderFunc returns the function that encodes values of the type.
+func (d *deriver) encoderFunc(typ ast.Type, depth int) string {
+	switch t := typ.(type) {
+	case *ast.VarType:
+		return varEncoder(t.Name)
+	case *ast.NamedType:
+		name := typeName(t)
+		if basics[name] && len(t.Args) == 0 {
+			return "Encode." + strings.ToLower(name)
+		}
+		if d.types[name] != nil && len(t.Args) == 0 && len(d.declArgs(name)) == 0 {
+			return "encode" + name
+		}
+	}
+
+	v := fmt.Sprintf("x%d", depth)
+	return fmt.Sprintf("(\\%s -> %s)", v, d.encoder(typ, v, depth+1))
+}
+
+// args checks that the named type has the given number of arguments.
+func (d *deriver) args(t *ast.NamedType, n int) {
+	if len(t.Args) != n {
+		d.fail(t, "%s has %d arguments, but it must have %d", typeName(t), len(t.Args), n)
+	}
+}
+
+// stringKeys checks that the keys of the Dict type are strings, which are the
+// only keys of JSON objects.
+func (d *deriver) stringKeys(t *ast.NamedType) {
+	if key, ok := t.Args[0].(*ast.NamedType); !ok || typeName(key) != "String" {
+		d.fail(t, "only a Dict with String keys is a JSON object")
+	}
+}
+
+// declArgs returns the arguments of the type of the module.
+func (d *deriver) declArgs(name string) []*ast.Ident {
+	switch decl := d.types[name].(type) {
+	case *ast.AliasDecl:
+		return decl.Args
+	case *ast.UnionDecl:
+		return decl.Args
+	}
+	return nil
+}
+
+// typeName returns the name of the named type, without its module.
+func typeName(t *ast.NamedType) string {
+	switch name := t.Name.(type) {
+	case *ast.Ident:
+		return name.Name
+	case *ast.SelectorExpr:
+		return name.Selector.Name
+	}
+	return ""
+}
+
+// typeWithArgs returns the type with the given arguments.
+func typeWithArgs(name string, args []*ast.Ident) string {
+	for _, a := range args {
+		name += " " + a.Name
+	}
+	return name
+}
+
+func varDecoder(name string) string {
+	return "decode" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+func varEncoder(name string) string {
+	return "encode" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// parens returns the code in parentheses if it has spaces and it is not in
+// parentheses already.
+func parens(code string) string {
+	if !strings.ContainsAny(code, " \n") || (strings.HasPrefix(code, "(") && strings.HasSuffix(code, ")") && balanced(code[1:len(code)-1])) {
+		return code
+	}
+	return "(" + code + ")"
+}
+
+// unparens returns the code without the parentheses around it, if any.
+func unparens(code string) string {
+	if strings.HasPrefix(code, "(") && strings.HasSuffix(code, ")") && balanced(code[1:len(code)-1]) {
+		return code[1 : len(code)-1]
+	}
+	return code
+}
+
+// balanced reports whether the parentheses of the code are balanced.
+func balanced(code string) bool {
+	var depth int
+	for _, r := range code {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// indent indents every line of the code but the empty ones.
+func indent(code string, n int) string {
+	lines := strings.Split(code, "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = strings.Repeat(" ", n) + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
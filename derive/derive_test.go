@@ -0,0 +1,147 @@
+package derive
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	require := require.New(t)
+
+	f, err := os.Open("_testdata/Model.elm")
+	require.NoError(err)
+	defer f.Close()
+
+	mod, err := parser.ParseFrom("Model.elm", f, parser.FullParse)
+	require.NoError(err)
+
+	src, err := JSON(mod)
+	require.NoError(err)
+
+	elm := string(src)
+	require.Contains(elm, "module Model.Json exposing (decodeUser, encodeUser, decodeShape, encodeShape, decodeTree, encodeTree, decodePair, encodePair, decodeSettings, encodeSettings)\n")
+	require.Contains(elm, "import Dict\nimport Json.Decode as Decode\nimport Json.Encode as Encode\nimport Model exposing (..)\n")
+	require.NotContains(elm, "Secret")
+
+	require.Contains(elm, `decodeUser : Decode.Decoder User
+decodeUser =
+    Decode.map5 User (Decode.field "id" Decode.int) (Decode.field "name" Decode.string) (Decode.field "email" (Decode.nullable Decode.string)) (Decode.field "tags" (Decode.list Decode.string)) (Decode.field "shape" decodeShape)
+`)
+	require.Contains(elm, `( "email", Maybe.withDefault Encode.null (Maybe.map Encode.string value.email) )`)
+
+	require.Contains(elm, `                    "Rect" ->
+                        Decode.field "args" (Decode.map2 Rect (Decode.index 0 Decode.float) (Decode.index 1 Decode.float))
+`)
+	require.Contains(elm, `        Rect a1 a2 ->
+            Encode.object
+                [ ( "tag", Encode.string "Rect" )
+                , ( "args", Encode.list [ Encode.float a1, Encode.float a2 ] )
+                ]
+`)
+
+	require.Contains(elm, "decodeTree : Decode.Decoder a -> Decode.Decoder (Tree a)\ndecodeTree decodeA =\n")
+	require.Contains(elm, `(Decode.index 0 (Decode.lazy (\_ -> decodeTree decodeA)))`)
+	require.Contains(elm, "encodeTree : (a -> Encode.Value) -> Tree a -> Encode.Value\nencodeTree encodeA value =\n")
+	require.Contains(elm, "Encode.list [ encodeTree encodeA a1, encodeA a2, encodeTree encodeA a3 ]")
+
+	require.Contains(elm, `decodePair decodeA =
+    Decode.map2 (\t1 t2 -> ( t1, t2 )) (Decode.index 0 decodeA) (Decode.index 1 (Decode.list (Decode.map2 (\t1 t2 -> ( t1, t2 )) (Decode.index 0 Decode.int) (Decode.index 1 decodeA))))
+`)
+	require.Contains(elm, `(Decode.field "flags" (Decode.dict Decode.bool))`)
+	require.Contains(elm, `(Decode.map2 (\x y -> { x = x, y = y }) (Decode.field "x" Decode.float) (Decode.field "y" Decode.float))`)
+	require.Contains(elm, `( "point", Encode.object [ ( "x", Encode.float value.point.x ), ( "y", Encode.float value.point.y ) ] )`)
+}
+
+func TestJSONNames(t *testing.T) {
+	require := require.New(t)
+
+	mod := parse(t, `module Model exposing (..)
+
+type alias Point =
+    { x : Float
+    , y : Float
+    }
+
+type alias Line =
+    ( Point, Point )
+`)
+
+	src, err := JSON(mod, "Point")
+	require.NoError(err)
+	require.Contains(string(src), "module Model.Json exposing (decodePoint, encodePoint)\n")
+	require.NotContains(string(src), "Line")
+}
+
+func TestJSONAndMap(t *testing.T) {
+	require := require.New(t)
+
+	mod := parse(t, `module Model exposing (..)
+
+type alias Big =
+    { a : Int, b : Int, c : Int, d : Int, e : Int, f : Int, g : Int, h : Int, i : Int }
+`)
+
+	src, err := JSON(mod)
+	require.NoError(err)
+	require.Contains(string(src), `    Decode.succeed Big
+        |> andMap (Decode.field "a" Decode.int)
+`)
+	require.Contains(string(src), "\nandMap =\n    Decode.map2 (|>)\n")
+}
+
+func TestJSONErrors(t *testing.T) {
+	cases := []struct {
+		src   string
+		names []string
+		err   string
+	}{
+		{
+			"module Model exposing (Shape)\n\ntype Shape\n    = Circle Float\n",
+			[]string{"Shape"},
+			"derive: Shape: it is not exposed with its constructors by module Model",
+		},
+		{
+			"module Model exposing (..)\n\ntype alias Handler =\n    { run : Int -> Int }\n",
+			nil,
+			"derive: Handler: functions have no JSON representation",
+		},
+		{
+			"module Model exposing (..)\n\nimport Set exposing (Set)\n\ntype alias Tags =\n    Set String\n",
+			nil,
+			"derive: Tags: there is no decoder of Set, which is not a type of module Model",
+		},
+		{
+			"module Model exposing (..)\n\nimport Dict exposing (Dict)\n\ntype alias Scores =\n    Dict Int Float\n",
+			nil,
+			"derive: Scores: only a Dict with String keys is a JSON object",
+		},
+		{
+			"module Model exposing (..)\n\ntype alias Point =\n    { x : Float }\n",
+			[]string{"Line"},
+			"derive: Line: there is no type Line in module Model",
+		},
+		{
+			"module Model exposing (..)\n\nx : Int\nx =\n    1\n",
+			nil,
+			"derive: module Model has no types",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.err, func(t *testing.T) {
+			_, err := JSON(parse(t, c.src), c.names...)
+			require.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func parse(t *testing.T, src string) *ast.Module {
+	mod, err := parser.ParseFrom("Model.elm", strings.NewReader(src), parser.FullParse)
+	require.NoError(t, err)
+	return mod
+}
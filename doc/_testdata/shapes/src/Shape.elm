@@ -0,0 +1,96 @@
+module Shape exposing (Shape(..), Point, Color, origin, area, (<+>), scale)
+
+{-| Shapes and their areas.
+
+# Shapes
+
+@docs Shape, Point, Color
+
+# Operations
+
+@docs origin, area, (<+>)
+
+-}
+
+
+{-| A point in the plane.
+-}
+type alias Point =
+    ( Int, Int )
+
+
+{-| A shape, which is either a square or a rectangle.
+
+    Square 2
+
+-}
+type Shape
+    = Square Int
+    | Rect Int Int
+
+
+{-| An opaque color.
+
+```js
+Red
+```
+
+-}
+type Color
+    = Red
+    | Blue
+
+
+{-| The center of the plane.
+
+```
+origin
+```
+
+-}
+origin : Point
+origin =
+    ( 0, 0 )
+
+
+{-| The area of a shape.
+
+```elm
+area (Square 3) ==> 3
+
+rect =
+    Rect 2 5
+
+area rect
+    ==> 2
+```
+
+-}
+area : Shape -> Int
+area shape =
+    case shape of
+        Square side ->
+            side
+
+        Rect w h ->
+            w
+
+
+{-| Joins two shapes.
+-}
+(<+>) : Shape -> Shape -> Shape
+(<+>) a b =
+    a
+
+
+scale n =
+    n
+
+
+-- internal
+hidden : Int
+hidden =
+    1
+
+
+infixl 6 <+>
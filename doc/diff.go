@@ -0,0 +1,160 @@
+package doc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elm-tangram/tangram/package"
+)
+
+// Changes are the changes between the exposed APIs of two versions of a
+// package.
+type Changes struct {
+	// Added are the names of the modules only exposed by the new version.
+	Added []string `json:"added,omitempty"`
+	// Removed are the names of the modules only exposed by the old version.
+	Removed []string `json:"removed,omitempty"`
+	// Modules are the changes of the modules exposed by both versions, only
+	// for the ones that changed.
+	Modules []ModuleChanges `json:"modules,omitempty"`
+}
+
+// ModuleChanges are the changes of the exposed declarations of a module,
+// by their names.
+type ModuleChanges struct {
+	Name    string   `json:"name"`
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Diff returns the changes from the documentation of the old version of a
+// package to the documentation of the new one. Only the exposed
+// declarations are compared, so changes in the comments do not count.
+func Diff(old, new []*Module) *Changes {
+	var c Changes
+	var oldMods = make(map[string]*Module)
+	for _, m := range old {
+		oldMods[m.Name] = m
+	}
+
+	var newMods = make(map[string]bool)
+	for _, m := range new {
+		newMods[m.Name] = true
+		o, ok := oldMods[m.Name]
+		if !ok {
+			c.Added = append(c.Added, m.Name)
+			continue
+		}
+
+		if mc := diffModule(o, m); mc != nil {
+			c.Modules = append(c.Modules, *mc)
+		}
+	}
+
+	for _, m := range old {
+		if !newMods[m.Name] {
+			c.Removed = append(c.Removed, m.Name)
+		}
+	}
+
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+	sort.Slice(c.Modules, func(i, j int) bool {
+		return c.Modules[i].Name < c.Modules[j].Name
+	})
+	return &c
+}
+
+// Magnitude returns the magnitude of the changes, which is major if anything
+// was removed or changed and minor if anything was only added.
+func (c *Changes) Magnitude() pkg.Magnitude {
+	if len(c.Removed) > 0 {
+		return pkg.Major
+	}
+
+	m := pkg.Patch
+	if len(c.Added) > 0 {
+		m = pkg.Minor
+	}
+
+	for _, mod := range c.Modules {
+		if len(mod.Changed) > 0 || len(mod.Removed) > 0 {
+			return pkg.Major
+		}
+
+		if len(mod.Added) > 0 {
+			m = pkg.Minor
+		}
+	}
+	return m
+}
+
+// diffModule returns the changes between two versions of a module, or nil
+// if there are none.
+func diffModule(old, new *Module) *ModuleChanges {
+	o, n := apiOf(old), apiOf(new)
+	c := ModuleChanges{Name: new.Name}
+	for name, decl := range n {
+		if prev, ok := o[name]; !ok {
+			c.Added = append(c.Added, name)
+		} else if prev != decl {
+			c.Changed = append(c.Changed, name)
+		}
+	}
+
+	for name := range o {
+		if _, ok := n[name]; !ok {
+			c.Removed = append(c.Removed, name)
+		}
+	}
+
+	if len(c.Added)+len(c.Changed)+len(c.Removed) == 0 {
+		return nil
+	}
+
+	sort.Strings(c.Added)
+	sort.Strings(c.Changed)
+	sort.Strings(c.Removed)
+	return &c
+}
+
+// apiOf returns a description of every exposed declaration of the module by
+// its name, which is the same for two declarations if and only if they are
+// compatible.
+func apiOf(m *Module) map[string]string {
+	var api = make(map[string]string)
+	for _, a := range m.Aliases {
+		api[a.Name] = "alias " + strings.Join(a.Args, " ") + " = " + normalize(a.Type)
+	}
+
+	for _, u := range m.Types {
+		var cases []string
+		for _, c := range u.Cases {
+			args := make([]string, len(c.Args))
+			for i, arg := range c.Args {
+				args[i] = "(" + normalize(arg) + ")"
+			}
+			cases = append(cases, c.Name+" "+strings.Join(args, " "))
+		}
+
+		// the order of the constructors does not matter
+		sort.Strings(cases)
+		api[u.Name] = "type " + strings.Join(u.Args, " ") + " = " + strings.Join(cases, " | ")
+	}
+
+	for _, v := range m.Values {
+		decl := normalize(v.Type)
+		if v.Precedence != nil {
+			decl += " infix " + v.Associativity + " " + strconv.Itoa(int(*v.Precedence))
+		}
+		api[v.Name] = decl
+	}
+	return api
+}
+
+// normalize removes the differences in the spacing of a type.
+func normalize(typ string) string {
+	return strings.Join(strings.Fields(typ), " ")
+}
@@ -0,0 +1,50 @@
+package doc
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/package"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	require := require.New(t)
+	old := shapeDocs(t)
+
+	c := Diff([]*Module{old}, []*Module{old})
+	require.Equal(&Changes{}, c)
+	require.Equal(pkg.Patch, c.Magnitude())
+
+	// the comments and the spacing of the types are not part of the API
+	patched := *old
+	patched.Comment = "Shapes."
+	patched.Values = append([]Value(nil), old.Values...)
+	patched.Values[1].Type = "Shape ->  Int"
+	require.Equal(pkg.Patch, Diff([]*Module{old}, []*Module{&patched}).Magnitude())
+
+	added := *old
+	added.Values = append(append([]Value(nil), old.Values...), Value{Name: "zero", Type: "Shape"})
+	other := &Module{Name: "Other"}
+	c = Diff([]*Module{old}, []*Module{&added, other})
+	require.Equal(&Changes{
+		Added:   []string{"Other"},
+		Modules: []ModuleChanges{{Name: "Shape", Added: []string{"zero"}}},
+	}, c)
+	require.Equal(pkg.Minor, c.Magnitude())
+
+	changed := *old
+	changed.Values = append([]Value(nil), old.Values[1:]...)
+	changed.Values[0].Type = "Shape -> Float"
+	changed.Types = append([]Union(nil), old.Types...)
+	changed.Types[1].Cases = []Case{old.Types[1].Cases[1], old.Types[1].Cases[0]}
+	c = Diff([]*Module{old}, []*Module{&changed})
+	require.Equal(&Changes{
+		Modules: []ModuleChanges{{Name: "Shape", Changed: []string{"area"}, Removed: []string{"<+>"}}},
+	}, c)
+	require.Equal(pkg.Major, c.Magnitude())
+
+	c = Diff([]*Module{old, other}, []*Module{old})
+	require.Equal(&Changes{Removed: []string{"Other"}}, c)
+	require.Equal(pkg.Major, c.Magnitude())
+}
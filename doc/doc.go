@@ -0,0 +1,331 @@
+// Package doc implements the documentation of Elm packages, which is made of
+// the doc comments and the exposed declarations of their modules. The
+// documentation is encoded in the format of the docs.json files of the
+// package registry, and it can also be rendered as an HTML page.
+//
+// A doc comment starts with "{-|". The first doc comment after the module
+// declaration documents the module, and the rest document the declaration
+// that follows them.
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/iface"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// ElmVersion is the version of Elm the documentation is generated for.
+const ElmVersion = "0.18.0"
+
+// Module is the documentation of a module.
+type Module struct {
+	// Name of the module.
+	Name string `json:"name"`
+	// Comment is the doc comment of the module.
+	Comment string `json:"comment"`
+	// Aliases are the exposed type aliases.
+	Aliases []Alias `json:"aliases"`
+	// Types are the exposed union types.
+	Types []Union `json:"types"`
+	// Values are the exposed definitions, including the operators.
+	Values []Value `json:"values"`
+	// ElmVersion is the version of Elm the documentation was generated
+	// for.
+	ElmVersion string `json:"generated-with-elm-version"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The
+// documentation of Elm 0.19 is decoded too, whose union types are in
+// "unions" and whose operators are in "binops" instead of the values.
+func (m *Module) UnmarshalJSON(data []byte) error {
+	type module Module
+	var d struct {
+		*module
+		Unions []Union `json:"unions"`
+		Binops []Value `json:"binops"`
+	}
+	d.module = (*module)(m)
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+
+	m.Types = append(m.Types, d.Unions...)
+	m.Values = append(m.Values, d.Binops...)
+	return nil
+}
+
+// Alias is the documentation of a type alias.
+type Alias struct {
+	Name    string   `json:"name"`
+	Comment string   `json:"comment"`
+	Args    []string `json:"args"`
+	// Type is the aliased type.
+	Type string `json:"type"`
+}
+
+// Union is the documentation of an union type.
+type Union struct {
+	Name    string   `json:"name"`
+	Comment string   `json:"comment"`
+	Args    []string `json:"args"`
+	// Cases are the exposed constructors of the type.
+	Cases []Case `json:"cases"`
+}
+
+// Case is a constructor of an union type, which is encoded as a pair of its
+// name and the types of its arguments.
+type Case struct {
+	Name string
+	Args []string
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c Case) MarshalJSON() ([]byte, error) {
+	args := c.Args
+	if args == nil {
+		args = []string{}
+	}
+	return json.Marshal([]interface{}{c.Name, args})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (c *Case) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+
+	if len(pair) != 2 {
+		return fmt.Errorf("doc: expecting a constructor and its arguments, got %s", data)
+	}
+
+	if err := json.Unmarshal(pair[0], &c.Name); err != nil {
+		return err
+	}
+	return json.Unmarshal(pair[1], &c.Args)
+}
+
+// Value is the documentation of a definition.
+type Value struct {
+	Name    string `json:"name"`
+	Comment string `json:"comment"`
+	Type    string `json:"type"`
+	// Associativity is the associativity of an operator with a fixity
+	// declaration: left, right or non.
+	Associativity string `json:"associativity,omitempty"`
+	// Precedence is the precedence of an operator with a fixity
+	// declaration.
+	Precedence *uint `json:"precedence,omitempty"`
+}
+
+// IsOp reports whether the value is an operator.
+func (v Value) IsOp() bool {
+	return v.Name != "" && !isIdentStart(v.Name[0])
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+var assocNames = map[ast.Associativity]string{
+	ast.Left:     "left",
+	ast.Right:    "right",
+	ast.NonAssoc: "non",
+}
+
+// New returns the documentation of the given module, which must have been
+// parsed with its comments and resolved. The types of the exposed
+// definitions without annotations are taken from info, so it must be the
+// info of the checked module.
+func New(mod *ast.Module, info *types.Info) *Module {
+	i := iface.New(mod, "")
+	comment, comments := docComments(mod)
+	d := &Module{
+		Name:       mod.Name,
+		Comment:    comment,
+		Aliases:    []Alias{},
+		Types:      []Union{},
+		Values:     []Value{},
+		ElmVersion: ElmVersion,
+	}
+
+	for _, t := range i.Types {
+		args := t.Args
+		if args == nil {
+			args = []string{}
+		}
+
+		if isAlias(mod, t.Name) {
+			d.Aliases = append(d.Aliases, Alias{Name: t.Name, Comment: comments[t.Name], Args: args, Type: t.Alias})
+			continue
+		}
+
+		u := Union{Name: t.Name, Comment: comments[t.Name], Args: args, Cases: []Case{}}
+		for _, c := range t.Ctors {
+			u.Cases = append(u.Cases, Case{Name: c.Name, Args: c.Args})
+		}
+		d.Types = append(d.Types, u)
+	}
+
+	var fixities = make(map[string]iface.Fixity)
+	for _, f := range i.Fixities {
+		fixities[f.Op] = f
+	}
+
+	for _, v := range i.Values {
+		value := Value{Name: v.Name, Comment: comments[v.Name], Type: v.Type}
+		if value.Type == "" {
+			value.Type = inferredType(mod, info, v.Name)
+		}
+
+		if f, ok := fixities[v.Name]; ok {
+			precedence := f.Precedence
+			value.Associativity = assocNames[f.Assoc]
+			value.Precedence = &precedence
+		}
+		d.Values = append(d.Values, value)
+	}
+	return d
+}
+
+// isAlias reports whether the type with the given name is a type alias.
+func isAlias(mod *ast.Module, name string) bool {
+	for _, d := range mod.Decls {
+		if a, ok := d.(*ast.AliasDecl); ok && a.Name.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inferredType returns the type of the definition with the given name in
+// the info, or an empty string if it was not checked.
+func inferredType(mod *ast.Module, info *types.Info, name string) string {
+	if info == nil {
+		return ""
+	}
+
+	for _, d := range mod.Decls {
+		if def, ok := d.(*ast.Definition); ok && def.Name.Name == name {
+			if obj := info.Defs[def.Name]; obj != nil {
+				return types.TypeString(obj.Type)
+			}
+		}
+	}
+	return ""
+}
+
+// docComments returns the text of the doc comment of the module and the
+// ones of its declarations by the name of what they document.
+func docComments(mod *ast.Module) (string, map[string]string) {
+	var comment string
+	c, nodes := docCommentNodes(mod)
+	if c != nil {
+		comment = commentText(c)
+	}
+
+	var comments = make(map[string]string, len(nodes))
+	for name, c := range nodes {
+		comments[name] = commentText(c)
+	}
+	return comment, comments
+}
+
+// docCommentNodes returns the doc comment of the module, which is nil if it
+// has none, and the ones of its declarations by the name of what they
+// document.
+func docCommentNodes(mod *ast.Module) (*ast.Comment, map[string]*ast.Comment) {
+	var docs []*ast.Comment
+	for _, c := range mod.Comments {
+		if strings.HasPrefix(c.Text, "{-|") {
+			docs = append(docs, c)
+		}
+	}
+
+	var comment *ast.Comment
+	var comments = make(map[string]*ast.Comment)
+	var prevEnd token.Pos
+	if mod.Module != nil {
+		prevEnd = mod.Module.End()
+	}
+
+	var first = token.Pos(-1)
+	if len(mod.Imports) > 0 {
+		first = mod.Imports[0].Pos()
+	} else if len(mod.Decls) > 0 {
+		first = mod.Decls[0].Pos()
+	}
+
+	if len(docs) > 0 && docs[0].Pos() >= prevEnd && (first < 0 || docs[0].End() <= first) {
+		comment = docs[0]
+		prevEnd = docs[0].End()
+	}
+
+	for _, d := range mod.Decls {
+		if name := declName(d); name != "" {
+			if c := commentBetween(docs, prevEnd, d.Pos()); c != nil {
+				comments[name] = c
+			}
+		}
+		prevEnd = d.End()
+	}
+	return comment, comments
+}
+
+// commentBetween returns the last doc comment between the given positions,
+// if any.
+func commentBetween(docs []*ast.Comment, start, end token.Pos) *ast.Comment {
+	var result *ast.Comment
+	for _, c := range docs {
+		if c.Pos() >= start && c.End() <= end {
+			result = c
+		}
+	}
+	return result
+}
+
+// commentText returns the text of a doc comment without its delimiters.
+func commentText(c *ast.Comment) string {
+	return strings.TrimSuffix(strings.TrimPrefix(c.Text, "{-|"), "-}")
+}
+
+// declName returns the name of the declaration that can be documented, or
+// an empty string if it cannot be.
+func declName(d ast.Decl) string {
+	switch d := d.(type) {
+	case *ast.Definition:
+		return d.Name.Name
+	case *ast.AliasDecl:
+		return d.Name.Name
+	case *ast.UnionDecl:
+		return d.Name.Name
+	}
+	return ""
+}
+
+// Write encodes the documentation of the given modules to w, in the format
+// of docs.json files.
+func Write(w io.Writer, mods []*Module) error {
+	data, err := json.MarshalIndent(mods, "", "    ")
+	if err != nil {
+		return fmt.Errorf("doc: can't encode documentation: %s", err)
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Read decodes the documentation of some modules from a docs.json file.
+func Read(r io.Reader) ([]*Module, error) {
+	var mods []*Module
+	if err := json.NewDecoder(r).Decode(&mods); err != nil {
+		return nil, fmt.Errorf("doc: can't decode documentation: %s", err)
+	}
+	return mods, nil
+}
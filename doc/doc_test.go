@@ -0,0 +1,119 @@
+package doc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func shapeDocs(t *testing.T) *Module {
+	pkg, err := parser.Parse("_testdata/shapes/src/Shape.elm", parser.FullParse|parser.ParseComments)
+	require.NoError(t, err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(t, conf.Check(pkg, info))
+	return New(pkg.Modules["Shape"], info)
+}
+
+func TestNew(t *testing.T) {
+	require := require.New(t)
+	m := shapeDocs(t)
+
+	require.Equal("Shape", m.Name)
+	require.Equal(" Shapes and their areas.\n\n# Shapes\n\n@docs Shape, Point, Color\n\n# Operations\n\n@docs origin, area, (<+>)\n\n", m.Comment)
+	require.Equal([]Alias{{"Point", " A point in the plane.\n", []string{}, "(Int, Int)"}}, m.Aliases)
+	require.Equal([]Union{
+		{"Color", " An opaque color.\n\n```js\nRed\n```\n\n", []string{}, []Case{}},
+		{"Shape", " A shape, which is either a square or a rectangle.\n\n    Square 2\n\n", []string{}, []Case{
+			{"Square", []string{"Int"}},
+			{"Rect", []string{"Int", "Int"}},
+		}},
+	}, m.Types)
+
+	six := uint(6)
+	require.Equal([]Value{
+		{Name: "<+>", Comment: " Joins two shapes.\n", Type: "Shape -> Shape -> Shape", Associativity: "left", Precedence: &six},
+		{Name: "area", Comment: " The area of a shape.\n\n```elm\narea (Square 3) ==> 3\n\nrect =\n    Rect 2 5\n\narea rect\n    ==> 2\n```\n\n", Type: "Shape -> Int"},
+		{Name: "origin", Comment: " The center of the plane.\n\n```\norigin\n```\n\n", Type: "Point"},
+		{Name: "scale", Type: "a -> a"},
+	}, m.Values)
+}
+
+func TestWriteRead(t *testing.T) {
+	require := require.New(t)
+	m := shapeDocs(t)
+
+	var buf bytes.Buffer
+	require.NoError(Write(&buf, []*Module{m}))
+	require.Contains(buf.String(), `"cases": [
+                    [
+                        "Square",
+                        [
+                            "Int"
+                        ]
+                    ],`)
+	require.Contains(buf.String(), `"generated-with-elm-version": "0.18.0"`)
+	require.Contains(buf.String(), `"associativity": "left",`)
+	require.NotContains(buf.String(), "null")
+
+	mods, err := Read(&buf)
+	require.NoError(err)
+	require.Equal([]*Module{m}, mods)
+}
+
+func TestReadElm019(t *testing.T) {
+	require := require.New(t)
+
+	mods, err := Read(strings.NewReader(`[{
+		"name": "Shapes",
+		"comment": "Shapes.",
+		"unions": [{"name": "Shape", "comment": "", "args": [], "cases": [["Square", ["Basics.Int"]]]}],
+		"aliases": [],
+		"values": [{"name": "area", "comment": "", "type": "Shapes.Shape -> Basics.Int"}],
+		"binops": [{"name": "<+>", "comment": "", "type": "Shapes.Shape -> Shapes.Shape -> Shapes.Shape", "associativity": "left", "precedence": 6}]
+	}]`))
+	require.NoError(err)
+	require.Len(mods, 1)
+	require.Equal([]Union{{Name: "Shape", Comment: "", Args: []string{}, Cases: []Case{{"Square", []string{"Basics.Int"}}}}}, mods[0].Types)
+	require.Len(mods[0].Values, 2)
+	require.Equal("area", mods[0].Values[0].Name)
+	require.Equal("<+>", mods[0].Values[1].Name)
+	require.True(mods[0].Values[1].IsOp())
+	require.Equal("left", mods[0].Values[1].Associativity)
+}
+
+func TestHTML(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	require.NoError(HTML(&buf, "shapes 1.0.0", []*Module{shapeDocs(t)}))
+	out := buf.String()
+
+	expected := []string{
+		"<title>shapes 1.0.0</title>",
+		`<li><a href="#Shape">Shape</a></li>`,
+		"<p>Shapes and their areas.</p>\n<h3>Shapes</h3>\n",
+		`<div class="decl" id="Shape.Shape"><pre><code>type Shape
+    = Square Int
+    | Rect Int Int</code></pre>
+<p>A shape, which is either a square or a rectangle.</p>
+<pre><code>Square 2</code></pre>
+</div>`,
+		"<pre><code>(&lt;+&gt;) : Shape -&gt; Shape -&gt; Shape</code></pre>",
+		"<p>The area of a shape.</p>\n<pre><code>area (Square 3) ==&gt; 3\n\nrect =\n    Rect 2 5\n\narea rect\n    ==&gt; 2</code></pre>\n",
+	}
+	for _, e := range expected {
+		require.Contains(out, e)
+	}
+
+	// the declarations listed in @docs go in their order, and the rest
+	// after them
+	require.True(strings.Index(out, `id="Shape.Color"`) < strings.Index(out, `id="Shape.origin"`))
+	require.True(strings.Index(out, `id="Shape.&lt;+&gt;"`) < strings.Index(out, `id="Shape.scale"`))
+}
@@ -0,0 +1,262 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Example is a code example of a doc comment, which is in a fenced code
+// block whose language is elm or none. Every line that is not indented
+// starts a snippet of the example, and the indented lines continue it.
+//
+// A snippet such as `double 2 ==> 4` is an expectation, which passes if
+// the expression equals the expected value. The imports are the ones of the
+// example, and the rest of the snippets are the definitions the
+// expectations use or expressions that are only compiled.
+type Example struct {
+	// Name is the name of the declaration whose doc comment has the
+	// example, or an empty string if it is the one of the module.
+	Name string
+	// Pos is the position of the code block in the file of the module.
+	Pos token.Pos
+	// Imports are the import snippets.
+	Imports []Snippet
+	// Decls are the definitions and the expressions of the example.
+	Decls []Snippet
+	// Expectations are its expectations, in the order of the code.
+	Expectations []Expectation
+}
+
+// Snippet is some code of an example, with the indentation of the code
+// block removed.
+type Snippet struct {
+	Code string
+	// Pos is the position of the code in the file of the module.
+	Pos token.Pos
+}
+
+// Expectation is a snippet of an example with the value its expression
+// must equal, which are separated by ==>.
+type Expectation struct {
+	Expr     string
+	Expected string
+	// Pos is the position of the expression in the file of the module.
+	Pos token.Pos
+}
+
+// Examples returns the code examples of the doc comments of the module,
+// which must have been parsed with its comments, in the order of the code.
+func Examples(mod *ast.Module) []*Example {
+	comment, comments := docCommentNodes(mod)
+
+	var examples []*Example
+	if comment != nil {
+		examples = append(examples, commentExamples("", comment)...)
+	}
+
+	for name, c := range comments {
+		examples = append(examples, commentExamples(name, c)...)
+	}
+
+	sort.Slice(examples, func(i, j int) bool {
+		return examples[i].Pos < examples[j].Pos
+	})
+	return examples
+}
+
+// line is a line of a comment, with the position where it starts.
+type line struct {
+	text string
+	pos  token.Pos
+}
+
+// commentExamples returns the examples in the fenced code blocks of the
+// comment.
+func commentExamples(name string, c *ast.Comment) []*Example {
+	var lines []line
+	var offset int
+	for _, text := range strings.Split(c.Text, "\n") {
+		lines = append(lines, line{text, c.Pos() + token.Pos(offset)})
+		offset += len(text) + 1
+	}
+
+	var examples []*Example
+	for i := 0; i < len(lines); i++ {
+		fence := strings.TrimSpace(lines[i].text)
+		if !strings.HasPrefix(fence, "```") {
+			continue
+		}
+
+		lang := strings.TrimSpace(strings.TrimLeft(fence, "`"))
+		indent := len(lines[i].text) - len(strings.TrimLeft(lines[i].text, " "))
+		var block []line
+		for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i].text), "```"); i++ {
+			block = append(block, dedent(lines[i], indent))
+		}
+
+		if lang == "" || lang == "elm" {
+			examples = append(examples, newExample(name, block))
+		}
+	}
+	return examples
+}
+
+// dedent removes the indentation of the code block from the line, which is
+// at most the given number of spaces.
+func dedent(l line, indent int) line {
+	for i := 0; i < indent && strings.HasPrefix(l.text, " "); i++ {
+		l.text = l.text[1:]
+		l.pos++
+	}
+	return l
+}
+
+// newExample returns the example of the lines of a code block.
+func newExample(name string, block []line) *Example {
+	e := &Example{Name: name}
+	if len(block) > 0 {
+		e.Pos = block[0].pos
+	}
+
+	var snippets []Snippet
+	for _, l := range block {
+		switch {
+		case strings.TrimSpace(l.text) == "":
+			if len(snippets) > 0 {
+				snippets[len(snippets)-1].Code += "\n"
+			}
+		case !strings.HasPrefix(l.text, " ") || len(snippets) == 0:
+			snippets = append(snippets, Snippet{Code: l.text, Pos: l.pos})
+		default:
+			snippets[len(snippets)-1].Code += "\n" + l.text
+		}
+	}
+
+	for _, s := range snippets {
+		s.Code = strings.TrimRight(s.Code, "\n")
+		if i := strings.Index(s.Code, "==>"); i >= 0 {
+			e.Expectations = append(e.Expectations, Expectation{
+				Expr:     strings.TrimSpace(s.Code[:i]),
+				Expected: strings.TrimSpace(s.Code[i+len("==>"):]),
+				Pos:      s.Pos,
+			})
+		} else if strings.HasPrefix(s.Code, "import ") {
+			e.Imports = append(e.Imports, s)
+		} else {
+			e.Decls = append(e.Decls, s)
+		}
+	}
+	return e
+}
+
+// definition matches the snippets that are a definition or a type
+// annotation, instead of an expression.
+var definition = regexp.MustCompile(`^[a-z_][A-Za-z0-9_']*((\s+[a-z_][A-Za-z0-9_']*)*\s*=($|[^=])|\s*:($|[^:]))`)
+
+// TestModule is a module that compiles the examples of a module against
+// its exposed API, which are the names the module it is generated from
+// exposes, and has a test for every expectation.
+type TestModule struct {
+	// Name of the module.
+	Name string
+	// Source is its source code.
+	Source []byte
+	// Path is the path of the file of the module of the examples.
+	Path string
+	// Tests are the positions of the expectations in that file, by the
+	// names of their tests, which are Expectations.
+	Tests map[string]token.Pos
+}
+
+// NewTestModule returns the test module of the examples of the given
+// module, which is named DocTests followed by its name. Every expectation
+// is a test of the Expect module and has the definitions of its example in
+// a let expression, so the definitions of different examples never clash,
+// and the examples without expectations are only compiled.
+func NewTestModule(mod *ast.Module, examples []*Example) *TestModule {
+	m := &TestModule{
+		Name:  "DocTests." + mod.Name,
+		Path:  mod.Path,
+		Tests: make(map[string]token.Pos),
+	}
+
+	var src bytes.Buffer
+	// Basics is imported for the core package as well, which has no
+	// default imports
+	fmt.Fprintf(&src, "module %s exposing (..)\n\nimport Basics exposing (..)\nimport Expect\nimport %s exposing (..)\n", m.Name, mod.Name)
+	var imported = make(map[string]bool)
+	for _, e := range examples {
+		for _, imp := range e.Imports {
+			if !imported[imp.Code] {
+				imported[imp.Code] = true
+				src.WriteString(imp.Code + "\n")
+			}
+		}
+	}
+
+	var tests, compiled int
+	for _, e := range examples {
+		for _, exp := range e.Expectations {
+			tests++
+			name := fmt.Sprintf("testExample%d", tests)
+			m.Tests[name] = exp.Pos
+			fmt.Fprintf(&src, "\n\n%s : Expect.Expectation\n%s =\n", name, name)
+			writeLet(&src, e.Decls, fmt.Sprintf(
+				"Expect.equal\n    (%s\n    )\n    (%s\n    )",
+				indent(exp.Expected, "     "),
+				indent(exp.Expr, "     "),
+			))
+		}
+
+		if len(e.Expectations) == 0 && len(e.Decls) > 0 {
+			compiled++
+			fmt.Fprintf(&src, "\n\nexample%d =\n", compiled)
+			writeLet(&src, e.Decls, "()")
+		}
+	}
+
+	m.Source = src.Bytes()
+	return m
+}
+
+// writeLet writes the body of a definition with the snippets in a let
+// expression whose result is the given code. The expressions are given
+// names, so they are compiled too.
+func writeLet(buf *bytes.Buffer, decls []Snippet, result string) {
+	if len(decls) == 0 {
+		buf.WriteString(indent("    "+result, "    ") + "\n")
+		return
+	}
+
+	buf.WriteString("    let\n")
+	for i, d := range decls {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+
+		code := d.Code
+		if !definition.MatchString(code) {
+			code = fmt.Sprintf("expression%d =\n    %s", i+1, indent(code, "    "))
+		}
+		buf.WriteString("        " + indent(code, "        ") + "\n")
+	}
+	buf.WriteString("    in\n        " + indent(result, "        ") + "\n")
+}
+
+// indent adds the prefix to every line of the code but the first, which
+// is the one that is already indented.
+func indent(code, prefix string) string {
+	lines := strings.Split(code, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
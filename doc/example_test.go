@@ -0,0 +1,106 @@
+package doc
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/token"
+
+	"github.com/stretchr/testify/require"
+)
+
+const shapePath = "_testdata/shapes/src/Shape.elm"
+
+func TestExamples(t *testing.T) {
+	require := require.New(t)
+	pkg, err := parser.Parse(shapePath, parser.FullParse|parser.ParseComments)
+	require.NoError(err)
+
+	examples := Examples(pkg.Modules["Shape"])
+	require.Len(examples, 2)
+
+	require.Equal("origin", examples[0].Name)
+	require.Nil(examples[0].Expectations)
+	require.Equal([]string{"origin"}, snippetCode(examples[0].Decls))
+
+	area := examples[1]
+	require.Equal("area", area.Name)
+	require.Equal([]string{"rect =\n    Rect 2 5"}, snippetCode(area.Decls))
+	require.Len(area.Expectations, 2)
+	require.Equal("area (Square 3)", area.Expectations[0].Expr)
+	require.Equal("3", area.Expectations[0].Expected)
+	require.Equal("area rect", area.Expectations[1].Expr)
+	require.Equal("2", area.Expectations[1].Expected)
+
+	data, err := ioutil.ReadFile(shapePath)
+	require.NoError(err)
+	src := string(data)
+	require.Equal("area (Square 3) ==> 3", src[area.Expectations[0].Pos:int(area.Expectations[0].Pos)+len("area (Square 3) ==> 3")])
+	require.Equal("rect =", src[area.Decls[0].Pos:int(area.Decls[0].Pos)+len("rect =")])
+	require.Equal(area.Expectations[0].Pos, area.Pos)
+}
+
+func TestNewTestModule(t *testing.T) {
+	require := require.New(t)
+	pkg, err := parser.Parse(shapePath, parser.FullParse|parser.ParseComments)
+	require.NoError(err)
+
+	mod := pkg.Modules["Shape"]
+	examples := Examples(mod)
+	m := NewTestModule(mod, examples)
+	require.Equal("DocTests.Shape", m.Name)
+	require.Equal(mod.Path, m.Path)
+	require.Equal(map[string]token.Pos{
+		"testExample1": examples[1].Expectations[0].Pos,
+		"testExample2": examples[1].Expectations[1].Pos,
+	}, m.Tests)
+	require.Equal(`module DocTests.Shape exposing (..)
+
+import Basics exposing (..)
+import Expect
+import Shape exposing (..)
+
+
+example1 =
+    let
+        expression1 =
+            origin
+    in
+        ()
+
+
+testExample1 : Expect.Expectation
+testExample1 =
+    let
+        rect =
+            Rect 2 5
+    in
+        Expect.equal
+            (3
+            )
+            (area (Square 3)
+            )
+
+
+testExample2 : Expect.Expectation
+testExample2 =
+    let
+        rect =
+            Rect 2 5
+    in
+        Expect.equal
+            (2
+            )
+            (area rect
+            )
+`, string(m.Source))
+}
+
+func snippetCode(snippets []Snippet) []string {
+	var code []string
+	for _, s := range snippets {
+		code = append(code, s.Code)
+	}
+	return code
+}
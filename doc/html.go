@@ -0,0 +1,186 @@
+package doc
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 0 auto; padding: 20px; }
+pre { background: #f5f5f5; padding: 10px; overflow: auto; }
+.decl { border-top: 1px solid #ddd; margin-top: 20px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>
+{{range .Modules}}<li><a href="#{{.Name}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{range .Modules}}<h2 id="{{.Name}}">{{.Name}}</h2>
+{{.Body}}
+{{end}}</body>
+</html>
+`))
+
+type pageData struct {
+	Title   string
+	Modules []pageModule
+}
+
+type pageModule struct {
+	Name string
+	Body template.HTML
+}
+
+// HTML renders the documentation of the given modules as an HTML page with
+// the given title. The comments are rendered as paragraphs, headings and
+// code blocks, and the @docs lines of the comments of the modules are
+// replaced by the declarations they list. The declarations that are not
+// listed are rendered after the comment.
+func HTML(w io.Writer, title string, mods []*Module) error {
+	data := pageData{Title: title}
+	for _, m := range mods {
+		data.Modules = append(data.Modules, pageModule{Name: m.Name, Body: moduleBody(m)})
+	}
+	return page.Execute(w, data)
+}
+
+// moduleBody returns the HTML of the comment and declarations of a module.
+func moduleBody(m *Module) template.HTML {
+	var decls = make(map[string]string)
+	var order []string
+	add := func(name, signature, comment string) {
+		decls[name] = `<div class="decl" id="` + template.HTMLEscapeString(m.Name+"."+name) + `"><pre><code>` +
+			template.HTMLEscapeString(signature) + "</code></pre>\n" + markdown(comment) + "</div>\n"
+		order = append(order, name)
+	}
+
+	for _, a := range m.Aliases {
+		add(a.Name, "type alias "+strings.Join(append([]string{a.Name}, a.Args...), " ")+" =\n    "+a.Type, a.Comment)
+	}
+
+	for _, t := range m.Types {
+		signature := "type " + strings.Join(append([]string{t.Name}, t.Args...), " ")
+		for i, c := range t.Cases {
+			sep := "|"
+			if i == 0 {
+				sep = "="
+			}
+			signature += "\n    " + sep + " " + strings.Join(append([]string{c.Name}, c.Args...), " ")
+		}
+		add(t.Name, signature, t.Comment)
+	}
+
+	for _, v := range m.Values {
+		name := v.Name
+		if v.IsOp() {
+			name = "(" + name + ")"
+		}
+		add(v.Name, name+" : "+v.Type, v.Comment)
+	}
+
+	var buf bytes.Buffer
+	var rendered = make(map[string]bool)
+	var text []string
+	flush := func() {
+		buf.WriteString(markdown(strings.Join(text, "\n")))
+		text = nil
+	}
+
+	for _, line := range strings.Split(m.Comment, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "@docs ") {
+			text = append(text, line)
+			continue
+		}
+
+		flush()
+		for _, name := range strings.Split(strings.TrimPrefix(strings.TrimSpace(line), "@docs "), ",") {
+			name = strings.Trim(strings.TrimSpace(name), "()")
+			if html, ok := decls[name]; ok && !rendered[name] {
+				buf.WriteString(html)
+				rendered[name] = true
+			}
+		}
+	}
+	flush()
+
+	for _, name := range order {
+		if !rendered[name] {
+			buf.WriteString(decls[name])
+		}
+	}
+	return template.HTML(buf.String())
+}
+
+// markdown renders the text of a comment as HTML. Paragraphs are separated
+// by blank lines, lines starting with # are headings and lines indented
+// with four spaces or fenced with ``` are code blocks.
+func markdown(text string) string {
+	var buf bytes.Buffer
+	var paragraph, code []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			buf.WriteString("<p>" + template.HTMLEscapeString(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+
+		for len(code) > 0 && code[len(code)-1] == "" {
+			code = code[:len(code)-1]
+		}
+
+		if len(code) > 0 {
+			buf.WriteString("<pre><code>" + template.HTMLEscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			code = nil
+		}
+	}
+
+	var fenced bool
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			fenced = !fenced
+			continue
+		}
+
+		if fenced {
+			code = append(code, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "    ") && len(paragraph) == 0 && trimmed != "":
+			code = append(code, strings.TrimPrefix(line, "    "))
+		case trimmed == "":
+			if len(code) > 0 {
+				code = append(code, "")
+				continue
+			}
+			flush()
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			heading := strings.TrimLeft(trimmed, "#")
+			level := len(trimmed) - len(heading)
+			if level > 3 {
+				level = 3
+			}
+			tag := string('2' + byte(level))
+			buf.WriteString("<h" + tag + ">" + template.HTMLEscapeString(strings.TrimSpace(heading)) + "</h" + tag + ">\n")
+		default:
+			if len(code) > 0 {
+				flush()
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+
+	flush()
+	return buf.String()
+}
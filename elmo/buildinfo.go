@@ -0,0 +1,107 @@
+package elmo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+)
+
+// Version is the version of elmo. The releases set it when they are built,
+// with `-ldflags "-X github.com/elm-tangram/tangram/elmo.Version=1.2.0"`.
+// Otherwise, the version is the one of the module elmo was built from, as
+// with `go install`, or devel if it was built from a checkout.
+var Version = ""
+
+// The dialects of Elm that elmo compiles.
+const (
+	// Elm018 is Elm 0.18, with the packages of elm-package.json.
+	Elm018 = "elm-0.18"
+	// Elm019Packages are the packages published for Elm 0.19, with an
+	// elm.json, which can be dependencies.
+	Elm019Packages = "elm-0.19-packages"
+	// Markdown are the modules that are Markdown files, whose code is in
+	// their fenced code blocks.
+	Markdown = "markdown"
+)
+
+// features are the features of elmo, sorted.
+var features = []string{
+	"compiler-api",
+	"doc-tests",
+	"kernel-modules",
+	"lsp",
+	"partial-parse",
+	"plugins",
+	"reproducible-builds",
+	"stdin-protocol",
+	"synthetic-modules",
+	"target-pragmas",
+	"time-travel-debugger",
+}
+
+// Info is what a build of elmo is and what it supports, so the build tools
+// and the editors can tell what they can use instead of guessing from the
+// version.
+type Info struct {
+	// Version is the version of elmo, as in Version.
+	Version string `json:"version"`
+	// Go is the version of Go elmo was built with.
+	Go string `json:"go"`
+	// Dialects are the dialects of Elm it compiles, such as Elm018.
+	Dialects []string `json:"dialects"`
+	// Backends are the backends that generate the code, with their targets.
+	Backends []Backend `json:"backends"`
+	// Features are the names of the features it has, such as "lsp" or
+	// "doc-tests", sorted. A feature is never renamed, so a tool can check
+	// for it across versions.
+	Features []string `json:"features"`
+}
+
+// Backend is a backend that generates code.
+type Backend struct {
+	// Name is the name of the backend, such as "js".
+	Name string `json:"name"`
+	// Targets are the targets it builds, sorted.
+	Targets []string `json:"targets"`
+}
+
+// BuildInfo returns what this build of elmo is and what it supports.
+func BuildInfo() Info {
+	var backends []Backend
+	var byName = make(map[string]int)
+	for _, t := range target.Targets {
+		i, ok := byName[t.Backend()]
+		if !ok {
+			i = len(backends)
+			byName[t.Backend()] = i
+			backends = append(backends, Backend{Name: t.Backend()})
+		}
+		backends[i].Targets = append(backends[i].Targets, string(t))
+	}
+
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].Name < backends[j].Name
+	})
+
+	return Info{
+		Version:  version(),
+		Go:       runtime.Version(),
+		Dialects: []string{Elm018, Elm019Packages, Markdown},
+		Backends: backends,
+		Features: append([]string(nil), features...),
+	}
+}
+
+// version returns the version of elmo.
+func version() string {
+	if Version != "" {
+		return Version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
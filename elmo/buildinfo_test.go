@@ -0,0 +1,25 @@
+package elmo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInfo(t *testing.T) {
+	require := require.New(t)
+
+	info := BuildInfo()
+	require.Equal("devel", info.Version)
+	require.NotEmpty(info.Go)
+	require.Equal([]string{Elm018, Elm019Packages, Markdown}, info.Dialects)
+	require.Equal([]Backend{
+		{Name: "go", Targets: []string{"go", "wasm"}},
+		{Name: "js", Targets: []string{"html", "js", "node", "webworker"}},
+	}, info.Backends)
+	require.Contains(info.Features, "doc-tests")
+
+	Version = "1.2.0"
+	defer func() { Version = "" }()
+	require.Equal("1.2.0", BuildInfo().Version)
+}
@@ -0,0 +1,582 @@
+// Package elmo compiles Elm packages that are only in memory, such as the
+// code of a web playground or the scripts of a program that embeds Elm. It
+// never reads or writes the file system, and the compilation is bounded by
+// limits on the size of the code and the time it takes, so the code can
+// come from anyone.
+package elmo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/golang"
+	"github.com/elm-tangram/tangram/codegen/js"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/crash"
+	"github.com/elm-tangram/tangram/ffi"
+	"github.com/elm-tangram/tangram/format"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// manifestFile is the path to the manifest of the package in the files.
+const manifestFile = "elm-package.json"
+
+// defaultManifest is the manifest of the packages without one, whose
+// modules are in the root.
+const defaultManifest = `{"source-directories": ["."]}`
+
+// runtimeNatives are the native modules provided by the JavaScript runtime,
+// which need no source.
+var runtimeNatives = map[string]bool{
+	"Native.Platform": true,
+	"Native.Debug":    true,
+}
+
+// Limits are the limits of a compilation. A zero limit is no limit.
+type Limits struct {
+	// MaxFiles is the maximum number of files.
+	MaxFiles int
+	// MaxFileSize is the maximum size of a file, and MaxSize the maximum
+	// size of all the files, in bytes.
+	MaxFileSize, MaxSize int
+	// MaxModules is the maximum number of modules the entry module imports,
+	// directly or not, itself included.
+	MaxModules int
+	// MaxDepth is the maximum number of parentheses, brackets and braces
+	// that can be nested in an Elm file.
+	MaxDepth int
+	// MaxOutput is the maximum size of the generated code, in bytes.
+	MaxOutput int
+	// Timeout is the maximum time the compilation takes.
+	Timeout time.Duration
+}
+
+// DefaultLimits are the limits of CompileString, which are enough for the
+// programs of a playground.
+var DefaultLimits = Limits{
+	MaxFiles:    64,
+	MaxFileSize: 256 << 10,
+	MaxSize:     1 << 20,
+	MaxModules:  32,
+	MaxDepth:    128,
+	MaxOutput:   8 << 20,
+	Timeout:     10 * time.Second,
+}
+
+// Output is the result of a compilation.
+type Output struct {
+	// Main is the name of the entry module.
+	Main string
+	// Code is the generated code in the default template of the target.
+	// For the wasm target, it is the Go program that is built to the
+	// WebAssembly module, which is not built.
+	Code string
+}
+
+// Diagnostic is a problem found in a file.
+type Diagnostic struct {
+	File string `json:"file"`
+	// Type is the kind of problem, such as "syntax error" or "type error".
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Line and Col are the position of the problem, which are zero if it
+	// has no position.
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", d.File, d.Type, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Col, d.Type, d.Message)
+}
+
+// CompileString compiles the module in the entry file and all the modules
+// it imports for the given target with the DefaultLimits. The files are the
+// source code of the package by their paths, relative to its root, such as
+// `src/Main.elm`. The `elm-package.json` of the package is one of them, or
+// its modules are in the root. The modules of its dependencies, such as
+// `Basics`, must be among the files, because they are not installed.
+//
+// The code is only generated if there are no errors, and the diagnostics
+// are all the problems found, warnings included.
+func CompileString(files map[string]string, entry string, t target.Target) (Output, []Diagnostic) {
+	return CompileStringWith(files, entry, t, DefaultLimits)
+}
+
+// CompileStringWith is like CompileString, but with the given limits. If
+// the compilation times out, it stops before the next module is parsed,
+// checked or generated.
+func CompileStringWith(files map[string]string, entry string, t target.Target, limits Limits) (Output, []Diagnostic) {
+	return run(&compilation{files: files, entry: entry, limits: limits}, t)
+}
+
+// CheckString is like CompileString, but the code is only parsed and type
+// checked for the given target, and not generated, as `elmo check` does.
+func CheckString(files map[string]string, entry string, t target.Target) []Diagnostic {
+	return CheckStringWith(files, entry, t, DefaultLimits)
+}
+
+// CheckStringWith is like CheckString, but with the given limits.
+func CheckStringWith(files map[string]string, entry string, t target.Target, limits Limits) []Diagnostic {
+	_, diags := run(&compilation{files: files, entry: entry, limits: limits, checkOnly: true}, t)
+	return diags
+}
+
+// ParseString parses every Elm file on its own and returns the syntax
+// errors in them, without resolving the names or type checking the code,
+// which is fast enough to report the problems of a file as it is typed.
+func ParseString(files map[string]string) []Diagnostic {
+	var paths = make([]string, 0, len(files))
+	for path := range files {
+		if filepath.Ext(path) == ".elm" || source.IsLiterate(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var diags []Diagnostic
+	for _, path := range paths {
+		_, err := parser.ParseFrom(path, strings.NewReader(files[path]), parser.SkipWarnings)
+		diags = append(diags, parseDiagnostics(path, err)...)
+	}
+	return diags
+}
+
+// FormatString formats the source code of the Elm module at the given path,
+// like `elmo fmt` does. The source code is not formatted if it has syntax
+// errors, which are returned instead, or if it is a literate module, whose
+// Markdown would be lost.
+func FormatString(path, src string) (string, []Diagnostic) {
+	if source.IsLiterate(path) {
+		c := &compilation{}
+		c.errorf(path, "literate modules cannot be formatted")
+		return "", c.diags
+	}
+
+	formatted, err := format.File(path, []byte(src))
+	if err != nil {
+		return "", parseDiagnostics(path, err)
+	}
+	return string(formatted), nil
+}
+
+// parseDiagnostics returns the diagnostics of the error of parsing the file
+// at the given path on its own, if there is one.
+func parseDiagnostics(path string, err error) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+
+	var c compilation
+	if derr, ok := err.(*parser.DiagnosticError); ok {
+		for _, file := range derr.Files {
+			c.Emit(file, derr.Diagnostics[file])
+		}
+	} else {
+		c.errorf(path, "%s", err)
+	}
+	return c.diags
+}
+
+// run runs the compilation for the given target within its timeout.
+func run(c *compilation, t target.Target) (Output, []Diagnostic) {
+	type result struct {
+		out   Output
+		diags []Diagnostic
+	}
+
+	ctx := context.Background()
+	if c.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.limits.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan result, 1)
+	c.finished = make(chan struct{})
+	go func() {
+		defer close(c.finished)
+		out := c.compile(ctx, t)
+		sort.SliceStable(c.diags, func(i, j int) bool {
+			a, b := c.diags[i], c.diags[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+		})
+		done <- result{out, c.diags}
+	}()
+
+	select {
+	case r := <-done:
+		if ctx.Err() == nil {
+			return r.out, r.diags
+		}
+	case <-ctx.Done():
+	}
+
+	return Output{}, []Diagnostic{{
+		File:    c.entry,
+		Type:    report.OtherError.String(),
+		Message: fmt.Sprintf("the compilation took longer than the limit of %s", c.limits.Timeout),
+	}}
+}
+
+// compilation is the compilation of the files of a package.
+type compilation struct {
+	files  map[string]string
+	entry  string
+	limits Limits
+	diags  []Diagnostic
+	// sources are the files by their clean paths.
+	sources map[string]string
+	pkg     *pkg.Package
+	// phase is the phase of the compilation, which names the internal
+	// error of a crash.
+	phase string
+	// checkOnly reports whether the code is only type checked, and not
+	// generated.
+	checkOnly bool
+	// finished is closed once the compilation has stopped, even if it
+	// timed out.
+	finished chan struct{}
+}
+
+func (c *compilation) errorf(file, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{
+		File:    file,
+		Type:    report.OtherError.String(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Emit collects the diagnostics of a file, so the compilation is the
+// emitter of the diagnostics of the parser and the type checker.
+func (c *compilation) Emit(file string, diagnostics []*report.Diagnostic) error {
+	for _, d := range diagnostics {
+		c.diags = append(c.diags, Diagnostic{
+			File:    file,
+			Type:    d.Type.String(),
+			Message: d.Message,
+			Line:    d.Pos.Line,
+			Col:     d.Pos.Col,
+		})
+	}
+	return nil
+}
+
+func (c *compilation) compile(ctx context.Context, t target.Target) (out Output) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = Output{}
+			c.errorf(c.entry, "%s", crash.New(c.phase, r))
+		}
+	}()
+
+	c.phase = "load"
+	if _, err := target.Parse(string(t)); err != nil {
+		c.errorf(c.entry, "%s", err)
+		return Output{}
+	}
+
+	loader, paths, ok := c.load()
+	if !ok {
+		return Output{}
+	}
+
+	manifest, ok := c.sources[manifestFile]
+	if !ok {
+		manifest = defaultManifest
+	}
+
+	var err error
+	if c.pkg, err = pkg.NewMemory(strings.NewReader(manifest), paths); err != nil {
+		c.errorf(manifestFile, "%s", err)
+		return Output{}
+	}
+
+	entry := filepath.Clean(c.entry)
+	if _, ok := c.sources[entry]; !ok {
+		c.errorf(c.entry, "there is no entry file %s", c.entry)
+		return Output{}
+	}
+
+	c.phase = "parse"
+	modules := source.NewLimitLoader(loader, source.Limits{MaxModules: c.limits.MaxModules})
+	parsed, err := parser.Parse(
+		entry, parser.FullParse,
+		parser.WithEmitter(c),
+		parser.WithPackage(c.pkg),
+		parser.WithLoader(modules),
+		parser.WithTarget(t),
+		parser.WithContext(ctx),
+	)
+	if err == parser.ErrProblems {
+		return Output{}
+	} else if err != nil {
+		c.errorf(entry, "%s", err)
+		return Output{}
+	}
+
+	if parsed == nil || len(parsed.Order) == 0 {
+		c.errorf(entry, "there are no modules in %s", entry)
+		return Output{}
+	}
+
+	c.phase = "type check"
+	main := parsed.Order[len(parsed.Order)-1]
+	info := types.NewInfo()
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, c)
+	conf := types.Config{
+		Main:    main,
+		Context: ctx,
+		Error: func(typeErr *types.Error) {
+			if err := cm.Add(typeErr.Path); err != nil {
+				c.errorf(typeErr.Path, "%s", err)
+				return
+			}
+			reporter.Report(report.NewTypeError(typeErr))
+		},
+	}
+
+	checkErr := conf.Check(parsed, info)
+	if crashErr, ok := checkErr.(*crash.Error); ok {
+		crashErr.Locate(cm)
+		c.errorf(entry, "%s", crashErr)
+		return Output{}
+	}
+
+	if err := reporter.Emit(); err != nil {
+		c.errorf(entry, "%s", err)
+		return Output{}
+	}
+
+	if checkErr != nil {
+		return Output{}
+	}
+
+	if t.Backend() == "go" && !c.checkNatives(parsed, loader, cm) {
+		return Output{}
+	}
+
+	if c.checkOnly {
+		return Output{Main: main}
+	}
+
+	if err := ctx.Err(); err != nil {
+		c.errorf(entry, "%s", err)
+		return Output{}
+	}
+
+	c.phase = "generate"
+	code, err := c.generate(ctx, ir.Lower(parsed, info), main, t)
+	if err != nil {
+		c.errorf(entry, "%s", err)
+		return Output{}
+	}
+
+	if c.limits.MaxOutput > 0 && len(code) > c.limits.MaxOutput {
+		c.errorf(entry, "the generated code is larger than the limit of %d bytes", c.limits.MaxOutput)
+		return Output{}
+	}
+	return Output{Main: main, Code: code}
+}
+
+// load checks the limits of the files and returns the loader of them, with
+// their clean paths in order. It reports whether they are within the limits.
+func (c *compilation) load() (source.Loader, []string, bool) {
+	if c.limits.MaxFiles > 0 && len(c.files) > c.limits.MaxFiles {
+		c.errorf(c.entry, "there are %d files, but the limit is %d", len(c.files), c.limits.MaxFiles)
+		return nil, nil, false
+	}
+
+	var paths []string
+	for path := range c.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ok = true
+	var size int
+	c.sources = make(map[string]string)
+	loader := source.NewMemLoader()
+	for i, path := range paths {
+		src := c.files[path]
+		size += len(src)
+		if c.limits.MaxFileSize > 0 && len(src) > c.limits.MaxFileSize {
+			c.errorf(path, "the file is larger than the limit of %d bytes", c.limits.MaxFileSize)
+			ok = false
+			continue
+		}
+
+		if (filepath.Ext(path) == ".elm" || source.IsLiterate(path)) && !c.checkDepth(path, src) {
+			ok = false
+			continue
+		}
+
+		paths[i] = filepath.Clean(path)
+		c.sources[paths[i]] = src
+		loader.Add(paths[i], src)
+	}
+
+	if c.limits.MaxSize > 0 && size > c.limits.MaxSize {
+		c.errorf(c.entry, "the files are larger than the limit of %d bytes", c.limits.MaxSize)
+		return nil, nil, false
+	}
+	return loader, paths, ok
+}
+
+// checkDepth reports whether the parentheses, brackets and braces of the
+// Elm source are nested within the limit, so it can be parsed without
+// exhausting the stack.
+func (c *compilation) checkDepth(path, src string) bool {
+	if c.limits.MaxDepth <= 0 {
+		return true
+	}
+
+	if source.IsLiterate(path) {
+		src = string(source.Literate([]byte(src)))
+	}
+
+	s := scanner.New(path, strings.NewReader(src))
+	s.Run()
+
+	var depth int
+	for tok := s.Next(); tok != nil && tok.Type != token.EOF; tok = s.Next() {
+		switch tok.Type {
+		case token.LeftParen, token.LeftBracket, token.LeftBrace:
+			depth++
+			if depth > c.limits.MaxDepth {
+				c.errorf(path, "expressions are nested more than %d times", c.limits.MaxDepth)
+				return false
+			}
+		case token.RightParen, token.RightBracket, token.RightBrace:
+			depth--
+		}
+	}
+	return true
+}
+
+// checkNatives reports whether the Go files of the native modules of the
+// package implement their declarations, and reports the mismatches.
+func (c *compilation) checkNatives(parsed *ast.Package, loader source.Loader, cm *source.CodeMap) bool {
+	errs, err := ffi.Check(parsed, loader)
+	if err != nil {
+		c.errorf(c.entry, "%s", err)
+		return false
+	}
+
+	reporter := report.NewReporter(cm, c)
+	for _, e := range errs {
+		if err := cm.Add(e.Path); err != nil {
+			c.errorf(e.Path, "%s", err)
+			return false
+		}
+		reporter.Report(report.NewBaseReport(report.TypeError, token.At(e.Path, e.Pos), e.Message, nil))
+	}
+	if err := reporter.Emit(); err != nil {
+		c.errorf(c.entry, "%s", err)
+		return false
+	}
+	return len(errs) == 0
+}
+
+// generate returns the code of the package for the target, in its default
+// template. It is a program if the main module has a main, or a library
+// with everything exposed otherwise, which can only be built for the js
+// target.
+func (c *compilation) generate(ctx context.Context, lowered *ir.Package, module string, t target.Target) (string, error) {
+	var main string
+	if mod := lowered.Lookup(module); mod != nil && mod.Lookup("main") != nil {
+		main = module
+	}
+
+	if main == "" && t != target.JS {
+		return "", fmt.Errorf("module %s has no main, so it cannot be built for the %s target", module, t)
+	}
+	ir.Optimize(lowered, ir.Options{})
+
+	var code bytes.Buffer
+	switch t.Backend() {
+	case "go":
+		if err := golang.Generate(&code, lowered, golang.Options{Main: main, Context: ctx}); err != nil {
+			return "", err
+		}
+	default:
+		natives, err := c.natives(lowered)
+		if err != nil {
+			return "", err
+		}
+
+		if err := js.Generate(&code, lowered, js.Options{Main: main, Natives: natives, Context: ctx}); err != nil {
+			return "", err
+		}
+	}
+
+	tmpl, err := target.Default(t)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, target.Data{Main: main, Code: code.String()}); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// natives returns the JavaScript source of the native modules used by the
+// package, which is in the file with the same path as the Go file of the
+// native module and the .js extension, such as `src/Native/List.js`.
+func (c *compilation) natives(lowered *ir.Package) (map[string]string, error) {
+	var natives = make(map[string]string)
+	for _, m := range lowered.Modules {
+		for _, d := range m.Defs {
+			ir.Inspect(d.Expr, func(e ir.Expr) bool {
+				if g, ok := e.(*ir.Global); ok && strings.HasPrefix(g.Module, "Native.") {
+					natives[g.Module] = ""
+				}
+				return true
+			})
+		}
+	}
+
+	for name := range natives {
+		path, err := c.pkg.FindModule(name)
+		if err != nil {
+			return nil, fmt.Errorf("can't find native module %s: %s", name, err)
+		}
+
+		jsPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".js"
+		src, ok := c.sources[jsPath]
+		switch {
+		case ok:
+			if natives[name], err = js.Kernel(name, src); err != nil {
+				return nil, fmt.Errorf("%s: %s", jsPath, err)
+			}
+		case runtimeNatives[name]:
+			delete(natives, name)
+		default:
+			return nil, fmt.Errorf("there is no JavaScript source for %s, it must be at %s", name, jsPath)
+		}
+	}
+	return natives, nil
+}
@@ -0,0 +1,234 @@
+package elmo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/stretchr/testify/require"
+)
+
+// coreManifest is the manifest of a core package, whose modules have no
+// default imports.
+const coreManifest = `{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["src"]}`
+
+var files = map[string]string{
+	"elm-package.json": coreManifest,
+	"src/Main.elm": `module Main exposing (..)
+
+import Util exposing (double)
+
+
+answer : Int
+answer =
+    double 21
+`,
+	"src/Basics.elm": `module Basics exposing (..)
+
+import Native.Basics
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Basics.add
+`,
+	"src/Native/Basics.go": "package native\n",
+	"src/Native/Basics.js": "var Native$Basics = { add: _F(2, function(a, b) { return a + b; }) };\n",
+	"src/Util.elm": `module Util exposing (double)
+
+import Basics exposing (..)
+
+
+double : Int -> Int
+double n =
+    n + n
+`,
+}
+
+func TestCompileString(t *testing.T) {
+	require := require.New(t)
+
+	out, diags := CompileString(files, "src/Main.elm", target.JS)
+	require.Empty(diags)
+	require.Equal("Main", out.Main)
+	require.Contains(out.Code, "answer")
+	require.Contains(out.Code, files["src/Native/Basics.js"])
+
+	_, diags = CompileString(files, "src/Main.elm", target.Go)
+	require.Equal([]Diagnostic{
+		{File: "src/Native/Basics.go", Type: "type error", Message: "native module Native.Basics has no declarations, they must be in src/Native/Basics.decl"},
+	}, diags)
+
+	declared := withFile("src/Native/Basics.decl", "-- the natives of Basics\nadd : number -> number -> number\n")
+	_, diags = CompileString(declared, "src/Main.elm", target.Go)
+	require.Equal([]Diagnostic{
+		{File: "src/Native/Basics.decl", Type: "type error", Message: "add is declared, but there is no function BasicsAdd in src/Native/Basics.go", Line: 2, Col: 1},
+	}, diags)
+
+	declared["src/Native/Basics.go"] = "package native\n\nfunc BasicsAdd(a, b rt.Value) rt.Value { return nil }\n"
+	_, diags = CompileString(declared, "src/Main.elm", target.Go)
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "module Main has no main, so it cannot be built for the go target"},
+	}, diags)
+}
+
+func TestCompileStringKernel(t *testing.T) {
+	require := require.New(t)
+
+	kernel := withFile("src/Native/Basics.js", "var _user$project$Native_Basics = { add: _F(2, function(a, b) { return a + b; }) };\n")
+	delete(kernel, "src/Native/Basics.go")
+	out, diags := CompileString(kernel, "src/Main.elm", target.JS)
+	require.Empty(diags)
+	require.Contains(out.Code, "var Native$Basics = _user$project$Native_Basics;")
+
+	_, diags = CompileString(kernel, "src/Main.elm", target.Go)
+	require.Equal([]Diagnostic{
+		{File: "src/Native/Basics.js", Type: "type error", Message: "native module Native.Basics is a JavaScript kernel module, so it cannot be built for the go target without a Go file"},
+	}, diags)
+
+	kernel["src/Native/Basics.js"] = "var Basics = {};\n"
+	_, diags = CompileString(kernel, "src/Main.elm", target.JS)
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "src/Native/Basics.js: the JavaScript source of Native.Basics does not assign it to Native$Basics"},
+	}, diags)
+}
+
+func TestCompileStringErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, diags := CompileString(withFile("src/Util.elm", `module Util exposing (double)
+
+import Basics exposing (..)
+
+
+double : Int -> Int
+double n =
+    n + "n"
+`), "src/Main.elm", target.JS)
+	require.Len(diags, 1)
+	require.Equal("src/Util.elm", diags[0].File)
+	require.Equal("type error", diags[0].Type)
+	require.Equal(8, diags[0].Line)
+
+	_, diags = CompileString(withFile("src/Main.elm", "module Main exposing (..)\n\nimport Missing\n"), "src/Main.elm", target.JS)
+	require.Len(diags, 1)
+	require.Equal("src/Main.elm", diags[0].File)
+	require.Contains(diags[0].Message, `I could not find module "Missing"`)
+
+	_, diags = CompileString(files, "src/Other.elm", target.JS)
+	require.Equal([]Diagnostic{
+		{File: "src/Other.elm", Type: "error", Message: "there is no entry file src/Other.elm"},
+	}, diags)
+}
+
+func TestCompileStringLimits(t *testing.T) {
+	require := require.New(t)
+
+	_, diags := CompileStringWith(files, "src/Main.elm", target.JS, Limits{MaxFiles: 5})
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "there are 6 files, but the limit is 5"},
+	}, diags)
+
+	_, diags = CompileStringWith(files, "src/Main.elm", target.JS, Limits{MaxFileSize: 110})
+	require.Equal([]Diagnostic{
+		{File: "src/Basics.elm", Type: "error", Message: "the file is larger than the limit of 110 bytes"},
+	}, diags)
+
+	_, diags = CompileStringWith(files, "src/Main.elm", target.JS, Limits{MaxSize: 400})
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "the files are larger than the limit of 400 bytes"},
+	}, diags)
+
+	_, diags = CompileStringWith(files, "src/Main.elm", target.JS, Limits{MaxModules: 2})
+	require.Equal([]Diagnostic{
+		{File: "src/Basics.elm", Type: "syntax error", Message: "I could not load this module because src/Basics.elm is the module number 3, but the maximum number of modules is 2."},
+	}, diags)
+
+	nested := "module Main exposing (..)\n\n\nanswer =\n    " + strings.Repeat("(", 10) + "1" + strings.Repeat(")", 10) + "\n"
+	_, diags = CompileStringWith(withFile("src/Main.elm", nested), "src/Main.elm", target.JS, Limits{MaxDepth: 5})
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "expressions are nested more than 5 times"},
+	}, diags)
+
+	_, diags = CompileStringWith(files, "src/Main.elm", target.JS, Limits{MaxOutput: 10})
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "the generated code is larger than the limit of 10 bytes"},
+	}, diags)
+}
+
+func TestCompileStringTimeout(t *testing.T) {
+	require := require.New(t)
+
+	c := &compilation{files: files, entry: "src/Main.elm", limits: Limits{Timeout: time.Nanosecond}}
+	_, diags := run(c, target.JS)
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "the compilation took longer than the limit of 1ns"},
+	}, diags)
+
+	select {
+	case <-c.finished:
+	case <-time.After(5 * time.Second):
+		require.FailNow("the compilation kept running after the timeout")
+	}
+	require.Equal([]Diagnostic{
+		{File: "src/Main.elm", Type: "error", Message: "context deadline exceeded"},
+	}, c.diags)
+}
+
+func TestCheckString(t *testing.T) {
+	require := require.New(t)
+
+	require.Empty(CheckStringWith(files, "src/Main.elm", target.JS, Limits{MaxOutput: 10}))
+
+	diags := CheckString(withFile("src/Util.elm", `module Util exposing (double)
+
+import Basics exposing (..)
+
+
+double : Int -> Int
+double n =
+    n + "n"
+`), "src/Main.elm", target.JS)
+	require.Len(diags, 1)
+	require.Equal("src/Util.elm", diags[0].File)
+	require.Equal("type error", diags[0].Type)
+}
+
+func TestParseString(t *testing.T) {
+	require := require.New(t)
+
+	require.Empty(ParseString(files))
+
+	diags := ParseString(withFile("src/Util.elm", "module Util exposing (double)\n\n\ndouble n =\n    (n\n"))
+	require.Len(diags, 1)
+	require.Equal("src/Util.elm", diags[0].File)
+	require.Equal("syntax error", diags[0].Type)
+
+	// the files are parsed on their own, so the missing modules are no problem
+	require.Empty(ParseString(withFile("src/Main.elm", "module Main exposing (..)\n\nimport Missing\n")))
+}
+
+func TestFormatString(t *testing.T) {
+	require := require.New(t)
+
+	formatted, diags := FormatString("src/Main.elm", "module Main exposing (..)\nanswer = 42\n")
+	require.Empty(diags)
+	require.Equal("module Main exposing (..)\n\n\nanswer =\n    42\n", formatted)
+
+	_, diags = FormatString("src/Main.elm", "module Main exposing (..)\nanswer =\n")
+	require.Len(diags, 1)
+	require.Equal("src/Main.elm", diags[0].File)
+	require.Equal("syntax error", diags[0].Type)
+}
+
+// withFile returns the files with the source of the file at the given path
+// replaced.
+func withFile(path, src string) map[string]string {
+	var result = make(map[string]string)
+	for p, s := range files {
+		result[p] = s
+	}
+	result[path] = src
+	return result
+}
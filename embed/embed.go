@@ -0,0 +1,148 @@
+// Package embed ships the compiled front-end of an elmo program in a Go
+// binary. Write writes the compiled files to the directory of a Go package,
+// next to a Go file that embeds them with a go:embed directive and serves
+// them with Handler:
+//
+//	//go:embed index.html
+//	var files embed.FS
+//
+//	// Handler serves the embedded files.
+//	var Handler = elmoembed.Handler(files)
+//
+// So a Go service that imports the package serves its front-end with
+// `http.Handle("/", frontend.Handler)`, and is deployed as a single binary.
+package embed
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GoFile is the name of the Go file that embeds the files.
+const GoFile = "embed.go"
+
+// Index is the file served for the paths of the pages of the program, which
+// are not files, so the programs that handle their own URLs work when they
+// are loaded from any of them.
+const Index = "index.html"
+
+// Source returns the source of the Go file of the package with the given
+// name that embeds the files with the given names and serves them.
+func Source(pkg string, files []string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) || token.IsKeyword(pkg) {
+		return nil, fmt.Errorf("embed: %q is not a valid Go package name", pkg)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("embed: there are no files to embed")
+	}
+
+	var patterns []string
+	for _, f := range files {
+		if !fs.ValidPath(f) || strings.ContainsAny(f, " \"`") || f == GoFile {
+			return nil, fmt.Errorf("embed: %q cannot be embedded", f)
+		}
+		patterns = append(patterns, f)
+	}
+	sort.Strings(patterns)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by elmo build. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "// Package %s embeds the compiled front-end of an elmo program.\n", pkg)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	buf.WriteString("import (\n\t\"embed\"\n\t\"net/http\"\n\n\telmoembed \"github.com/elm-tangram/tangram/embed\"\n)\n\n")
+	fmt.Fprintf(&buf, "//go:embed %s\n", strings.Join(patterns, " "))
+	buf.WriteString("var files embed.FS\n\n")
+	buf.WriteString("// Handler serves the embedded files.\n")
+	buf.WriteString("var Handler http.Handler = elmoembed.Handler(files)\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("embed: the Go file is not valid: %s", err)
+	}
+	return src, nil
+}
+
+// Write writes the files, by their path relative to the given directory, and
+// the Go file that embeds them to the directory. The package of the Go file
+// is named after the directory.
+func Write(dir string, files map[string][]byte) error {
+	pkg, err := PackageName(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, filepath.ToSlash(name))
+	}
+
+	src, err := Source(pkg, names)
+	if err != nil {
+		return err
+	}
+
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, name), content); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(dir, GoFile), src)
+}
+
+// PackageName returns the name of the Go package in the given directory,
+// which is the name of the directory.
+func PackageName(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.ToLower(strings.Replace(filepath.Base(abs), "-", "", -1))
+	if !token.IsIdentifier(name) || token.IsKeyword(name) {
+		return "", fmt.Errorf("embed: the Go package in %s cannot be named after the directory, %q is not a valid Go package name", dir, name)
+	}
+	return name, nil
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// Handler returns the handler that serves the files of fsys. The paths that
+// are not files and have no extension are pages of the program, which are
+// served the index file if there is one.
+func Handler(fsys fs.FS) http.Handler {
+	files := http.FileServer(http.FS(fsys))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name != "" && path.Ext(name) == "" && !exists(fsys, name) && exists(fsys, Index) {
+			r2 := *r
+			u := *r.URL
+			u.Path = "/"
+			r2.URL = &u
+			files.ServeHTTP(w, &r2)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// exists reports whether there is a file or directory with the name in fsys.
+func exists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
@@ -0,0 +1,95 @@
+package embed
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource(t *testing.T) {
+	require := require.New(t)
+
+	src, err := Source("frontend", []string{"index.html", "assets/app.css"})
+	require.NoError(err)
+	require.Equal(`// Code generated by elmo build. DO NOT EDIT.
+
+// Package frontend embeds the compiled front-end of an elmo program.
+package frontend
+
+import (
+	"embed"
+	"net/http"
+
+	elmoembed "github.com/elm-tangram/tangram/embed"
+)
+
+//go:embed assets/app.css index.html
+var files embed.FS
+
+// Handler serves the embedded files.
+var Handler http.Handler = elmoembed.Handler(files)
+`, string(src))
+
+	_, err = Source("func", []string{"index.html"})
+	require.EqualError(err, `embed: "func" is not a valid Go package name`)
+
+	_, err = Source("frontend", []string{"../elm.js"})
+	require.EqualError(err, `embed: "../elm.js" cannot be embedded`)
+
+	_, err = Source("frontend", nil)
+	require.EqualError(err, "embed: there are no files to embed")
+}
+
+func TestWrite(t *testing.T) {
+	require := require.New(t)
+	tmp, err := ioutil.TempDir("", "elmo-embed")
+	require.NoError(err)
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "web-app")
+	require.NoError(Write(dir, map[string][]byte{"elm.js": []byte("var x;")}))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "elm.js"))
+	require.NoError(err)
+	require.Equal("var x;", string(content))
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, GoFile))
+	require.NoError(err)
+	require.Contains(string(content), "package webapp\n")
+	require.Contains(string(content), "//go:embed elm.js\n")
+
+	err = Write(filepath.Join(tmp, "1st"), map[string][]byte{"elm.js": nil})
+	require.Error(err)
+}
+
+func TestHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<html></html>")},
+		"elm.js":     {Data: []byte("var x;")},
+	}
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/", 200, "<html></html>"},
+		{"/elm.js", 200, "var x;"},
+		{"/users/1", 200, "<html></html>"},
+		{"/missing.js", 404, "404 page not found\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler(fsys).ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			require.Equal(t, c.code, w.Code)
+			require.Equal(t, c.body, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,413 @@
+// Package eval implements an interpreter of the core IR, which evaluates the
+// definitions of a lowered package directly, without generating code for a
+// backend. It is what computes the values of constant definitions at
+// compile time and runs simple programs in the compiler itself.
+//
+// The values are the ones of the runtime of the Go backend, described in
+// the rt package, so the natives written for the Go backend work with the
+// interpreter too, and its programs run with rt.Worker. The constructors
+// of union types created by the interpreter have their names, so Show can
+// print them as Elm code.
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/elm-tangram/tangram/codegen/golang/rt"
+	"github.com/elm-tangram/tangram/ir"
+)
+
+var (
+	// ErrTooDeep is the error of the evaluations that nest more calls than
+	// the maximum depth of the interpreter.
+	ErrTooDeep = errors.New("eval: the calls are nested too deeply")
+	// ErrTooLong is the error of the evaluations that take more steps than
+	// the maximum of the interpreter.
+	ErrTooLong = errors.New("eval: the evaluation takes too many steps")
+)
+
+// Natives are the values of the natives by their module and name, such as
+// `Native.Basics.add`. Functions are either a rt.Func or a Go function
+// whose parameters and result are rt.Value, such as the functions of the
+// natives of the Go backend, which are called with as many arguments as
+// parameters they have. The natives of `Native.Platform` are provided by
+// the interpreter.
+type Natives map[string]interface{}
+
+// platform are the natives of `Native.Platform`, which are provided by the
+// rt package.
+var platform = Natives{
+	"Native.Platform.none":             rt.PlatformNone,
+	"Native.Platform.batch":            rt.PlatformBatch,
+	"Native.Platform.map":              rt.PlatformMap,
+	"Native.Platform.program":          rt.PlatformProgram,
+	"Native.Platform.programWithFlags": rt.PlatformProgramWithFlags,
+}
+
+// Interpreter evaluates the definitions of a lowered package. The values
+// of the top-level definitions are computed the first time they are used
+// and kept. An Interpreter is not safe for concurrent use.
+type Interpreter struct {
+	// MaxDepth is the maximum number of nested calls of an evaluation, or
+	// no maximum if it is zero.
+	MaxDepth int
+	// MaxSteps is the maximum number of expressions an evaluation
+	// evaluates, or no maximum if it is zero. Every evaluation starts
+	// counting them again, including the calls of the functions of the
+	// package from Go, such as the ones of a worker.
+	MaxSteps int
+	// Coverage counts the evaluations of the points of the package if it is
+	// instrumented, by their IDs.
+	Coverage []int
+
+	defs    map[[2]string]*ir.Def
+	ports   map[[2]string]*ir.Port
+	natives map[string]rt.Value
+	// ctors are the constructors of the package by their module, union
+	// type and tag.
+	ctors  map[ctorKey]*ir.Ctor
+	values map[[2]string]rt.Value
+	// evaluating are the top-level definitions being evaluated.
+	evaluating map[[2]string]bool
+	rtPorts    *rt.Ports
+	// running reports whether Global or Eval are evaluating, so the calls
+	// do not start counting the steps again.
+	running bool
+	depth   int
+	steps   int
+}
+
+type ctorKey struct {
+	module, union string
+	tag           int
+}
+
+// New returns an interpreter of the package with the given natives.
+func New(pkg *ir.Package, natives Natives) (*Interpreter, error) {
+	in := &Interpreter{
+		Coverage:   make([]int, len(pkg.Points)),
+		defs:       make(map[[2]string]*ir.Def),
+		ports:      make(map[[2]string]*ir.Port),
+		natives:    make(map[string]rt.Value),
+		ctors:      make(map[ctorKey]*ir.Ctor),
+		values:     make(map[[2]string]rt.Value),
+		evaluating: make(map[[2]string]bool),
+		rtPorts:    rt.NewPorts(),
+	}
+
+	for _, ns := range []Natives{platform, natives} {
+		for name, v := range ns {
+			f, err := wrapNative(v)
+			if err != nil {
+				return nil, fmt.Errorf("eval: native %s is not valid: %s", name, err)
+			}
+			in.natives[name] = f
+		}
+	}
+
+	for _, m := range pkg.Modules {
+		for _, p := range m.Ports {
+			in.ports[[2]string{m.Name, p.Name}] = p
+		}
+
+		for _, d := range m.Defs {
+			in.defs[[2]string{m.Name, d.Name}] = d
+			ir.Inspect(d.Expr, func(e ir.Expr) bool {
+				switch e := e.(type) {
+				case *ir.Ctor:
+					in.addCtor(e)
+				case *ir.Case:
+					for _, alt := range e.Alts {
+						if alt.Ctor != nil {
+							in.addCtor(alt.Ctor)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return in, nil
+}
+
+func (in *Interpreter) addCtor(c *ir.Ctor) {
+	in.ctors[ctorKey{c.Module, c.Union, c.Tag}] = c
+}
+
+var valueType = reflect.TypeOf((*rt.Value)(nil)).Elem()
+
+// wrapNative returns the value of a native, wrapping the Go functions so they
+// are curried.
+func wrapNative(v interface{}) (rt.Value, error) {
+	fn := reflect.ValueOf(v)
+	if _, ok := v.(*rt.Func); ok || fn.Kind() != reflect.Func {
+		return v, nil
+	}
+
+	t := fn.Type()
+	if t.NumIn() == 0 || t.IsVariadic() || t.NumOut() != 1 || t.Out(0) != valueType {
+		return nil, fmt.Errorf("functions must have rt.Value parameters and result, but it is %s", t)
+	}
+
+	for i := 0; i < t.NumIn(); i++ {
+		if t.In(i) != valueType {
+			return nil, fmt.Errorf("functions must have rt.Value parameters and result, but it is %s", t)
+		}
+	}
+
+	return rt.F(t.NumIn(), func(args []rt.Value) rt.Value {
+		var in = make([]reflect.Value, len(args))
+		for i := range args {
+			in[i] = reflect.ValueOf(&args[i]).Elem()
+		}
+		return fn.Call(in)[0].Interface()
+	}), nil
+}
+
+// Global returns the value of the top-level definition with the given
+// module and name.
+func (in *Interpreter) Global(module, name string) (v rt.Value, err error) {
+	err = in.run(func() {
+		v = in.global(module, name)
+	})
+	return v, err
+}
+
+// Eval returns the value of an expression that has no free variables,
+// such as the expression of a top-level definition.
+func (in *Interpreter) Eval(e ir.Expr) (v rt.Value, err error) {
+	err = in.run(func() {
+		v = in.eval(e, nil)
+	})
+	return v, err
+}
+
+// run runs the evaluation and returns the error it panics with, if any.
+// The crashes of the program are *rt.CrashError.
+func (in *Interpreter) run(eval func()) (err error) {
+	defer func() {
+		in.running = false
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("eval: %v", r)
+			}
+		}
+	}()
+
+	in.running = true
+	in.steps = 0
+	eval()
+	return nil
+}
+
+// env is the binding of a local to its value in a scope, and next are the
+// bindings of the enclosing scopes.
+type env struct {
+	local *ir.Local
+	value rt.Value
+	next  *env
+}
+
+func (e *env) lookup(l *ir.Local) rt.Value {
+	for ; e != nil; e = e.next {
+		if e.local == l {
+			return e.value
+		}
+	}
+	panic(fmt.Errorf("eval: variable %s_%d is not bound", l.Name, l.ID))
+}
+
+// tailCall is the result of the evaluation of a tail call, which are the
+// arguments of the next iteration of the function it is in.
+type tailCall []rt.Value
+
+func (in *Interpreter) eval(e ir.Expr, scope *env) rt.Value {
+	in.steps++
+	if in.MaxSteps > 0 && in.steps > in.MaxSteps {
+		panic(ErrTooLong)
+	}
+
+	switch e := e.(type) {
+	case *ir.Local:
+		return scope.lookup(e)
+	case *ir.Global:
+		return in.global(e.Module, e.Name)
+	case *ir.Lit:
+		return literal(e)
+	case *ir.Ctor:
+		return ctor(e)
+	case *ir.Lambda:
+		return rt.F(len(e.Params), func(args []rt.Value) rt.Value {
+			return in.call(e, scope, args)
+		})
+	case *ir.App:
+		if c, ok := e.Func.(*ir.Ctor); ok && c.Arity == len(e.Args) {
+			return &rt.Ctor{Tag: c.Tag, Args: in.exprs(e.Args, scope), Name: c.Name}
+		}
+		return rt.A(in.eval(e.Func, scope), in.exprs(e.Args, scope)...)
+	case *ir.Call:
+		return rt.A(in.eval(e.Func, scope), in.exprs(e.Args, scope)...)
+	case *ir.Let:
+		binds := ir.SortBinds(e.Binds)
+		var frames = make([]*env, len(binds))
+		for i, b := range binds {
+			scope = &env{local: b.Var, next: scope}
+			frames[i] = scope
+		}
+
+		for i, b := range binds {
+			frames[i].value = in.eval(b.Expr, scope)
+		}
+		return in.eval(e.Body, scope)
+	case *ir.Case:
+		return in.caseExpr(e, scope)
+	case *ir.Tuple:
+		return rt.Tuple(in.exprs(e.Elems, scope))
+	case *ir.Index:
+		return in.eval(e.Expr, scope).(rt.Tuple)[e.Index]
+	case *ir.Record:
+		return in.fields(e.Fields, scope)
+	case *ir.Access:
+		return in.eval(e.Expr, scope).(rt.Record)[e.Field]
+	case *ir.Update:
+		return rt.Update(in.eval(e.Record, scope), in.fields(e.Fields, scope))
+	case *ir.List:
+		return rt.List(in.exprs(e.Elems, scope)...)
+	case *ir.TailCall:
+		return tailCall(in.exprs(e.Args, scope))
+	case *ir.Probe:
+		if e.ID < len(in.Coverage) {
+			in.Coverage[e.ID]++
+		}
+		return in.eval(e.Expr, scope)
+	case *ir.Fail:
+		return rt.Crash(e.Module, e.Msg)
+	}
+	panic(fmt.Errorf("eval: unknown expression %T", e))
+}
+
+func (in *Interpreter) exprs(exprs []ir.Expr, scope *env) []rt.Value {
+	var values = make([]rt.Value, len(exprs))
+	for i, e := range exprs {
+		values[i] = in.eval(e, scope)
+	}
+	return values
+}
+
+func (in *Interpreter) fields(fields []*ir.Field, scope *env) rt.Record {
+	var record = make(rt.Record, len(fields))
+	for _, f := range fields {
+		record[f.Name] = in.eval(f.Expr, scope)
+	}
+	return record
+}
+
+// call calls the function with the given arguments. The function is called
+// again with the arguments of its tail calls until it returns a value.
+func (in *Interpreter) call(fn *ir.Lambda, closure *env, args []rt.Value) rt.Value {
+	if !in.running && in.depth == 0 {
+		in.steps = 0
+	}
+
+	in.depth++
+	defer func() { in.depth-- }()
+	if in.MaxDepth > 0 && in.depth > in.MaxDepth {
+		panic(ErrTooDeep)
+	}
+
+	for {
+		scope := closure
+		for i, p := range fn.Params {
+			scope = &env{p, args[i], scope}
+		}
+
+		result := in.eval(fn.Body, scope)
+		next, ok := result.(tailCall)
+		if !ok {
+			return result
+		}
+		args = next
+	}
+}
+
+func (in *Interpreter) caseExpr(e *ir.Case, scope *env) rt.Value {
+	subject := scope.lookup(e.Subject)
+	for _, alt := range e.Alts {
+		if alt.Lit != nil {
+			if subject == literal(alt.Lit) {
+				return in.eval(alt.Body, scope)
+			}
+			continue
+		}
+
+		c := subject.(*rt.Ctor)
+		if c.Tag != alt.Ctor.Tag {
+			continue
+		}
+
+		for i, v := range alt.Vars {
+			scope = &env{v, c.Args[i], scope}
+		}
+		return in.eval(alt.Body, scope)
+	}
+
+	if e.Default == nil {
+		panic(fmt.Errorf("eval: no alternative matches %s", Show(subject)))
+	}
+	return in.eval(e.Default, scope)
+}
+
+// global returns the value of a top-level definition, a port or a native,
+// evaluating the definition if it was not evaluated yet.
+func (in *Interpreter) global(module, name string) rt.Value {
+	if strings.HasPrefix(module, "Native.") {
+		v, ok := in.natives[module+"."+name]
+		if !ok {
+			panic(fmt.Errorf("eval: there is no native %s.%s", module, name))
+		}
+		return v
+	}
+
+	key := [2]string{module, name}
+	if v, ok := in.values[key]; ok {
+		return v
+	}
+
+	if p, ok := in.ports[key]; ok {
+		v := in.port(p)
+		in.values[key] = v
+		return v
+	}
+
+	d, ok := in.defs[key]
+	if !ok {
+		panic(fmt.Errorf("eval: there is no definition %s.%s", module, name))
+	}
+
+	if in.evaluating[key] {
+		panic(fmt.Errorf("eval: the value of %s.%s depends on itself", module, name))
+	}
+
+	in.evaluating[key] = true
+	defer delete(in.evaluating, key)
+	v := in.eval(d.Expr, nil)
+	in.values[key] = v
+	return v
+}
+
+// ctor returns the value of a constructor that is not applied to all its
+// arguments, which is a function that creates the value.
+func ctor(c *ir.Ctor) rt.Value {
+	if c.Arity == 0 {
+		return &rt.Ctor{Tag: c.Tag, Name: c.Name}
+	}
+
+	return rt.F(c.Arity, func(args []rt.Value) rt.Value {
+		return &rt.Ctor{Tag: c.Tag, Args: args, Name: c.Name}
+	})
+}
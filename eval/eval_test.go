@@ -0,0 +1,202 @@
+package eval
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/codegentest"
+	native "github.com/elm-tangram/tangram/codegen/golang/_testdata/worker/src/Native"
+	"github.com/elm-tangram/tangram/codegen/golang/rt"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/types"
+	"github.com/stretchr/testify/require"
+)
+
+const fixture = "../codegen/golang/_testdata/worker"
+
+var natives = Natives{
+	"Native.Basics.add": native.BasicsAdd,
+	"Native.Basics.sub": native.BasicsSub,
+	"Native.Basics.eq":  native.BasicsEq,
+	"Native.List.cons":  native.ListCons,
+}
+
+func newInterpreter(t *testing.T) (*Interpreter, *ir.Package) {
+	pkg := codegentest.Lower(t, fixture)
+	in, err := New(pkg, natives)
+	require.NoError(t, err)
+	return in, pkg
+}
+
+func TestWorker(t *testing.T) {
+	require := require.New(t)
+	in, _ := newInterpreter(t)
+
+	w, err := in.Worker("Main")
+	require.NoError(err)
+
+	input, err := ioutil.ReadFile(fixture + "/input.jsonl")
+	require.NoError(err)
+
+	var out bytes.Buffer
+	require.NoError(rt.Run(w, nil, bytes.NewReader(input), &out))
+
+	expected, err := ioutil.ReadFile(fixture + "/go.output.golden")
+	require.NoError(err)
+	require.Equal(string(expected), out.String())
+
+	_, err = in.Worker("Basics")
+	require.EqualError(err, "eval: module Basics has no main")
+}
+
+func TestGlobal(t *testing.T) {
+	require := require.New(t)
+	in, _ := newInterpreter(t)
+
+	v, err := in.Global("Main", "initial")
+	require.NoError(err)
+	require.Equal("{ history = [], total = 0 }", Show(v))
+
+	v, err = in.Eval(sumTo(100))
+	require.NoError(err)
+	require.Equal(5050, v)
+
+	_, err = in.Global("Main", "nothing")
+	require.EqualError(err, "eval: there is no definition Main.nothing")
+
+	_, err = New(codegentest.Lower(t, fixture), Natives{"Native.Basics.add": func(a int) int { return a }})
+	require.EqualError(err, "eval: native Native.Basics.add is not valid: functions must have rt.Value parameters and result, but it is func(int) int")
+}
+
+func TestLimits(t *testing.T) {
+	require := require.New(t)
+	in, _ := newInterpreter(t)
+
+	in.MaxSteps = 100
+	_, err := in.Eval(sumTo(100))
+	require.Equal(ErrTooLong, err)
+
+	v, err := in.Eval(sumTo(5))
+	require.NoError(err)
+	require.Equal(15, v)
+
+	in.MaxSteps = 0
+	in.MaxDepth = 1
+	sum := &ir.Ctor{Module: "Main", Union: "Msg", Name: "Sum", Tag: 2, Arity: 1, Span: 3}
+	update := &ir.App{
+		Func: &ir.Global{Module: "Main", Name: "update"},
+		Args: []ir.Expr{
+			&ir.App{Func: sum, Args: []ir.Expr{&ir.Lit{Kind: ast.Int, Value: "3"}}},
+			&ir.Global{Module: "Main", Name: "initial"},
+		},
+	}
+	_, err = in.Eval(update)
+	require.Equal(ErrTooDeep, err)
+
+	in.MaxDepth = 2
+	v, err = in.Eval(update)
+	require.NoError(err)
+	require.Equal("{ history = [], total = 6 }", Show(v.(rt.Tuple)[0]))
+}
+
+func TestShow(t *testing.T) {
+	require := require.New(t)
+
+	just := &rt.Ctor{Tag: 0, Args: []rt.Value{-1}, Name: "Just"}
+	cases := []struct {
+		value    rt.Value
+		expected string
+	}{
+		{1, "1"},
+		{1.5, "1.5"},
+		{2.0, "2"},
+		{1e21, "1e+21"},
+		{1e-7, "1e-7"},
+		{math.Inf(-1), "-Infinity"},
+		{true, "True"},
+		{"a\"b", `"a\"b"`},
+		{'a', "'a'"},
+		{rt.Tuple{1, "a"}, `(1,"a")`},
+		{rt.List(1, 2), "[1,2]"},
+		{rt.Record{"b": 1, "a": rt.Nil}, "{ a = [], b = 1 }"},
+		{rt.Record{}, "{}"},
+		{just, "Just (-1)"},
+		{&rt.Ctor{Tag: 0, Args: []rt.Value{just, rt.List(1)}, Name: "Pair"}, "Pair (Just (-1)) [1]"},
+		{rt.C(1), "<1>"},
+		{rt.F(1, nil), "<function>"},
+		{rt.PlatformNone, "<internals>"},
+	}
+
+	for _, c := range cases {
+		require.Equal(c.expected, Show(c.value))
+	}
+}
+
+func TestPrecompute(t *testing.T) {
+	require := require.New(t)
+	in, pkg := newInterpreter(t)
+
+	n := &ir.Local{Name: "n", ID: 100}
+	initial := pkg.Lookup("Main").Lookup("initial")
+	initial.Expr = &ir.Let{
+		Binds: []*ir.Bind{{Var: n, Expr: sumTo(3)}},
+		Body: &ir.Record{Fields: []*ir.Field{
+			{Name: "total", Expr: n},
+			{Name: "history", Expr: &ir.List{Elems: []ir.Expr{n}}},
+		}},
+	}
+
+	require.Equal(1, in.Precompute(pkg))
+
+	record, ok := initial.Expr.(*ir.Record)
+	require.True(ok)
+	require.Equal("history", record.Fields[0].Name)
+	require.Equal(&ir.List{
+		Elems: []ir.Expr{&ir.Lit{Kind: ast.Int, Value: "6", Typ: record.Fields[0].Expr.(*ir.List).Elems[0].Type()}},
+		Typ:   record.Fields[0].Expr.Type(),
+	}, record.Fields[0].Expr)
+	require.Equal(&ir.Lit{Kind: ast.Int, Value: "6", Typ: record.Fields[1].Expr.Type()}, record.Fields[1].Expr)
+
+	require.Equal(0, in.Precompute(pkg))
+}
+
+func TestReify(t *testing.T) {
+	require := require.New(t)
+	in, pkg := newInterpreter(t)
+
+	msg := types.Prune(pkg.Lookup("Main").Lookup("update").Type).(*types.Func).Arg
+	e, ok := in.Reify(&rt.Ctor{Tag: 2, Args: []rt.Value{3}, Name: "Sum"}, msg)
+	require.True(ok)
+
+	app, ok := e.(*ir.App)
+	require.True(ok)
+	require.Equal("Sum", app.Func.(*ir.Ctor).Name)
+	require.Equal(&ir.Lit{Kind: ast.Int, Value: "3", Typ: app.Args[0].Type()}, app.Args[0])
+
+	e, ok = in.Reify(rt.C(1), msg)
+	require.True(ok)
+	require.Equal("Reset", e.(*ir.Ctor).Name)
+
+	_, ok = in.Reify(rt.C(3), msg)
+	require.False(ok)
+
+	_, ok = in.Reify(rt.F(1, nil), msg)
+	require.False(ok)
+
+	_, ok = in.Reify(math.NaN(), nil)
+	require.False(ok)
+}
+
+func sumTo(n int) ir.Expr {
+	return &ir.App{
+		Func: &ir.Global{Module: "Main", Name: "sumTo"},
+		Args: []ir.Expr{
+			&ir.Lit{Kind: ast.Int, Value: strconv.Itoa(n)},
+			&ir.Lit{Kind: ast.Int, Value: "0"},
+		},
+	}
+}
@@ -0,0 +1,278 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/golang/rt"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// literal returns the value of a literal. Number literals have the
+// representation of the type of the value, Int or Float.
+func literal(lit *ir.Lit) rt.Value {
+	var v rt.Value
+	var err error
+	switch lit.Kind {
+	case ast.Bool:
+		v = lit.Value == "True"
+	case ast.String:
+		if strings.HasPrefix(lit.Value, `"""`) {
+			v = strings.TrimSuffix(strings.TrimPrefix(lit.Value, `"""`), `"""`)
+		} else {
+			v, err = strconv.Unquote(lit.Value)
+		}
+	case ast.Char:
+		var r rune
+		r, _, _, err = strconv.UnquoteChar(strings.Trim(lit.Value, "'"), '\'')
+		v = r
+	case ast.Int, ast.Float:
+		if isFloat(lit.Typ) {
+			v, err = strconv.ParseFloat(lit.Value, 64)
+		} else {
+			var n int64
+			n, err = strconv.ParseInt(lit.Value, 0, 0)
+			v = int(n)
+		}
+	default:
+		err = fmt.Errorf("unknown kind of literal")
+	}
+
+	if err != nil {
+		panic(fmt.Errorf("eval: literal %s is not valid: %s", lit.Value, err))
+	}
+	return v
+}
+
+func isFloat(t types.Type) bool {
+	n, ok := types.Prune(t).(*types.Named)
+	return ok && n.Module == "" && n.Name == "Float"
+}
+
+// Show returns the representation of a value as Elm code, the same as the
+// toString of Elm, except for the constructors that were not created by the
+// interpreter, which are shown with their tag.
+func Show(v rt.Value) string {
+	switch v := v.(type) {
+	case *rt.Func:
+		return "<function>"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return showFloat(v)
+	case string:
+		return strconv.Quote(v)
+	case rune:
+		return strconv.QuoteRune(v)
+	case rt.Tuple:
+		var elems = make([]string, len(v))
+		for i, e := range v {
+			elems[i] = Show(e)
+		}
+		return "(" + strings.Join(elems, ",") + ")"
+	case rt.Record:
+		if len(v) == 0 {
+			return "{}"
+		}
+
+		var names []string
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var fields = make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name + " = " + Show(v[name])
+		}
+		return "{ " + strings.Join(fields, ", ") + " }"
+	case *rt.Ctor:
+		if v.Name == "[]" || v.Name == "::" {
+			var elems []string
+			for _, e := range rt.ToSlice(v) {
+				elems = append(elems, Show(e))
+			}
+			return "[" + strings.Join(elems, ",") + "]"
+		}
+
+		name := v.Name
+		if name == "" {
+			name = fmt.Sprintf("<%d>", v.Tag)
+		}
+
+		var parts = []string{name}
+		for _, a := range v.Args {
+			s := Show(a)
+			if c, ok := a.(*rt.Ctor); (ok && len(c.Args) > 0 && c.Name != "::") || strings.HasPrefix(s, "-") {
+				s = "(" + s + ")"
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, " ")
+	}
+	return "<internals>"
+}
+
+// showFloat formats a float the way JavaScript does, so floats are shown
+// the same regardless of the backend.
+func showFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	if abs := math.Abs(f); abs == 0 || (abs >= 1e-6 && abs < 1e21) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	s = strings.Replace(s, "e-0", "e-", 1)
+	return strings.Replace(s, "e+0", "e+", 1)
+}
+
+// Reify returns the expression of a value of the given type, which is a
+// constant, or false if the value cannot be an expression, such as
+// functions, commands or constructors with arguments of parametric union
+// types.
+func (in *Interpreter) Reify(v rt.Value, t types.Type) (ir.Expr, bool) {
+	switch v := v.(type) {
+	case bool:
+		value := "False"
+		if v {
+			value = "True"
+		}
+		return &ir.Lit{Kind: ast.Bool, Value: value, Typ: t}, true
+	case int:
+		return &ir.Lit{Kind: ast.Int, Value: strconv.Itoa(v), Typ: t}, true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		return &ir.Lit{Kind: ast.Float, Value: strconv.FormatFloat(v, 'g', -1, 64), Typ: t}, true
+	case string:
+		return &ir.Lit{Kind: ast.String, Value: strconv.Quote(v), Typ: t}, true
+	case rune:
+		return &ir.Lit{Kind: ast.Char, Value: strconv.QuoteRune(v), Typ: t}, true
+	case rt.Tuple:
+		tt, ok := types.Prune(t).(*types.Tuple)
+		if !ok || len(tt.Elems) != len(v) {
+			return nil, false
+		}
+
+		var elems = make([]ir.Expr, len(v))
+		for i, e := range v {
+			if elems[i], ok = in.Reify(e, tt.Elems[i]); !ok {
+				return nil, false
+			}
+		}
+		return &ir.Tuple{Elems: elems, Typ: t}, true
+	case rt.Record:
+		record, ok := types.Prune(t).(*types.Record)
+		if !ok {
+			return nil, false
+		}
+
+		var fields []*ir.Field
+		for _, name := range record.FieldNames() {
+			e, ok := in.Reify(v[name], record.Fields[name])
+			if !ok {
+				return nil, false
+			}
+			fields = append(fields, &ir.Field{Name: name, Expr: e})
+		}
+		return &ir.Record{Fields: fields, Typ: t}, true
+	case *rt.Ctor:
+		return in.reifyCtor(v, t)
+	}
+	return nil, false
+}
+
+func (in *Interpreter) reifyCtor(v *rt.Ctor, t types.Type) (ir.Expr, bool) {
+	union, ok := types.Prune(t).(*types.Named)
+	if !ok {
+		return nil, false
+	}
+
+	if union.Module == "" && union.Name == "List" {
+		var elems []ir.Expr
+		for _, e := range rt.ToSlice(v) {
+			elem, ok := in.Reify(e, union.Args[0])
+			if !ok {
+				return nil, false
+			}
+			elems = append(elems, elem)
+		}
+		return &ir.List{Elems: elems, Typ: t}, true
+	}
+
+	c, ok := in.ctors[ctorKey{union.Module, union.Name, v.Tag}]
+	if !ok || c.Arity != len(v.Args) || (c.Arity > 0 && len(union.Args) > 0) {
+		return nil, false
+	}
+
+	ctor := *c
+	if c.Arity == 0 {
+		ctor.Typ = t
+		return &ctor, true
+	}
+
+	var args = make([]ir.Expr, len(v.Args))
+	var fn = c.Typ
+	for i, a := range v.Args {
+		f, ok := types.Prune(fn).(*types.Func)
+		if !ok {
+			return nil, false
+		}
+
+		if args[i], ok = in.Reify(a, f.Arg); !ok {
+			return nil, false
+		}
+		fn = f.Result
+	}
+	return &ir.App{Func: &ctor, Args: args, Typ: t}, true
+}
+
+// Precompute replaces the expressions of the top-level definitions of the
+// package that are not functions or constants with their values, if they
+// can be computed and reified, and returns the number of definitions
+// replaced. The natives must not have side effects, and the limits of the
+// interpreter should be set, because the definitions may not terminate.
+func (in *Interpreter) Precompute(pkg *ir.Package) int {
+	var replaced int
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			if _, ok := types.Prune(d.Type).(*types.Func); ok {
+				continue
+			}
+
+			if ir.IsConstant(d.Expr) {
+				continue
+			}
+
+			v, err := in.Global(m.Name, d.Name)
+			if err != nil {
+				continue
+			}
+
+			if e, ok := in.Reify(v, d.Type); ok {
+				d.Expr = e
+				replaced++
+			}
+		}
+	}
+	return replaced
+}
@@ -0,0 +1,121 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/elm-tangram/tangram/codegen/golang/rt"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Worker returns a new worker of the main program of the given module,
+// which must be a Platform program. The worker is the same as the one of
+// the Go backend, so it communicates with Go through the ports.
+func (in *Interpreter) Worker(module string) (*rt.Worker, error) {
+	d, ok := in.defs[[2]string{module, "main"}]
+	if !ok {
+		return nil, fmt.Errorf("eval: module %s has no main", module)
+	}
+
+	program, ok := types.Prune(d.Type).(*types.Named)
+	if !ok || program.Module != "Platform" || program.Name != "Program" || len(program.Args) != 3 {
+		return nil, fmt.Errorf(
+			"eval: main has type %s, but only Platform programs are supported",
+			types.TypeString(d.Type),
+		)
+	}
+
+	var flags rt.Converter
+	if n, ok := types.Prune(program.Args[0]).(*types.Named); !ok || n.Module != "Basics" || n.Name != "Never" {
+		flags = fromGo(program.Args[0])
+	}
+
+	var main rt.Value
+	err := in.run(func() {
+		for key := range in.ports {
+			in.global(key[0], key[1])
+		}
+		main = in.global(module, "main")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt.NewWorker(main, flags, in.rtPorts), nil
+}
+
+// port returns the function of a port, which creates its commands if it
+// is outgoing or its subscriptions if it is incoming.
+func (in *Interpreter) port(p *ir.Port) rt.Value {
+	fn, ok := types.Prune(p.Type).(*types.Func)
+	if !ok {
+		panic(fmt.Errorf("eval: port %s has type %s, which is not a function", p.Name, types.TypeString(p.Type)))
+	}
+
+	result, ok := types.Prune(fn.Result).(*types.Named)
+	if tagger, isFunc := types.Prune(fn.Arg).(*types.Func); ok && isFunc && result.Name == "Sub" {
+		return in.rtPorts.Incoming(p.Name, fromGo(tagger.Arg))
+	}
+	return in.rtPorts.Outgoing(p.Name, toGo(fn.Arg))
+}
+
+// toGo returns the converter from Elm values of the given type to the Go
+// values that are sent through outgoing ports.
+func toGo(t types.Type) rt.Converter {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "List":
+			return rt.ListToGo(toGo(t.Args[0]))
+		case t.Module == "Maybe" && t.Name == "Maybe":
+			return rt.MaybeToGo(toGo(t.Args[0]))
+		}
+	case *types.Tuple:
+		var elems = make([]rt.Converter, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = toGo(e)
+		}
+		return rt.TupleToGo(elems...)
+	case *types.Record:
+		return rt.RecordToGo(fieldConverters(t, toGo))
+	}
+	return rt.Identity
+}
+
+// fromGo returns the converter from the Go values received through
+// incoming ports, or given as flags, to Elm values of the given type.
+func fromGo(t types.Type) rt.Converter {
+	switch t := types.Prune(t).(type) {
+	case *types.Named:
+		switch {
+		case t.Module == "" && t.Name == "Int":
+			return rt.DecodeInt
+		case t.Module == "" && t.Name == "Float":
+			return rt.DecodeFloat
+		case t.Module == "" && t.Name == "Bool":
+			return rt.DecodeBool
+		case t.Module == "" && t.Name == "String":
+			return rt.DecodeString
+		case t.Module == "" && t.Name == "List":
+			return rt.ListFromGo(fromGo(t.Args[0]))
+		case t.Module == "Maybe" && t.Name == "Maybe":
+			return rt.MaybeFromGo(fromGo(t.Args[0]))
+		}
+	case *types.Tuple:
+		var elems = make([]rt.Converter, len(t.Elems))
+		for i, e := range t.Elems {
+			elems[i] = fromGo(e)
+		}
+		return rt.TupleFromGo(elems...)
+	case *types.Record:
+		return rt.RecordFromGo(fieldConverters(t, fromGo))
+	}
+	return rt.Identity
+}
+
+func fieldConverters(t *types.Record, converter func(types.Type) rt.Converter) map[string]rt.Converter {
+	var fields = make(map[string]rt.Converter, len(t.Fields))
+	for name, f := range t.Fields {
+		fields[name] = converter(f)
+	}
+	return fields
+}
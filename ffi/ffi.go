@@ -0,0 +1,223 @@
+// Package ffi checks the native modules of the Go backend against their
+// declarations. A native module `Native.X` is a Go file in the `Native`
+// directory of a source directory, whose values are declared next to it in
+// a file with the types of the values in the syntax of the type annotations
+// of Elm, such as `src/Native/List.decl` for `src/Native/List.go`:
+//
+//	cons : a -> List a -> List a
+//
+//	empty : List a
+//
+// The type checker gives the values of the native modules the types they are
+// declared with, and Check verifies that the Go file implements them, so a
+// mismatch is found when the program is compiled instead of when the Go
+// code is built or run. A value declared with a function type of n arguments
+// is a function with n parameters of type rt.Value that returns a rt.Value,
+// and any other value is a variable of type rt.Value, named like GoName.
+package ffi
+
+import (
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// provided are the native modules provided by the runtime of the Go backend,
+// which are not checked.
+var provided = map[string]bool{
+	"Native.Platform": true,
+}
+
+// Error is a mismatch between a native module and its declarations.
+type Error struct {
+	// Path is the path to the file of the declarations, or to the Go file if
+	// the module has no declarations.
+	Path string
+	// Pos is the position of the declaration of the value in the file, if
+	// any.
+	Pos token.Pos
+	// Message describes the mismatch.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// GoName returns the name of the Go function or variable of a value of the
+// given native module, such as `ListCons` for `Native.List.cons`.
+func GoName(module, name string) string {
+	module = strings.Replace(strings.TrimPrefix(module, "Native."), ".", "", -1)
+	var runes = []rune(name)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return module + string(runes)
+}
+
+// Check checks the native modules of the package, which are read with the
+// given loader, and returns the mismatches found, in the order of the names
+// of the modules. Every native module must have declarations. The error is
+// the one of reading or parsing a Go file.
+func Check(pkg *ast.Package, loader source.Loader) ([]*Error, error) {
+	var names []string
+	for name := range pkg.Natives {
+		if !provided[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs []*Error
+	for _, name := range names {
+		moduleErrs, err := CheckModule(pkg.Natives[name], loader)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, moduleErrs...)
+	}
+	return errs, nil
+}
+
+// CheckModule checks that the Go file of the native module, which is read
+// with the given loader, implements the values it declares.
+func CheckModule(native *ast.NativeModule, loader source.Loader) ([]*Error, error) {
+	if pkg.IsKernel(native.Path) {
+		return []*Error{{
+			Path:    native.Path,
+			Message: fmt.Sprintf("native module %s is a JavaScript kernel module, so it cannot be built for the go target without a Go file", native.Name),
+		}}, nil
+	}
+
+	if native.DeclPath == "" {
+		return []*Error{{
+			Path:    native.Path,
+			Message: fmt.Sprintf("native module %s has no declarations, they must be in %s", native.Name, pkg.NativeDecls(native.Path)),
+		}}, nil
+	}
+
+	symbols, err := goSymbols(native.Path, loader)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []*Error
+	for _, decl := range native.Decls {
+		if msg := checkDecl(native, decl, symbols); msg != "" {
+			errs = append(errs, &Error{native.DeclPath, decl.Name.Pos(), msg})
+		}
+	}
+	return errs, nil
+}
+
+// checkDecl returns what is wrong with the symbol of the declaration, if
+// anything.
+func checkDecl(native *ast.NativeModule, decl *ast.TypeAnnotation, symbols map[string]goast.Node) string {
+	name := GoName(native.Name, decl.Name.Name)
+	arity := arity(decl.Type)
+
+	switch sym := symbols[name].(type) {
+	case nil:
+		if arity > 0 {
+			return fmt.Sprintf("%s is declared, but there is no function %s in %s", decl.Name.Name, name, native.Path)
+		}
+		return fmt.Sprintf("%s is declared, but there is no variable %s in %s", decl.Name.Name, name, native.Path)
+	case *goast.FuncDecl:
+		if arity == 0 {
+			return fmt.Sprintf("%s is not a function, so %s must be a variable of type rt.Value", decl.Name.Name, name)
+		}
+
+		if params := fieldCount(sym.Type.Params); params != arity {
+			return fmt.Sprintf("%s is declared with %d arguments, but %s has %d parameters", decl.Name.Name, arity, name, params)
+		}
+
+		if results := fieldCount(sym.Type.Results); results != 1 {
+			return fmt.Sprintf("%s must return a single rt.Value, but it returns %d values", name, results)
+		}
+	default:
+		if arity > 0 {
+			return fmt.Sprintf("%s is declared with %d arguments, but %s is not a function", decl.Name.Name, arity, name)
+		}
+	}
+	return ""
+}
+
+// arity returns the number of arguments of a type, which is zero if it is not
+// a function.
+func arity(typ ast.Type) int {
+	if fn, ok := typ.(*ast.FuncType); ok {
+		return len(fn.Args) + arity(fn.Return)
+	}
+	return 0
+}
+
+// fieldCount returns the number of parameters or results in the list.
+func fieldCount(fields *goast.FieldList) int {
+	if fields == nil {
+		return 0
+	}
+
+	var n int
+	for _, f := range fields.List {
+		if len(f.Names) == 0 {
+			n++
+		} else {
+			n += len(f.Names)
+		}
+	}
+	return n
+}
+
+// goSymbols returns the top-level functions and variables of the Go file at
+// the given path by their name.
+func goSymbols(path string, loader source.Loader) (map[string]goast.Node, error) {
+	src, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(src)
+	if c, ok := src.(io.Closer); ok {
+		c.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), path, content, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var symbols = make(map[string]goast.Node)
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *goast.FuncDecl:
+			if d.Recv == nil {
+				symbols[d.Name.Name] = d
+			}
+		case *goast.GenDecl:
+			if d.Tok != gotoken.VAR {
+				continue
+			}
+
+			for _, spec := range d.Specs {
+				for _, name := range spec.(*goast.ValueSpec).Names {
+					symbols[name.Name] = name
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
@@ -0,0 +1,106 @@
+package ffi
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/stretchr/testify/require"
+)
+
+const listNatives = `package native
+
+import "github.com/elm-tangram/tangram/codegen/golang/rt"
+
+var ListEmpty rt.Value = rt.Nil
+
+func ListCons(head, tail rt.Value) rt.Value {
+	return rt.Cons(head, tail)
+}
+
+func ListLength(list rt.Value) (rt.Value, error) {
+	return nil, nil
+}
+
+func ListMap(f rt.Value) rt.Value {
+	return nil
+}
+
+var ListSingleton = 1
+`
+
+func TestGoName(t *testing.T) {
+	require := require.New(t)
+	require.Equal("ListCons", GoName("Native.List", "cons"))
+	require.Equal("JsonDecodeField", GoName("Native.Json.Decode", "field"))
+}
+
+func TestCheck(t *testing.T) {
+	require := require.New(t)
+
+	loader := source.NewMemLoader()
+	loader.Add("Native/List.go", listNatives)
+
+	var pos int
+	decl := func(name string, arity int) *ast.TypeAnnotation {
+		pos += 10
+		var typ ast.Type = &ast.VarType{Ident: ast.NewIdent("a", 0)}
+		if arity > 0 {
+			args := make([]ast.Type, arity)
+			for i := range args {
+				args[i] = typ
+			}
+			typ = &ast.FuncType{Args: args, Return: typ}
+		}
+		return &ast.TypeAnnotation{Name: ast.NewIdent(name, token.Pos(pos)), Type: typ}
+	}
+
+	pkg := &ast.Package{Natives: map[string]*ast.NativeModule{
+		"Native.Platform": {Name: "Native.Platform", Path: "Native/Platform.go"},
+		"Native.String":   {Name: "Native.String", Path: "Native/String.go"},
+		"Native.List": {
+			Name:     "Native.List",
+			Path:     "Native/List.go",
+			DeclPath: "Native/List.decl",
+			Decls: []*ast.TypeAnnotation{
+				decl("empty", 0),
+				decl("cons", 2),
+				decl("length", 1),
+				decl("map", 2),
+				decl("singleton", 1),
+				decl("reverse", 1),
+				decl("sum", 0),
+				decl("empty", 1),
+			},
+		},
+	}}
+
+	errs, err := Check(pkg, loader)
+	require.NoError(err)
+
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	require.Equal([]string{
+		"Native/List.decl: ListLength must return a single rt.Value, but it returns 2 values",
+		"Native/List.decl: map is declared with 2 arguments, but ListMap has 1 parameters",
+		"Native/List.decl: singleton is declared with 1 arguments, but ListSingleton is not a function",
+		"Native/List.decl: reverse is declared, but there is no function ListReverse in Native/List.go",
+		"Native/List.decl: sum is declared, but there is no variable ListSum in Native/List.go",
+		"Native/List.decl: empty is declared with 1 arguments, but ListEmpty is not a function",
+		"Native/String.go: native module Native.String has no declarations, they must be in Native/String.decl",
+	}, msgs)
+	require.Equal(token.Pos(30), errs[0].Pos)
+
+	pkg.Natives["Native.List"].Decls = []*ast.TypeAnnotation{decl("cons", 0)}
+	errs, err = CheckModule(pkg.Natives["Native.List"], loader)
+	require.NoError(err)
+	require.Len(errs, 1)
+	require.Equal("cons is not a function, so ListCons must be a variable of type rt.Value", errs[0].Message)
+
+	loader.Add("Native/List.go", "package native\n\nfunc (")
+	_, err = CheckModule(pkg.Natives["Native.List"], loader)
+	require.Error(err)
+}
@@ -0,0 +1,35 @@
+// Package format implements the canonical formatting of Elm source code.
+//
+// The formatting follows the style of elm-format: declarations are
+// separated by two blank lines, the bodies of definitions always start in
+// their own line, indented with four spaces, and so do the branches of case
+// expressions and the parts of if and let expressions. Records, lists,
+// tuples, applications and operators are written in one line if they were
+// in one line in the source code, and with each element in its own line
+// otherwise. Comments are kept where they are in the source code.
+package format
+
+import (
+	"bytes"
+
+	"github.com/elm-tangram/tangram/parser"
+)
+
+// Source formats the given source code of an Elm module. The source code
+// must be syntactically valid, otherwise the syntax errors are returned.
+func Source(src []byte) ([]byte, error) {
+	return File("", src)
+}
+
+// File formats the source code of the Elm module at the given path, which
+// is the file reported in the syntax errors.
+func File(path string, src []byte) ([]byte, error) {
+	mod, err := parser.ParseFrom(path, bytes.NewReader(src), parser.ParseComments|parser.SkipWarnings)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPrinter(src, mod.Comments)
+	p.module(mod)
+	return p.bytes(), nil
+}
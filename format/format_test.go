@@ -0,0 +1,227 @@
+package format
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var formatCases = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{
+		"declarations",
+		`module Main exposing (main, Msg(..), Model)
+import List as L exposing (map)
+type alias Model = Int
+type Msg = Inc | Set Int
+main : Int
+main = 1
+`,
+		`module Main exposing (main, Msg(..), Model)
+
+import List as L exposing (map)
+
+
+type alias Model =
+    Int
+
+
+type Msg
+    = Inc
+    | Set Int
+
+
+main : Int
+main =
+    1
+`,
+	},
+	{
+		"comments",
+		`module Main exposing (..)
+{-| The answer.
+-}
+answer : Int
+answer = 42 -- not a guess
+
+-- after the answer
+
+-- the end
+`,
+		`module Main exposing (..)
+
+
+{-| The answer.
+-}
+answer : Int
+answer =
+    42 -- not a guess
+
+
+-- after the answer
+
+-- the end
+`,
+	},
+	{
+		"patterns",
+		`module Main exposing (..)
+first (a, b) ((x, _) as pair) {c} = x
+rest list = case list of
+  ((Just x) :: xs) as all -> xs
+  Just (y :: _) -> []
+  [] -> []
+`,
+		`module Main exposing (..)
+
+
+first ( a, b ) (( x, _ ) as pair) { c } =
+    x
+
+
+rest list =
+    case list of
+        ((Just x) :: xs) as all ->
+            xs
+
+        Just (y :: _) ->
+            []
+
+        [] ->
+            []
+`,
+	},
+	{
+		"types",
+		`module Main exposing (..)
+apply : (a -> b) -> List (Maybe a) -> { x : Int, y : (Int, Int) }
+apply f l = f
+`,
+		`module Main exposing (..)
+
+
+apply : (a -> b) -> List (Maybe a) -> { x : Int, y : ( Int, Int ) }
+apply f l =
+    f
+`,
+	},
+	{
+		"multiline",
+		`module Main exposing (..)
+update : Msg
+  -> Model -> Model
+update msg model = { model | total = model.total
+  + 1, history = [1,
+  2] }
+`,
+		`module Main exposing (..)
+
+
+update :
+    Msg
+    -> Model
+    -> Model
+update msg model =
+    { model
+        | total =
+            model.total
+                + 1
+        , history =
+            [ 1
+            , 2
+            ]
+    }
+`,
+	},
+	{
+		"blocks",
+		`module Main exposing (..)
+f x =
+  let
+    y = x + 1
+    z = \a -> a
+  in
+    if y > 0 then z y else case x of
+        _ -> 0
+`,
+		`module Main exposing (..)
+
+
+f x =
+    let
+        y =
+            x + 1
+
+        z =
+            \a -> a
+    in
+        if y > 0 then
+            z y
+        else
+            case x of
+                _ ->
+                    0
+`,
+	},
+}
+
+func TestSource(t *testing.T) {
+	for _, tt := range formatCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			out, err := Source([]byte(tt.input))
+			require.NoError(err)
+			require.Equal(tt.expected, string(out))
+
+			out, err = Source(out)
+			require.NoError(err)
+			require.Equal(tt.expected, string(out), "formatting is not idempotent")
+		})
+	}
+}
+
+func TestFileError(t *testing.T) {
+	_, err := File("Main.elm", []byte("module Main exposing (..)\n\nanswer =\n    (42\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Main.elm")
+}
+
+func TestSourceFixtures(t *testing.T) {
+	var files []string
+	err := filepath.Walk("..", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == "elm-stuff" {
+			return filepath.SkipDir
+		}
+
+		if strings.HasSuffix(path, ".elm") && strings.Contains(path, "_testdata") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, f := range files {
+		src, err := ioutil.ReadFile(f)
+		require.NoError(t, err)
+		out, err := File(f, src)
+		if err != nil {
+			// some fixtures are not valid on purpose
+			continue
+		}
+
+		again, err := File(f, out)
+		require.NoError(t, err, f)
+		require.Equal(t, string(out), string(again), "formatting %s is not idempotent", f)
+	}
+}
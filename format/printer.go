@@ -0,0 +1,855 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// indentation is the indentation of the blocks, such as the body of a
+// definition.
+const indentation = "    "
+
+type printer struct {
+	buf bytes.Buffer
+	src []byte
+	// lines are the offsets where the lines of the source code start.
+	lines []int
+	// comments are the comments that have not been printed yet.
+	comments []*ast.Comment
+	// indent is the indentation of the lines being printed.
+	indent string
+	// lineStart reports whether nothing has been printed in the current
+	// line yet, so the indentation still has to be printed.
+	lineStart bool
+	// last is the end of the last node printed.
+	last token.Pos
+}
+
+func newPrinter(src []byte, comments []*ast.Comment) *printer {
+	var lines = []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &printer{src: src, lines: lines, comments: comments, lineStart: true}
+}
+
+// bytes returns the printed code, which always ends with a single newline.
+func (p *printer) bytes() []byte {
+	return append(bytes.TrimRight(p.buf.Bytes(), "\n"), '\n')
+}
+
+// line returns the line of the source code where the given position is.
+func (p *printer) line(pos token.Pos) int {
+	return sort.SearchInts(p.lines, int(pos)+1) - 1
+}
+
+// multiline reports whether the node spans more than one line in the source
+// code.
+func (p *printer) multiline(n ast.Node) bool {
+	return p.line(n.Pos()) != p.line(n.End())
+}
+
+func (p *printer) write(format string, args ...interface{}) {
+	if p.lineStart {
+		p.buf.WriteString(p.indent)
+		p.lineStart = false
+	}
+	fmt.Fprintf(&p.buf, format, args...)
+}
+
+// column returns the column of the current line where the next code will be
+// printed, starting at 0.
+func (p *printer) column() int {
+	if p.lineStart {
+		return len(p.indent)
+	}
+	b := p.buf.Bytes()
+	return len(b) - (bytes.LastIndexByte(b, '\n') + 1)
+}
+
+// align makes the current column the indentation of the next lines, and
+// returns the function that restores the previous indentation.
+func (p *printer) align() func() {
+	return p.indented(strings.Repeat(" ", p.column()))
+}
+
+// indented makes the given string the indentation of the next lines, and
+// returns the function that restores the previous indentation.
+func (p *printer) indented(indent string) func() {
+	prev := p.indent
+	p.indent = indent
+	return func() { p.indent = prev }
+}
+
+// newline ends the current line, if anything has been printed in it, after
+// the line comments that were in the same line as the last node printed.
+func (p *printer) newline() {
+	p.newlineBefore(token.Pos(len(p.src) + 1))
+}
+
+// newlineBefore is like newline, but it only prints the comments before the
+// given position, which is the next node to print.
+func (p *printer) newlineBefore(pos token.Pos) {
+	if p.lineStart {
+		return
+	}
+
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		if !c.IsLine() || c.Pos() < p.last || c.Pos() > pos || p.line(c.Pos()) != p.line(p.last) {
+			break
+		}
+		p.write(" %s", c.Text)
+		p.comments = p.comments[1:]
+	}
+
+	p.buf.WriteByte('\n')
+	p.lineStart = true
+}
+
+// blank ends the current line and leaves n blank lines after it.
+func (p *printer) blank(n int) {
+	p.newline()
+	b := p.buf.Bytes()
+	lines := len(b) - len(bytes.TrimRight(b, "\n"))
+	for ; lines <= n; lines++ {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// mark records that the code up to the given position has been printed,
+// so the comments right after it are trailing comments.
+func (p *printer) mark(pos token.Pos) {
+	if pos > p.last {
+		p.last = pos
+	}
+}
+
+// leading prints the comments before the given position, each one in its
+// own line. The blank lines after the comments are kept, but only one of
+// them.
+func (p *printer) leading(pos token.Pos) {
+	for len(p.comments) > 0 && p.comments[0].Pos() < pos {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.write("%s", c.Text)
+		p.last = c.End()
+
+		var next = pos
+		if len(p.comments) > 0 && p.comments[0].Pos() < pos {
+			next = p.comments[0].Pos()
+		}
+
+		if p.line(next)-p.line(c.End()) > 1 {
+			p.blank(1)
+		} else {
+			p.newline()
+		}
+	}
+}
+
+// trailing prints the comments that are left at the end of the module.
+func (p *printer) trailing() {
+	if len(p.comments) == 0 {
+		return
+	}
+
+	p.blank(2)
+	p.leading(token.Pos(len(p.src) + 1))
+}
+
+// node prints the given node with the function and records its end.
+func (p *printer) node(n ast.Node, print func()) {
+	print()
+	p.mark(n.End())
+}
+
+// items prints n items between the open and close delimiters, separated by
+// commas. They are all written in the same line, or each one in its own
+// line if the node was multiline in the source code.
+func (p *printer) items(n ast.Node, open, close string, count int, item func(i int), pos func(i int) token.Pos) {
+	if count == 0 {
+		p.write("%s%s", open, close)
+		return
+	}
+
+	if !p.multiline(n) {
+		p.write("%s ", open)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				p.write(", ")
+			}
+			item(i)
+		}
+		p.write(" %s", close)
+		return
+	}
+
+	defer p.align()()
+	p.write("%s ", open)
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			p.newline()
+			p.leading(pos(i))
+			p.write(", ")
+		}
+		item(i)
+	}
+	p.newline()
+	p.leading(n.End())
+	p.write("%s", close)
+}
+
+func (p *printer) module(m *ast.Module) {
+	p.leading(m.Module.Pos())
+	p.moduleDecl(m.Module)
+
+	var imports []*ast.ImportDecl
+	for _, imp := range m.Imports {
+		// the default imports are not in the source code
+		if imp.Import != token.NoPos {
+			imports = append(imports, imp)
+		}
+	}
+
+	if len(imports) > 0 {
+		p.blank(1)
+		for i, imp := range imports {
+			if i > 0 {
+				p.newline()
+			}
+			p.leading(imp.Pos())
+			p.importDecl(imp)
+		}
+	}
+
+	for i, d := range m.Decls {
+		_, infix := d.(*ast.InfixDecl)
+		if _, prevInfix := prevDecl(m.Decls, i).(*ast.InfixDecl); infix && prevInfix {
+			p.newline()
+		} else {
+			p.blank(2)
+		}
+
+		p.leading(d.Pos())
+		p.decl(d)
+	}
+
+	p.trailing()
+}
+
+func prevDecl(decls []ast.Decl, i int) ast.Decl {
+	if i == 0 {
+		return nil
+	}
+	return decls[i-1]
+}
+
+func (p *printer) moduleDecl(d *ast.ModuleDecl) {
+	p.node(d, func() {
+		switch {
+		case d.IsEffect():
+			p.write("effect ")
+		case d.PortModule:
+			p.write("port ")
+		}
+
+		p.write("module %s", name(d.Name))
+		if d.IsEffect() {
+			p.write(" where { ")
+			for i, f := range d.Manager {
+				if i > 0 {
+					p.write(", ")
+				}
+				p.write("%s = %s", f.Field.Name, name(f.Expr))
+			}
+			p.write(" }")
+		}
+
+		if d.Exposing != nil {
+			p.write(" exposing ")
+			p.exposedList(d.Exposing)
+		}
+	})
+}
+
+func (p *printer) importDecl(d *ast.ImportDecl) {
+	p.node(d, func() {
+		p.write("import %s", name(d.Module))
+		if d.Alias != nil {
+			p.write(" as %s", d.Alias.Name)
+		}
+
+		if d.Exposing != nil {
+			p.write(" exposing ")
+			p.exposedList(d.Exposing)
+		}
+	})
+}
+
+func (p *printer) exposedList(l ast.ExposedList) {
+	switch l := l.(type) {
+	case *ast.OpenList:
+		p.write("(..)")
+	case *ast.ClosedList:
+		p.write("(")
+		for i, e := range l.Exposed {
+			if i > 0 {
+				p.write(", ")
+			}
+
+			switch e := e.(type) {
+			case *ast.ExposedVar:
+				p.write("%s", varName(e.Ident))
+			case *ast.ExposedUnion:
+				p.write("%s", e.Type.Name)
+				if e.Ctors != nil {
+					p.exposedList(e.Ctors)
+				}
+			}
+		}
+		p.write(")")
+	}
+}
+
+func (p *printer) decl(d ast.Decl) {
+	switch d := d.(type) {
+	case *ast.InfixDecl:
+		p.node(d, func() {
+			p.write("%s %s %s", d.Assoc.Keyword(), d.Precedence.Value, d.Op.Name)
+		})
+	case *ast.AliasDecl:
+		p.write("type alias %s", d.Name.Name)
+		p.typeArgs(d.Args)
+		p.write(" =")
+		p.body(d.Type.Pos(), func() { p.typ(d.Type, typeTop) })
+	case *ast.UnionDecl:
+		p.write("type %s", d.Name.Name)
+		p.typeArgs(d.Args)
+		restore := p.indented(p.indent + indentation)
+		for i, c := range d.Ctors {
+			p.newline()
+			p.leading(c.Pos())
+			if i == 0 {
+				p.write("= ")
+			} else {
+				p.write("| ")
+			}
+
+			p.node(c, func() {
+				p.write("%s", c.Name.Name)
+				for _, arg := range c.Args {
+					p.write(" ")
+					p.typ(arg, typeArg)
+				}
+			})
+		}
+		restore()
+	case *ast.PortDecl:
+		p.write("port %s :", d.Name.Name)
+		p.annotationType(d.Type)
+	case *ast.DestructuringAssignment:
+		p.pattern(d.Pattern, patternTop)
+		p.write(" =")
+		p.mark(d.Eq + 1)
+		p.body(d.Expr.Pos(), func() { p.expr(d.Expr) })
+	case *ast.Definition:
+		if d.Annotation != nil {
+			p.write("%s :", varName(d.Annotation.Name))
+			p.annotationType(d.Annotation.Type)
+			p.newline()
+			p.leading(d.Name.Pos())
+		}
+
+		p.write("%s", varName(d.Name))
+		for _, arg := range d.Args {
+			p.write(" ")
+			p.pattern(arg, patternArg)
+		}
+		p.write(" =")
+		p.mark(d.Eq + 1)
+		p.body(d.Body.Pos(), func() { p.expr(d.Body) })
+	}
+}
+
+func (p *printer) typeArgs(args []*ast.Ident) {
+	for _, arg := range args {
+		p.write(" %s", arg.Name)
+	}
+}
+
+// body prints an indented block in the next line, such as the body of a
+// definition, which starts at the given position.
+func (p *printer) body(pos token.Pos, print func()) {
+	defer p.indented(p.indent + indentation)()
+	p.newlineBefore(pos)
+	p.leading(pos)
+	print()
+}
+
+// annotationType prints the type of an annotation or a port after the
+// colon. If the type is a function that was multiline in the source code,
+// every argument is written in its own line.
+func (p *printer) annotationType(t ast.Type) {
+	fn, ok := t.(*ast.FuncType)
+	if !ok || !p.multiline(fn) {
+		p.write(" ")
+		p.typ(t, typeTop)
+		return
+	}
+
+	defer p.indented(p.indent + indentation)()
+	for i, arg := range append(fn.Args, fn.Return) {
+		p.newline()
+		p.leading(arg.Pos())
+		if i > 0 {
+			p.write("-> ")
+		}
+		p.typ(arg, typeFuncArg)
+	}
+}
+
+// typeContext is the context a type is printed in, which decides whether it
+// needs parenthesis.
+type typeContext int
+
+const (
+	typeTop typeContext = iota
+	// typeFuncArg is an argument of a function type.
+	typeFuncArg
+	// typeArg is an argument of a named type or a constructor.
+	typeArg
+)
+
+func (p *printer) typ(t ast.Type, ctx typeContext) {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		parens := ctx == typeArg && len(t.Args) > 0
+		if parens {
+			p.write("(")
+		}
+		p.write("%s", name(t.Name))
+		for _, arg := range t.Args {
+			p.write(" ")
+			p.typ(arg, typeArg)
+		}
+		if parens {
+			p.write(")")
+		}
+	case *ast.VarType:
+		p.write("%s", t.Name)
+	case *ast.FuncType:
+		parens := ctx != typeTop
+		if parens {
+			p.write("(")
+		}
+		for _, arg := range t.Args {
+			p.typ(arg, typeFuncArg)
+			p.write(" -> ")
+		}
+		p.typ(t.Return, typeFuncArg)
+		if parens {
+			p.write(")")
+		}
+	case *ast.RecordType:
+		p.items(t, "{", "}", len(t.Fields), func(i int) {
+			f := t.Fields[i]
+			p.write("%s : ", f.Name.Name)
+			p.typ(f.Type, typeTop)
+		}, func(i int) token.Pos {
+			return t.Fields[i].Pos()
+		})
+	case *ast.TupleType:
+		p.items(t, "(", ")", len(t.Elems), func(i int) {
+			p.typ(t.Elems[i], typeTop)
+		}, func(i int) token.Pos {
+			return t.Elems[i].Pos()
+		})
+	}
+
+	p.mark(t.End())
+}
+
+// patternContext is the context a pattern is printed in, which decides
+// whether it needs parenthesis.
+type patternContext int
+
+const (
+	patternTop patternContext = iota
+	// patternArg is an argument of a function or a constructor.
+	patternArg
+	// patternConsLeft is the head of a list pattern with "::".
+	patternConsLeft
+	// patternConsRight is the tail of a list pattern with "::".
+	patternConsRight
+	// patternAliased is the pattern of an alias.
+	patternAliased
+)
+
+func (p *printer) pattern(pat ast.Pattern, ctx patternContext) {
+	var parens bool
+	switch pat := pat.(type) {
+	case *ast.VarPattern:
+		p.write("%s", pat.Name.Name)
+	case *ast.AnythingPattern:
+		p.write("_")
+	case *ast.LiteralPattern:
+		p.write("%s", pat.Literal.Value)
+	case *ast.AliasPattern:
+		parens = ctx != patternTop
+		if parens {
+			p.write("(")
+		}
+		p.pattern(pat.Pattern, patternAliased)
+		p.write(" as %s", pat.Name.Name)
+	case *ast.CtorPattern:
+		if isCons(pat) {
+			parens = ctx == patternArg || ctx == patternConsLeft || ctx == patternAliased
+			if parens {
+				p.write("(")
+			}
+			p.pattern(pat.Args[0], patternConsLeft)
+			p.write(" :: ")
+			p.pattern(pat.Args[1], patternConsRight)
+			break
+		}
+
+		parens = len(pat.Args) > 0 && (ctx == patternArg || ctx == patternConsLeft)
+		if parens {
+			p.write("(")
+		}
+		p.write("%s", name(pat.Ctor))
+		for _, arg := range pat.Args {
+			p.write(" ")
+			p.pattern(arg, patternArg)
+		}
+	case *ast.TuplePattern:
+		p.patterns("(", ")", pat.Elems)
+	case *ast.RecordPattern:
+		p.patterns("{", "}", pat.Fields)
+	case *ast.ListPattern:
+		p.patterns("[", "]", pat.Elems)
+	}
+
+	if parens {
+		p.write(")")
+	}
+}
+
+func isCons(pat *ast.CtorPattern) bool {
+	id, ok := pat.Ctor.(*ast.Ident)
+	return ok && id.Name == "::" && len(pat.Args) == 2
+}
+
+func (p *printer) patterns(open, close string, pats []ast.Pattern) {
+	if len(pats) == 0 {
+		p.write("%s%s", open, close)
+		return
+	}
+
+	p.write("%s ", open)
+	for i, pat := range pats {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.pattern(pat, patternTop)
+	}
+	p.write(" %s", close)
+}
+
+func (p *printer) expr(e ast.Expr) {
+	p.node(e, func() { p.exprNode(e) })
+}
+
+func (p *printer) exprNode(e ast.Expr) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		p.write("%s", varName(e))
+	case *ast.SelectorExpr:
+		p.write("%s", e.String())
+	case *ast.BasicLit:
+		p.write("%s", e.Value)
+	case *ast.TupleLit:
+		p.items(e, "(", ")", len(e.Elems), func(i int) {
+			p.expr(e.Elems[i])
+		}, func(i int) token.Pos {
+			return e.Elems[i].Pos()
+		})
+	case *ast.TupleCtor:
+		p.write("(%s)", strings.Repeat(",", e.Elems-1))
+	case *ast.ListLit:
+		p.items(e, "[", "]", len(e.Elems), func(i int) {
+			p.expr(e.Elems[i])
+		}, func(i int) token.Pos {
+			return e.Elems[i].Pos()
+		})
+	case *ast.RecordLit:
+		p.items(e, "{", "}", len(e.Fields), func(i int) {
+			p.field(e.Fields[i])
+		}, func(i int) token.Pos {
+			return e.Fields[i].Pos()
+		})
+	case *ast.RecordUpdate:
+		p.recordUpdate(e)
+	case *ast.FuncApp:
+		p.expr(e.Func)
+		if !p.multiline(e) {
+			for _, arg := range e.Args {
+				p.write(" ")
+				p.expr(arg)
+			}
+			break
+		}
+
+		restore := p.indented(p.indent + indentation)
+		for _, arg := range e.Args {
+			p.newline()
+			p.leading(arg.Pos())
+			p.expr(arg)
+		}
+		restore()
+	case *ast.BinaryOp:
+		p.binaryOp(e)
+	case *ast.UnaryOp:
+		p.write("%s", e.Op.Name)
+		p.expr(e.Expr)
+	case *ast.AccessorExpr:
+		p.write(".%s", e.Field.Name)
+	case *ast.Lambda:
+		p.write("\\")
+		for i, arg := range e.Args {
+			if i > 0 {
+				p.write(" ")
+			}
+			p.pattern(arg, patternArg)
+		}
+		p.write(" ->")
+		p.mark(e.Arrow + 2)
+
+		if p.multiline(e) {
+			p.body(e.Expr.Pos(), func() { p.expr(e.Expr) })
+		} else {
+			p.write(" ")
+			p.expr(e.Expr)
+		}
+	case *ast.ParensExpr:
+		if !p.multiline(e) {
+			p.write("(")
+			p.expr(e.Expr)
+			p.write(")")
+			break
+		}
+
+		restore := p.align()
+		p.write("(")
+		p.expr(e.Expr)
+		p.newline()
+		p.leading(e.Rparen)
+		p.write(")")
+		restore()
+	case *ast.IfExpr:
+		p.ifExpr(e)
+	case *ast.CaseExpr:
+		p.caseExpr(e)
+	case *ast.LetExpr:
+		p.letExpr(e)
+	}
+}
+
+func (p *printer) field(f *ast.FieldAssign) {
+	p.write("%s =", f.Field.Name)
+	if isBlock(f.Expr) || p.multiline(f.Expr) {
+		p.body(f.Expr.Pos(), func() { p.expr(f.Expr) })
+		return
+	}
+
+	p.write(" ")
+	p.expr(f.Expr)
+}
+
+// isBlock reports whether the expression is an if, case or let expression,
+// which are always written in their own lines.
+func isBlock(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.IfExpr, *ast.CaseExpr, *ast.LetExpr:
+		return true
+	}
+	return false
+}
+
+func (p *printer) recordUpdate(e *ast.RecordUpdate) {
+	if !p.multiline(e) {
+		p.write("{ %s | ", e.Record.Name)
+		for i, f := range e.Fields {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.field(f)
+		}
+		p.write(" }")
+		return
+	}
+
+	defer p.align()()
+	p.write("{ %s", e.Record.Name)
+	restore := p.indented(p.indent + indentation)
+	for i, f := range e.Fields {
+		p.newline()
+		p.leading(f.Pos())
+		if i == 0 {
+			p.write("| ")
+		} else {
+			p.write(", ")
+		}
+		p.field(f)
+	}
+	restore()
+	p.newline()
+	p.leading(e.Rbrace)
+	p.write("}")
+}
+
+func (p *printer) binaryOp(e *ast.BinaryOp) {
+	// the operands are printed in the same order they were parsed, so the
+	// operators do not need any parenthesis to be parsed the same
+	var operands []ast.Expr
+	var ops []*ast.Ident
+	var flatten func(e ast.Expr)
+	flatten = func(e ast.Expr) {
+		if op, ok := e.(*ast.BinaryOp); ok {
+			flatten(op.Lhs)
+			ops = append(ops, op.Op)
+			flatten(op.Rhs)
+			return
+		}
+		operands = append(operands, e)
+	}
+	flatten(e)
+
+	p.expr(operands[0])
+	if !p.multiline(e) {
+		for i, op := range ops {
+			p.write(" %s ", op.Name)
+			p.expr(operands[i+1])
+		}
+		return
+	}
+
+	defer p.indented(p.indent + indentation)()
+	for i, op := range ops {
+		p.newline()
+		p.leading(op.Pos())
+		p.write("%s ", op.Name)
+		p.expr(operands[i+1])
+	}
+}
+
+func (p *printer) ifExpr(e *ast.IfExpr) {
+	if !p.multiline(e) {
+		p.write("if ")
+		p.expr(e.Cond)
+		p.write(" then ")
+		p.expr(e.ThenExpr)
+		p.write(" else ")
+		p.expr(e.ElseExpr)
+		return
+	}
+
+	defer p.align()()
+	for {
+		p.write("if ")
+		p.expr(e.Cond)
+		p.write(" then")
+		p.mark(e.Then + 4)
+		p.body(e.ThenExpr.Pos(), func() { p.expr(e.ThenExpr) })
+		p.newline()
+		p.leading(e.Else)
+		p.write("else")
+		p.mark(e.Else + 4)
+
+		next, ok := e.ElseExpr.(*ast.IfExpr)
+		if !ok {
+			p.body(e.ElseExpr.Pos(), func() { p.expr(e.ElseExpr) })
+			return
+		}
+
+		p.write(" ")
+		e = next
+	}
+}
+
+func (p *printer) caseExpr(e *ast.CaseExpr) {
+	defer p.align()()
+	p.write("case ")
+	p.expr(e.Expr)
+	p.write(" of")
+	p.mark(e.Of + 2)
+
+	restore := p.indented(p.indent + indentation)
+	for i, b := range e.Branches {
+		if i > 0 {
+			p.blank(1)
+		} else {
+			p.newline()
+		}
+
+		p.leading(b.Pos())
+		p.pattern(b.Pattern, patternTop)
+		p.write(" ->")
+		p.mark(b.Arrow + 2)
+		p.body(b.Expr.Pos(), func() { p.expr(b.Expr) })
+	}
+	restore()
+}
+
+func (p *printer) letExpr(e *ast.LetExpr) {
+	defer p.align()()
+	p.write("let")
+	p.mark(e.Let + 3)
+
+	restore := p.indented(p.indent + indentation)
+	for i, d := range e.Decls {
+		if i > 0 {
+			p.blank(1)
+		} else {
+			p.newline()
+		}
+
+		p.leading(d.Pos())
+		p.decl(d)
+	}
+	restore()
+
+	p.newline()
+	p.leading(e.In)
+	p.write("in")
+	p.mark(e.In + 2)
+
+	// the body must be indented, unlike in elm-format, or the parser would
+	// not find it
+	p.body(e.Body.Pos(), func() { p.expr(e.Body) })
+}
+
+// name returns the name of an identifier or a qualified identifier.
+func name(e ast.Expr) string {
+	if s, ok := e.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "_"
+}
+
+// varName returns the name of the identifier, with parenthesis if it is an
+// operator.
+func varName(id *ast.Ident) string {
+	if id.IsOp() {
+		return "(" + id.Name + ")"
+	}
+	return id.Name
+}
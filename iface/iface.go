@@ -0,0 +1,264 @@
+// Package iface implements module interface files. An interface is a compact
+// summary of everything a module exposes to its dependants: the exposed
+// values and their types, the exposed types and their constructors and the
+// fixities of the exposed operators. Interfaces are keyed by the hash of the
+// module source, so dependants of an unchanged module can load its interface
+// instead of checking it again.
+package iface
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+const (
+	elmStuffDir   = "elm-stuff"
+	interfacesDir = "interfaces"
+	ext           = ".elmi.json"
+)
+
+var (
+	// ErrNotFound is returned when there is no interface file for a module.
+	ErrNotFound = errors.New("iface: interface not found")
+	// ErrStale is returned when the interface file of a module was generated
+	// from a different version of its source code.
+	ErrStale = errors.New("iface: interface is stale")
+)
+
+// Interface is the public interface of a single module.
+type Interface struct {
+	// Module is the name of the module.
+	Module string `json:"module"`
+	// Hash is the hash of the source code the interface was generated from.
+	Hash string `json:"hash"`
+	// Values are the exposed definitions of the module.
+	Values []Value `json:"values,omitempty"`
+	// Types are the exposed type aliases and union types of the module.
+	Types []Type `json:"types,omitempty"`
+	// Fixities are the fixity declarations for the exposed operators.
+	Fixities []Fixity `json:"fixities,omitempty"`
+}
+
+// Value is an exposed definition.
+type Value struct {
+	// Name of the definition.
+	Name string `json:"name"`
+	// Type is the annotated type of the definition. It will be empty if the
+	// definition is not annotated.
+	Type string `json:"type,omitempty"`
+}
+
+// Type is an exposed type alias or union type.
+type Type struct {
+	// Name of the type.
+	Name string `json:"name"`
+	// Args are the type variables of the type.
+	Args []string `json:"args,omitempty"`
+	// Alias is the aliased type. It will only be set for type aliases.
+	Alias string `json:"alias,omitempty"`
+	// Ctors are the exposed constructors of an union type.
+	Ctors []Ctor `json:"ctors,omitempty"`
+}
+
+// Ctor is an exposed constructor of an union type.
+type Ctor struct {
+	// Name of the constructor.
+	Name string `json:"name"`
+	// Args are the types of the constructor arguments.
+	Args []string `json:"args,omitempty"`
+}
+
+// Fixity is the fixity of an exposed operator.
+type Fixity struct {
+	// Op is the name of the operator.
+	Op string `json:"op"`
+	// Assoc is the associativity of the operator.
+	Assoc ast.Associativity `json:"assoc"`
+	// Precedence of the operator.
+	Precedence uint `json:"precedence"`
+}
+
+// New creates the interface of the given module, which must have already
+// been resolved. Hash is the hash of the module source code.
+func New(mod *ast.Module, hash string) *Interface {
+	i := &Interface{Module: mod.Name, Hash: hash}
+	if mod.Scope == nil {
+		return i
+	}
+
+	var ctors = make(map[*ast.Constructor]struct{})
+	for _, obj := range mod.Scope.Exposed {
+		if obj.Kind == ast.Ctor {
+			if ctor, ok := obj.Node.(*ast.Constructor); ok {
+				ctors[ctor] = struct{}{}
+			}
+		}
+	}
+
+	for _, d := range mod.Decls {
+		switch d := d.(type) {
+		case *ast.Definition:
+			if !isExposed(mod.Scope, d.Name.Name, ast.Var) {
+				continue
+			}
+
+			v := Value{Name: d.Name.Name}
+			if d.Annotation != nil {
+				v.Type = typeString(d.Annotation.Type)
+			}
+			i.Values = append(i.Values, v)
+		case *ast.AliasDecl:
+			if !isExposed(mod.Scope, d.Name.Name, ast.Typ) {
+				continue
+			}
+
+			i.Types = append(i.Types, Type{
+				Name:  d.Name.Name,
+				Args:  identNames(d.Args),
+				Alias: typeString(d.Type),
+			})
+		case *ast.UnionDecl:
+			if !isExposed(mod.Scope, d.Name.Name, ast.Typ) {
+				continue
+			}
+
+			t := Type{Name: d.Name.Name, Args: identNames(d.Args)}
+			for _, c := range d.Ctors {
+				if _, ok := ctors[c]; !ok {
+					continue
+				}
+
+				ctor := Ctor{Name: c.Name.Name}
+				for _, arg := range c.Args {
+					ctor.Args = append(ctor.Args, typeString(arg))
+				}
+				t.Ctors = append(t.Ctors, ctor)
+			}
+			i.Types = append(i.Types, t)
+		case *ast.InfixDecl:
+			if !isExposed(mod.Scope, d.Op.Name, ast.Var) {
+				continue
+			}
+
+			n, _ := strconv.Atoi(d.Precedence.Value)
+			i.Fixities = append(i.Fixities, Fixity{
+				Op:         d.Op.Name,
+				Assoc:      d.Assoc,
+				Precedence: uint(n),
+			})
+		}
+	}
+
+	sort.Slice(i.Values, func(a, b int) bool { return i.Values[a].Name < i.Values[b].Name })
+	sort.Slice(i.Types, func(a, b int) bool { return i.Types[a].Name < i.Types[b].Name })
+	sort.Slice(i.Fixities, func(a, b int) bool { return i.Fixities[a].Op < i.Fixities[b].Op })
+	return i
+}
+
+func isExposed(scope *ast.ModuleScope, name string, kind ast.ObjKind) bool {
+	return scope.LookupExposed(name, kind) != nil
+}
+
+func identNames(idents []*ast.Ident) []string {
+	var names []string
+	for _, id := range idents {
+		names = append(names, id.Name)
+	}
+	return names
+}
+
+// Hash returns the hash of the given source code.
+func Hash(src io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, src); err != nil {
+		return "", fmt.Errorf("iface: can't hash source: %s", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Write encodes the interface to the given writer.
+func Write(w io.Writer, i *Interface) error {
+	if err := json.NewEncoder(w).Encode(i); err != nil {
+		return fmt.Errorf("iface: can't encode interface of %s: %s", i.Module, err)
+	}
+	return nil
+}
+
+// Read decodes an interface from the given reader.
+func Read(r io.Reader) (*Interface, error) {
+	var i Interface
+	if err := json.NewDecoder(r).Decode(&i); err != nil {
+		return nil, fmt.Errorf("iface: can't decode interface: %s", err)
+	}
+	return &i, nil
+}
+
+// Cache stores the interface files of a package in a directory.
+type Cache struct {
+	dir string
+}
+
+// NewCache creates a new cache of interfaces for the package at the given
+// root. Interfaces are stored in the elm-stuff directory of the package.
+func NewCache(root string) *Cache {
+	return &Cache{filepath.Join(root, elmStuffDir, interfacesDir)}
+}
+
+func (c *Cache) path(module string) string {
+	return filepath.Join(c.dir, module+ext)
+}
+
+// Load returns the interface of the given module if it was generated from
+// source code with the given hash. ErrNotFound will be returned if there is
+// no interface for the module and ErrStale if the interface does not match
+// the hash.
+func (c *Cache) Load(module, hash string) (*Interface, error) {
+	f, err := os.Open(c.path(module))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("iface: can't open interface of %s: %s", module, err)
+	}
+	defer f.Close()
+
+	i, err := Read(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Hash != hash {
+		return nil, ErrStale
+	}
+
+	return i, nil
+}
+
+// Save writes the given interface to the cache, replacing the previous one,
+// if any.
+func (c *Cache) Save(i *Interface) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("iface: can't create interfaces directory: %s", err)
+	}
+
+	f, err := os.Create(c.path(i.Module))
+	if err != nil {
+		return fmt.Errorf("iface: can't create interface of %s: %s", i.Module, err)
+	}
+
+	if err := Write(f, i); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,113 @@
+package iface
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseFixture(t *testing.T) *ast.Package {
+	path := filepath.Join("..", "parser", "_testdata", "valid_fullparse", "src", "Main.elm")
+	pkg, err := parser.Parse(path, parser.FullParse)
+	require.NoError(t, err)
+	return pkg
+}
+
+func TestNew(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	dep := New(pkg.Modules["Dependency"], "hash")
+	require.Equal("Dependency", dep.Module)
+	require.Equal("hash", dep.Hash)
+	require.Equal([]Value{
+		{"?", "Maybe a -> a -> a"},
+		{"?:", "Maybe a -> a -> a"},
+	}, dep.Values)
+	require.Equal([]Fixity{{"?", ast.Left, 2}}, dep.Fixities)
+	require.Len(dep.Types, 0)
+
+	maybe := New(pkg.Modules["Maybe"], "hash")
+	require.Equal([]Type{
+		{
+			Name: "Maybe",
+			Args: []string{"a"},
+			Ctors: []Ctor{
+				{"Just", []string{"a"}},
+				{"Nothing", nil},
+			},
+		},
+	}, maybe.Types)
+	require.Equal([]Value{{"withDefault", "Maybe a -> a -> a"}}, maybe.Values)
+}
+
+func TestTypeString(t *testing.T) {
+	named := func(name string, args ...ast.Type) ast.Type {
+		return &ast.NamedType{Name: ast.NewIdent(name, 0), Args: args}
+	}
+	v := func(name string) ast.Type {
+		return &ast.VarType{Ident: ast.NewIdent(name, 0)}
+	}
+
+	cases := []struct {
+		typ      ast.Type
+		expected string
+	}{
+		{named("Int"), "Int"},
+		{named("List", named("Maybe", v("a"))), "List (Maybe a)"},
+		{
+			&ast.FuncType{
+				Args:   []ast.Type{&ast.FuncType{Args: []ast.Type{v("a")}, Return: v("b")}, named("List", v("a"))},
+				Return: named("List", v("b")),
+			},
+			"(a -> b) -> List a -> List b",
+		},
+		{
+			&ast.RecordType{Fields: []*ast.RecordField{
+				{Name: ast.NewIdent("x", 0), Type: named("Int")},
+				{Name: ast.NewIdent("y", 0), Type: named("Int")},
+			}},
+			"{ x : Int, y : Int }",
+		},
+		{&ast.TupleType{Elems: []ast.Type{v("a"), named("String")}}, "(a, String)"},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, typeString(c.typ))
+	}
+}
+
+func TestCache(t *testing.T) {
+	require := require.New(t)
+	root, err := ioutil.TempDir(os.TempDir(), "iface")
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	hash, err := Hash(strings.NewReader("module Foo exposing (..)"))
+	require.NoError(err)
+
+	cache := NewCache(root)
+	_, err = cache.Load("Foo", hash)
+	require.Equal(ErrNotFound, err)
+
+	i := &Interface{
+		Module: "Foo",
+		Hash:   hash,
+		Values: []Value{{"foo", "Int"}},
+	}
+	require.NoError(cache.Save(i))
+
+	loaded, err := cache.Load("Foo", hash)
+	require.NoError(err)
+	require.Equal(i, loaded)
+
+	_, err = cache.Load("Foo", "other")
+	require.Equal(ErrStale, err)
+}
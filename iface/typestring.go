@@ -0,0 +1,76 @@
+package iface
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// typeString returns the textual representation of a type as it would be
+// written in Elm source code.
+func typeString(typ ast.Type) string {
+	var buf bytes.Buffer
+	writeType(&buf, typ, false)
+	return buf.String()
+}
+
+// writeType writes the given type to the buffer. If nested is true, types
+// that would be ambiguous as an argument of another type will be wrapped
+// with parenthesis.
+func writeType(buf *bytes.Buffer, typ ast.Type, nested bool) {
+	switch t := typ.(type) {
+	case *ast.NamedType:
+		if nested && len(t.Args) > 0 {
+			buf.WriteRune('(')
+			defer buf.WriteRune(')')
+		}
+
+		buf.WriteString(fmt.Sprint(t.Name))
+		for _, arg := range t.Args {
+			buf.WriteRune(' ')
+			writeType(buf, arg, true)
+		}
+	case *ast.VarType:
+		buf.WriteString(t.Name)
+	case *ast.FuncType:
+		if nested {
+			buf.WriteRune('(')
+			defer buf.WriteRune(')')
+		}
+
+		for _, arg := range t.Args {
+			_, isFunc := arg.(*ast.FuncType)
+			writeType(buf, arg, isFunc)
+			buf.WriteString(" -> ")
+		}
+		writeType(buf, t.Return, false)
+	case *ast.RecordType:
+		if len(t.Fields) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+
+		buf.WriteString("{ ")
+		for i, f := range t.Fields {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(f.Name.Name)
+			buf.WriteString(" : ")
+			writeType(buf, f.Type, false)
+		}
+		buf.WriteString(" }")
+	case *ast.TupleType:
+		buf.WriteRune('(')
+		for i, el := range t.Elems {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeType(buf, el, false)
+		}
+		buf.WriteRune(')')
+	default:
+		buf.WriteRune('_')
+	}
+}
@@ -0,0 +1,40 @@
+module Basics exposing (..)
+
+import Native.Basics
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Basics.add
+
+
+(-) : number -> number -> number
+(-) =
+    Native.Basics.sub
+
+
+(*) : number -> number -> number
+(*) =
+    Native.Basics.mul
+
+
+(++) : appendable -> appendable -> appendable
+(++) =
+    Native.Basics.append
+
+
+(==) : a -> a -> Bool
+(==) =
+    Native.Basics.eq
+
+
+negate : number -> number
+negate =
+    Native.Basics.negate
+
+
+infixl 6 +
+infixl 6 -
+infixl 7 *
+infixr 5 ++
+infix 4 ==
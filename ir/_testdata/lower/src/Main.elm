@@ -0,0 +1,144 @@
+module Main exposing (..)
+
+import Basics exposing (..)
+import List exposing ((::))
+import Maybe exposing (Maybe(..))
+
+
+type alias Point =
+    { x : Int, y : Int }
+
+
+origin : Point
+origin =
+    { x = 0, y = 0 }
+
+
+add : Int -> Int -> Int
+add a b =
+    a + b
+
+
+neg n =
+    -n
+
+
+withDefault default maybe =
+    case maybe of
+        Just value ->
+            value
+
+        Nothing ->
+            default
+
+
+sign n =
+    if n == 0 then
+        0
+    else
+        1
+
+
+firstTwo list =
+    case list of
+        [ a, b ] ->
+            a + b
+
+        a :: _ ->
+            a
+
+        [] ->
+            0
+
+
+swap ( a, b ) =
+    ( b, a )
+
+
+getX =
+    .x
+
+
+moveX p =
+    { p | x = p.x + 1 }
+
+
+twice =
+    let
+        double n =
+            n + n
+
+        ( a, b ) =
+            ( 1, 2 )
+    in
+        double a + b
+
+
+( first, second ) =
+    ( 1, "a" )
+
+
+isOne n =
+    case n of
+        1 ->
+            True
+
+        _ ->
+            False
+
+
+both pair =
+    case pair of
+        ( Just a, Just b ) ->
+            a + b
+
+        ( Just a, Nothing ) ->
+            a
+
+        ( Nothing, _ ) ->
+            0
+
+
+count acc list =
+    case list of
+        [] ->
+            acc
+
+        _ :: rest ->
+            count (acc + 1) rest
+
+
+constants =
+    let
+        n =
+            2 * 3 + 1
+    in
+        if n == 7 then
+            { origin | x = n }
+        else
+            origin
+
+
+greeting =
+    "hello, " ++ "world"
+
+
+primes =
+    2 :: [ 3, 5 ] ++ [ 7 ]
+
+
+firstPrime =
+    case primes of
+        p :: _ ->
+            p + first
+
+        [] ->
+            0
+
+
+three =
+    add 1 2
+
+
+fallback =
+    withDefault 0 Nothing
@@ -0,0 +1,6 @@
+module Maybe exposing (..)
+
+
+type Maybe a
+    = Just a
+    | Nothing
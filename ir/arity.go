@@ -0,0 +1,73 @@
+package ir
+
+import "github.com/elm-tangram/tangram/types"
+
+// Uncurry replaces the applications of known functions to at least as many
+// arguments as their parameters with direct calls. The known functions are
+// the top-level definitions and let bindings whose value is a lambda, so
+// their arity is the number of parameters of the lambda. The arguments
+// left after the call are applied to its result.
+func Uncurry(pkg *Package) {
+	var globals = make(map[[2]string]int)
+	var locals = make(map[*Local]int)
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			if fn, ok := d.Expr.(*Lambda); ok {
+				globals[[2]string{m.Name, d.Name}] = len(fn.Params)
+			}
+
+			Inspect(d.Expr, func(e Expr) bool {
+				if let, ok := e.(*Let); ok {
+					for _, b := range let.Binds {
+						if fn, ok := b.Expr.(*Lambda); ok {
+							locals[b.Var] = len(fn.Params)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	arity := func(e Expr) int {
+		switch e := e.(type) {
+		case *Global:
+			return globals[[2]string{e.Module, e.Name}]
+		case *Local:
+			return locals[e]
+		}
+		return 0
+	}
+
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			d.Expr = Rewrite(d.Expr, func(e Expr) Expr {
+				app, ok := e.(*App)
+				if !ok {
+					return e
+				}
+
+				n := arity(app.Func)
+				if n == 0 || len(app.Args) < n {
+					return e
+				}
+
+				if len(app.Args) == n {
+					return &Call{app.Func, app.Args, app.Typ, app.Pos}
+				}
+
+				call := &Call{app.Func, app.Args[:n], resultType(app.Func.Type(), n), app.Pos}
+				return &App{call, app.Args[n:], app.Typ, app.Pos}
+			})
+		}
+	}
+}
+
+// resultType returns the type of the result of applying a function of the
+// given type to n arguments.
+func resultType(t types.Type, n int) types.Type {
+	for i := 0; i < n; i++ {
+		_, t = funcParts(t)
+	}
+	return t
+}
@@ -0,0 +1,29 @@
+package ir_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUncurry(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.Uncurry(pkg)
+	main := pkg.Lookup("Main")
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"twice", `(let double$20 = (\n$23 -> (Basics.+ n$23 n$23)); $24 = (1, 2); a$21 = $24.0; b$22 = $24.1 in (Basics.+ (call double$20 a$21) b$22))`},
+		{"count", `(\acc$35 list$36 -> (case list$36 of [] -> acc$35 | :: $38 rest$37 -> (call Main.count (Basics.+ acc$35 1) rest$37)))`},
+		{"three", "(call Main.add 1 2)"},
+		{"fallback", "(call Main.withDefault 0 Maybe.Nothing)"},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, ir.String(main.Lookup(c.name).Expr), c.name)
+	}
+}
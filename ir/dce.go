@@ -0,0 +1,66 @@
+package ir
+
+// Exposed returns the globals of all the definitions exposed by the modules
+// of the package, which are the roots of the dead code elimination of a
+// library.
+func (p *Package) Exposed() []*Global {
+	var roots []*Global
+	for _, m := range p.Modules {
+		for _, d := range m.Defs {
+			if d.Exposed {
+				roots = append(roots, &Global{m.Name, d.Name, d.Type})
+			}
+		}
+	}
+	return roots
+}
+
+// EliminateDeadCode removes from the package all the definitions that are
+// not reachable from the given roots, such as the main of a program, and
+// the modules that end up with no definitions or ports. Ports are never
+// removed, because they can be used from outside the program.
+func EliminateDeadCode(pkg *Package, roots ...*Global) {
+	var defs = make(map[[2]string]*Def)
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			defs[[2]string{m.Name, d.Name}] = d
+		}
+	}
+
+	var live = make(map[*Def]bool)
+	var pending = make([]*Global, len(roots))
+	copy(pending, roots)
+	for len(pending) > 0 {
+		g := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		d := defs[[2]string{g.Module, g.Name}]
+		if d == nil || live[d] {
+			continue
+		}
+
+		live[d] = true
+		Inspect(d.Expr, func(e Expr) bool {
+			if g, ok := e.(*Global); ok {
+				pending = append(pending, g)
+			}
+			return true
+		})
+	}
+
+	var modules = pkg.Modules[:0]
+	for _, m := range pkg.Modules {
+		var kept = m.Defs[:0]
+		for _, d := range m.Defs {
+			if live[d] {
+				kept = append(kept, d)
+			}
+		}
+		m.Defs = kept
+
+		if len(m.Defs) > 0 || len(m.Ports) > 0 {
+			modules = append(modules, m)
+		}
+	}
+	pkg.Modules = modules
+}
@@ -0,0 +1,42 @@
+package ir_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExposed(t *testing.T) {
+	var names []string
+	for _, g := range lowerTestdata(t).Exposed() {
+		if g.Module == "Main" {
+			names = append(names, g.Name)
+		}
+	}
+
+	require.Equal(t, []string{
+		"origin", "add", "neg", "withDefault", "sign", "firstTwo", "swap",
+		"getX", "moveX", "twice", "first", "second", "isOne", "both", "count",
+		"constants", "greeting", "primes", "firstPrime", "three", "fallback",
+	}, names)
+}
+
+func TestEliminateDeadCode(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.EliminateDeadCode(pkg, &ir.Global{Module: "Main", Name: "twice"}, &ir.Global{Module: "Main", Name: "second"})
+
+	var defs = make(map[string][]string)
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			defs[m.Name] = append(defs[m.Name], d.Name)
+		}
+	}
+
+	require.Equal(t, map[string][]string{
+		"Basics": {"+"},
+		"Main":   {"twice", "_25", "second"},
+	}, defs)
+	require.Len(t, pkg.Modules, 2)
+}
@@ -0,0 +1,639 @@
+package ir
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// FoldConstants evaluates at compile time the expressions whose value is
+// known, such as arithmetic on literals, the concatenation of string
+// literals and lists, the construction of lists with `::` and the
+// projections of constant tuples and records. Literals bound to variables
+// and top-level definitions are propagated to the places where they are
+// used, and case expressions on known values are replaced with the
+// alternative that matches them.
+func FoldConstants(pkg *Package) {
+	f := &folder{
+		defs:    make(map[[2]string]*Def),
+		folded:  make(map[*Def]bool),
+		values:  make(map[*Local]Expr),
+		aliases: make(map[[2]string]*Global),
+	}
+
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			f.defs[[2]string{m.Name, d.Name}] = d
+			if g, ok := d.Expr.(*Global); ok && (m.Name == "Basics" || m.Name == "List") {
+				f.aliases[[2]string{g.Module, g.Name}] = &Global{m.Name, d.Name, d.Type}
+			}
+		}
+	}
+
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			f.def(d)
+		}
+	}
+}
+
+type folder struct {
+	defs map[[2]string]*Def
+	// folded contains the definitions that have been folded, or are being
+	// folded if they are false.
+	folded map[*Def]bool
+	// values contains the constant values of the bound variables.
+	values map[*Local]Expr
+	// aliases contains the functions of Basics and List by the natives
+	// they are defined as, so they are still folded after inlining.
+	aliases map[[2]string]*Global
+}
+
+func (f *folder) def(d *Def) {
+	if _, ok := f.folded[d]; ok {
+		return
+	}
+
+	f.folded[d] = false
+	d.Expr = f.fold(d.Expr)
+	f.folded[d] = true
+}
+
+// global returns the value of the definition of the global if it is a
+// constant. The definition is folded first, so the values that can be
+// computed from other constants are also known.
+func (f *folder) global(g *Global) Expr {
+	d := f.defs[[2]string{g.Module, g.Name}]
+	if d == nil {
+		return nil
+	}
+
+	f.def(d)
+	if IsConstant(d.Expr) {
+		return d.Expr
+	}
+	return nil
+}
+
+// value returns the constant value of the expression, which may be a
+// constant itself or refer to one.
+func (f *folder) value(e Expr) Expr {
+	switch e := e.(type) {
+	case *Local:
+		return f.values[e]
+	case *Global:
+		return f.global(e)
+	}
+
+	if IsConstant(e) {
+		return e
+	}
+	return nil
+}
+
+func (f *folder) fold(e Expr) Expr {
+	switch e := e.(type) {
+	case *Local:
+		if v := f.values[e]; isSimple(v) {
+			return retype(v, e.Typ)
+		}
+	case *Global:
+		if v := f.global(e); isSimple(v) {
+			return retype(v, e.Typ)
+		}
+	case *Lambda:
+		e.Body = f.fold(e.Body)
+	case *App:
+		e.Func = f.fold(e.Func)
+		f.foldExprs(e.Args)
+		if folded := f.app(e); folded != nil {
+			return folded
+		}
+	case *Call:
+		e.Func = f.fold(e.Func)
+		f.foldExprs(e.Args)
+	case *Let:
+		return f.let(e)
+	case *Case:
+		return f.caseExpr(e)
+	case *Tuple:
+		f.foldExprs(e.Elems)
+	case *Index:
+		e.Expr = f.fold(e.Expr)
+		if t, ok := e.Expr.(*Tuple); ok && IsConstant(t) {
+			return t.Elems[e.Index]
+		}
+
+		if t, ok := f.value(e.Expr).(*Tuple); ok && isSimple(t.Elems[e.Index]) {
+			return retype(t.Elems[e.Index], e.Typ)
+		}
+	case *Record:
+		f.foldFields(e.Fields)
+	case *Access:
+		e.Expr = f.fold(e.Expr)
+		if r, ok := e.Expr.(*Record); ok && IsConstant(r) {
+			return field(r, e.Field)
+		}
+
+		if r, ok := f.value(e.Expr).(*Record); ok && isSimple(field(r, e.Field)) {
+			return retype(field(r, e.Field), e.Typ)
+		}
+	case *Update:
+		e.Record = f.fold(e.Record)
+		f.foldFields(e.Fields)
+		if r, ok := f.value(e.Record).(*Record); ok && constantFields(e.Fields) {
+			return update(Copy(r).(*Record), e.Fields, e.Typ)
+		}
+	case *List:
+		f.foldExprs(e.Elems)
+	case *TailCall:
+		f.foldExprs(e.Args)
+	case *Probe:
+		e.Expr = f.fold(e.Expr)
+	}
+	return e
+}
+
+func (f *folder) foldExprs(exprs []Expr) {
+	for i, e := range exprs {
+		exprs[i] = f.fold(e)
+	}
+}
+
+func (f *folder) foldFields(fields []*Field) {
+	for _, field := range fields {
+		field.Expr = f.fold(field.Expr)
+	}
+}
+
+// let folds the binds of a let expression in order, so the constants are
+// known when the binds that use them are folded, and removes the constant
+// binds that are no longer used.
+func (f *folder) let(e *Let) Expr {
+	for _, b := range SortBinds(e.Binds) {
+		b.Expr = f.fold(b.Expr)
+		if v := f.value(b.Expr); v != nil {
+			f.values[b.Var] = v
+		}
+	}
+	e.Body = f.fold(e.Body)
+
+	var used = make(map[*Local]bool)
+	mark := func(e Expr) bool {
+		if v, ok := e.(*Local); ok {
+			used[v] = true
+		}
+		return true
+	}
+	for _, b := range e.Binds {
+		Inspect(b.Expr, mark)
+	}
+	Inspect(e.Body, mark)
+
+	var binds = e.Binds[:0]
+	for _, b := range e.Binds {
+		if used[b.Var] || f.value(b.Expr) == nil {
+			binds = append(binds, b)
+		}
+	}
+	e.Binds = binds
+
+	if len(e.Binds) == 0 {
+		return e.Body
+	}
+	return e
+}
+
+// caseExpr replaces a case expression on a constant with the alternative
+// that matches it. The variables bound by the alternative are bound to the
+// arguments of the constructor.
+func (f *folder) caseExpr(e *Case) Expr {
+	if v := f.values[e.Subject]; v != nil {
+		if body, ok := f.match(e, v); ok {
+			return f.fold(body)
+		}
+	}
+
+	for _, alt := range e.Alts {
+		alt.Body = f.fold(alt.Body)
+	}
+	if e.Default != nil {
+		e.Default = f.fold(e.Default)
+	}
+	return e
+}
+
+func (f *folder) match(e *Case, v Expr) (Expr, bool) {
+	var ctor *Ctor
+	var args []Expr
+	switch v := v.(type) {
+	case *Ctor:
+		ctor = v
+	case *App:
+		ctor, args = v.Func.(*Ctor), v.Args
+	case *List:
+		ctor = &Ctor{Name: "[]", Tag: 0}
+		if len(v.Elems) > 0 {
+			var rest = &List{v.Elems[1:], v.Typ}
+			ctor, args = &Ctor{Name: "::", Tag: 1}, []Expr{v.Elems[0], rest}
+		}
+	}
+
+	for i, alt := range e.Alts {
+		if alt.Ctor != nil {
+			if ctor == nil {
+				return nil, false
+			}
+
+			if alt.Ctor.Tag != ctor.Tag {
+				continue
+			}
+
+			var binds = make([]*Bind, len(alt.Vars))
+			for j, v := range alt.Vars {
+				binds[j] = &Bind{v, Copy(args[j])}
+			}
+			if len(binds) == 0 {
+				return alt.Body, true
+			}
+			return &Let{binds, alt.Body}, true
+		}
+
+		lit, ok := v.(*Lit)
+		if !ok {
+			return nil, false
+		}
+
+		equal, ok := equalLits(alt.Lit, lit)
+		if !ok {
+			return nil, false
+		}
+
+		// the last alternative matches anything if there is no default
+		if equal || (e.Default == nil && i == len(e.Alts)-1) {
+			return alt.Body, true
+		}
+	}
+
+	if e.Default != nil {
+		return e.Default, true
+	}
+	return nil, false
+}
+
+// app evaluates the application of the Basics operators and functions, or
+// the natives they are defined as, to literals and the application of `::`
+// to lists. It returns nil if the
+// application cannot be folded.
+func (f *folder) app(e *App) Expr {
+	fn := e.Func
+	if g, ok := fn.(*Global); ok {
+		if alias, ok := f.aliases[[2]string{g.Module, g.Name}]; ok {
+			fn = alias
+		}
+	}
+
+	if isCons(fn) && len(e.Args) == 2 {
+		if l, ok := e.Args[1].(*List); ok {
+			return &List{append([]Expr{e.Args[0]}, l.Elems...), e.Typ}
+		}
+		return nil
+	}
+
+	if fn, ok := fn.(*Global); ok && fn.Module == "Basics" {
+		if len(e.Args) == 1 {
+			return unaryOp(fn.Name, e.Args[0], e.Typ)
+		}
+
+		if len(e.Args) == 2 {
+			return binaryOp(fn.Name, e.Args[0], e.Args[1], e.Typ)
+		}
+	}
+	return nil
+}
+
+// isCons reports whether the expression is the list constructor `::`, which
+// is also a function of the List module.
+func isCons(e Expr) bool {
+	switch e := e.(type) {
+	case *Ctor:
+		return e.Module == "" && e.Name == "::"
+	case *Global:
+		return e.Module == "List" && e.Name == "::"
+	}
+	return false
+}
+
+func unaryOp(op string, x Expr, t types.Type) Expr {
+	lit, ok := x.(*Lit)
+	if !ok {
+		return nil
+	}
+
+	switch op {
+	case "negate":
+		if lit.Kind == ast.Int || lit.Kind == ast.Float {
+			return arith("-", &Lit{ast.Int, "0", lit.Typ}, lit, t)
+		}
+	case "not":
+		if lit.Kind == ast.Bool {
+			return boolLit(lit.Value != "True")
+		}
+	}
+	return nil
+}
+
+func binaryOp(op string, x, y Expr, t types.Type) Expr {
+	if op == "++" {
+		return concat(x, y, t)
+	}
+
+	a, ok := x.(*Lit)
+	if !ok {
+		return nil
+	}
+
+	b, ok := y.(*Lit)
+	if !ok {
+		return nil
+	}
+
+	switch op {
+	case "+", "-", "*", "/", "//":
+		return arith(op, a, b, t)
+	case "==", "/=":
+		equal, ok := equalLits(a, b)
+		if !ok {
+			return nil
+		}
+		return boolLit(equal == (op == "=="))
+	case "&&", "||":
+		if a.Kind != ast.Bool || b.Kind != ast.Bool {
+			return nil
+		}
+
+		if op == "&&" {
+			return boolLit(a.Value == "True" && b.Value == "True")
+		}
+		return boolLit(a.Value == "True" || b.Value == "True")
+	}
+	return nil
+}
+
+// arith evaluates an arithmetic operation on number literals. Integer
+// results out of the range that all the backends can represent exactly
+// and float results that are not finite are not folded.
+func arith(op string, a, b *Lit, t types.Type) Expr {
+	if !isNumber(a) || !isNumber(b) {
+		return nil
+	}
+
+	if op == "/" || isFloat(t) || a.Kind == ast.Float || b.Kind == ast.Float {
+		if op == "//" {
+			return nil
+		}
+
+		x, err := strconv.ParseFloat(a.Value, 64)
+		if err != nil {
+			return nil
+		}
+
+		y, err := strconv.ParseFloat(b.Value, 64)
+		if err != nil {
+			return nil
+		}
+
+		var r float64
+		switch op {
+		case "+":
+			r = x + y
+		case "-":
+			r = x - y
+		case "*":
+			r = x * y
+		case "/":
+			r = x / y
+		}
+
+		if math.IsInf(r, 0) || math.IsNaN(r) {
+			return nil
+		}
+		return &Lit{ast.Float, strconv.FormatFloat(r, 'g', -1, 64), t}
+	}
+
+	x, ok := new(big.Int).SetString(a.Value, 10)
+	if !ok {
+		return nil
+	}
+
+	y, ok := new(big.Int).SetString(b.Value, 10)
+	if !ok {
+		return nil
+	}
+
+	var r = new(big.Int)
+	switch op {
+	case "+":
+		r.Add(x, y)
+	case "-":
+		r.Sub(x, y)
+	case "*":
+		r.Mul(x, y)
+	case "//":
+		// integer division by zero is zero in elm, which is left to the
+		// backends
+		if y.Sign() == 0 {
+			return nil
+		}
+		r.Quo(x, y)
+	}
+
+	if r.CmpAbs(big.NewInt(types.MaxSafeInt)) > 0 {
+		return nil
+	}
+	return &Lit{ast.Int, r.String(), t}
+}
+
+// concat evaluates the concatenation of two string literals or lists.
+// Strings with triple quotes are not folded, because their contents may
+// need to be escaped to be in single quotes.
+func concat(x, y Expr, t types.Type) Expr {
+	switch x := x.(type) {
+	case *Lit:
+		y, ok := y.(*Lit)
+		if !ok || !isSingleQuoted(x) || !isSingleQuoted(y) {
+			return nil
+		}
+
+		value := x.Value[:len(x.Value)-1] + y.Value[1:]
+		return &Lit{ast.String, value, t}
+	case *List:
+		y, ok := y.(*List)
+		if !ok {
+			return nil
+		}
+
+		var elems = make([]Expr, 0, len(x.Elems)+len(y.Elems))
+		elems = append(elems, x.Elems...)
+		return &List{append(elems, y.Elems...), t}
+	}
+	return nil
+}
+
+// equalLits reports whether two literals are equal. The second result is
+// false if the literals cannot be compared at compile time, such as
+// strings that are written differently but may have the same value.
+func equalLits(a, b *Lit) (equal, ok bool) {
+	if a.Kind != b.Kind {
+		return false, false
+	}
+
+	switch a.Kind {
+	case ast.Bool:
+		return a.Value == b.Value, true
+	case ast.Int:
+		x, ok := new(big.Int).SetString(a.Value, 10)
+		if !ok {
+			return false, false
+		}
+
+		y, ok := new(big.Int).SetString(b.Value, 10)
+		if !ok {
+			return false, false
+		}
+		return x.Cmp(y) == 0, true
+	case ast.Float:
+		x, err := strconv.ParseFloat(a.Value, 64)
+		if err != nil {
+			return false, false
+		}
+
+		y, err := strconv.ParseFloat(b.Value, 64)
+		if err != nil {
+			return false, false
+		}
+		return x == y, true
+	case ast.String, ast.Char:
+		if a.Value == b.Value {
+			return true, true
+		}
+
+		// without escapes, different sources are different values
+		if !strings.Contains(a.Value, `\`) && !strings.Contains(b.Value, `\`) {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// IsConstant reports whether the expression is a value that is known at
+// compile time.
+func IsConstant(e Expr) bool {
+	switch e := e.(type) {
+	case *Lit, *Ctor:
+		return true
+	case *App:
+		c, ok := e.Func.(*Ctor)
+		return ok && c.Arity == len(e.Args) && allConstant(e.Args)
+	case *Tuple:
+		return allConstant(e.Elems)
+	case *Record:
+		return constantFields(e.Fields)
+	case *List:
+		return allConstant(e.Elems)
+	}
+	return false
+}
+
+func constantFields(fields []*Field) bool {
+	for _, f := range fields {
+		if !IsConstant(f.Expr) {
+			return false
+		}
+	}
+	return true
+}
+
+func allConstant(exprs []Expr) bool {
+	for _, e := range exprs {
+		if !IsConstant(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSimple reports whether the expression is a constant that can be copied
+// to the places it is used without allocating more values.
+func isSimple(e Expr) bool {
+	switch e := e.(type) {
+	case *Lit:
+		return true
+	case *Ctor:
+		return e.Arity == 0
+	}
+	return false
+}
+
+// retype returns a copy of the simple constant with the type it has where
+// it is used.
+func retype(e Expr, t types.Type) Expr {
+	switch e := e.(type) {
+	case *Lit:
+		return &Lit{e.Kind, e.Value, t}
+	case *Ctor:
+		c := *e
+		c.Typ = t
+		return &c
+	}
+	return Copy(e)
+}
+
+func field(r *Record, name string) Expr {
+	return fieldOf(r.Fields, name)
+}
+
+func fieldOf(fields []*Field, name string) Expr {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Expr
+		}
+	}
+	return nil
+}
+
+func update(r *Record, fields []*Field, t types.Type) *Record {
+	var result = &Record{Typ: t}
+	for _, f := range r.Fields {
+		if v := fieldOf(fields, f.Name); v != nil {
+			result.Fields = append(result.Fields, &Field{f.Name, v})
+		} else {
+			result.Fields = append(result.Fields, f)
+		}
+	}
+	return result
+}
+
+func boolLit(b bool) *Lit {
+	if b {
+		return &Lit{ast.Bool, "True", types.Bool}
+	}
+	return &Lit{ast.Bool, "False", types.Bool}
+}
+
+func isNumber(lit *Lit) bool {
+	return lit.Kind == ast.Int || lit.Kind == ast.Float
+}
+
+func isFloat(t types.Type) bool {
+	n, ok := types.Prune(t).(*types.Named)
+	return ok && n.Module == "" && n.Name == "Float"
+}
+
+func isSingleQuoted(lit *Lit) bool {
+	return lit.Kind == ast.String && !strings.HasPrefix(lit.Value, `"""`)
+}
@@ -0,0 +1,40 @@
+package ir_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldConstants(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.FoldConstants(pkg)
+	main := pkg.Lookup("Main")
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"sign", `(\n$7 -> (let $8 = (Basics.== n$7 0) in (case $8 of True -> 0 | _ -> 1)))`},
+		{"twice", `(let double$20 = (\n$23 -> (Basics.+ n$23 n$23)) in (Basics.+ (double$20 1) 2))`},
+		{"first", "1"},
+		{"second", `"a"`},
+		{"constants", "{ x = 7, y = 0 }"},
+		{"greeting", `"hello, world"`},
+		{"primes", "[2, 3, 5, 7]"},
+		{"firstPrime", "3"},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, ir.String(main.Lookup(c.name).Expr), c.name)
+	}
+}
+
+func TestOptimizeNoFold(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.Optimize(pkg, ir.Options{NoFold: true})
+
+	require.Equal(t, "Main._25.0", ir.String(pkg.Lookup("Main").Lookup("first").Expr))
+}
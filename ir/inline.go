@@ -0,0 +1,259 @@
+package ir
+
+import "github.com/elm-tangram/tangram/token"
+
+// maxInlineSize is the greatest number of expressions in the body of the
+// functions that are inlined.
+const maxInlineSize = 12
+
+// Inline replaces the uses of the definitions that are aliases of other
+// globals, such as the operators implemented by natives, with the globals
+// they alias, and the applications of small functions to all their
+// parameters with the body of the functions. The arguments are bound to
+// the parameters in a let expression, so they are still evaluated once.
+//
+// Functions that refer to themselves or contain tail calls or lambdas are
+// never inlined, and the bodies that are inlined are not inlined again, so
+// mutually recursive functions do not grow forever.
+func Inline(pkg *Package) {
+	in := &inliner{defs: make(map[[2]string]*Def)}
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			in.defs[[2]string{m.Name, d.Name}] = d
+		}
+	}
+	in.lastID = lastID(pkg)
+
+	for _, m := range pkg.Modules {
+		in.module = m.Name
+		for _, d := range m.Defs {
+			in.self = d
+			d.Expr = Rewrite(d.Expr, in.inline)
+		}
+	}
+}
+
+type inliner struct {
+	defs map[[2]string]*Def
+	// self is the definition being rewritten, which is never inlined in
+	// itself.
+	self *Def
+	// module is the name of the module of the definition being rewritten.
+	module string
+	lastID int
+}
+
+func (in *inliner) inline(e Expr) Expr {
+	switch e := e.(type) {
+	case *Global:
+		if target := in.alias(e); target != nil {
+			return target
+		}
+	case *App:
+		g, ok := e.Func.(*Global)
+		if !ok {
+			return e
+		}
+
+		fn := in.inlinable(g)
+		if fn == nil || len(e.Args) < len(fn.Params) {
+			return e
+		}
+
+		// positions are only meaningful in the module of their source
+		// code, so the body of a function of another module takes the
+		// position of the application
+		var pos = token.NoPos
+		if g.Module != in.module {
+			pos = e.Pos
+		}
+
+		body := in.instantiate(fn, e.Args[:len(fn.Params)], pos)
+		if len(e.Args) == len(fn.Params) {
+			return body
+		}
+		return &App{body, e.Args[len(fn.Params):], e.Typ, e.Pos}
+	}
+	return e
+}
+
+// alias returns the global aliased by the definition of the given global,
+// following the aliases of aliases, or nil if it is not an alias. The
+// global keeps the type it has in the definition, because the natives get
+// their arity from it.
+func (in *inliner) alias(g *Global) *Global {
+	var target *Global
+	var seen = make(map[*Def]bool)
+	for {
+		d := in.defs[[2]string{g.Module, g.Name}]
+		if d == nil || d == in.self || seen[d] {
+			break
+		}
+		seen[d] = true
+
+		next, ok := d.Expr.(*Global)
+		if !ok {
+			break
+		}
+		g, target = next, next
+	}
+
+	if target == nil {
+		return nil
+	}
+	return Copy(target).(*Global)
+}
+
+// inlinable returns the function of the definition of the given global if
+// it can be inlined.
+func (in *inliner) inlinable(g *Global) *Lambda {
+	d := in.defs[[2]string{g.Module, g.Name}]
+	if d == nil || d == in.self {
+		return nil
+	}
+
+	fn, ok := d.Expr.(*Lambda)
+	if !ok {
+		return nil
+	}
+
+	var size int
+	var inlinable = true
+	Inspect(fn.Body, func(e Expr) bool {
+		size++
+		switch e := e.(type) {
+		case *Lambda, *TailCall:
+			inlinable = false
+		case *Global:
+			if e.Module == g.Module && e.Name == g.Name {
+				inlinable = false
+			}
+		}
+		return inlinable
+	})
+
+	if !inlinable || size > maxInlineSize {
+		return nil
+	}
+	return fn
+}
+
+// instantiate returns a copy of the body of the function with the given
+// arguments bound to its parameters. All the variables bound in the copy
+// are new, so the function can be inlined several times in the same scope.
+// If pos is not NoPos, it replaces the positions of the copy.
+func (in *inliner) instantiate(fn *Lambda, args []Expr, pos token.Pos) Expr {
+	var fresh = make(map[*Local]*Local)
+	var binds []*Bind
+	for i, p := range fn.Params {
+		if v, ok := args[i].(*Local); ok {
+			fresh[p] = v
+		} else {
+			fresh[p] = in.newLocal(p)
+			binds = append(binds, &Bind{fresh[p], args[i]})
+		}
+	}
+
+	body := Copy(fn.Body)
+	Inspect(body, func(e Expr) bool {
+		switch e := e.(type) {
+		case *Let:
+			for _, b := range e.Binds {
+				fresh[b.Var] = in.newLocal(b.Var)
+			}
+		case *Case:
+			for _, alt := range e.Alts {
+				for _, v := range alt.Vars {
+					fresh[v] = in.newLocal(v)
+				}
+			}
+		}
+		return true
+	})
+
+	body = Rewrite(body, func(e Expr) Expr {
+		switch e := e.(type) {
+		case *Local:
+			if v, ok := fresh[e]; ok {
+				return v
+			}
+		case *App:
+			if pos != token.NoPos {
+				e.Pos = pos
+			}
+		case *Call:
+			if pos != token.NoPos {
+				e.Pos = pos
+			}
+		case *Fail:
+			if pos != token.NoPos {
+				e.Pos = pos
+			}
+		case *Let:
+			for _, b := range e.Binds {
+				b.Var = fresh[b.Var]
+			}
+		case *Case:
+			if v, ok := fresh[e.Subject]; ok {
+				e.Subject = v
+			}
+
+			for _, alt := range e.Alts {
+				var vars = make([]*Local, len(alt.Vars))
+				for i, v := range alt.Vars {
+					vars[i] = fresh[v]
+				}
+				alt.Vars = vars
+			}
+		}
+		return e
+	})
+
+	if len(binds) == 0 {
+		return body
+	}
+	return &Let{binds, body}
+}
+
+// lastID returns the greatest ID of the variables bound in the package.
+// Variables are always bound before they are used, but they may not be
+// used at all, so only the binders need to be inspected.
+func lastID(pkg *Package) int {
+	var last int
+	bind := func(v *Local) {
+		if v.ID > last {
+			last = v.ID
+		}
+	}
+
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			Inspect(d.Expr, func(e Expr) bool {
+				switch e := e.(type) {
+				case *Lambda:
+					for _, p := range e.Params {
+						bind(p)
+					}
+				case *Let:
+					for _, b := range e.Binds {
+						bind(b.Var)
+					}
+				case *Case:
+					bind(e.Subject)
+					for _, alt := range e.Alts {
+						for _, v := range alt.Vars {
+							bind(v)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return last
+}
+
+func (in *inliner) newLocal(v *Local) *Local {
+	in.lastID++
+	return &Local{v.Name, in.lastID, v.Typ}
+}
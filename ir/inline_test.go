@@ -0,0 +1,54 @@
+package ir_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInline(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.Inline(pkg)
+	main := pkg.Lookup("Main")
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"add", `(\a$1 b$2 -> (Native.Basics.add a$1 b$2))`},
+		{"sign", `(\n$7 -> (let $8 = (Native.Basics.eq n$7 0) in (case $8 of True -> 0 | _ -> 1)))`},
+		{"count", `(\acc$35 list$36 -> (case list$36 of [] -> acc$35 | :: $38 rest$37 -> (Main.count (Native.Basics.add acc$35 1) rest$37)))`},
+		{"three", `(let a$44 = 1; b$45 = 2 in (Native.Basics.add a$44 b$45))`},
+		{"fallback", `(let default$46 = 0; maybe$47 = Maybe.Nothing in (case maybe$47 of Maybe.Just value$48 -> value$48 | Maybe.Nothing -> default$46))`},
+		{"both", `(\pair$29 -> (let $33 = pair$29.0; $34 = pair$29.1 in (case $33 of Maybe.Just a$30 -> (case $34 of Maybe.Just b$31 -> (Native.Basics.add a$30 b$31) | Maybe.Nothing -> (let a$32 = a$30 in a$32)) | Maybe.Nothing -> 0)))`},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, ir.String(main.Lookup(c.name).Expr), c.name)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.Optimize(pkg, ir.Options{})
+	main := pkg.Lookup("Main")
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"twice", `(let double$20 = (\n$23 -> (Native.Basics.add n$23 n$23)) in (Native.Basics.add (call double$20 1) 2))`},
+		{"count", `(\acc$35 list$36 -> (case list$36 of [] -> acc$35 | :: $38 rest$37 -> (tailcall (Native.Basics.add acc$35 1) rest$37)))`},
+		{"constants", "{ x = 7, y = 0 }"},
+		{"primes", "[2, 3, 5, 7]"},
+		{"firstPrime", "3"},
+		{"three", "3"},
+		{"fallback", "0"},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, ir.String(main.Lookup(c.name).Expr), c.name)
+	}
+}
@@ -0,0 +1,398 @@
+// Package ir defines the core intermediate representation of the compiler.
+// Type checked packages are lowered to the core IR, which is the input of
+// the optimizations and the code generation backends, instead of working
+// with the surface AST.
+//
+// The core IR is a small, explicitly typed language. All the syntactic sugar
+// of the surface language is removed during lowering:
+//
+//   - functions with arguments are lambdas and definitions are just names
+//     bound to expressions.
+//   - operators are applications of the functions that implement them.
+//   - if expressions are case expressions on booleans.
+//   - patterns are compiled to case expressions that only inspect one
+//     variable at a time, and only by its constructor or literal value, and
+//     to projections of tuple elements and record fields.
+//
+// Every expression has a type, which is the type the type checker inferred
+// for the expression it was lowered from.
+package ir
+
+import (
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Package is a lowered package.
+type Package struct {
+	// Modules of the package in resolution order, that is, every module is
+	// after the modules it imports.
+	Modules []*Module
+	// Points are the places of the source code whose evaluations are
+	// counted by the probes of an instrumented package, by the IDs of the
+	// probes.
+	Points []*Point
+}
+
+// Point is a place of the source code that is measured by a probe: a
+// function definition or a branch of a case or if expression.
+type Point struct {
+	// Module is the name of the module of the point, and Path the path to
+	// its file.
+	Module, Path string
+	// Name is the name of the function definition, or of the definition
+	// the branch is in. The names of the definitions of let expressions
+	// have the names of the ones they are in as prefixes, such as
+	// `update.step`.
+	Name string
+	// Branch reports whether the point is a branch instead of a function.
+	Branch bool
+	// Pos is the position of the definition or the branch, and Expr is the
+	// position of the case or if expression of a branch.
+	Pos, Expr token.Pos
+}
+
+// Module is a lowered module.
+type Module struct {
+	// Name of the module.
+	Name string
+	// Path to the file of the module.
+	Path string
+	// Defs are all the top-level definitions of the module, in the order
+	// they were declared.
+	Defs []*Def
+	// Ports are the ports declared by the module.
+	Ports []*Port
+}
+
+// Def is a top-level definition.
+type Def struct {
+	// Name of the definition.
+	Name string
+	// Type of the definition.
+	Type types.Type
+	// Expr is the value of the definition.
+	Expr Expr
+	// Exposed reports whether the definition is exposed by its module.
+	Exposed bool
+	// Pos is the position of the definition in the source code of its
+	// module.
+	Pos token.Pos
+}
+
+// Port is a port declared in a module. Ports are referred to as globals
+// in the module that declares them.
+type Port struct {
+	// Name of the port.
+	Name string
+	// Type of the port.
+	Type types.Type
+}
+
+// Expr is an expression of the core IR.
+type Expr interface {
+	// Type returns the type of the expression.
+	Type() types.Type
+	isExpr()
+}
+
+// Local is a variable bound by a lambda, a let expression or a case
+// alternative. Locals are compared by identity, so there is only one Local
+// for every bound variable and it is shared by all its uses.
+type Local struct {
+	// Name of the variable in the source code. Variables introduced during
+	// lowering have no name.
+	Name string
+	// ID of the variable, which is unique in the package.
+	ID int
+	// Typ is the type of the variable.
+	Typ types.Type
+}
+
+// Global is a reference to a top-level definition, a port or a value of a
+// native module.
+type Global struct {
+	// Module where the value is defined. Values of native modules have the
+	// name of the native module, such as `Native.List`.
+	Module string
+	// Name of the value.
+	Name string
+	// Typ is the type of the value where it is used.
+	Typ types.Type
+}
+
+// Lit is a literal value.
+type Lit struct {
+	// Kind of the literal.
+	Kind ast.BasicLitType
+	// Value of the literal as it was written in the source code. String
+	// and character literals include their quotes and booleans are either
+	// `True` or `False`.
+	Value string
+	// Typ is the type of the literal.
+	Typ types.Type
+}
+
+// Ctor is an union type constructor. Constructors are functions with as
+// many arguments as the constructor has, or values if they have none. The
+// constructors of lists are `[]` and `::`, which have no module.
+type Ctor struct {
+	// Module where the union type is declared.
+	Module string
+	// Union is the name of the union type.
+	Union string
+	// Name of the constructor.
+	Name string
+	// Tag is the index of the constructor in the union type declaration.
+	Tag int
+	// Arity is the number of arguments of the constructor.
+	Arity int
+	// Span is the number of constructors of the union type.
+	Span int
+	// Typ is the type of the constructor where it is used.
+	Typ types.Type
+}
+
+// Lambda is an anonymous function with one or more parameters.
+type Lambda struct {
+	// Params of the function.
+	Params []*Local
+	// Body of the function.
+	Body Expr
+	// Typ is the type of the function.
+	Typ types.Type
+	// Pos is the position of the function in the source code.
+	Pos token.Pos
+}
+
+// App is the application of a function to one or more arguments. The
+// function may take more or less arguments than the ones given.
+type App struct {
+	// Func is the function being applied.
+	Func Expr
+	// Args are the arguments the function is applied to.
+	Args []Expr
+	// Typ is the type of the result.
+	Typ types.Type
+	// Pos is the position of the application in the source code. The
+	// position of an operator is the position of the operator itself.
+	Pos token.Pos
+}
+
+// Call is the application of a known function to exactly as many
+// arguments as its parameters. The function is a global or a local bound
+// to a lambda, so the backends can call it directly instead of applying it
+// one argument at a time. Applications are only replaced with calls while
+// optimizing.
+type Call struct {
+	// Func is the function being called.
+	Func Expr
+	// Args are the arguments of the call.
+	Args []Expr
+	// Typ is the type of the result.
+	Typ types.Type
+	// Pos is the position of the application replaced by the call.
+	Pos token.Pos
+}
+
+// Let binds some variables in the scope of an expression. All the bindings
+// are in scope in all the bound expressions, so they can be mutually
+// recursive.
+type Let struct {
+	// Binds are the bound variables.
+	Binds []*Bind
+	// Body is the expression where the variables are in scope.
+	Body Expr
+}
+
+// Bind is a variable bound to an expression.
+type Bind struct {
+	// Var is the variable being bound.
+	Var *Local
+	// Expr is the value of the variable.
+	Expr Expr
+}
+
+// Case inspects the value of a variable and evaluates the alternative that
+// matches it or the default expression if none does.
+type Case struct {
+	// Subject is the variable being inspected.
+	Subject *Local
+	// Alts are the alternatives. All of them match constructors or all of
+	// them match literals.
+	Alts []*Alt
+	// Default is evaluated if no alternative matches. It is nil if the
+	// alternatives cover all the possible values.
+	Default Expr
+	// Typ is the type of the result.
+	Typ types.Type
+}
+
+// Alt is an alternative of a case expression.
+type Alt struct {
+	// Ctor is the constructor matched by the alternative, if it matches a
+	// constructor.
+	Ctor *Ctor
+	// Lit is the literal matched by the alternative, if it matches a
+	// literal.
+	Lit *Lit
+	// Vars are bound to the arguments of the constructor. There is one
+	// for every argument of the constructor.
+	Vars []*Local
+	// Body is evaluated if the alternative matches.
+	Body Expr
+}
+
+// Tuple creates a tuple. A tuple with no elements is the unit value.
+type Tuple struct {
+	// Elems are the elements of the tuple.
+	Elems []Expr
+	// Typ is the type of the tuple.
+	Typ types.Type
+}
+
+// Index is the projection of an element of a tuple.
+type Index struct {
+	// Expr is the tuple.
+	Expr Expr
+	// Index of the element.
+	Index int
+	// Typ is the type of the element.
+	Typ types.Type
+}
+
+// Record creates a record.
+type Record struct {
+	// Fields of the record.
+	Fields []*Field
+	// Typ is the type of the record.
+	Typ types.Type
+}
+
+// Field is the value assigned to a record field.
+type Field struct {
+	// Name of the field.
+	Name string
+	// Expr is the value of the field.
+	Expr Expr
+}
+
+// Access is the projection of a record field.
+type Access struct {
+	// Expr is the record.
+	Expr Expr
+	// Field is the name of the field.
+	Field string
+	// Typ is the type of the field.
+	Typ types.Type
+}
+
+// Update creates a copy of a record with some of its fields updated.
+type Update struct {
+	// Record being updated.
+	Record Expr
+	// Fields are the updated fields.
+	Fields []*Field
+	// Typ is the type of the record.
+	Typ types.Type
+}
+
+// List creates a list with the given elements.
+type List struct {
+	// Elems are the elements of the list.
+	Elems []Expr
+	// Typ is the type of the list.
+	Typ types.Type
+}
+
+// TailCall is a call of the function it is in, with all its parameters,
+// in tail position. Calls are only replaced with tail calls while
+// optimizing, and the backends compile the functions that contain them to
+// loops.
+type TailCall struct {
+	// Args are the new values of the parameters of the function.
+	Args []Expr
+	// Typ is the type of the result.
+	Typ types.Type
+}
+
+// Probe counts the evaluations of an expression, the body of the function
+// or the branch of a point, before evaluating it.
+type Probe struct {
+	// ID is the index of the point of the probe in the package.
+	ID int
+	// Expr is the expression whose evaluations are counted.
+	Expr Expr
+}
+
+// Fail stops the program. It is evaluated when no pattern matches a value.
+type Fail struct {
+	// Module where the failure is.
+	Module string
+	// Msg describes the failure.
+	Msg string
+	// Typ is the type of the expression the failure replaces.
+	Typ types.Type
+	// Pos is the position of the expression that fails in the source code.
+	Pos token.Pos
+}
+
+func (*Local) isExpr()    {}
+func (*Global) isExpr()   {}
+func (*Lit) isExpr()      {}
+func (*Ctor) isExpr()     {}
+func (*Lambda) isExpr()   {}
+func (*App) isExpr()      {}
+func (*Call) isExpr()     {}
+func (*Let) isExpr()      {}
+func (*Case) isExpr()     {}
+func (*Tuple) isExpr()    {}
+func (*Index) isExpr()    {}
+func (*Record) isExpr()   {}
+func (*Access) isExpr()   {}
+func (*Update) isExpr()   {}
+func (*List) isExpr()     {}
+func (*TailCall) isExpr() {}
+func (*Probe) isExpr()    {}
+func (*Fail) isExpr()     {}
+
+func (e *Local) Type() types.Type    { return e.Typ }
+func (e *Global) Type() types.Type   { return e.Typ }
+func (e *Lit) Type() types.Type      { return e.Typ }
+func (e *Ctor) Type() types.Type     { return e.Typ }
+func (e *Lambda) Type() types.Type   { return e.Typ }
+func (e *Call) Type() types.Type     { return e.Typ }
+func (e *App) Type() types.Type      { return e.Typ }
+func (e *Let) Type() types.Type      { return e.Body.Type() }
+func (e *Case) Type() types.Type     { return e.Typ }
+func (e *Tuple) Type() types.Type    { return e.Typ }
+func (e *Index) Type() types.Type    { return e.Typ }
+func (e *Record) Type() types.Type   { return e.Typ }
+func (e *Access) Type() types.Type   { return e.Typ }
+func (e *Update) Type() types.Type   { return e.Typ }
+func (e *List) Type() types.Type     { return e.Typ }
+func (e *TailCall) Type() types.Type { return e.Typ }
+func (e *Probe) Type() types.Type    { return e.Expr.Type() }
+func (e *Fail) Type() types.Type     { return e.Typ }
+
+// Lookup returns the module with the given name, or nil if there is none.
+func (p *Package) Lookup(name string) *Module {
+	for _, m := range p.Modules {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Lookup returns the definition with the given name, or nil if there is
+// none.
+func (m *Module) Lookup(name string) *Def {
+	for _, d := range m.Defs {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,596 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Lower lowers the given package to the core IR. The package must have
+// been type checked without errors and info must contain all the type
+// information recorded while checking it, that is, none of its maps can be
+// nil.
+func Lower(pkg *ast.Package, info *types.Info) *Package {
+	return lower(pkg, info, false)
+}
+
+// Instrument is like Lower, but the body of every function definition and
+// every branch of the case and if expressions is wrapped in a probe, which
+// counts how many times it is evaluated to measure the coverage of the
+// tests. The points of the probes are in the package.
+func Instrument(pkg *ast.Package, info *types.Info) *Package {
+	return lower(pkg, info, true)
+}
+
+func lower(pkg *ast.Package, info *types.Info, instrument bool) *Package {
+	l := &lowerer{
+		info:       info,
+		ctors:      make(map[*ast.Constructor]*Ctor),
+		locals:     make(map[ast.Node]*Local),
+		instrument: instrument,
+	}
+
+	for _, name := range pkg.Order {
+		l.declareCtors(pkg.Modules[name])
+	}
+
+	var p = new(Package)
+	for _, name := range pkg.Order {
+		p.Modules = append(p.Modules, l.lowerModule(pkg.Modules[name]))
+	}
+	p.Points = l.points
+	return p
+}
+
+type lowerer struct {
+	info *types.Info
+	mod  *ast.Module
+
+	// ctors contains the untyped constructor of every constructor
+	// declaration.
+	ctors map[*ast.Constructor]*Ctor
+	// locals contains the local variables by the node that binds them,
+	// which is the node the objects of the variables point to.
+	locals map[ast.Node]*Local
+	lastID int
+
+	// instrument reports whether the probes of the points are added, and
+	// defs are the names of the definitions being lowered, from the
+	// outermost.
+	instrument bool
+	points     []*Point
+	defs       []string
+}
+
+func (l *lowerer) declareCtors(mod *ast.Module) {
+	for _, decl := range mod.Decls {
+		union, ok := decl.(*ast.UnionDecl)
+		if !ok {
+			continue
+		}
+
+		for i, ctor := range union.Ctors {
+			l.ctors[ctor] = &Ctor{
+				Module: mod.Name,
+				Union:  union.Name.Name,
+				Name:   ctor.Name.Name,
+				Tag:    i,
+				Arity:  len(ctor.Args),
+				Span:   len(union.Ctors),
+			}
+		}
+	}
+}
+
+func (l *lowerer) lowerModule(mod *ast.Module) *Module {
+	l.mod = mod
+	m := &Module{Name: mod.Name, Path: mod.Path}
+	for _, decl := range mod.Decls {
+		// top-level variables are globals, so the locals of a declaration
+		// are never visible in the rest of them
+		l.locals = make(map[ast.Node]*Local)
+
+		switch decl := decl.(type) {
+		case *ast.Definition:
+			m.Defs = append(m.Defs, &Def{
+				Name:    decl.Name.Name,
+				Type:    l.defType(decl.Name),
+				Expr:    l.definition(decl),
+				Exposed: l.isExposed(decl.Name.Name),
+				Pos:     decl.Pos(),
+			})
+		case *ast.DestructuringAssignment:
+			m.Defs = append(m.Defs, l.destructuring(decl)...)
+		case *ast.PortDecl:
+			m.Ports = append(m.Ports, &Port{decl.Name.Name, l.defType(decl.Name)})
+		}
+	}
+	return m
+}
+
+// destructuring lowers a top-level destructuring assignment to a
+// definition with the destructured value and a definition for every
+// variable of the pattern.
+func (l *lowerer) destructuring(decl *ast.DestructuringAssignment) []*Def {
+	value := &Def{
+		Name: fmt.Sprintf("_%d", l.nextID()),
+		Type: l.info.TypeOf(decl.Expr),
+		Expr: l.expr(decl.Expr),
+		Pos:  decl.Pos(),
+	}
+
+	var defs = []*Def{value}
+	l.declare(decl.Pattern)
+	for _, b := range l.bindPattern(&Global{l.mod.Name, value.Name, value.Type}, decl.Pattern) {
+		defs = append(defs, &Def{b.Var.Name, b.Var.Typ, b.Expr, l.isExposed(b.Var.Name), decl.Pos()})
+	}
+	return defs
+}
+
+func (l *lowerer) definition(def *ast.Definition) Expr {
+	l.defs = append(l.defs, def.Name.Name)
+	defer func() { l.defs = l.defs[:len(l.defs)-1] }()
+
+	if len(def.Args) == 0 {
+		return l.expr(def.Body)
+	}
+
+	fn := l.function(def.Args, def.Body, l.defType(def.Name), def.Pos())
+	if lambda, ok := fn.(*Lambda); ok {
+		lambda.Body = l.probe(lambda.Body, false, def.Pos(), token.NoPos)
+	}
+	return fn
+}
+
+// probe returns the expression wrapped in the probe of a new point if the
+// package is instrumented, or the expression itself otherwise.
+func (l *lowerer) probe(e Expr, branch bool, pos, expr token.Pos) Expr {
+	if !l.instrument {
+		return e
+	}
+
+	l.points = append(l.points, &Point{
+		Module: l.mod.Name,
+		Path:   l.mod.Path,
+		Name:   strings.Join(l.defs, "."),
+		Branch: branch,
+		Pos:    pos,
+		Expr:   expr,
+	})
+	return &Probe{len(l.points) - 1, e}
+}
+
+// function lowers a function with the given arguments and body to a
+// lambda. Arguments that are not variables are matched against the
+// parameters of the lambda.
+func (l *lowerer) function(args []ast.Pattern, body ast.Expr, t types.Type, pos token.Pos) Expr {
+	var params = make([]*Local, len(args))
+	var rest = t
+	for i, arg := range args {
+		var param types.Type
+		param, rest = funcParts(rest)
+
+		l.declare(arg)
+		if v, ok := arg.(*ast.VarPattern); ok {
+			params[i] = l.locals[v]
+		} else {
+			params[i] = l.newLocal("", param)
+		}
+	}
+
+	result := l.expr(body)
+	fail := &Fail{l.mod.Name, "function arguments did not match", result.Type(), pos}
+	return &Lambda{params, l.compileMatch(params, [][]ast.Pattern{args}, []Expr{result}, fail), t, pos}
+}
+
+func (l *lowerer) expr(e ast.Expr) Expr {
+	t := l.info.TypeOf(e)
+	switch e := e.(type) {
+	case *ast.Ident:
+		return l.ident(e, t)
+	case *ast.SelectorExpr:
+		return l.selector(e, t)
+	case *ast.BasicLit:
+		return &Lit{e.Type, e.Value, t}
+	case *ast.TupleLit:
+		return &Tuple{l.exprs(e.Elems), t}
+	case *ast.ListLit:
+		return &List{l.exprs(e.Elems), t}
+	case *ast.RecordLit:
+		return &Record{l.fields(e.Fields), t}
+	case *ast.RecordUpdate:
+		return &Update{l.expr(e.Record), l.fields(e.Fields), t}
+	case *ast.FuncApp:
+		return &App{l.expr(e.Func), l.exprs(e.Args), t, e.Pos()}
+	case *ast.BinaryOp:
+		return &App{l.expr(e.Op), []Expr{l.expr(e.Lhs), l.expr(e.Rhs)}, t, e.Op.Pos()}
+	case *ast.UnaryOp:
+		// the only unary operator is the negation
+		negate := &Global{"Basics", "negate", types.NewFunc(t, t)}
+		return &App{negate, []Expr{l.expr(e.Expr)}, t, e.Pos()}
+	case *ast.IfExpr:
+		return l.caseOn(l.expr(e.Cond), func(cond *Local) Expr {
+			return &Case{
+				Subject: cond,
+				Alts: []*Alt{
+					{Lit: &Lit{ast.Bool, "True", types.Bool}, Body: l.probe(l.expr(e.ThenExpr), true, e.ThenExpr.Pos(), e.Pos())},
+				},
+				Default: l.probe(l.expr(e.ElseExpr), true, e.ElseExpr.Pos(), e.Pos()),
+				Typ:     t,
+			}
+		})
+	case *ast.CaseExpr:
+		return l.caseExpr(e, t)
+	case *ast.LetExpr:
+		return l.letExpr(e)
+	case *ast.Lambda:
+		return l.function(e.Args, e.Expr, t, e.Pos())
+	case *ast.AccessorExpr:
+		arg, result := funcParts(t)
+		record := l.newLocal("", arg)
+		return &Lambda{
+			[]*Local{record},
+			&Access{record, e.Field.Name, result},
+			t,
+			e.Pos(),
+		}
+	case *ast.TupleCtor:
+		var params = make([]*Local, e.Elems)
+		var elems = make([]Expr, e.Elems)
+		var rest = t
+		for i := range params {
+			var param types.Type
+			param, rest = funcParts(rest)
+			params[i] = l.newLocal("", param)
+			elems[i] = params[i]
+		}
+		return &Lambda{params, &Tuple{elems, rest}, t, e.Pos()}
+	case *ast.ParensExpr:
+		return l.expr(e.Expr)
+	}
+
+	return &Fail{l.mod.Name, fmt.Sprintf("unsupported expression %T", e), t, e.Pos()}
+}
+
+func (l *lowerer) exprs(exprs []ast.Expr) []Expr {
+	var result = make([]Expr, len(exprs))
+	for i, e := range exprs {
+		result[i] = l.expr(e)
+	}
+	return result
+}
+
+func (l *lowerer) fields(fields []*ast.FieldAssign) []*Field {
+	var result = make([]*Field, len(fields))
+	for i, f := range fields {
+		result[i] = &Field{f.Field.Name, l.expr(f.Expr)}
+	}
+	return result
+}
+
+func (l *lowerer) ident(id *ast.Ident, t types.Type) Expr {
+	if id.Obj == nil && (id.Name == "True" || id.Name == "False") {
+		return &Lit{ast.Bool, id.Name, types.Bool}
+	}
+
+	if id.Obj != nil {
+		if v, ok := l.locals[id.Obj.Node]; ok {
+			return v
+		}
+	}
+
+	obj := l.info.Uses[id]
+	if obj == nil {
+		return &Fail{l.mod.Name, fmt.Sprintf("undefined name %s", id.Name), t, id.Pos()}
+	}
+
+	if obj.Kind == ast.Ctor {
+		ctor := l.ctor(obj)
+		ctor.Typ = t
+		return ctor
+	}
+	return &Global{obj.Module, obj.Name, t}
+}
+
+// selector lowers a qualified name, a record field access or both, such as
+// `Foo.bar.baz`.
+func (l *lowerer) selector(e *ast.SelectorExpr, t types.Type) Expr {
+	idents := selectorIdents(e)
+
+	var i int
+	for i < len(idents)-1 && isUpper(idents[i].Name) {
+		i++
+	}
+
+	var fields = idents[i+1:]
+	var valueType = t
+	if len(fields) > 0 {
+		valueType = l.info.TypeOf(idents[i])
+	}
+
+	var result Expr
+	if module := joinIdents(idents[:i]); strings.HasPrefix(module, "Native.") {
+		result = &Global{module, idents[i].Name, valueType}
+	} else {
+		result = l.ident(idents[i], valueType)
+	}
+
+	for j, f := range fields {
+		var ft = t
+		if j < len(fields)-1 {
+			ft = fieldType(result.Type(), f.Name)
+		}
+		result = &Access{result, f.Name, ft}
+	}
+	return result
+}
+
+func (l *lowerer) caseExpr(e *ast.CaseExpr, t types.Type) Expr {
+	return l.caseOn(l.expr(e.Expr), func(subject *Local) Expr {
+		var patterns = make([][]ast.Pattern, len(e.Branches))
+		var bodies = make([]Expr, len(e.Branches))
+		for i, b := range e.Branches {
+			l.declare(b.Pattern)
+			patterns[i] = []ast.Pattern{b.Pattern}
+			bodies[i] = l.probe(l.expr(b.Expr), true, b.Pattern.Pos(), e.Pos())
+		}
+
+		fail := &Fail{l.mod.Name, "case expression is not exhaustive", t, e.Pos()}
+		return l.compileMatch([]*Local{subject}, patterns, bodies, fail)
+	})
+}
+
+func (l *lowerer) letExpr(e *ast.LetExpr) Expr {
+	for _, decl := range e.Decls {
+		switch decl := decl.(type) {
+		case *ast.Definition:
+			l.locals[decl.Name] = l.newLocal(decl.Name.Name, l.defType(decl.Name))
+		case *ast.DestructuringAssignment:
+			l.declare(decl.Pattern)
+		}
+	}
+
+	var binds []*Bind
+	for _, decl := range e.Decls {
+		switch decl := decl.(type) {
+		case *ast.Definition:
+			binds = append(binds, &Bind{l.locals[decl.Name], l.definition(decl)})
+		case *ast.DestructuringAssignment:
+			value := l.newLocal("", l.info.TypeOf(decl.Expr))
+			binds = append(binds, &Bind{value, l.expr(decl.Expr)})
+			binds = append(binds, l.bindPattern(value, decl.Pattern)...)
+		}
+	}
+
+	return &Let{binds, l.expr(e.Body)}
+}
+
+// caseOn calls fn with a variable bound to the value of the given
+// expression, so it can be inspected by a case expression.
+func (l *lowerer) caseOn(e Expr, fn func(*Local) Expr) Expr {
+	if v, ok := e.(*Local); ok {
+		return fn(v)
+	}
+
+	v := l.newLocal("", e.Type())
+	return &Let{[]*Bind{{v, e}}, fn(v)}
+}
+
+// declare creates the local variables of all the variables bound by the
+// pattern.
+func (l *lowerer) declare(pattern ast.Pattern) {
+	switch p := pattern.(type) {
+	case *ast.VarPattern:
+		l.locals[p] = l.newLocal(p.Name.Name, l.defType(p.Name))
+	case *ast.AliasPattern:
+		l.locals[p] = l.newLocal(p.Name.Name, l.defType(p.Name))
+		l.declare(p.Pattern)
+	case *ast.CtorPattern:
+		for _, arg := range p.Args {
+			l.declare(arg)
+		}
+	case *ast.TuplePattern:
+		for _, el := range p.Elems {
+			l.declare(el)
+		}
+	case *ast.RecordPattern:
+		for _, f := range p.Fields {
+			l.declare(f)
+		}
+	case *ast.ListPattern:
+		for _, el := range p.Elems {
+			l.declare(el)
+		}
+	}
+}
+
+// bindPattern returns the bindings of the variables of a pattern that
+// always matches, such as the ones of destructuring assignments, to the
+// parts of the given value they are bound to.
+func (l *lowerer) bindPattern(value Expr, pattern ast.Pattern) []*Bind {
+	switch p := pattern.(type) {
+	case *ast.VarPattern:
+		return []*Bind{{l.locals[p], value}}
+	case *ast.AliasPattern:
+		return append(
+			[]*Bind{{l.locals[p], value}},
+			l.bindPattern(Copy(value), p.Pattern)...,
+		)
+	case *ast.TuplePattern:
+		var binds []*Bind
+		for i, el := range p.Elems {
+			elem := &Index{Copy(value), i, elemType(value.Type(), i)}
+			binds = append(binds, l.bindPattern(elem, el)...)
+		}
+		return binds
+	case *ast.RecordPattern:
+		var binds []*Bind
+		for _, f := range p.Fields {
+			if v, ok := f.(*ast.VarPattern); ok {
+				field := &Access{Copy(value), v.Name.Name, l.locals[v].Typ}
+				binds = append(binds, &Bind{l.locals[v], field})
+			}
+		}
+		return binds
+	}
+	return nil
+}
+
+// ctor returns a new untyped constructor for the given object.
+func (l *lowerer) ctor(obj *types.Object) *Ctor {
+	ctor := *l.ctors[obj.Node.(*ast.Constructor)]
+	return &ctor
+}
+
+func (l *lowerer) defType(id *ast.Ident) types.Type {
+	if obj := l.info.Defs[id]; obj != nil {
+		return obj.Type
+	}
+	return nil
+}
+
+func (l *lowerer) isExposed(name string) bool {
+	return l.mod.Scope.LookupExposed(name, ast.Var) != nil
+}
+
+func (l *lowerer) newLocal(name string, t types.Type) *Local {
+	return &Local{name, l.nextID(), t}
+}
+
+func (l *lowerer) nextID() int {
+	l.lastID++
+	return l.lastID
+}
+
+func consCtor(list types.Type) *Ctor {
+	return &Ctor{
+		Union: "List",
+		Name:  "::",
+		Tag:   1,
+		Arity: 2,
+		Span:  2,
+		Typ:   types.NewFunc(listElem(list), list, list),
+	}
+}
+
+func nilCtor(list types.Type) *Ctor {
+	return &Ctor{Union: "List", Name: "[]", Span: 2, Typ: list}
+}
+
+// ctorArgs returns the types of the arguments of a constructor when it
+// builds a value of the given type.
+func ctorArgs(obj *types.Object, t types.Type) []types.Type {
+	var args []types.Type
+	ctor := obj.Type
+	for {
+		fn, ok := types.Prune(ctor).(*types.Func)
+		if !ok {
+			break
+		}
+
+		args = append(args, fn.Arg)
+		ctor = fn.Result
+	}
+
+	// the result of a constructor is the union type applied to the type
+	// variables of its declaration
+	var subst = make(map[*types.Var]types.Type)
+	result, ok := types.Prune(ctor).(*types.Named)
+	named, ok2 := types.Prune(t).(*types.Named)
+	if ok && ok2 && len(result.Args) == len(named.Args) {
+		for i, arg := range result.Args {
+			if v, ok := arg.(*types.Var); ok {
+				subst[v] = named.Args[i]
+			}
+		}
+	}
+
+	for i, arg := range args {
+		args[i] = types.Substitute(arg, subst)
+	}
+	return args
+}
+
+func funcParts(t types.Type) (arg, result types.Type) {
+	if fn, ok := types.Prune(t).(*types.Func); ok {
+		return fn.Arg, fn.Result
+	}
+	return nil, nil
+}
+
+func elemType(t types.Type, i int) types.Type {
+	if tuple, ok := types.Prune(t).(*types.Tuple); ok && i < len(tuple.Elems) {
+		return tuple.Elems[i]
+	}
+	return nil
+}
+
+func fieldType(t types.Type, name string) types.Type {
+	for t != nil {
+		record, ok := types.Prune(t).(*types.Record)
+		if !ok {
+			return nil
+		}
+
+		if f, ok := record.Fields[name]; ok {
+			return f
+		}
+		t = record.Ext
+	}
+	return nil
+}
+
+func listElem(t types.Type) types.Type {
+	if list, ok := types.Prune(t).(*types.Named); ok && list.Name == "List" && len(list.Args) == 1 {
+		return list.Args[0]
+	}
+	return nil
+}
+
+// selectorIdents returns all the identifiers of a selector in order.
+func selectorIdents(e *ast.SelectorExpr) []*ast.Ident {
+	var idents []*ast.Ident
+	var expr ast.Expr = e
+	for expr != nil {
+		switch sel := expr.(type) {
+		case *ast.SelectorExpr:
+			idents = append(idents, sel.Selector)
+			expr = sel.Expr
+		case *ast.Ident:
+			idents = append(idents, sel)
+			expr = nil
+		default:
+			expr = nil
+		}
+	}
+	return idents
+}
+
+func lastIdent(e ast.Expr) *ast.Ident {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		idents := selectorIdents(e)
+		return idents[len(idents)-1]
+	}
+	return &ast.Ident{Name: "_"}
+}
+
+func joinIdents(idents []*ast.Ident) string {
+	var names = make([]string, len(idents))
+	for i, id := range idents {
+		names[i] = id.Name
+	}
+	return strings.Join(names, ".")
+}
+
+func isUpper(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,166 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func lowerTestdata(t *testing.T) *ir.Package {
+	pkg, err := parser.Parse("_testdata/lower/src/Main.elm", parser.FullParse)
+	require.NoError(t, err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(t, conf.Check(pkg, info))
+	return ir.Lower(pkg, info)
+}
+
+func TestLower(t *testing.T) {
+	pkg := lowerTestdata(t)
+	main := pkg.Lookup("Main")
+	require.NotNil(t, main)
+
+	cases := []struct {
+		name     string
+		typ      string
+		expected string
+	}{
+		{"origin", "{ x : Int, y : Int }", "{ x = 0, y = 0 }"},
+		{"add", "Int -> Int -> Int", `(\a$1 b$2 -> (Basics.+ a$1 b$2))`},
+		{"neg", "number -> number", `(\n$3 -> (Basics.negate n$3))`},
+		{
+			"withDefault",
+			"a -> Maybe.Maybe a -> a",
+			`(\default$4 maybe$5 -> (case maybe$5 of Maybe.Just value$6 -> value$6 | Maybe.Nothing -> default$4))`,
+		},
+		{"sign", "number -> number1", `(\n$7 -> (let $8 = (Basics.== n$7 0) in (case $8 of True -> 0 | _ -> 1)))`},
+		{
+			"firstTwo",
+			"List number -> number",
+			`(\list$9 -> (case list$9 of :: a$10 $13 -> (case $13 of :: b$11 $14 -> (case $14 of [] -> (Basics.+ a$10 b$11) | _ -> (let a$12 = a$10 in a$12)) | _ -> (let a$12 = a$10 in a$12)) | [] -> 0))`,
+		},
+		{"swap", "(a, b) -> (b, a)", `(\$17 -> (let a$15 = $17.0; b$16 = $17.1 in (b$16, a$15)))`},
+		{"getX", "{ a | x : b } -> b", `(\$18 -> $18.x)`},
+		{"moveX", "{ a | x : number } -> { a | x : number }", `(\p$19 -> { p$19 | x = (Basics.+ p$19.x 1) })`},
+		{
+			"twice",
+			"number",
+			`(let double$20 = (\n$23 -> (Basics.+ n$23 n$23)); $24 = (1, 2); a$21 = $24.0; b$22 = $24.1 in (Basics.+ (double$20 a$21) b$22))`,
+		},
+		{"_25", "(Int, String)", `(1, "a")`},
+		{"first", "Int", "Main._25.0"},
+		{"second", "String", "Main._25.1"},
+		{"isOne", "number -> Bool", `(\n$28 -> (case n$28 of 1 -> True | _ -> False))`},
+		{
+			"both",
+			"(Maybe.Maybe number, Maybe.Maybe number) -> number",
+			`(\pair$29 -> (let $33 = pair$29.0; $34 = pair$29.1 in (case $33 of Maybe.Just a$30 -> (case $34 of Maybe.Just b$31 -> (Basics.+ a$30 b$31) | Maybe.Nothing -> (let a$32 = a$30 in a$32)) | Maybe.Nothing -> 0)))`,
+		},
+	}
+
+	for _, c := range cases {
+		def := main.Lookup(c.name)
+		require.NotNil(t, def, c.name)
+		require.Equal(t, c.typ, types.TypeString(def.Type), c.name)
+		require.Equal(t, c.expected, ir.String(def.Expr), c.name)
+	}
+}
+
+func TestLowerCase(t *testing.T) {
+	require := require.New(t)
+	main := lowerTestdata(t).Lookup("Main")
+
+	fn := main.Lookup("withDefault").Expr.(*ir.Lambda)
+	require.Len(fn.Params, 2)
+	require.Equal("Maybe.Maybe a", types.TypeString(fn.Params[1].Type()))
+
+	just := fn.Body.(*ir.Case)
+	require.Equal(fn.Params[1], just.Subject)
+	require.Equal("a", types.TypeString(just.Alts[0].Vars[0].Type()))
+
+	ctor := just.Alts[0].Ctor
+	require.Equal("Maybe", ctor.Module)
+	require.Equal("Maybe", ctor.Union)
+	require.Equal(0, ctor.Tag)
+	require.Equal(1, ctor.Arity)
+	require.Equal(2, ctor.Span)
+	require.Equal("a -> Maybe.Maybe a", types.TypeString(ctor.Type()))
+
+	nothing := just.Alts[1].Ctor
+	require.Equal(1, nothing.Tag)
+	require.Equal(0, nothing.Arity)
+	require.Nil(just.Default, "the case expression is exhaustive")
+
+	// every value is only tested once, even if it is matched by several
+	// branches
+	for name, tested := range map[string]int{"firstTwo": 3, "both": 2} {
+		var subjects = make(map[*ir.Local]int)
+		ir.Inspect(main.Lookup(name).Expr, func(e ir.Expr) bool {
+			if c, ok := e.(*ir.Case); ok {
+				subjects[c.Subject]++
+			}
+			return true
+		})
+
+		require.Len(subjects, tested, name)
+		for v, n := range subjects {
+			require.Equal(1, n, "%s: %s", name, v)
+		}
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/lower/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(conf.Check(pkg, info))
+
+	instrumented := ir.Instrument(pkg, info)
+	main := instrumented.Lookup("Main")
+
+	sign := main.Lookup("sign").Expr.(*ir.Lambda)
+	probe, ok := sign.Body.(*ir.Probe)
+	require.True(ok, "the body of a function is probed")
+
+	point := instrumented.Points[probe.ID]
+	require.Equal("Main", point.Module)
+	require.Equal("sign", point.Name)
+	require.False(point.Branch)
+
+	var branches []*ir.Point
+	ir.Inspect(probe.Expr, func(e ir.Expr) bool {
+		if p, ok := e.(*ir.Probe); ok {
+			branches = append(branches, instrumented.Points[p.ID])
+		}
+		return true
+	})
+	require.Len(branches, 2, "both branches of the if expression are probed")
+	for _, b := range branches {
+		require.True(b.Branch)
+		require.Equal("sign", b.Name)
+		require.Equal(branches[0].Expr, b.Expr)
+	}
+
+	origin := main.Lookup("origin").Expr
+	_, ok = origin.(*ir.Probe)
+	require.False(ok, "the values are not probed")
+
+	var let string
+	for _, p := range instrumented.Points {
+		if p.Module == "Main" && strings.HasPrefix(p.Name, "twice.") {
+			let = p.Name
+		}
+	}
+	require.Equal("twice.double", let)
+	require.Empty(ir.Lower(pkg, info).Points)
+}
@@ -0,0 +1,320 @@
+package ir
+
+import (
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// row is a row of a pattern matrix. It contains the patterns the subjects
+// have to match for its branch to be taken, which are nil once they match
+// anything, and the bindings of the variables of the patterns that have
+// already been matched.
+type row struct {
+	patterns []ast.Pattern
+	binds    []*Bind
+	branch   *branch
+}
+
+// branch is the body of a branch of a case expression or a function.
+type branch struct {
+	body Expr
+	// used reports whether the body is already in the decision tree, in
+	// which case it has to be copied to be used again.
+	used bool
+}
+
+// head is the constructor or literal a pattern tests a value against, along
+// with the patterns of the arguments of the constructor.
+type head struct {
+	ctor *Ctor
+	lit  *Lit
+	args []ast.Pattern
+	// types of the arguments.
+	types []types.Type
+}
+
+func (h *head) key() interface{} {
+	if h.ctor != nil {
+		return h.ctor.Tag
+	}
+	return h.lit.Value
+}
+
+// compileMatch compiles the matching of the given subjects against the
+// rows of patterns to a decision tree. The first row whose patterns match
+// evaluates the body at the same position and fail is evaluated if none of
+// them do. The variables of the patterns must have been declared.
+//
+// Every value is only tested once in each path of the tree and the parts of
+// the values are only extracted once, so the tree is made of case
+// expressions that test one value each against all the constructors or
+// literals it may match, which is what the backends compile efficiently.
+// The bodies that can be reached through more than one path are copied,
+// sharing the variables.
+func (l *lowerer) compileMatch(subjects []*Local, patterns [][]ast.Pattern, bodies []Expr, fail Expr) Expr {
+	var rows = make([]*row, len(patterns))
+	for i, ps := range patterns {
+		rows[i] = &row{patterns: ps, branch: &branch{body: bodies[i]}}
+	}
+	return l.compileRows(subjects, rows, fail)
+}
+
+func (l *lowerer) compileRows(subjects []*Local, rows []*row, fail Expr) Expr {
+	if len(rows) == 0 {
+		return Copy(fail)
+	}
+
+	for _, r := range rows {
+		for i, s := range subjects {
+			r.patterns[i] = l.bindIrrefutable(r, s, r.patterns[i])
+		}
+	}
+
+	var col = -1
+	for i, p := range rows[0].patterns {
+		if p != nil {
+			col = i
+			break
+		}
+	}
+
+	if col < 0 {
+		return leaf(rows[0])
+	}
+
+	subject := subjects[col]
+	if tuple, ok := rows[0].patterns[col].(*ast.TuplePattern); ok {
+		return l.expandTuple(subjects, rows, col, len(tuple.Elems), fail)
+	}
+
+	var heads []*head
+	var seen = make(map[interface{}]bool)
+	for _, r := range rows {
+		if p := r.patterns[col]; p != nil {
+			if h := l.head(subject, p); !seen[h.key()] {
+				seen[h.key()] = true
+				heads = append(heads, h)
+			}
+		}
+	}
+
+	c := &Case{Subject: subject, Typ: fail.Type()}
+	for _, h := range heads {
+		c.Alts = append(c.Alts, l.specialize(subjects, rows, col, h, fail))
+	}
+
+	if !exhaustive(heads) {
+		var defaults []*row
+		for _, r := range rows {
+			if r.patterns[col] == nil {
+				defaults = append(defaults, removeColumn(r, col))
+			}
+		}
+		c.Default = l.compileRows(removeSubject(subjects, col), defaults, fail)
+	}
+	return c
+}
+
+// bindIrrefutable adds to the row the bindings of the pattern if it always
+// matches and returns nil in that case. Otherwise, it returns the part of
+// the pattern that needs to be tested.
+func (l *lowerer) bindIrrefutable(r *row, subject *Local, pattern ast.Pattern) ast.Pattern {
+	for {
+		switch p := pattern.(type) {
+		case nil, *ast.AnythingPattern:
+			return nil
+		case *ast.VarPattern:
+			if v := l.locals[p]; v != subject {
+				r.binds = append(r.binds, &Bind{v, subject})
+			}
+			return nil
+		case *ast.AliasPattern:
+			r.binds = append(r.binds, &Bind{l.locals[p], subject})
+			pattern = p.Pattern
+		case *ast.RecordPattern:
+			r.binds = append(r.binds, l.bindPattern(subject, p)...)
+			return nil
+		default:
+			return pattern
+		}
+	}
+}
+
+// leaf returns the body of the row with its variables bound.
+func leaf(r *row) Expr {
+	body := r.branch.body
+	if r.branch.used {
+		body = Copy(body)
+	}
+	r.branch.used = true
+
+	if len(r.binds) == 0 {
+		return body
+	}
+	return &Let{r.binds, body}
+}
+
+// expandTuple replaces the column of a tuple with columns for all of its
+// elements, which are extracted from the tuple once.
+func (l *lowerer) expandTuple(subjects []*Local, rows []*row, col, n int, fail Expr) Expr {
+	subject := subjects[col]
+
+	var elems = make([][]ast.Pattern, n)
+	var expanded = make([]*row, len(rows))
+	for i, r := range rows {
+		var ps = make([]ast.Pattern, n)
+		if tuple, ok := r.patterns[col].(*ast.TuplePattern); ok {
+			copy(ps, tuple.Elems)
+		}
+
+		for j, p := range ps {
+			elems[j] = append(elems[j], p)
+		}
+		expanded[i] = replaceColumn(r, col, ps)
+	}
+
+	var binds []*Bind
+	var vars = make([]*Local, n)
+	for i := range vars {
+		vars[i] = l.subjectLocal(elems[i], elemType(subject.Typ, i))
+		binds = append(binds, &Bind{vars[i], &Index{subject, i, vars[i].Typ}})
+	}
+
+	body := l.compileRows(replaceSubject(subjects, col, vars), expanded, fail)
+	if len(binds) == 0 {
+		return body
+	}
+	return &Let{binds, body}
+}
+
+// specialize returns the alternative of the case expression on the subject
+// at the given column for the given head, with the rows that may match
+// when the subject matches the head.
+func (l *lowerer) specialize(subjects []*Local, rows []*row, col int, h *head, fail Expr) *Alt {
+	subject := subjects[col]
+	n := len(h.args)
+
+	var args = make([][]ast.Pattern, n)
+	var specialized []*row
+	for _, r := range rows {
+		var ps = make([]ast.Pattern, n)
+		if p := r.patterns[col]; p != nil {
+			other := l.head(subject, p)
+			if other.key() != h.key() {
+				continue
+			}
+			copy(ps, other.args)
+		}
+
+		for j, p := range ps {
+			args[j] = append(args[j], p)
+		}
+		specialized = append(specialized, replaceColumn(r, col, ps))
+	}
+
+	if h.lit != nil {
+		return &Alt{Lit: h.lit, Body: l.compileRows(removeSubject(subjects, col), specialized, fail)}
+	}
+
+	var vars = make([]*Local, n)
+	for i := range vars {
+		vars[i] = l.subjectLocal(args[i], h.types[i])
+	}
+
+	return &Alt{
+		Ctor: h.ctor,
+		Vars: vars,
+		Body: l.compileRows(replaceSubject(subjects, col, vars), specialized, fail),
+	}
+}
+
+// subjectLocal returns the variable of a part of a value that is matched
+// against the given patterns. The variable of the first one that is a
+// variable pattern is used, so it does not need to be bound.
+func (l *lowerer) subjectLocal(patterns []ast.Pattern, t types.Type) *Local {
+	for _, p := range patterns {
+		if v, ok := p.(*ast.VarPattern); ok {
+			return l.locals[v]
+		}
+	}
+	return l.newLocal("", t)
+}
+
+// head returns the head of a pattern that needs to be tested, which is a
+// constructor, list or literal pattern.
+func (l *lowerer) head(subject *Local, pattern ast.Pattern) *head {
+	switch p := pattern.(type) {
+	case *ast.LiteralPattern:
+		return &head{lit: &Lit{p.Literal.Type, p.Literal.Value, subject.Typ}}
+	case *ast.ListPattern:
+		if len(p.Elems) == 0 {
+			return &head{ctor: nilCtor(subject.Typ)}
+		}
+
+		tail := &ast.ListPattern{Elems: p.Elems[1:]}
+		return &head{
+			ctor:  consCtor(subject.Typ),
+			args:  []ast.Pattern{p.Elems[0], tail},
+			types: []types.Type{listElem(subject.Typ), subject.Typ},
+		}
+	case *ast.CtorPattern:
+		id := lastIdent(p.Ctor)
+		if id.Obj == nil && (id.Name == "True" || id.Name == "False") {
+			return &head{lit: &Lit{ast.Bool, id.Name, types.Bool}}
+		}
+
+		if id.Name == "::" {
+			return &head{
+				ctor:  consCtor(subject.Typ),
+				args:  p.Args,
+				types: []types.Type{listElem(subject.Typ), subject.Typ},
+			}
+		}
+
+		if obj := l.info.Uses[id]; obj != nil && obj.Kind == ast.Ctor {
+			ctor, args := l.ctor(obj), ctorArgs(obj, subject.Typ)
+			ctor.Typ = types.NewFunc(append(args, subject.Typ)...)
+			return &head{ctor: ctor, args: p.Args, types: args}
+		}
+	}
+
+	// unreachable in type checked packages
+	return &head{lit: &Lit{ast.Error, "", subject.Typ}}
+}
+
+// exhaustive reports whether the heads cover all the possible values.
+func exhaustive(heads []*head) bool {
+	if len(heads) == 0 {
+		return false
+	}
+
+	if h := heads[0]; h.ctor != nil {
+		return len(heads) == h.ctor.Span
+	}
+	return len(heads) == 2 && heads[0].lit.Kind == ast.Bool
+}
+
+func replaceColumn(r *row, col int, patterns []ast.Pattern) *row {
+	var ps = make([]ast.Pattern, 0, len(r.patterns)-1+len(patterns))
+	ps = append(ps, r.patterns[:col]...)
+	ps = append(ps, patterns...)
+	ps = append(ps, r.patterns[col+1:]...)
+
+	binds := append([]*Bind(nil), r.binds...)
+	return &row{ps, binds, r.branch}
+}
+
+func removeColumn(r *row, col int) *row {
+	return replaceColumn(r, col, nil)
+}
+
+func replaceSubject(subjects []*Local, col int, vars []*Local) []*Local {
+	var result = make([]*Local, 0, len(subjects)-1+len(vars))
+	result = append(result, subjects[:col]...)
+	result = append(result, vars...)
+	return append(result, subjects[col+1:]...)
+}
+
+func removeSubject(subjects []*Local, col int) []*Local {
+	return replaceSubject(subjects, col, nil)
+}
@@ -0,0 +1,39 @@
+package ir
+
+// Options are the options of the optimizations made by Optimize.
+type Options struct {
+	// Roots are the values used from outside the package, such as the main
+	// of a program. If there are any, the definitions that are not
+	// reachable from them are eliminated.
+	Roots []*Global
+	// NoFold disables constant folding and propagation, so every
+	// expression of the source code is kept in the generated code, which
+	// makes it easier to debug.
+	NoFold bool
+	// NoInline disables inlining, so every function of the source code is
+	// called where it is used.
+	NoInline bool
+}
+
+// Optimize runs all the optimizations over the package in the order they
+// work best: functions are inlined first, so the constants they are
+// applied to can be folded, and then the definitions that are no longer
+// used become dead code. Tail calls are replaced after that, because
+// folding can turn calls into tail calls, and the remaining applications
+// of known functions are replaced with direct calls last.
+func Optimize(pkg *Package, opts Options) {
+	if !opts.NoInline {
+		Inline(pkg)
+	}
+
+	if !opts.NoFold {
+		FoldConstants(pkg)
+	}
+
+	if len(opts.Roots) > 0 {
+		EliminateDeadCode(pkg, opts.Roots...)
+	}
+
+	OptimizeTailCalls(pkg)
+	Uncurry(pkg)
+}
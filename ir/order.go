@@ -0,0 +1,84 @@
+package ir
+
+// SortBinds returns the binds of a let expression sorted so that every bind
+// is after the binds its value refers to, unless they refer to each other.
+// Strict backends can evaluate the sorted binds in order, because values
+// cannot be mutually recursive, only functions can.
+func SortBinds(binds []*Bind) []*Bind {
+	var index = make(map[*Local]int, len(binds))
+	for i, b := range binds {
+		index[b.Var] = i
+	}
+
+	order := sortDeps(len(binds), func(i int) []int {
+		var deps []int
+		Inspect(binds[i].Expr, func(e Expr) bool {
+			if v, ok := e.(*Local); ok {
+				if j, ok := index[v]; ok {
+					deps = append(deps, j)
+				}
+			}
+			return true
+		})
+		return deps
+	})
+
+	var sorted = make([]*Bind, len(binds))
+	for i, j := range order {
+		sorted[i] = binds[j]
+	}
+	return sorted
+}
+
+// SortDefs returns the definitions of the module sorted so that every
+// definition is after the definitions of the same module it refers to,
+// unless they refer to each other.
+func SortDefs(mod *Module) []*Def {
+	var index = make(map[string]int, len(mod.Defs))
+	for i, d := range mod.Defs {
+		index[d.Name] = i
+	}
+
+	order := sortDeps(len(mod.Defs), func(i int) []int {
+		var deps []int
+		Inspect(mod.Defs[i].Expr, func(e Expr) bool {
+			if g, ok := e.(*Global); ok && g.Module == mod.Name {
+				if j, ok := index[g.Name]; ok {
+					deps = append(deps, j)
+				}
+			}
+			return true
+		})
+		return deps
+	})
+
+	var sorted = make([]*Def, len(mod.Defs))
+	for i, j := range order {
+		sorted[i] = mod.Defs[j]
+	}
+	return sorted
+}
+
+// sortDeps returns the indexes of n nodes in depth-first post order of
+// their dependencies, keeping the original order when possible.
+func sortDeps(n int, deps func(int) []int) []int {
+	var visited = make([]bool, n)
+	var order = make([]int, 0, n)
+	var visit func(int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+
+		visited[i] = true
+		for _, d := range deps(i) {
+			visit(d)
+		}
+		order = append(order, i)
+	}
+
+	for i := 0; i < n; i++ {
+		visit(i)
+	}
+	return order
+}
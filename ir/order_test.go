@@ -0,0 +1,48 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortBinds(t *testing.T) {
+	a := &Local{Name: "a", ID: 1, Typ: types.Int}
+	b := &Local{Name: "b", ID: 2, Typ: types.Int}
+	f := &Local{Name: "f", ID: 3}
+	x := &Local{Name: "x", ID: 4, Typ: types.Int}
+
+	// a = f b; f = \x -> a; b = 1
+	binds := []*Bind{
+		{a, &App{f, []Expr{b}, types.Int, token.NoPos}},
+		{f, &Lambda{[]*Local{x}, a, nil, token.NoPos}},
+		{b, &Lit{ast.Int, "1", types.Int}},
+	}
+
+	var names []string
+	for _, b := range SortBinds(binds) {
+		names = append(names, b.Var.Name)
+	}
+	require.Equal(t, []string{"f", "b", "a"}, names)
+}
+
+func TestSortDefs(t *testing.T) {
+	mod := &Module{
+		Name: "Main",
+		Defs: []*Def{
+			{Name: "a", Expr: &Global{"Main", "b", types.Int}},
+			{Name: "b", Expr: &Global{"Other", "a", types.Int}},
+			{Name: "c", Expr: &Global{"Main", "a", types.Int}},
+		},
+	}
+
+	var names []string
+	for _, d := range SortDefs(mod) {
+		names = append(names, d.Name)
+	}
+	require.Equal(t, []string{"b", "a", "c"}, names)
+}
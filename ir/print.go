@@ -0,0 +1,172 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// String returns a textual representation of the expression, meant for
+// debugging and tests. Locals are written with their name followed by `$`
+// and their ID, such as `x$3`, so variables with the same name can be told
+// apart.
+func String(e Expr) string {
+	var buf bytes.Buffer
+	writeExpr(&buf, e)
+	return buf.String()
+}
+
+func (l *Local) String() string {
+	return fmt.Sprintf("%s$%d", l.Name, l.ID)
+}
+
+func writeExpr(buf *bytes.Buffer, e Expr) {
+	switch e := e.(type) {
+	case nil:
+		buf.WriteString("<nil>")
+	case *Local:
+		buf.WriteString(e.String())
+	case *Global:
+		writeQualified(buf, e.Module, e.Name)
+	case *Lit:
+		buf.WriteString(e.Value)
+	case *Ctor:
+		writeQualified(buf, e.Module, e.Name)
+	case *Lambda:
+		buf.WriteString(`(\`)
+		for i, p := range e.Params {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(p.String())
+		}
+		buf.WriteString(" -> ")
+		writeExpr(buf, e.Body)
+		buf.WriteByte(')')
+	case *App:
+		buf.WriteByte('(')
+		writeExpr(buf, e.Func)
+		for _, arg := range e.Args {
+			buf.WriteByte(' ')
+			writeExpr(buf, arg)
+		}
+		buf.WriteByte(')')
+	case *Call:
+		buf.WriteString("(call ")
+		writeExpr(buf, e.Func)
+		for _, arg := range e.Args {
+			buf.WriteByte(' ')
+			writeExpr(buf, arg)
+		}
+		buf.WriteByte(')')
+	case *Let:
+		buf.WriteString("(let ")
+		for i, b := range e.Binds {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			buf.WriteString(b.Var.String())
+			buf.WriteString(" = ")
+			writeExpr(buf, b.Expr)
+		}
+		buf.WriteString(" in ")
+		writeExpr(buf, e.Body)
+		buf.WriteByte(')')
+	case *Case:
+		buf.WriteString("(case ")
+		buf.WriteString(e.Subject.String())
+		buf.WriteString(" of ")
+		for i, alt := range e.Alts {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+
+			if alt.Ctor != nil {
+				writeExpr(buf, alt.Ctor)
+			} else {
+				writeExpr(buf, alt.Lit)
+			}
+
+			for _, v := range alt.Vars {
+				buf.WriteByte(' ')
+				buf.WriteString(v.String())
+			}
+			buf.WriteString(" -> ")
+			writeExpr(buf, alt.Body)
+		}
+
+		if e.Default != nil {
+			if len(e.Alts) > 0 {
+				buf.WriteString(" | ")
+			}
+			buf.WriteString("_ -> ")
+			writeExpr(buf, e.Default)
+		}
+		buf.WriteByte(')')
+	case *Tuple:
+		buf.WriteByte('(')
+		writeExprs(buf, e.Elems)
+		buf.WriteByte(')')
+	case *Index:
+		writeExpr(buf, e.Expr)
+		fmt.Fprintf(buf, ".%d", e.Index)
+	case *Record:
+		buf.WriteString("{ ")
+		writeFields(buf, e.Fields)
+		buf.WriteString(" }")
+	case *Access:
+		writeExpr(buf, e.Expr)
+		buf.WriteByte('.')
+		buf.WriteString(e.Field)
+	case *Update:
+		buf.WriteString("{ ")
+		writeExpr(buf, e.Record)
+		buf.WriteString(" | ")
+		writeFields(buf, e.Fields)
+		buf.WriteString(" }")
+	case *List:
+		buf.WriteByte('[')
+		writeExprs(buf, e.Elems)
+		buf.WriteByte(']')
+	case *TailCall:
+		buf.WriteString("(tailcall")
+		for _, arg := range e.Args {
+			buf.WriteByte(' ')
+			writeExpr(buf, arg)
+		}
+		buf.WriteByte(')')
+	case *Probe:
+		fmt.Fprintf(buf, "(probe %d ", e.ID)
+		writeExpr(buf, e.Expr)
+		buf.WriteByte(')')
+	case *Fail:
+		fmt.Fprintf(buf, "(fail %q)", e.Msg)
+	default:
+		fmt.Fprintf(buf, "<%T>", e)
+	}
+}
+
+func writeQualified(buf *bytes.Buffer, module, name string) {
+	if module != "" {
+		buf.WriteString(module)
+		buf.WriteByte('.')
+	}
+	buf.WriteString(name)
+}
+
+func writeExprs(buf *bytes.Buffer, exprs []Expr) {
+	for i, e := range exprs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeExpr(buf, e)
+	}
+}
+
+func writeFields(buf *bytes.Buffer, fields []*Field) {
+	var parts = make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.Name + " = " + String(f.Expr)
+	}
+	buf.WriteString(strings.Join(parts, ", "))
+}
@@ -0,0 +1,75 @@
+package ir
+
+// OptimizeTailCalls replaces the calls that functions make to themselves in
+// tail position with tail calls, so the backends can compile them to loops
+// instead of growing the stack with every iteration. Only the functions
+// bound to top-level definitions or let bindings can call themselves, and
+// only the calls with as many arguments as the parameters of the function
+// are replaced.
+func OptimizeTailCalls(pkg *Package) {
+	for _, m := range pkg.Modules {
+		for _, d := range m.Defs {
+			if fn, ok := d.Expr.(*Lambda); ok {
+				module, name := m.Name, d.Name
+				replaceTailCalls(fn, func(e Expr) bool {
+					g, ok := e.(*Global)
+					return ok && g.Module == module && g.Name == name
+				})
+			}
+
+			Inspect(d.Expr, func(e Expr) bool {
+				if let, ok := e.(*Let); ok {
+					for _, b := range let.Binds {
+						if fn, ok := b.Expr.(*Lambda); ok {
+							v := b.Var
+							replaceTailCalls(fn, func(e Expr) bool { return e == v })
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// HasTailCalls reports whether the body of the function contains tail
+// calls to it.
+func HasTailCalls(fn *Lambda) bool {
+	var found bool
+	Inspect(fn.Body, func(e Expr) bool {
+		switch e.(type) {
+		case *TailCall:
+			found = true
+		case *Lambda:
+			// tail calls always call the innermost function
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+func replaceTailCalls(fn *Lambda, self func(Expr) bool) {
+	fn.Body = replaceTailCall(fn.Body, len(fn.Params), self)
+}
+
+func replaceTailCall(e Expr, arity int, self func(Expr) bool) Expr {
+	switch e := e.(type) {
+	case *App:
+		if self(e.Func) && len(e.Args) == arity {
+			return &TailCall{e.Args, e.Typ}
+		}
+	case *Let:
+		e.Body = replaceTailCall(e.Body, arity, self)
+	case *Case:
+		for _, alt := range e.Alts {
+			alt.Body = replaceTailCall(alt.Body, arity, self)
+		}
+		if e.Default != nil {
+			e.Default = replaceTailCall(e.Default, arity, self)
+		}
+	case *Probe:
+		e.Expr = replaceTailCall(e.Expr, arity, self)
+	}
+	return e
+}
@@ -0,0 +1,31 @@
+package ir_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ir"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimizeTailCalls(t *testing.T) {
+	pkg := lowerTestdata(t)
+	ir.OptimizeTailCalls(pkg)
+	main := pkg.Lookup("Main")
+
+	for _, d := range main.Defs {
+		fn, ok := d.Expr.(*ir.Lambda)
+		if !ok {
+			continue
+		}
+
+		require.Equal(t, d.Name == "count", ir.HasTailCalls(fn), d.Name)
+		if d.Name == "count" {
+			require.Equal(
+				t,
+				`(\acc$35 list$36 -> (case list$36 of [] -> acc$35 | :: $38 rest$37 -> (tailcall (Basics.+ acc$35 1) rest$37)))`,
+				ir.String(fn),
+			)
+		}
+	}
+}
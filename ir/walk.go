@@ -0,0 +1,211 @@
+package ir
+
+// Inspect traverses the expression in depth-first order. It calls f with
+// every expression and only visits the children of the ones for which f
+// returns true.
+func Inspect(e Expr, f func(Expr) bool) {
+	if e == nil || !f(e) {
+		return
+	}
+
+	switch e := e.(type) {
+	case *Lambda:
+		Inspect(e.Body, f)
+	case *App:
+		Inspect(e.Func, f)
+		for _, arg := range e.Args {
+			Inspect(arg, f)
+		}
+	case *Call:
+		Inspect(e.Func, f)
+		for _, arg := range e.Args {
+			Inspect(arg, f)
+		}
+	case *Let:
+		for _, b := range e.Binds {
+			Inspect(b.Expr, f)
+		}
+		Inspect(e.Body, f)
+	case *Case:
+		Inspect(e.Subject, f)
+		for _, alt := range e.Alts {
+			Inspect(alt.Body, f)
+		}
+		Inspect(e.Default, f)
+	case *Tuple:
+		for _, el := range e.Elems {
+			Inspect(el, f)
+		}
+	case *Index:
+		Inspect(e.Expr, f)
+	case *Record:
+		for _, field := range e.Fields {
+			Inspect(field.Expr, f)
+		}
+	case *Access:
+		Inspect(e.Expr, f)
+	case *Update:
+		Inspect(e.Record, f)
+		for _, field := range e.Fields {
+			Inspect(field.Expr, f)
+		}
+	case *List:
+		for _, el := range e.Elems {
+			Inspect(el, f)
+		}
+	case *TailCall:
+		for _, arg := range e.Args {
+			Inspect(arg, f)
+		}
+	case *Probe:
+		Inspect(e.Expr, f)
+	}
+}
+
+// Rewrite replaces every expression with the result of calling f with it,
+// in depth-first order, so f is called with the expressions after their
+// children have been rewritten. The expressions are modified in place.
+// The variables bound by lambdas, let expressions and case alternatives and
+// the subjects of case expressions are not rewritten.
+func Rewrite(e Expr, f func(Expr) Expr) Expr {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *Lambda:
+		e.Body = Rewrite(e.Body, f)
+	case *App:
+		e.Func = Rewrite(e.Func, f)
+		rewriteExprs(e.Args, f)
+	case *Call:
+		e.Func = Rewrite(e.Func, f)
+		rewriteExprs(e.Args, f)
+	case *Let:
+		for _, b := range e.Binds {
+			b.Expr = Rewrite(b.Expr, f)
+		}
+		e.Body = Rewrite(e.Body, f)
+	case *Case:
+		for _, alt := range e.Alts {
+			alt.Body = Rewrite(alt.Body, f)
+		}
+		e.Default = Rewrite(e.Default, f)
+	case *Tuple:
+		rewriteExprs(e.Elems, f)
+	case *Index:
+		e.Expr = Rewrite(e.Expr, f)
+	case *Record:
+		rewriteFields(e.Fields, f)
+	case *Access:
+		e.Expr = Rewrite(e.Expr, f)
+	case *Update:
+		e.Record = Rewrite(e.Record, f)
+		rewriteFields(e.Fields, f)
+	case *List:
+		rewriteExprs(e.Elems, f)
+	case *TailCall:
+		rewriteExprs(e.Args, f)
+	case *Probe:
+		e.Expr = Rewrite(e.Expr, f)
+	}
+	return f(e)
+}
+
+func rewriteExprs(exprs []Expr, f func(Expr) Expr) {
+	for i, e := range exprs {
+		exprs[i] = Rewrite(e, f)
+	}
+}
+
+func rewriteFields(fields []*Field, f func(Expr) Expr) {
+	for _, field := range fields {
+		field.Expr = Rewrite(field.Expr, f)
+	}
+}
+
+// Copy returns a deep copy of the expression. Locals are not copied, so the
+// copy binds and refers to the same variables as the original.
+func Copy(e Expr) Expr {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *Local:
+		return e
+	case *Global:
+		g := *e
+		return &g
+	case *Lit:
+		l := *e
+		return &l
+	case *Ctor:
+		c := *e
+		return &c
+	case *Lambda:
+		return &Lambda{e.Params, Copy(e.Body), e.Typ, e.Pos}
+	case *App:
+		return &App{Copy(e.Func), copyExprs(e.Args), e.Typ, e.Pos}
+	case *Call:
+		return &Call{Copy(e.Func), copyExprs(e.Args), e.Typ, e.Pos}
+	case *Let:
+		var binds = make([]*Bind, len(e.Binds))
+		for i, b := range e.Binds {
+			binds[i] = &Bind{b.Var, Copy(b.Expr)}
+		}
+		return &Let{binds, Copy(e.Body)}
+	case *Case:
+		var alts = make([]*Alt, len(e.Alts))
+		for i, alt := range e.Alts {
+			a := *alt
+			if alt.Ctor != nil {
+				c := *alt.Ctor
+				a.Ctor = &c
+			}
+			if alt.Lit != nil {
+				l := *alt.Lit
+				a.Lit = &l
+			}
+			a.Body = Copy(alt.Body)
+			alts[i] = &a
+		}
+		return &Case{e.Subject, alts, Copy(e.Default), e.Typ}
+	case *Tuple:
+		return &Tuple{copyExprs(e.Elems), e.Typ}
+	case *Index:
+		return &Index{Copy(e.Expr), e.Index, e.Typ}
+	case *Record:
+		return &Record{copyFields(e.Fields), e.Typ}
+	case *Access:
+		return &Access{Copy(e.Expr), e.Field, e.Typ}
+	case *Update:
+		return &Update{Copy(e.Record), copyFields(e.Fields), e.Typ}
+	case *List:
+		return &List{copyExprs(e.Elems), e.Typ}
+	case *TailCall:
+		return &TailCall{copyExprs(e.Args), e.Typ}
+	case *Probe:
+		return &Probe{e.ID, Copy(e.Expr)}
+	case *Fail:
+		f := *e
+		return &f
+	}
+	return e
+}
+
+func copyExprs(exprs []Expr) []Expr {
+	if exprs == nil {
+		return nil
+	}
+
+	var result = make([]Expr, len(exprs))
+	for i, e := range exprs {
+		result[i] = Copy(e)
+	}
+	return result
+}
+
+func copyFields(fields []*Field) []*Field {
+	var result = make([]*Field, len(fields))
+	for i, f := range fields {
+		result[i] = &Field{f.Name, Copy(f.Expr)}
+	}
+	return result
+}
@@ -0,0 +1,6 @@
+module Extra exposing (..)
+
+
+one : Float
+one =
+    1
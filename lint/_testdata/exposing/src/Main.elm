@@ -0,0 +1,24 @@
+module Main exposing (..)
+
+import Shape exposing (..)
+import Util exposing (..)
+import Extra as E exposing (..)
+
+
+type alias Size =
+    Float
+
+
+size : Shape -> Size
+size shape =
+    case shape of
+        Shape.Circle r ->
+            r |> identity
+
+        _ ->
+            E.one
+
+
+corner : Point
+corner =
+    origin
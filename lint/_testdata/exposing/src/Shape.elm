@@ -0,0 +1,20 @@
+module Shape exposing (..)
+
+
+type Shape
+    = Circle Float
+    | Square Float
+
+
+type alias Point =
+    { x : Float, y : Float }
+
+
+origin : Point
+origin =
+    { x = 0, y = 0 }
+
+
+unit : Float
+unit =
+    1
@@ -0,0 +1,11 @@
+module Util exposing (..)
+
+
+identity : a -> a
+identity x =
+    x
+
+
+(|>) : a -> (a -> b) -> b
+(|>) x f =
+    f x
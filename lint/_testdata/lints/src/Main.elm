@@ -0,0 +1,44 @@
+module Main exposing (view, update, count)
+
+import Util exposing (identity)
+import Extra
+
+
+view : Int -> Int
+view x =
+    identity x
+
+
+
+-- TODO: annotate this
+
+
+update x =
+    let
+        unused =
+            1
+
+        y =
+            x
+    in
+        y
+
+
+count : Int -> Int
+count n =
+    case n of
+        0 ->
+            0
+
+        1 ->
+            1
+
+        2 ->
+            2
+
+        _ ->
+            3
+
+
+helper =
+    2
@@ -0,0 +1,165 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+func exposingAll(p *Pass) {
+	if decl := p.Module.Module; decl != nil {
+		if list, ok := decl.Exposing.(*ast.OpenList); ok {
+			names := declaredNames(p.Module)
+			if len(names) > 0 {
+				fix := listFix(list, "Expose the names explicitly", names)
+				p.ReportFix(list, fix, "module %s exposes all of its names", p.Module.Name)
+			} else {
+				p.Report(list, "module %s exposes all of its names", p.Module.Name)
+			}
+		}
+	}
+
+	scope := p.Module.Scope
+	if scope == nil {
+		return
+	}
+
+	used := unqualifiedObjects(p.Module)
+	for _, imp := range p.Module.Imports {
+		list, ok := imp.Exposing.(*ast.OpenList)
+		// the default imports have no position
+		if !ok || imp.Import == token.NoPos {
+			continue
+		}
+
+		name := imp.ModuleName()
+		var mod *ast.Module
+		if obj := scope.Modules[name]; obj != nil {
+			mod, _ = obj.Node.(*ast.Module)
+		}
+		if mod == nil || mod.Scope == nil {
+			continue
+		}
+
+		var fix report.Fix
+		if names := usedNames(mod, used); len(names) > 0 {
+			fix = listFix(list, "Expose only the names that are used", names)
+		} else {
+			var start = imp.Module.End()
+			if imp.Alias != nil {
+				start = imp.Alias.End()
+			}
+			fix = report.Fix{Message: "Remove the exposing list", Pos: start, End: list.Rparen + 1}
+		}
+		p.ReportFix(list, fix, "module %s is imported exposing all of its names", name)
+	}
+}
+
+// listFix returns the fix that replaces the open list with the given names.
+func listFix(list *ast.OpenList, msg string, names []string) report.Fix {
+	text := "(" + strings.Join(names, ", ") + ")"
+	return report.Fix{
+		Message: fmt.Sprintf("%s: %s", msg, text),
+		Pos:     list.Lparen,
+		End:     list.Rparen + 1,
+		Text:    text,
+	}
+}
+
+// unqualifiedObjects returns the objects the identifiers of the module refer
+// to without the name of their module.
+func unqualifiedObjects(mod *ast.Module) map[*ast.Object]bool {
+	var qualified = make(map[*ast.Ident]bool)
+	var used = make(map[*ast.Object]bool)
+	inspect(mod, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// the selector is the first name of the path, and the last one
+			// is the name selected from it
+			if id, ok := n.Expr.(*ast.Ident); ok {
+				qualified[id] = true
+			}
+		case *ast.Ident:
+			if n.Obj != nil && !qualified[n] {
+				used[n.Obj] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// usedNames returns the names of the exposing list of the module that
+// exposes the used objects, in the order they are declared. Only the types
+// and values are imported with (..), so the constructors are not exposed.
+func usedNames(mod *ast.Module, used map[*ast.Object]bool) []string {
+	var positions = make(map[string]token.Pos)
+	for _, obj := range mod.Scope.Exposed {
+		if !used[obj] {
+			continue
+		}
+
+		switch obj.Kind {
+		case ast.Var:
+			positions[exposedVar(obj.Name)] = obj.Node.Pos()
+		case ast.Typ:
+			positions[obj.Name] = obj.Node.Pos()
+		}
+	}
+
+	var names = make([]string, 0, len(positions))
+	for name := range positions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if positions[names[i]] != positions[names[j]] {
+			return positions[names[i]] < positions[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// declaredNames returns the names of the exposing list with all the
+// top-level declarations of the module, in the order they are declared,
+// which exposes the same names as (..).
+func declaredNames(mod *ast.Module) []string {
+	var names []string
+	for _, d := range mod.Decls {
+		switch d := d.(type) {
+		case *ast.Definition:
+			names = append(names, exposedVar(d.Name.Name))
+		case *ast.PortDecl:
+			names = append(names, d.Name.Name)
+		case *ast.AliasDecl:
+			names = append(names, d.Name.Name)
+		case *ast.UnionDecl:
+			names = append(names, d.Name.Name+"(..)")
+		case *ast.DestructuringAssignment:
+			ast.WalkFunc(d.Pattern, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.VarPattern:
+					names = append(names, n.Name.Name)
+				case *ast.AliasPattern:
+					names = append(names, n.Name.Name)
+				}
+				return true
+			})
+		}
+	}
+	return names
+}
+
+// exposedVar returns the name of a value in an exposing list, which is in
+// parenthesis if it is an operator.
+func exposedVar(name string) string {
+	if !scanner.IsIdentifier(name) {
+		return "(" + name + ")"
+	}
+	return name
+}
@@ -0,0 +1,187 @@
+// Package lint implements the linting of Elm modules, which finds code that
+// is valid but is likely to be a mistake or to be hard to maintain. Every
+// problem is found by a rule, and the built-in rules can be disabled, or
+// enabled if they are opt-in, and tuned with a configuration file in the
+// root of the package. Programs can add their own rules as well.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// ConfigFile is the name of the configuration file, which is in the root of
+// the package.
+const ConfigFile = "elmo-lint.json"
+
+// Config is the configuration of the rules.
+type Config struct {
+	// Rules are the configurations of the rules by their name. The rules
+	// that are not in the configuration are enabled with their defaults.
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+// RuleConfig is the configuration of a rule.
+type RuleConfig struct {
+	// Disabled reports whether the rule is not run.
+	Disabled bool `json:"disabled,omitempty"`
+	// Enabled reports whether the rule is run if it is an opt-in rule,
+	// which are only run if they are enabled.
+	Enabled bool `json:"enabled,omitempty"`
+	// Max is the limit of the rules that have one. If it is zero, the
+	// default limit of the rule is used.
+	Max int `json:"max,omitempty"`
+}
+
+// ReadConfig decodes a configuration.
+func ReadConfig(r io.Reader) (*Config, error) {
+	var conf Config
+	if err := json.NewDecoder(r).Decode(&conf); err != nil {
+		return nil, fmt.Errorf("lint: can't decode configuration: %s", err)
+	}
+	return &conf, nil
+}
+
+// LoadConfig loads the configuration file of the package in the given
+// directory. If there is none, the configuration is empty, so all the rules
+// are enabled with their defaults.
+func LoadConfig(dir string) (*Config, error) {
+	f, err := os.Open(filepath.Join(dir, ConfigFile))
+	if os.IsNotExist(err) {
+		return new(Config), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("lint: can't open configuration: %s", err)
+	}
+	defer f.Close()
+	return ReadConfig(f)
+}
+
+// Rule is a lint rule, which finds a kind of problem in the modules.
+type Rule struct {
+	// Name of the rule, which is used in the configuration.
+	Name string
+	// Doc describes the problems found by the rule.
+	Doc string
+	// Max is the default limit of the rule, if it has one.
+	Max int
+	// OptIn reports whether the rule is only run if it is enabled in the
+	// configuration, because the code it reports is not a problem for
+	// everyone.
+	OptIn bool
+	// Run finds the problems of the module of the pass and reports them.
+	Run func(p *Pass)
+}
+
+// Issue is a problem found by a rule.
+type Issue struct {
+	// Rule is the name of the rule that found the problem.
+	Rule string
+	// Module is the name of the module with the problem.
+	Module string
+	// Path is the path to the file of the module.
+	Path string
+	// Pos and End are the positions where the problematic code starts and
+	// ends.
+	Pos token.Pos
+	End token.Pos
+	// Message describes the problem.
+	Message string
+	// Fixes are the changes of the code that fix the problem, if any.
+	Fixes []report.Fix
+}
+
+// Pass is the run of a rule on a module.
+type Pass struct {
+	// Module is the module being linted. It has been resolved, and it has
+	// its comments if it was parsed with them.
+	Module *ast.Module
+	// Info is the type information of the module, which is nil if it was
+	// not type checked.
+	Info *types.Info
+	// Max is the limit of the rule, from the configuration or its default.
+	Max int
+
+	rule   *Rule
+	issues []Issue
+}
+
+// Report reports a problem with the given node.
+func (p *Pass) Report(node ast.Node, format string, args ...interface{}) {
+	p.issues = append(p.issues, Issue{
+		Rule:    p.rule.Name,
+		Module:  p.Module.Name,
+		Path:    p.Module.Path,
+		Pos:     node.Pos(),
+		End:     node.End(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// ReportFix reports a problem with the given node that is fixed by the
+// given change of the code.
+func (p *Pass) ReportFix(node ast.Node, fix report.Fix, format string, args ...interface{}) {
+	p.Report(node, format, args...)
+	issue := &p.issues[len(p.issues)-1]
+	issue.Fixes = append(issue.Fixes, fix)
+}
+
+// Linter runs the enabled rules of a configuration.
+type Linter struct {
+	rules  []*Rule
+	config map[string]RuleConfig
+}
+
+// New creates a linter with the given rules, which are the built-in rules
+// if there is none. It fails if the configuration has rules that are not
+// any of them.
+func New(conf *Config, rules ...*Rule) (*Linter, error) {
+	if len(rules) == 0 {
+		rules = Rules
+	}
+
+	var known = make(map[string]bool)
+	for _, r := range rules {
+		known[r.Name] = true
+	}
+
+	for name := range conf.Rules {
+		if !known[name] {
+			return nil, fmt.Errorf("lint: unknown rule %q in the configuration", name)
+		}
+	}
+	return &Linter{rules, conf.Rules}, nil
+}
+
+// Lint runs the enabled rules on the given module and returns the problems
+// they found in the order they appear in the source code. Info is the type
+// information of the module, if it was type checked.
+func (l *Linter) Lint(mod *ast.Module, info *types.Info) []Issue {
+	var issues []Issue
+	for _, r := range l.rules {
+		conf := l.config[r.Name]
+		if conf.Disabled || (r.OptIn && !conf.Enabled) {
+			continue
+		}
+
+		p := &Pass{Module: mod, Info: info, Max: r.Max, rule: r}
+		if conf.Max > 0 {
+			p.Max = conf.Max
+		}
+		r.Run(p)
+		issues = append(issues, p.issues...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Pos < issues[j].Pos
+	})
+	return issues
+}
@@ -0,0 +1,113 @@
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func lintFixture(t *testing.T, conf *Config) []string {
+	pkg, err := parser.Parse("_testdata/lints/src/Main.elm", parser.FullParse|parser.ParseComments)
+	require.NoError(t, err)
+
+	info := types.NewInfo()
+	var check types.Config
+	require.NoError(t, check.Check(pkg, info))
+
+	l, err := New(conf)
+	require.NoError(t, err)
+
+	var result []string
+	for _, issue := range l.Lint(pkg.Modules["Main"], info) {
+		require.Equal(t, "Main", issue.Module)
+		require.True(t, issue.End > issue.Pos)
+		result = append(result, issue.Rule+": "+issue.Message)
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	require.Equal(t, []string{
+		"unused-import: module Extra is imported but it is not used",
+		"todo: there is a TODO comment: annotate this",
+		"missing-signature: update is exposed but it has no type annotation",
+		"unused-value: unused is defined but it is not used",
+		"unused-value: helper is defined but it is not used",
+	}, lintFixture(t, new(Config)))
+}
+
+func TestLintConfig(t *testing.T) {
+	conf, err := ReadConfig(strings.NewReader(`{
+		"rules": {
+			"todo": {"disabled": true},
+			"unused-value": {"disabled": true},
+			"long-case": {"max": 3}
+		}
+	}`))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"unused-import: module Extra is imported but it is not used",
+		"missing-signature: update is exposed but it has no type annotation",
+		"long-case: this case expression has 4 branches, more than 3",
+	}, lintFixture(t, conf))
+
+	_, err = New(&Config{Rules: map[string]RuleConfig{"unknown": {}}})
+	require.Error(t, err)
+}
+
+func TestExposingAll(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/exposing/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	l, err := New(new(Config))
+	require.NoError(err)
+	require.Empty(l.Lint(pkg.Modules["Main"], nil))
+
+	l, err = New(&Config{Rules: map[string]RuleConfig{"exposing-all": {Enabled: true}}})
+	require.NoError(err)
+
+	var result []string
+	for _, issue := range l.Lint(pkg.Modules["Main"], nil) {
+		require.Len(issue.Fixes, 1)
+		require.True(issue.Fixes[0].End > issue.Fixes[0].Pos)
+		result = append(result, issue.Message+": "+issue.Fixes[0].Message)
+	}
+
+	require.Equal([]string{
+		"module Main exposes all of its names: Expose the names explicitly: (Size, size, corner)",
+		"module Shape is imported exposing all of its names: Expose only the names that are used: (Shape, Point, origin)",
+		"module Util is imported exposing all of its names: Expose only the names that are used: (identity, (|>))",
+		"module Extra is imported exposing all of its names: Remove the exposing list",
+	}, result)
+}
+
+func TestLoadConfig(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "lint")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	conf, err := LoadConfig(dir)
+	require.NoError(err)
+	require.Empty(conf.Rules)
+
+	content := `{"rules": {"long-case": {"max": 5}}}`
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, ConfigFile), []byte(content), 0644))
+	conf, err = LoadConfig(dir)
+	require.NoError(err)
+	require.Equal(map[string]RuleConfig{"long-case": {Max: 5}}, conf.Rules)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, ConfigFile), []byte("{"), 0644))
+	_, err = LoadConfig(dir)
+	require.Error(err)
+}
@@ -0,0 +1,218 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Rules are the built-in rules.
+var Rules = []*Rule{
+	{
+		Name: "unused-import",
+		Doc:  "imports of modules that are not used, qualified or through their exposed names",
+		Run:  unusedImports,
+	},
+	{
+		Name: "unused-value",
+		Doc:  "top-level definitions that are not exposed nor used, and let definitions that are not used",
+		Run:  unusedValues,
+	},
+	{
+		Name: "todo",
+		Doc:  "comments with a TODO or a FIXME, if the module was parsed with its comments",
+		Run:  todoComments,
+	},
+	{
+		Name: "long-case",
+		Doc:  "case expressions with more branches than the limit",
+		Max:  10,
+		Run:  longCases,
+	},
+	{
+		Name: "missing-signature",
+		Doc:  "exposed definitions without a type annotation",
+		Run:  missingSignatures,
+	},
+	{
+		Name:  "exposing-all",
+		Doc:   "imports and modules exposing (..), whose names become ambiguous as the code grows",
+		OptIn: true,
+		Run:   exposingAll,
+	},
+}
+
+// inspect walks all the declarations of the module.
+func inspect(mod *ast.Module, fn func(ast.Node) bool) {
+	for _, d := range mod.Decls {
+		ast.WalkFunc(d, fn)
+	}
+}
+
+// usedObjects returns the objects the identifiers of the module refer to,
+// except the names of the definitions and their annotations.
+func usedObjects(mod *ast.Module) map[*ast.Object]bool {
+	var names = make(map[*ast.Ident]bool)
+	inspect(mod, func(n ast.Node) bool {
+		if def, ok := n.(*ast.Definition); ok {
+			names[def.Name] = true
+			if def.Annotation != nil {
+				names[def.Annotation.Name] = true
+			}
+		}
+		return true
+	})
+
+	var used = make(map[*ast.Object]bool)
+	var visit = func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Obj != nil && !names[id] {
+			used[id.Obj] = true
+		}
+		return true
+	}
+
+	// the effect managers refer to their functions in the module
+	// declaration
+	if mod.Module != nil {
+		ast.WalkFunc(mod.Module, visit)
+	}
+	inspect(mod, visit)
+	return used
+}
+
+func unusedImports(p *Pass) {
+	scope := p.Module.Scope
+	if scope == nil {
+		return
+	}
+
+	used := usedObjects(p.Module)
+	for _, imp := range p.Module.Imports {
+		// the default imports have no position
+		if imp.Import == token.NoPos {
+			continue
+		}
+
+		name := imp.ModuleName()
+		if used[scope.Modules[name]] || (imp.Alias != nil && used[scope.Modules[imp.Alias.Name]]) {
+			continue
+		}
+
+		if obj := scope.Modules[name]; obj != nil {
+			if mod, ok := obj.Node.(*ast.Module); ok && usesExposed(used, mod) {
+				continue
+			}
+		}
+		p.Report(imp, "module %s is imported but it is not used", name)
+	}
+}
+
+// usesExposed reports whether any of the exposed objects of the module is
+// used.
+func usesExposed(used map[*ast.Object]bool, mod *ast.Module) bool {
+	if mod.Scope == nil {
+		return false
+	}
+
+	for _, obj := range mod.Scope.Exposed {
+		if used[obj] {
+			return true
+		}
+	}
+	return false
+}
+
+func unusedValues(p *Pass) {
+	used := make(map[ast.Node]bool)
+	for obj := range usedObjects(p.Module) {
+		used[obj.Node] = true
+	}
+
+	for _, d := range p.Module.Decls {
+		def, ok := d.(*ast.Definition)
+		if !ok || def.Name.Name == "main" || used[def.Name] {
+			continue
+		}
+
+		if p.Module.Scope != nil && p.Module.Scope.LookupExposed(def.Name.Name, ast.Var) != nil {
+			continue
+		}
+		p.Report(def.Name, "%s is defined but it is not used", def.Name.Name)
+	}
+
+	inspect(p.Module, func(n ast.Node) bool {
+		let, ok := n.(*ast.LetExpr)
+		if !ok {
+			return true
+		}
+
+		for _, d := range let.Decls {
+			if def, ok := d.(*ast.Definition); ok && !used[def.Name] {
+				p.Report(def.Name, "%s is defined but it is not used", def.Name.Name)
+			}
+		}
+		return true
+	})
+}
+
+// todoMarkers are the words that mark a comment as a TODO.
+var todoMarkers = []string{"TODO", "FIXME"}
+
+func todoComments(p *Pass) {
+	for _, c := range p.Module.Comments {
+		for _, marker := range todoMarkers {
+			i := strings.Index(c.Text, marker)
+			if i < 0 {
+				continue
+			}
+
+			text := c.Text[i+len(marker):]
+			if end := strings.IndexByte(text, '\n'); end >= 0 {
+				text = text[:end]
+			}
+
+			text = strings.TrimSuffix(strings.TrimSpace(text), "-}")
+			text = strings.TrimSpace(strings.TrimLeft(text, ":"))
+			if text == "" {
+				p.Report(c, "there is a %s comment", marker)
+			} else {
+				p.Report(c, "there is a %s comment: %s", marker, text)
+			}
+			break
+		}
+	}
+}
+
+// caseHeader is the "case ... of" part of a case expression.
+type caseHeader struct {
+	*ast.CaseExpr
+}
+
+func (h caseHeader) End() token.Pos { return h.Of + token.Pos(len("of")) }
+
+func longCases(p *Pass) {
+	inspect(p.Module, func(n ast.Node) bool {
+		if c, ok := n.(*ast.CaseExpr); ok && len(c.Branches) > p.Max {
+			p.Report(caseHeader{c}, "this case expression has %d branches, more than %d", len(c.Branches), p.Max)
+		}
+		return true
+	})
+}
+
+func missingSignatures(p *Pass) {
+	if p.Module.Scope == nil {
+		return
+	}
+
+	for _, d := range p.Module.Decls {
+		def, ok := d.(*ast.Definition)
+		if !ok || def.Annotation != nil {
+			continue
+		}
+
+		if p.Module.Scope.LookupExposed(def.Name.Name, ast.Var) != nil {
+			p.Report(def.Name, "%s is exposed but it has no type annotation", def.Name.Name)
+		}
+	}
+}
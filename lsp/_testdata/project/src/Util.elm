@@ -0,0 +1,28 @@
+module Util exposing (Shape(..), identity, width)
+
+{-| Utilities for the tests.
+-}
+
+
+{-| A shape.
+-}
+type Shape
+    = Square Int
+    | Rect Int Int
+
+
+{-| Returns its argument.
+-}
+identity : a -> a
+identity x =
+    x
+
+
+width : Shape -> Int
+width shape =
+    case shape of
+        Square side ->
+            side
+
+        Rect w _ ->
+            w
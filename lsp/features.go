@@ -0,0 +1,509 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/symbol"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// position returns the analysis of the document and the offset in the
+// module of the document of the position of the request. The analysis is
+// nil if the document was never parsed.
+func (s *Server) position(params json.RawMessage, p *textDocumentPositionParams) (*analysis, *ast.Module, token.Pos, error) {
+	if err := decode(params, p); err != nil {
+		return nil, nil, 0, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil || d.analysis == nil {
+		return nil, nil, 0, err
+	}
+
+	mod := d.analysis.pkg.Modules[d.analysis.module]
+	t, err := d.analysis.text(mod.Path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return d.analysis, mod, t.offset(p.Position), nil
+}
+
+// typeOf returns the type of the object, or nil if it was not checked.
+func typeOf(info *types.Info, obj *ast.Object) types.Type {
+	if id := symbol.NameOf(obj.Node); id != nil {
+		if o := info.Defs[id]; o != nil {
+			return o.Type
+		}
+	}
+	return nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	a, mod, off, err := s.position(params, &p)
+	if a == nil {
+		return nil, err
+	}
+
+	id := symbol.IdentAt(mod, off)
+	if id == nil {
+		return nil, nil
+	}
+
+	desc := symbol.Describe(a.pkg, a.info, mod, id)
+	if desc == nil {
+		return nil, nil
+	}
+
+	t, err := a.text(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := t.rangeOf(id.Pos(), id.End())
+	return &hover{Contents: markupContent{Kind: "markdown", Value: desc.Markdown()}, Range: &r}, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	a, mod, off, err := s.position(params, &p)
+	if a == nil {
+		return nil, err
+	}
+
+	id := symbol.IdentAt(mod, off)
+	if id == nil {
+		return nil, nil
+	}
+
+	obj := symbol.ObjectOf(a.pkg, mod, id)
+	if obj == nil || obj.Node == nil {
+		return nil, nil
+	}
+
+	loc, err := a.location(symbol.ModuleOf(a.pkg, mod, obj), obj.Node)
+	if err != nil || loc == nil {
+		return nil, err
+	}
+	return []Location{*loc}, nil
+}
+
+// location returns the location of the name of the node defining an
+// object in the given module.
+func (a *analysis) location(mod *ast.Module, node ast.Node) (*Location, error) {
+	var start, end = node.Pos(), node.End()
+	if id := symbol.NameOf(node); id != nil {
+		start, end = id.Pos(), id.End()
+	}
+
+	t, err := a.text(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &Location{URI: pathToURI(mod.Path), Range: t.rangeOf(start, end)}, nil
+}
+
+func (s *Server) references(params json.RawMessage) (interface{}, error) {
+	var p referenceParams
+	a, mod, off, err := s.position(params, &p.textDocumentPositionParams)
+	if a == nil {
+		return nil, err
+	}
+
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	id := symbol.IdentAt(mod, off)
+	if id == nil {
+		return nil, nil
+	}
+
+	obj := symbol.ObjectOf(a.pkg, mod, id)
+	if obj == nil {
+		return nil, nil
+	}
+
+	def := symbol.NameOf(obj.Node)
+	var locs = []Location{}
+	for _, name := range a.pkg.Order {
+		m := a.pkg.Modules[name]
+		if m == nil {
+			continue
+		}
+
+		var ids []*ast.Ident
+		ast.WalkFunc(m, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.NamePos != token.NoPos {
+				if id.Obj == obj && id != def || id == def && p.Context.IncludeDeclaration {
+					ids = append(ids, id)
+				}
+			}
+			return n != nil
+		})
+
+		for _, id := range ids {
+			loc, err := a.location(m, id)
+			if err != nil {
+				return nil, err
+			}
+			locs = append(locs, *loc)
+		}
+	}
+	return locs, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p documentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.analysis == nil {
+		return []DocumentSymbol{}, nil
+	}
+
+	a := d.analysis
+	mod := a.pkg.Modules[a.module]
+	t, err := a.text(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.documentSymbols(t, symbol.Outline(mod)), nil
+}
+
+func (s *Server) workspaceSymbol(params json.RawMessage) (interface{}, error) {
+	var p workspaceSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	overlay := s.overlay()
+	idx, err := packageIndex(s.docs, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analysis{overlay: overlay, texts: make(map[string]*text)}
+	var result = []SymbolInformation{}
+	for _, sym := range idx.Search(p.Query) {
+		t, err := a.text(sym.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, SymbolInformation{
+			Name:          sym.Name,
+			Kind:          symbolKinds[sym.Kind],
+			ContainerName: sym.Container,
+			Location: Location{
+				URI:   pathToURI(filepath.Clean(sym.Path)),
+				Range: t.rangeOf(sym.Node.Pos(), sym.Node.End()),
+			},
+		})
+	}
+	return result, nil
+}
+
+// packageIndex returns the index of the symbols of the modules of the
+// packages of the open documents, parsed with their current content. The
+// modules that cannot be parsed are left out, and so are the files of the
+// documents that are not in a package.
+func packageIndex(docs map[string]*document, overlay source.Overlay) (*symbol.Index, error) {
+	var idx = symbol.NewIndex()
+	var seen = make(map[string]bool)
+	for path := range docs {
+		files, err := packageFiles(path)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+
+			content, ok := overlay[file]
+			if !ok {
+				data, err := ioutil.ReadFile(file)
+				if err != nil {
+					return nil, err
+				}
+				content = string(data)
+			}
+
+			if mod, err := parser.ParseFrom(file, strings.NewReader(content), parser.FullParse); err == nil {
+				idx.Add(mod)
+			}
+		}
+	}
+	return idx, nil
+}
+
+// symbolKinds are the kinds of the symbols of every kind of declaration.
+var symbolKinds = map[symbol.Kind]int{
+	symbol.Value:       symbolVariable,
+	symbol.Function:    symbolFunction,
+	symbol.Type:        symbolEnum,
+	symbol.TypeAlias:   symbolClass,
+	symbol.Constructor: symbolEnumMember,
+	symbol.Field:       symbolField,
+	symbol.Port:        symbolFunction,
+	symbol.Operator:    symbolOperator,
+}
+
+// symbolDetails are the details of the symbols of the declarations that
+// are not definitions, which have their types instead.
+var symbolDetails = map[symbol.Kind]string{
+	symbol.Type:      "type",
+	symbol.TypeAlias: "type alias",
+	symbol.Port:      "port",
+	symbol.Operator:  "infix",
+}
+
+// documentSymbols returns the document symbols of the symbols of the
+// module with the given text. The definitions have their types as detail.
+func (a *analysis) documentSymbols(t *text, symbols []*symbol.Symbol) []DocumentSymbol {
+	var result = []DocumentSymbol{}
+	for _, sym := range symbols {
+		detail, ok := symbolDetails[sym.Kind]
+		if obj := a.info.Defs[sym.Ident]; !ok && obj != nil && obj.Type != nil {
+			detail = types.TypeString(obj.Type)
+		}
+
+		ds := DocumentSymbol{
+			Name:           sym.Name,
+			Detail:         detail,
+			Kind:           symbolKinds[sym.Kind],
+			Range:          t.rangeOf(sym.Node.Pos(), sym.Node.End()),
+			SelectionRange: t.rangeOf(sym.Ident.Pos(), sym.Ident.End()),
+		}
+		if len(sym.Children) > 0 {
+			ds.Children = a.documentSymbols(t, sym.Children)
+		}
+		result = append(result, ds)
+	}
+	return result
+}
+
+// semanticTokenTypes are the types of the semantic tokens of every kind of
+// token, in the order of the legend.
+var semanticTokenTypes = [...]string{
+	symbol.ModuleToken:        "namespace",
+	symbol.TypeToken:          "type",
+	symbol.TypeParameterToken: "typeParameter",
+	symbol.ConstructorToken:   "enumMember",
+	symbol.FunctionToken:      "function",
+	symbol.VariableToken:      "variable",
+	symbol.ParameterToken:     "parameter",
+	symbol.PropertyToken:      "property",
+	symbol.OperatorToken:      "operator",
+}
+
+// semanticTokenModifiers are the modifiers of the semantic tokens, which
+// are bits in the order of the legend.
+var semanticTokenModifiers = []string{"declaration"}
+
+const modifierDeclaration = 1 << 0
+
+func (s *Server) semanticTokens(params json.RawMessage) (interface{}, error) {
+	var p semanticTokensParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = &semanticTokens{Data: []int{}}
+	if d.analysis == nil {
+		return result, nil
+	}
+
+	a := d.analysis
+	mod := a.pkg.Modules[a.module]
+	t, err := a.text(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// every token is five numbers: its line and character relative to the
+	// previous token, its length and its type and modifiers
+	var last Position
+	for _, tok := range symbol.Tokens(a.pkg, mod) {
+		pos := t.position(tok.Pos)
+		char := pos.Character
+		if pos.Line == last.Line {
+			char -= last.Character
+		}
+
+		var modifiers int
+		if tok.Declaration {
+			modifiers |= modifierDeclaration
+		}
+
+		length := utf16Len(t.content[tok.Pos:tok.End])
+		result.Data = append(result.Data, pos.Line-last.Line, char, length, int(tok.Kind), modifiers)
+		last = pos
+	}
+	return result, nil
+}
+
+// completionKinds are the kinds of the completion items of every kind of
+// object.
+var completionKinds = map[ast.ObjKind]int{
+	ast.Mod:    completionModule,
+	ast.Typ:    completionClass,
+	ast.Ctor:   completionConstructor,
+	ast.Var:    completionVariable,
+	ast.VarTyp: completionTypeParameter,
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	var list = &completionList{Items: []CompletionItem{}}
+	if d.analysis == nil {
+		return list, nil
+	}
+
+	// the name being written is in the current text, which may not parse,
+	// and the names that are visible in the analysis
+	a := d.analysis
+	mod := a.pkg.Modules[a.module]
+	qualifier, prefix := trimQualifier(d.text.wordBefore(d.text.offset(p.Position)))
+
+	var objects []*ast.Object
+	if qualifier != "" {
+		objects = exposedObjects(mod, qualifier)
+	} else {
+		t, err := a.text(mod.Path)
+		if err != nil {
+			return nil, err
+		}
+		objects = visibleObjects(mod, t.offset(p.Position))
+	}
+
+	var seen = make(map[string]bool)
+	for _, obj := range objects {
+		kind, ok := completionKinds[obj.Kind]
+		if !ok || seen[obj.Name] || !strings.HasPrefix(obj.Name, prefix) {
+			continue
+		}
+		seen[obj.Name] = true
+
+		item := CompletionItem{Label: obj.Name, Kind: kind}
+		if t := typeOf(a.info, obj); t != nil {
+			item.Detail = types.TypeString(t)
+			if _, ok := t.(*types.Func); ok && obj.Kind == ast.Var {
+				item.Kind = completionFunction
+			}
+		}
+
+		if obj.Kind == ast.Typ {
+			if _, ok := obj.Node.(*ast.UnionDecl); ok {
+				item.Kind = completionEnum
+			}
+		}
+		list.Items = append(list.Items, item)
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Label < list.Items[j].Label
+	})
+	return list, nil
+}
+
+// exposedObjects returns the objects exposed by the module imported with
+// the given name or alias.
+func exposedObjects(mod *ast.Module, name string) []*ast.Object {
+	if mod.Scope == nil {
+		return nil
+	}
+
+	obj := mod.Scope.Modules[name]
+	if obj == nil {
+		return nil
+	}
+
+	imported, ok := obj.Node.(*ast.Module)
+	if !ok || imported.Scope == nil {
+		return nil
+	}
+
+	var objects []*ast.Object
+	for _, obj := range imported.Scope.Exposed {
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+// visibleObjects returns the objects that are visible at the given offset
+// of the module, from the innermost scope to the imported ones.
+func visibleObjects(mod *ast.Module, off token.Pos) []*ast.Object {
+	if mod.Scope == nil {
+		return nil
+	}
+
+	var chain = []*ast.NodeScope{mod.Scope.NodeScope}
+	for {
+		var inner *ast.NodeScope
+		for _, child := range chain[len(chain)-1].Children() {
+			if child.Root != nil && off >= child.Root.Pos() && off <= child.Root.End() {
+				inner = child
+				break
+			}
+		}
+
+		if inner == nil {
+			break
+		}
+		chain = append(chain, inner)
+	}
+
+	var objects []*ast.Object
+	for i := len(chain) - 1; i >= 0; i-- {
+		objects = append(objects, sortedObjects(chain[i].Objects)...)
+	}
+	objects = append(objects, sortedObjects(mod.Scope.Imported)...)
+	return append(objects, sortedObjects(mod.Scope.Modules)...)
+}
+
+func sortedObjects(objects map[string]*ast.Object) []*ast.Object {
+	var names []string
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []*ast.Object
+	for _, name := range names {
+		result = append(result, objects[name])
+	}
+	return result
+}
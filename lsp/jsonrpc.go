@@ -0,0 +1,83 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// conn reads and writes the messages of the protocol, each of them with a
+// header with its length:
+//
+//	Content-Length: 52\r\n
+//	\r\n
+//	{"jsonrpc":"2.0","id":1,"method":"shutdown"}
+type conn struct {
+	r *bufio.Reader
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: bufio.NewReader(r), w: w}
+}
+
+// read returns the content of the next message. It returns io.EOF if there
+// are no more messages.
+func (c *conn) read() ([]byte, error) {
+	var length = -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err == io.EOF && line == "" && length < 0 {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, fmt.Errorf("lsp: can't read header: %s", err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("lsp: invalid header %q", line)
+		}
+
+		if strings.EqualFold(strings.TrimSpace(line[:i]), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(line[i+1:])); err != nil || length < 0 {
+				return nil, fmt.Errorf("lsp: invalid content length %q", line[i+1:])
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("lsp: message without content length")
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, fmt.Errorf("lsp: can't read message: %s", err)
+	}
+	return data, nil
+}
+
+// write encodes the message and writes it with its header.
+func (c *conn) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("lsp: can't encode message: %s", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		return fmt.Errorf("lsp: can't write message: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,510 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/source"
+	"github.com/stretchr/testify/require"
+)
+
+// session is the messages sent by a client to the server.
+type session struct {
+	buf bytes.Buffer
+	id  int
+	// server is the server of the session, or a new one if it is nil.
+	server *Server
+}
+
+func (s *session) request(method string, params interface{}) int {
+	s.id++
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "id": s.id, "method": method, "params": params})
+	return s.id
+}
+
+func (s *session) notify(method string, params interface{}) {
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *session) write(msg interface{}) {
+	data, _ := json.Marshal(msg)
+	fmt.Fprintf(&s.buf, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// replies are the messages sent by the server.
+type replies struct {
+	results       map[int]json.RawMessage
+	errors        map[int]responseError
+	notifications []notification
+}
+
+func (s *session) run(t *testing.T) *replies {
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+
+	server := s.server
+	if server == nil {
+		server = NewServer()
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, server.Serve(&s.buf, &out))
+
+	r := &replies{results: make(map[int]json.RawMessage), errors: make(map[int]responseError)}
+	c := newConn(&out, ioutil.Discard)
+	for {
+		data, err := c.read()
+		if err == io.EOF {
+			return r
+		}
+		require.NoError(t, err)
+
+		var msg struct {
+			ID     *int
+			Method string
+			Params json.RawMessage
+			Result json.RawMessage
+			Error  *responseError
+		}
+		require.NoError(t, json.Unmarshal(data, &msg))
+		switch {
+		case msg.ID == nil:
+			r.notifications = append(r.notifications, notification{Method: msg.Method, Params: msg.Params})
+		case msg.Error != nil:
+			r.errors[*msg.ID] = *msg.Error
+		default:
+			r.results[*msg.ID] = msg.Result
+		}
+	}
+}
+
+func (r *replies) result(t *testing.T, id int, v interface{}) {
+	data, ok := r.results[id]
+	require.True(t, ok, "no result for request %d", id)
+	require.NoError(t, json.Unmarshal(data, v))
+}
+
+func (r *replies) diagnostics(t *testing.T) map[string][]Diagnostic {
+	var result = make(map[string][]Diagnostic)
+	for _, n := range r.notifications {
+		if n.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var p publishDiagnosticsParams
+		require.NoError(t, json.Unmarshal(n.Params.(json.RawMessage), &p))
+		result[p.URI] = p.Diagnostics
+	}
+	return result
+}
+
+func fixtureURI(t *testing.T, name string) (string, string) {
+	path, err := filepath.Abs(filepath.Join("_testdata", "project", "src", name+".elm"))
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return pathToURI(path), string(content)
+}
+
+func open(s *session, uri, content string) {
+	s.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "elm", "version": 1, "text": content},
+	})
+}
+
+func at(uri string, line, char int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     Position{Line: line, Character: char},
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	var s session
+	id := s.request("initialize", map[string]interface{}{"processId": nil, "rootUri": nil})
+	unknown := s.request("textDocument/unknown", nil)
+	r := s.run(t)
+
+	var result initializeResult
+	r.result(t, id, &result)
+	require.Equal(t, textDocumentSyncFull, result.Capabilities.TextDocumentSync)
+	require.True(t, result.Capabilities.HoverProvider)
+	require.Equal(t, codeMethodNotFound, r.errors[unknown].Code)
+	require.Equal(t, serverInfo{Name: "elmo"}, result.ServerInfo)
+	require.Nil(t, result.Capabilities.Experimental)
+}
+
+func TestInitializeBuildInfo(t *testing.T) {
+	var s session
+	s.server = NewServer()
+	s.server.Version = "1.2.0"
+	s.server.BuildInfo = map[string]interface{}{"features": []string{"lsp"}}
+	id := s.request("initialize", map[string]interface{}{"processId": nil, "rootUri": nil})
+	r := s.run(t)
+
+	var result struct {
+		Capabilities struct {
+			Experimental map[string]interface{} `json:"experimental"`
+		} `json:"capabilities"`
+		ServerInfo serverInfo `json:"serverInfo"`
+	}
+	r.result(t, id, &result)
+	require.Equal(t, serverInfo{Name: "elmo", Version: "1.2.0"}, result.ServerInfo)
+	require.Equal(t, map[string]interface{}{
+		"buildInfo": map[string]interface{}{"features": []interface{}{"lsp"}},
+	}, result.Capabilities.Experimental)
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	var s session
+	s.notify("exit", nil)
+	require.Equal(t, ErrNoShutdown, NewServer().Serve(&s.buf, ioutil.Discard))
+}
+
+func TestDiagnostics(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, content)
+	s.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]interface{}{{"text": content + "\nbroken = = 1\n"}},
+	})
+	r := s.run(t)
+
+	require.Len(t, r.notifications, 2)
+	var first publishDiagnosticsParams
+	require.NoError(t, json.Unmarshal(r.notifications[0].Params.(json.RawMessage), &first))
+	require.Equal(t, uri, first.URI)
+	require.Empty(t, first.Diagnostics)
+
+	diags := r.diagnostics(t)[uri]
+	require.Len(t, diags, 1)
+	require.Equal(t, severityError, diags[0].Severity)
+	require.Equal(t, 13, diags[0].Range.Start.Line)
+}
+
+func TestDiagnosticsUTF16(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, content+"\nbroken = ( \"😀😀\", missing )\n")
+	r := s.run(t)
+
+	diags := r.diagnostics(t)[uri]
+	require.Len(t, diags, 1)
+	require.Equal(t, Range{Position{13, 19}, Position{13, 19}}, diags[0].Range)
+}
+
+func TestLinePosition(t *testing.T) {
+	text := newText("a = 1\n\t\"😀\" b\n")
+	require.Equal(t, Position{0, 4}, text.linePosition(source.LinePos{Line: 1, Col: 5}))
+	require.Equal(t, Position{1, 1}, text.linePosition(source.LinePos{Line: 2, Col: 5}))
+	require.Equal(t, Position{1, 5}, text.linePosition(source.LinePos{Line: 2, Col: 8}))
+	require.Equal(t, Position{1, 7}, text.linePosition(source.LinePos{Line: 2, Col: 20}))
+	require.Equal(t, Position{}, text.linePosition(source.LinePos{}))
+}
+
+func TestHover(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, content)
+	double := s.request("textDocument/hover", at(uri, 9, 13))
+	size := s.request("textDocument/hover", at(uri, 8, 9))
+	none := s.request("textDocument/hover", at(uri, 4, 0))
+	r := s.run(t)
+
+	var h hover
+	r.result(t, double, &h)
+	require.Equal(t, "markdown", h.Contents.Kind)
+	require.Equal(t, "```elm\nidentity : a -> a\n```\n\nDefined in `Util`.\n\nReturns its argument.", h.Contents.Value)
+	require.Equal(t, Range{Position{9, 12}, Position{9, 20}}, *h.Range)
+
+	r.result(t, size, &h)
+	require.Equal(t, "```elm\nsize : number\n```", h.Contents.Value)
+	require.Equal(t, "null", string(r.results[none]))
+}
+
+func TestDefinition(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+	util, _ := fixtureURI(t, "Util")
+
+	var s session
+	open(&s, uri, content)
+	double := s.request("textDocument/definition", at(uri, 9, 14))
+	size := s.request("textDocument/definition", at(uri, 11, 28))
+	r := s.run(t)
+
+	var locs []Location
+	r.result(t, double, &locs)
+	require.Equal(t, []Location{{URI: util, Range: Range{Position{16, 0}, Position{16, 8}}}}, locs)
+
+	r.result(t, size, &locs)
+	require.Equal(t, []Location{{URI: uri, Range: Range{Position{8, 8}, Position{8, 12}}}}, locs)
+}
+
+func TestReferences(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, content)
+	params := at(uri, 8, 9)
+	params["context"] = map[string]bool{"includeDeclaration": true}
+	id := s.request("textDocument/references", params)
+	r := s.run(t)
+
+	var locs []Location
+	r.result(t, id, &locs)
+	require.Equal(t, []Location{
+		{URI: uri, Range: Range{Position{8, 8}, Position{8, 12}}},
+		{URI: uri, Range: Range{Position{11, 27}, Position{11, 31}}},
+	}, locs)
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	uri, content := fixtureURI(t, "Util")
+
+	var s session
+	open(&s, uri, content)
+	id := s.request("textDocument/documentSymbol", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+	})
+	r := s.run(t)
+
+	var symbols []DocumentSymbol
+	r.result(t, id, &symbols)
+
+	var names []string
+	for _, sym := range symbols {
+		names = append(names, fmt.Sprintf("%s %d %s", sym.Name, sym.Kind, sym.Detail))
+		for _, c := range sym.Children {
+			names = append(names, fmt.Sprintf("  %s %d", c.Name, c.Kind))
+		}
+	}
+
+	require.Equal(t, []string{
+		"Shape 10 type",
+		"  Square 22",
+		"  Rect 22",
+		"identity 12 a -> a",
+		"width 12 Util.Shape -> Int",
+	}, names)
+}
+
+func TestWorkspaceSymbol(t *testing.T) {
+	require := require.New(t)
+	uri, content := fixtureURI(t, "Main")
+	util, _ := fixtureURI(t, "Util")
+
+	var s session
+	open(&s, uri, content)
+	id := s.request("workspace/symbol", map[string]interface{}{"query": "sq"})
+	all := s.request("workspace/symbol", map[string]interface{}{"query": ""})
+	r := s.run(t)
+
+	var symbols []SymbolInformation
+	r.result(t, id, &symbols)
+	require.Equal([]SymbolInformation{{
+		Name:          "Square",
+		Kind:          symbolEnumMember,
+		ContainerName: "Shape",
+		Location:      Location{URI: util, Range: Range{Position{9, 6}, Position{9, 16}}},
+	}}, symbols)
+
+	r.result(t, all, &symbols)
+	var names []string
+	for _, sym := range symbols {
+		names = append(names, sym.ContainerName+"."+sym.Name)
+	}
+	require.Equal([]string{"Shape.Rect", "Main.main", "Util.Shape", "Util.width", "Shape.Square", "Util.identity"}, names)
+}
+
+func TestSemanticTokens(t *testing.T) {
+	uri, content := fixtureURI(t, "Util")
+
+	var s session
+	open(&s, uri, content)
+	id := s.request("textDocument/semanticTokens/full", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+	})
+	r := s.run(t)
+
+	var tokens semanticTokens
+	r.result(t, id, &tokens)
+	require.Equal(t, []int{
+		0, 7, 4, 0, modifierDeclaration,
+		0, 15, 5, 1, 0,
+		0, 11, 8, 4, 0,
+		0, 10, 5, 4, 0,
+		8, 5, 5, 1, modifierDeclaration,
+		1, 6, 6, 3, modifierDeclaration,
+	}, tokens.Data[:30])
+}
+
+func TestCompletion(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, content)
+	qualified := s.request("textDocument/completion", at(uri, 11, 14))
+	local := s.request("textDocument/completion", at(uri, 11, 29))
+	r := s.run(t)
+
+	labels := func(id int) []string {
+		var list completionList
+		r.result(t, id, &list)
+
+		var result []string
+		for _, item := range list.Items {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+
+	require.Equal(t, []string{"width"}, labels(qualified))
+	require.Equal(t, []string{"size"}, labels(local))
+}
+
+func TestRename(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+	util, _ := fixtureURI(t, "Util")
+
+	var s session
+	open(&s, uri, content)
+	params := at(uri, 9, 13)
+	params["newName"] = "id"
+	id := s.request("textDocument/rename", params)
+	params = at(uri, 9, 13)
+	params["newName"] = "width"
+	collision := s.request("textDocument/rename", params)
+	r := s.run(t)
+
+	var edit WorkspaceEdit
+	r.result(t, id, &edit)
+	require.Equal(t, []TextEdit{
+		{Range: Range{Position{2, 33}, Position{2, 41}}, NewText: "id"},
+		{Range: Range{Position{9, 12}, Position{9, 20}}, NewText: "id"},
+	}, edit.Changes[uri])
+	require.Len(t, edit.Changes[util], 3)
+
+	require.Equal(t, codeRequestFailed, r.errors[collision].Code)
+	require.Equal(t, "refactor: width is already defined in module Util", r.errors[collision].Message)
+}
+
+func TestCodeAction(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+	content = strings.Replace(content, "import Util exposing (Shape(..), identity)", "import Util exposing (identity, Shape(..), width)", 1)
+
+	var s session
+	open(&s, uri, content)
+	params := map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range":        Range{},
+		"context":      map[string]interface{}{"diagnostics": []interface{}{}},
+	}
+	id := s.request("textDocument/codeAction", params)
+	params["context"] = map[string]interface{}{"only": []string{"quickfix"}}
+	quickfix := s.request("textDocument/codeAction", params)
+	r := s.run(t)
+
+	var actions []CodeAction
+	r.result(t, id, &actions)
+	require.Len(t, actions, 1)
+	require.Equal(t, organizeImports, actions[0].Kind)
+	require.Equal(t, []TextEdit{{
+		Range:   Range{Position{2, 0}, Position{2, 49}},
+		NewText: "import Util exposing (Shape(..), identity)",
+	}}, actions[0].Edit.Changes[uri])
+
+	r.result(t, quickfix, &actions)
+	require.Empty(t, actions)
+}
+
+func TestQuickFix(t *testing.T) {
+	uri, _ := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, "module Main exposing (main)\n\nimport Util exposing (Shape(..))\n\n\nmain : Int\nmain =\n    identity 2\n")
+	params := map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range":        Range{Position{7, 0}, Position{7, 14}},
+		"context":      map[string]interface{}{"only": []string{"quickfix"}},
+	}
+	id := s.request("textDocument/codeAction", params)
+	params["range"] = Range{Position{5, 0}, Position{6, 6}}
+	outside := s.request("textDocument/codeAction", params)
+	r := s.run(t)
+
+	var actions []CodeAction
+	r.result(t, id, &actions)
+	require.Len(t, actions, 1)
+	require.Equal(t, quickFix, actions[0].Kind)
+	require.Equal(t, `Expose "identity" in the import of Util`, actions[0].Title)
+	require.Len(t, actions[0].Diagnostics, 1)
+	require.Equal(t, []TextEdit{{
+		Range:   Range{Position{2, 31}, Position{2, 31}},
+		NewText: ", identity",
+	}}, actions[0].Edit.Changes[uri])
+
+	r.result(t, outside, &actions)
+	require.Empty(t, actions)
+}
+
+func TestQuickFixTabs(t *testing.T) {
+	uri, _ := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, "module Main exposing (main)\n\nimport\tUtil exposing (Shape(..))\n\n\nmain : Int\nmain =\n    identity 2\n")
+	id := s.request("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range":        Range{Position{7, 0}, Position{7, 14}},
+		"context":      map[string]interface{}{"only": []string{"quickfix"}},
+	})
+	r := s.run(t)
+
+	var actions []CodeAction
+	r.result(t, id, &actions)
+	require.Len(t, actions, 1)
+	require.Equal(t, []TextEdit{{
+		Range:   Range{Position{2, 31}, Position{2, 31}},
+		NewText: ", identity",
+	}}, actions[0].Edit.Changes[uri])
+}
+
+func TestExtractAction(t *testing.T) {
+	uri, content := fixtureURI(t, "Main")
+
+	var s session
+	open(&s, uri, content)
+	id := s.request("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range":        Range{Position{11, 20}, Position{11, 31}},
+		"context":      map[string]interface{}{"only": []string{"refactor"}},
+	})
+	r := s.run(t)
+
+	var actions []CodeAction
+	r.result(t, id, &actions)
+	require.Len(t, actions, 2)
+
+	require.Equal(t, "Extract function", actions[0].Title)
+	require.Equal(t, refactorExtract, actions[0].Kind)
+	require.Equal(t, []TextEdit{
+		{Range: Range{Position{5, 0}, Position{5, 0}}, NewText: "extracted size =\n    Square size\n\n\n"},
+		{Range: Range{Position{11, 20}, Position{11, 31}}, NewText: "extracted size"},
+	}, actions[0].Edit.Changes[uri])
+
+	require.Equal(t, "Extract let binding", actions[1].Title)
+	require.Equal(t, []TextEdit{
+		{Range: Range{Position{9, 22}, Position{9, 22}}, NewText: "\n\n        extracted =\n            Square size"},
+		{Range: Range{Position{11, 19}, Position{11, 32}}, NewText: "extracted"},
+	}, actions[1].Edit.Changes[uri])
+}
@@ -0,0 +1,312 @@
+package lsp
+
+import "encoding/json"
+
+// The messages of the protocol, which are JSON-RPC 2.0 requests, responses
+// and notifications.
+
+// request is a request or a notification received from the client. Only
+// the requests have an ID.
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// response is the successful response to a request, whose result can be
+// null.
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+// errorResponse is the response to a request that failed.
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   responseError    `json:"error"`
+}
+
+// notification is a notification sent to the client.
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// The error codes of the responses.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+	codeRequestFailed  = -32803
+)
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Position is a position in a document. Both the line and the character are
+// zero based, and the character is counted in UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is the range between two positions of a document.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range of the document with the given URI.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type initializeParams struct {
+	RootURI  string `json:"rootUri"`
+	RootPath string `json:"rootPath"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type serverInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// textDocumentSyncFull means that the whole content of the documents is
+// sent with every change.
+const textDocumentSyncFull = 1
+
+type serverCapabilities struct {
+	TextDocumentSync        int                       `json:"textDocumentSync"`
+	HoverProvider           bool                      `json:"hoverProvider"`
+	DefinitionProvider      bool                      `json:"definitionProvider"`
+	ReferencesProvider      bool                      `json:"referencesProvider"`
+	DocumentSymbolProvider  bool                      `json:"documentSymbolProvider"`
+	WorkspaceSymbolProvider bool                      `json:"workspaceSymbolProvider"`
+	CompletionProvider      *completionOptions        `json:"completionProvider"`
+	RenameProvider          bool                      `json:"renameProvider"`
+	CodeActionProvider      *codeActionOptions        `json:"codeActionProvider"`
+	SemanticTokensProvider  *semanticTokensOptions    `json:"semanticTokensProvider"`
+	Experimental            *experimentalCapabilities `json:"experimental,omitempty"`
+}
+
+// experimentalCapabilities are the capabilities of the server that are not
+// part of the protocol.
+type experimentalCapabilities struct {
+	BuildInfo interface{} `json:"buildInfo"`
+}
+
+type codeActionOptions struct {
+	CodeActionKinds []string `json:"codeActionKinds"`
+}
+
+type semanticTokensOptions struct {
+	Legend semanticTokensLegend `json:"legend"`
+	Full   bool                 `json:"full"`
+}
+
+type semanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type completionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type textDocumentItem struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange        `json:"contentChanges"`
+}
+
+// contentChange is a change of a document. The server only asks for full
+// changes, so the text is the whole new content.
+type contentChange struct {
+	Text string `json:"text"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type referenceParams struct {
+	textDocumentPositionParams
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+type renameParams struct {
+	textDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type codeActionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      struct {
+		Only []string `json:"only"`
+	} `json:"context"`
+}
+
+// organizeImports is the kind of the code action that organizes the
+// imports of a module.
+const organizeImports = "source.organizeImports"
+
+// refactorExtract is the kind of the code actions that extract code to a
+// new declaration.
+const refactorExtract = "refactor.extract"
+
+// quickFix is the kind of the code actions that fix the problem of a
+// diagnostic.
+const quickFix = "quickfix"
+
+// CodeAction is a change of the code the editor can offer.
+type CodeAction struct {
+	Title string `json:"title"`
+	Kind  string `json:"kind"`
+	// Diagnostics are the diagnostics whose problem the action fixes.
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit"`
+}
+
+type documentSymbolParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// The severities of the diagnostics.
+const (
+	severityError   = 1
+	severityWarning = 2
+	severityInfo    = 3
+)
+
+// Diagnostic is a problem in a document.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// The kinds of the symbols.
+const (
+	symbolModule     = 2
+	symbolClass      = 5
+	symbolField      = 8
+	symbolEnum       = 10
+	symbolFunction   = 12
+	symbolVariable   = 13
+	symbolEnumMember = 22
+	symbolOperator   = 25
+)
+
+// DocumentSymbol is a declaration of a document.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+type semanticTokensParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type semanticTokens struct {
+	Data []int `json:"data"`
+}
+
+type workspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// SymbolInformation is a declaration found in the workspace.
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+// The kinds of the completion items.
+const (
+	completionFunction      = 3
+	completionConstructor   = 4
+	completionVariable      = 6
+	completionClass         = 7
+	completionModule        = 9
+	completionEnum          = 13
+	completionTypeParameter = 25
+)
+
+// CompletionItem is a name that can be completed.
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type completionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// TextEdit is the replacement of a range of a document.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit are the edits of many documents by their URI.
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
@@ -0,0 +1,374 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/refactor"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/symbol"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// errUnparsed is the error of the refactorings of modules that cannot be
+// parsed, because their edits could not be computed.
+var errUnparsed = errRequestFailed{errors.New("the modules have errors, fix them first")}
+
+func (s *Server) rename(params json.RawMessage) (interface{}, error) {
+	var p renameParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last analysis may be of a previous version of the documents, so
+	// the edits are computed from their current content
+	overlay := s.overlay()
+	tree, err := parseOverlay(d.path, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	var mod *ast.Module
+	for _, m := range tree.Modules {
+		if filepath.Clean(m.Path) == d.path {
+			mod = m
+		}
+	}
+
+	var obj *ast.Object
+	if mod != nil {
+		if id := symbol.IdentAt(mod, d.text.offset(p.Position)); id != nil {
+			obj = symbol.ObjectOf(tree, mod, id)
+		}
+	}
+
+	if obj == nil {
+		return nil, errRequestFailed{errors.New("there is nothing to rename here")}
+	}
+
+	def := symbol.ModuleOf(tree, mod, obj)
+	if strings.Contains(filepath.ToSlash(def.Path), "/elm-stuff/") {
+		return nil, errRequestFailed{fmt.Errorf("%s is defined in a dependency", obj.Name)}
+	}
+
+	edits, err := refactor.Rename(tree, obj, p.NewName)
+	if err != nil {
+		return nil, errRequestFailed{err}
+	}
+
+	// the top-level declarations are used by the modules of the package
+	// that the document does not import as well
+	if def.Scope != nil && def.Scope.Objects[obj.Name] == obj {
+		more, err := renamePackage(d.path, overlay, tree, def.Name, obj.Name, p.NewName)
+		if err != nil {
+			return nil, err
+		}
+		edits = refactor.Merge(edits, more)
+	}
+
+	return workspaceEdit(overlay, edits)
+}
+
+// workspaceEdit returns the edits of the files with the ranges of the
+// documents, which are computed from the given content of the open ones.
+func workspaceEdit(overlay source.Overlay, edits []refactor.Edit) (*WorkspaceEdit, error) {
+	a := &analysis{overlay: overlay, texts: make(map[string]*text)}
+	var changes = make(map[string][]TextEdit)
+	for _, e := range edits {
+		t, err := a.text(e.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		uri := pathToURI(filepath.Clean(e.Path))
+		changes[uri] = append(changes[uri], TextEdit{Range: t.rangeOf(e.Pos, e.End), NewText: e.Text})
+	}
+	return &WorkspaceEdit{Changes: changes}, nil
+}
+
+func (s *Server) codeAction(params json.RawMessage) (interface{}, error) {
+	var p codeActionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions = []CodeAction{}
+	overlay := s.overlay()
+	if wants(p.Context.Only, quickFix) {
+		fixes, err := quickFixes(d.path, overlay, p.Range)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, fixes...)
+	}
+
+	if !wants(p.Context.Only, organizeImports) && !wants(p.Context.Only, refactorExtract) {
+		return actions, nil
+	}
+
+	// the modules with errors cannot be refactored, so there are no more
+	// actions offered
+	tree, err := parseOverlay(d.path, overlay)
+	if err == errUnparsed {
+		return actions, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var mod *ast.Module
+	for _, m := range tree.Modules {
+		if filepath.Clean(m.Path) == d.path {
+			mod = m
+		}
+	}
+	if mod == nil {
+		return actions, nil
+	}
+
+	// the imports cannot be organized if there are comments between them
+	if wants(p.Context.Only, organizeImports) {
+		if edits, err := refactor.OrganizeImports(mod); err == nil && len(edits) > 0 {
+			edit, err := workspaceEdit(overlay, edits)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, CodeAction{Title: "Organize imports", Kind: organizeImports, Edit: edit})
+		}
+	}
+
+	if wants(p.Context.Only, refactorExtract) && p.Range.Start != p.Range.End {
+		pos, end := d.text.offset(p.Range.Start), d.text.offset(p.Range.End)
+		extracted, err := extractions(mod, overlay, []byte(d.text.content), pos, end)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, extracted...)
+	}
+	return actions, nil
+}
+
+// extractions returns the code actions that extract the expression between
+// pos and end of the module to a function or a let binding, if it is an
+// expression. There is no way to ask for the name, so it is a new name
+// that the editor can rename afterwards.
+func extractions(mod *ast.Module, overlay source.Overlay, src []byte, pos, end token.Pos) ([]CodeAction, error) {
+	var refactorings = []struct {
+		title   string
+		extract func(*ast.Module, []byte, token.Pos, token.Pos, string) ([]refactor.Edit, error)
+	}{
+		{"Extract function", refactor.ExtractFunction},
+		{"Extract let binding", refactor.ExtractLet},
+	}
+
+	name := unusedName(mod, "extracted")
+	var actions []CodeAction
+	for _, r := range refactorings {
+		edits, err := r.extract(mod, src, pos, end, name)
+		if err != nil {
+			continue
+		}
+
+		edit, err := workspaceEdit(overlay, edits)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, CodeAction{Title: r.title, Kind: refactorExtract, Edit: edit})
+	}
+	return actions, nil
+}
+
+// unusedName returns the given name, followed by a number if it is used in
+// the module already.
+func unusedName(mod *ast.Module, name string) string {
+	var used = make(map[string]bool)
+	ast.WalkFunc(mod, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			used[id.Name] = true
+		}
+		return n != nil
+	})
+
+	candidate := name
+	for i := 2; used[candidate] || mod.Scope != nil && mod.Scope.Imported[candidate] != nil; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	return candidate
+}
+
+// quickFixes returns the code actions that apply the fixes of the
+// diagnostics of the module at the given path that start in the range.
+func quickFixes(path string, overlay source.Overlay, rng Range) ([]CodeAction, error) {
+	fixes := &fixable{path: path}
+	_, err := parser.ParseOverlay(path, parser.FullParse|parser.ParseComments, fixes, overlay)
+	if err != nil && err != parser.ErrProblems {
+		return nil, err
+	}
+
+	t, err := readText(path, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []CodeAction
+	for _, d := range fixes.diags {
+		diag := newDiagnostic(t, d)
+		if diag.Range.Start.Line < rng.Start.Line || diag.Range.Start.Line > rng.End.Line {
+			continue
+		}
+
+		for _, f := range d.Fixes {
+			edit := TextEdit{Range: Range{t.linePosition(f.Start), t.linePosition(f.End)}, NewText: f.Text}
+			actions = append(actions, CodeAction{
+				Title:       f.Message,
+				Kind:        quickFix,
+				Diagnostics: []Diagnostic{diag},
+				Edit:        &WorkspaceEdit{Changes: map[string][]TextEdit{pathToURI(path): {edit}}},
+			})
+		}
+	}
+	return actions, nil
+}
+
+// fixable is an emitter that keeps the diagnostics of a file that have
+// fixes.
+type fixable struct {
+	path  string
+	diags []*report.Diagnostic
+}
+
+// Emit implements the report.Emitter interface.
+func (f *fixable) Emit(file string, diags []*report.Diagnostic) error {
+	if filepath.Clean(file) != f.path {
+		return nil
+	}
+
+	for _, d := range diags {
+		if len(d.Fixes) > 0 {
+			f.diags = append(f.diags, d)
+		}
+	}
+	return nil
+}
+
+// wants reports whether the code actions of the given kind are wanted by
+// the client, which are all of them if it asks for no kinds. The kinds are
+// hierarchical, so "source" includes "source.organizeImports".
+func wants(only []string, kind string) bool {
+	if len(only) == 0 {
+		return true
+	}
+
+	for _, k := range only {
+		if k == kind || strings.HasPrefix(kind, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// renamePackage returns the edits that rename the top-level declaration of
+// the module in the modules of the package of the given file that were not
+// parsed already.
+func renamePackage(path string, overlay source.Overlay, parsed *ast.Package, module, name, newName string) ([]refactor.Edit, error) {
+	files, err := packageFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var seen = make(map[string]bool)
+	for _, m := range parsed.Modules {
+		seen[filepath.Clean(m.Path)] = true
+	}
+
+	var edits [][]refactor.Edit
+	for _, file := range files {
+		if seen[file] {
+			continue
+		}
+
+		tree, err := parseOverlay(file, overlay)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range tree.Modules {
+			seen[filepath.Clean(m.Path)] = true
+		}
+
+		obj := refactor.Lookup(tree, module, name)
+		if obj == nil {
+			continue
+		}
+
+		e, err := refactor.Rename(tree, obj, newName)
+		if err != nil {
+			return nil, errRequestFailed{err}
+		}
+		edits = append(edits, e)
+	}
+	return refactor.Merge(edits...), nil
+}
+
+// parseOverlay parses the module at the given path and the modules it
+// imports with the content of the open documents.
+func parseOverlay(path string, overlay source.Overlay) (*ast.Package, error) {
+	diags := newDiagnostics(overlay)
+	tree, err := parser.ParseOverlay(path, parser.FullParse|parser.ParseComments, diags, overlay)
+	if err == parser.ErrProblems {
+		return nil, errUnparsed
+	}
+	return tree, err
+}
+
+// packageFiles returns the paths of the Elm files in the source directories
+// of the package of the given file.
+func packageFiles(path string) ([]string, error) {
+	p, err := pkg.Load(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, dir := range p.SourceDirectories {
+		err := filepath.Walk(filepath.Join(p.Root(), dir), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() && info.Name() == "elm-stuff" {
+				return filepath.SkipDir
+			}
+
+			if !info.IsDir() && filepath.Ext(path) == ".elm" {
+				abs, err := filepath.Abs(path)
+				if err != nil {
+					return err
+				}
+				files = append(files, abs)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,528 @@
+// Package lsp implements a server of the Language Server Protocol for Elm
+// packages, which gives editors the diagnostics of the modules being edited,
+// the types of the values on hover, the definitions and references of the
+// names, the declarations of the modules and the search of those of the
+// package, the kinds of the names for highlighting, the completion of
+// names, the renaming of names in all the modules of the package and the
+// organization of the imports.
+//
+// The documents open in the editor are checked with their unsaved content
+// every time they change, along with all the modules they import. The
+// features that need the syntax tree of a module use the last version of it
+// that could be parsed, so they keep working while it is being edited.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// ErrNoShutdown is returned by Serve when the client asks the server to
+// exit without asking it to shut down first.
+var ErrNoShutdown = errors.New("lsp: exit without shutdown")
+
+// Server is a server of the protocol. It handles one request at a time, in
+// the order they are received.
+type Server struct {
+	// Log receives the errors of the requests and notifications, if it is
+	// not nil.
+	Log *log.Logger
+	// Version is the version of the server sent to the client when it is
+	// initialized, and BuildInfo what the compiler supports, which is sent
+	// as the buildInfo of the experimental capabilities, if they are set.
+	Version   string
+	BuildInfo interface{}
+
+	conn *conn
+	// docs are the documents open in the editor by their path.
+	docs map[string]*document
+	// shutdown reports whether the client asked the server to shut down.
+	shutdown bool
+	// sources are the sources of the files of the previous analyses, which
+	// are only scanned again once they change.
+	sources *source.Cache
+}
+
+// document is a document open in the editor.
+type document struct {
+	path string
+	text *text
+	// analysis is the last analysis of the document that could be parsed,
+	// which may be of a previous version of its text.
+	analysis *analysis
+	// published are the paths of the files the diagnostics of the last
+	// analysis of the document were published for.
+	published []string
+}
+
+// analysis is a module and all the modules it imports, parsed and type
+// checked.
+type analysis struct {
+	pkg *ast.Package
+	// module is the name of the module that was analysed.
+	module string
+	info   *types.Info
+	// overlay is the content of the open documents when the analysis was
+	// made, and texts are the contents of the files of the modules, which
+	// are loaded when needed.
+	overlay source.Overlay
+	texts   map[string]*text
+}
+
+// NewServer returns a new server without documents.
+func NewServer() *Server {
+	return &Server{docs: make(map[string]*document), sources: source.NewCache()}
+}
+
+// Serve reads the requests and notifications from r and writes the
+// responses and notifications to w until the client asks the server to
+// exit or there are no more messages.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		data, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{codeParseError, err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+
+		result, rerr := s.handle(&req)
+		if req.ID == nil {
+			if rerr != nil {
+				s.logf("%s: %s", req.Method, rerr.Message)
+			}
+			continue
+		}
+
+		if err := s.reply(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	if rerr != nil {
+		s.logf("%s", rerr.Message)
+		return s.conn.write(&errorResponse{JSONRPC: "2.0", ID: id, Error: *rerr})
+	}
+	return s.conn.write(&response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	if err := s.conn.write(&notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		s.logf("%s", err)
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Log != nil {
+		s.Log.Printf(format, args...)
+	}
+}
+
+// handlers are the handlers of the requests and notifications by their
+// method. The notifications that are not here are ignored.
+var handlers = map[string]func(*Server, json.RawMessage) (interface{}, error){
+	"initialize":                       (*Server).initialize,
+	"initialized":                      func(*Server, json.RawMessage) (interface{}, error) { return nil, nil },
+	"shutdown":                         (*Server).shutdownRequest,
+	"textDocument/didOpen":             (*Server).didOpen,
+	"textDocument/didChange":           (*Server).didChange,
+	"textDocument/didClose":            (*Server).didClose,
+	"textDocument/didSave":             func(*Server, json.RawMessage) (interface{}, error) { return nil, nil },
+	"textDocument/hover":               (*Server).hover,
+	"textDocument/definition":          (*Server).definition,
+	"textDocument/references":          (*Server).references,
+	"textDocument/documentSymbol":      (*Server).documentSymbol,
+	"textDocument/completion":          (*Server).completion,
+	"textDocument/rename":              (*Server).rename,
+	"textDocument/codeAction":          (*Server).codeAction,
+	"textDocument/semanticTokens/full": (*Server).semanticTokens,
+	"workspace/symbol":                 (*Server).workspaceSymbol,
+}
+
+// errInvalidParams is the error of the requests whose parameters cannot be
+// decoded.
+type errInvalidParams struct {
+	err error
+}
+
+func (e errInvalidParams) Error() string {
+	return fmt.Sprintf("invalid parameters: %s", e.err)
+}
+
+// errRequestFailed is the error of the requests that are valid but cannot
+// be done, such as renames to a name that is already used.
+type errRequestFailed struct {
+	err error
+}
+
+func (e errRequestFailed) Error() string {
+	return e.err.Error()
+}
+
+func (s *Server) handle(req *request) (interface{}, *responseError) {
+	h, ok := handlers[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil, nil
+		}
+		return nil, &responseError{codeMethodNotFound, fmt.Sprintf("method %q not found", req.Method)}
+	}
+
+	result, err := h(s, req.Params)
+	if _, ok := err.(errInvalidParams); ok {
+		return nil, &responseError{codeInvalidParams, err.Error()}
+	} else if _, ok := err.(errRequestFailed); ok {
+		return nil, &responseError{codeRequestFailed, err.Error()}
+	} else if err != nil {
+		return nil, &responseError{codeInternalError, err.Error()}
+	}
+	return result, nil
+}
+
+// decode decodes the parameters of a request.
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return errInvalidParams{err}
+	}
+	return nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p initializeParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	result := &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:        textDocumentSyncFull,
+			HoverProvider:           true,
+			DefinitionProvider:      true,
+			ReferencesProvider:      true,
+			DocumentSymbolProvider:  true,
+			WorkspaceSymbolProvider: true,
+			CompletionProvider:      &completionOptions{TriggerCharacters: []string{"."}},
+			RenameProvider:          true,
+			CodeActionProvider:      &codeActionOptions{CodeActionKinds: []string{quickFix, organizeImports, refactorExtract}},
+			SemanticTokensProvider: &semanticTokensOptions{
+				Legend: semanticTokensLegend{
+					TokenTypes:     semanticTokenTypes[:],
+					TokenModifiers: semanticTokenModifiers,
+				},
+				Full: true,
+			},
+		},
+		ServerInfo: serverInfo{Name: "elmo", Version: s.Version},
+	}
+	if s.BuildInfo != nil {
+		result.Capabilities.Experimental = &experimentalCapabilities{BuildInfo: s.BuildInfo}
+	}
+	return result, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p didOpenParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	path, ok := uriToPath(p.TextDocument.URI)
+	if !ok {
+		return nil, errInvalidParams{fmt.Errorf("%q is not a file URI", p.TextDocument.URI)}
+	}
+
+	doc := &document{path: path, text: newText(p.TextDocument.Text)}
+	s.docs[path] = doc
+	s.analyze(doc)
+	return nil, nil
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var p didChangeParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+
+	// the changes are full, so only the last one matters
+	doc.text = newText(p.ContentChanges[len(p.ContentChanges)-1].Text)
+	s.analyze(doc)
+	return nil, nil
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var p didCloseParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(s.docs, doc.path)
+	for _, path := range doc.published {
+		s.publish(path, nil)
+	}
+	return nil, nil
+}
+
+// document returns the open document with the given URI.
+func (s *Server) document(uri string) (*document, error) {
+	path, ok := uriToPath(uri)
+	if !ok {
+		return nil, errInvalidParams{fmt.Errorf("%q is not a file URI", uri)}
+	}
+
+	doc, ok := s.docs[path]
+	if !ok {
+		return nil, fmt.Errorf("the document %s is not open", uri)
+	}
+	return doc, nil
+}
+
+// overlay returns the content of the open documents.
+func (s *Server) overlay() source.Overlay {
+	var overlay = make(source.Overlay)
+	for path, doc := range s.docs {
+		overlay[path] = doc.text.content
+	}
+	return overlay
+}
+
+// analyze parses and type checks the document and the modules it imports,
+// and publishes their diagnostics. The analysis of the document is only
+// replaced if it could be parsed.
+func (s *Server) analyze(doc *document) {
+	overlay := s.overlay()
+	diags := newDiagnostics(overlay)
+	if a, err := newAnalysis(doc.path, overlay, s.sources, diags); err != nil {
+		diags.files[doc.path] = append(diags.files[doc.path], Diagnostic{
+			Severity: severityError,
+			Source:   "elmo",
+			Message:  err.Error(),
+		})
+	} else if a != nil {
+		doc.analysis = a
+	}
+
+	// the document always has its diagnostics published, even if it has
+	// none, so those of its previous versions are cleared
+	if _, ok := diags.files[doc.path]; !ok {
+		diags.files[doc.path] = nil
+	}
+
+	var paths []string
+	for path := range diags.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// the files without diagnostics anymore have them cleared
+	for _, path := range doc.published {
+		if _, ok := diags.files[path]; !ok {
+			s.publish(path, nil)
+		}
+	}
+
+	for _, path := range paths {
+		s.publish(path, diags.files[path])
+	}
+	doc.published = paths
+}
+
+func (s *Server) publish(path string, diags []Diagnostic) {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{URI: pathToURI(path), Diagnostics: diags})
+}
+
+// newAnalysis analyses the module at the given path with the files of the
+// overlay, whose sources are kept in the cache. The diagnostics are emitted
+// to diags, and the analysis is nil if the modules could not be parsed. The
+// error is the one of loading the package or the files.
+func newAnalysis(path string, overlay source.Overlay, cache *source.Cache, diags *diagnostics) (*analysis, error) {
+	parsed, err := parser.ParseCached(path, parser.FullParse|parser.ParseComments, diags, nil, overlay, cache)
+	if err == parser.ErrProblems {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if parsed == nil || len(parsed.Order) == 0 {
+		return nil, nil
+	}
+
+	p, err := pkg.Load(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	cm := source.NewCodeMap(source.NewOverlayLoader(source.NewFsLoader(p), overlay))
+	defer cm.Close()
+
+	reporter := report.NewReporter(cm, diags)
+	var loadErr error
+	conf := types.Config{
+		Error: func(err *types.Error) {
+			if err := cm.Add(err.Path); err != nil {
+				loadErr = err
+				return
+			}
+			reporter.Report(report.NewTypeError(err))
+		},
+	}
+
+	a := &analysis{
+		pkg:     parsed,
+		module:  parsed.Order[len(parsed.Order)-1],
+		info:    types.NewInfo(),
+		overlay: overlay,
+		texts:   make(map[string]*text),
+	}
+
+	// the type errors are diagnostics, and the analysis is still useful
+	// with them
+	conf.Check(parsed, a.info)
+	if err := reporter.Emit(); err != nil {
+		return nil, err
+	}
+	return a, loadErr
+}
+
+// text returns the text of the file at the given path, as it was when the
+// analysis was made.
+func (a *analysis) text(path string) (*text, error) {
+	path = filepath.Clean(path)
+	if t, ok := a.texts[path]; ok {
+		return t, nil
+	}
+
+	t, err := readText(path, a.overlay)
+	if err != nil {
+		return nil, err
+	}
+	a.texts[path] = t
+	return t, nil
+}
+
+// diagnostics is an emitter that keeps the diagnostics of every file, with
+// the texts of the files, which are their content in the overlay if they
+// are open.
+type diagnostics struct {
+	files   map[string][]Diagnostic
+	overlay source.Overlay
+	texts   map[string]*text
+}
+
+func newDiagnostics(overlay source.Overlay) *diagnostics {
+	return &diagnostics{
+		files:   make(map[string][]Diagnostic),
+		overlay: overlay,
+		texts:   make(map[string]*text),
+	}
+}
+
+// Emit implements the report.Emitter interface.
+func (d *diagnostics) Emit(file string, diags []*report.Diagnostic) error {
+	path := filepath.Clean(file)
+	t, ok := d.texts[path]
+	if !ok {
+		t, _ = readText(path, d.overlay)
+		d.texts[path] = t
+	}
+
+	for _, diag := range diags {
+		d.files[path] = append(d.files[path], newDiagnostic(t, diag))
+	}
+	return nil
+}
+
+// newDiagnostic converts a diagnostic of elmo in the given text to a
+// diagnostic of the protocol. The diagnostics only have a position, so the
+// range ends where the code of the diagnostic ends, if it has any. The
+// columns of the diagnostic are runes, which are converted to the UTF-16
+// code units of the protocol if the text is not nil.
+func newDiagnostic(t *text, d *report.Diagnostic) Diagnostic {
+	position := func(pos source.LinePos) Position {
+		if t == nil {
+			return Position{Line: pos.Line - 1, Character: pos.Col - 1}
+		}
+		return t.linePosition(pos)
+	}
+
+	var start Position
+	if d.Pos.Line > 0 {
+		start = position(d.Pos)
+	}
+
+	end := start
+	if d.Region != nil && len(d.Region.Lines) > 0 {
+		last := d.Region.Lines[len(d.Region.Lines)-1]
+		e := position(source.LinePos{Line: d.Region.Start + len(d.Region.Lines) - 1, Col: utf8.RuneCountInString(last) + 1})
+		if e.Line > start.Line || e.Line == start.Line && e.Character > start.Character {
+			end = e
+		}
+	}
+
+	severity := severityError
+	switch d.Type {
+	case report.Warning:
+		severity = severityWarning
+	case report.Info:
+		severity = severityInfo
+	}
+
+	return Diagnostic{
+		Range:    Range{Start: start, End: end},
+		Severity: severity,
+		Source:   "elmo",
+		Message:  d.Message,
+	}
+}
@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// tabWidth is the number of columns of a tab in the positions of the
+// diagnostics.
+const tabWidth = 4
+
+// text is the content of a file with the offsets where its lines start,
+// which converts the offsets of the nodes to the positions of the protocol
+// and back.
+type text struct {
+	content string
+	lines   []int
+}
+
+func newText(content string) *text {
+	t := &text{content: content, lines: []int{0}}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			t.lines = append(t.lines, i+1)
+		}
+	}
+	return t
+}
+
+// position returns the position of the given offset.
+func (t *text) position(off token.Pos) Position {
+	o := int(off)
+	if o > len(t.content) {
+		o = len(t.content)
+	} else if o < 0 {
+		o = 0
+	}
+
+	line := sort.Search(len(t.lines), func(i int) bool { return t.lines[i] > o }) - 1
+	return Position{Line: line, Character: utf16Len(t.content[t.lines[line]:o])}
+}
+
+// offset returns the offset of the given position. Positions past the end
+// of their line are at the end of it.
+func (t *text) offset(p Position) token.Pos {
+	if p.Line < 0 {
+		return 0
+	} else if p.Line >= len(t.lines) {
+		return token.Pos(len(t.content))
+	}
+
+	o := t.lines[p.Line]
+	for units := 0; o < len(t.content) && t.content[o] != '\n' && units < p.Character; {
+		r, size := utf8.DecodeRuneInString(t.content[o:])
+		units += utf16Units(r)
+		o += size
+	}
+	return token.Pos(o)
+}
+
+// linePosition returns the position of a position of a diagnostic, whose
+// column counts the runes of the line from 1, with the tabs as tabWidth
+// columns.
+func (t *text) linePosition(pos source.LinePos) Position {
+	if pos.Line < 1 {
+		return Position{}
+	} else if pos.Line > len(t.lines) {
+		return t.position(token.Pos(len(t.content)))
+	}
+
+	o := t.lines[pos.Line-1]
+	for col := 1; o < len(t.content) && t.content[o] != '\n' && col < pos.Col; {
+		r, size := utf8.DecodeRuneInString(t.content[o:])
+		if r == '\t' {
+			col += tabWidth
+		} else {
+			col++
+		}
+		o += size
+	}
+	return t.position(token.Pos(o))
+}
+
+// rangeOf returns the range between the given offsets.
+func (t *text) rangeOf(start, end token.Pos) Range {
+	return Range{Start: t.position(start), End: t.position(end)}
+}
+
+// wordBefore returns the qualified name that ends at the given offset,
+// such as "List.ma".
+func (t *text) wordBefore(off token.Pos) string {
+	end := int(off)
+	if end > len(t.content) {
+		end = len(t.content)
+	}
+
+	start := end
+	for start > 0 && isNameChar(t.content[start-1]) {
+		start--
+	}
+	return t.content[start:end]
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '.' || c == '\'' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func utf16Len(s string) int {
+	var n int
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+// utf16Units returns the number of UTF-16 code units of the rune, which
+// are two for the runes outside the basic multilingual plane.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// readText returns the text of the file at the given path, which is its
+// content in the overlay if it is open.
+func readText(path string, overlay source.Overlay) (*text, error) {
+	content, ok := overlay[path]
+	if !ok {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = string(data)
+	}
+	return newText(content), nil
+}
+
+// uriToPath returns the path of the file with the given URI, which must be
+// a file URI.
+func uriToPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path)), true
+}
+
+// pathToURI returns the file URI of the file at the given path.
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// trimQualifier splits a qualified name into its qualifier, if any, and the
+// rest of the name.
+func trimQualifier(name string) (string, string) {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
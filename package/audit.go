@@ -0,0 +1,155 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Advisories is the advisory feed of a registry, which lists the versions
+// of the packages that are known to be bad and the packages that are no
+// longer maintained.
+type Advisories struct {
+	// Advisories are the known problems of the versions of the packages,
+	// such as vulnerabilities.
+	Advisories []Advisory `json:"advisories"`
+	// Yanked are the versions withdrawn by the authors of their packages,
+	// by the name of the package.
+	Yanked map[string][]Version `json:"yanked"`
+	// Unmaintained are the packages that are no longer maintained, with the
+	// reason, such as the package that replaces them.
+	Unmaintained map[string]string `json:"unmaintained"`
+}
+
+// Advisory is a known problem of the versions of a package.
+type Advisory struct {
+	// ID identifies the advisory in the feed.
+	ID string `json:"id"`
+	// Package is the name of the package, of the form user/project.
+	Package string `json:"package"`
+	// Versions is the range of the versions that have the problem.
+	Versions VersionRange `json:"versions"`
+	// Severity is how bad the problem is, such as low, high or critical.
+	Severity string `json:"severity"`
+	// Summary describes the problem.
+	Summary string `json:"summary"`
+	// URL is where the problem is described in detail, if any.
+	URL string `json:"url,omitempty"`
+}
+
+// Advisories returns the advisory feed at the given URL, or the one of the
+// registry, at /advisories, if the URL is empty.
+func (r *Registry) Advisories(url string) (*Advisories, error) {
+	if url == "" {
+		url = r.URL + "/advisories?elm-package-version=0.18"
+	}
+
+	var a Advisories
+	if err := r.getJSON(url, &a); err != nil {
+		return nil, fmt.Errorf("pkg: can't get the advisories of the registry: %s", err)
+	}
+	return &a, nil
+}
+
+// FindingKind is the kind of a finding of an audit.
+type FindingKind string
+
+const (
+	// Vulnerable versions have an advisory.
+	Vulnerable FindingKind = "advisory"
+	// Yanked versions were withdrawn by the authors of the package.
+	Yanked FindingKind = "yanked"
+	// Unmaintained packages are no longer maintained, but their versions
+	// are not known to be bad.
+	Unmaintained FindingKind = "unmaintained"
+)
+
+// Finding is a problem of an exact dependency found by an audit.
+type Finding struct {
+	Package string      `json:"package"`
+	Version Version     `json:"version"`
+	Kind    FindingKind `json:"kind"`
+	// Advisory is the advisory of the version, if it is vulnerable.
+	Advisory *Advisory `json:"advisory,omitempty"`
+	// Reason is why the package is no longer maintained, if it is
+	// unmaintained.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Bad reports whether the version of the finding is known to be bad, so it
+// should not be used, which is the case of the vulnerable and the yanked
+// versions.
+func (f Finding) Bad() bool {
+	return f.Kind != Unmaintained
+}
+
+func (f Finding) String() string {
+	switch f.Kind {
+	case Vulnerable:
+		s := fmt.Sprintf("%s %s is affected by %s (%s): %s", f.Package, f.Version, f.Advisory.ID, f.Advisory.Severity, f.Advisory.Summary)
+		if f.Advisory.URL != "" {
+			s += " " + f.Advisory.URL
+		}
+		return s
+	case Yanked:
+		return fmt.Sprintf("%s %s was yanked", f.Package, f.Version)
+	}
+
+	if f.Reason == "" {
+		return fmt.Sprintf("%s is no longer maintained", f.Package)
+	}
+	return fmt.Sprintf("%s is no longer maintained: %s", f.Package, f.Reason)
+}
+
+// Audit returns the findings of the exact dependencies in the advisories,
+// sorted by the name of the package, and the advisories before the yanked
+// versions and the unmaintained packages. The advisories with the given
+// IDs are ignored.
+func (a *Advisories) Audit(deps ExactDependencies, ignore ...string) []Finding {
+	var ignored = make(map[string]bool)
+	for _, id := range ignore {
+		ignored[id] = true
+	}
+
+	var findings []Finding
+	for i, adv := range a.Advisories {
+		v, ok := deps[adv.Package]
+		if ok && !ignored[adv.ID] && adv.Versions.Contains(v) {
+			findings = append(findings, Finding{Package: adv.Package, Version: v, Kind: Vulnerable, Advisory: &a.Advisories[i]})
+		}
+	}
+
+	for name, versions := range a.Yanked {
+		v, ok := deps[name]
+		if !ok {
+			continue
+		}
+
+		for _, yanked := range versions {
+			if yanked == v {
+				findings = append(findings, Finding{Package: name, Version: v, Kind: Yanked})
+				break
+			}
+		}
+	}
+
+	for name, reason := range a.Unmaintained {
+		if v, ok := deps[name]; ok {
+			findings = append(findings, Finding{Package: name, Version: v, Kind: Unmaintained, Reason: reason})
+		}
+	}
+
+	var order = map[FindingKind]int{Vulnerable: 0, Yanked: 1, Unmaintained: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		fi, fj := findings[i], findings[j]
+		switch {
+		case fi.Package != fj.Package:
+			return fi.Package < fj.Package
+		case fi.Kind != fj.Kind:
+			return order[fi.Kind] < order[fj.Kind]
+		case fi.Advisory != nil && fj.Advisory != nil:
+			return fi.Advisory.ID < fj.Advisory.ID
+		}
+		return false
+	})
+	return findings
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ModuleCaseError is returned when the file of a module is only found if the
+// case of its path is ignored, such as the file src/page/Home.elm of the
+// module Page.Home. The file is found on the case-insensitive file systems of
+// macOS and Windows, but not on the ones of Linux, so the module is found
+// with this error on all of them.
+type ModuleCaseError struct {
+	// Module is the name of the module.
+	Module string
+	// Path is the path of the file of the module according to its name.
+	Path string
+	// File is the path of the file that was found.
+	File string
+}
+
+func (e *ModuleCaseError) Error() string {
+	return fmt.Sprintf("pkg: module %s must be in %s, but its file is %s", e.Module, e.Path, e.File)
+}
+
+// CaseConflictError is returned when the paths of more than one file of a
+// module only differ in case, such as src/Utils.elm and src/utils.elm, so
+// only one of them can exist on a case-insensitive file system.
+type CaseConflictError struct {
+	// Module is the name of the module.
+	Module string
+	// Paths are the paths that only differ in case, sorted.
+	Paths []string
+}
+
+func (e *CaseConflictError) Error() string {
+	return fmt.Sprintf("pkg: the paths of module %s only differ in case: %s", e.Module, strings.Join(e.Paths, ", "))
+}
+
+// findEntry returns the name of the entry of the directory that is the given
+// name if the case is ignored, which is empty if there is none. It is a
+// *CaseConflictError, without the module, if there is more than one. The
+// entries of every directory are only read once by the package.
+func (p *Package) findEntry(dir, name string) (string, error) {
+	names, ok := p.dirNames[dir]
+	if !ok {
+		var err error
+		if names, err = readDirNames(dir); err != nil {
+			return "", err
+		}
+
+		if p.dirNames == nil {
+			p.dirNames = make(map[string][]string)
+		}
+		p.dirNames[dir] = names
+	}
+	return matchCase(names, name, func(n string) string { return filepath.Join(dir, n) })
+}
+
+// readDirNames returns the names of the entries of the directory, which are
+// none if it does not exist.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	return names, err
+}
+
+// matchCase returns the one of the names that is the given name if the case
+// is ignored, and an error if there is more than one. The paths of the error
+// are the ones of the names.
+func matchCase(names []string, name string, path func(string) string) (string, error) {
+	var found []string
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			found = append(found, n)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+
+	sort.Strings(found)
+	var paths = make([]string, len(found))
+	for i, n := range found {
+		paths[i] = path(n)
+	}
+	return "", &CaseConflictError{Paths: paths}
+}
@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	elmJSONFile = "elm.json"
+	// elmHomeVersion is the version of Elm 0.19 whose directory of
+	// ELM_HOME has the packages it downloaded.
+	elmHomeVersion = "0.19.1"
+)
+
+// elmJSON is the manifest of a package or an application of Elm 0.19, its
+// elm.json.
+type elmJSON struct {
+	Type              string          `json:"type"`
+	Name              string          `json:"name"`
+	Summary           string          `json:"summary"`
+	License           string          `json:"license"`
+	Version           *Version        `json:"version"`
+	ExposedModules    exposedModules  `json:"exposed-modules"`
+	ElmVersion        string          `json:"elm-version"`
+	SourceDirectories []string        `json:"source-directories"`
+	Dependencies      json.RawMessage `json:"dependencies"`
+}
+
+// exposedModules are the exposed modules of an elm.json, which are either a
+// list or lists by the name of their category.
+type exposedModules []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *exposedModules) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*m = list
+		return nil
+	}
+
+	var categories map[string][]string
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return fmt.Errorf("pkg: the exposed modules must be a list or lists by category, got %s", data)
+	}
+
+	var names []string
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		*m = append(*m, categories[name]...)
+	}
+	return nil
+}
+
+// applicationDeps are the exact versions of the dependencies of an
+// application, the ones it imports and the ones they depend on.
+type applicationDeps struct {
+	Direct   ExactDependencies `json:"direct"`
+	Indirect ExactDependencies `json:"indirect"`
+}
+
+// decodeElmJSON decodes an elm.json as the manifest of a package. The
+// source directory of a package is src, and its repository is the one of
+// its name on GitHub. An application depends on the exact versions of its
+// direct dependencies, which are installed with the indirect ones.
+func decodeElmJSON(f *os.File) (*Package, error) {
+	var m elmJSON
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, fmt.Errorf("pkg: can't decode elm.json: %s", err)
+	}
+
+	p := &Package{
+		Summary:        m.Summary,
+		License:        m.License,
+		ExposedModules: m.ExposedModules,
+		ElmJSON:        true,
+	}
+
+	switch m.Type {
+	case "package":
+		if m.Version != nil {
+			p.Version = *m.Version
+		}
+		p.Repository = "https://github.com/" + m.Name + ".git"
+		p.SourceDirectories = []string{"src"}
+		if err := unmarshalElmJSON(m.Dependencies, &p.Dependencies); err != nil {
+			return nil, err
+		}
+		if err := p.ElmVersion.UnmarshalText([]byte(m.ElmVersion)); err != nil {
+			return nil, fmt.Errorf("pkg: can't decode elm.json: %s", err)
+		}
+	case "application":
+		var deps applicationDeps
+		if err := unmarshalElmJSON(m.Dependencies, &deps); err != nil {
+			return nil, err
+		}
+
+		var elmVersion Version
+		if err := elmVersion.UnmarshalText([]byte(m.ElmVersion)); err != nil {
+			return nil, fmt.Errorf("pkg: can't decode elm.json: %s", err)
+		}
+
+		p.SourceDirectories = m.SourceDirectories
+		p.ElmVersion = exactly(elmVersion)
+		p.Dependencies = make(Dependencies)
+		p.ExactDependencies = make(ExactDependencies)
+		for name, v := range deps.Direct {
+			p.Dependencies[name] = exactly(v)
+			p.ExactDependencies[name] = v
+		}
+		for name, v := range deps.Indirect {
+			p.ExactDependencies[name] = v
+		}
+	default:
+		return nil, fmt.Errorf("pkg: can't decode elm.json: the type must be package or application, not %q", m.Type)
+	}
+	return p, nil
+}
+
+func unmarshalElmJSON(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("pkg: can't decode the dependencies of elm.json: %s", err)
+	}
+	return nil
+}
+
+// exactly returns the range with only the given version.
+func exactly(v Version) VersionRange {
+	return VersionRange{Min: v, Max: v.Bump(Patch)}
+}
+
+// ElmHome returns the directory where Elm 0.19 keeps the packages it
+// downloads, which is ELM_HOME or ~/.elm by default.
+func ElmHome() string {
+	if dir := os.Getenv("ELM_HOME"); dir != "" {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".elm")
+}
+
+// dependencyDir returns the directory of the given version of a dependency,
+// which is in elm-stuff/packages or, if it is not installed there, in the
+// packages downloaded by Elm 0.19.
+func (p *Package) dependencyDir(name string, v Version) (string, error) {
+	dir := filepath.Join(p.root, elmStuffDir, packagesDir, name, v.String())
+	if ok, err := exists(dir); err != nil || ok {
+		return dir, err
+	}
+
+	if home := ElmHome(); home != "" {
+		elmDir := filepath.Join(home, elmHomeVersion, packagesDir, name, v.String())
+		if ok, err := exists(elmDir); err != nil {
+			return "", err
+		} else if ok {
+			return elmDir, nil
+		}
+	}
+	return dir, nil
+}
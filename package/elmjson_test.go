@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const elmJSONApplication = `{
+    "type": "application",
+    "source-directories": ["src"],
+    "elm-version": "0.19.1",
+    "dependencies": {
+        "direct": {"elm/json": "1.1.3"},
+        "indirect": {"elm/core": "1.0.5"}
+    },
+    "test-dependencies": {"direct": {}, "indirect": {}}
+}`
+
+const elmJSONPackage = `{
+    "type": "package",
+    "name": "elm/json",
+    "summary": "Encode and decode JSON values",
+    "license": "BSD-3-Clause",
+    "version": "1.1.3",
+    "exposed-modules": {"Primitives": ["Json.Decode", "Json.Encode"]},
+    "elm-version": "0.19.0 <= v < 0.20.0",
+    "dependencies": {"elm/core": "1.0.0 <= v < 2.0.0"},
+    "test-dependencies": {}
+}`
+
+func TestLoadElmJSON(t *testing.T) {
+	require := require.New(t)
+	home, err := createStructure(
+		entry{"0.19.1/packages/elm/core/1.0.5/elm.json", `{"type": "package", "name": "elm/core", "version": "1.0.5", "exposed-modules": ["Basics"], "elm-version": "0.19.0 <= v < 0.20.0", "dependencies": {}}`},
+		entry{"0.19.1/packages/elm/core/1.0.5/src/Basics.elm", nil},
+	)
+	require.NoError(err)
+	defer os.RemoveAll(home)
+	defer os.Setenv("ELM_HOME", os.Getenv("ELM_HOME"))
+	require.NoError(os.Setenv("ELM_HOME", home))
+
+	root, err := createStructure(
+		entry{"elm.json", elmJSONApplication},
+		entry{"src/Main.elm", nil},
+		entry{"elm-stuff/packages/elm/json/1.1.3/elm.json", elmJSONPackage},
+		entry{"elm-stuff/packages/elm/json/1.1.3/src/Json/Decode.elm", nil},
+	)
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	p, err := Load(filepath.Join(root, "src"))
+	require.NoError(err)
+	require.True(p.ElmJSON)
+	require.Equal(root, p.Root())
+	require.Equal([]string{"src"}, p.SourceDirectories)
+	require.Equal(Dependencies{"elm/json": {Version{1, 1, 3}, Version{1, 1, 4}}}, p.Dependencies)
+	require.Equal(ExactDependencies{"elm/json": {1, 1, 3}, "elm/core": {1, 0, 5}}, p.ExactDependencies)
+	require.Equal(VersionRange{Version{0, 19, 1}, Version{0, 19, 2}}, p.ElmVersion)
+
+	path, err := p.FindModule("Json.Decode")
+	require.NoError(err)
+	require.Equal(filepath.Join(root, "elm-stuff/packages/elm/json/1.1.3/src/Json/Decode.elm"), path)
+
+	path, err = p.FindModule("Basics")
+	require.NoError(err)
+	require.Equal(filepath.Join(home, "0.19.1/packages/elm/core/1.0.5/src/Basics.elm"), path)
+
+	require.Error(p.Save())
+
+	dep, err := loadPackage(filepath.Join(root, "elm-stuff/packages/elm/json/1.1.3"), false)
+	require.NoError(err)
+	require.Equal("https://github.com/elm/json.git", dep.Repository)
+	require.Equal(Version{1, 1, 3}, dep.Version)
+	require.Equal([]string{"src"}, dep.SourceDirectories)
+	require.Equal([]string{"Json.Decode", "Json.Encode"}, dep.ExposedModules)
+	require.Equal(Dependencies{"elm/core": {Version{1, 0, 0}, Version{2, 0, 0}}}, dep.Dependencies)
+}
+
+func TestLoadElmJSONErrors(t *testing.T) {
+	cases := []string{
+		`{"type": "library"}`,
+		`{"type": "application", "elm-version": "0.19.1", "dependencies": {"direct": {"elm/json": "1"}}}`,
+		`{"type": "package", "elm-version": "0.19.0", "exposed-modules": []}`,
+		`{"type": "package", "elm-version": "0.19.0 <= v < 0.20.0", "exposed-modules": 1}`,
+	}
+
+	for _, c := range cases {
+		root, err := createStructure(entry{"elm.json", c})
+		require.NoError(t, err)
+
+		_, err = Load(root)
+		require.Error(t, err, c)
+		os.RemoveAll(root)
+	}
+}
@@ -1,32 +1,41 @@
 package pkg
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
 
-// Graph represents a dependency graph.
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Graph represents a dependency graph. The modules are interned, so the
+// graphs of large packages are resolved comparing pointers instead of names.
 type Graph struct {
 	// Node is the root node of the graph, which is always one module.
 	root  *node
-	nodes map[string]*node
+	nodes map[token.Name]*node
+	names *token.Interner
 }
 
 // NewGraph creates a new graph with the given root module.
 func NewGraph(root string) *Graph {
-	rootNode := newNode(root)
+	names := token.NewInterner()
+	rootNode := newNode(names.Intern(root))
 	return &Graph{
 		root: rootNode,
-		nodes: map[string]*node{
-			root: rootNode,
+		nodes: map[token.Name]*node{
+			rootNode.module: rootNode,
 		},
+		names: names,
 	}
 }
 
 // Add adds `to` as a dependency of `from`.
 func (g *Graph) Add(to, from string) *Graph {
-	g.node(from).add(g.node(to))
+	g.node(g.names.Intern(from)).add(g.node(g.names.Intern(to)))
 	return g
 }
 
-func (g *Graph) node(module string) *node {
+func (g *Graph) node(module token.Name) *node {
 	if n, ok := g.nodes[module]; ok {
 		return n
 	}
@@ -49,15 +58,15 @@ func (g *Graph) Resolve() ([]string, error) {
 }
 
 type node struct {
-	module     string
-	edges      map[string]*node
-	dependants []string
+	module     token.Name
+	edges      map[token.Name]*node
+	dependants []token.Name
 }
 
-func newNode(module string) *node {
+func newNode(module token.Name) *node {
 	return &node{
 		module: module,
-		edges:  make(map[string]*node),
+		edges:  make(map[token.Name]*node),
 	}
 }
 
@@ -70,11 +79,21 @@ func (n *node) add(node *node) {
 
 func (n *node) resolve(ctx *resolutionCtx) error {
 	ctx.unresolved.add(n.module)
+	ctx.path = append(ctx.path, n.module)
 
 	for _, mod := range n.dependants {
 		if !ctx.resolved.contains(mod) {
 			if ctx.unresolved.contains(mod) {
-				return NewCircularDependencyError(n.module, mod)
+				err := NewCircularDependencyError(n.module.String(), mod.String())
+				for i := len(ctx.path) - 1; i >= 0; i-- {
+					if ctx.path[i] == mod {
+						for _, m := range ctx.path[i:] {
+							err.Cycle = append(err.Cycle, m.String())
+						}
+						break
+					}
+				}
+				return err
 			}
 
 			if err := n.edges[mod].resolve(ctx); err != nil {
@@ -84,18 +103,19 @@ func (n *node) resolve(ctx *resolutionCtx) error {
 	}
 
 	delete(ctx.unresolved, n.module)
+	ctx.path = ctx.path[:len(ctx.path)-1]
 	ctx.resolved.add(n.module)
-	ctx.nodes = append(ctx.nodes, n.module)
+	ctx.nodes = append(ctx.nodes, n.module.String())
 	return nil
 }
 
-type moduleSet map[string]struct{}
+type moduleSet map[token.Name]struct{}
 
-func (m moduleSet) add(module string) {
+func (m moduleSet) add(module token.Name) {
 	m[module] = struct{}{}
 }
 
-func (m moduleSet) contains(module string) bool {
+func (m moduleSet) contains(module token.Name) bool {
 	_, ok := m[module]
 	return ok
 }
@@ -104,6 +124,9 @@ type resolutionCtx struct {
 	nodes      []string
 	unresolved moduleSet
 	resolved   moduleSet
+	// path are the modules being resolved, each one imported by the
+	// previous one.
+	path []token.Name
 }
 
 func newResolutionCtx() *resolutionCtx {
@@ -118,11 +141,15 @@ func newResolutionCtx() *resolutionCtx {
 type CircularDependencyError struct {
 	// Modules that depended on each other.
 	Modules [2]string
+	// Cycle are all the modules of the circular dependency, starting with
+	// the second of Modules. Every module imports the next one, and the
+	// last one, the first of Modules, imports the first one.
+	Cycle []string
 }
 
 // NewCircularDependencyError returns a new CircularDependencyError.
 func NewCircularDependencyError(a, b string) *CircularDependencyError {
-	return &CircularDependencyError{[2]string{a, b}}
+	return &CircularDependencyError{Modules: [2]string{a, b}}
 }
 
 func (e CircularDependencyError) Error() string {
@@ -132,3 +159,109 @@ func (e CircularDependencyError) Error() string {
 		e.Modules[1],
 	)
 }
+
+// Root returns the root module of the graph.
+func (g *Graph) Root() string {
+	return g.root.module.String()
+}
+
+// Modules returns all the modules in the graph, sorted by name.
+func (g *Graph) Modules() []string {
+	var modules = make([]string, 0, len(g.nodes))
+	for m := range g.nodes {
+		modules = append(modules, m.String())
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// Imports returns the modules the given module depends on, in the order in
+// which they were added.
+func (g *Graph) Imports(module string) []string {
+	n, ok := g.nodes[g.names.Intern(module)]
+	if !ok {
+		return nil
+	}
+
+	var imports = make([]string, len(n.dependants))
+	for i, mod := range n.dependants {
+		imports[i] = mod.String()
+	}
+	return imports
+}
+
+// Cycles returns the groups of modules of the graph that depend on each
+// other, directly or not. Each group is sorted by name, and so are the
+// groups by their first module. A graph without circular dependencies has
+// no cycles.
+func (g *Graph) Cycles() [][]string {
+	ctx := &cyclesCtx{
+		graph: g,
+		index: make(map[token.Name]int),
+		low:   make(map[token.Name]int),
+		stack: make(moduleSet),
+	}
+
+	for _, m := range g.Modules() {
+		n := g.nodes[g.names.Intern(m)]
+		if _, ok := ctx.index[n.module]; !ok {
+			ctx.visit(n)
+		}
+	}
+
+	sort.Slice(ctx.cycles, func(i, j int) bool {
+		return ctx.cycles[i][0] < ctx.cycles[j][0]
+	})
+	return ctx.cycles
+}
+
+// cyclesCtx finds the strongly connected components of a graph using
+// Tarjan's algorithm.
+type cyclesCtx struct {
+	graph  *Graph
+	next   int
+	index  map[token.Name]int
+	low    map[token.Name]int
+	stack  moduleSet
+	path   []token.Name
+	cycles [][]string
+}
+
+func (c *cyclesCtx) visit(n *node) {
+	c.index[n.module] = c.next
+	c.low[n.module] = c.next
+	c.next++
+	c.path = append(c.path, n.module)
+	c.stack.add(n.module)
+
+	for _, mod := range n.dependants {
+		if _, ok := c.index[mod]; !ok {
+			c.visit(n.edges[mod])
+			if c.low[mod] < c.low[n.module] {
+				c.low[n.module] = c.low[mod]
+			}
+		} else if c.stack.contains(mod) && c.index[mod] < c.low[n.module] {
+			c.low[n.module] = c.index[mod]
+		}
+	}
+
+	if c.low[n.module] != c.index[n.module] {
+		return
+	}
+
+	var component []string
+	for {
+		mod := c.path[len(c.path)-1]
+		c.path = c.path[:len(c.path)-1]
+		delete(c.stack, mod)
+		component = append(component, mod.String())
+		if mod == n.module {
+			break
+		}
+	}
+
+	if _, self := n.edges[n.module]; len(component) > 1 || self {
+		sort.Strings(component)
+		c.cycles = append(c.cycles, component)
+	}
+}
@@ -46,5 +46,41 @@ func TestCircularDep(t *testing.T) {
 	circular, ok := err.(*CircularDependencyError)
 	require.True(t, ok, "expected a CircularDependencyError")
 	require.Equal(t, [2]string{"f", "b"}, circular.Modules)
+	require.Equal(t, []string{"b", "e", "f"}, circular.Cycle)
 	require.Nil(t, nodes)
 }
+
+func TestGraphModules(t *testing.T) {
+	require := require.New(t)
+	g := NewGraph("a").
+		Add("c", "a").
+		Add("b", "a").
+		Add("c", "b")
+
+	require.Equal("a", g.Root())
+	require.Equal([]string{"a", "b", "c"}, g.Modules())
+	require.Equal([]string{"c", "b"}, g.Imports("a"))
+	require.Equal([]string{"c"}, g.Imports("b"))
+	require.Empty(g.Imports("c"))
+	require.Nil(g.Imports("d"))
+	require.Empty(g.Cycles())
+}
+
+func TestCycles(t *testing.T) {
+	g := NewGraph("a").
+		Add("b", "a").
+		Add("c", "b").
+		Add("b", "c").
+		Add("d", "a").
+		Add("e", "d").
+		Add("f", "e").
+		Add("d", "f").
+		Add("g", "g").
+		Add("g", "a")
+
+	require.Equal(t, [][]string{
+		{"b", "c"},
+		{"d", "e", "f"},
+		{"g"},
+	}, g.Cycles())
+}
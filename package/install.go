@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// AddDependency adds the newest version of the given package that is
+// compatible with the rest of the dependencies to them, with the range of
+// the versions until its next major version, and returns it. The manifest
+// is not saved.
+func (p *Package) AddDependency(r *Registry, name string) (Version, error) {
+	versions, err := r.Versions(name)
+	if err != nil {
+		return Version{}, err
+	}
+
+	for _, v := range versions {
+		deps := Dependencies{name: v.UntilNextMajor()}
+		for dep, vr := range p.Dependencies {
+			if dep != name {
+				deps[dep] = vr
+			}
+		}
+
+		_, err := Solve(r, deps, p.ExactDependencies)
+		if _, ok := err.(*ConflictError); ok {
+			continue
+		} else if err != nil {
+			return Version{}, err
+		}
+
+		p.Dependencies = deps
+		return v, nil
+	}
+
+	return Version{}, &ConflictError{name}
+}
+
+// Install resolves the exact versions of the dependencies of the package,
+// keeping the installed ones if they are still valid, and downloads the
+// versions that are not in elm-stuff/packages yet, nor in the packages
+// downloaded by Elm 0.19. The exact versions are saved in
+// elm-stuff/exact-dependencies.json, and the names of the packages that were
+// downloaded are returned.
+func (p *Package) Install(r *Registry) ([]string, error) {
+	exact, err := Solve(r, p.Dependencies, p.ExactDependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range exact {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var downloaded []string
+	for _, name := range names {
+		dir, err := p.dependencyDir(name, exact[name])
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, err := exists(dir); err != nil {
+			return nil, err
+		} else if ok {
+			continue
+		}
+
+		if err := r.Download(name, exact[name], dir); err != nil {
+			return nil, err
+		}
+		downloaded = append(downloaded, name)
+	}
+
+	p.ExactDependencies = exact
+	p.dependencyCache = make(map[string]*Package)
+	p.moduleCache = make(map[string]string)
+	if err := writeJSON(filepath.Join(p.root, elmStuffDir, exactDepsFile), exact); err != nil {
+		return nil, fmt.Errorf("pkg: can't save exact dependencies: %s", err)
+	}
+	return downloaded, nil
+}
+
+// Save writes the manifest of the package to its elm-package.json.
+func (p *Package) Save() error {
+	if p.ElmJSON {
+		return fmt.Errorf("pkg: can't save the manifest of the package, it is the elm.json of Elm 0.19")
+	}
+
+	manifest := *p
+	if p.manifestDirs != nil {
+		manifest.SourceDirectories = p.manifestDirs
+	}
+
+	if err := writeJSON(filepath.Join(p.root, pkgFile), &manifest); err != nil {
+		return fmt.Errorf("pkg: can't save elm-package.json: %s", err)
+	}
+	return nil
+}
+
+// writeJSON writes the value encoded as indented JSON in the file at the
+// given path, without escaping the < of the version ranges.
+func writeJSON(path string, v interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
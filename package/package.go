@@ -7,13 +7,19 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/elm-tangram/tangram/config"
 )
 
 const (
 	pkgFile       = "elm-package.json"
 	ext           = ".elm"
+	literateExt   = ".md"
 	nativeExt     = ".go"
+	kernelExt     = ".js"
+	declExt       = ".decl"
 	elmStuffDir   = "elm-stuff"
 	packagesDir   = "packages"
 	exactDepsFile = "exact-dependencies.json"
@@ -36,13 +42,28 @@ var (
 // This contains all the package information useful for the compiler, including
 // its dependencies, etc.
 type Package struct {
-	Repository        string            `json:"repository"`
 	Version           Version           `json:"version"`
+	Summary           string            `json:"summary"`
+	Repository        string            `json:"repository"`
+	License           string            `json:"license"`
 	SourceDirectories []string          `json:"source-directories"`
-	NativeModules     bool              `json:"native-modules"`
+	ExposedModules    []string          `json:"exposed-modules"`
+	NativeModules     bool              `json:"native-modules,omitempty"`
 	Dependencies      Dependencies      `json:"dependencies"`
 	ElmVersion        VersionRange      `json:"elm-version"`
 	ExactDependencies ExactDependencies `json:"-"`
+	// Templates are the paths to the custom templates the generated code
+	// is injected into, relative to the root, by the name of their target.
+	Templates map[string]string `json:"templates,omitempty"`
+	// ModuleTags are the names of the modules that are only built for some
+	// purposes, by their tag: TestTag or ExampleTag. A name ending in .*,
+	// such as Examples.*, is every module under it. The tagged modules are
+	// not found, nor listed, unless the package includes their tag, as the
+	// ones of WithTags do.
+	ModuleTags map[string][]string `json:"module-tags,omitempty"`
+	// ElmJSON reports whether the manifest was read from the elm.json of a
+	// package or an application of Elm 0.19, which cannot be saved.
+	ElmJSON bool `json:"-"`
 
 	// root of the package, that is, the directory where elm-package.json is
 	root string
@@ -53,6 +74,24 @@ type Package struct {
 	// moduleCache keeps the resolved paths for modules so they don't have to
 	// looked up again
 	moduleCache map[string]string
+	// files are the paths to the files of a package that is not in the file
+	// system, which is nil for the packages that are, and fileNames are the
+	// same paths, sorted
+	files     map[string]bool
+	fileNames []string
+	// dirNames are the names of the entries of the directories where the
+	// modules have been looked up, by their path, so they are only read
+	// once
+	dirNames map[string][]string
+	// manifestDirs are the source directories of the manifest when they
+	// are replaced by the ones of the project configuration, which are the
+	// ones saved
+	manifestDirs []string
+	// tags are the tags of the modules that are found, besides the ones
+	// without tags
+	tags map[string]bool
+	// synthetic are the synthetic modules of the package by their path
+	synthetic map[string]*synthetic
 }
 
 // Root returns the package root.
@@ -60,6 +99,16 @@ func (p *Package) Root() string {
 	return p.root
 }
 
+// Template returns the path to the custom template of the given target, or
+// an empty string if the target has no custom template.
+func (p *Package) Template(target string) string {
+	path, ok := p.Templates[target]
+	if !ok {
+		return ""
+	}
+	return filepath.Join(p.root, path)
+}
+
 func (p *Package) cacheModule(module string, filePath string) {
 	p.moduleCache[module] = filePath
 }
@@ -102,15 +151,30 @@ func (p *Package) FindModule(path string) (string, error) {
 }
 
 // FindSourceModule tries to find a module with the given path in all the
-// source directories.
+// source directories, and then in the synthetic modules of the package. A
+// module with a tag the package does not include is an
+// *ExcludedModuleError.
 func (p *Package) FindSourceModule(path string) (string, error) {
+	if err := p.excluded(path); err != nil {
+		return "", err
+	}
+
 	if cachedPath, ok := p.moduleCache[path]; ok {
 		return cachedPath, nil
 	}
 
+	// a file whose path only differs in case is only reported if no
+	// source directory has the module
+	var caseErr *ModuleCaseError
 	pathParts := strings.Split(path, ".")
 	for _, dir := range p.SourceDirectories {
-		if moduleFilePath, err := p.findModuleInDir(pathParts, dir); err != nil {
+		moduleFilePath, err := p.findModuleInDir(pathParts, dir)
+		if e, ok := err.(*ModuleCaseError); ok {
+			if caseErr == nil {
+				caseErr = e
+			}
+			continue
+		} else if err != nil {
 			return "", err
 		} else if moduleFilePath != "" {
 			p.cacheModule(path, moduleFilePath)
@@ -118,6 +182,13 @@ func (p *Package) FindSourceModule(path string) (string, error) {
 		}
 	}
 
+	if moduleFilePath := p.findSyntheticModule(path); moduleFilePath != "" {
+		return moduleFilePath, nil
+	}
+
+	if caseErr != nil {
+		return "", caseErr
+	}
 	return "", ErrModuleNotFound
 }
 
@@ -128,12 +199,20 @@ func (p *Package) FindDependencyModule(path string) (string, error) {
 		return cachedPath, nil
 	}
 
+	if p.files != nil {
+		return "", ErrModuleNotFound
+	}
+
 	if p.ExactDependencies == nil {
 		return "", ErrDepsNotInstalled
 	}
 
 	for dep, v := range p.ExactDependencies {
-		dir := filepath.Join(p.root, elmStuffDir, packagesDir, dep, v.String())
+		dir, err := p.dependencyDir(dep, v)
+		if err != nil {
+			return "", err
+		}
+
 		var (
 			pkg *Package
 			ok  bool
@@ -159,26 +238,220 @@ func (p *Package) FindDependencyModule(path string) (string, error) {
 	return "", ErrModuleNotFound
 }
 
+// Modules returns the names of the modules in the source directories of the
+// package, of its synthetic modules and of its installed dependencies, sorted, which are the modules
+// it can import. The native modules and the modules with the tags the
+// package does not include are left out.
+func (p *Package) Modules() ([]string, error) {
+	var names = make(map[string]bool)
+	if err := p.sourceModules(names); err != nil {
+		return nil, err
+	}
+
+	if p.files == nil {
+		for dep, v := range p.ExactDependencies {
+			dir, err := p.dependencyDir(dep, v)
+			if err != nil {
+				return nil, err
+			}
+
+			pkg, ok := p.dependencyCache[dep]
+			if !ok {
+				if pkg, err = loadPackage(dir, false); err != nil {
+					return nil, fmt.Errorf("pkg: expected %s version %s to be a valid Elm package: %s", dep, v, err)
+				}
+			}
+
+			if err := pkg.sourceModules(names); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var result = make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// sourceModules adds the names of the modules in the source directories of
+// the package and of its synthetic modules to names, except the ones with
+// the tags it does not include.
+func (p *Package) sourceModules(result map[string]bool) error {
+	var names = make(map[string]bool)
+	for _, s := range p.synthetic {
+		names[s.module] = true
+	}
+
+	for _, dir := range p.SourceDirectories {
+		if p.files != nil {
+			for path := range p.files {
+				if rel, err := filepath.Rel(dir, path); err == nil {
+					addModule(names, rel)
+				}
+			}
+			continue
+		}
+
+		root := filepath.Join(p.root, dir)
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			} else if err != nil {
+				return err
+			}
+
+			if fi.IsDir() && fi.Name() == elmStuffDir {
+				return filepath.SkipDir
+			}
+
+			if rel, err := filepath.Rel(root, path); err == nil && !fi.IsDir() {
+				addModule(names, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for name := range names {
+		if p.excluded(name) == nil {
+			result[name] = true
+		}
+	}
+	return nil
+}
+
+// addModule adds the name of the module of the file at the given path,
+// relative to its source directory, to names, if it is an Elm module.
+func addModule(names map[string]bool, path string) {
+	fileExt := filepath.Ext(path)
+	if fileExt != ext && fileExt != literateExt {
+		return
+	}
+
+	parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(path, fileExt)), "/")
+	for _, part := range parts {
+		if part == "" || part[0] < 'A' || part[0] > 'Z' {
+			return
+		}
+	}
+	names[strings.Join(parts, ".")] = true
+}
+
 func (p *Package) findModuleInDir(pathParts []string, dir string) (string, error) {
-	var path = filepath.Join(p.root, dir)
-	for i, p := range pathParts {
-		if i+1 == len(pathParts) {
-			var fileExt = ext
-			if pathParts[0] == "Native" {
-				fileExt = nativeExt
+	path, err := p.findModuleFile(pathParts, dir)
+	if e, ok := err.(*CaseConflictError); ok {
+		e.Module = strings.Join(pathParts, ".")
+	}
+	return path, err
+}
+
+// findModuleFile returns the path of the file of the module with the given
+// path parts in the directory, which is empty if there is none. The case of
+// the path is ignored, so a file with a path that only differs in case is a
+// *ModuleCaseError instead of not being found, no matter the file system.
+func (p *Package) findModuleFile(pathParts []string, dir string) (string, error) {
+	if p.files != nil {
+		return p.findModuleInFiles(pathParts, dir)
+	}
+
+	var (
+		path     = filepath.Join(p.root, dir)
+		expected = path
+	)
+	for _, part := range pathParts[:len(pathParts)-1] {
+		entry, err := p.findEntry(path, part)
+		if err != nil || entry == "" {
+			return "", err
+		}
+
+		path = filepath.Join(path, entry)
+		expected = filepath.Join(expected, part)
+	}
+
+	var caseErr error
+	for _, fileExt := range moduleExts(pathParts) {
+		name := pathParts[len(pathParts)-1] + fileExt
+		entry, err := p.findEntry(path, name)
+		if err != nil {
+			return "", err
+		} else if entry == "" {
+			continue
+		}
+
+		filePath := filepath.Join(path, entry)
+		if expectedFile := filepath.Join(expected, name); filePath != expectedFile {
+			if caseErr == nil {
+				caseErr = &ModuleCaseError{strings.Join(pathParts, "."), expectedFile, filePath}
 			}
-			p = p + fileExt
+			continue
 		}
-		path = filepath.Join(path, p)
+		return filePath, nil
+	}
+	return "", caseErr
+}
 
-		if ok, err := exists(path); err != nil {
+func (p *Package) findModuleInFiles(pathParts []string, dir string) (string, error) {
+	var caseErr error
+	for _, fileExt := range moduleExts(pathParts) {
+		expected := filepath.Join(append([]string{dir}, pathParts...)...) + fileExt
+		path, err := matchCase(p.fileNames, expected, func(n string) string { return n })
+		if err != nil {
 			return "", err
-		} else if !ok {
-			return "", nil
+		} else if path == "" {
+			continue
 		}
+
+		if path != expected {
+			if caseErr == nil {
+				caseErr = &ModuleCaseError{strings.Join(pathParts, "."), expected, path}
+			}
+			continue
+		}
+		return path, nil
+	}
+	return "", caseErr
+}
+
+// moduleExts returns the extensions of the files a module with the given
+// path parts can be in, in order of preference. A native module is a Go file,
+// or a JavaScript kernel module if there is no Go file, which can only be
+// built for the JavaScript targets. Any other module is an Elm file, or a
+// literate module, which is a Markdown file with fenced blocks of Elm code,
+// if there is no Elm file.
+func moduleExts(pathParts []string) []string {
+	if pathParts[0] == "Native" {
+		return []string{nativeExt, kernelExt}
 	}
+	return []string{ext, literateExt}
+}
 
-	return path, nil
+// NativeDecls returns the path to the file with the declarations of the
+// values of the native module whose Go or JavaScript file is at the given
+// path, such as `src/Native/List.decl` for `src/Native/List.go`.
+func NativeDecls(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + declExt
+}
+
+// IsNative reports whether the file at the given path is the one of a native
+// module, which is a Go file or a JavaScript kernel module.
+func IsNative(path string) bool {
+	switch filepath.Ext(path) {
+	case nativeExt, kernelExt:
+		return true
+	}
+	return false
+}
+
+// IsKernel reports whether the file at the given path is the one of a native
+// module that is a JavaScript kernel module, whose values are only available
+// in the JavaScript targets.
+func IsKernel(path string) bool {
+	return filepath.Ext(path) == kernelExt
 }
 
 // Dependencies is a map between a dependency name and a version range.
@@ -189,7 +462,8 @@ type ExactDependencies map[string]Version
 
 // Load will load the manifest of the package from the given path until it
 // reaches the root of the filesystem.
-// It expects path to be a directory.
+// It expects path to be a directory. The source directories are the ones of
+// the project configuration of the root of the package, if it has any.
 func Load(path string) (*Package, error) {
 	pkg, err := loadPackage(path, true)
 	if err != nil {
@@ -198,6 +472,22 @@ func Load(path string) (*Package, error) {
 
 	pkg.dependencyCache = make(map[string]*Package)
 
+	// the project configuration can replace the source directories
+	conf, err := config.Load(pkg.root)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.SourceDirectories) > 0 {
+		pkg.manifestDirs = pkg.SourceDirectories
+		pkg.SourceDirectories = conf.SourceDirectories
+	}
+
+	// the exact dependencies of an application of Elm 0.19 are in its
+	// elm.json already
+	if pkg.ElmJSON && pkg.ExactDependencies != nil {
+		return pkg, nil
+	}
+
 	if err := pkg.tryLoadExactDependencies(); err != nil {
 		return nil, err
 	}
@@ -205,6 +495,34 @@ func Load(path string) (*Package, error) {
 	return pkg, nil
 }
 
+// NewMemory returns the package with the manifest read from r whose files
+// are not in the file system, but are the given paths relative to its root,
+// such as `src/Main.elm`. Its modules are only found in those files, so it
+// has no dependencies.
+func NewMemory(r io.Reader, paths []string) (*Package, error) {
+	var pkg Package
+	if err := json.NewDecoder(r).Decode(&pkg); err != nil {
+		return nil, fmt.Errorf("pkg: can't decode elm-package.json: %s", err)
+	}
+
+	if err := pkg.validateTags(); err != nil {
+		return nil, err
+	}
+
+	pkg.moduleCache = make(map[string]string)
+	pkg.dependencyCache = make(map[string]*Package)
+	pkg.files = make(map[string]bool)
+	for _, path := range paths {
+		pkg.files[filepath.Clean(path)] = true
+	}
+
+	for path := range pkg.files {
+		pkg.fileNames = append(pkg.fileNames, path)
+	}
+	sort.Strings(pkg.fileNames)
+	return &pkg, nil
+}
+
 func loadPackage(path string, recursive bool) (*Package, error) {
 	f, root, err := findPackageFile(path, recursive)
 	if err != nil {
@@ -216,22 +534,35 @@ func loadPackage(path string, recursive bool) (*Package, error) {
 	}
 
 	defer f.Close()
-	var pkg Package
-	if err := json.NewDecoder(f).Decode(&pkg); err != nil {
+	var pkg = new(Package)
+	if filepath.Base(f.Name()) == elmJSONFile {
+		if pkg, err = decodeElmJSON(f); err != nil {
+			return nil, err
+		}
+	} else if err := json.NewDecoder(f).Decode(pkg); err != nil {
 		return nil, fmt.Errorf("pkg: can't decode elm-package.json: %s", err)
 	}
+	if err := pkg.validateTags(); err != nil {
+		return nil, err
+	}
+
 	pkg.root = root
 	pkg.moduleCache = make(map[string]string)
-	return &pkg, nil
+	return pkg, nil
 }
 
-func findPackageFile(path string, recursive bool) (io.ReadCloser, string, error) {
+// findPackageFile opens the manifest of the package in the given path, which
+// is its elm-package.json or, if it has none, the elm.json of Elm 0.19.
+func findPackageFile(path string, recursive bool) (*os.File, string, error) {
 	if path == separator {
 		return nil, "", nil
 	}
 
-	file := filepath.Join(path, pkgFile)
-	f, err := os.Open(file)
+	f, err := os.Open(filepath.Join(path, pkgFile))
+	if os.IsNotExist(err) {
+		f, err = os.Open(filepath.Join(path, elmJSONFile))
+	}
+
 	if os.IsNotExist(err) && recursive {
 		if path == filepath.Dir(path) {
 			return nil, "", nil
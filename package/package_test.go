@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -31,6 +32,9 @@ var validPackageEntries = []entry{
 	{"src/Foo/Bar.elm", nil},
 	{"src/Foo/Bar/Baz.elm", nil},
 	{"src2/Bar.elm", nil},
+	{"src/Native/Foo.go", nil},
+	{"src/Native/Foo.js", nil},
+	{"src/Native/Bar.js", nil},
 	{
 		"elm-stuff/exact-dependencies.json",
 		ExactDependencies{
@@ -117,6 +121,29 @@ func TestLoad(t *testing.T) {
 	createStructure(validPackageEntries...)
 }
 
+func TestLoadProjectConfig(t *testing.T) {
+	require := require.New(t)
+	root, err := createStructure(append(validPackageEntries, entry{"elmo.toml", "source-directories = [\"src2\"]\n"})...)
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	pkg, err := Load(root)
+	require.NoError(err)
+	require.Equal([]string{"src2"}, pkg.SourceDirectories)
+
+	_, err = pkg.FindSourceModule("Foo")
+	require.Equal(ErrModuleNotFound, err)
+
+	// the source directories of the manifest are the ones saved
+	require.NoError(pkg.Save())
+	manifest, err := ioutil.ReadFile(filepath.Join(root, pkgFile))
+	require.NoError(err)
+	require.Contains(string(manifest), `"source-directories": [
+        "src",
+        "src2"
+    ]`)
+}
+
 func TestFindModule(t *testing.T) {
 	require := require.New(t)
 	root, err := createStructure(validPackageEntries...)
@@ -138,6 +165,8 @@ func TestFindModule(t *testing.T) {
 		{"Foo.Bar.Baz.Qux", "elm-stuff/packages/foo/bar/1.0.0/src/Foo/Bar/Baz/Qux.elm", nil},
 		{"Foo.Bar.Baz.Mux", "elm-stuff/packages/foo/baz/1.5.0/src/Foo/Bar/Baz/Mux.elm", nil},
 		{"Foo.Bar.Baz.Mux", "elm-stuff/packages/foo/baz/1.5.0/src/Foo/Bar/Baz/Mux.elm", nil}, // this one is cached
+		{"Native.Foo", "src/Native/Foo.go", nil},
+		{"Native.Bar", "src/Native/Bar.js", nil},
 		{"Bar.Foo", "", ErrModuleNotFound},
 	}
 
@@ -156,6 +185,142 @@ func TestFindModule(t *testing.T) {
 	}
 }
 
+func TestFindModuleCase(t *testing.T) {
+	require := require.New(t)
+	root, err := createStructure(append(
+		validPackageEntries,
+		entry{"src/page/Home.elm", nil},
+		entry{"src/Api/user.elm", nil},
+		entry{"src/Utils.elm", nil},
+		entry{"src/utils.elm", nil},
+		entry{"src/page/About.elm", nil},
+		entry{"src2/Page/About.elm", nil},
+	)...)
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	pkg, err := Load(root)
+	require.NoError(err)
+
+	_, err = pkg.FindModule("Page.Home")
+	require.Equal(&ModuleCaseError{
+		Module: "Page.Home",
+		Path:   filepath.Join(root, "src", "Page", "Home.elm"),
+		File:   filepath.Join(root, "src", "page", "Home.elm"),
+	}, err)
+
+	_, err = pkg.FindModule("Api.User")
+	require.Equal(&ModuleCaseError{
+		Module: "Api.User",
+		Path:   filepath.Join(root, "src", "Api", "User.elm"),
+		File:   filepath.Join(root, "src", "Api", "user.elm"),
+	}, err)
+
+	_, err = pkg.FindModule("Utils")
+	require.Equal(&CaseConflictError{
+		Module: "Utils",
+		Paths:  []string{filepath.Join(root, "src", "Utils.elm"), filepath.Join(root, "src", "utils.elm")},
+	}, err)
+
+	// the exact path in a later source directory is found
+	path, err := pkg.FindModule("Page.About")
+	require.NoError(err)
+	require.Equal(filepath.Join(root, "src2", "Page", "About.elm"), path)
+
+	// the entries of the directories are only read once
+	require.NoError(os.Remove(filepath.Join(root, "src", "Utils.elm")))
+	_, err = pkg.FindModule("Utils")
+	require.IsType(&CaseConflictError{}, err)
+
+	mem, err := NewMemory(strings.NewReader(`{"source-directories": ["src", "src2"]}`), []string{"src/page/Home.elm", "src/Utils.elm", "src/utils.elm", "src/page/About.elm", "src2/Page/About.elm"})
+	require.NoError(err)
+
+	_, err = mem.FindModule("Page.Home")
+	require.Equal(&ModuleCaseError{Module: "Page.Home", Path: "src/Page/Home.elm", File: "src/page/Home.elm"}, err)
+
+	path, err = mem.FindModule("Page.About")
+	require.NoError(err)
+	require.Equal("src2/Page/About.elm", path)
+
+	_, err = mem.FindModule("Utils")
+	require.Equal(&CaseConflictError{Module: "Utils", Paths: []string{"src/Utils.elm", "src/utils.elm"}}, err)
+}
+
+func TestModules(t *testing.T) {
+	require := require.New(t)
+	root, err := createStructure(validPackageEntries...)
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	pkg, err := Load(root)
+	require.NoError(err)
+
+	modules, err := pkg.Modules()
+	require.NoError(err)
+	require.Equal([]string{"Bar", "Foo", "Foo.Bar", "Foo.Bar.Baz", "Foo.Bar.Baz.Mux", "Foo.Bar.Baz.Qux"}, modules)
+
+	mem, err := NewMemory(strings.NewReader(`{"source-directories": ["src"]}`), []string{"src/Main.elm", "src/Page/Home.elm", "tests/Test.elm", "src/Native/List.go"})
+	require.NoError(err)
+	modules, err = mem.Modules()
+	require.NoError(err)
+	require.Equal([]string{"Main", "Page.Home"}, modules)
+}
+
+func TestNewMemory(t *testing.T) {
+	require := require.New(t)
+	manifest := strings.NewReader(`{"source-directories": ["src", "."]}`)
+	pkg, err := NewMemory(manifest, []string{"src/Foo.elm", "./src/Foo/Bar.elm", "Bar.elm", "src/Native/Foo.go", "src/Native/Bar.js"})
+	require.NoError(err)
+
+	cases := []struct {
+		module   string
+		expected string
+		err      error
+	}{
+		{"Foo", "src/Foo.elm", nil},
+		{"Foo.Bar", "src/Foo/Bar.elm", nil},
+		{"Bar", "Bar.elm", nil},
+		{"Native.Foo", "src/Native/Foo.go", nil},
+		{"Native.Bar", "src/Native/Bar.js", nil},
+		{"Foo.Baz", "", ErrModuleNotFound},
+		{"Basics", "", ErrModuleNotFound},
+	}
+
+	for _, c := range cases {
+		path, err := pkg.FindModule(c.module)
+		require.Equal(c.err, err, c.module)
+		require.Equal(c.expected, path, c.module)
+	}
+}
+
+func TestNativeFiles(t *testing.T) {
+	require := require.New(t)
+	require.True(IsNative("src/Native/List.go"))
+	require.True(IsNative("src/Native/List.js"))
+	require.False(IsNative("src/List.elm"))
+	require.False(IsKernel("src/Native/List.go"))
+	require.True(IsKernel("src/Native/List.js"))
+	require.Equal("src/Native/List.decl", NativeDecls("src/Native/List.go"))
+	require.Equal("src/Native/List.decl", NativeDecls("src/Native/List.js"))
+}
+
+func TestTemplate(t *testing.T) {
+	require := require.New(t)
+	root, err := createStructure(entry{
+		"elm-package.json",
+		Package{
+			SourceDirectories: []string{"src"},
+			Templates:         map[string]string{"html": "templates/index.html"},
+		},
+	})
+	require.NoError(err)
+
+	pkg, err := Load(root)
+	require.NoError(err)
+	require.Equal(filepath.Join(pkg.Root(), "templates", "index.html"), pkg.Template("html"))
+	require.Equal("", pkg.Template("go"))
+}
+
 type entry struct {
 	file    string
 	content interface{}
@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is the URL of the public package registry.
+	DefaultRegistry = "http://package.elm-lang.org"
+	// DefaultArchives is the URL the archives of the packages in the
+	// public registry are downloaded from.
+	DefaultArchives = "https://github.com"
+)
+
+// Registry is a package registry, which lists the published versions of the
+// packages and their manifests. The archives of the versions are downloaded
+// from the repositories of the packages.
+type Registry struct {
+	// URL of the registry.
+	URL string
+	// Archives is the URL of the host of the repositories. The archive of a
+	// version is at Archives/user/project/zipball/version/.
+	Archives string
+	// Client is the client used for the requests.
+	Client *http.Client
+
+	// versions are the published versions of every package, which are
+	// only requested once.
+	versions map[string][]Version
+	// manifests keeps the manifests already requested by package and
+	// version.
+	manifests map[string]*Package
+}
+
+// NewRegistry returns a registry at the given URL whose archives are
+// downloaded from the given host.
+func NewRegistry(url, archives string) *Registry {
+	return &Registry{
+		URL:       strings.TrimSuffix(url, "/"),
+		Archives:  strings.TrimSuffix(archives, "/"),
+		Client:    http.DefaultClient,
+		manifests: make(map[string]*Package),
+	}
+}
+
+// registryPackage is a package in the list of all the packages of the
+// registry.
+type registryPackage struct {
+	Name     string    `json:"name"`
+	Versions []Version `json:"versions"`
+}
+
+// Versions returns the published versions of the given package, from the
+// newest to the oldest.
+func (r *Registry) Versions(name string) ([]Version, error) {
+	if r.versions == nil {
+		var all []registryPackage
+		if err := r.getJSON(r.URL+"/all-packages?elm-package-version=0.18", &all); err != nil {
+			return nil, fmt.Errorf("pkg: can't list the packages of the registry: %s", err)
+		}
+
+		r.versions = make(map[string][]Version)
+		for _, p := range all {
+			versions := append([]Version(nil), p.Versions...)
+			sort.Slice(versions, func(i, j int) bool {
+				return versions[j].Less(versions[i])
+			})
+			r.versions[p.Name] = versions
+		}
+	}
+
+	versions, ok := r.versions[name]
+	if !ok {
+		return nil, &NotFoundError{name}
+	}
+	return versions, nil
+}
+
+// NotFoundError describes an error because a package is not in the
+// registry, which is the case of the packages that were never published.
+type NotFoundError struct {
+	// Package that is not in the registry.
+	Package string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("pkg: there is no package %s in the registry", e.Package)
+}
+
+// Manifest returns the manifest of the given version of a package.
+func (r *Registry) Manifest(name string, v Version) (*Package, error) {
+	key := name + "@" + v.String()
+	if p, ok := r.manifests[key]; ok {
+		return p, nil
+	}
+
+	query := url.Values{"name": {name}, "version": {v.String()}}
+	var p Package
+	if err := r.getJSON(r.URL+"/description?"+query.Encode(), &p); err != nil {
+		return nil, fmt.Errorf("pkg: can't get the manifest of %s %s: %s", name, v, err)
+	}
+
+	r.manifests[key] = &p
+	return &p, nil
+}
+
+// Download downloads the archive of the given version of a package and
+// extracts it in dir.
+func (r *Registry) Download(name string, v Version, dir string) error {
+	data, err := r.get(fmt.Sprintf("%s/%s/zipball/%s/", r.Archives, name, v))
+	if err != nil {
+		return fmt.Errorf("pkg: can't download %s %s: %s", name, v, err)
+	}
+
+	if err := extract(data, dir); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("pkg: can't extract %s %s: %s", name, v, err)
+	}
+	return nil
+}
+
+// Documentation returns the documentation of the given version of a
+// package, in the format of the docs.json files.
+func (r *Registry) Documentation(name string, v Version) ([]byte, error) {
+	query := url.Values{"name": {name}, "version": {v.String()}}
+	data, err := r.get(r.URL + "/documentation?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("pkg: can't get the documentation of %s %s: %s", name, v, err)
+	}
+	return data, nil
+}
+
+// Release is a version of a package to be published.
+type Release struct {
+	// Name of the package, of the form user/project.
+	Name string
+	// Version to publish. Its tag must already be in the repository of the
+	// package, which is where its archive is downloaded from.
+	Version Version
+	// Manifest is the content of the elm-package.json of the package.
+	Manifest []byte
+	// Documentation is the documentation of the package, in the format of
+	// the docs.json files.
+	Documentation []byte
+	// Readme is the content of the README.md of the package.
+	Readme []byte
+}
+
+// Publish registers the given release of a package in the registry.
+func (r *Registry) Publish(rel *Release) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, f := range []struct {
+		field, name string
+		content     []byte
+	}{
+		{"description", pkgFile, rel.Manifest},
+		{"documentation", "documentation.json", rel.Documentation},
+		{"readme", "README.md", rel.Readme},
+	} {
+		w, err := mw.CreateFormFile(f.field, f.name)
+		if err != nil {
+			return err
+		}
+		w.Write(f.content)
+	}
+
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	query := url.Values{"name": {rel.Name}, "version": {rel.Version.String()}}
+	resp, err := r.Client.Post(r.URL+"/register?"+query.Encode(), mw.FormDataContentType(), &body)
+	if err != nil {
+		return fmt.Errorf("pkg: can't publish %s %s: %s", rel.Name, rel.Version, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("pkg: can't publish %s %s: unexpected status %s: %s", rel.Name, rel.Version, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	// the versions of the package are not the same anymore
+	r.versions = nil
+	return nil
+}
+
+func (r *Registry) get(url string) ([]byte, error) {
+	resp, err := r.Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (r *Registry) getJSON(url string, v interface{}) error {
+	data, err := r.get(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// extract extracts the zip archive in dir. All the files of the archive are
+// inside a single directory, which is not extracted.
+func extract(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		name := filepath.FromSlash(f.Name)
+		if i := strings.IndexRune(name, filepath.Separator); i >= 0 {
+			name = name[i+1:]
+		} else {
+			continue
+		}
+
+		if name == "" || f.FileInfo().IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if !strings.HasPrefix(path, filepath.Clean(dir)+separator) {
+			return fmt.Errorf("invalid file path %q", f.Name)
+		}
+
+		if err := extractFile(f, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
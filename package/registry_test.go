@@ -0,0 +1,306 @@
+package pkg
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// registryPackages are the packages of the test registry, with the
+// dependencies of every version.
+var registryPackages = map[string]map[string]Dependencies{
+	"foo/bar": {
+		"1.0.0": nil,
+		"1.1.0": nil,
+		"2.0.0": {"foo/baz": Version{2, 0, 0}.UntilNextMajor()},
+	},
+	"foo/baz": {
+		"1.0.0": nil,
+		"2.0.0": nil,
+	},
+	"foo/qux": {
+		"1.0.0": {"foo/baz": Version{1, 0, 0}.UntilNextMajor()},
+	},
+}
+
+// newTestRegistry returns a registry of the registryPackages and the
+// server that serves them, which must be closed.
+func newTestRegistry(t *testing.T) (*Registry, *httptest.Server) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/all-packages", func(w http.ResponseWriter, r *http.Request) {
+		var all []registryPackage
+		for name, versions := range registryPackages {
+			p := registryPackage{Name: name}
+			for v := range versions {
+				var version Version
+				require.NoError(t, version.UnmarshalText([]byte(v)))
+				p.Versions = append(p.Versions, version)
+			}
+			all = append(all, p)
+		}
+		json.NewEncoder(w).Encode(all)
+	})
+
+	mux.HandleFunc("/description", func(w http.ResponseWriter, r *http.Request) {
+		deps, ok := registryPackages[r.FormValue("name")][r.FormValue("version")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(testManifest(r.FormValue("version"), deps))
+	})
+
+	mux.HandleFunc("/documentation", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := registryPackages[r.FormValue("name")][r.FormValue("version")]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"name": "` + r.FormValue("version") + `"}]`))
+	})
+
+	mux.HandleFunc("/advisories", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testAdvisories))
+	})
+
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := registryPackages[r.FormValue("name")][r.FormValue("version")]; ok {
+			http.Error(w, "version already published", http.StatusConflict)
+			return
+		}
+
+		for _, field := range []string{"description", "documentation", "readme"} {
+			f, _, err := r.FormFile(field)
+			require.NoError(t, err)
+			content, err := ioutil.ReadAll(f)
+			require.NoError(t, err)
+			require.Equal(t, field, string(content))
+		}
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 4 || parts[2] != "zipball" {
+			http.NotFound(w, r)
+			return
+		}
+
+		name := parts[0] + "/" + parts[1]
+		deps, ok := registryPackages[name][parts[3]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		manifest, err := json.Marshal(testManifest(parts[3], deps))
+		require.NoError(t, err)
+
+		module := strings.Title(parts[1])
+		zw := zip.NewWriter(w)
+		for path, content := range map[string]string{
+			"elm-package.json":       string(manifest),
+			"src/" + module + ".elm": "module " + module + " exposing (..)\n",
+		} {
+			f, err := zw.Create(parts[0] + "-" + parts[1] + "-abcdef/" + path)
+			require.NoError(t, err)
+			f.Write([]byte(content))
+		}
+		require.NoError(t, zw.Close())
+	})
+
+	srv := httptest.NewServer(mux)
+	return NewRegistry(srv.URL, srv.URL), srv
+}
+
+// testAdvisories is the advisory feed of the test registry.
+const testAdvisories = `{
+	"advisories": [
+		{"id": "ELM-2", "package": "foo/bar", "versions": "1.0.0 <= v < 1.1.0", "severity": "low", "summary": "Leaks the model"},
+		{"id": "ELM-1", "package": "foo/bar", "versions": "1.0.0 <= v < 2.0.0", "severity": "high", "summary": "Runs untrusted code", "url": "https://example.com/ELM-1"},
+		{"id": "ELM-3", "package": "foo/baz", "versions": "2.0.0 <= v < 3.0.0", "severity": "critical", "summary": "Crashes"}
+	],
+	"yanked": {"foo/bar": ["1.1.0", "1.0.0"], "foo/baz": ["1.0.1"]},
+	"unmaintained": {"foo/qux": "use foo/baz instead", "foo/bar": ""}
+}`
+
+func testManifest(version string, deps Dependencies) *Package {
+	p := &Package{SourceDirectories: []string{"src"}, Dependencies: deps}
+	p.Version.UnmarshalText([]byte(version))
+	return p
+}
+
+func TestRegistry(t *testing.T) {
+	require := require.New(t)
+	r, srv := newTestRegistry(t)
+	defer srv.Close()
+
+	versions, err := r.Versions("foo/bar")
+	require.NoError(err)
+	require.Equal([]Version{{2, 0, 0}, {1, 1, 0}, {1, 0, 0}}, versions)
+
+	_, err = r.Versions("foo/unknown")
+	require.Equal(&NotFoundError{"foo/unknown"}, err)
+
+	m, err := r.Manifest("foo/bar", Version{2, 0, 0})
+	require.NoError(err)
+	require.Equal(Dependencies{"foo/baz": Version{2, 0, 0}.UntilNextMajor()}, m.Dependencies)
+
+	_, err = r.Manifest("foo/bar", Version{3, 0, 0})
+	require.Error(err)
+
+	dir, err := ioutil.TempDir("", "elmo-registry")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(r.Download("foo/bar", Version{1, 0, 0}, dir))
+	content, err := ioutil.ReadFile(filepath.Join(dir, "src", "Bar.elm"))
+	require.NoError(err)
+	require.Equal("module Bar exposing (..)\n", string(content))
+
+	require.Error(r.Download("foo/bar", Version{3, 0, 0}, filepath.Join(dir, "other")))
+}
+
+func TestRegistryPublish(t *testing.T) {
+	require := require.New(t)
+	r, srv := newTestRegistry(t)
+	defer srv.Close()
+
+	docs, err := r.Documentation("foo/bar", Version{1, 1, 0})
+	require.NoError(err)
+	require.Equal(`[{"name": "1.1.0"}]`, string(docs))
+
+	_, err = r.Documentation("foo/bar", Version{3, 0, 0})
+	require.Error(err)
+
+	rel := &Release{
+		Name:          "foo/bar",
+		Version:       Version{3, 0, 0},
+		Manifest:      []byte("description"),
+		Documentation: []byte("documentation"),
+		Readme:        []byte("readme"),
+	}
+	require.NoError(r.Publish(rel))
+
+	rel.Version = Version{2, 0, 0}
+	err = r.Publish(rel)
+	require.Error(err)
+	require.Contains(err.Error(), "version already published")
+}
+
+func TestAudit(t *testing.T) {
+	require := require.New(t)
+	r, srv := newTestRegistry(t)
+	defer srv.Close()
+
+	a, err := r.Advisories("")
+	require.NoError(err)
+	require.Len(a.Advisories, 3)
+
+	deps := ExactDependencies{
+		"foo/bar": Version{1, 0, 0},
+		"foo/baz": Version{1, 0, 0},
+		"foo/qux": Version{1, 0, 0},
+	}
+	var found []string
+	for _, f := range a.Audit(deps, "ELM-2") {
+		found = append(found, fmt.Sprintf("%s %v", f, f.Bad()))
+	}
+	require.Equal([]string{
+		"foo/bar 1.0.0 is affected by ELM-1 (high): Runs untrusted code https://example.com/ELM-1 true",
+		"foo/bar 1.0.0 was yanked true",
+		"foo/bar is no longer maintained false",
+		"foo/qux is no longer maintained: use foo/baz instead false",
+	}, found)
+
+	findings := a.Audit(ExactDependencies{"foo/bar": Version{1, 0, 0}})
+	require.Equal("ELM-1", findings[0].Advisory.ID)
+	require.Equal("ELM-2", findings[1].Advisory.ID)
+
+	require.Empty(a.Audit(ExactDependencies{"foo/baz": Version{1, 0, 0}, "foo/other": Version{1, 0, 0}}))
+
+	_, err = r.Advisories(srv.URL + "/unknown")
+	require.Error(err)
+}
+
+func TestSolve(t *testing.T) {
+	require := require.New(t)
+	r, srv := newTestRegistry(t)
+	defer srv.Close()
+
+	exact, err := Solve(r, Dependencies{
+		"foo/bar": VersionRange{Version{1, 0, 0}, Version{3, 0, 0}},
+		"foo/qux": Version{1, 0, 0}.UntilNextMajor(),
+	}, nil)
+	require.NoError(err)
+	require.Equal(ExactDependencies{
+		"foo/bar": Version{1, 1, 0},
+		"foo/baz": Version{1, 0, 0},
+		"foo/qux": Version{1, 0, 0},
+	}, exact)
+
+	exact, err = Solve(r, Dependencies{
+		"foo/bar": Version{1, 0, 0}.UntilNextMajor(),
+	}, ExactDependencies{"foo/bar": Version{1, 0, 0}})
+	require.NoError(err)
+	require.Equal(ExactDependencies{"foo/bar": Version{1, 0, 0}}, exact)
+
+	_, err = Solve(r, Dependencies{
+		"foo/bar": Version{2, 0, 0}.UntilNextMajor(),
+		"foo/qux": Version{1, 0, 0}.UntilNextMajor(),
+	}, nil)
+	require.Equal(&ConflictError{"foo/qux"}, err)
+}
+
+func TestInstall(t *testing.T) {
+	require := require.New(t)
+	r, srv := newTestRegistry(t)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "elmo-install")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	manifest := testManifest("1.0.0", Dependencies{"foo/qux": Version{1, 0, 0}.UntilNextMajor()})
+	manifest.root = dir
+	require.NoError(manifest.Save())
+
+	p, err := Load(dir)
+	require.NoError(err)
+
+	v, err := p.AddDependency(r, "foo/bar")
+	require.NoError(err)
+	require.Equal(Version{1, 1, 0}, v)
+	require.NoError(p.Save())
+
+	downloaded, err := p.Install(r)
+	require.NoError(err)
+	require.Equal([]string{"foo/bar", "foo/baz", "foo/qux"}, downloaded)
+
+	p, err = Load(dir)
+	require.NoError(err)
+	require.Equal(Version{1, 1, 0}.UntilNextMajor(), p.Dependencies["foo/bar"])
+	require.Equal(ExactDependencies{
+		"foo/bar": Version{1, 1, 0},
+		"foo/baz": Version{1, 0, 0},
+		"foo/qux": Version{1, 0, 0},
+	}, p.ExactDependencies)
+
+	path, err := p.FindModule("Baz")
+	require.NoError(err)
+	require.Equal(filepath.Join(dir, "elm-stuff", "packages", "foo", "baz", "1.0.0", "src", "Baz.elm"), path)
+
+	downloaded, err = p.Install(r)
+	require.NoError(err)
+	require.Empty(downloaded)
+
+	_, err = p.AddDependency(r, "foo/unknown")
+	require.Error(err)
+}
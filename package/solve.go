@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ConflictError describes an error because there are no versions of a
+// package that satisfy the version ranges required by the dependencies.
+type ConflictError struct {
+	// Package whose versions do not satisfy the ranges.
+	Package string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf(
+		"pkg: there are no versions of %s that satisfy the constraints of the dependencies",
+		e.Package,
+	)
+}
+
+// Solve returns the exact versions of the given dependencies and all the
+// packages they depend on, which satisfy all the version ranges. The newest
+// versions are chosen, unless the version in locked satisfies the ranges,
+// so that the versions already installed are kept.
+func Solve(r *Registry, deps Dependencies, locked ExactDependencies) (ExactDependencies, error) {
+	s := &solver{registry: r, locked: locked}
+	ranges := make(map[string][]VersionRange)
+	for name, vr := range deps {
+		ranges[name] = []VersionRange{vr}
+	}
+
+	result, err := s.solve(make(ExactDependencies), ranges)
+	if err == errNoSolution {
+		return nil, &ConflictError{s.failed}
+	}
+	return result, err
+}
+
+// errNoSolution is returned by the solver when there are no versions left to
+// try for a package.
+var errNoSolution = errors.New("pkg: no solution")
+
+type solver struct {
+	registry *Registry
+	locked   ExactDependencies
+	// failed is the first package whose versions were all discarded.
+	failed string
+}
+
+func (s *solver) solve(chosen ExactDependencies, ranges map[string][]VersionRange) (ExactDependencies, error) {
+	name := next(chosen, ranges)
+	if name == "" {
+		return chosen, nil
+	}
+
+	candidates, err := s.candidates(name, ranges[name])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range candidates {
+		m, err := s.registry.Manifest(name, v)
+		if err != nil {
+			return nil, err
+		}
+
+		if !compatible(m.Dependencies, chosen) {
+			continue
+		}
+
+		nextChosen := make(ExactDependencies, len(chosen)+1)
+		for dep, v := range chosen {
+			nextChosen[dep] = v
+		}
+		nextChosen[name] = v
+
+		nextRanges := make(map[string][]VersionRange, len(ranges))
+		for dep, rs := range ranges {
+			nextRanges[dep] = rs
+		}
+		for dep, vr := range m.Dependencies {
+			nextRanges[dep] = append(append([]VersionRange(nil), nextRanges[dep]...), vr)
+		}
+
+		result, err := s.solve(nextChosen, nextRanges)
+		if err == errNoSolution {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	if s.failed == "" {
+		s.failed = name
+	}
+	return nil, errNoSolution
+}
+
+// candidates returns the versions of the package that are in all the given
+// ranges, in the order they have to be tried.
+func (s *solver) candidates(name string, ranges []VersionRange) ([]Version, error) {
+	versions, err := s.registry.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Version
+	for _, v := range versions {
+		if inRanges(v, ranges) {
+			if locked, ok := s.locked[name]; ok && v == locked {
+				result = append([]Version{v}, result...)
+			} else {
+				result = append(result, v)
+			}
+		}
+	}
+	return result, nil
+}
+
+// next returns the first package, by name, that has ranges but no chosen
+// version, or an empty string if there are none.
+func next(chosen ExactDependencies, ranges map[string][]VersionRange) string {
+	var names []string
+	for name := range ranges {
+		if _, ok := chosen[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+	return names[0]
+}
+
+// compatible reports whether the chosen versions are in the ranges of the
+// given dependencies.
+func compatible(deps Dependencies, chosen ExactDependencies) bool {
+	for dep, vr := range deps {
+		if v, ok := chosen[dep]; ok && !vr.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func inRanges(v Version, ranges []VersionRange) bool {
+	for _, vr := range ranges {
+		if !vr.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GeneratedDir is the directory, in the elm-stuff directory of a package,
+// of the paths of its synthetic modules. The files are never written, but
+// the paths tell where the modules come from in the diagnostics.
+const GeneratedDir = "generated"
+
+// ModuleProvider provides the source code of the synthetic modules of a
+// package, which are generated at build time, such as the manifest of its
+// assets or the decoders derived from its types. They are found and loaded
+// like the modules of the source directories without being written to
+// files, so a step of the build that generates code can feed it back into
+// the compilation.
+type ModuleProvider interface {
+	// Modules returns the names of the modules it provides.
+	Modules() []string
+	// Source returns the source code of the module with the given name.
+	Source(module string) ([]byte, error)
+}
+
+// MemoryModules is a ModuleProvider of the modules whose source code is in
+// the map, by their name.
+type MemoryModules map[string][]byte
+
+// Modules returns the names of the modules in the map, sorted.
+func (m MemoryModules) Modules() []string {
+	var names = make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the source code of the module with the given name.
+func (m MemoryModules) Source(module string) ([]byte, error) {
+	src, ok := m[module]
+	if !ok {
+		return nil, ErrModuleNotFound
+	}
+	return src, nil
+}
+
+// synthetic is a synthetic module of the package.
+type synthetic struct {
+	module   string
+	provider ModuleProvider
+}
+
+// Provide adds the modules of the provider, the ones it returns when it is
+// added, to the synthetic modules of the package. They are found after the
+// modules of the source directories, so a module of the source directories
+// with the same name is found instead, and it is an error if another
+// provider already provides one of them.
+func (p *Package) Provide(provider ModuleProvider) error {
+	if p.synthetic == nil {
+		p.synthetic = make(map[string]*synthetic)
+	}
+
+	modules := provider.Modules()
+	for _, module := range modules {
+		if _, ok := p.synthetic[p.SyntheticPath(module)]; ok {
+			return fmt.Errorf("pkg: synthetic module %s is provided more than once", module)
+		}
+	}
+
+	for _, module := range modules {
+		p.synthetic[p.SyntheticPath(module)] = &synthetic{module, provider}
+	}
+	return nil
+}
+
+// SyntheticPath returns the path of the synthetic module with the given
+// name, in the GeneratedDir of the elm-stuff directory of the package.
+func (p *Package) SyntheticPath(module string) string {
+	return filepath.Join(p.root, elmStuffDir, GeneratedDir, strings.Replace(module, ".", string(filepath.Separator), -1)+ext)
+}
+
+// SyntheticSource returns the source code of the synthetic module at the
+// given path, and false if it is not the path of a synthetic module of
+// the package.
+func (p *Package) SyntheticSource(path string) ([]byte, bool, error) {
+	s, ok := p.synthetic[filepath.Clean(path)]
+	if !ok {
+		return nil, false, nil
+	}
+
+	src, err := s.provider.Source(s.module)
+	if err != nil {
+		return nil, true, fmt.Errorf("pkg: can't generate synthetic module %s: %s", s.module, err)
+	}
+	return src, true, nil
+}
+
+// findSyntheticModule returns the path of the synthetic module with the
+// given name, or an empty string if the package has no such module.
+func (p *Package) findSyntheticModule(module string) string {
+	path := p.SyntheticPath(module)
+	if _, ok := p.synthetic[path]; ok {
+		return path
+	}
+	return ""
+}
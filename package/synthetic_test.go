@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyntheticModules(t *testing.T) {
+	require := require.New(t)
+	p, err := NewMemory(strings.NewReader(`{"source-directories": ["src"]}`), []string{"src/Main.elm", "src/Assets.elm"})
+	require.NoError(err)
+
+	require.NoError(p.Provide(MemoryModules{
+		"Api.Decoders": []byte("module Api.Decoders exposing (..)\n"),
+		"Assets":       []byte("module Assets exposing (..)\n"),
+	}))
+
+	path, err := p.FindModule("Api.Decoders")
+	require.NoError(err)
+	require.Equal(filepath.Join("elm-stuff", "generated", "Api", "Decoders.elm"), path)
+	require.Equal(path, p.SyntheticPath("Api.Decoders"))
+
+	src, ok, err := p.SyntheticSource(path)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal("module Api.Decoders exposing (..)\n", string(src))
+
+	// the modules of the source directories are found first
+	path, err = p.FindModule("Assets")
+	require.NoError(err)
+	require.Equal(filepath.Join("src", "Assets.elm"), path)
+
+	_, ok, err = p.SyntheticSource(filepath.Join("src", "Main.elm"))
+	require.NoError(err)
+	require.False(ok)
+
+	modules, err := p.Modules()
+	require.NoError(err)
+	require.Equal([]string{"Api.Decoders", "Assets", "Main"}, modules)
+
+	require.EqualError(p.Provide(MemoryModules{"Api.Decoders": nil}), "pkg: synthetic module Api.Decoders is provided more than once")
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The tags of the modules of a package, in the module-tags of its manifest,
+// which leave them out of the builds that do not include their tag.
+const (
+	// TestTag is the tag of the modules that are only imported by the
+	// tests, such as their fixtures and helpers.
+	TestTag = "test"
+	// ExampleTag is the tag of the modules that are examples of the
+	// documentation.
+	ExampleTag = "example"
+)
+
+// ExcludedModuleError is returned when a module is found, but it has a tag
+// that the package does not include, such as a test module imported by the
+// code of a program.
+type ExcludedModuleError struct {
+	// Module is the name of the module.
+	Module string
+	// Tag is the tag of the module.
+	Tag string
+}
+
+func (e *ExcludedModuleError) Error() string {
+	return fmt.Sprintf("pkg: module %s is tagged %s, so it is only built with the modules tagged %s", e.Module, e.Tag, e.Tag)
+}
+
+// ModuleTag returns the tag of the module with the given name in the
+// manifest, or an empty string if it has none.
+func (p *Package) ModuleTag(module string) string {
+	var tags = make([]string, 0, len(p.ModuleTags))
+	for tag := range p.ModuleTags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		for _, pattern := range p.ModuleTags[tag] {
+			if matchModule(pattern, module) {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// WithTags returns a copy of the package whose modules with the given tags
+// are found too, as well as the ones without tags. The copy starts with the
+// modules already found by the package, but it keeps the ones it finds to
+// itself.
+func (p *Package) WithTags(tags ...string) *Package {
+	c := *p
+	c.moduleCache = make(map[string]string, len(p.moduleCache))
+	for module, path := range p.moduleCache {
+		c.moduleCache[module] = path
+	}
+
+	c.dirNames = make(map[string][]string, len(p.dirNames))
+	for dir, names := range p.dirNames {
+		c.dirNames[dir] = names
+	}
+
+	c.tags = make(map[string]bool)
+	for tag := range p.tags {
+		c.tags[tag] = true
+	}
+	for _, tag := range tags {
+		c.tags[tag] = true
+	}
+	return &c
+}
+
+// excluded returns the error of the module if it has a tag that the
+// package does not include, or nil if it is found.
+func (p *Package) excluded(module string) error {
+	if tag := p.ModuleTag(module); tag != "" && !p.tags[tag] {
+		return &ExcludedModuleError{Module: module, Tag: tag}
+	}
+	return nil
+}
+
+// matchModule reports whether the name of the module matches the pattern
+// of the module tags, which is a name or a name ending in .*, which matches
+// the modules under it.
+func matchModule(pattern, module string) bool {
+	if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+		return strings.HasPrefix(module, prefix)
+	}
+	return pattern == module
+}
+
+// validateTags returns an error if the module tags of the manifest are not
+// valid.
+func (p *Package) validateTags() error {
+	for tag, patterns := range p.ModuleTags {
+		if tag != TestTag && tag != ExampleTag {
+			return fmt.Errorf("pkg: unknown module tag %q, it must be %s or %s", tag, TestTag, ExampleTag)
+		}
+
+		for _, pattern := range patterns {
+			name := strings.TrimSuffix(pattern, ".*")
+			if name == "" || strings.Contains(name, "*") {
+				return fmt.Errorf("pkg: %q is not a valid module name or pattern of the module tag %s", pattern, tag)
+			}
+		}
+	}
+	return nil
+}
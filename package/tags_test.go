@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleTags(t *testing.T) {
+	require := require.New(t)
+	manifest := `{"source-directories": ["src"], "module-tags": {"test": ["Fixtures", "Helpers.*"], "example": ["Examples.*"]}}`
+	p, err := NewMemory(strings.NewReader(manifest), []string{
+		"src/Main.elm",
+		"src/Fixtures.elm",
+		"src/Helpers/Http.elm",
+		"src/Helpers.elm",
+		"src/Examples/Counter.elm",
+	})
+	require.NoError(err)
+
+	require.Equal(TestTag, p.ModuleTag("Fixtures"))
+	require.Equal(TestTag, p.ModuleTag("Helpers.Http"))
+	require.Equal("", p.ModuleTag("Helpers"))
+	require.Equal(ExampleTag, p.ModuleTag("Examples.Counter"))
+	require.Equal("", p.ModuleTag("Main"))
+
+	_, err = p.FindModule("Fixtures")
+	require.Equal(&ExcludedModuleError{Module: "Fixtures", Tag: TestTag}, err)
+	_, err = p.FindModule("Examples.Counter")
+	require.Equal(&ExcludedModuleError{Module: "Examples.Counter", Tag: ExampleTag}, err)
+
+	modules, err := p.Modules()
+	require.NoError(err)
+	require.Equal([]string{"Helpers", "Main"}, modules)
+
+	tp := p.WithTags(TestTag)
+	path, err := tp.FindModule("Helpers.Http")
+	require.NoError(err)
+	require.Equal("src/Helpers/Http.elm", path)
+	_, err = tp.FindModule("Examples.Counter")
+	require.Equal(&ExcludedModuleError{Module: "Examples.Counter", Tag: ExampleTag}, err)
+
+	modules, err = tp.WithTags(ExampleTag).Modules()
+	require.NoError(err)
+	require.Equal([]string{"Examples.Counter", "Fixtures", "Helpers", "Helpers.Http", "Main"}, modules)
+
+	// the copy does not change the tags of the package
+	_, err = p.FindModule("Helpers.Http")
+	require.Error(err)
+
+	// nor the modules it has found
+	_, err = p.FindModule("Main")
+	require.NoError(err)
+	_, err = p.WithTags(TestTag).FindModule("Fixtures")
+	require.NoError(err)
+	require.Equal(map[string]string{"Main": "src/Main.elm"}, p.moduleCache)
+}
+
+func TestModuleTagsInvalid(t *testing.T) {
+	require := require.New(t)
+	cases := []struct {
+		tags string
+		err  string
+	}{
+		{`{"bench": ["Bench"]}`, `pkg: unknown module tag "bench", it must be test or example`},
+		{`{"test": [".*"]}`, `pkg: ".*" is not a valid module name or pattern of the module tag test`},
+		{`{"test": ["Foo*"]}`, `pkg: "Foo*" is not a valid module name or pattern of the module tag test`},
+	}
+
+	for _, c := range cases {
+		_, err := NewMemory(strings.NewReader(`{"source-directories": ["."], "module-tags": `+c.tags+`}`), nil)
+		require.EqualError(err, c.err, c.tags)
+	}
+}
@@ -64,3 +64,65 @@ func (v Version) MarshalText() ([]byte, error) {
 func (v Version) String() string {
 	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
 }
+
+// Contains reports whether the given version is in the range.
+func (vr VersionRange) Contains(v Version) bool {
+	return !v.Less(vr.Min) && v.Less(vr.Max)
+}
+
+// Less reports whether the version is lower than the other one.
+func (v Version) Less(other Version) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// UntilNextMajor returns the range of versions from this one until the next
+// major version, which are the ones that are compatible with it.
+func (v Version) UntilNextMajor() VersionRange {
+	return VersionRange{Min: v, Max: Version{v[0] + 1, 0, 0}}
+}
+
+// Magnitude is the magnitude of the changes between two versions of a
+// package, which tells which number of the version must be increased.
+type Magnitude int
+
+const (
+	// Patch changes do not change the exposed API of the package.
+	Patch Magnitude = iota
+	// Minor changes add things to the exposed API of the package, so the
+	// code that uses the previous version still works.
+	Minor
+	// Major changes remove or change things of the exposed API of the
+	// package, so the code that uses the previous version may not work.
+	Major
+)
+
+var magnitudeNames = map[Magnitude]string{
+	Patch: "PATCH",
+	Minor: "MINOR",
+	Major: "MAJOR",
+}
+
+func (m Magnitude) String() string {
+	return magnitudeNames[m]
+}
+
+func (m Magnitude) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Bump returns the next version after this one with changes of the given
+// magnitude.
+func (v Version) Bump(m Magnitude) Version {
+	switch m {
+	case Major:
+		return Version{v[0] + 1, 0, 0}
+	case Minor:
+		return Version{v[0], v[1] + 1, 0}
+	}
+	return Version{v[0], v[1], v[2] + 1}
+}
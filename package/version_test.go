@@ -66,3 +66,32 @@ func TestVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestVersionRangeContains(t *testing.T) {
+	require := require.New(t)
+	r := Version{1, 2, 0}.UntilNextMajor()
+	require.Equal("1.2.0 <= v < 2.0.0", r.String())
+
+	require.True(r.Contains(Version{1, 2, 0}))
+	require.True(r.Contains(Version{1, 10, 3}))
+	require.False(r.Contains(Version{1, 1, 9}))
+	require.False(r.Contains(Version{2, 0, 0}))
+	require.False(r.Contains(Version{0, 3, 0}))
+}
+
+func TestVersionLess(t *testing.T) {
+	require := require.New(t)
+	require.True(Version{1, 0, 0}.Less(Version{1, 0, 1}))
+	require.True(Version{1, 9, 9}.Less(Version{2, 0, 0}))
+	require.False(Version{1, 0, 0}.Less(Version{1, 0, 0}))
+	require.False(Version{1, 1, 0}.Less(Version{1, 0, 9}))
+}
+
+func TestVersionBump(t *testing.T) {
+	require := require.New(t)
+	v := Version{1, 2, 3}
+	require.Equal(Version{1, 2, 4}, v.Bump(Patch))
+	require.Equal(Version{1, 3, 0}, v.Bump(Minor))
+	require.Equal(Version{2, 0, 0}, v.Bump(Major))
+	require.Equal("MINOR", Minor.String())
+}
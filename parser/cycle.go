@@ -0,0 +1,339 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/report"
+)
+
+// maxListedNames is the maximum number of names of a module that are listed
+// in the explanation of an import of a cycle.
+const maxListedNames = 5
+
+// cycleImport is an import of a circular dependency and the names it uses
+// from the imported module.
+type cycleImport struct {
+	from, to string
+	used     []string
+}
+
+// cycleMessage returns the message of the circular dependency between the
+// given modules, each of which imports the next one, and the last one the
+// first one. It explains which names every import uses and suggests how to
+// break the cycle at the import that uses the fewest names.
+func (p *fullParser) cycleMessage(cycle []string) string {
+	var buf strings.Builder
+	buf.WriteString("I found a circular dependency in your code between these modules:\n")
+	for _, m := range cycle {
+		fmt.Fprintf(&buf, "- %s\n", m)
+	}
+
+	modules := make(map[string]*ast.Module, len(cycle))
+	for _, m := range cycle {
+		if mod := p.parseForCycle(m); mod != nil {
+			modules[m] = mod
+		}
+	}
+
+	var imports []*cycleImport
+	for i, m := range cycle {
+		to := cycle[(i+1)%len(cycle)]
+		from, imported := modules[m], modules[to]
+		if from == nil || imported == nil {
+			return strings.TrimSuffix(buf.String(), "\n")
+		}
+		imports = append(imports, &cycleImport{m, to, usedNames(from, imported)})
+	}
+
+	buf.WriteString("\n")
+	var fewest *cycleImport
+	for _, imp := range imports {
+		if fewest == nil || len(imp.used) < len(fewest.used) {
+			fewest = imp
+		}
+		fmt.Fprintf(&buf, "- %s imports %s and %s\n", imp.from, imp.to, imp.uses())
+	}
+
+	buf.WriteString("\n")
+	switch n := len(fewest.used); {
+	case n == 0:
+		fmt.Fprintf(&buf, "Nothing of %s is used in %s, so removing the import breaks the cycle.", fewest.to, fewest.from)
+	case n <= maxListedNames:
+		fmt.Fprintf(
+			&buf,
+			"Only %s of %s %s used in %s, so consider moving %s to another module that both can import.",
+			fewest.names(), fewest.to, plural(n, "is", "are"), fewest.from, plural(n, "it", "them"),
+		)
+	default:
+		fmt.Fprintf(&buf, "Consider moving the code of %s that %s uses to another module that both can import.", fewest.to, fewest.from)
+	}
+	return buf.String()
+}
+
+// uses describes the names used by the import.
+func (imp *cycleImport) uses() string {
+	switch n := len(imp.used); {
+	case n == 0:
+		return "uses nothing of it"
+	case n <= maxListedNames:
+		return "uses " + imp.names()
+	default:
+		return fmt.Sprintf("uses %d names of it, such as %s", n, imp.qualified(imp.used[:3]))
+	}
+}
+
+// names returns the list of names used by the import.
+func (imp *cycleImport) names() string {
+	return imp.qualified(imp.used)
+}
+
+func (imp *cycleImport) qualified(names []string) string {
+	var list = make([]string, len(names))
+	for i, n := range names {
+		list[i] = fmt.Sprintf("`%s.%s`", imp.to, n)
+	}
+
+	if len(list) == 1 {
+		return list[0]
+	}
+	return strings.Join(list[:len(list)-1], ", ") + " and " + list[len(list)-1]
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// parseForCycle parses the definitions of the given module of a cycle with
+// a parser of its own, whose problems are not reported, since the module is
+// only parsed to know the names it uses.
+func (p *fullParser) parseForCycle(module string) *ast.Module {
+	path, ok := p.modCache[p.optable.names.Intern(module)]
+	if !ok {
+		return nil
+	}
+
+	sess := NewSession(report.NewReporter(p.cm, nil), p.cm, p.optable)
+	cp := newParser(sess)
+	var mod *ast.Module
+	err := cp.run(func() {
+		cp.init(path, p.cm.Source(path).Scanner(), FullParse)
+		cp.fixities = p.optable.snapshot(p.optable.names.Intern(module))
+		mod = parseFile(cp)
+	})
+	if err != nil {
+		return nil
+	}
+	return mod
+}
+
+// usedNames returns the names that the module uses from the imported one,
+// in the order they are first used. They are the names qualified with the
+// module or its alias, and the names it exposes that are used unqualified
+// and are not defined in the module. The names are not resolved, so a name
+// bound in a pattern is never taken as one of the imported module.
+func usedNames(mod, imported *ast.Module) []string {
+	var imp *ast.ImportDecl
+	for _, i := range mod.Imports {
+		if i.ModuleName() == imported.Name {
+			imp = i
+		}
+	}
+
+	if imp == nil {
+		return nil
+	}
+
+	qualifier := imported.Name
+	if imp.Alias != nil {
+		qualifier = imp.Alias.Name
+	}
+
+	exposed := exposedByImport(imp, imported)
+	bound := boundNames(mod)
+
+	var (
+		used []string
+		seen = make(map[string]bool)
+	)
+	use := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			used = append(used, name)
+		}
+	}
+
+	for _, d := range mod.Decls {
+		ast.WalkFunc(d, func(n ast.Node) bool {
+			var expr ast.Expr
+			switch n := n.(type) {
+			case *ast.Ident:
+				expr = n
+			case *ast.SelectorExpr:
+				expr = n
+			default:
+				return true
+			}
+
+			module, name := splitQualified(expr)
+			switch {
+			case module == qualifier:
+				use(name)
+			case module == "" && exposed[name] && !bound[name]:
+				use(name)
+			}
+			return false
+		})
+	}
+	return used
+}
+
+// splitQualified returns the module and the name of a qualified name, such
+// as `Json.Decode.map`. The module is empty if the name is not qualified.
+// The fields accessed on a name, such as `model.count`, are left out.
+func splitQualified(expr ast.Expr) (module, name string) {
+	var path []string
+	for expr != nil {
+		var id *ast.Ident
+		switch e := expr.(type) {
+		case *ast.Ident:
+			id, expr = e, nil
+		case *ast.SelectorExpr:
+			id, expr = e.Selector, e.Expr
+		default:
+			expr = nil
+			continue
+		}
+
+		path = append(path, id.Name)
+		if !isUpper(id.Name) {
+			break
+		}
+	}
+
+	if len(path) == 0 {
+		return "", ""
+	}
+	return strings.Join(path[:len(path)-1], "."), path[len(path)-1]
+}
+
+// exposedByImport returns the names of the imported module that the import
+// exposes to be used unqualified.
+func exposedByImport(imp *ast.ImportDecl, imported *ast.Module) map[string]bool {
+	var names = make(map[string]bool)
+	switch list := imp.Exposing.(type) {
+	case *ast.OpenList:
+		for name := range moduleExports(imported) {
+			names[name] = true
+		}
+	case *ast.ClosedList:
+		for _, e := range list.Exposed {
+			switch e := e.(type) {
+			case *ast.ExposedVar:
+				names[e.Name] = true
+			case *ast.ExposedUnion:
+				names[e.Type.Name] = true
+				for _, ctor := range exposedCtors(e, imported) {
+					names[ctor] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// moduleExports returns the names exposed by the module.
+func moduleExports(mod *ast.Module) map[string]bool {
+	var names = make(map[string]bool)
+	switch list := mod.Module.Exposing.(type) {
+	case *ast.OpenList:
+		for _, d := range mod.Decls {
+			for _, name := range declNames(d) {
+				names[name] = true
+			}
+		}
+	case *ast.ClosedList:
+		for _, e := range list.Exposed {
+			switch e := e.(type) {
+			case *ast.ExposedVar:
+				names[e.Name] = true
+			case *ast.ExposedUnion:
+				names[e.Type.Name] = true
+				for _, ctor := range exposedCtors(e, mod) {
+					names[ctor] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// exposedCtors returns the names of the constructors of an exposed union of
+// the module.
+func exposedCtors(e *ast.ExposedUnion, mod *ast.Module) []string {
+	var ctors []string
+	switch list := e.Ctors.(type) {
+	case *ast.OpenList:
+		for _, d := range mod.Decls {
+			if u, ok := d.(*ast.UnionDecl); ok && u.Name.Name == e.Type.Name {
+				for _, c := range u.Ctors {
+					ctors = append(ctors, c.Name.Name)
+				}
+			}
+		}
+	case *ast.ClosedList:
+		for _, c := range list.Exposed {
+			if v, ok := c.(*ast.ExposedVar); ok {
+				ctors = append(ctors, v.Name)
+			}
+		}
+	}
+	return ctors
+}
+
+// declNames returns the names defined by the declaration.
+func declNames(d ast.Decl) []string {
+	switch d := d.(type) {
+	case *ast.Definition:
+		return []string{d.Name.Name}
+	case *ast.AliasDecl:
+		return []string{d.Name.Name}
+	case *ast.PortDecl:
+		return []string{d.Name.Name}
+	case *ast.UnionDecl:
+		names := []string{d.Name.Name}
+		for _, c := range d.Ctors {
+			names = append(names, c.Name.Name)
+		}
+		return names
+	}
+	return nil
+}
+
+// boundNames returns the names defined by the module, at the top level or
+// bound in its patterns and let expressions.
+func boundNames(mod *ast.Module) map[string]bool {
+	var names = make(map[string]bool)
+	for _, d := range mod.Decls {
+		for _, name := range declNames(d) {
+			names[name] = true
+		}
+
+		ast.WalkFunc(d, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.VarPattern:
+				names[n.Name.Name] = true
+			case *ast.AliasPattern:
+				names[n.Name.Name] = true
+			case *ast.Definition:
+				names[n.Name.Name] = true
+			}
+			return true
+		})
+	}
+	return names
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
 	"github.com/elm-tangram/tangram/token"
 )
 
@@ -13,9 +15,24 @@ func parseModule(p *parser) *ast.ModuleDecl {
 	prevRegion := p.startRegion()
 
 	stepOut := p.indentedBlock()
+	var isEffect bool
+	switch {
+	case p.is(token.Effect):
+		isEffect = true
+		decl.Effect = p.expect(token.Effect)
+	case p.is(token.Port):
+		decl.PortModule = true
+		decl.Port = p.expect(token.Port)
+	}
+
 	decl.Module = p.expect(token.Module)
 	decl.Name = parseModuleName(p)
 
+	if isEffect {
+		decl.Where = p.expect(token.Where)
+		decl.Manager = parseEffectManager(p)
+	}
+
 	if p.is(token.Exposing) {
 		p.expect(token.Exposing)
 		decl.Exposing = parseExposedList(p, false)
@@ -26,6 +43,42 @@ func parseModule(p *parser) *ast.ModuleDecl {
 	return decl
 }
 
+const errorMsgInvalidManagerField = `I found an invalid field %q in the effect module declaration. I was expecting one of the following:
+
+- command
+- subscription`
+
+// parseEffectManager parses the types managed by an effect module, such as
+// `{ command = MyCmd, subscription = MySub }`.
+func parseEffectManager(p *parser) []*ast.FieldAssign {
+	var fields []*ast.FieldAssign
+	p.expect(token.LeftBrace)
+	for !p.is(token.RightBrace) && !p.is(token.EOF) {
+		name := parseLowerName(p)
+		if name.Name != "command" && name.Name != "subscription" {
+			p.errorMessage(name.Pos(), errorMsgInvalidManagerField, name.Name)
+		}
+
+		eq := p.expect(token.Assign)
+		fields = append(fields, &ast.FieldAssign{
+			Eq:    eq,
+			Field: name,
+			Expr:  parseUpperName(p),
+		})
+
+		if !p.is(token.RightBrace) {
+			p.expect(token.Comma)
+		}
+	}
+
+	if len(fields) == 0 {
+		p.errorMessage(p.tok.Offset, "An effect module must manage commands, subscriptions or both.")
+	}
+
+	p.expect(token.RightBrace)
+	return fields
+}
+
 func parseImports(p *parser) []*ast.ImportDecl {
 	var imports []*ast.ImportDecl
 	for p.tok.Type == token.Import {
@@ -72,7 +125,7 @@ func parseModuleName(p *parser) ast.Expr {
 		return path[0]
 	}
 
-	return ast.NewSelectorExpr(path...)
+	return p.arena.NewSelectorExpr(path...)
 }
 
 func parseExposedList(p *parser, parsingUnion bool) ast.ExposedList {
@@ -142,8 +195,11 @@ func parseDecl(p *parser) ast.Decl {
 	case token.Infixl, token.Infixr, token.Infix:
 		decl = parseInfixDecl(p)
 
+	case token.Port:
+		decl = parsePortDecl(p)
+
 	case token.Identifier:
-		if p.tok.Value == "_" {
+		if p.tok.Value == "_" || isUpper(p.tok.Value) {
 			decl = parseDestructuringAssignment(p)
 		} else {
 			decl = parseDefinition(p)
@@ -159,9 +215,14 @@ func parseDecl(p *parser) ast.Decl {
 	case token.LeftBrace:
 		decl = parseDestructuringAssignment(p)
 
+	// none of these can start a valid destructuring assignment, but they
+	// are parsed as one to explain which patterns can be used
+	case token.LeftBracket, token.Int, token.Float, token.Char, token.String:
+		decl = parseDestructuringAssignment(p)
+
 	default:
 		p.errorExpectedOneOf(p.tok, token.TypeDef, token.Identifier)
-		panic(bailout{})
+		p.stop()
 	}
 
 	p.endRegion(prevRegion)
@@ -179,7 +240,11 @@ I am looking for one of the following things:
 - a lower case name
 - an underscore ("_")
 - a tuple pattern (e.g. "(first, second)")
-- a record pattern (e.g. "{x, y}")`
+- a record pattern (e.g. "{x, y}")
+- any of them with an alias (e.g. "((x, y) as point)")
+
+Lists, literals and constructors are not allowed, because the value may not
+match them. Use a case expression to match them instead.`
 
 func parseDestructuringAssignment(p *parser) *ast.DestructuringAssignment {
 	a := new(ast.DestructuringAssignment)
@@ -188,10 +253,10 @@ func parseDestructuringAssignment(p *parser) *ast.DestructuringAssignment {
 	_, ok := a.Pattern.(ast.ArgPattern)
 	if !ok {
 		p.errorMessage(
-			p.tok.Offset,
+			a.Pattern.Pos(),
 			errorMsgInvalidDestructuringPattern,
 		)
-		panic(bailout{})
+		p.stop()
 	}
 
 	a.Eq = p.expect(token.Assign)
@@ -202,6 +267,41 @@ func parseDestructuringAssignment(p *parser) *ast.DestructuringAssignment {
 	return a
 }
 
+// infixAssocs are the associativities of the infix declarations of Elm 0.19,
+// such as `infix left 6 (+) = add`.
+var infixAssocs = map[string]bool{"left": true, "right": true, "non": true}
+
+const errorMsgNewInfixSyntax = `This looks like the infix declaration of Elm 0.19, such as:
+
+    infix left 6 (+.) = add
+
+which only the core packages can use there. Here, the fixity of an operator is declared with infixl, infixr or infix, and the operator is defined like a function in the same module:
+
+    infixl 6 +.
+
+    (+.) : Float -> Float -> Float
+    (+.) =
+        add`
+
+const errorMsgRepeatedFixity = "The fixity of operator %s is declared more than once in this module. Keep only one of them, since the precedence and the associativity of an operator are the same in all the code."
+
+// checkFixities reports the operators whose fixity is declared more than
+// once among the declarations.
+func checkFixities(p *parser, decls []ast.Decl) {
+	var declared = make(map[string]bool)
+	for _, d := range decls {
+		fixity, ok := d.(*ast.InfixDecl)
+		if !ok || fixity.Op == nil || fixity.Op.Name == "_" {
+			continue
+		}
+
+		if declared[fixity.Op.Name] {
+			p.errorMessage(fixity.Op.Pos(), errorMsgRepeatedFixity, fixity.Op.Name)
+		}
+		declared[fixity.Op.Name] = true
+	}
+}
+
 func parseInfixDecl(p *parser) ast.Decl {
 	var assoc ast.Associativity
 	if p.is(token.Infixl) {
@@ -212,6 +312,13 @@ func parseInfixDecl(p *parser) ast.Decl {
 
 	stepOut := p.indentedBlock()
 	pos := p.expectOneOf(token.Infixl, token.Infixr, token.Infix)
+	if p.is(token.Identifier) {
+		if infixAssocs[p.tok.Value] {
+			p.errorMessage(p.tok.Offset, errorMsgNewInfixSyntax)
+			p.stop()
+		}
+	}
+
 	if !p.is(token.Int) {
 		p.errorExpected(p.tok, token.Int)
 	}
@@ -288,6 +395,36 @@ func parseConstructor(p *parser) *ast.Constructor {
 	return c
 }
 
+// parseNativeDecls parses the declarations of the values of a native module,
+// which are type annotations without definitions. Their names are not
+// operators, because they are used qualified, such as `Native.List.cons`.
+func parseNativeDecls(p *parser) []*ast.TypeAnnotation {
+	var decls []*ast.TypeAnnotation
+	for !p.is(token.EOF) {
+		indent, line := p.currentPos()
+		decl := new(ast.TypeAnnotation)
+		decl.Name = parseLowerName(p)
+		decl.Colon = p.expect(token.Colon)
+		stepOut := p.indentedBlockAt(indent, line)
+		decl.Type = p.expectType()
+		stepOut()
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+func parsePortDecl(p *parser) ast.Decl {
+	decl := new(ast.PortDecl)
+	indent, line := p.currentPos()
+	decl.Port = p.expect(token.Port)
+	decl.Name = parseLowerName(p)
+	decl.Colon = p.expect(token.Colon)
+	stepOut := p.indentedBlockAt(indent, line)
+	decl.Type = p.expectType()
+	stepOut()
+	return decl
+}
+
 func parseDefinition(p *parser) ast.Decl {
 	decl := new(ast.Definition)
 
@@ -328,8 +465,73 @@ func parseDefinition(p *parser) ast.Decl {
 
 	decl.Args = parseFuncArgs(p, token.Assign)
 	decl.Eq = p.expect(token.Assign)
+	// only the top-level definitions, which are in the first column, are
+	// lazy, and not the ones of let expressions
+	if p.mode.Is(LazyBodies) && indent == 1 && p.tok.Column != 1 && !p.is(token.EOF) {
+		decl.Body = parseLazyBody(p, indent, line)
+		return decl
+	}
+
 	stepOut := p.indentedBlockAt(indent, line)
 	decl.Body = parseExpr(p)
 	stepOut()
 	return decl
 }
+
+// parseLazyBody skips the body of a top-level definition, which ends before
+// the next token in the first column, and returns a lazy expression that
+// parses it with the same tokens when it is needed. Its problems are found
+// then, and not reported with the ones of the module.
+func parseLazyBody(p *parser, indent, line int) *ast.LazyExpr {
+	var (
+		first                      = p.tok
+		last                       = p.tok
+		currentIndent, currentLine = p.currentPos()
+		fileName                   = p.fileName
+		modName                    = p.modName
+		mode                       = p.mode &^ LazyBodies
+		sess                       = p.sess
+		scanner                    = p.scanner
+		fixities                   = p.fixities
+	)
+
+	silent := p.silent
+	p.silent = true
+	for !p.is(token.EOF) && p.tok.Column != 1 {
+		last = p.tok
+		p.next()
+	}
+	p.silent = silent
+
+	stop := last.Offset + token.Pos(len(last.Value))
+	return ast.NewLazyExpr(first.Offset, stop, func(lazy *ast.LazyExpr) (expr ast.Expr, err error) {
+		cm := source.NewCodeMap(sess.CodeMap.Loader())
+		defer cm.Close()
+		if err := cm.Add(fileName); err != nil {
+			return nil, err
+		}
+
+		diagnostics := newDiagnosticEmitter(false)
+		reporter := report.NewReporter(cm, diagnostics)
+		lp := newParser(NewSession(reporter, cm, sess.opTable))
+		lp.init(fileName, scanner.From(first), mode)
+		lp.modName = modName
+		lp.fixities = fixities
+		lp.currentIndent, lp.currentLine = currentIndent, currentLine
+		err = lp.run(func() {
+			stepOut := lp.indentedBlockAt(indent, line)
+			expr = parseExpr(lp)
+			stepOut()
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if expr != nil && lazy.Scope != nil && !reporter.HasErrors() {
+			r := &resolver{reporter: reporter, path: fileName}
+			r.resolveExpr(lazy.Scope, expr)
+			r.checkUnresolvedChildren([]*ast.NodeScope{lazy.Scope})
+		}
+		return expr, diagnostics.result(reporter.Emit())
+	})
+}
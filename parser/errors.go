@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/elm-tangram/tangram/report"
+)
+
+// DiagnosticError is the error returned when there are problems in the
+// modules and no emitter was given to emit them, such as the one of Parse,
+// ParseFrom or Graph. It has the diagnostics of every file, so they can be
+// inspected with errors.As:
+//
+//	var derr *parser.DiagnosticError
+//	if errors.As(err, &derr) {
+//		for _, file := range derr.Files {
+//			for _, d := range derr.Diagnostics[file] {
+//				fmt.Println(file, d.Pos.Line, d.Message)
+//			}
+//		}
+//	}
+//
+// It is also ErrProblems for errors.Is.
+type DiagnosticError struct {
+	// Files are the paths of the files with diagnostics, sorted.
+	Files []string
+	// Diagnostics are the diagnostics of every file, in the order they were
+	// reported.
+	Diagnostics map[string][]*report.Diagnostic
+}
+
+// Error returns the diagnostics of all the files, as they are written to a
+// terminal without colors.
+func (e *DiagnosticError) Error() string {
+	var buf bytes.Buffer
+	for _, file := range e.Files {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+
+		fmt.Fprintf(&buf, "problems found at file: %s\n\n", file)
+		if err := report.Writer(&buf, true, false).Emit(file, e.Diagnostics[file]); err != nil {
+			buf.WriteString(err.Error())
+		}
+	}
+	return buf.String()
+}
+
+// Is reports whether the target is ErrProblems.
+func (e *DiagnosticError) Is(target error) bool {
+	return target == ErrProblems
+}
+
+// Errors returns the number of diagnostics that are not warnings.
+func (e *DiagnosticError) Errors() int {
+	var n int
+	for _, ds := range e.Diagnostics {
+		for _, d := range ds {
+			if d.Type != report.Warning {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Warnings returns the number of diagnostics that are warnings.
+func (e *DiagnosticError) Warnings() int {
+	var n int
+	for _, ds := range e.Diagnostics {
+		n += len(ds)
+	}
+	return n - e.Errors()
+}
+
+// diagnosticEmitter is an emitter that collects the diagnostics in a
+// DiagnosticError. The warnings are only collected if warnings is true.
+type diagnosticEmitter struct {
+	warnings bool
+	err      DiagnosticError
+}
+
+func newDiagnosticEmitter(warnings bool) *diagnosticEmitter {
+	return &diagnosticEmitter{
+		warnings: warnings,
+		err:      DiagnosticError{Diagnostics: make(map[string][]*report.Diagnostic)},
+	}
+}
+
+func (e *diagnosticEmitter) Emit(file string, diagnostics []*report.Diagnostic) error {
+	for _, d := range diagnostics {
+		if d.Type == report.Warning && !e.warnings {
+			continue
+		}
+
+		if _, ok := e.err.Diagnostics[file]; !ok {
+			e.err.Files = append(e.err.Files, file)
+			sort.Strings(e.err.Files)
+		}
+		e.err.Diagnostics[file] = append(e.err.Diagnostics[file], d)
+	}
+	return nil
+}
+
+// result returns the error of emitting the diagnostics, or the
+// DiagnosticError with them if there is any.
+func (e *diagnosticEmitter) result(err error) error {
+	if err != nil || len(e.err.Files) == 0 {
+		return err
+	}
+	return &e.err
+}
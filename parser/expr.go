@@ -31,8 +31,12 @@ func parseTerm(p *parser) ast.Expr {
 			}
 		}
 
-		p.errorMessage(p.tok.Offset, fmt.Sprintf("I ran into an unexpected operator %s. I was expecting an expression.", op.Name))
-		panic(bailout{})
+		if op.Name == "-" {
+			p.errorMessage(op.Pos(), errorMsgSpacedNegation)
+		} else {
+			p.errorMessage(p.tok.Offset, fmt.Sprintf("I ran into an unexpected operator %s. I was expecting an expression.", op.Name))
+		}
+		p.stop()
 	case token.Identifier:
 		return parseIdentTerm(p)
 	}
@@ -48,7 +52,7 @@ func parseUpperQualifiedIdentifier(p *parser) ast.Expr {
 	}
 
 	if len(path) > 1 {
-		return ast.NewSelectorExpr(path...)
+		return p.arena.NewSelectorExpr(path...)
 	}
 
 	return path[0]
@@ -83,7 +87,7 @@ func parseQualifiedIdentifier(p *parser) ast.Expr {
 			return path[0]
 		}
 
-		return ast.NewSelectorExpr(path...)
+		return p.arena.NewSelectorExpr(path...)
 	}
 	return parseLowerName(p)
 }
@@ -98,7 +102,7 @@ func parseIdentifier(p *parser) *ast.Ident {
 		p.expect(token.Identifier)
 	}
 
-	return ast.NewIdent(name, pos.Offset)
+	return p.arena.NewIdent(name, pos.Offset)
 }
 
 func parseLambda(p *parser) *ast.Lambda {
@@ -165,9 +169,14 @@ func parseRecordFields(p *parser) (fields []*ast.FieldAssign) {
 func parseLeftParen(p *parser) ast.Expr {
 	lparenPos := p.expect(token.LeftParen)
 	// if next token is an op, we're looking at an operator
-	// used as a function, e.g. `(++) a b`
-	if p.is(token.Op) {
+	// used as a function, e.g. `(++) a b`, unless it is a negation,
+	// e.g. `(-x)`
+	if p.is(token.Op) && !isNegation(p) {
 		op := parseOp(p)
+		if !p.is(token.RightParen) && !p.is(token.EOF) {
+			p.errorMessage(op.Pos(), errorMsgRightSection, op.Name, op.Name)
+			p.stop()
+		}
 		p.expect(token.RightParen)
 		return op
 	}
@@ -209,6 +218,31 @@ func parseLeftParen(p *parser) ast.Expr {
 	}
 }
 
+const (
+	errorMsgRightSection   = "Elm has no operator sections, such as `(%s x)`. Use a function instead, such as `\\y -> y %s x`."
+	errorMsgLeftSection    = "Elm has no operator sections, such as `(x %s)`. Give the operator its first argument as a function instead, such as `(%s) x`."
+	errorMsgSpacedNegation = "I found a minus sign followed by whitespace where I was expecting an expression. A negative number or a negation must have the minus sign right before it, such as `-1` or `-x`."
+)
+
+// isNegativeArg reports whether the current token is a minus sign that
+// negates the term right after it and has whitespace before it, such as the
+// one of `f -1`, which makes the negation an argument instead of a
+// subtraction, like `f - 1` or `f-1` are.
+func isNegativeArg(p *parser) bool {
+	return isNegation(p) && p.tok.Offset > p.prevEnd
+}
+
+// isNegation reports whether the current token is a minus sign right before
+// a term, without any whitespace in between.
+func isNegation(p *parser) bool {
+	if p.tok.Value != "-" {
+		return false
+	}
+
+	next := p.peek()
+	return next.Offset == p.tok.Offset+1 && next.Type != token.RightParen
+}
+
 func parseLeftBracket(p *parser) ast.Expr {
 	lbracketPos := p.expect(token.LeftBracket)
 	expr := parseExpr(p)
@@ -256,7 +290,7 @@ func parseIdentTerm(p *parser) ast.Expr {
 		return path[0]
 	}
 
-	return ast.NewSelectorExpr(path...)
+	return p.arena.NewSelectorExpr(path...)
 }
 
 func parseExprList(p *parser, first ast.Expr) []ast.Expr {
@@ -281,7 +315,7 @@ func parseExpr(p *parser) ast.Expr {
 		return parseLet(p)
 	case token.EOF:
 		p.errorMessage(p.tok.Offset, "Unexpected EOF")
-		panic(bailout{})
+		p.stop()
 	}
 
 	term := parseTerm(p)
@@ -302,41 +336,52 @@ func atExprFinalizer(p *parser) bool {
 	return !p.isCorrectlyIndented()
 }
 
-const errorMsgMultipleNonAssocOps = `Binary operators %s and %s are non associative and have the same precedence. Consider using parenthesis to disambiguate.`
+const (
+	errorMsgMultipleNonAssocOps = `Binary operators %s and %s are non associative and have the same precedence. Consider using parenthesis to disambiguate.`
+	errorMsgConflictingAssocOps = `Binary operators %s and %s have the same precedence, but different associativity, so they cannot be mixed. Consider using parenthesis to disambiguate.`
+)
 
+// parseBinaryOp parses the binary expression whose first term is lhs with
+// the operators of the given precedence or higher, climbing to the
+// operators of higher precedence on the right. The operands are whole
+// function applications, so `a - f b + c` is `(a - (f b)) + c`.
 func parseBinaryOp(p *parser, lhs ast.Expr, precedence uint) ast.Expr {
-	lhs = tryFlattenApp(lhs)
-	if atExprFinalizer(p) {
-		return lhs
-	}
-
-	if !p.is(token.Op) {
-		return parseBinaryOp(p, &ast.FuncApp{
-			Func: lhs,
-			Args: []ast.Expr{
-				parseTerm(p),
-			},
-		}, 0)
-	}
+	lhs = parseApp(p, lhs)
+	for isBinaryOp(p) {
+		opInfo := p.opInfo(p.tok.Value)
+		if opInfo.Precedence < precedence {
+			break
+		}
 
-	opInfo := p.opInfo(p.tok.Value)
-	for p.tok.Type == token.Op &&
-		opInfo.Precedence >= precedence {
 		op := parseOp(p)
-		rhs := parseTerm(p)
-		prevOp := opInfo
-		opInfo = p.opInfo(p.tok.Value)
-
-		for p.tok.Type == token.Op &&
-			(opInfo.Precedence > prevOp.Precedence ||
-				(opInfo.Associativity == ast.Right &&
-					opInfo.Precedence == prevOp.Precedence)) {
-			rhs = parseBinaryOp(p, rhs, opInfo.Precedence)
-			opInfo = p.opInfo(p.tok.Value)
+		if p.is(token.RightParen) {
+			p.errorMessage(op.Pos(), errorMsgLeftSection, op.Name, op.Name)
+			p.stop()
 		}
 
-		if p.tok.Type != token.Op {
-			rhs = parseBinaryOp(p, rhs, 0)
+		rhs := parseApp(p, parseTerm(p))
+		// an operator of the same precedence that associates in the
+		// other direction, such as |> after <|, could take either rhs or
+		// the whole expression as its first operand
+		if isBinaryOp(p) {
+			next := p.opInfo(p.tok.Value)
+			if next.Precedence == opInfo.Precedence && next.Associativity != opInfo.Associativity {
+				p.errorMessage(p.tok.Offset, fmt.Sprintf(
+					errorMsgConflictingAssocOps,
+					p.tok.Value,
+					op.Name,
+				))
+				p.stop()
+			}
+		}
+
+		for isBinaryOp(p) {
+			next := p.opInfo(p.tok.Value)
+			if next.Precedence < opInfo.Precedence ||
+				next.Precedence == opInfo.Precedence && next.Associativity != ast.Right {
+				break
+			}
+			rhs = parseBinaryOp(p, rhs, next.Precedence)
 		}
 
 		lhs = &ast.BinaryOp{
@@ -345,20 +390,38 @@ func parseBinaryOp(p *parser, lhs ast.Expr, precedence uint) ast.Expr {
 			Rhs: rhs,
 		}
 
-		if opInfo.Associativity == ast.NonAssoc &&
-			opInfo.Precedence == prevOp.Precedence {
-			p.errorMessage(p.tok.Offset, fmt.Sprintf(
-				errorMsgMultipleNonAssocOps,
-				p.tok.Value,
-				op.Name,
-			))
-			panic(bailout{})
+		if isBinaryOp(p) {
+			next := p.opInfo(p.tok.Value)
+			if next.Associativity == ast.NonAssoc && next.Precedence == opInfo.Precedence {
+				p.errorMessage(p.tok.Offset, fmt.Sprintf(
+					errorMsgMultipleNonAssocOps,
+					p.tok.Value,
+					op.Name,
+				))
+				p.stop()
+			}
 		}
 	}
 
 	return lhs
 }
 
+// isBinaryOp reports whether the current token is the operator of a binary
+// expression, which is not a negative argument nor after the end of the
+// expression.
+func isBinaryOp(p *parser) bool {
+	return p.is(token.Op) && !isNegativeArg(p) && !atExprFinalizer(p)
+}
+
+// parseApp parses the arguments of the application of the given term, if
+// it has any, until the next operator or the end of the expression.
+func parseApp(p *parser, fn ast.Expr) ast.Expr {
+	for !atExprFinalizer(p) && (!p.is(token.Op) || isNegativeArg(p)) {
+		fn = p.arena.NewFuncApp(fn, parseTerm(p))
+	}
+	return tryFlattenApp(fn)
+}
+
 func tryFlattenApp(expr ast.Expr) ast.Expr {
 	if app, ok := expr.(*ast.FuncApp); ok {
 		return flattenApp(app)
@@ -380,15 +443,10 @@ func parseLet(p *parser) *ast.LetExpr {
 	indent, line := p.currentPos()
 	e := &ast.LetExpr{Let: p.expect(token.Let)}
 	stepOut := p.indentedBlockAt(indent, line)
-	for p.is(token.Identifier) || p.is(token.LeftParen) || p.is(token.LeftBrace) {
-		switch p.tok.Type {
-		case token.Identifier:
-			if p.tok.Value != "_" {
-				e.Decls = append(e.Decls, parseDefinition(p))
-			} else {
-				e.Decls = append(e.Decls, parseDestructuringAssignment(p))
-			}
-		case token.LeftParen, token.LeftBrace:
+	for isLetDeclStart(p.tok) {
+		if p.is(token.Identifier) && p.tok.Value != "_" && !isUpper(p.tok.Value) {
+			e.Decls = append(e.Decls, parseDefinition(p))
+		} else {
 			e.Decls = append(e.Decls, parseDestructuringAssignment(p))
 		}
 	}
@@ -402,6 +460,18 @@ func parseLet(p *parser) *ast.LetExpr {
 	return e
 }
 
+// isLetDeclStart reports whether the token starts a declaration of a let
+// expression. Lists, literals and upper case names are not valid patterns
+// for its destructuring assignments, but they start one to report it.
+func isLetDeclStart(tok *token.Token) bool {
+	switch tok.Type {
+	case token.Identifier, token.LeftParen, token.LeftBrace, token.LeftBracket,
+		token.Int, token.Float, token.Char, token.String:
+		return true
+	}
+	return false
+}
+
 func parseIf(p *parser) *ast.IfExpr {
 	var expr = new(ast.IfExpr)
 
@@ -492,9 +562,5 @@ func parseLiteral(p *parser) *ast.BasicLit {
 
 	t := p.tok
 	p.next()
-	return &ast.BasicLit{
-		Type:     typ,
-		Position: t.Offset,
-		Value:    t.Value,
-	}
+	return p.arena.NewBasicLit(typ, t.Offset, t.Value)
 }
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// missingImport is a name that could not be resolved in a module because
+// the module that exposes it may not be imported.
+type missingImport struct {
+	mod   *ast.Module
+	fixes *report.FixList
+	// module is the name the name is qualified with, which is empty if it
+	// is not qualified.
+	module string
+	name   string
+	// kind is the kind of the object of a qualified name. The unqualified
+	// names can be either types or constructors if they are uppercase.
+	kind ast.ObjKind
+}
+
+// fixMissingImports adds to the reports of the missing imports the fix
+// that imports the module exposing the name, if there is only one module
+// of the program that does.
+func (r *resolver) fixMissingImports() {
+	for _, m := range r.missing {
+		if imported, obj := r.missingImport(m); imported != nil {
+			if fix, ok := importFix(m.mod, imported, obj); ok {
+				m.fixes.AddFix(fix)
+			}
+		}
+	}
+	r.missing = nil
+}
+
+// missingImport returns the module that makes the name visible in its
+// module once it is imported, or nil if there is no module exposing it or
+// there are many. The object is the one of the name if it is not
+// qualified, so the module has to expose it, and nil otherwise.
+func (r *resolver) missingImport(m missingImport) (*ast.Module, *ast.Object) {
+	if m.module != "" {
+		mod := r.pkg.Modules[m.module]
+		if mod == nil || mod == m.mod || mod.Scope == nil {
+			return nil, nil
+		}
+
+		if mod.Scope.LookupExposed(m.name, m.kind) == nil {
+			return nil, nil
+		}
+		return mod, nil
+	}
+
+	var imported *ast.Module
+	var exposed *ast.Object
+	for _, name := range r.pkg.Order {
+		mod := r.pkg.Modules[name]
+		if mod == m.mod || mod.Scope == nil {
+			continue
+		}
+
+		obj := mod.Scope.Exposed[m.name]
+		if obj == nil || isUpper(m.name) != (obj.Kind != ast.Var) {
+			continue
+		}
+
+		if imported != nil {
+			return nil, nil
+		}
+		imported, exposed = mod, obj
+	}
+	return imported, exposed
+}
+
+// exposedIdent returns the name of the object in an exposing list. The
+// constructors are exposed with their type.
+func exposedIdent(mod *ast.Module, obj *ast.Object) string {
+	if union := ctorUnion(mod, obj); union != nil {
+		return fmt.Sprintf("%s(%s)", union.Name.Name, obj.Name)
+	}
+
+	if !scanner.IsIdentifier(obj.Name) {
+		return "(" + obj.Name + ")"
+	}
+	return obj.Name
+}
+
+// ctorUnion returns the union type of the module the object is a
+// constructor of, or nil if it is not a constructor.
+func ctorUnion(mod *ast.Module, obj *ast.Object) *ast.UnionDecl {
+	if obj.Kind != ast.Ctor {
+		return nil
+	}
+
+	for _, decl := range mod.Decls {
+		if union, ok := decl.(*ast.UnionDecl); ok && union.LookupCtor(obj.Name) != nil {
+			return union
+		}
+	}
+	return nil
+}
+
+// importFix returns the fix that makes the object of the imported module
+// visible in the module, or the imported module itself if the object is
+// nil. If the module already imports it, the object is added to the
+// exposing list of the import. Otherwise, the import declaration is added
+// after the last import of the module, or after its module declaration if
+// it has no imports. The default imports have no position, so they are not
+// taken into account.
+func importFix(mod *ast.Module, imported *ast.Module, obj *ast.Object) (report.Fix, bool) {
+	for _, i := range mod.Imports {
+		if i.Pos() != token.NoPos && i.ModuleName() == imported.Name {
+			// there is nothing to add to an import for the qualified
+			// names of its module
+			if obj == nil {
+				return report.Fix{}, false
+			}
+			return exposeFix(i, imported, obj)
+		}
+	}
+
+	imp := "import " + imported.Name
+	if obj != nil {
+		imp = fmt.Sprintf("import %s exposing (%s)", imported.Name, exposedIdent(imported, obj))
+	}
+
+	fix := report.Fix{Message: fmt.Sprintf("Add %q", imp)}
+	for _, i := range mod.Imports {
+		if i.Pos() == token.NoPos {
+			continue
+		}
+
+		fix.Pos = i.End()
+		if i.Exposing != nil {
+			fix.Pos = i.Exposing.End() + 1
+		}
+		fix.Text = "\n" + imp
+	}
+
+	if fix.Text == "" {
+		if mod.Module == nil || mod.Module.Exposing == nil {
+			return fix, false
+		}
+		fix.Pos = mod.Module.End() + 1
+		fix.Text = "\n\n" + imp
+	}
+
+	fix.End = fix.Pos
+	return fix, true
+}
+
+// exposeFix returns the fix that adds the object of the imported module to
+// the exposing list of its import, or false if the import already exposes
+// it.
+func exposeFix(imp *ast.ImportDecl, imported *ast.Module, obj *ast.Object) (report.Fix, bool) {
+	ident := exposedIdent(imported, obj)
+	fix := report.Fix{Message: fmt.Sprintf("Expose %q in the import of %s", ident, imported.Name)}
+	switch list := imp.Exposing.(type) {
+	case nil:
+		fix.Pos = imp.End()
+		fix.Text = " exposing (" + ident + ")"
+	case *ast.OpenList:
+		return fix, false
+	case *ast.ClosedList:
+		fix.Pos = list.Rparen
+		fix.Text = ", " + ident
+		union := ctorUnion(imported, obj)
+		for _, e := range list.Exposed {
+			switch e := e.(type) {
+			case *ast.ExposedVar:
+				if e.Name == obj.Name {
+					return fix, false
+				}
+
+				// the type is exposed without its constructors
+				if union != nil && e.Name == union.Name.Name {
+					fix.Pos = e.End()
+					fix.Text = "(" + obj.Name + ")"
+				}
+			case *ast.ExposedUnion:
+				if union == nil || e.Type.Name != union.Name.Name {
+					continue
+				}
+
+				ctors, ok := e.Ctors.(*ast.ClosedList)
+				if !ok {
+					return fix, false
+				}
+
+				for _, c := range ctors.Exposed {
+					if v, ok := c.(*ast.ExposedVar); ok && v.Name == obj.Name {
+						return fix, false
+					}
+				}
+				fix.Pos = ctors.Rparen
+				fix.Text = ", " + obj.Name
+			}
+		}
+	}
+
+	fix.End = fix.Pos
+	return fix, true
+}
@@ -139,6 +139,15 @@ func Definition(
 	}
 }
 
+func Port(name string, typeAssert TypeAssert) DeclAssert {
+	return func(t *testing.T, decl ast.Decl) {
+		port, ok := decl.(*ast.PortDecl)
+		require.True(t, ok, "expected declaration to be a PortDecl, is %T", decl)
+		assertIdent(t, name, port.Name)
+		typeAssert(t, port.Type)
+	}
+}
+
 func Destructuring(pattern PatternAssert, expr ExprAssert) DeclAssert {
 	return func(t *testing.T, decl ast.Decl) {
 		d, ok := decl.(*ast.DestructuringAssignment)
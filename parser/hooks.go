@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"io"
+	"time"
+
+	"github.com/elm-tangram/tangram/profile"
+	"github.com/elm-tangram/tangram/report"
+)
+
+// Hooks are the functions called as a package is parsed, which the programs
+// that embed the compiler, such as build servers or web playgrounds, use to
+// collect their own metrics and enforce their own limits. Any of them can be
+// nil. They are set on the Session of the parse with WithHooks.
+type Hooks struct {
+	// OnFileLoaded is called when the file of a module is loaded, with its
+	// path and its size in bytes. If it returns an error, the file is
+	// reported as not loaded with it and the parse stops.
+	OnFileLoaded func(path string, size int64) error
+	// OnModuleParsed is called when a module has been completely parsed,
+	// with its name and the path of its file. If it returns an error, it is
+	// reported in the module and the parse stops.
+	OnModuleParsed func(module, path string) error
+	// OnDiagnostic is called with every diagnostic before it is emitted,
+	// with the path of its file.
+	OnDiagnostic func(path string, d *report.Diagnostic)
+	// OnPhaseEnd is called when the load, scan, parse or resolve phase of
+	// a module ends, with the time it took.
+	OnPhaseEnd func(phase profile.Phase, module string, duration time.Duration)
+}
+
+// fileLoaded calls OnFileLoaded with the given source, if it is set.
+func (h *Hooks) fileLoaded(path string, src io.Seeker) error {
+	if h == nil || h.OnFileLoaded == nil {
+		return nil
+	}
+
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return h.OnFileLoaded(path, size)
+}
+
+// moduleParsed calls OnModuleParsed, if it is set.
+func (h *Hooks) moduleParsed(module, path string) error {
+	if h == nil || h.OnModuleParsed == nil {
+		return nil
+	}
+	return h.OnModuleParsed(module, path)
+}
+
+// emitter returns an emitter that calls OnDiagnostic before emitting the
+// diagnostics with the given one, or the given one if it is not set.
+func (h *Hooks) emitter(emitter report.Emitter) report.Emitter {
+	if h == nil || h.OnDiagnostic == nil {
+		return emitter
+	}
+	return &hookEmitter{h.OnDiagnostic, emitter}
+}
+
+type hookEmitter struct {
+	onDiagnostic func(string, *report.Diagnostic)
+	emitter      report.Emitter
+}
+
+func (e *hookEmitter) Emit(file string, diagnostics []*report.Diagnostic) error {
+	for _, d := range diagnostics {
+		e.onDiagnostic(file, d)
+	}
+	return e.emitter.Emit(file, diagnostics)
+}
+
+// measure is a measure of a phase both in the profile and for OnPhaseEnd.
+// The time is only taken if OnPhaseEnd is set.
+type measure struct {
+	m     profile.Measure
+	hooks *Hooks
+	start time.Time
+}
+
+// startMeasure starts a measure in the profile and for the hooks, either of
+// which can be nil.
+func startMeasure(prof *profile.Profile, hooks *Hooks) measure {
+	m := measure{m: prof.Start(), hooks: hooks}
+	if hooks != nil && hooks.OnPhaseEnd != nil {
+		m.start = time.Now()
+	}
+	return m
+}
+
+// Stop stops the measure and adds it to the given phase of the module.
+func (m measure) Stop(phase profile.Phase, module string) {
+	m.End().Add(phase, module)
+}
+
+// End stops the measure without adding it yet, for the phases whose module
+// is not known until later.
+func (m measure) End() sample {
+	s := sample{s: m.m.End(), hooks: m.hooks}
+	if !m.start.IsZero() {
+		s.duration = time.Since(m.start)
+	}
+	return s
+}
+
+// sample is a measure that has been stopped and has not been added yet.
+type sample struct {
+	s        profile.Sample
+	hooks    *Hooks
+	duration time.Duration
+}
+
+// Add adds the sample to the given phase of the module, and calls
+// OnPhaseEnd with it.
+func (s sample) Add(phase profile.Phase, module string) {
+	s.s.Add(phase, module)
+	if s.hooks != nil && s.hooks.OnPhaseEnd != nil {
+		s.hooks.OnPhaseEnd(phase, module, s.duration)
+	}
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// operators returns the operators available in every module of the package
+// by the name of the module and their name, from the snapshots of their
+// fixities. An operator is the same value in every module it is available
+// in, with the doc comment of its definition.
+func (p *fullParser) operators(pkg *ast.Package) map[string]map[string]*ast.Operator {
+	var ops = make(map[*operatorInfo]*ast.Operator)
+	var result = make(map[string]map[string]*ast.Operator, len(pkg.Order))
+	for _, m := range pkg.Order {
+		f := p.fixities[p.optable.names.Intern(m)]
+		if f == nil {
+			continue
+		}
+
+		var available = make(map[string]*ast.Operator)
+		for _, bucket := range f.buckets {
+			for _, fixity := range bucket {
+				op, ok := ops[fixity.info]
+				if !ok {
+					op = &ast.Operator{
+						Name:       fixity.name,
+						Module:     fixity.info.Module,
+						Assoc:      fixity.info.Associativity,
+						Precedence: fixity.info.Precedence,
+						Decl:       fixity.info.Decl,
+						Doc:        operatorDoc(pkg.Modules[fixity.info.Module], fixity.name),
+					}
+					ops[fixity.info] = op
+				}
+				available[fixity.name] = op
+			}
+		}
+
+		if len(available) > 0 {
+			result[m] = available
+		}
+	}
+	return result
+}
+
+// operatorDoc returns the text of the doc comment of the definition of the
+// operator in the module, without its delimiters, or an empty string if it
+// has none. The comments are attached like in the documentation: the first
+// doc comment before the imports and the declarations is the one of the
+// module, and the one of a declaration is the last one before it.
+func operatorDoc(mod *ast.Module, name string) string {
+	if mod == nil || mod.Module == nil {
+		return ""
+	}
+
+	var docs []*ast.Comment
+	for _, c := range mod.Comments {
+		if strings.HasPrefix(c.Text, "{-|") {
+			docs = append(docs, c)
+		}
+	}
+
+	var prevEnd = mod.Module.End()
+	var first = token.Pos(-1)
+	if len(mod.Imports) > 0 {
+		first = mod.Imports[0].Pos()
+	} else if len(mod.Decls) > 0 {
+		first = mod.Decls[0].Pos()
+	}
+
+	if len(docs) > 0 && docs[0].Pos() >= prevEnd && (first < 0 || docs[0].End() <= first) {
+		prevEnd = docs[0].End()
+	}
+
+	for _, d := range mod.Decls {
+		if def, ok := d.(*ast.Definition); ok && def.Name.Name == name {
+			var doc string
+			for _, c := range docs {
+				if c.Pos() >= prevEnd && c.End() <= d.Pos() {
+					doc = strings.TrimSuffix(strings.TrimPrefix(c.Text, "{-|"), "-}")
+				}
+			}
+			return doc
+		}
+		prevEnd = d.End()
+	}
+	return ""
+}
@@ -2,23 +2,63 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
 )
 
 // opTable is the implementation of an operator table. It contains the operators
-// and their info.
+// and their info. It is safe to read it from several goroutines while the
+// modules are written: the operators of every module are kept apart, with
+// their own lock, and only the parse of the module itself writes them, so
+// the parses of different modules never wait for each other.
 type opTable struct {
-	ops         map[operator]*operatorInfo
-	opsByModule map[string]map[string]string
+	// modules are the *moduleOps of every module by its interned name,
+	// which are added once and read many times.
+	modules sync.Map
+	// names interns the names of the modules of the parse the table is
+	// used by.
+	names *token.Interner
+}
+
+// moduleOps are the operators of a module.
+type moduleOps struct {
+	mu sync.RWMutex
+	// defined are the operators defined in the module by their name.
+	defined map[string]*operatorInfo
+	// available are the modules of the operators available in the module,
+	// imported or defined, by their name.
+	available map[string]token.Name
 }
 
 // newOpTable creates a new empty operator table.
 func newOpTable() *opTable {
-	return &opTable{
-		ops:         make(map[operator]*operatorInfo),
-		opsByModule: make(map[string]map[string]string),
+	return &opTable{names: token.NewInterner()}
+}
+
+// module returns the operators of the module with the given name, which are
+// created if they are not in the table yet.
+func (t *opTable) module(name token.Name) *moduleOps {
+	if m, ok := t.modules.Load(name); ok {
+		return m.(*moduleOps)
+	}
+
+	m, _ := t.modules.LoadOrStore(name, &moduleOps{
+		defined:   make(map[string]*operatorInfo),
+		available: make(map[string]token.Name),
+	})
+	return m.(*moduleOps)
+}
+
+// lookupModule returns the operators of the module with the given name, or
+// nil if it has none.
+func (t *opTable) lookupModule(name token.Name) *moduleOps {
+	if m, ok := t.modules.Load(name); ok {
+		return m.(*moduleOps)
 	}
+	return nil
 }
 
 // builtinOpTable creates a new operator table with all the builtin operators
@@ -64,46 +104,144 @@ var builtinOps = []struct {
 // add inserts the given operator and its data in the operator table. It
 // returns an error if the operator is a builtin or has already been defined.
 func (t *opTable) add(name, path string, assoc ast.Associativity, precedence uint) error {
-	if _, ok := t.ops[operator{name, path}]; ok {
+	return t.define(name, &operatorInfo{Associativity: assoc, Precedence: precedence, Module: path})
+}
+
+// declare inserts the operator of the fixity declaration of the module with
+// the given name in the operator table, like add.
+func (t *opTable) declare(module string, decl *ast.InfixDecl) error {
+	n, _ := strconv.Atoi(decl.Precedence.Value)
+	return t.define(decl.Op.Name, &operatorInfo{
+		Associativity: decl.Assoc,
+		Precedence:    uint(n),
+		Module:        module,
+		Decl:          decl,
+	})
+}
+
+// define inserts the operator with the given name and info in the table.
+func (t *opTable) define(name string, info *operatorInfo) error {
+	m := t.module(t.names.Intern(info.Module))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.defined[name]; ok {
 		return fmt.Errorf("operator %s is already defined somewhere else", name)
 	}
 
-	t.ops[operator{name, path}] = &operatorInfo{assoc, precedence}
+	m.defined[name] = info
 	return nil
 }
 
 // addToModule adds an operator as available in the given `module`.
 func (t *opTable) addToModule(module, opModule, opName string) {
-	if _, ok := t.opsByModule[module]; !ok {
-		t.opsByModule[module] = make(map[string]string)
+	m := t.module(t.names.Intern(module))
+	m.mu.Lock()
+	m.available[opName] = t.names.Intern(opModule)
+	m.mu.Unlock()
+}
+
+// definedIn returns the names of the operators defined in the module with
+// the given name.
+func (t *opTable) definedIn(module string) []string {
+	m := t.lookupModule(t.names.Intern(module))
+	if m == nil {
+		return nil
 	}
 
-	t.opsByModule[module][opName] = opModule
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names = make([]string, 0, len(m.defined))
+	for name := range m.defined {
+		names = append(names, name)
+	}
+	return names
 }
 
 // find finds a specific operator and returns its info. Will return nil if
 // the operator does not exist.
-func (t *opTable) find(name, path string) *operatorInfo {
-	return t.ops[operator{name, path}]
+func (t *opTable) find(name string, path token.Name) *operatorInfo {
+	m := t.lookupModule(path)
+	if m == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.defined[name]
 }
 
 // lookup finds an operator that is available (imported or defined) in the current module.
-func (t *opTable) lookup(name string, currentModule string) *operatorInfo {
-	if ops, ok := t.opsByModule[currentModule]; ok {
-		if mod, ok := ops[name]; ok {
-			return t.find(name, mod)
-		}
+func (t *opTable) lookup(name string, currentModule token.Name) *operatorInfo {
+	m := t.lookupModule(currentModule)
+	if m == nil {
+		return nil
 	}
 
-	return nil
+	m.mu.RLock()
+	mod, ok := m.available[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return t.find(name, mod)
 }
 
-// operator represents a qualified operator with the module where it was defined.
-type operator struct {
-	// Name of the operator.
-	Name string
-	// Module of the operator.
-	Module string
+// fixities is a snapshot of the operators available in a module, which is
+// made once the fixities of all the modules are known. Profiles of large
+// packages showed that looking up the operators of every binary expression
+// in the table, which interns their names, takes a good part of parsing, so
+// the snapshot is a flat array of the operators by their first byte, whose
+// buckets have very few of them, and it needs no maps nor locks.
+type fixities struct {
+	module  token.Name
+	buckets [256][]fixity
+}
+
+// fixity is an operator in a snapshot of fixities.
+type fixity struct {
+	name string
+	info *operatorInfo
+}
+
+// snapshot returns the snapshot of the fixities of the operators available
+// in the given module.
+func (t *opTable) snapshot(module token.Name) *fixities {
+	f := &fixities{module: module}
+	m := t.lookupModule(module)
+	if m == nil {
+		return f
+	}
+
+	// the module of the operators may be the same, so they are found once
+	// its lock is released
+	m.mu.RLock()
+	available := make(map[string]token.Name, len(m.available))
+	for op, mod := range m.available {
+		available[op] = mod
+	}
+	m.mu.RUnlock()
+
+	for op, mod := range available {
+		if info := t.find(op, mod); info != nil && op != "" {
+			f.buckets[op[0]] = append(f.buckets[op[0]], fixity{op, info})
+		}
+	}
+	return f
+}
+
+// lookup finds the operator with the given name, or returns nil if it is not
+// available in the module.
+func (f *fixities) lookup(name string) *operatorInfo {
+	if name == "" {
+		return nil
+	}
+
+	for _, op := range f.buckets[name[0]] {
+		if op.name == name {
+			return op.info
+		}
+	}
+	return nil
 }
 
 // operatorInfo contains the info about an operator.
@@ -112,4 +250,9 @@ type operatorInfo struct {
 	Associativity ast.Associativity
 	// Precedence of the operator.
 	Precedence uint
+	// Module is the name of the module that defines the operator.
+	Module string
+	// Decl is the fixity declaration of the operator, which is nil for the
+	// builtin operators of the modules parsed on their own.
+	Decl *ast.InfixDecl
 }
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/token"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,4 +15,51 @@ func TestAdd(t *testing.T) {
 	table := newOpTable()
 	s.NoError(table.add("?", "foo", ast.Left, 0))
 	s.Error(table.add("?", "foo", ast.Left, 0))
+
+	decl := &ast.InfixDecl{Assoc: ast.Right, Op: ast.NewIdent("<|>", token.NoPos), Precedence: &ast.BasicLit{Value: "3"}}
+	s.NoError(table.declare("Bar", decl))
+	s.Error(table.declare("Bar", decl))
+	s.Equal(&operatorInfo{ast.Right, 3, "Bar", decl}, table.find("<|>", table.names.Intern("Bar")))
+}
+
+func TestSnapshot(t *testing.T) {
+	s := require.New(t)
+	table := builtinOpTable()
+	s.NoError(table.add(":>", "Foo", ast.NonAssoc, 5))
+	table.addToModule("Test", "Foo", ":>")
+	for _, op := range []string{"+", "++", "|>"} {
+		table.addToModule("Test", "Basics", op)
+	}
+
+	mod := table.names.Intern("Test")
+	snapshot := table.snapshot(mod)
+	for _, op := range []string{":>", "+", "++", "|>", "-", "::", ""} {
+		s.Equal(table.lookup(op, mod), snapshot.lookup(op), op)
+	}
+	s.Equal(&operatorInfo{ast.NonAssoc, 5, "Foo", nil}, snapshot.lookup(":>"))
+	s.Nil(snapshot.lookup("-"))
+	s.Nil(table.snapshot(table.names.Intern("Other")).lookup("+"))
+}
+
+func TestOpTableConcurrent(t *testing.T) {
+	s := require.New(t)
+	table := builtinOpTable()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			module := fmt.Sprintf("Module%d", i)
+			s.NoError(table.add(":>", module, ast.Left, uint(i)))
+			table.addToModule(module, module, ":>")
+			table.addToModule(module, "Basics", "+")
+			for j := 0; j < 100; j++ {
+				table.lookup("+", table.names.Intern(fmt.Sprintf("Module%d", j%8)))
+			}
+			s.Equal(&operatorInfo{ast.Left, uint(i), module, nil}, table.lookup(":>", table.names.Intern(module)))
+			s.Equal(&operatorInfo{ast.Left, 6, "Basics", nil}, table.snapshot(table.names.Intern(module)).lookup("+"))
+		}(i)
+	}
+	wg.Wait()
 }
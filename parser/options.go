@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/profile"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+)
+
+// Option configures how Parse parses a package. A ParseMode is an Option
+// too, which adds its flags to the mode of the parse, so the options that
+// carry values are given with the flags:
+//
+//	parser.Parse(path, parser.FullParse|parser.ParseComments, parser.WithEmitter(e))
+type Option interface {
+	apply(*options)
+}
+
+// options are the configuration of a parse.
+type options struct {
+	mode    ParseMode
+	emitter report.Emitter
+	profile *profile.Profile
+	pkg     *pkg.Package
+	loader  source.Loader
+	overlay source.Overlay
+	cache   *source.Cache
+	limits  *source.Limits
+	hooks   *Hooks
+	target  target.Target
+	// ctx and maxErrors interrupt the parse.
+	ctx       context.Context
+	maxErrors int
+}
+
+func (pm ParseMode) apply(o *options) {
+	o.mode |= pm
+}
+
+// optionFunc is an Option that is a function.
+type optionFunc func(*options)
+
+func (fn optionFunc) apply(o *options) {
+	fn(o)
+}
+
+// WithEmitter makes the diagnostics be emitted with the given emitter no
+// matter the mode. Parse returns ErrProblems if any of them is an error.
+func WithEmitter(emitter report.Emitter) Option {
+	return optionFunc(func(o *options) {
+		o.emitter = emitter
+	})
+}
+
+// WithProfile measures the load, scan, parse and resolve phases of every
+// module in prof.
+func WithProfile(prof *profile.Profile) Option {
+	return optionFunc(func(o *options) {
+		o.profile = prof
+	})
+}
+
+// WithPackage makes the modules be found in the given package instead of
+// the one loaded from the directory of the file, and be parsed as modules
+// of it, which is how the packages that are only in memory, such as the
+// ones of pkg.NewMemory, are parsed.
+func WithPackage(p *pkg.Package) Option {
+	return optionFunc(func(o *options) {
+		o.pkg = p
+	})
+}
+
+// WithLoader makes the files be read with the given loader instead of the
+// file system. The synthetic modules of the package are still read with its
+// providers.
+func WithLoader(loader source.Loader) Option {
+	return optionFunc(func(o *options) {
+		o.loader = loader
+	})
+}
+
+// WithOverlay makes the files in the overlay be read from it, which is how
+// editors check the files that are not saved yet. The files must still be
+// found as modules.
+func WithOverlay(overlay source.Overlay) Option {
+	return optionFunc(func(o *options) {
+		o.overlay = overlay
+	})
+}
+
+// WithCache makes the sources of the files be taken from the cache, which is
+// shared by the repeated parses of a package, such as the ones of the
+// language server or of the watch mode. A file that did not change is not
+// scanned again, and neither is the header of its module parsed again.
+func WithCache(cache *source.Cache) Option {
+	return optionFunc(func(o *options) {
+		o.cache = cache
+	})
+}
+
+// WithContext makes the parse stop when the context is done, before the
+// next module is loaded or parsed. Parse returns the error of the context
+// then, with a package that is Partial.
+func WithContext(ctx context.Context) Option {
+	return optionFunc(func(o *options) {
+		o.ctx = ctx
+	})
+}
+
+// WithMaxErrors makes the parse stop once there are the given number of
+// errors, before the next module is loaded or parsed, and report that it
+// stopped. Parse returns a package that is Partial then, with the error of
+// the diagnostics.
+func WithMaxErrors(n int) Option {
+	return optionFunc(func(o *options) {
+		o.maxErrors = n
+	})
+}
+
+// WithLimits makes the files be only read while they are within the given
+// limits, so a package that is too large is a diagnostic instead of running
+// out of memory.
+func WithLimits(limits source.Limits) Option {
+	return optionFunc(func(o *options) {
+		o.limits = &limits
+	})
+}
+
+// WithHooks sets the hooks on the session of the parse, which are called as
+// the package is parsed.
+func WithHooks(hooks *Hooks) Option {
+	return optionFunc(func(o *options) {
+		o.hooks = hooks
+	})
+}
+
+// WithTarget parses the package for the given target, so the declarations
+// guarded by target pragmas for other targets are left out. The target is
+// js by default.
+func WithTarget(t target.Target) Option {
+	return optionFunc(func(o *options) {
+		o.target = t
+	})
+}
+
+// newOptions returns the configuration of the given options, in order.
+func newOptions(opts []Option) *options {
+	var o = options{target: target.JS}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+	return &o
+}
+
+// load returns the package of the file at the given path, and the loader of
+// its files.
+func (o *options) load(path string) (*pkg.Package, source.Loader, error) {
+	p := o.pkg
+	if p == nil {
+		var err error
+		if p, err = pkg.Load(filepath.Dir(path)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var loader = o.loader
+	if loader == nil {
+		loader = source.NewFsLoader(p)
+	} else {
+		// the synthetic modules of the package are not in the given loader
+		loader = source.NewPackageLoader(loader, p)
+	}
+	if o.limits != nil {
+		loader = source.NewLimitLoader(loader, *o.limits)
+	}
+	if o.overlay != nil {
+		loader = source.NewOverlayLoader(loader, o.overlay)
+	}
+	if o.cache != nil {
+		loader = o.cache.Loader(loader)
+	}
+	return p, loader, nil
+}
@@ -2,15 +2,18 @@ package parser
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/target"
 	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/profile"
 	"github.com/elm-tangram/tangram/report"
 	"github.com/elm-tangram/tangram/scanner"
 	"github.com/elm-tangram/tangram/source"
@@ -36,8 +39,25 @@ const (
 	StderrDiagnostics
 	// SkipWarnings will skip the warning diagnostics.
 	SkipWarnings
+	// ParseComments will keep the comments of the modules in the AST,
+	// which is needed to print the modules back.
+	ParseComments
+	// ArenaAlloc will allocate the most common nodes of every module in
+	// chunks of an ast.Arena, which puts less pressure on the garbage
+	// collector when large packages are parsed.
+	ArenaAlloc
+	// LazyBodies will skip the bodies of the top-level definitions, which
+	// are ast.LazyExpr nodes that are parsed and resolved when they are
+	// walked or ast.ParseLazy is called. The type checker parses them, so
+	// only the tools that just need the signatures of the definitions,
+	// such as the documentation, save time with it.
+	LazyBodies
 )
 
+// moduleModes are the flags of the mode used to parse every module of a
+// package.
+const moduleModes = ParseComments | ArenaAlloc | LazyBodies
+
 // Is reports whether the given flag is present in the current parse mode.
 func (pm ParseMode) Is(flag ParseMode) bool {
 	return pm&flag > 0
@@ -48,6 +68,15 @@ type Session struct {
 	*report.Reporter
 	*source.CodeMap
 	*opTable
+	// Hooks are the functions called as the package is parsed, if it is not
+	// nil.
+	Hooks *Hooks
+	// Target is the target the package is parsed for. The top-level
+	// declarations guarded by a target pragma, such as `{- elmo:target js -}`,
+	// are left out if it is not one of their targets, so a module can have a
+	// definition for every backend. The declarations of every target are
+	// kept if it is empty.
+	Target target.Target
 }
 
 // NewSession creates a new parsing session with a way of diagnosing errors
@@ -56,7 +85,7 @@ func NewSession(
 	r *report.Reporter,
 	cm *source.CodeMap,
 	ops *opTable) *Session {
-	return &Session{r, cm, ops}
+	return &Session{Reporter: r, CodeMap: cm, opTable: ops}
 }
 
 // ParseResult is the result after a full parse, which is a set of parsed files
@@ -71,24 +100,111 @@ type ParseResult struct {
 	Modules map[string]*ast.Module
 }
 
+// ErrProblems is returned by ParseWith and GraphWith when there are errors in
+// the modules, which have been emitted already.
+var ErrProblems = errors.New("parser: there are problems in the modules")
+
 // Parse will parse the file at the given path and all its imported modules
-// with the given mode of parsing.
-func Parse(path string, mode ParseMode) (result *ast.Package, err error) {
-	pkg, err := pkg.Load(filepath.Dir(path))
+// with the given options, which are usually just the mode of parsing. Unless
+// they have an emitter, the diagnostics are returned as a *DiagnosticError,
+// or written to stderr with the StderrDiagnostics mode.
+//
+// If the parse is interrupted, as with WithContext and WithMaxErrors, the
+// package of the modules parsed until then is returned with the error,
+// instead of nil, and it is Partial.
+func Parse(path string, opts ...Option) (*ast.Package, error) {
+	o := newOptions(opts)
+	var diagnostics *diagnosticEmitter
+	emitter := o.emitter
+	if emitter == nil && o.mode.Is(StderrDiagnostics) {
+		emitter = report.Stderr(!o.mode.Is(SkipWarnings), true)
+	} else if emitter == nil {
+		diagnostics = newDiagnosticEmitter(!o.mode.Is(SkipWarnings))
+		emitter = diagnostics
+	}
+
+	p, loader, err := o.load(path)
 	if err != nil {
 		return nil, err
 	}
 
-	cm := source.NewCodeMap(source.NewFsLoader(pkg))
-	defer cm.Close()
+	result, ok, err := parsePackage(p, loader, path, o.mode, emitter, o.profile, o.hooks, o.target, o.pkg != nil, o.ctx, o.maxErrors)
+	if diagnostics != nil {
+		return result, diagnostics.result(err)
+	} else if o.emitter == nil {
+		return result, err
+	}
+	return problems(result, ok, err)
+}
 
-	var emitter report.Emitter
-	if mode.Is(StderrDiagnostics) {
-		emitter = report.Stderr(!mode.Is(SkipWarnings), true)
-	} else {
-		emitter = report.Errors(!mode.Is(SkipWarnings))
+// ParseWith is like Parse, but the diagnostics are emitted with the given
+// emitter no matter the mode. ErrProblems is returned if any of them is an
+// error. The load, scan, parse and resolve phases of every module are
+// measured in prof, if it is not nil. It is the same as Parse with
+// WithEmitter and WithProfile.
+func ParseWith(path string, mode ParseMode, emitter report.Emitter, prof *profile.Profile) (*ast.Package, error) {
+	return Parse(path, mode, WithEmitter(emitter), WithProfile(prof))
+}
+
+// ParseOverlay is like ParseWith, but the source code of the files in the
+// overlay is read from it instead of the file system, which is how editors
+// check the files that are not saved yet. The files must still exist in the
+// file system to be found as modules. It is the same as Parse with
+// WithEmitter and WithOverlay.
+func ParseOverlay(path string, mode ParseMode, emitter report.Emitter, overlay source.Overlay) (*ast.Package, error) {
+	return Parse(path, mode, WithEmitter(emitter), WithOverlay(overlay))
+}
+
+// ParseCached is like ParseWith with the files of the overlay, if it is not
+// nil, but the sources of the files are taken from the cache, as with
+// WithCache.
+func ParseCached(path string, mode ParseMode, emitter report.Emitter, prof *profile.Profile, overlay source.Overlay, cache *source.Cache) (*ast.Package, error) {
+	return Parse(path, mode, WithEmitter(emitter), WithProfile(prof), WithOverlay(overlay), WithCache(cache))
+}
+
+// ParseIn is like ParseWith, but the modules are found in the given package
+// and read with the given loader instead of the file system, as with
+// WithPackage and WithLoader.
+func ParseIn(p *pkg.Package, loader source.Loader, path string, mode ParseMode, emitter report.Emitter) (*ast.Package, error) {
+	return Parse(path, mode, WithEmitter(emitter), WithPackage(p), WithLoader(loader))
+}
+
+// ParseLimited is like ParseWith, but the files are only read while they are
+// within the given limits, as with WithLimits. It is meant for the programs
+// that parse any code they are given, such as playgrounds or CI bots.
+func ParseLimited(path string, mode ParseMode, emitter report.Emitter, limits source.Limits) (*ast.Package, error) {
+	return Parse(path, mode, WithEmitter(emitter), WithLimits(limits))
+}
+
+// problems returns the result of a parse, or ErrProblems if it had errors.
+// A partial result is returned with the error.
+func problems(result *ast.Package, ok bool, err error) (*ast.Package, error) {
+	if result != nil && result.Partial {
+		if err == nil && !ok {
+			err = ErrProblems
+		}
+		return result, err
 	}
 
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrProblems
+	}
+	return result, nil
+}
+
+// parsePackage parses the file at the given path and all its imported
+// modules, which are found in the package and read with the loader. If
+// inPackage is true, they are all parsed as modules of the package. The
+// parse is interrupted when ctx is done, if it is not nil, or once there
+// are maxErrors errors, if it is not zero, and the result is partial then.
+func parsePackage(pkg *pkg.Package, loader source.Loader, path string, mode ParseMode, emitter report.Emitter, prof *profile.Profile, hooks *Hooks, t target.Target, inPackage bool, ctx context.Context, maxErrors int) (result *ast.Package, ok bool, err error) {
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+
 	var optable *opTable
 	if mode.Is(JustModule) {
 		optable = builtinOpTable()
@@ -96,22 +212,87 @@ func Parse(path string, mode ParseMode) (result *ast.Package, err error) {
 		optable = newOpTable()
 	}
 
-	reporter := report.NewReporter(cm, emitter)
+	reporter := report.NewReporter(cm, hooks.emitter(emitter))
 	sess := NewSession(reporter, cm, optable)
+	sess.Hooks = hooks
+	sess.Target = t
 
 	p := newParser(sess)
-	defer catchBailout()
-	if !mode.Is(StderrDiagnostics) {
-		defer func() {
-			err = sess.Emit()
-		}()
-	} else {
-		defer sess.Emit()
+	if inPackage {
+		p.pkg = pkg
 	}
-
 	fp := newFullParser(p, pkg, optable, cm, reporter)
-	result = fp.parse(path)
-	return
+	fp.modes = mode & moduleModes
+	fp.profile = prof
+	fp.resolver.profile = prof
+	fp.resolver.hooks = hooks
+	fp.ctx, fp.maxErrors = ctx, maxErrors
+	if err := p.run(func() { result = fp.parse(path) }); err != nil {
+		fp.resolver.locate(err, cm)
+		return nil, false, err
+	}
+
+	if fp.interrupted != nil {
+		if err := p.run(func() { result = fp.partial() }); err != nil {
+			fp.resolver.locate(err, cm)
+			return nil, false, err
+		}
+	}
+
+	ok = !reporter.HasErrors()
+	if err = sess.Emit(); err == nil && fp.interrupted != errTooManyErrors {
+		err = fp.interrupted
+	}
+	return result, ok, err
+}
+
+// Graph parses the imports of the module at the given path and of all the
+// modules it imports, and returns their dependency graph. Unlike Parse, the
+// graph is not resolved, so there is no error if there are circular
+// dependencies between the modules. The errors in the modules are returned
+// as a *DiagnosticError.
+func Graph(path string) (*pkg.Graph, error) {
+	diagnostics := newDiagnosticEmitter(false)
+	g, _, err := graph(path, diagnostics)
+	return g, diagnostics.result(err)
+}
+
+// GraphWith is like Graph, but the diagnostics are emitted with the given
+// emitter. ErrProblems is returned if any of them is an error.
+func GraphWith(path string, emitter report.Emitter) (*pkg.Graph, error) {
+	g, ok, err := graph(path, emitter)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrProblems
+	}
+	return g, nil
+}
+
+func graph(path string, emitter report.Emitter) (g *pkg.Graph, ok bool, err error) {
+	pkg, err := pkg.Load(filepath.Dir(path))
+	if err != nil {
+		return nil, false, err
+	}
+
+	cm := source.NewCodeMap(source.NewFsLoader(pkg))
+	defer cm.Close()
+
+	optable := newOpTable()
+	reporter := report.NewReporter(cm, emitter)
+	sess := NewSession(reporter, cm, optable)
+
+	fp := newFullParser(newParser(sess), pkg, optable, cm, reporter)
+	if err := fp.p.run(func() { fp.firstPass(path, make(map[string]struct{})) }); err != nil {
+		return nil, false, err
+	}
+
+	if reporter.HasErrors() {
+		return nil, false, sess.Emit()
+	}
+	return fp.g, true, sess.Emit()
 }
 
 type fullParser struct {
@@ -122,9 +303,33 @@ type fullParser struct {
 	g        *pkg.Graph
 	reporter *report.Reporter
 	resolver *resolver
-	modCache map[string]string
+	// modCache are the paths of the modules, by their interned names.
+	modCache map[token.Name]string
+	// modes are the flags of the mode that apply to every module, such as
+	// ParseComments.
+	modes ParseMode
+	// profile measures the phases of every module, if it is not nil.
+	profile *profile.Profile
+	// fixities are the snapshots of the fixities of every module.
+	fixities map[token.Name]*fixities
+	// natives are the native modules imported by the modules.
+	natives map[string]*ast.NativeModule
+	// ctx interrupts the parse when it is done, if it is not nil, and
+	// maxErrors once there are that many errors, if it is not zero.
+	ctx       context.Context
+	maxErrors int
+	// interrupted is why the parse was interrupted, if it was: the error of
+	// ctx or errTooManyErrors.
+	interrupted error
+	// parsed are the modules completely parsed so far, in order.
+	parsed []*ast.Module
+	// exposing are the exposing lists of the modules, by their name.
+	exposing map[string]ast.ExposedList
 }
 
+// errTooManyErrors is why a parse with too many errors is interrupted.
+var errTooManyErrors = errors.New("parser: too many errors")
+
 func newFullParser(p *parser, pkg *pkg.Package, optable *opTable, cm *source.CodeMap, r *report.Reporter) *fullParser {
 	return &fullParser{
 		p,
@@ -134,7 +339,16 @@ func newFullParser(p *parser, pkg *pkg.Package, optable *opTable, cm *source.Cod
 		nil,
 		r,
 		&resolver{reporter: r},
-		make(map[string]string),
+		make(map[token.Name]string),
+		0,
+		nil,
+		nil,
+		make(map[string]*ast.NativeModule),
+		nil,
+		0,
+		nil,
+		nil,
+		make(map[string]ast.ExposedList),
 	}
 }
 
@@ -142,13 +356,15 @@ func (p *fullParser) parse(path string) *ast.Package {
 	// do a first parse to gather all the imports and operator fixities
 	p.firstPass(path, make(map[string]struct{}))
 
+	// the modules that could not be found cannot be resolved
+	if p.reporter.HasErrors() {
+		p.p.stop()
+	}
+
 	modules, err := p.g.Resolve()
 	switch err := err.(type) {
 	case *pkg.CircularDependencyError:
-		p.error(
-			path,
-			fmt.Sprintf("I found a circular dependency in your code between these modules:\n- %s\n- %s", err.Modules[0], err.Modules[1]),
-		)
+		p.error(path, "%s", p.cycleMessage(err.Cycle))
 	case nil:
 	default:
 		p.error(
@@ -157,10 +373,20 @@ func (p *fullParser) parse(path string) *ast.Package {
 		)
 	}
 
-	r := &ast.Package{Order: modules, Modules: make(map[string]*ast.Module)}
+	// the fixities of every module are known once all the modules have been
+	// found, so the binary expressions are parsed with a snapshot of them
+	p.fixities = make(map[token.Name]*fixities, len(modules))
 	for _, m := range modules {
+		name := p.optable.names.Intern(m)
+		p.fixities[name] = p.optable.snapshot(name)
+	}
+
+	r := &ast.Package{Order: modules, Modules: make(map[string]*ast.Module), Natives: p.natives}
+	for _, m := range modules {
+		p.checkInterrupted(p.modCache[p.optable.names.Intern(m)])
 		if file := p.completeParse(m); file != nil {
 			r.Modules[m] = file
+			p.parsed = append(p.parsed, file)
 		}
 	}
 
@@ -168,49 +394,162 @@ func (p *fullParser) parse(path string) *ast.Package {
 		return nil
 	}
 
+	r.Operators = p.operators(r)
 	return r
 }
 
+// checkInterrupted interrupts the parse if ctx is done or there are
+// maxErrors errors, before the module at the given path is loaded or
+// parsed. Reaching the cap of errors is reported in that module.
+func (p *fullParser) checkInterrupted(path string) {
+	if p.ctx != nil && p.ctx.Err() != nil {
+		p.interrupted = p.ctx.Err()
+		p.p.stop()
+	}
+
+	if p.maxErrors > 0 && p.reporter.Errors() >= p.maxErrors {
+		p.interrupted = errTooManyErrors
+		p.error(path, "I stopped because there are %d errors already, so this module and the ones after it were not parsed.", p.maxErrors)
+		p.p.stop()
+	}
+}
+
+// partial returns the resolved package of the modules parsed without errors
+// before the parse was interrupted, whose imports are in it too.
+func (p *fullParser) partial() *ast.Package {
+	r := &ast.Package{Modules: make(map[string]*ast.Module), Natives: p.natives, Partial: true}
+	for _, file := range p.parsed {
+		if hasErrors(p.reporter.Reports(file.Path)) {
+			continue
+		}
+
+		var imported = true
+		for _, imp := range file.Imports {
+			_, native := p.natives[imp.ModuleName()]
+			if _, ok := r.Modules[imp.ModuleName()]; !ok && !native {
+				imported = false
+			}
+		}
+
+		if imported {
+			r.Order = append(r.Order, file.Name)
+			r.Modules[file.Name] = file
+		}
+	}
+
+	p.resolver.resolve(r)
+	r.Operators = p.operators(r)
+	return r
+}
+
+// hasErrors reports whether any of the reports is not a warning.
+func hasErrors(reports []report.Report) bool {
+	for _, r := range reports {
+		if r.Type() != report.Warning {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *fullParser) firstPass(path string, visited map[string]struct{}) {
+	p.checkInterrupted(path)
+	m := p.measure()
 	if err := p.cm.Add(path); err != nil {
-		p.error(path, "Oops, unexpected error reading file: %s", err)
-		panic(bailout{})
+		if lerr, ok := err.(*source.LimitError); ok {
+			p.error(path, "I could not load this module because %s.", lerr)
+		} else {
+			p.error(path, "Oops, unexpected error reading file: %s", err)
+		}
+		p.p.stop()
 	}
 	source := p.cm.Source(path)
-	scanner := source.Scanner()
+	if err := p.p.sess.Hooks.fileLoaded(path, source.Src); err != nil {
+		p.error(path, "I could not load this module because %s.", err)
+		p.p.stop()
+	}
+	load := m.End()
 
-	p.p.init(source.Path, scanner, SkipDefinitions)
-	file := parseFile(p.p)
+	m = p.measure()
+	scanner := source.Scanner()
+	scan := m.End()
+
+	// the header of a module that did not change since the last parse is
+	// taken from the cache, if it had no problems
+	m = p.measure()
+	var file *ast.Module
+	if header, ok := source.Header().(*ast.Module); ok {
+		f := *header
+		file = &f
+	} else {
+		p.p.init(source.Path, scanner, SkipDefinitions)
+		file = parseFile(p.p)
+		if len(p.reporter.Reports(path)) == 0 {
+			header := *file
+			source.SetHeader(&header)
+		}
+	}
 
+	// the name of the module is only known once it is parsed
 	mod := file.Module.ModuleName()
+	p.profile.Event(profile.ModuleEvent, mod, map[string]interface{}{"path": path})
+	m.Stop(profile.Parse, mod)
+	load.Add(profile.Load, mod)
+	scan.Add(profile.Scan, mod)
 	// TODO: check module name corresponds to the path
 	visited[mod] = struct{}{}
+	p.exposing[mod] = file.Module.Exposing
 	if p.g == nil {
 		p.g = pkg.NewGraph(mod)
+		// the root module does not need to be in the source directories
+		p.modCache[p.optable.names.Intern(mod)] = path
+		// a tagged root module is built with the modules with its tag,
+		// such as an example that imports the other ones
+		if tag := p.pkg.ModuleTag(mod); tag != "" {
+			p.pkg = p.pkg.WithTags(tag)
+		}
 	}
 
 	if p.p.mode.Is(JustModule) {
 		return
 	}
 
+	// open are the modules imported exposing all their names, whose
+	// operators are only known once they have been parsed
+	var open []string
 	for _, imp := range file.Imports {
 		importMod := imp.ModuleName()
+		importName := p.optable.names.Intern(importMod)
 
-		importPath, ok := p.modCache[importMod]
+		importPath, ok := p.modCache[importName]
 		if !ok {
 			var err error
 			importPath, err = p.pkg.FindModule(importMod)
-			if err != nil {
-				p.error(
-					path,
-					fmt.Sprintf("I could not find module %q in any of the package source directories or any of its dependencies. Maybe you're missing a dependency?", importMod),
-				)
+			switch err := err.(type) {
+			case nil:
+			case *pkg.ModuleCaseError:
+				p.p.sess.Report(report.NewModuleCaseError(path, imp.Module, importMod, err.Path, err.File))
+				continue
+			case *pkg.CaseConflictError:
+				p.p.sess.Report(report.NewCaseConflictError(path, imp.Module, importMod, err.Paths))
+				continue
+			case *pkg.ExcludedModuleError:
+				p.p.sess.Report(report.NewExcludedModuleError(path, imp.Module, importMod, err.Tag))
+				continue
+			default:
+				// the suggestions are left out if the modules cannot be listed
+				modules, _ := p.pkg.Modules()
+				p.p.sess.Report(report.NewModuleNotFoundError(
+					path, imp.Module, importMod, report.SuggestModule(importMod, modules)...,
+				))
 				continue
 			}
-			p.modCache[importMod] = importPath
+			p.modCache[importName] = importPath
 		}
 
-		if imp.Exposing != nil {
+		if _, ok := imp.Exposing.(*ast.OpenList); ok {
+			open = append(open, importMod)
+		} else if imp.Exposing != nil {
 			ast.WalkFunc(imp.Exposing, func(n ast.Node) bool {
 				if v, ok := n.(*ast.ExposedVar); ok && v.IsOp() {
 					p.optable.addToModule(mod, importMod, v.Name)
@@ -219,8 +558,11 @@ func (p *fullParser) firstPass(path string, visited map[string]struct{}) {
 			})
 		}
 
-		if isNative(importPath) {
+		if pkg.IsNative(importPath) {
 			file.NativeImports = append(file.NativeImports, importPath)
+			if _, ok := p.natives[importMod]; !ok {
+				p.natives[importMod] = p.parseNative(importMod, importPath)
+			}
 		} else {
 			p.g.Add(importMod, mod)
 
@@ -230,41 +572,100 @@ func (p *fullParser) firstPass(path string, visited map[string]struct{}) {
 		}
 	}
 
+	for _, importMod := range open {
+		for _, op := range p.optable.definedIn(importMod) {
+			if exposesOp(p.exposing[importMod], op) {
+				p.optable.addToModule(mod, importMod, op)
+			}
+		}
+	}
+
 	for _, d := range file.Decls {
+		// the operators are available in the module that defines them too
 		if fixity, ok := d.(*ast.InfixDecl); ok {
-			n, _ := strconv.Atoi(fixity.Precedence.Value)
-			p.optable.add(fixity.Op.Name, mod, fixity.Assoc, uint(n))
+			p.optable.declare(mod, fixity)
+			p.optable.addToModule(mod, mod, fixity.Op.Name)
+		}
+	}
+}
+
+// exposesOp reports whether the exposing list of a module has the operator
+// with the given name.
+func exposesOp(exposing ast.ExposedList, op string) bool {
+	switch exposing := exposing.(type) {
+	case *ast.OpenList:
+		return true
+	case *ast.ClosedList:
+		for _, e := range exposing.Exposed {
+			if v, ok := e.(*ast.ExposedVar); ok && v.Name == op {
+				return true
+			}
 		}
 	}
+	return false
 }
 
-func isNative(path string) bool {
-	return strings.HasSuffix(path, ".go")
+// parseNative parses the declarations of the native module with the given
+// name and Go file, if it has a file of declarations.
+func (p *fullParser) parseNative(name, path string) *ast.NativeModule {
+	native := &ast.NativeModule{Name: name, Path: path}
+	declPath := pkg.NativeDecls(path)
+	if err := p.cm.Add(declPath); os.IsNotExist(err) {
+		return native
+	} else if err != nil {
+		p.error(declPath, "Oops, unexpected error reading file: %s", err)
+		return native
+	}
+
+	native.DeclPath = declPath
+	p.p.init(declPath, p.cm.Source(declPath).Scanner(), FullParse)
+	p.p.modName = p.optable.names.Intern(name)
+	native.Decls = parseNativeDecls(p.p)
+	return native
 }
 
 func (p *fullParser) completeParse(module string) *ast.Module {
-	path, err := p.pkg.FindModule(module)
-	if err != nil {
-		// TODO: fix this, but should be unreachable
-		panic(err)
+	path, ok := p.modCache[p.optable.names.Intern(module)]
+	if !ok {
+		var err error
+		if path, err = p.pkg.FindModule(module); err != nil {
+			// TODO: fix this, but should be unreachable
+			panic(err)
+		}
 	}
 
+	var mode = FullParse | p.modes
+
+	m := p.measure()
 	source := p.cm.Source(path)
-	p.p.init(path, source.Scanner(), FullParse)
-	return parseFile(p.p)
+	p.p.init(path, source.Scanner(), mode)
+	p.p.fixities = p.fixities[p.optable.names.Intern(module)]
+	file := parseFile(p.p)
+	m.Stop(profile.Parse, module)
+
+	if err := p.p.sess.Hooks.moduleParsed(module, path); err != nil {
+		p.error(path, "I could not parse this module because %s.", err)
+		p.p.stop()
+	}
+	return file
+}
+
+// measure starts a measure of a phase for the profile and the hooks.
+func (p *fullParser) measure() measure {
+	return startMeasure(p.profile, p.p.sess.Hooks)
 }
 
 func (p *fullParser) error(path, msg string, args ...interface{}) {
 	msg = fmt.Sprintf(msg, args...)
-	p.p.sess.Report(path, report.NewBaseReport(
-		report.SyntaxError, token.NoPos, msg, nil,
+	p.p.sess.Report(report.NewBaseReport(
+		report.SyntaxError, token.Location{File: path}, msg, nil,
 	))
 }
 
 // ParseFrom parses the contents of the given reader and returns the
 // corresponding AST file. It will only parse itself and not the imported
 // modules, even if it's explicitly requested in the ParseMode.
-// All parsing errors encountered will be retuned in the error return value,
+// All parsing errors encountered will be retuned as a *DiagnosticError,
 // even though StderrDiagnostics mode is present in mode.
 func ParseFrom(name string, src io.Reader, mode ParseMode) (f *ast.Module, err error) {
 	loader := source.NewMemLoader()
@@ -277,29 +678,36 @@ func ParseFrom(name string, src io.Reader, mode ParseMode) (f *ast.Module, err e
 	loader.Add(name, string(content))
 	cm := source.NewCodeMap(loader)
 	defer cm.Close()
+	if err = cm.Add(name); err != nil {
+		return nil, err
+	}
 
+	diagnostics := newDiagnosticEmitter(!mode.Is(SkipWarnings))
 	sess := NewSession(
-		report.NewReporter(cm, report.Errors(!mode.Is(SkipWarnings))),
+		report.NewReporter(cm, diagnostics),
 		cm,
 		builtinOpTable(),
 	)
 
 	p := newParser(sess)
-	s := scanner.New(name, bytes.NewBuffer(content))
+	code := content
+	if source.IsLiterate(name) {
+		code = source.Literate(content)
+	}
+
+	s := scanner.New(name, bytes.NewBuffer(code))
 	s.Run()
 	p.init(name, s, mode)
-	defer catchBailout()
-	defer func() {
-		err = sess.Emit()
-	}()
-	f = parseFile(p)
-	return
-
+	if err := p.run(func() { f = parseFile(p) }); err != nil {
+		return nil, err
+	}
+	return f, diagnostics.result(sess.Emit())
 }
 
-// catchBailout catches "bailout", which means parser has exited on purpose
-// due to errors during the parsing. If it's not a bailout the error comes from
-// somewhere else and is panicked again.
+// catchBailout catches the bailout of any parser and panics again with any
+// other value. It is kept for the code that calls the parsing functions
+// directly outside of the run of a parser, which should be used instead, as
+// it does not hide the stack trace of the real panics.
 func catchBailout() {
 	if r := recover(); r != nil {
 		if _, ok := r.(bailout); !ok {
@@ -1,12 +1,25 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/crash"
+	"github.com/elm-tangram/tangram/package"
+	"github.com/elm-tangram/tangram/profile"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
 
 	"github.com/stretchr/testify/require"
 )
@@ -257,3 +270,888 @@ func TestParseFull(t *testing.T) {
 		expected(t, f)
 	}
 }
+
+const commentsFixture = `module Foo exposing (..)
+
+{-| The answer. -}
+answer : Int
+answer =
+    -- not a guess
+    case 42 of
+        n ->
+            { value = n } -- a record
+`
+
+func TestGraph(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+	g, err := Graph(filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm"))
+	require.NoError(err)
+
+	require.Equal("Main", g.Root())
+	require.Equal(
+		[]string{
+			"Basics",
+			"List",
+			"Maybe",
+			"Result",
+			"String",
+			"Tuple",
+			"Debug",
+			"Internal.Dependency",
+			"Dependency",
+		},
+		g.Imports("Main"),
+	)
+	require.Len(g.Modules(), 10)
+	require.Empty(g.Cycles())
+
+	_, err = Graph(filepath.Join(wd, "_testdata", "unresolved", "Missing.elm"))
+	require.Error(err)
+}
+
+// parsedNodes returns the type, the position and whether it is resolved of
+// every node of the modules of the package.
+func parsedNodes(t *testing.T, pkg *ast.Package) []string {
+	var nodes []string
+	for _, name := range pkg.Order {
+		require.NoError(t, ast.ParseLazy(pkg.Modules[name]))
+		ast.WalkFunc(pkg.Modules[name], func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				nodes = append(nodes, fmt.Sprintf("%T %d %d %t", n, n.Pos(), n.End(), id.Obj != nil))
+			} else if n != nil {
+				nodes = append(nodes, fmt.Sprintf("%T %d %d", n, n.Pos(), n.End()))
+			}
+			return true
+		})
+	}
+	return nodes
+}
+
+func TestParseArena(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	expected, err := Parse(path, FullParse)
+	require.NoError(err)
+	result, err := Parse(path, FullParse|ArenaAlloc)
+	require.NoError(err)
+	require.Equal(parsedNodes(t, expected), parsedNodes(t, result))
+}
+
+func TestParseLazy(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	expected, err := Parse(path, FullParse)
+	require.NoError(err)
+	result, err := Parse(path, FullParse|LazyBodies)
+	require.NoError(err)
+
+	var lazy int
+	for _, d := range result.Modules["Main"].Decls {
+		if def, ok := d.(*ast.Definition); ok {
+			body, ok := def.Body.(*ast.LazyExpr)
+			require.True(ok, "body of %s is %T", def.Name.Name, def.Body)
+			require.False(body.Parsed())
+			lazy++
+		}
+	}
+	require.NotZero(lazy)
+	require.Equal(parsedNodes(t, expected), parsedNodes(t, result))
+
+	f, err := ParseFrom("Foo.elm", strings.NewReader("module Foo exposing (..)\n\nfoo = 1 +\n\nbar = 2\n"), LazyBodies)
+	require.NoError(err)
+	require.Len(f.Decls, 2)
+	err = ast.ParseLazy(f)
+	require.Error(err)
+	require.Contains(err.Error(), "problems found at file: Foo.elm")
+	require.Equal(&ast.BasicLit{Position: 43, Type: ast.Int, Value: "2"}, f.Decls[1].(*ast.Definition).Body)
+}
+
+func TestRun(t *testing.T) {
+	require := require.New(t)
+
+	p, other := newParser(nil), newParser(nil)
+	require.NoError(p.run(func() {}))
+	require.NoError(p.run(p.stop))
+
+	err := p.run(other.stop)
+	require.IsType(&crash.Error{}, err)
+
+	err = p.run(func() { panic("oops") })
+	require.IsType(&crash.Error{}, err)
+	require.Equal("oops", err.(*crash.Error).Value)
+	require.Equal("parse", err.(*crash.Error).Phase)
+	require.Contains(err.Error(), "internal compiler error: oops")
+	require.Contains(string(err.(*crash.Error).Stack), "TestRun")
+
+	// the error is located at the token being parsed
+	src := "module Foo exposing (..)\n\nfoo =\n    bar baz\n"
+	p = newParser(nil)
+	s := scanner.New("Foo.elm", strings.NewReader(src))
+	s.Run()
+	p.init("Foo.elm", s, FullParse)
+	p.modName = token.NewInterner().Intern("Foo")
+	loader := source.NewMemLoader()
+	loader.Add("Foo.elm", src)
+	p.sess = NewSession(nil, source.NewCodeMap(loader), nil)
+	err = p.run(func() {
+		for p.tok == nil || p.tok.Value != "baz" {
+			p.next()
+		}
+		panic("oops")
+	})
+
+	crash := err.(*crash.Error)
+	require.Equal("Foo", crash.Module)
+	require.Equal("Foo.elm", crash.Path)
+	require.Equal(4, crash.Line)
+	require.Equal(9, crash.Col)
+	require.Equal("    bar baz", crash.Snippet)
+	require.Contains(err.Error(), "4|     bar baz\n           ^\n")
+}
+
+func TestParseWith(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	result, err := ParseWith(path, FullParse, report.JSON(&buf, false), nil)
+	require.NoError(err)
+	require.Len(result.Modules, 10)
+	require.Equal("", buf.String())
+
+	path = filepath.Join(wd, "_testdata", "unresolved", "src", "Main.elm")
+	_, err = ParseWith(path, FullParse, report.JSON(&buf, false), nil)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `{"event":"diagnostic","file":"`+path+`","type":"syntax error","message":"I could not find module \"Basics\"`)
+
+	buf.Reset()
+	_, err = GraphWith(path, report.JSON(&buf, false))
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `I could not find module \"Basics\"`)
+}
+
+func TestParseOverlay(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	overlay := source.Overlay{path: "module Main exposing (..)\n\nmain = = 1\n"}
+	_, err = ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"file":"`+path+`","type":"syntax error"`)
+
+	buf.Reset()
+	overlay[path] = "module Main exposing (..)\n\nmain =\n    1\n"
+	result, err := ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.NoError(err)
+	require.Equal("", buf.String())
+	require.Len(result.Modules["Main"].Decls, 1)
+}
+
+func TestParseCached(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	cache := source.NewCache()
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	expected, err := ParseCached(path, FullParse, report.JSON(&buf, false), nil, nil, cache)
+	require.NoError(err)
+	require.Equal(10, cache.Len())
+
+	result, err := ParseCached(path, FullParse, report.JSON(&buf, false), nil, nil, cache)
+	require.NoError(err)
+	require.Equal(10, cache.Len())
+	require.Equal(parsedNodes(t, expected), parsedNodes(t, result))
+
+	overlay := source.Overlay{path: "module Main exposing (..)\n\nmain = = 1\n"}
+	_, err = ParseCached(path, FullParse, report.JSON(&buf, false), nil, overlay, cache)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"file":"`+path+`","type":"syntax error"`)
+	require.Equal(10, cache.Len())
+}
+
+func TestParseLimited(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	result, err := ParseLimited(path, FullParse, report.JSON(&buf, false), source.Limits{MaxModules: 10})
+	require.NoError(err)
+	require.Len(result.Modules, 10)
+
+	_, err = ParseLimited(path, FullParse, report.JSON(&buf, false), source.Limits{MaxModules: 9})
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `is the module number 10, but the maximum number of modules is 9`)
+
+	buf.Reset()
+	_, err = ParseLimited(path, FullParse, report.JSON(&buf, false), source.Limits{MaxFileSize: 10})
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"file":"`+path+`","type":"syntax error","message":"I could not load this module because the file `+path+` has`)
+}
+
+func TestParseOptions(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	path := filepath.Join(wd, "_testdata", "valid_fullparse", "src", "Main.elm")
+	overlay := source.Overlay{path: "module Main exposing (..)\n\nmain = = 1\n"}
+	_, err = Parse(path, FullParse, WithEmitter(report.JSON(&buf, false)), WithOverlay(overlay), WithLimits(source.Limits{MaxModules: 10}))
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"file":"`+path+`","type":"syntax error"`)
+
+	// a mode can be given as many options too
+	prof := profile.New()
+	result, err := Parse(path, FullParse, ParseComments, WithProfile(prof))
+	require.NoError(err)
+	require.Len(result.Modules, 10)
+	require.NotEmpty(prof.Entries())
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm"})
+	require.NoError(err)
+
+	buf.Reset()
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nmain =\n    1\n")
+	result, err = Parse("Main.elm", JustModule, WithPackage(p), WithLoader(loader), WithEmitter(report.JSON(&buf, false)))
+	require.NoError(err, buf.String())
+	require.Len(result.Modules["Main"].Decls, 1)
+}
+
+func TestParseInterrupted(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Good.elm", "Broken.elm", "Uses.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Broken\nimport Good\nimport Uses\n\nmain =\n    Good.good\n")
+	loader.Add("Good.elm", "module Good exposing (..)\n\ngood =\n    1\n")
+	loader.Add("Uses.elm", "module Uses exposing (..)\n\nimport Good\n\nuses =\n    Good.good\n")
+	loader.Add("Broken.elm", "module Broken exposing (..)\n\nbroken =\n    1\n")
+
+	// the modules parsed before the context is canceled are kept, but not
+	// the ones that import the modules that were not parsed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hooks := &Hooks{
+		OnModuleParsed: func(module, path string) error {
+			if module == "Uses" {
+				cancel()
+			}
+			return nil
+		},
+	}
+
+	result, err := Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithContext(ctx), WithHooks(hooks), WithMaxErrors(10))
+	require.Equal(context.Canceled, err)
+	require.True(result.Partial)
+	require.Contains(result.Order, "Uses")
+	require.NotContains(result.Order, "Main")
+	require.NotNil(result.Modules["Uses"].Scope)
+
+	// a parse canceled before it starts has no modules
+	result, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithContext(ctx))
+	require.Equal(context.Canceled, err)
+	require.True(result.Partial)
+	require.Empty(result.Modules)
+
+	// the errors in the first modules stop the parse before the rest are
+	// loaded
+	loader.Add("Broken.elm", "module Broken exposing (..)\n\nimport Missing\n")
+	var buf bytes.Buffer
+	result, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithEmitter(report.JSON(&buf, false)), WithMaxErrors(1))
+	require.Equal(ErrProblems, err)
+	require.True(result.Partial)
+	require.Contains(buf.String(), `"file":"Good.elm","type":"syntax error","message":"I stopped because there are 1 errors already`)
+
+	// the parse is not interrupted if the errors are below the cap
+	buf.Reset()
+	result, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithEmitter(report.JSON(&buf, false)), WithMaxErrors(2))
+	require.Equal(ErrProblems, err)
+	require.Nil(result)
+	require.NotContains(buf.String(), "I stopped")
+}
+
+func TestModuleNotFound(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Json/Decode.elm", "Page/Home.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Decode\nimport Page.Hom\nimport Svg\n\nmain =\n    1\n")
+	loader.Add("Json/Decode.elm", "module Json.Decode exposing (..)\n\nx =\n    1\n")
+	loader.Add("Page/Home.elm", "module Page.Home exposing (..)\n\nx =\n    1\n")
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+
+	var messages []string
+	for _, d := range derr.Diagnostics["Main.elm"] {
+		messages = append(messages, d.Message)
+	}
+	require.Equal([]string{
+		"I could not find module \"Decode\" in any of the package source directories or any of its dependencies.\n\nMaybe you want one of the following?\n\n    Json.Decode",
+		"I could not find module \"Page.Hom\" in any of the package source directories or any of its dependencies.\n\nMaybe you want one of the following?\n\n    Page.Home",
+		"I could not find module \"Svg\" in any of the package source directories or any of its dependencies. Maybe you're missing a dependency?",
+	}, messages)
+	require.Equal(3, derr.Diagnostics["Main.elm"][0].Pos.Line)
+}
+
+func TestModuleCase(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "page/Home.elm", "Utils.elm", "utils.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Page.Home\nimport Utils\n\nmain =\n    1\n")
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+
+	diagnostics := derr.Diagnostics["Main.elm"]
+	require.Len(diagnostics, 2)
+	require.Equal(3, diagnostics[0].Pos.Line)
+	require.Contains(diagnostics[0].Message, "I found the file of module \"Page.Home\", but the case of its path does not match the name of the module:\n\n    expected: Page/Home.elm\n    found:    page/Home.elm")
+	require.Contains(diagnostics[1].Message, "I found more than one file for module \"Utils\", whose paths only differ in case:\n\n    Utils.elm\n    utils.elm")
+}
+
+func TestExcludedModule(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."], "module-tags": {"test": ["Fixtures"], "example": ["Examples.*"]}}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Fixtures.elm", "Examples/Counter.elm", "Examples/View.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Fixtures\n\nmain =\n    Fixtures.user\n")
+	loader.Add("Fixtures.elm", "module Fixtures exposing (..)\n\nuser =\n    1\n")
+	loader.Add("Examples/Counter.elm", "module Examples.Counter exposing (..)\n\nimport Examples.View\n\ncounter =\n    Examples.View.view\n")
+	loader.Add("Examples/View.elm", "module Examples.View exposing (..)\n\nview =\n    1\n")
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+
+	diagnostics := derr.Diagnostics["Main.elm"]
+	require.Len(diagnostics, 1)
+	require.Equal(3, diagnostics[0].Pos.Line)
+	require.Contains(diagnostics[0].Message, "I found module \"Fixtures\", but it is tagged test in the module-tags of the manifest of its package, so it is only built with the modules tagged test, such as the tests, and not with this one.")
+
+	// an example is built with the other examples
+	_, err = Parse("Examples/Counter.elm", FullParse, WithPackage(p), WithLoader(loader))
+	require.NoError(err)
+}
+
+func TestReportLocation(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "User.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport User exposing (User(..))\n\nmain =\n    1\n")
+	loader.Add("User.elm", "module User exposing (..)\n\n\n\n\n\n\n\ntype alias User =\n    { name : String }\n")
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+	require.Len(derr.Diagnostics, 1)
+
+	// the position is the one of the import, not the one of the declaration
+	// in the other module
+	diagnostics := derr.Diagnostics["Main.elm"]
+	require.Len(diagnostics, 1)
+	require.Equal(source.LinePos{Line: 3, Col: 23}, diagnostics[0].Pos)
+	require.Contains(diagnostics[0].Message, "I was expecting \"User\" to be an union type")
+}
+
+func TestOperators(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Parser.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Parser exposing ((|=))\n\nmain =\n    1 |= 2 |= 3\n")
+	loader.Add("Parser.elm", "module Parser exposing ((|=))\n\n{-| The parsers.\n-}\n\n\n{-| Keeps both values.\n-}\n(|=) : a -> b -> b\n(|=) a b =\n    b\n\n\ninfixr 5 |=\n\n\nboth =\n    1 |= 2 |= 3\n")
+
+	result, err := Parse("Main.elm", FullParse, ParseComments, WithPackage(p), WithLoader(loader))
+	require.NoError(err)
+
+	op := result.Operator("Main", "|=")
+	require.NotNil(op)
+	require.Equal("|=", op.Name)
+	require.Equal("Parser", op.Module)
+	require.Equal("infixr 5", op.Fixity())
+	require.Equal(" Keeps both values.\n", op.Doc)
+	require.NotNil(op.Decl)
+	require.Equal(op, result.Operator("Parser", "|="))
+	require.Nil(result.Operator("Main", "+"))
+
+	// the operator is right associative in the module that defines it too
+	for _, mod := range []string{"Main", "Parser"} {
+		decl := result.Modules[mod].Decls[len(result.Modules[mod].Decls)-1].(*ast.Definition)
+		expr := decl.Body.(*ast.BinaryOp)
+		require.IsType(&ast.BinaryOp{}, expr.Rhs, mod)
+	}
+}
+
+func TestOperatorsOpenImport(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Closed.elm", "Ops.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Ops exposing (..)\nimport Closed\n\ncalc =\n    1 + 2 * 3\n")
+	loader.Add("Closed.elm", "module Closed exposing (..)\n\nimport Ops exposing ((+), (*))\n\ncalc =\n    1 + 2 * 3\n")
+	loader.Add("Ops.elm", "module Ops exposing ((+), (*))\n\n(+) a b =\n    a\n\n\n(*) a b =\n    b\n\n\n(^) a b =\n    a\n\n\ninfixl 6 +\ninfixl 7 *\ninfixr 8 ^\n")
+
+	result, err := Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	require.NoError(err)
+
+	// the operators of a module imported exposing all its names are the
+	// ones it exposes, which are available in the importing module
+	for _, mod := range []string{"Main", "Closed"} {
+		expr := result.Modules[mod].Decls[0].(*ast.Definition).Body.(*ast.BinaryOp)
+		require.Equal("+", expr.Op.Name, mod)
+		require.Equal("*", expr.Rhs.(*ast.BinaryOp).Op.Name, mod)
+
+		op := result.Operator(mod, "+")
+		require.NotNil(op, mod)
+		require.Equal("infixl 6", op.Fixity())
+		require.Equal(op, result.Operator("Ops", "+"))
+		require.Nil(result.Operator(mod, "^"), mod)
+	}
+}
+
+func TestFixitiesDefaultImports(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	root := filepath.Join(wd, "_testdata", "valid_fullparse")
+	path := filepath.Join(root, "src", "Main.elm")
+	basics := filepath.Join(root, "elm-stuff", "packages", "elm-lang", "core", "5.1.1", "src", "Basics.elm")
+	overlay := source.Overlay{
+		path:   "module Main exposing (..)\n\n\ncalc =\n    1 + 2 * 3 - 4\n",
+		basics: "module Basics exposing ((+), (-), (*))\n\nimport Native.Basics\n\n\n(+) =\n    Native.Basics.add\n\n\n(-) =\n    Native.Basics.sub\n\n\n(*) =\n    Native.Basics.mul\n\n\ninfixl 6 +\ninfixl 6 -\ninfixl 7 *\n",
+	}
+
+	// the snapshot of the fixities of a module has the operators of the
+	// default import of Basics, which exposes all its names
+	result, err := Parse(path, FullParse, WithOverlay(overlay))
+	require.NoError(err)
+
+	expr := result.Modules["Main"].Decls[0].(*ast.Definition).Body.(*ast.BinaryOp)
+	require.Equal("-", expr.Op.Name)
+	lhs := expr.Lhs.(*ast.BinaryOp)
+	require.Equal("+", lhs.Op.Name)
+	require.Equal("*", lhs.Rhs.(*ast.BinaryOp).Op.Name)
+}
+
+func TestSyntheticModules(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm"})
+	require.NoError(err)
+	require.NoError(p.Provide(pkg.MemoryModules{
+		"Assets": []byte("module Assets exposing (logo)\n\nlogo =\n    \"logo.png\"\n"),
+	}))
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Assets\n\nmain =\n    Assets.logo\n")
+
+	result, err := Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	require.NoError(err)
+	require.Equal([]string{"Assets", "Main"}, result.Order)
+	require.Equal(p.SyntheticPath("Assets"), result.Modules["Assets"].Path)
+}
+
+func TestSyntheticRoot(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	p, err := pkg.Load(filepath.Join(wd, "_testdata", "valid_fullparse"))
+	require.NoError(err)
+	require.NoError(p.Provide(pkg.MemoryModules{
+		"Root": []byte("module Root exposing (..)\n\nimport Main\n"),
+	}))
+
+	// the modules of the dependencies are parsed with their own manifest,
+	// so the core package has no default imports
+	result, err := Parse(p.SyntheticPath("Root"), FullParse, WithPackage(p))
+	require.NoError(err)
+	require.Equal("Root", result.Order[len(result.Order)-1])
+	require.Contains(result.Order, "Dependency")
+}
+
+func TestTargetPragma(t *testing.T) {
+	require := require.New(t)
+
+	manifest := `{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`
+	p, err := pkg.NewMemory(strings.NewReader(manifest), []string{"Main.elm"})
+	require.NoError(err)
+
+	src := "module Main exposing (..)\n\n{- elmo:target js -}\nnow : Int\nnow =\n    1\n\n{- elmo:target go wasm -}\n-- the Go backend has no clock yet\nnow : Int\nnow =\n    2\n\n{- elmo:target node -}\nplatform =\n    1\n\nmain =\n    now\n"
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", src)
+
+	body := func(t target.Target) []string {
+		result, err := Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithTarget(t))
+		require.NoError(err, string(t))
+
+		var bodies []string
+		for _, d := range result.Modules["Main"].Decls {
+			def := d.(*ast.Definition)
+			switch b := def.Body.(type) {
+			case *ast.BasicLit:
+				bodies = append(bodies, def.Name.Name+" = "+b.Value)
+			case *ast.Ident:
+				bodies = append(bodies, def.Name.Name+" = "+b.Name)
+			}
+		}
+		return bodies
+	}
+
+	require.Equal([]string{"now = 1", "main = now"}, body(target.HTML))
+	require.Equal([]string{"now = 1", "platform = 1", "main = now"}, body(target.Node))
+	require.Equal([]string{"now = 2", "main = now"}, body(target.WASM))
+
+	mod, err := ParseFrom("Main.elm", strings.NewReader(src), FullParse)
+	require.NoError(err)
+	require.Len(mod.Decls, 4)
+
+	loader.Add("Main.elm", "module Main exposing (..)\n\n{- elmo:target -}\na =\n    1\n\n{- elmo:target js ios -}\nb =\n    1\n")
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+	diagnostics := derr.Diagnostics["Main.elm"]
+	require.Len(diagnostics, 2)
+	require.Contains(diagnostics[0].Message, "This target pragma has no targets.")
+	require.Equal(3, diagnostics[0].Pos.Line)
+	require.Contains(diagnostics[1].Message, `I do not know the target "ios" of this target pragma. The targets are go, html, js, node, wasm, webworker.`)
+	require.Equal(7, diagnostics[1].Pos.Line)
+}
+
+func TestLiterateModule(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Guide.md"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Guide\n\nmain =\n    Guide.answer\n")
+	loader.Add("Guide.md", "# Guide\n\nThe module:\n\n```elm\nmodule Guide exposing (..)\n```\n\nThe answer:\n\n```elm\nanswer =\n    42\n```\n")
+
+	result, err := Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	require.NoError(err)
+	guide := result.Modules["Guide"]
+	require.NotNil(guide)
+	require.Equal("Guide.md", guide.Path)
+	require.Len(guide.Decls, 1)
+
+	loader.Add("Guide.md", "# Guide\n\n```elm\nmodule Guide exposing (..)\n```\n\nThe answer:\n\n```elm\nanswer =\n    question\n```\n")
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+	require.Len(derr.Diagnostics["Guide.md"], 1)
+	require.Equal(11, derr.Diagnostics["Guide.md"][0].Pos.Line)
+	require.Equal(5, derr.Diagnostics["Guide.md"][0].Pos.Col)
+}
+
+func TestResolveDestructuring(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Pair.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Pair exposing (first, x)\n\nmain =\n    [ first, x, Pair.second, Pair.y, point, total ]\n\n( point, total ) =\n    let\n        sum =\n            a\n\n        ( a, b ) =\n            ( 1, 2 )\n    in\n        ( [ a, b ], sum )\n")
+	loader.Add("Pair.elm", "module Pair exposing (first, second, x, y)\n\n( first, second ) =\n    ( 1, 2 )\n\n{ x, y } =\n    { x = 1, y = 2 }\n")
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	require.NoError(err)
+
+	loader.Add("Main.elm", "module Main exposing (..)\n\nfirst =\n    1\n\n( first, second ) =\n    ( 1, 2 )\n\nmain =\n    let\n        ( a, b ) =\n            ( 1, 2 )\n    in\n        [ a, c ]\n")
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+
+	diagnostics := derr.Diagnostics["Main.elm"]
+	require.Len(diagnostics, 2)
+	require.Contains(diagnostics[0].Message, `Name "first" has already been declared`)
+	require.Equal(6, diagnostics[0].Pos.Line)
+	require.Contains(diagnostics[1].Message, `I could not find any definition for "c".`)
+	require.Equal(14, diagnostics[1].Pos.Line)
+}
+
+func TestCircularDependency(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "A.elm", "B.elm", "C.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport A\n\nmain =\n    A.view\n")
+	loader.Add("A.elm", "module A exposing (..)\n\nimport B\n\ntype Msg\n    = Click\n\nview =\n    B.one + B.two\n")
+	loader.Add("B.elm", "module B exposing (..)\n\nimport C exposing (helper)\n\none =\n    helper 1\n\ntwo =\n    let\n        x = 2\n    in\n        x\n")
+	loader.Add("C.elm", "module C exposing (..)\n\nimport A as X\n\nhelper : X.Msg -> Int\nhelper x =\n    X.view\n")
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+	require.Len(derr.Diagnostics["Main.elm"], 1)
+	require.Equal(
+		"I found a circular dependency in your code between these modules:\n- A\n- B\n- C\n\n"+
+			"- A imports B and uses `B.one` and `B.two`\n"+
+			"- B imports C and uses `C.helper`\n"+
+			"- C imports A and uses `A.Msg` and `A.view`\n\n"+
+			"Only `C.helper` of C is used in B, so consider moving it to another module that both can import.",
+		derr.Diagnostics["Main.elm"][0].Message,
+	)
+}
+
+func TestHooks(t *testing.T) {
+	require := require.New(t)
+
+	manifest := strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`)
+	p, err := pkg.NewMemory(manifest, []string{"Main.elm", "Page.elm"})
+	require.NoError(err)
+
+	loader := source.NewMemLoader()
+	loader.Add("Main.elm", "module Main exposing (..)\n\nimport Page\n\nmain =\n    Page.view\n")
+	loader.Add("Page.elm", "module Page exposing (..)\n\nimport Svg\n\nview =\n    1\n")
+
+	var (
+		files       = make(map[string]int64)
+		modules     []string
+		diagnostics []string
+		phases      = make(map[profile.Phase][]string)
+	)
+	hooks := &Hooks{
+		OnFileLoaded: func(path string, size int64) error {
+			files[path] = size
+			return nil
+		},
+		OnModuleParsed: func(module, path string) error {
+			modules = append(modules, module)
+			return nil
+		},
+		OnDiagnostic: func(path string, d *report.Diagnostic) {
+			diagnostics = append(diagnostics, path)
+		},
+		OnPhaseEnd: func(phase profile.Phase, module string, duration time.Duration) {
+			phases[phase] = append(phases[phase], module)
+		},
+	}
+
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithHooks(hooks))
+	require.True(errors.Is(err, ErrProblems))
+	require.Equal(map[string]int64{"Main.elm": 61, "Page.elm": 52}, files)
+	require.Equal([]string{"Page.elm"}, diagnostics)
+	require.Equal([]string{"Main", "Page"}, phases[profile.Load])
+	require.Equal([]string{"Main", "Page"}, phases[profile.Scan])
+	require.Empty(modules)
+
+	loader.Add("Page.elm", "module Page exposing (..)\n\nview =\n    1\n")
+	files, phases = make(map[string]int64), make(map[profile.Phase][]string)
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithHooks(hooks))
+	require.NoError(err)
+	require.Equal([]string{"Page", "Main"}, modules)
+	require.Equal([]string{"Main", "Page", "Page", "Main"}, phases[profile.Parse])
+	require.Equal([]string{"Page", "Main"}, phases[profile.Resolve])
+
+	hooks.OnFileLoaded = func(path string, size int64) error {
+		if path == "Page.elm" {
+			return errors.New("it is too large")
+		}
+		return nil
+	}
+	_, err = Parse("Main.elm", FullParse, WithPackage(p), WithLoader(loader), WithHooks(hooks))
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+	require.Equal("I could not load this module because it is too large.", derr.Diagnostics["Page.elm"][0].Message)
+}
+
+func TestDiagnosticError(t *testing.T) {
+	require := require.New(t)
+
+	_, err := ParseFrom("Foo.elm", strings.NewReader("module Foo exposing (..)\n\nfoo = = 1\n"), FullParse)
+	require.Error(err)
+	require.True(errors.Is(err, ErrProblems))
+
+	var derr *DiagnosticError
+	require.True(errors.As(err, &derr))
+	require.Equal([]string{"Foo.elm"}, derr.Files)
+	require.Equal(1, derr.Errors())
+	require.Equal(0, derr.Warnings())
+
+	d := derr.Diagnostics["Foo.elm"][0]
+	require.Equal(report.SyntaxError, d.Type)
+	require.Equal(3, d.Pos.Line)
+	require.Contains(err.Error(), "problems found at file: Foo.elm")
+	require.Contains(err.Error(), d.Message)
+}
+
+func TestParseComments(t *testing.T) {
+	require := require.New(t)
+
+	f, err := ParseFrom("Foo.elm", strings.NewReader(commentsFixture), ParseComments)
+	require.NoError(err)
+
+	var texts []string
+	for _, c := range f.Comments {
+		require.Equal(c.Text, commentsFixture[c.Pos():c.End()])
+		texts = append(texts, c.Text)
+	}
+	require.Equal([]string{"{-| The answer. -}", "-- not a guess", "-- a record"}, texts)
+	require.False(f.Comments[0].IsLine())
+	require.True(f.Comments[1].IsLine())
+
+	f, err = ParseFrom("Foo.elm", strings.NewReader(commentsFixture), FullParse)
+	require.NoError(err)
+	require.Len(f.Comments, 0)
+}
+
+func TestMissingImportFixes(t *testing.T) {
+	require := require.New(t)
+	wd, err := os.Getwd()
+	require.NoError(err)
+
+	root := filepath.Join(wd, "_testdata", "valid_fullparse")
+	path := filepath.Join(root, "src", "Main.elm")
+	overlay := source.Overlay{
+		filepath.Join(root, "src", "Internal", "Dependency.elm"):                                             "module Internal.Dependency exposing (maybeStr, Bag(..))\n\nimport Dependency\n\ntype Bag\n    = Stuffed\n    | Hollow\n\nmaybeStr =\n    Just \"hi\"\n",
+		filepath.Join(root, "elm-stuff", "packages", "some", "dependency", "1.0.0", "src", "Dependency.elm"): "module Dependency exposing (withDefault, (?))\n\nwithDefault a m =\n    Maybe.withDefault a m\n\n(?) m a =\n    Maybe.withDefault a m\n",
+		path: "module Main exposing (..)\n\nimport Internal.Dependency\n\nmain =\n    maybeStr ? \"hello\"\n",
+	}
+
+	var buf bytes.Buffer
+	_, err = ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"fixes":[{"message":"Expose \"maybeStr\" in the import of Internal.Dependency","line":3,"col":27,"end-line":3,"end-col":27,"text":" exposing (maybeStr)"}]`)
+	require.Contains(buf.String(), `"fixes":[{"message":"Add \"import Dependency exposing ((?))\"","line":3,"col":27,"end-line":3,"end-col":27,"text":"\nimport Dependency exposing ((?))"}]`)
+
+	buf.Reset()
+	overlay[path] = "module Main exposing (..)\n\nimport Internal.Dependency exposing (maybeStr)\n\nmain =\n    Dependency.withDefault \"hello\" maybeStr\n"
+	_, err = ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"fixes":[{"message":"Add \"import Dependency\"","line":3,"col":47,"end-line":3,"end-col":47,"text":"\nimport Dependency"}]`)
+
+	// the names of the modules that are already imported are exposed by
+	// their imports
+	buf.Reset()
+	overlay[path] = "module Main exposing (..)\n\nimport Dependency exposing (withDefault)\nimport Internal.Dependency exposing (maybeStr)\n\nmain =\n    withDefault \"hello\" maybeStr ? \"hi\"\n"
+	_, err = ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"fixes":[{"message":"Expose \"(?)\" in the import of Dependency","line":3,"col":40,"end-line":3,"end-col":40,"text":", (?)"}]`)
+	require.NotContains(buf.String(), `import Dependency exposing ((?))`)
+
+	buf.Reset()
+	overlay[path] = "module Main exposing (..)\n\nimport Internal.Dependency exposing (Bag, maybeStr)\n\nmain =\n    [ Stuffed, Hollow ]\n"
+	_, err = ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"fixes":[{"message":"Expose \"Bag(Stuffed)\" in the import of Internal.Dependency","line":3,"col":41,"end-line":3,"end-col":41,"text":"(Stuffed)"}]`)
+
+	buf.Reset()
+	overlay[path] = "module Main exposing (..)\n\nimport Internal.Dependency exposing (Bag(Stuffed), maybeStr)\n\nmain =\n    [ Stuffed, Hollow ]\n"
+	_, err = ParseOverlay(path, FullParse, report.JSON(&buf, false), overlay)
+	require.Equal(ErrProblems, err)
+	require.Contains(buf.String(), `"fixes":[{"message":"Expose \"Bag(Hollow)\" in the import of Internal.Dependency","line":3,"col":49,"end-line":3,"end-col":49,"text":", Hollow"}]`)
+}
+
+func TestParseNativeDecls(t *testing.T) {
+	require := require.New(t)
+
+	var files = map[string]string{
+		"Main.elm": `module Main exposing (..)
+
+import Native.List
+
+
+cons =
+    Native.List.cons
+`,
+		"Native/List.go": "package native\n",
+		"Native/List.decl": `cons : a -> List a -> List a
+
+append : List a -> List a -> List a
+
+empty :
+    List a
+`,
+	}
+
+	parse := func() (*ast.Package, string, error) {
+		loader := source.NewMemLoader()
+		var paths []string
+		for path, src := range files {
+			loader.Add(path, src)
+			paths = append(paths, path)
+		}
+
+		p, err := pkg.NewMemory(strings.NewReader(`{"repository": "https://github.com/elm-lang/core.git", "source-directories": ["."]}`), paths)
+		require.NoError(err)
+
+		var buf bytes.Buffer
+		result, err := ParseIn(p, loader, "Main.elm", FullParse, report.JSON(&buf, false))
+		return result, buf.String(), err
+	}
+
+	result, out, err := parse()
+	require.NoError(err, out)
+	native := result.Natives["Native.List"]
+	require.NotNil(native)
+	require.Equal("Native/List.go", native.Path)
+	require.Equal("Native/List.decl", native.DeclPath)
+	require.Len(native.Decls, 3)
+	require.Equal("append", native.Decls[1].Name.Name)
+	require.NotNil(native.Lookup("empty"))
+	require.Nil(native.Lookup("map"))
+
+	files["Native/List.decl"] = "cons : a -> Lst a -> List a\n"
+	_, out, err = parse()
+	require.Equal(ErrProblems, err)
+	require.Contains(out, `"file":"Native/List.decl"`)
+	require.Contains(out, `Lst`)
+
+	delete(files, "Native/List.decl")
+	result, out, err = parse()
+	require.NoError(err, out)
+	require.Equal("", result.Natives["Native.List"].DeclPath)
+}
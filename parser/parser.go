@@ -3,10 +3,12 @@ package parser
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/crash"
 	"github.com/elm-tangram/tangram/package"
 	"github.com/elm-tangram/tangram/report"
 	"github.com/elm-tangram/tangram/scanner"
@@ -85,6 +87,9 @@ type parser struct {
 
 	// tok is the current token.
 	tok *token.Token
+	// prevEnd is the end of the previous token, which tells whether there
+	// is whitespace before the current one.
+	prevEnd token.Pos
 	// region is the current region start for error reporting.
 	region *token.Position
 	// indent is the last indentation point.
@@ -103,16 +108,70 @@ type parser struct {
 	// tokens.
 	silent bool
 	// modName is the name of the current module being parsed.
-	modName string
+	modName token.Name
+	// comments are the comments found so far, if the comments are parsed.
+	comments []*ast.Comment
+	// pragma is the target pragma in the comments right before the current
+	// token, if there is one.
+	pragma *pragma
+	// arena allocates the nodes of the module, if the mode is ArenaAlloc.
+	arena *ast.Arena
+	// fixities are the operators available in the module, if they are all
+	// known before parsing it. Otherwise, they are looked up in the table.
+	fixities *fixities
+	// pkg is the package of all the files, if they are not in the file
+	// system. Otherwise, the package of every file is loaded from it.
+	pkg *pkg.Package
 }
 
 func newParser(sess *Session) *parser {
 	return &parser{sess: sess}
 }
 
-// bailout is the type used to stop parsing. It's the only panic
-// that will be caught because it means we stopped parsing deliberately.
-type bailout struct{}
+// bailout is the value the parser panics with to stop parsing, once it has
+// reported the problem that made it stop. It is only recovered by the run of
+// the parser that stopped, so any other panic, even the bailout of another
+// parser, goes through.
+type bailout struct {
+	p *parser
+}
+
+// stop stops parsing after a problem has been reported.
+func (p *parser) stop() {
+	panic(bailout{p})
+}
+
+// run calls fn, which is stopped if the parser stops. As the problem that
+// made the parser stop has been reported already, run only returns an error,
+// which is a crash.Error, if fn panicked because of something else.
+func (p *parser) run(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if b, ok := r.(bailout); ok && b.p == p {
+				return
+			}
+			err = p.crash(r)
+		}
+	}()
+
+	fn()
+	return nil
+}
+
+// crash returns the internal error of a panic with the given value while
+// parsing the current token.
+func (p *parser) crash(value interface{}) *crash.Error {
+	e := crash.New("parse", value)
+	e.Module, e.Path = p.modName.String(), p.fileName
+	if p.tok != nil {
+		e.Pos = p.tok.Offset
+	}
+
+	if p.sess != nil {
+		e.Locate(p.sess.CodeMap)
+	}
+	return e
+}
 
 func (p *parser) init(fileName string, s *scanner.Scanner, mode ParseMode) {
 	p.scanner = s
@@ -124,14 +183,20 @@ func (p *parser) init(fileName string, s *scanner.Scanner, mode ParseMode) {
 	p.currentIndent = 1
 	p.silent = false
 	p.expectIndented = false
-	p.modName = ""
+	p.modName = token.Name{}
+	p.comments = nil
+	p.arena = nil
+	p.fixities = nil
+	if mode.Is(ArenaAlloc) {
+		p.arena = ast.NewArena()
+	}
 
 	p.next()
 }
 
 func parseFile(p *parser) *ast.Module {
 	mod := parseModule(p)
-	p.modName = mod.ModuleName()
+	p.modName = p.sess.opTable.names.Intern(mod.ModuleName())
 	var imports []*ast.ImportDecl
 	if p.needsDefaultImports() {
 		imports = defaultImports
@@ -144,15 +209,28 @@ func parseFile(p *parser) *ast.Module {
 
 	var decls []ast.Decl
 	for p.tok.Type != token.EOF {
-		decls = append(decls, parseDecl(p))
+		pragma := p.pragma
+		if pragma != nil && !p.mode.Is(SkipDefinitions) {
+			checkPragma(p, pragma)
+		}
+
+		decl := parseDecl(p)
+		if pragma == nil || p.sess.Target == "" || pragma.includes(p.sess.Target) {
+			decls = append(decls, decl)
+		}
+	}
+
+	if !p.mode.Is(SkipDefinitions) {
+		checkFixities(p, decls)
 	}
 
 	return &ast.Module{
-		Path:    p.fileName,
-		Name:    mod.ModuleName(),
-		Module:  mod,
-		Imports: imports,
-		Decls:   decls,
+		Path:     p.fileName,
+		Name:     mod.ModuleName(),
+		Module:   mod,
+		Imports:  imports,
+		Decls:    decls,
+		Comments: p.comments,
 	}
 }
 
@@ -205,7 +283,7 @@ func parseOp(p *parser) *ast.Ident {
 		p.expect(token.Op)
 	}
 
-	return &ast.Ident{NamePos: pos.Offset, Name: name}
+	return p.arena.NewIdent(name, pos.Offset)
 }
 
 func (p *parser) indentedBlock() func() {
@@ -243,10 +321,22 @@ func (p *parser) next() {
 		}
 	}
 
+	if p.tok != nil {
+		p.prevEnd = p.tok.Offset + token.Pos(len(p.tok.Value))
+	}
+
+	// comments can be anywhere, so they are skipped without checking the
+	// indentation
 	p.tok = p.scanner.Next()
-	if p.is(token.Comment) {
-		// ignore comments for now
-		p.next()
+	p.pragma = nil
+	for p.is(token.Comment) {
+		if p.mode.Is(ParseComments) {
+			p.addComment(p.tok)
+		}
+		if pr := parsePragma(p.tok); pr != nil {
+			p.pragma = pr
+		}
+		p.tok = p.scanner.Next()
 	}
 
 	if p.tok.Line != p.currentLine {
@@ -255,6 +345,16 @@ func (p *parser) next() {
 	}
 }
 
+// addComment adds the comment in the given token to the comments of the
+// module. The tokens after a backup are scanned again, so the comments that
+// were already added are skipped.
+func (p *parser) addComment(tok *token.Token) {
+	if n := len(p.comments); n > 0 && p.comments[n-1].Position >= tok.Offset {
+		return
+	}
+	p.comments = append(p.comments, &ast.Comment{Position: tok.Offset, Text: tok.Value})
+}
+
 func (p *parser) backup(until *token.Token) {
 	p.scanner.Backup(until)
 	p.next()
@@ -316,33 +416,53 @@ func (p *parser) is(typ token.Type) bool {
 	return p.tok.Type == typ
 }
 
+// defaultOperator is the fixity of the operators that are not found, which
+// must not be modified.
+var defaultOperator = operatorInfo{
+	Precedence:    0,
+	Associativity: ast.Left,
+}
+
 func (p *parser) opInfo(name string) *operatorInfo {
-	info := p.sess.opTable.lookup(name, p.modName)
-	if info != nil {
-		return info
+	var info *operatorInfo
+	if p.fixities != nil && p.fixities.module == p.modName {
+		info = p.fixities.lookup(name)
+	} else {
+		info = p.sess.opTable.lookup(name, p.modName)
 	}
 
-	return &operatorInfo{
-		Precedence:    0,
-		Associativity: ast.Left,
+	if info != nil {
+		return info
 	}
+	return &defaultOperator
 }
 
 func (p *parser) needsDefaultImports() bool {
-	pkg, err := pkg.Load(filepath.Dir(p.fileName))
-	if err != nil {
-		return false
+	manifest := p.pkg
+	if manifest == nil || inDependency(manifest, p.fileName) {
+		var err error
+		if manifest, err = pkg.Load(filepath.Dir(p.fileName)); err != nil {
+			return false
+		}
 	}
 
-	_, ok := specialPackages[pkg.Repository]
+	_, ok := specialPackages[manifest.Repository]
 	return !ok
 }
 
+// inDependency reports whether the file is in the directory of one of the
+// dependencies of the package, which have their own manifest.
+func inDependency(p *pkg.Package, path string) bool {
+	rel, err := filepath.Rel(filepath.Join(p.Root(), "elm-stuff", "packages"), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 var specialPackages = map[string]struct{}{
 	"https://github.com/elm-lang/core.git":    struct{}{},
 	"http://github.com/elm-lang/core.git":     struct{}{},
 	"https://github.com/elm-tangram/core.git": struct{}{},
 	"http://github.com/elm-tangram/core.git":  struct{}{},
+	"https://github.com/elm/core.git":         struct{}{},
 }
 
 func (p *parser) checkAligned() bool {
@@ -375,28 +495,28 @@ func (p *parser) regionStart() *token.Position {
 func (p *parser) errorExpected(t *token.Token, typ token.Type) {
 	if t.Type == token.EOF {
 		p.errorUnexpectedEOF()
-		panic(bailout{})
+		p.stop()
 	}
 
 	p.errorExpectedOneOf(t, typ)
 }
 
 func (p *parser) errorExpectedOneOf(t *token.Token, types ...token.Type) {
-	p.report(report.NewUnexpectedTokenError(t, p.currentRegion(), types...))
+	p.report(report.NewUnexpectedTokenError(p.fileName, t, p.currentRegion(), types...))
 }
 
 func (p *parser) errorUnexpectedEOF() {
-	p.report(report.NewUnexpectedEOFError(p.tok.Offset, p.currentRegion()))
-	panic(bailout{})
+	p.report(report.NewUnexpectedEOFError(p.fileName, p.tok.Offset, p.currentRegion()))
+	p.stop()
 }
 
 func (p *parser) errorExpectedType(pos token.Pos) {
-	p.report(report.NewExpectedTypeError(pos, p.currentRegion()))
-	panic(bailout{})
+	p.report(report.NewExpectedTypeError(p.fileName, pos, p.currentRegion()))
+	p.stop()
 }
 
 func (p *parser) errorMessage(pos token.Pos, msg string, args ...interface{}) {
-	p.report(report.NewBaseReport(report.SyntaxError, pos, fmt.Sprintf(msg, args...), p.currentRegion()))
+	p.report(report.NewBaseReport(report.SyntaxError, token.At(p.fileName, pos), fmt.Sprintf(msg, args...), p.currentRegion()))
 }
 
 func (p *parser) currentRegion() *report.Region {
@@ -409,7 +529,7 @@ func (p *parser) report(report report.Report) {
 		return
 	}
 
-	p.sess.Report(p.fileName, report)
+	p.sess.Report(report)
 }
 
 func isLower(name string) bool {
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/elm-tangram/tangram/report"
 	"github.com/elm-tangram/tangram/scanner"
 	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
 	"github.com/stretchr/testify/require"
 )
 
@@ -76,6 +78,59 @@ func TestParseModule(t *testing.T) {
 	}
 }
 
+func TestParseEffectModule(t *testing.T) {
+	cases := []struct {
+		input   string
+		ok, eof bool
+		manager map[string]string
+	}{
+		{
+			"effect module Foo where { command = MyCmd } exposing (..)",
+			true, false,
+			map[string]string{"command": "MyCmd"},
+		},
+		{
+			"effect module Foo where { command = MyCmd, subscription = MySub } exposing (..)",
+			true, false,
+			map[string]string{"command": "MyCmd", "subscription": "MySub"},
+		},
+		{"effect module Foo where { foo = MyCmd } exposing (..)", false, false, nil},
+		{"effect module Foo where {} exposing (..)", false, false, nil},
+		{"effect module Foo exposing (..)", false, true, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			defer assertEOF(t, c.input, c.eof)
+			p := stringParser(t, c.input)
+			defer p.sess.Emit()
+			mod := parseModule(p)
+			require.Equal(t, c.ok, p.sess.IsOK())
+			if c.ok {
+				require.True(t, mod.IsEffect())
+				require.Equal(t, "Foo", mod.ModuleName())
+				require.Len(t, mod.Manager, len(c.manager))
+				for field, typ := range c.manager {
+					assertIdent(t, typ, mod.ManagedType(field).(*ast.Ident))
+				}
+			}
+		})
+	}
+}
+
+func TestParsePortModule(t *testing.T) {
+	input := "port module Foo exposing (..)"
+	defer assertEOF(t, input, false)
+	p := stringParser(t, input)
+	defer p.sess.Emit()
+	mod := parseModule(p)
+	require.True(t, p.sess.IsOK())
+	require.True(t, mod.PortModule)
+	require.False(t, mod.IsEffect())
+	require.Equal(t, token.Pos(0), mod.Pos())
+	Module("Foo", OpenList)(t, mod)
+}
+
 func TestParseImport(t *testing.T) {
 	cases := []struct {
 		input   string
@@ -610,6 +665,36 @@ func TestParseDefinition(t *testing.T) {
 	}
 }
 
+func TestParsePortDecl(t *testing.T) {
+	cases := []struct {
+		input  string
+		ok     bool
+		assert DeclAssert
+	}{
+		{
+			"port output : String -> Cmd msg",
+			true,
+			Port("output", FuncType(
+				NamedType("String"),
+				NamedType("Cmd", VarType("msg")),
+			)),
+		},
+		{
+			"port input : (Int -> msg) -> Sub msg",
+			true,
+			Port("input", FuncType(
+				FuncType(NamedType("Int"), VarType("msg")),
+				NamedType("Sub", VarType("msg")),
+			)),
+		},
+		{"port Output : String -> Cmd msg", false, nil},
+	}
+
+	for _, c := range cases {
+		mustParseDecl(t, c.input, false, c.ok, c.assert)
+	}
+}
+
 func TestParseDestructuringAssignment(t *testing.T) {
 	cases := []struct {
 		input  string
@@ -653,6 +738,28 @@ func TestParseDestructuringAssignment(t *testing.T) {
 	for _, c := range cases {
 		mustParseDecl(t, c.input, false, true, c.assert)
 	}
+
+	invalid := []struct {
+		input string
+		line  int
+	}{
+		{"[ a, b ] = [ 1, 2 ]\n", 3},
+		{"Just a = Nothing\n", 3},
+		{"(a :: rest) = [ 1 ]\n", 3},
+		{"1 = 1\n", 3},
+		{"x =\n    let\n        [ a ] = [ 1 ]\n    in\n        a\n", 5},
+		{"x =\n    let\n        Just a = Nothing\n    in\n        a\n", 5},
+	}
+
+	for _, c := range invalid {
+		_, err := ParseFrom("Foo.elm", strings.NewReader("module Foo exposing (..)\n\n"+c.input), FullParse)
+		var derr *DiagnosticError
+		require.True(t, errors.As(err, &derr), c.input)
+		require.Len(t, derr.Diagnostics["Foo.elm"], 1, c.input)
+		d := derr.Diagnostics["Foo.elm"][0]
+		require.Contains(t, d.Message, "This is not a valid pattern for a destructuring assignment.", c.input)
+		require.Equal(t, c.line, d.Pos.Line, c.input)
+	}
 }
 
 func TestParsePattern(t *testing.T) {
@@ -766,6 +873,13 @@ func TestParseType(t *testing.T) {
 				NamedType("List", NamedType("Int")),
 			),
 		},
+		{
+			"List (Foo a) -> Int",
+			FuncType(
+				NamedType("List", NamedType("Foo", VarType("a"))),
+				NamedType("Int"),
+			),
+		},
 		// TODO(erizocosmico): improve this tests cases and relieve pressure
 		// from ParseTypeUnion and ParseTypeAlias
 	}
@@ -1072,6 +1186,16 @@ func TestParseExpr(t *testing.T) {
 			),
 		},
 		{`(,,)`, TupleCtor(3)},
+		{`(-)`, Identifier("-")},
+		{`(-x)`, Parens(UnaryOp("-", Identifier("x")))},
+		{`f (-1)`, FuncApp(Identifier("f"), Parens(UnaryOp("-", Literal(ast.Int, "1"))))},
+		{`f -1`, FuncApp(Identifier("f"), UnaryOp("-", Literal(ast.Int, "1")))},
+		{`f -x -1`, FuncApp(Identifier("f"), UnaryOp("-", Identifier("x")), UnaryOp("-", Literal(ast.Int, "1")))},
+		{`a - 1`, BinaryOp("-", Identifier("a"), Literal(ast.Int, "1"))},
+		{`a-1`, BinaryOp("-", Identifier("a"), Literal(ast.Int, "1"))},
+		{`a - -1`, BinaryOp("-", Identifier("a"), UnaryOp("-", Literal(ast.Int, "1")))},
+		{`(+) 1`, FuncApp(Identifier("+"), Literal(ast.Int, "1"))},
+		{`((+) 1) 2`, FuncApp(Parens(FuncApp(Identifier("+"), Literal(ast.Int, "1"))), Literal(ast.Int, "2"))},
 		{
 			`{ a = 1, b = [ 1, 2 ], c = { x = 1, y = 2 } }`,
 			RecordLiteral(
@@ -1238,24 +1362,72 @@ func TestParseExpr(t *testing.T) {
 			`fn a 1 + fn b c + fn d e`,
 			BinaryOp(
 				"+",
-				FuncApp(
-					Identifier("fn"),
-					Identifier("a"),
-					Literal(ast.Int, "1"),
-				),
 				BinaryOp(
 					"+",
+					FuncApp(
+						Identifier("fn"),
+						Identifier("a"),
+						Literal(ast.Int, "1"),
+					),
 					FuncApp(
 						Identifier("fn"),
 						Identifier("b"),
 						Identifier("c"),
 					),
+				),
+				FuncApp(
+					Identifier("fn"),
+					Identifier("d"),
+					Identifier("e"),
+				),
+			),
+		},
+		{
+			`10 - idInt 1 + 5`,
+			BinaryOp(
+				"+",
+				BinaryOp(
+					"-",
+					Literal(ast.Int, "10"),
+					FuncApp(
+						Identifier("idInt"),
+						Literal(ast.Int, "1"),
+					),
+				),
+				Literal(ast.Int, "5"),
+			),
+		},
+		{
+			`x + f y == 5`,
+			BinaryOp(
+				"==",
+				BinaryOp(
+					"+",
+					Identifier("x"),
 					FuncApp(
-						Identifier("fn"),
-						Identifier("d"),
-						Identifier("e"),
+						Identifier("f"),
+						Identifier("y"),
+					),
+				),
+				Literal(ast.Int, "5"),
+			),
+		},
+		{
+			`a * f b <| g c`,
+			BinaryOp(
+				"<|",
+				BinaryOp(
+					"*",
+					Identifier("a"),
+					FuncApp(
+						Identifier("f"),
+						Identifier("b"),
 					),
 				),
+				FuncApp(
+					Identifier("g"),
+					Identifier("c"),
+				),
 			),
 		},
 		{
@@ -1323,6 +1495,67 @@ func TestParseExpr_NonAssocOp(t *testing.T) {
 	})
 }
 
+func TestParseExpr_ConflictingAssocOps(t *testing.T) {
+	for _, input := range []string{`f <| x |> g`, `x |> f <| y`, `f <| g <| x |> h`} {
+		t.Run(input, func(t *testing.T) {
+			defer assertEOF(t, input, true)
+
+			p := stringParser(t, input)
+			defer p.sess.Emit()
+			parseExpr(p)
+		})
+	}
+
+	t.Run("same associativity", func(t *testing.T) {
+		input := `f <| g <| x`
+		defer assertEOF(t, input, false)
+
+		p := stringParser(t, input)
+		expr := parseExpr(p)
+
+		BinaryOp(
+			"<|",
+			Identifier("f"),
+			BinaryOp(
+				"<|",
+				Identifier("g"),
+				Identifier("x"),
+			),
+		)(t, expr)
+	})
+}
+
+func TestParseOperatorErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		err   string
+	}{
+		{"x =\n    (+ 1)\n", "Elm has no operator sections, such as `(+ x)`. Use a function instead, such as `\\y -> y + x`."},
+		{"x =\n    (- 1)\n", "Elm has no operator sections, such as `(- x)`."},
+		{"x =\n    (1 +)\n", "Elm has no operator sections, such as `(x +)`. Give the operator its first argument as a function instead, such as `(+) x`."},
+		{"x =\n    [ - 1 ]\n", "I found a minus sign followed by whitespace where I was expecting an expression."},
+		{"infix left 6 (+.) = add\n", "This looks like the infix declaration of Elm 0.19"},
+		{"infixl 6 +.\ninfixr 5 +.\n\n(+.) a b =\n    a\n", "The fixity of operator +. is declared more than once in this module."},
+	}
+
+	for _, c := range cases {
+		_, err := ParseFrom("Foo.elm", strings.NewReader("module Foo exposing (..)\n\n"+c.input), FullParse)
+		var derr *DiagnosticError
+		require.True(t, errors.As(err, &derr), c.input)
+		require.Len(t, derr.Diagnostics["Foo.elm"], 1, c.input)
+		require.Contains(t, derr.Diagnostics["Foo.elm"][0].Message, c.err, c.input)
+	}
+
+	m, err := ParseFrom("Foo.elm", strings.NewReader("module Foo exposing (..)\n\ninfixl 6 +.\n\n(+.) : Int -> Int -> Int\n(+.) a b =\n    a -b\n"), FullParse)
+	require.NoError(t, err)
+	def, ok := m.Decls[1].(*ast.Definition)
+	require.True(t, ok)
+	require.Equal(t, "+.", def.Name.Name)
+	require.Len(t, def.Args, 2)
+	require.Equal(t, "+.", def.Annotation.Name.Name)
+	FuncApp(Identifier("a"), UnaryOp("-", Identifier("b")))(t, def.Body)
+}
+
 func assertEOF(t *testing.T, input string, eof bool) {
 	if r := recover(); r != nil {
 		switch r.(type) {
@@ -1362,6 +1595,6 @@ func stringParser(t *testing.T, str string) *parser {
 	sess := NewSession(d, cm, opTable)
 	var p = newParser(sess)
 	p.init("test", scanner, FullParse)
-	p.modName = "Test"
+	p.modName = opTable.names.Intern("Test")
 	return p
 }
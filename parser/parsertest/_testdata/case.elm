@@ -0,0 +1,11 @@
+port module Case exposing (..)
+
+
+name : Maybe String -> String
+name maybe =
+    case maybe of
+        Just n ->
+            n
+
+        Nothing ->
+            "anonymous"
@@ -0,0 +1,10 @@
+module Let exposing (area)
+
+
+area : Float -> Float
+area r =
+    let
+        pi =
+            3.14
+    in
+        pi * r * r
@@ -0,0 +1,170 @@
+// Package parsertest implements helpers to test the parser and the tools
+// built on it with golden files.
+//
+// The fixtures are Elm files, which are parsed on their own, or packages,
+// which are parsed with all the modules they import. The syntax tree of a
+// module is rendered by Dump as text, one node per line with its position,
+// and compared with a golden file, so a change in the parser shows up as a
+// diff of the trees instead of a failing assertion deep in a test.
+//
+// Running the tests with the -update flag writes the golden files instead,
+// so a new case only needs a fixture, and the golden files of its expected
+// output are reviewed like any other change.
+package parsertest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "write the golden files of the tests")
+
+// GoldenExt is the extension of the golden file of a fixture, which
+// replaces the extension of the fixture.
+const GoldenExt = ".golden"
+
+// Parse parses the Elm file at the given path on its own with the given
+// mode, without the modules it imports, and fails the test if it has
+// syntax errors.
+func Parse(t *testing.T, path string, mode parser.ParseMode) *ast.Module {
+	src, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	mod, err := parser.ParseFrom(path, bytes.NewReader(src), mode)
+	require.NoError(t, err)
+	return mod
+}
+
+// ParsePackage parses the module at the given path and all the modules it
+// imports with the given options, and fails the test if there are errors.
+func ParsePackage(t *testing.T, path string, opts ...parser.Option) *ast.Package {
+	pkg, err := parser.Parse(path, append([]parser.Option{parser.FullParse}, opts...)...)
+	require.NoError(t, err)
+	return pkg
+}
+
+// Dump renders the syntax tree of the given node as text. Every node is a
+// line, indented by its depth, with its type, its name or value if it is
+// an identifier or a literal, and its position in src, the source code of
+// the file of the node, as line:col. The text only depends on the tree and
+// the source code, so it is stable across runs.
+func Dump(node ast.Node, src []byte) string {
+	loader := source.NewMemLoader()
+	loader.Add(dumpFile, string(src))
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+	if err := cm.Add(dumpFile); err != nil {
+		panic(err)
+	}
+	file := cm.Source(dumpFile)
+
+	var (
+		buf   bytes.Buffer
+		depth int
+	)
+	ast.WalkFunc(node, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+
+		fmt.Fprintf(&buf, "%s%s", strings.Repeat("  ", depth), strings.TrimPrefix(reflect.TypeOf(n).String(), "*ast."))
+		if desc := describe(n); desc != "" {
+			fmt.Fprintf(&buf, " %s", desc)
+		}
+		fmt.Fprintf(&buf, " %s\n", position(file, n.Pos()))
+		depth++
+		return true
+	})
+	return buf.String()
+}
+
+// dumpFile is the path of the source code of Dump in its code map.
+const dumpFile = "dump.elm"
+
+// describe returns the details of the node that are not its children.
+func describe(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.BasicLit:
+		return fmt.Sprintf("%s %s", n.Type, n.Value)
+	case *ast.ModuleDecl:
+		if n.PortModule {
+			return "port"
+		}
+	case *ast.TupleCtor:
+		return fmt.Sprint(n.Elems)
+	}
+	return ""
+}
+
+func position(file *source.Source, pos token.Pos) string {
+	lp, err := file.LinePos(pos)
+	if err != nil {
+		return fmt.Sprintf("@%d", pos)
+	}
+	return fmt.Sprintf("%d:%d", lp.Line, lp.Col)
+}
+
+// Compare fails the test if got is not the content of the golden file at
+// the given path, or writes it to the file if the tests are run with the
+// -update flag.
+func Compare(t *testing.T, path string, got []byte) {
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, got, 0644))
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("there is no golden file %s, run the tests with -update to write it", path)
+	}
+	require.NoError(t, err)
+
+	if !bytes.Equal(expected, got) {
+		t.Errorf("%s does not match the golden file, run the tests with -update to update it", path)
+		require.Equal(t, string(expected), string(got))
+	}
+}
+
+// Golden returns the path of the golden file of the fixture at the given
+// path, such as `_testdata/let.golden` for `_testdata/let.elm`.
+func Golden(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + GoldenExt
+}
+
+// Test parses every Elm file that matches the given pattern, such as
+// `_testdata/*.elm`, on its own with the given mode and compares the dump
+// of its syntax tree with its golden file.
+func Test(t *testing.T, pattern string, mode parser.ParseMode) {
+	paths, err := filepath.Glob(pattern)
+	require.NoError(t, err)
+	if len(paths) == 0 {
+		t.Fatalf("there are no fixtures in %s", pattern)
+	}
+
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			mod := Parse(t, path, mode)
+			src, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			Compare(t, Golden(path), []byte(Dump(mod, src)))
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package parsertest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	require := require.New(t)
+
+	src := "module Main exposing (..)\n\n\nanswer =\n    42\n"
+	mod, err := parser.ParseFrom("Main.elm", strings.NewReader(src), parser.SkipWarnings)
+	require.NoError(err)
+
+	require.Equal(`Module 1:1
+  ModuleDecl 1:1
+    Ident Main 1:8
+    OpenList 1:22
+  Definition 4:1
+    Ident answer 4:1
+    BasicLit Int 42 5:5
+`, Dump(mod, []byte(src)))
+}
+
+func TestGolden(t *testing.T) {
+	require.Equal(t, "_testdata/let.golden", Golden("_testdata/let.elm"))
+	Test(t, "_testdata/*.elm", parser.SkipWarnings)
+}
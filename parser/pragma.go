@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/elm-tangram/tangram/codegen/target"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// targetPragma is the prefix of the comments that guard the top-level
+// declaration after them, such as `{- elmo:target js -}`, which is only
+// compiled for the given targets or backends.
+const targetPragma = "elmo:target"
+
+// pragma is the guard of a top-level declaration.
+type pragma struct {
+	tok     *token.Token
+	targets []string
+}
+
+// parsePragma returns the pragma of the given comment, or nil if it is not
+// one. Only block comments can be pragmas.
+func parsePragma(tok *token.Token) *pragma {
+	text := tok.Value
+	if !strings.HasPrefix(text, "{-") || !strings.HasSuffix(text, "-}") || strings.HasPrefix(text, "{-|") {
+		return nil
+	}
+
+	fields := strings.Fields(text[2 : len(text)-2])
+	if len(fields) == 0 || fields[0] != targetPragma {
+		return nil
+	}
+	return &pragma{tok, fields[1:]}
+}
+
+// includes reports whether the declaration guarded by the pragma is
+// compiled for the given target, which it is if the pragma names the target
+// or its backend.
+func (pr *pragma) includes(t target.Target) bool {
+	for _, name := range pr.targets {
+		if name == string(t) || name == t.Backend() {
+			return true
+		}
+	}
+	return false
+}
+
+const errorMsgInvalidPragma = `This target pragma has no targets. It needs the names of the targets or
+backends the declaration after it is compiled for, such as:
+
+    {- elmo:target js -}
+    {- elmo:target go wasm -}`
+
+// checkPragma reports the targets of the pragma that do not exist, or the
+// pragma itself if it has none.
+func checkPragma(p *parser, pr *pragma) {
+	if len(pr.targets) == 0 {
+		p.errorMessage(pr.tok.Offset, errorMsgInvalidPragma)
+		return
+	}
+
+	for _, name := range pr.targets {
+		if _, err := target.Parse(name); err != nil {
+			p.errorMessage(
+				pr.tok.Offset,
+				"I do not know the target %q of this target pragma. The targets are %s.",
+				name, targetNames(),
+			)
+		}
+	}
+}
+
+func targetNames() string {
+	var names = make([]string, len(target.Targets))
+	for i, t := range target.Targets {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
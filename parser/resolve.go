@@ -1,10 +1,15 @@
 package parser
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/crash"
+	"github.com/elm-tangram/tangram/profile"
 	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
 )
 
 type resolver struct {
@@ -12,18 +17,45 @@ type resolver struct {
 	reporter *report.Reporter
 
 	path string
+	mod  *ast.Module
+	// pos is the position of the declaration being resolved.
+	pos token.Pos
+	// natives are the native modules whose declarations are resolved.
+	natives map[*ast.NativeModule]bool
+	// missing are the names that may be unresolved because their module
+	// is not imported, which are fixed once all the modules are resolved.
+	missing []missingImport
+	// profile measures the resolution of every module, if it is not nil.
+	profile *profile.Profile
+	// hooks are called when the resolution of every module ends, if they
+	// are not nil.
+	hooks *Hooks
 }
 
 func (r *resolver) resolve(pkg *ast.Package) bool {
 	r.pkg = pkg
 	var resolved = true
 	for _, m := range pkg.Order {
-		r.path = pkg.Modules[m].Path
-		resolved = r.resolveModule(pkg.Modules[m]) && resolved
+		r.mod = pkg.Modules[m]
+		r.path = r.mod.Path
+		measure := startMeasure(r.profile, r.hooks)
+		resolved = r.resolveModule(r.mod) && resolved
+		measure.Stop(profile.Resolve, m)
 	}
+	r.mod = nil
+	r.fixMissingImports()
 	return resolved
 }
 
+// locate makes the internal error of a panic while resolving a module an
+// error of the resolution of the declaration being resolved.
+func (r *resolver) locate(err error, cm *source.CodeMap) {
+	if e, ok := err.(*crash.Error); ok && r.mod != nil {
+		e.Phase, e.Module, e.Path, e.Pos, e.Line = "resolve", r.mod.Name, r.path, r.pos, 0
+		e.Locate(cm)
+	}
+}
+
 func (r *resolver) resolveModule(mod *ast.Module) bool {
 	mod.Scope = ast.NewModuleScope(mod)
 
@@ -32,11 +64,48 @@ func (r *resolver) resolveModule(mod *ast.Module) bool {
 	}
 
 	for _, decl := range mod.Decls {
+		r.declare(mod.Scope, decl)
+	}
+
+	for _, decl := range mod.Decls {
+		r.pos = decl.Pos()
 		r.resolveDecl(mod.Scope, decl)
 	}
 
 	r.resolveModuleDecl(mod.Scope, mod.Module)
-	return r.checkUnresolved(mod.Scope)
+	resolved := r.resolveNatives(mod)
+	return r.checkUnresolved(mod.Scope) && resolved
+}
+
+// resolveNatives resolves the types of the declarations of the native
+// modules imported by the module, which have not been resolved by other
+// modules, in the scope of the module. The names that can not be resolved
+// are reported in the files of the declarations.
+func (r *resolver) resolveNatives(mod *ast.Module) bool {
+	var resolved = true
+	for _, imp := range mod.Imports {
+		native := r.pkg.Natives[imp.ModuleName()]
+		if native == nil || native.DeclPath == "" || r.natives[native] {
+			continue
+		}
+
+		if r.natives == nil {
+			r.natives = make(map[*ast.NativeModule]bool)
+		}
+		r.natives[native] = true
+
+		scope := &ast.NodeScope{
+			Parent:     mod.Scope,
+			Objects:    make(map[string]*ast.Object),
+			Unresolved: make(map[string][]*ast.Ident),
+		}
+		decls := &resolver{pkg: r.pkg, reporter: r.reporter, path: native.DeclPath}
+		for _, d := range native.Decls {
+			decls.resolveType(scope, d.Type, false)
+		}
+		resolved = decls.checkUnresolvedChildren([]*ast.NodeScope{scope}) && resolved
+	}
+	return resolved
 }
 
 // TODO(erizocosmico): please, split this into smaller functions
@@ -50,42 +119,54 @@ func (r *resolver) resolveImport(scope *ast.ModuleScope, imp *ast.ImportDecl) {
 	obj := ast.NewObject(mod, kind, imp)
 	scope.ImportModule(obj)
 
+	var alias *ast.Object
 	if imp.Alias != nil {
-		alias := *obj
-		alias.Name = imp.Alias.Name
-		scope.ImportModule(&alias)
+		alias = ast.NewObject(imp.Alias.Name, kind, imp)
+		scope.ImportModule(alias)
 	}
 
 	if isNative {
 		if imp.Exposing != nil {
-			r.report(report.NewBaseReport(report.SyntaxError, imp.Exposing.Pos(), "Native modules cannot expose anything.", report.RegionFromNode(imp)))
+			r.report(report.NewBaseReport(report.SyntaxError, report.NodeLocation(r.path, imp.Exposing), "Native modules cannot expose anything.", report.RegionFromNode(imp)))
 		}
 		return
 	}
 
 	importScope := r.pkg.Modules[mod].Scope
 	obj.Node = r.pkg.Modules[mod]
+	if alias != nil {
+		alias.Node = obj.Node
+	}
 	switch exp := imp.Exposing.(type) {
 	case *ast.ClosedList:
 	Outer:
 		for _, id := range exp.Exposed {
 			switch id := id.(type) {
 			case *ast.ExposedVar:
-				if obj := importScope.LookupExposed(id.Name, ast.Var); obj != nil {
+				// the types are exposed without their constructors as well
+				obj := importScope.LookupExposed(id.Name, ast.Var)
+				if obj == nil {
+					obj = importScope.LookupExposed(id.Name, ast.Typ)
+				}
+
+				if obj != nil {
 					switch obj.Kind {
 					case ast.Typ, ast.Var:
 						scope.Import(obj)
 					default:
-						r.report(report.NewImportError(imp, imp.ModuleName(), id.Ident))
+						r.report(report.NewImportError(r.path, imp, imp.ModuleName(), id.Ident))
 					}
 				} else {
-					r.report(report.NewImportError(imp, imp.ModuleName(), id.Ident))
+					r.report(report.NewImportError(
+						r.path, imp, imp.ModuleName(), id.Ident,
+						report.Suggest(id.Name, exposedNames(importScope, ast.Var))...,
+					))
 				}
 			case *ast.ExposedUnion:
 				if obj := importScope.LookupExposed(id.Type.Name, ast.Typ); obj != nil {
 					union, ok := obj.Node.(*ast.UnionDecl)
 					if !ok {
-						r.report(report.NewExpectedUnionError(imp, obj))
+						r.report(report.NewExpectedUnionError(r.path, imp, id.Type, obj))
 						continue Outer
 					}
 
@@ -99,10 +180,13 @@ func (r *resolver) resolveImport(scope *ast.ModuleScope, imp *ast.ImportDecl) {
 									if obj.Kind == ast.Ctor {
 										scope.Import(obj)
 									} else {
-										r.report(report.NewExpectedCtorError(imp, obj))
+										r.report(report.NewExpectedCtorError(r.path, imp, id.Ident, obj))
 									}
 								} else {
-									r.report(report.NewImportError(imp, imp.ModuleName(), id.Ident))
+									r.report(report.NewImportError(
+										r.path, imp, imp.ModuleName(), id.Ident,
+										report.Suggest(id.Name, exposedNames(importScope, ast.Ctor))...,
+									))
 								}
 							default:
 								// unreachable
@@ -117,7 +201,10 @@ func (r *resolver) resolveImport(scope *ast.ModuleScope, imp *ast.ImportDecl) {
 						}
 					}
 				} else {
-					r.report(report.NewImportError(imp, imp.ModuleName(), id.Type))
+					r.report(report.NewImportError(
+						r.path, imp, imp.ModuleName(), id.Type,
+						report.Suggest(id.Type.Name, exposedNames(importScope, ast.Typ))...,
+					))
 				}
 			}
 		}
@@ -131,6 +218,35 @@ func (r *resolver) resolveImport(scope *ast.ModuleScope, imp *ast.ImportDecl) {
 	}
 }
 
+// declare adds the names of the values declared by a definition, port or
+// destructuring assignment to the scope, so they can be used in the
+// declarations before them of a module or let expression. A name that is
+// already declared in the scope is reported.
+func (r *resolver) declare(scope ast.Scope, decl ast.Decl) {
+	var add = func(obj *ast.Object, name *ast.Ident) {
+		if !scope.Add(obj) {
+			r.report(report.NewAlreadyDeclaredError(r.path, decl, name))
+		}
+	}
+
+	switch decl := decl.(type) {
+	case *ast.Definition:
+		add(ast.NewObject(decl.Name.Name, ast.Var, decl.Name), decl.Name)
+	case *ast.PortDecl:
+		add(ast.NewObject(decl.Name.Name, ast.Var, decl.Name), decl.Name)
+	case *ast.DestructuringAssignment:
+		ast.WalkFunc(decl.Pattern, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.VarPattern:
+				add(ast.NewObject(n.Name.Name, ast.Var, n), n.Name)
+			case *ast.AliasPattern:
+				add(ast.NewObject(n.Name.Name, ast.Var, n), n.Name)
+			}
+			return true
+		})
+	}
+}
+
 // TODO: add again VarTyp resolution to decls, a lookup is enough
 // because they must be previously declared
 // TODO: check when adding a new type to the top-level that is not already declared.
@@ -151,14 +267,23 @@ func (r *resolver) resolveDecl(scope ast.Scope, decl ast.Decl) {
 		for _, arg := range decl.Args {
 			r.resolvePattern(defScope, arg)
 		}
+
+		// lazy bodies are resolved once they are parsed
+		if lazy, ok := decl.Body.(*ast.LazyExpr); ok {
+			lazy.Scope = defScope
+			return
+		}
 		r.resolveExpr(defScope, decl.Body)
+	case *ast.PortDecl:
+		r.resolveType(scope, decl.Type, false)
+		scope.Add(ast.NewObject(decl.Name.Name, ast.Var, decl.Name))
 	case *ast.AliasDecl:
 		scope.Add(ast.NewObject(decl.Name.Name, ast.Typ, decl))
 		declScope := ast.NewNodeScope(decl, scope)
 		set := make(map[string]struct{})
 		for _, arg := range decl.Args {
 			if _, ok := set[arg.Name]; ok {
-				r.report(report.NewRepeatedVarTypeError(decl, arg))
+				r.report(report.NewRepeatedVarTypeError(r.path, decl, arg))
 				return
 			}
 			set[arg.Name] = struct{}{}
@@ -171,7 +296,7 @@ func (r *resolver) resolveDecl(scope ast.Scope, decl ast.Decl) {
 		set := make(map[string]struct{})
 		for _, arg := range decl.Args {
 			if _, ok := set[arg.Name]; ok {
-				r.report(report.NewRepeatedVarTypeError(decl, arg))
+				r.report(report.NewRepeatedVarTypeError(r.path, decl, arg))
 				return
 			}
 			set[arg.Name] = struct{}{}
@@ -181,7 +306,7 @@ func (r *resolver) resolveDecl(scope ast.Scope, decl ast.Decl) {
 		set = make(map[string]struct{})
 		for _, ctor := range decl.Ctors {
 			if _, ok := set[ctor.Name.Name]; ok {
-				r.report(report.NewRepeatedCtorError(decl, ctor.Name))
+				r.report(report.NewRepeatedCtorError(r.path, decl, ctor.Name))
 				return
 			}
 			set[ctor.Name.Name] = struct{}{}
@@ -192,6 +317,10 @@ func (r *resolver) resolveDecl(scope ast.Scope, decl ast.Decl) {
 
 // TODO(erizocosmico): please, split this into smaller functions
 func (r *resolver) resolveModuleDecl(scope *ast.ModuleScope, mod *ast.ModuleDecl) {
+	for _, f := range mod.Manager {
+		r.resolveQualifiedName(scope, f.Expr, ast.Typ)
+	}
+
 	switch list := mod.Exposing.(type) {
 	case *ast.OpenList:
 		for _, obj := range scope.Objects {
@@ -210,7 +339,7 @@ func (r *resolver) resolveModuleDecl(scope *ast.ModuleScope, mod *ast.ModuleDecl
 				if obj := r.tryExpose(scope, exposed.Type); obj != nil {
 					union, ok := obj.Node.(*ast.UnionDecl)
 					if !ok {
-						r.report(report.NewExpectedUnionError(mod, obj))
+						r.report(report.NewExpectedUnionError(r.path, mod, exposed.Type, obj))
 						continue Outer
 					}
 
@@ -225,7 +354,7 @@ func (r *resolver) resolveModuleDecl(scope *ast.ModuleScope, mod *ast.ModuleDecl
 								if ctor := union.LookupCtor(v.Name); ctor != nil {
 									r.tryExposeCtor(scope, ctor.Name)
 								} else {
-									r.report(report.NewExportError(mod, v.Ident))
+									r.report(report.NewExportError(r.path, mod, v.Ident))
 								}
 							} else {
 								// unreachable
@@ -253,7 +382,7 @@ func (r *resolver) tryExpose(scope *ast.ModuleScope, ident *ast.Ident) *ast.Obje
 		return obj
 	}
 
-	r.report(report.NewExportError(scope.Root.(*ast.Module).Module, ident))
+	r.report(report.NewExportError(r.path, scope.Root.(*ast.Module).Module, ident))
 	return nil
 }
 
@@ -263,7 +392,7 @@ func (r *resolver) tryExposeCtor(scope *ast.ModuleScope, ident *ast.Ident) *ast.
 		return obj
 	}
 
-	r.report(report.NewExportError(scope.Root.(*ast.Module).Module, ident))
+	r.report(report.NewExportError(r.path, scope.Root.(*ast.Module).Module, ident))
 	return nil
 }
 
@@ -294,6 +423,9 @@ func (r *resolver) resolveExpr(scope ast.Scope, expr ast.Expr) {
 		}
 	case *ast.LetExpr:
 		letScope := ast.NewNodeScope(expr, scope)
+		for _, d := range expr.Decls {
+			r.declare(letScope, d)
+		}
 		for _, d := range expr.Decls {
 			r.resolveDecl(letScope, d)
 		}
@@ -315,7 +447,7 @@ func (r *resolver) resolveExpr(scope ast.Scope, expr ast.Expr) {
 		var set = make(map[string]struct{})
 		for _, f := range expr.Fields {
 			if _, ok := set[f.Field.Name]; ok {
-				r.report(report.NewRepeatedFieldError(expr, f.Field))
+				r.report(report.NewRepeatedFieldError(r.path, expr, f.Field))
 				return
 			}
 			set[f.Field.Name] = struct{}{}
@@ -326,7 +458,7 @@ func (r *resolver) resolveExpr(scope ast.Scope, expr ast.Expr) {
 		var set = make(map[string]struct{})
 		for _, f := range expr.Fields {
 			if _, ok := set[f.Field.Name]; ok {
-				r.report(report.NewRepeatedFieldError(expr, f.Field))
+				r.report(report.NewRepeatedFieldError(r.path, expr, f.Field))
 				return
 			}
 			set[f.Field.Name] = struct{}{}
@@ -355,10 +487,14 @@ func (r *resolver) resolveExpr(scope ast.Scope, expr ast.Expr) {
 func (r *resolver) resolvePattern(scope ast.Scope, pattern ast.Pattern) {
 	switch pattern := pattern.(type) {
 	case *ast.AliasPattern:
-		scope.Add(ast.NewObject(pattern.Name.Name, ast.Var, pattern.Pattern))
+		scope.Add(ast.NewObject(pattern.Name.Name, ast.Var, pattern))
 		r.resolvePattern(scope, pattern.Pattern)
 	case *ast.CtorPattern:
-		r.resolveQualifiedName(scope, pattern.Ctor, ast.Var)
+		// True and False are not constructors, but they are parsed as
+		// constructor patterns
+		if id, ok := pattern.Ctor.(*ast.Ident); !ok || (id.Name != "True" && id.Name != "False") {
+			r.resolveQualifiedName(scope, pattern.Ctor, ast.Var)
+		}
 		for _, p := range pattern.Args {
 			r.resolvePattern(scope, p)
 		}
@@ -395,7 +531,7 @@ func (r *resolver) resolveType(scope ast.Scope, typ ast.Type, resolveVars bool)
 			if obj := scope.Lookup(typ.Name, ast.VarTyp); obj != nil {
 				typ.Obj = obj
 			} else {
-				r.report(report.NewUndefinedTypeVarError(typ, typ))
+				r.report(report.NewUndefinedTypeVarError(r.path, typ, typ))
 			}
 		}
 	case *ast.FuncType:
@@ -407,7 +543,7 @@ func (r *resolver) resolveType(scope ast.Scope, typ ast.Type, resolveVars bool)
 		var idents = make(map[string]struct{})
 		for _, f := range typ.Fields {
 			if _, ok := idents[f.Name.Name]; ok {
-				r.report(report.NewRepeatedFieldError(typ, f.Name))
+				r.report(report.NewRepeatedFieldError(r.path, typ, f.Name))
 				return
 			}
 			idents[f.Name.Name] = struct{}{}
@@ -476,7 +612,9 @@ func (r *resolver) resolveQualifiedName(scope ast.Scope, expr ast.Expr, kind ast
 
 			scope = obj.Node.(*ast.Module).Scope
 		} else {
-			r.report(report.NewModuleNotImportedError(expr, modName))
+			err := report.NewModuleNotImportedError(r.path, expr, modName)
+			r.missing = append(r.missing, missingImport{r.mod, &err.FixList, modName, varIdent.Name, kind})
+			r.report(err)
 			return
 		}
 	}
@@ -485,7 +623,11 @@ func (r *resolver) resolveQualifiedName(scope ast.Scope, expr ast.Expr, kind ast
 		varIdent.Obj = obj
 	} else {
 		if len(path) > 0 {
-			r.report(report.NewImportError(expr, modName, varIdent))
+			var suggestions []string
+			if modScope, ok := scope.(*ast.ModuleScope); ok {
+				suggestions = report.Suggest(varIdent.Name, exposedNames(modScope, kind))
+			}
+			r.report(report.NewImportError(r.path, expr, modName, varIdent, suggestions...))
 		} else {
 			scope.Resolve(varIdent.Name, varIdent, kind)
 		}
@@ -496,7 +638,7 @@ func (r *resolver) checkUnresolved(scope *ast.ModuleScope) bool {
 	var resolved = true
 	r.resolveBasicTypes(scope.Unresolved)
 	if len(scope.Unresolved) > 0 {
-		r.reportUnresolved(scope.Unresolved)
+		r.reportUnresolved(scope, scope.Unresolved)
 		resolved = false
 	}
 
@@ -512,14 +654,18 @@ var basicTypes = map[string]*ast.Object{
 	"List":   ast.NewObject("List", ast.BuiltinTyp, nil),
 }
 
+// checkUnresolvedChildren reports the unresolved names of the given scopes
+// and the ones nested in them, such as the ones of let expressions, case
+// branches and lambdas.
 func (r *resolver) checkUnresolvedChildren(scopes []*ast.NodeScope) bool {
 	var resolved = true
 	for _, scope := range scopes {
 		r.resolveBasicTypes(scope.Unresolved)
 		if len(scope.Unresolved) > 0 {
-			r.reportUnresolved(scope.Unresolved)
+			r.reportUnresolved(scope, scope.Unresolved)
 			resolved = false
 		}
+		resolved = r.checkUnresolvedChildren(scope.Children()) && resolved
 	}
 
 	return resolved
@@ -536,16 +682,68 @@ func (r *resolver) resolveBasicTypes(unresolved map[string][]*ast.Ident) {
 	}
 }
 
-func (r *resolver) reportUnresolved(unresolved map[string][]*ast.Ident) {
+func (r *resolver) reportUnresolved(scope ast.Scope, unresolved map[string][]*ast.Ident) {
 	for name, idents := range unresolved {
+		suggestions := report.Suggest(name, visibleNames(scope, name))
 		for _, ident := range idents {
-			r.report(report.NewUnresolvedNameError(name, ident))
+			err := report.NewUnresolvedNameError(r.path, name, ident, suggestions...)
+			r.missing = append(r.missing, missingImport{mod: r.mod, fixes: &err.FixList, name: name})
+			r.report(err)
+		}
+	}
+}
+
+// visibleNames returns the names of all the objects visible from the given
+// scope that could be used in place of the given name, that is, objects
+// whose name has the same case.
+func visibleNames(scope ast.Scope, name string) []string {
+	var names []string
+	var upper = isUpper(name)
+	var add = func(objs map[string]*ast.Object) {
+		for n, obj := range objs {
+			if obj.Kind != ast.Mod && obj.Kind != ast.NativeMod && isUpper(n) == upper {
+				names = append(names, n)
+			}
+		}
+	}
+
+	for scope != nil {
+		switch s := scope.(type) {
+		case *ast.ModuleScope:
+			add(s.Objects)
+			add(s.Imported)
+			scope = s.Parent
+		case *ast.NodeScope:
+			add(s.Objects)
+			scope = s.Parent
+		default:
+			scope = nil
+		}
+	}
+
+	if upper {
+		add(basicTypes)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// exposedNames returns the names of all the objects of the given kind
+// exposed by a module.
+func exposedNames(scope *ast.ModuleScope, kind ast.ObjKind) []string {
+	var names []string
+	for n, obj := range scope.Exposed {
+		if obj.Kind == kind {
+			names = append(names, n)
 		}
 	}
+	sort.Strings(names)
+	return names
 }
 
 func (r *resolver) report(report report.Report) {
-	r.reporter.Report(r.path, report)
+	r.reporter.Report(report)
 }
 
 func isNativeImport(module string) bool {
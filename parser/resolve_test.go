@@ -577,6 +577,42 @@ func TestResolveQualifiedName(t *testing.T) {
 	})
 }
 
+func TestUnresolvedSuggestions(t *testing.T) {
+	require := require.New(t)
+	r := newTestResolver(t)
+
+	scope := modScopeWithObjects(
+		ast.NewObject("maybeStr", ast.Var, nil),
+		ast.NewObject("Just", ast.Ctor, nil),
+	)
+	scope.Import(ast.NewObject("withDefault", ast.Var, nil))
+	child := ast.NewNodeScope(nil, scope)
+	child.Add(ast.NewObject("maybeStrs", ast.Var, nil))
+	child.Add(ast.NewObject("x", ast.Var, nil))
+
+	r.resolveExpr(child, ast.NewIdent("maybeSt", token.NoPos))
+	r.resolveExpr(child, ast.NewIdent("withDefualt", token.NoPos))
+	r.resolveExpr(scope, ast.NewIdent("Jsut", token.NoPos))
+	r.resolveExpr(scope, ast.NewIdent("Strnig", token.NoPos))
+	r.resolveExpr(child, ast.NewIdent("*", token.NoPos))
+	require.False(r.checkUnresolved(scope))
+
+	var suggestions = make(map[string][]string)
+	for _, rep := range r.reporter.Reports("test") {
+		e, ok := rep.(*report.UnresolvedNameError)
+		require.True(ok, "expected UnresolvedNameError, got %T", rep)
+		suggestions[e.Name] = e.Suggestions
+	}
+
+	require.Equal(map[string][]string{
+		"maybeSt":     {"maybeStr", "maybeStrs"},
+		"withDefualt": {"withDefault"},
+		"Jsut":        {"Just"},
+		"Strnig":      {"String"},
+		"*":           nil,
+	}, suggestions)
+}
+
 func TestResolveExpr(t *testing.T) {
 	r := newTestResolver(t)
 	newScope := func() *ast.NodeScope {
@@ -1414,5 +1450,5 @@ func newTestResolver(t *testing.T) *resolver {
 	cm := source.NewCodeMap(loader)
 	require.NoError(t, cm.Add(path), "adding %s", path)
 	reporter := report.NewReporter(cm, report.Stderr(true, true))
-	return &resolver{nil, reporter, path}
+	return &resolver{reporter: reporter, path: path}
 }
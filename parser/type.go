@@ -10,7 +10,7 @@ func parseTypeList(p *parser) (types []ast.Type) {
 		var typ ast.Type
 		switch p.tok.Type {
 		case token.LeftParen, token.LeftBrace:
-			typ = parseType(p)
+			typ = parseAtomType(p)
 		case token.Identifier:
 			ident := parseQualifiedIdentifier(p)
 			if name, ok := ident.(*ast.Ident); ok && isLower(name.Name) {
@@ -68,6 +68,12 @@ func parseAtomType(p *parser) ast.Type {
 	switch p.tok.Type {
 	case token.LeftParen:
 		lparenPos := p.expect(token.LeftParen)
+
+		// is the unit type
+		if p.is(token.RightParen) {
+			return &ast.TupleType{Lparen: lparenPos, Rparen: p.expect(token.RightParen)}
+		}
+
 		typ := p.expectType()
 
 		// is a tuple
@@ -102,7 +108,8 @@ func parseAtomType(p *parser) ast.Type {
 		return parseRecordType(p)
 	default:
 		p.errorExpectedOneOf(p.tok, token.LeftParen, token.LeftBrace, token.Identifier)
-		panic(bailout{})
+		p.stop()
+		return nil
 	}
 }
 
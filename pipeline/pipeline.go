@@ -0,0 +1,233 @@
+// Package pipeline lets programs plug their own analyses and transforms
+// into the compilation of a package without forking the compiler. A pass
+// runs at a stage of the compilation, after type checking or after
+// lowering, and it may use the results of the passes it requires, which
+// run before it.
+//
+// Passes are usually registered by the packages that implement them when
+// they are imported, so a program that imports them and runs the commands
+// of elmo compiles with them:
+//
+//	func init() {
+//		pipeline.Register(myPass{})
+//	}
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/ir"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Stage is the point of the compilation where a pass runs.
+type Stage byte
+
+const (
+	// Checked is the stage after type checking, when the unit has the
+	// syntax tree and the types of the package.
+	Checked Stage = iota
+	// Lowered is the stage after the package has been lowered and
+	// optimized, right before generating its code, when the unit has its
+	// core IR too. Passes of this stage may transform the IR.
+	Lowered
+)
+
+func (s Stage) String() string {
+	switch s {
+	case Checked:
+		return "checked"
+	case Lowered:
+		return "lowered"
+	}
+	return fmt.Sprintf("stage(%d)", s)
+}
+
+// Pass is an analysis or a transform of a package.
+type Pass interface {
+	// Name of the pass, which is unique among the passes of a pipeline.
+	Name() string
+	// Stage at which the pass runs.
+	Stage() Stage
+	// Requires are the names of the passes whose results the pass uses.
+	// They must run at the same stage or before.
+	Requires() []string
+	// Run runs the pass on the unit and returns its result, which the
+	// passes that require it get with Unit.Result. It reports the problems
+	// it finds in the code with Unit.Report, and it only returns an error
+	// if it could not run.
+	Run(u *Unit) (interface{}, error)
+}
+
+// Unit is the package being compiled, which the passes run on.
+type Unit struct {
+	// AST is the package, resolved.
+	AST *ast.Package
+	// Info is the type information of the package.
+	Info *types.Info
+	// IR is the package lowered to the core IR, which is nil before the
+	// Lowered stage.
+	IR *ir.Package
+	// CodeMap has the source of the files of the package, and the problems
+	// found by the passes are reported to Reporter.
+	CodeMap  *source.CodeMap
+	Reporter *report.Reporter
+
+	results map[string]interface{}
+}
+
+// Result returns the result of the pass with the given name, which must
+// have run already, as the passes that are required do.
+func (u *Unit) Result(name string) (interface{}, bool) {
+	r, ok := u.results[name]
+	return r, ok
+}
+
+// Report reports a problem in the file of its location.
+func (u *Unit) Report(r report.Report) error {
+	path := r.Location().File
+	if err := u.CodeMap.Add(path); err != nil {
+		return fmt.Errorf("pipeline: can't read %s: %s", path, err)
+	}
+	u.Reporter.Report(r)
+	return nil
+}
+
+// Pipeline is a set of passes in the order they run, so that every pass
+// runs after the passes it requires.
+type Pipeline struct {
+	passes []Pass
+}
+
+// New returns the pipeline of the given passes. It fails if any of them
+// requires a pass that is not among them or runs at a later stage, or if
+// their requirements have a cycle.
+func New(passes ...Pass) (*Pipeline, error) {
+	var byName = make(map[string]Pass)
+	var names []string
+	for _, p := range passes {
+		if _, ok := byName[p.Name()]; ok {
+			return nil, fmt.Errorf("pipeline: there are two passes named %s", p.Name())
+		}
+		byName[p.Name()] = p
+		names = append(names, p.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := byName[name]
+		for _, r := range p.Requires() {
+			required, ok := byName[r]
+			if !ok {
+				return nil, fmt.Errorf("pipeline: pass %s requires %s, which is not in the pipeline", name, r)
+			}
+
+			if required.Stage() > p.Stage() {
+				return nil, fmt.Errorf("pipeline: pass %s requires %s, which runs at a later stage", name, r)
+			}
+		}
+	}
+
+	var pl Pipeline
+	var visiting = make(map[string]bool)
+	var done = make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if done[name] {
+			return nil
+		}
+
+		if visiting[name] {
+			return fmt.Errorf("pipeline: pass %s requires itself", name)
+		}
+
+		visiting[name] = true
+		p := byName[name]
+		for _, r := range p.Requires() {
+			if err := visit(r); err != nil {
+				return err
+			}
+		}
+		done[name] = true
+		pl.passes = append(pl.passes, p)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(pl.passes, func(i, j int) bool {
+		return pl.passes[i].Stage() < pl.passes[j].Stage()
+	})
+	return &pl, nil
+}
+
+// Passes returns the passes of the pipeline in the order they run.
+func (pl *Pipeline) Passes() []Pass {
+	return pl.passes
+}
+
+// Run runs the passes of the given stage on the unit, in order. It stops
+// at the first pass that fails.
+func (pl *Pipeline) Run(u *Unit, stage Stage) error {
+	if u.results == nil {
+		u.results = make(map[string]interface{})
+	}
+
+	for _, p := range pl.passes {
+		if p.Stage() != stage {
+			continue
+		}
+
+		result, err := p.Run(u)
+		if err != nil {
+			return fmt.Errorf("pipeline: pass %s failed: %s", p.Name(), err)
+		}
+		u.results[p.Name()] = result
+	}
+	return nil
+}
+
+var (
+	mu         sync.Mutex
+	registered = make(map[string]Pass)
+)
+
+// Register registers a pass, so the pipeline returned by Default runs it.
+// It panics if a pass with the same name was registered already.
+func Register(p Pass) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registered[p.Name()]; ok {
+		panic(fmt.Sprintf("pipeline: pass %s is registered twice", p.Name()))
+	}
+	registered[p.Name()] = p
+}
+
+// Registered returns the registered passes sorted by name.
+func Registered() []Pass {
+	mu.Lock()
+	defer mu.Unlock()
+	var passes = make([]Pass, 0, len(registered))
+	for _, p := range registered {
+		passes = append(passes, p)
+	}
+
+	sort.Slice(passes, func(i, j int) bool {
+		return passes[i].Name() < passes[j].Name()
+	})
+	return passes
+}
+
+// Default returns the pipeline of the registered passes.
+func Default() (*Pipeline, error) {
+	return New(Registered()...)
+}
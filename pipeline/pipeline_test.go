@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPass struct {
+	name     string
+	stage    Stage
+	requires []string
+	run      func(u *Unit) (interface{}, error)
+}
+
+func (p *testPass) Name() string       { return p.name }
+func (p *testPass) Stage() Stage       { return p.stage }
+func (p *testPass) Requires() []string { return p.requires }
+
+func (p *testPass) Run(u *Unit) (interface{}, error) {
+	if p.run == nil {
+		return p.name, nil
+	}
+	return p.run(u)
+}
+
+func names(pl *Pipeline) []string {
+	var names []string
+	for _, p := range pl.Passes() {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+func TestNew(t *testing.T) {
+	require := require.New(t)
+
+	pl, err := New(
+		&testPass{name: "size", stage: Lowered, requires: []string{"exports"}},
+		&testPass{name: "exports", requires: []string{"uses"}},
+		&testPass{name: "uses"},
+		&testPass{name: "a", stage: Lowered},
+	)
+	require.NoError(err)
+	require.Equal([]string{"uses", "exports", "a", "size"}, names(pl))
+
+	cases := []struct {
+		passes []Pass
+		err    string
+	}{
+		{
+			[]Pass{&testPass{name: "a"}, &testPass{name: "a"}},
+			"pipeline: there are two passes named a",
+		},
+		{
+			[]Pass{&testPass{name: "a", requires: []string{"b"}}},
+			"pipeline: pass a requires b, which is not in the pipeline",
+		},
+		{
+			[]Pass{&testPass{name: "a", requires: []string{"b"}}, &testPass{name: "b", stage: Lowered}},
+			"pipeline: pass a requires b, which runs at a later stage",
+		},
+		{
+			[]Pass{&testPass{name: "a", requires: []string{"b"}}, &testPass{name: "b", requires: []string{"a"}}},
+			"pipeline: pass a requires itself",
+		},
+	}
+
+	for _, c := range cases {
+		_, err := New(c.passes...)
+		require.EqualError(err, c.err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	require := require.New(t)
+
+	var ran []string
+	record := func(name string, required ...string) func(u *Unit) (interface{}, error) {
+		return func(u *Unit) (interface{}, error) {
+			for _, r := range required {
+				result, ok := u.Result(r)
+				require.True(ok)
+				name += "(" + result.(string) + ")"
+			}
+			ran = append(ran, name)
+			return name, nil
+		}
+	}
+
+	pl, err := New(
+		&testPass{name: "uses", run: record("uses")},
+		&testPass{name: "exports", requires: []string{"uses"}, run: record("exports", "uses")},
+		&testPass{name: "size", stage: Lowered, requires: []string{"exports"}, run: record("size", "exports")},
+	)
+	require.NoError(err)
+
+	var u Unit
+	require.NoError(pl.Run(&u, Checked))
+	require.Equal([]string{"uses", "exports(uses)"}, ran)
+
+	require.NoError(pl.Run(&u, Lowered))
+	require.Equal([]string{"uses", "exports(uses)", "size(exports(uses))"}, ran)
+
+	pl, err = New(&testPass{name: "broken", run: func(*Unit) (interface{}, error) {
+		return nil, fmt.Errorf("no module")
+	}})
+	require.NoError(err)
+	require.EqualError(pl.Run(new(Unit), Checked), "pipeline: pass broken failed: no module")
+}
+
+func TestRegister(t *testing.T) {
+	require := require.New(t)
+	defer func() { registered = make(map[string]Pass) }()
+
+	Register(&testPass{name: "b"})
+	Register(&testPass{name: "a"})
+	require.Panics(func() { Register(&testPass{name: "a"}) })
+
+	pl, err := Default()
+	require.NoError(err)
+	require.Equal([]string{"a", "b"}, names(pl))
+}
@@ -0,0 +1,64 @@
+package profile
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Kinds of the events of the compilation, besides the phases, which are
+// the events of the measures of every phase.
+const (
+	// ModuleEvent is the event of a module whose file is being compiled.
+	ModuleEvent = "module"
+	// CacheEvent is the event of a module whose compiled code was looked
+	// up in a cache, with whether it was found or not.
+	CacheEvent = "cache"
+	// OutputEvent is the event of the code generated for a program or a
+	// module, with its size in bytes.
+	OutputEvent = "output"
+)
+
+// Event is something that happened during the compilation. In JSON, it is
+// an object with its data and "event", "module" and "time" fields, and the
+// module is left out if it is Program.
+type Event struct {
+	Kind   string
+	Module string
+	Time   time.Time
+	Data   map[string]interface{}
+}
+
+// MarshalJSON encodes the event as an object.
+func (e Event) MarshalJSON() ([]byte, error) {
+	var fields = make(map[string]interface{}, len(e.Data)+3)
+	for k, v := range e.Data {
+		fields[k] = v
+	}
+
+	fields["event"] = e.Kind
+	fields["time"] = e.Time.Format(time.RFC3339Nano)
+	if e.Module != Program {
+		fields["module"] = e.Module
+	}
+	return json.Marshal(fields)
+}
+
+// WriteEvents makes the profile write the events of the compilation to w
+// as JSON, one per line, as they happen, so the tools that build programs
+// can follow the work of the compiler. Every measure of a phase is an
+// event, with its duration in nanoseconds and the bytes allocated.
+func (p *Profile) WriteEvents(w io.Writer) {
+	p.events = json.NewEncoder(w)
+	p.events.SetEscapeHTML(false)
+}
+
+// Event writes an event of the given kind about the module, if the
+// profile writes events. The errors writing the events are ignored, so
+// they never stop the compilation.
+func (p *Profile) Event(kind, module string, data map[string]interface{}) {
+	if p == nil || p.events == nil {
+		return
+	}
+	p.events.Encode(Event{Kind: kind, Module: module, Time: time.Now(), Data: data})
+}
@@ -0,0 +1,194 @@
+// Package profile measures the time spent and the memory allocated in every
+// phase of the compilation of every module, so that the slow parts of a
+// build can be found and reported. A nil *Profile measures nothing, so the
+// phases can always be measured, no matter if the profile is enabled.
+// A profile can also write the events of the compilation as they happen,
+// such as the measures and the hits of the caches.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// Phase is a phase of the compilation.
+type Phase string
+
+const (
+	// Load is the reading of the source files.
+	Load Phase = "load"
+	// Scan is the scanning of the source files into tokens.
+	Scan Phase = "scan"
+	// Parse is the parsing of the tokens into the AST of the modules.
+	Parse Phase = "parse"
+	// Resolve is the resolution of the identifiers of the modules.
+	Resolve Phase = "resolve"
+	// Check is the type checking of the modules.
+	Check Phase = "check"
+	// Codegen is the generation of the code of the program.
+	Codegen Phase = "codegen"
+)
+
+// Phases are all the phases, in the order they happen.
+var Phases = []Phase{Load, Scan, Parse, Resolve, Check, Codegen}
+
+// Program is the module name of the measures of the phases that work on the
+// whole program instead of on a single module.
+const Program = ""
+
+// Entry is the time spent and the memory allocated in a phase of a module.
+type Entry struct {
+	Phase  Phase  `json:"phase"`
+	Module string `json:"module"`
+	// Duration is the time spent, in nanoseconds in JSON.
+	Duration time.Duration `json:"duration"`
+	// Alloc is the number of bytes allocated.
+	Alloc uint64 `json:"alloc"`
+}
+
+// Profile keeps the measures of the phases of every module. The measures
+// of the same phase and module are added up. It is not safe for concurrent
+// use.
+type Profile struct {
+	entries []Entry
+	// index is the position of each entry by its phase and module.
+	index map[key]int
+	// events is where the events are written, if anywhere.
+	events *json.Encoder
+}
+
+type key struct {
+	phase  Phase
+	module string
+}
+
+// New creates an empty profile.
+func New() *Profile {
+	return &Profile{index: make(map[key]int)}
+}
+
+// Measure is a measure that has started and has not been stopped yet.
+type Measure struct {
+	p     *Profile
+	start time.Time
+	alloc uint64
+}
+
+// Start starts a measure, which is stopped with the phase and module it
+// measured.
+func (p *Profile) Start() Measure {
+	if p == nil {
+		return Measure{}
+	}
+	return Measure{p, time.Now(), totalAlloc()}
+}
+
+// Stop stops the measure and adds it to the given phase of the module.
+func (m Measure) Stop(phase Phase, module string) {
+	m.End().Add(phase, module)
+}
+
+// End stops the measure without adding it to the profile yet, for the
+// phases whose module is not known until later.
+func (m Measure) End() Sample {
+	if m.p == nil {
+		return Sample{}
+	}
+	return Sample{m.p, time.Since(m.start), totalAlloc() - m.alloc}
+}
+
+// Sample is a measure that has been stopped and has not been added to the
+// profile yet.
+type Sample struct {
+	p        *Profile
+	duration time.Duration
+	alloc    uint64
+}
+
+// Add adds the sample to the given phase of the module.
+func (s Sample) Add(phase Phase, module string) {
+	if s.p == nil {
+		return
+	}
+
+	k := key{phase, module}
+	i, ok := s.p.index[k]
+	if !ok {
+		i = len(s.p.entries)
+		s.p.index[k] = i
+		s.p.entries = append(s.p.entries, Entry{Phase: phase, Module: module})
+	}
+	s.p.entries[i].Duration += s.duration
+	s.p.entries[i].Alloc += s.alloc
+	s.p.Event(string(phase), module, map[string]interface{}{
+		"duration": s.duration,
+		"alloc":    s.alloc,
+	})
+}
+
+// Entries returns the measures of every phase, in the order of the phases
+// and then in the order their modules were first measured.
+func (p *Profile) Entries() []Entry {
+	if p == nil {
+		return nil
+	}
+
+	var result = make([]Entry, 0, len(p.entries))
+	for _, phase := range Phases {
+		for _, e := range p.entries {
+			if e.Phase == phase {
+				result = append(result, e)
+			}
+		}
+	}
+	return result
+}
+
+// Write writes a table with the measures of every phase and the totals of
+// each of them.
+func (p *Profile) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PHASE\tMODULE\tTIME\tMEMORY")
+
+	var total Entry
+	var entries = p.Entries()
+	for i, e := range entries {
+		module := e.Module
+		if module == Program {
+			module = "(program)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Phase, module, formatDuration(e.Duration), formatBytes(e.Alloc))
+
+		total.Duration += e.Duration
+		total.Alloc += e.Alloc
+		if i == len(entries)-1 || entries[i+1].Phase != e.Phase {
+			fmt.Fprintf(tw, "%s\t(total)\t%s\t%s\n", e.Phase, formatDuration(total.Duration), formatBytes(total.Alloc))
+			total = Entry{}
+		}
+	}
+	return tw.Flush()
+}
+
+func totalAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.TotalAlloc
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,89 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sink keeps the allocations of the tests from being optimized away.
+var sink []byte
+
+func TestProfile(t *testing.T) {
+	require := require.New(t)
+	p := New()
+
+	p.Start().Stop(Check, "Main")
+	p.Start().Stop(Parse, "Main")
+	p.Start().Stop(Parse, "Basics")
+	m := p.Start()
+	sink = make([]byte, 1<<16)
+	m.Stop(Parse, "Main")
+	p.Start().Stop(Codegen, Program)
+
+	var phases, modules []string
+	for _, e := range p.Entries() {
+		phases = append(phases, string(e.Phase))
+		modules = append(modules, e.Module)
+	}
+	require.Equal([]string{"parse", "parse", "check", "codegen"}, phases)
+	require.Equal([]string{"Main", "Basics", "Main", ""}, modules)
+	require.True(p.Entries()[0].Alloc >= 1<<16)
+
+	var buf bytes.Buffer
+	require.NoError(p.Write(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(lines, 8)
+	require.Equal([]string{"PHASE", "MODULE", "TIME", "MEMORY"}, strings.Fields(lines[0]))
+	require.Equal([]string{"parse", "Main"}, strings.Fields(lines[1])[:2])
+	require.Equal([]string{"parse", "(total)"}, strings.Fields(lines[3])[:2])
+	require.Equal([]string{"codegen", "(program)"}, strings.Fields(lines[6])[:2])
+}
+
+func TestNilProfile(t *testing.T) {
+	var p *Profile
+	p.Start().Stop(Parse, "Main")
+	require.Nil(t, p.Entries())
+}
+
+func TestFormatBytes(t *testing.T) {
+	require := require.New(t)
+	require.Equal("512 B", formatBytes(512))
+	require.Equal("1.5 KB", formatBytes(1536))
+	require.Equal("2.0 MB", formatBytes(2<<20))
+}
+
+func TestEvents(t *testing.T) {
+	require := require.New(t)
+	p := New()
+
+	var buf bytes.Buffer
+	p.WriteEvents(&buf)
+	p.Event(ModuleEvent, "Main", map[string]interface{}{"path": "src/Main.elm"})
+	p.Start().Stop(Parse, "Main")
+	p.Event(OutputEvent, Program, map[string]interface{}{"bytes": 42})
+
+	var events []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e map[string]interface{}
+		require.NoError(dec.Decode(&e))
+		require.Contains(e, "time")
+		delete(e, "time")
+		events = append(events, e)
+	}
+
+	require.Len(events, 3)
+	require.Equal(map[string]interface{}{"event": "module", "module": "Main", "path": "src/Main.elm"}, events[0])
+	require.Equal("parse", events[1]["event"])
+	require.Equal("Main", events[1]["module"])
+	require.Contains(events[1], "duration")
+	require.Contains(events[1], "alloc")
+	require.Equal(map[string]interface{}{"event": "output", "bytes": float64(42)}, events[2])
+
+	var nilProfile *Profile
+	nilProfile.Event(ModuleEvent, "Main", nil)
+}
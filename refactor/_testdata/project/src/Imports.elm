@@ -0,0 +1,11 @@
+module Imports exposing (main)
+
+import Util exposing (width, identity, identity)
+import Extra
+import Util exposing (Shape(..))
+import Extra as E exposing (one)
+
+
+main : Int
+main =
+    Util.width (Square (identity E.one))
@@ -0,0 +1,12 @@
+module Main exposing (main)
+
+import Util exposing (Shape(Square), identity)
+
+
+main : Int
+main =
+    let
+        size =
+            identity 2
+    in
+        Util.width (Square size)
@@ -0,0 +1,33 @@
+module Util exposing (Shape(..), identity, width)
+
+{-| Utilities for the tests.
+
+@docs Shape, identity, width
+
+-}
+
+
+{-| A shape.
+-}
+type Shape
+    = Square Int
+    | Rect Int Int
+
+
+{-| Returns its argument.
+-}
+identity : a -> a
+identity x =
+    x
+
+
+{-| The width of a shape.
+-}
+width : Shape -> Int
+width shape =
+    case shape of
+        Square side ->
+            identity side
+
+        Rect w _ ->
+            w
@@ -0,0 +1,386 @@
+package refactor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// indentation is the indentation of the blocks of the code the refactorings
+// write, such as the body of a definition.
+const indentation = "    "
+
+// ExtractFunction returns the edits that move the expression between pos
+// and end of the module, whose source code is src, to a new top-level
+// function with the given name, defined before the declaration that has
+// the expression and its doc comment, because the top-level names are
+// resolved in the order they are declared. The expression is replaced by a call to the function, whose
+// arguments are the local names the expression uses that are defined out
+// of it, in the order they are first used. It fails if the selection is
+// not an expression of a top-level definition or if the name is already
+// visible from it.
+func ExtractFunction(mod *ast.Module, src []byte, pos, end token.Pos, name string) ([]Edit, error) {
+	x, err := newExtraction(mod, src, pos, end, name)
+	if err != nil {
+		return nil, err
+	}
+
+	decl, ok := x.path[0].(*ast.Definition)
+	if !ok {
+		return nil, fmt.Errorf("refactor: the selection is not in a top-level definition")
+	}
+
+	var call, def = []string{name}, []string{name}
+	for _, id := range x.freeVars() {
+		call = append(call, id.Name)
+		def = append(def, id.Name)
+	}
+
+	pos, end, replacement := x.pos, x.end, strings.Join(call, " ")
+	if len(call) > 1 && x.needsParens() {
+		replacement = "(" + replacement + ")"
+	} else if len(call) == 1 {
+		pos, end = x.unwrapped()
+	}
+
+	body := indentation + reindent(x.text(x.pos, x.end), x.column(x.pos), indentation)
+	declPos := x.declStart(decl)
+	return []Edit{
+		{Path: mod.Path, Pos: declPos, End: declPos, Text: strings.Join(def, " ") + " =\n" + body + "\n\n\n"},
+		{Path: mod.Path, Pos: pos, End: end, Text: replacement},
+	}, nil
+}
+
+// ExtractLet returns the edits that move the expression between pos and
+// end of the module, whose source code is src, to a let binding with the
+// given name, which replaces the expression. The binding is added to the
+// body of the definition, function or case branch closest to the
+// expression, so every local name it uses is still visible, or to the
+// declarations of the let expression if it is the closest. It fails if
+// the selection is not an expression or if the name is already visible
+// from it.
+func ExtractLet(mod *ast.Module, src []byte, pos, end token.Pos, name string) ([]Edit, error) {
+	x, err := newExtraction(mod, src, pos, end, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var body ast.Expr
+	var let *ast.LetExpr
+	for i := len(x.path) - 2; i >= 0 && body == nil; i-- {
+		switch n := x.path[i].(type) {
+		case *ast.Definition:
+			body = n.Body
+		case *ast.Lambda:
+			body = n.Expr
+		case *ast.CaseBranch:
+			body = n.Expr
+		case *ast.LetExpr:
+			body, let = n.Body, n
+		}
+	}
+	if body == nil {
+		return nil, fmt.Errorf("refactor: the selection is not in a definition")
+	}
+
+	pos, end = x.unwrapped()
+	if let != nil {
+		col := x.column(let.Decls[0].Pos())
+		indent := strings.Repeat(" ", col)
+		last := nodeEnd(let.Decls[len(let.Decls)-1])
+		binding := "\n\n" + indent + name + " =\n" + x.binding(indent+indentation)
+		return []Edit{
+			{Path: mod.Path, Pos: last, End: last, Text: binding},
+			{Path: mod.Path, Pos: pos, End: end, Text: name},
+		}, nil
+	}
+
+	// the let is written where the body is if it starts its line, or in
+	// the next line, indented, if it does not
+	start, bodyEnd := body.Pos(), nodeEnd(body)
+	col := x.column(start)
+	var buf bytes.Buffer
+	if lineStart := start - token.Pos(col); strings.TrimSpace(x.text(lineStart, start)) != "" {
+		col = x.indentation(start) + len(indentation)
+		for start > lineStart && src[start-1] == ' ' {
+			start--
+		}
+		buf.WriteString("\n" + strings.Repeat(" ", col))
+	}
+
+	indent := strings.Repeat(" ", col)
+	rest := x.text(body.Pos(), pos) + name + x.text(end, bodyEnd)
+	buf.WriteString("let\n")
+	buf.WriteString(indent + indentation + name + " =\n")
+	buf.WriteString(x.binding(indent+indentation+indentation) + "\n")
+	buf.WriteString(indent + "in\n")
+	buf.WriteString(indent + indentation + reindent(rest, x.column(body.Pos()), indent+indentation))
+	return []Edit{{Path: mod.Path, Pos: start, End: bodyEnd, Text: buf.String()}}, nil
+}
+
+// extraction is the expression of a module that is extracted.
+type extraction struct {
+	mod *ast.Module
+	src []byte
+	// pos and end are the positions where the expression starts and ends.
+	pos, end token.Pos
+	// path are the nodes that contain the expression, from the outermost
+	// declaration to the expression itself.
+	path []ast.Node
+}
+
+func newExtraction(mod *ast.Module, src []byte, pos, end token.Pos, name string) (*extraction, error) {
+	if !scanner.IsIdentifier(name) || isUpper(name) {
+		return nil, fmt.Errorf("refactor: %q is not a valid name for a var", name)
+	}
+
+	if mod.Scope == nil || int(end) > len(src) || pos > end {
+		return nil, fmt.Errorf("refactor: the selection is not an expression")
+	}
+
+	// the selection can have spaces around the expression
+	for pos < end && isSpace(src[pos]) {
+		pos++
+	}
+	for end > pos && isSpace(src[end-1]) {
+		end--
+	}
+
+	x := &extraction{mod: mod, src: src, pos: pos, end: end}
+	for _, decl := range mod.Decls {
+		if path := exprPath(decl, pos, end); path != nil {
+			x.path = path
+			break
+		}
+	}
+	if x.path == nil {
+		return nil, fmt.Errorf("refactor: the selection is not an expression")
+	}
+
+	if obj := x.scope().Lookup(name, ast.Var); obj != nil {
+		return nil, fmt.Errorf("refactor: %s is already defined in module %s", name, mod.Name)
+	}
+	return x, nil
+}
+
+// exprPath returns the nodes from the given one to the expression between
+// pos and end, or nil if there is no such expression in the node. The
+// names that are part of other expressions, such as the operators or the
+// selectors of the qualified names, are not expressions on their own.
+func exprPath(node ast.Node, pos, end token.Pos) []ast.Node {
+	var stack, path []ast.Node
+	var parts = make(map[ast.Node]bool)
+	ast.WalkFunc(node, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+
+		// the children of the nodes that are not descended are not
+		// visited, and neither are the nodes themselves afterwards
+		switch n.(type) {
+		case *ast.TypeAnnotation, ast.Pattern, ast.Type:
+			return false
+		}
+
+		if path != nil {
+			return false
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.Definition:
+			parts[n.Name] = true
+		case *ast.SelectorExpr:
+			parts[n.Selector], parts[n.Expr] = true, true
+		case *ast.BinaryOp:
+			parts[n.Op] = true
+		case *ast.UnaryOp:
+			parts[n.Op] = true
+		case *ast.FieldAssign:
+			parts[n.Field] = true
+		case *ast.RecordUpdate:
+			parts[n.Record] = true
+		case *ast.AccessorExpr:
+			parts[n.Field] = true
+		}
+
+		if _, ok := n.(ast.Expr); ok && !parts[n] && n.Pos() == pos && nodeEnd(n) == end {
+			path = append([]ast.Node(nil), stack...)
+		}
+		return true
+	})
+	return path
+}
+
+// scope returns the innermost scope that contains the expression.
+func (x *extraction) scope() ast.Scope {
+	for i := len(x.path) - 1; i >= 0; i-- {
+		if s := nodeScope(x.mod.Scope.NodeScope, x.path[i]); s != nil {
+			return s
+		}
+	}
+	return x.mod.Scope
+}
+
+// freeVars returns the first use of every local name in the expression
+// that is defined out of it, in the order they are used.
+func (x *extraction) freeVars() []*ast.Ident {
+	var seen = make(map[*ast.Object]bool)
+	var vars []*ast.Ident
+	expr := x.path[len(x.path)-1]
+	for _, ref := range references(x.mod, expr, func(id *ast.Ident) bool { return id.Obj != nil }) {
+		obj := ref.Obj
+		if ref.qualified || seen[obj] || obj.Kind != ast.Var {
+			continue
+		}
+
+		scope := scopeOf(x.mod.Scope.NodeScope, obj)
+		decl := nameOf(obj.Node)
+		if scope == nil || scope == x.mod.Scope.NodeScope || decl == nil {
+			continue
+		}
+
+		if decl.Pos() >= x.pos && decl.End() <= x.end {
+			continue
+		}
+
+		seen[obj] = true
+		vars = append(vars, ref.Ident)
+	}
+	return vars
+}
+
+// needsParens reports whether a function call that replaces the expression
+// must be wrapped in parenthesis, because it is an operand or an argument.
+func (x *extraction) needsParens() bool {
+	if len(x.path) < 2 {
+		return false
+	}
+
+	switch x.path[len(x.path)-2].(type) {
+	case *ast.FuncApp, *ast.BinaryOp, *ast.UnaryOp, *ast.SelectorExpr:
+		return true
+	}
+	return false
+}
+
+// declStart returns the position where the declaration starts, which is
+// the start of its doc comment if it has one.
+func (x *extraction) declStart(decl ast.Decl) token.Pos {
+	pos := decl.Pos()
+	for _, c := range x.mod.Comments {
+		if c.End() <= pos && strings.TrimSpace(x.text(c.End(), pos)) == "" {
+			return c.Pos()
+		}
+	}
+	return pos
+}
+
+// unwrapped returns the positions of the code a name replaces, which has
+// the parenthesis around the expression, if there are any.
+func (x *extraction) unwrapped() (token.Pos, token.Pos) {
+	if len(x.path) > 1 {
+		if parens, ok := x.path[len(x.path)-2].(*ast.ParensExpr); ok {
+			return parens.Pos(), nodeEnd(parens)
+		}
+	}
+	return x.pos, x.end
+}
+
+// binding returns the expression as the body of a binding, with the given
+// indentation.
+func (x *extraction) binding(indent string) string {
+	return indent + reindent(x.text(x.pos, x.end), x.column(x.pos), indent)
+}
+
+func (x *extraction) text(pos, end token.Pos) string {
+	return string(x.src[pos:end])
+}
+
+// column returns the column of the position, starting at 0.
+func (x *extraction) column(pos token.Pos) int {
+	return int(pos) - (bytes.LastIndexByte(x.src[:pos], '\n') + 1)
+}
+
+// indentation returns the indentation of the line of the position.
+func (x *extraction) indentation(pos token.Pos) int {
+	start := bytes.LastIndexByte(x.src[:pos], '\n') + 1
+	line := x.src[start:pos]
+	return len(line) - len(bytes.TrimLeft(line, " "))
+}
+
+// reindent returns the code that started at the given column with its lines
+// but the first one indented with the given indentation, keeping how much
+// they are indented from the least indented of them or the first line.
+func reindent(code string, col int, indent string) string {
+	lines := strings.Split(code, "\n")
+	base := col
+	for _, l := range lines[1:] {
+		if n := len(l) - len(strings.TrimLeft(l, " ")); strings.TrimSpace(l) != "" && n < base {
+			base = n
+		}
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = indent + lines[i][base:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nodeEnd returns the position right after the last character of the node.
+// The end of the nodes between brackets is the position of the closing
+// bracket, instead.
+func nodeEnd(n ast.Node) token.Pos {
+	switch n := n.(type) {
+	case *ast.ParensExpr, *ast.TupleLit, *ast.ListLit, *ast.RecordLit, *ast.RecordUpdate, *ast.TupleCtor:
+		return n.End() + 1
+	case *ast.FuncApp:
+		return nodeEnd(n.Args[len(n.Args)-1])
+	case *ast.BinaryOp:
+		return nodeEnd(n.Rhs)
+	case *ast.UnaryOp:
+		return nodeEnd(n.Expr)
+	case *ast.SelectorExpr:
+		return nodeEnd(n.Expr)
+	case *ast.LetExpr:
+		return nodeEnd(n.Body)
+	case *ast.IfExpr:
+		return nodeEnd(n.ElseExpr)
+	case *ast.CaseExpr:
+		return nodeEnd(n.Branches[len(n.Branches)-1])
+	case *ast.CaseBranch:
+		return nodeEnd(n.Expr)
+	case *ast.Lambda:
+		return nodeEnd(n.Expr)
+	case *ast.Definition:
+		return nodeEnd(n.Body)
+	}
+	return n.End()
+}
+
+// nodeScope returns the scope whose root is the node, if there is one.
+func nodeScope(scope *ast.NodeScope, node ast.Node) *ast.NodeScope {
+	for _, child := range scope.Children() {
+		if child.Root == node {
+			return child
+		}
+
+		if s := nodeScope(child, node); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\r' || b == '\t'
+}
@@ -0,0 +1,197 @@
+package refactor
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/token"
+
+	"github.com/stretchr/testify/require"
+)
+
+const extractSource = `module Main exposing (main)
+
+import Util exposing (Shape(..), identity, width)
+
+
+main : Int
+main =
+    let
+        size =
+            identity 2
+    in
+        area size
+
+
+area : Int -> Int
+area size =
+    width (Rect (identity size) size)
+
+
+double x = identity (identity x)
+`
+
+type extractFunc func(*ast.Module, []byte, token.Pos, token.Pos, string) ([]Edit, error)
+
+// extracted returns the source code with the first occurrence of the
+// selection extracted with the given name, which must still be valid.
+func extracted(t *testing.T, extract extractFunc, src, selection, name string) string {
+	mod := parseModuleSource(t, src)
+	defer os.Remove(mod.Path)
+
+	pos := token.Pos(strings.Index(src, selection))
+	edits, err := extract(mod, []byte(src), pos, pos+token.Pos(len(selection)), name)
+	require.NoError(t, err)
+
+	content, err := Apply([]byte(src), edits)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(mod.Path, content, 0644))
+	_, err = parser.Parse(mod.Path, parser.FullParse)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestExtractFunction(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(strings.Replace(extractSource, `area : Int -> Int
+area size =
+    width (Rect (identity size) size)
+`, `rect size =
+    Rect (identity size) size
+
+
+area : Int -> Int
+area size =
+    width (rect size)
+`, 1), extracted(t, ExtractFunction, extractSource, "Rect (identity size) size", "rect"))
+
+	require.Equal(strings.Replace(extractSource, `main : Int
+main =
+    let
+        size =
+            identity 2
+`, `two =
+    identity 2
+
+
+main : Int
+main =
+    let
+        size =
+            two
+`, 1), extracted(t, ExtractFunction, extractSource, " identity 2\n", "two"))
+
+	require.Equal(strings.Replace(extractSource, `area : Int -> Int
+area size =
+    width (Rect (identity size) size)
+`, `side size =
+    (identity size)
+
+
+area : Int -> Int
+area size =
+    width (Rect (side size) size)
+`, 1), extracted(t, ExtractFunction, extractSource, "(identity size)", "side"))
+}
+
+func TestExtractFunctionMultiline(t *testing.T) {
+	src := `module Main exposing (main)
+
+import Util exposing (Shape(..), identity, width)
+
+
+{-| The main value.
+-}
+main : Int
+main =
+    identity
+        (width
+            (Square 2)
+        )
+`
+
+	require.Equal(t, `module Main exposing (main)
+
+import Util exposing (Shape(..), identity, width)
+
+
+size =
+    (width
+        (Square 2)
+    )
+
+
+{-| The main value.
+-}
+main : Int
+main =
+    identity
+        size
+`, extracted(t, ExtractFunction, src, "(width\n            (Square 2)\n        )", "size"))
+}
+
+func TestExtractLet(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(strings.Replace(extractSource, `    width (Rect (identity size) size)
+`, `    let
+        shape =
+            Rect (identity size) size
+    in
+        width shape
+`, 1), extracted(t, ExtractLet, extractSource, "Rect (identity size) size", "shape"))
+
+	require.Equal(strings.Replace(extractSource, `            identity 2
+    in
+        area size
+`, `            identity 2
+
+        total =
+            area size
+    in
+        total
+`, 1), extracted(t, ExtractLet, extractSource, "area size\n", "total"))
+
+	require.Equal(strings.Replace(extractSource, `double x = identity (identity x)
+`, `double x =
+    let
+        y =
+            identity x
+    in
+        identity y
+`, 1), extracted(t, ExtractLet, extractSource, "identity x", "y"))
+}
+
+func TestExtractErrors(t *testing.T) {
+	require := require.New(t)
+	mod := parseModuleSource(t, extractSource)
+	defer os.Remove(mod.Path)
+
+	at := func(selection string) (token.Pos, token.Pos) {
+		pos := token.Pos(strings.Index(extractSource, selection))
+		return pos, pos + token.Pos(len(selection))
+	}
+
+	pos, end := at("area size =")
+	_, err := ExtractLet(mod, []byte(extractSource), pos, end, "x")
+	require.EqualError(err, "refactor: the selection is not an expression")
+
+	pos, end = at("(Rect")
+	_, err = ExtractFunction(mod, []byte(extractSource), pos, end, "x")
+	require.EqualError(err, "refactor: the selection is not an expression")
+
+	pos, end = at("identity 2")
+	_, err = ExtractLet(mod, []byte(extractSource), pos, end, "size")
+	require.EqualError(err, "refactor: size is already defined in module Main")
+
+	_, err = ExtractFunction(mod, []byte(extractSource), pos, end, "width")
+	require.EqualError(err, "refactor: width is already defined in module Main")
+
+	_, err = ExtractFunction(mod, []byte(extractSource), pos, end, "Two")
+	require.EqualError(err, `refactor: "Two" is not a valid name for a var`)
+}
@@ -0,0 +1,439 @@
+package refactor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// HeaderChanges are the changes of the header of a module, its module
+// declaration and its imports, made by EditHeader. The names of the
+// exposing lists are written as they are in the source code, such as
+// `view`, `Msg(..)`, `Shape(Circle)` or `(+)`.
+type HeaderChanges struct {
+	// Name is the new name of the module, if it is renamed. The file of the
+	// module is not moved.
+	Name string
+	// Expose are the names added to the exposing list of the module, and
+	// Hide the names removed from it.
+	Expose []string
+	Hide   []string
+	// Import are the imports added to the module, which are merged with
+	// the import of the same module with the same alias if there is one.
+	Import []Import
+	// Remove are the modules whose imports are removed.
+	Remove []string
+}
+
+// Import is an import of a module, with an alias and an exposing list if
+// they are not empty.
+type Import struct {
+	Module   string
+	Alias    string
+	Exposing []string
+}
+
+// EditHeader returns the edits that make the given changes in the header
+// of a parsed module, which do not need it to be resolved. Only the text
+// that changes is edited, so the format of the rest of the header and of
+// the code is kept: the new names are added at the end of the exposing
+// lists, the new imports after the last one, and the removed names and
+// imports are removed with the space before them. There are no edits for
+// the changes that are already made, such as exposing a name that is
+// already exposed. It fails if a change can't be made or if there are
+// comments in the text removed, which would be lost, so the module must be
+// parsed with its comments.
+func EditHeader(mod *ast.Module, changes HeaderChanges) ([]Edit, error) {
+	decl := mod.Module
+	if decl == nil {
+		return nil, fmt.Errorf("refactor: module %s has no module declaration", mod.Name)
+	}
+
+	h := &headerEditor{mod: mod}
+	if changes.Name != "" && changes.Name != decl.ModuleName() {
+		if !isModuleName(changes.Name) {
+			return nil, fmt.Errorf("refactor: %q is not a valid name for a module", changes.Name)
+		}
+		h.add(decl.Name.Pos(), decl.Name.End(), changes.Name)
+	}
+
+	if err := h.editModuleList(decl.Exposing, changes.Expose, changes.Hide); err != nil {
+		return nil, err
+	}
+
+	if err := h.editImports(changes.Import, changes.Remove); err != nil {
+		return nil, err
+	}
+	return Merge(h.edits), nil
+}
+
+// headerEditor collects the edits of the header of a module.
+type headerEditor struct {
+	mod   *ast.Module
+	edits []Edit
+}
+
+func (h *headerEditor) add(pos, end token.Pos, text string) {
+	h.edits = append(h.edits, Edit{Path: h.mod.Path, Pos: pos, End: end, Text: text})
+}
+
+// replace replaces the text between pos and end, and fails if it has
+// comments, which would be lost.
+func (h *headerEditor) replace(pos, end token.Pos, text, what string) error {
+	for _, c := range h.mod.Comments {
+		if c.Pos() < end && c.End() > pos {
+			return fmt.Errorf("refactor: can't remove %s of module %s, there are comments between them", what, h.mod.Name)
+		}
+	}
+	h.add(pos, end, text)
+	return nil
+}
+
+func (h *headerEditor) editModuleList(list ast.ExposedList, expose, hide []string) error {
+	var hidden = make(map[string]bool)
+	for _, name := range hide {
+		hidden[exposedKey(name)] = true
+	}
+
+	for _, name := range expose {
+		if hidden[exposedKey(name)] {
+			return fmt.Errorf("refactor: %s is both exposed and hidden", name)
+		}
+	}
+
+	closed, ok := list.(*ast.ClosedList)
+	if !ok {
+		// everything is already exposed, so only hiding is a problem
+		if len(hide) > 0 {
+			return fmt.Errorf("refactor: can't hide %s, module %s exposes all of its names", hide[0], h.mod.Name)
+		}
+		return nil
+	}
+
+	added, err := h.exposeIn(closed, expose)
+	if err != nil {
+		return err
+	}
+
+	var spans = itemSpans(closed)
+	var removed = make([]bool, len(closed.Exposed))
+	for i, e := range closed.Exposed {
+		removed[i] = hidden[itemKey(e)]
+	}
+
+	ranges, all := removals(spans, removed)
+	if all {
+		if len(added) == 0 {
+			return fmt.Errorf("refactor: can't hide all the names of module %s, it must expose at least one", h.mod.Name)
+		}
+
+		// the new names replace the hidden ones
+		return h.replace(spans[0][0], spans[len(spans)-1][1], strings.Join(added, ", "), "the exposed names")
+	}
+
+	for _, r := range ranges {
+		if err := h.replace(r[0], r[1], "", "the exposed names"); err != nil {
+			return err
+		}
+	}
+
+	if len(added) > 0 {
+		pos := spans[len(spans)-1][1]
+		h.add(pos, pos, ", "+strings.Join(added, ", "))
+	}
+	return nil
+}
+
+// exposeIn replaces the names of the list that are exposed with a
+// different list of constructors, and returns the names that are not in
+// the list yet, which must be added.
+func (h *headerEditor) exposeIn(list *ast.ClosedList, names []string) ([]string, error) {
+	var items = make(map[string]int)
+	var spans [][2]token.Pos
+	if list != nil {
+		spans = itemSpans(list)
+		for i, e := range list.Exposed {
+			items[itemKey(e)] = i
+		}
+	}
+
+	var added []string
+	var seen = make(map[string]bool)
+	for _, name := range names {
+		key := exposedKey(name)
+		if key == "" {
+			return nil, fmt.Errorf("refactor: %q is not a valid exposed name", name)
+		}
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		i, ok := items[key]
+		if !ok {
+			added = append(added, strings.TrimSpace(name))
+		} else if compact(exposedText(list.Exposed[i])) != compact(name) {
+			h.add(spans[i][0], spans[i][1], strings.TrimSpace(name))
+		}
+	}
+	return added, nil
+}
+
+func (h *headerEditor) editImports(imports []Import, remove []string) error {
+	var positioned []*ast.ImportDecl
+	for _, imp := range h.mod.Imports {
+		// the default imports have no position
+		if imp.Import != token.NoPos {
+			positioned = append(positioned, imp)
+		}
+	}
+
+	var removedModules = make(map[string]bool)
+	for _, name := range remove {
+		removedModules[name] = true
+	}
+
+	var added []string
+	for _, imp := range imports {
+		if !isModuleName(imp.Module) || (imp.Alias != "" && !isModuleName(imp.Alias)) {
+			return fmt.Errorf("refactor: %q is not a valid import", importLine(imp))
+		}
+
+		if removedModules[imp.Module] {
+			return fmt.Errorf("refactor: module %s is both imported and removed", imp.Module)
+		}
+
+		existing := findImport(positioned, imp)
+		if existing == nil {
+			added = append(added, importLine(imp))
+			continue
+		}
+
+		// the exposed names are merged with the ones of the import, unless
+		// it already exposes everything
+		if _, ok := existing.Exposing.(*ast.OpenList); ok {
+			continue
+		}
+
+		list, _ := existing.Exposing.(*ast.ClosedList)
+		names, err := h.exposeIn(list, imp.Exposing)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(names) == 0:
+		case list == nil:
+			h.add(existing.End(), existing.End(), " exposing ("+strings.Join(names, ", ")+")")
+		default:
+			pos := itemSpans(list)[len(list.Exposed)-1][1]
+			h.add(pos, pos, ", "+strings.Join(names, ", "))
+		}
+	}
+
+	var spans = make([][2]token.Pos, len(positioned))
+	var removed = make([]bool, len(positioned))
+	for i, imp := range positioned {
+		spans[i] = [2]token.Pos{imp.Pos(), importEnd(imp)}
+		removed[i] = removedModules[imp.ModuleName()]
+	}
+
+	text := strings.Join(added, "\n")
+
+	ranges, all := removals(spans, removed)
+	switch {
+	case all:
+		// the imports are removed with the lines between them and the
+		// module declaration, and the new ones are written there instead
+		if text != "" {
+			text = "\n\n" + text
+		}
+		return h.replace(h.headerEnd(), spans[len(spans)-1][1], text, "the imports")
+	case text == "":
+	case len(positioned) == 0:
+		pos := h.headerEnd()
+		h.add(pos, pos, "\n\n"+text)
+	default:
+		pos := spans[len(spans)-1][1]
+		h.add(pos, pos, "\n"+text)
+	}
+
+	for _, r := range ranges {
+		if err := h.replace(r[0], r[1], "", "the imports"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// headerEnd returns the position where the module declaration ends, after
+// the doc comment of the module if it has one.
+func (h *headerEditor) headerEnd() token.Pos {
+	end := h.mod.Module.Exposing.End() + 1
+	for _, c := range h.mod.Comments {
+		if c.Pos() < end {
+			continue
+		}
+
+		if strings.HasPrefix(c.Text, "{-|") && (len(h.mod.Decls) == 0 || c.Pos() < h.mod.Decls[0].Pos()) {
+			end = c.End()
+		}
+		break
+	}
+	return end
+}
+
+// findImport returns the import of the same module with the same alias as
+// the given one, if there is one.
+func findImport(imports []*ast.ImportDecl, imp Import) *ast.ImportDecl {
+	for _, decl := range imports {
+		var alias string
+		if decl.Alias != nil {
+			alias = decl.Alias.Name
+		}
+
+		if decl.ModuleName() == imp.Module && alias == imp.Alias {
+			return decl
+		}
+	}
+	return nil
+}
+
+// removals returns the ranges of the text removed to remove the elements of
+// a list, whose spans are given, that are removed: the elements before the
+// first one that is kept are removed with the text that follows them, and
+// the rest with the text that precedes them. all reports whether all the
+// elements are removed, in which case there are no ranges.
+func removals(spans [][2]token.Pos, removed []bool) (ranges [][2]token.Pos, all bool) {
+	var first = -1
+	for i := range spans {
+		if !removed[i] {
+			first = i
+			break
+		}
+	}
+
+	if first < 0 {
+		return nil, len(spans) > 0
+	}
+
+	if first > 0 {
+		ranges = append(ranges, [2]token.Pos{spans[0][0], spans[first][0]})
+	}
+
+	for i := first + 1; i < len(spans); i++ {
+		if removed[i] {
+			ranges = append(ranges, [2]token.Pos{spans[i-1][1], spans[i][1]})
+		}
+	}
+	return ranges, false
+}
+
+// itemSpans returns the positions where the names of an exposing list start
+// and end, which include the parenthesis of the operators and of the lists
+// of constructors.
+func itemSpans(list *ast.ClosedList) [][2]token.Pos {
+	var spans = make([][2]token.Pos, len(list.Exposed))
+	for i, e := range list.Exposed {
+		switch e := e.(type) {
+		case *ast.ExposedVar:
+			if scanner.IsIdentifier(e.Name) {
+				spans[i] = [2]token.Pos{e.Pos(), e.End()}
+			} else {
+				spans[i] = [2]token.Pos{e.Pos() - 1, e.End() + 1}
+			}
+		case *ast.ExposedUnion:
+			spans[i] = [2]token.Pos{e.Pos(), e.End() + 1}
+		}
+	}
+	return spans
+}
+
+// itemKey returns the name of an exposed name, without its constructors.
+func itemKey(e ast.ExposedIdent) string {
+	switch e := e.(type) {
+	case *ast.ExposedVar:
+		return e.Name
+	case *ast.ExposedUnion:
+		return e.Type.Name
+	}
+	return ""
+}
+
+// exposedKey returns the name of an exposed name written as it is in the
+// source code, such as `Shape` for `Shape(..)` and `+` for `(+)`, or an
+// empty string if it is not valid.
+func exposedKey(s string) string {
+	s = compact(s)
+	if strings.HasPrefix(s, "(") {
+		if !strings.HasSuffix(s, ")") || len(s) < 3 {
+			return ""
+		}
+		return s[1 : len(s)-1]
+	}
+
+	if i := strings.Index(s, "("); i >= 0 {
+		if !strings.HasSuffix(s, ")") || !isUpper(s[:i]) {
+			return ""
+		}
+		s = s[:i]
+	}
+
+	if !scanner.IsIdentifier(s) {
+		return ""
+	}
+	return s
+}
+
+// exposedText returns the text of an exposed name, written the way the
+// organized imports are.
+func exposedText(e ast.ExposedIdent) string {
+	switch e := e.(type) {
+	case *ast.ExposedVar:
+		return exposedName(e.Name)
+	case *ast.ExposedUnion:
+		var buf bytes.Buffer
+		buf.WriteString(e.Type.Name)
+		writeList(&buf, e.Ctors)
+		return buf.String()
+	}
+	return ""
+}
+
+// compact returns the text without spaces.
+func compact(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// importLine returns the source code of the import.
+func importLine(imp Import) string {
+	line := "import " + imp.Module
+	if imp.Alias != "" {
+		line += " as " + imp.Alias
+	}
+
+	if len(imp.Exposing) > 0 {
+		var names = make([]string, len(imp.Exposing))
+		for i, name := range imp.Exposing {
+			names[i] = strings.TrimSpace(name)
+		}
+		line += " exposing (" + strings.Join(names, ", ") + ")"
+	}
+	return line
+}
+
+// isModuleName reports whether the name is a valid name of a module, which
+// are capitalized names separated by dots.
+func isModuleName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !scanner.IsIdentifier(part) || !isUpper(part) {
+			return false
+		}
+	}
+	return true
+}
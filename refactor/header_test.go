@@ -0,0 +1,166 @@
+package refactor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+// editedHeader returns the source of the module with the given changes of
+// its header.
+func editedHeader(t *testing.T, src string, changes HeaderChanges) (string, error) {
+	mod, err := parser.ParseFrom("Main.elm", strings.NewReader(src), parser.ParseComments)
+	require.NoError(t, err)
+
+	edits, err := EditHeader(mod, changes)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := Apply([]byte(src), edits)
+	require.NoError(t, err)
+	return string(content), nil
+}
+
+const headerSource = `module Main exposing (main, (+++), Shape(Circle))
+
+{-| The main module.
+-}
+
+import Html exposing (div)
+import Util as U
+-- the extra values
+import Extra exposing (..)
+import List.Extra
+
+
+main =
+    div [] []
+`
+
+func TestEditHeader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		changes  HeaderChanges
+		expected string
+	}{
+		{
+			"unchanged",
+			HeaderChanges{
+				Name:   "Main",
+				Expose: []string{"main", "Shape( Circle )"},
+				Import: []Import{{Module: "Html", Exposing: []string{"div"}}, {Module: "Extra", Exposing: []string{"one"}}},
+			},
+			headerSource,
+		},
+		{
+			"rename",
+			HeaderChanges{Name: "App.Main"},
+			strings.Replace(headerSource, "module Main", "module App.Main", 1),
+		},
+		{
+			"expose",
+			HeaderChanges{Expose: []string{"view", "Shape(..)", "(<|>)", "view"}},
+			strings.Replace(headerSource, "(main, (+++), Shape(Circle))", "(main, (+++), Shape(..), view, (<|>))", 1),
+		},
+		{
+			"hide",
+			HeaderChanges{Hide: []string{"main", "Shape"}},
+			strings.Replace(headerSource, "(main, (+++), Shape(Circle))", "((+++))", 1),
+		},
+		{
+			"hide last",
+			HeaderChanges{Hide: []string{"(+++)", "Shape(..)", "missing"}, Expose: []string{"view"}},
+			strings.Replace(headerSource, "(main, (+++), Shape(Circle))", "(main, view)", 1),
+		},
+		{
+			"hide all",
+			HeaderChanges{Hide: []string{"(+++)", "Shape", "main"}, Expose: []string{"view"}},
+			strings.Replace(headerSource, "(main, (+++), Shape(Circle))", "(view)", 1),
+		},
+		{
+			"import",
+			HeaderChanges{Import: []Import{
+				{Module: "Html", Exposing: []string{"text", "div"}},
+				{Module: "Util", Alias: "U", Exposing: []string{"Shape(..)"}},
+				{Module: "Util"},
+				{Module: "Dict", Alias: "D", Exposing: []string{"Dict"}},
+			}},
+			strings.Replace(strings.Replace(strings.Replace(headerSource,
+				"import Html exposing (div)", "import Html exposing (div, text)", 1),
+				"import Util as U\n", "import Util as U exposing (Shape(..))\n", 1),
+				"import List.Extra\n", "import List.Extra\nimport Util\nimport Dict as D exposing (Dict)\n", 1),
+		},
+		{
+			"remove",
+			HeaderChanges{Remove: []string{"Html", "List.Extra", "Missing"}},
+			strings.Replace(strings.Replace(headerSource,
+				"import Html exposing (div)\n", "", 1),
+				"\nimport List.Extra", "", 1),
+		},
+		{
+			"remove and import",
+			HeaderChanges{Remove: []string{"List.Extra"}, Import: []Import{{Module: "Dict"}}},
+			strings.Replace(headerSource, "import List.Extra", "import Dict", 1),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := editedHeader(t, headerSource, tt.changes)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestEditHeaderImports(t *testing.T) {
+	require := require.New(t)
+
+	src := "module Main exposing (main)\n\n{-| The main module. -}\n\n\nmain =\n    1\n"
+	result, err := editedHeader(t, src, HeaderChanges{Import: []Import{{Module: "Html"}, {Module: "Dict", Exposing: []string{"Dict"}}}})
+	require.NoError(err)
+	require.Equal("module Main exposing (main)\n\n{-| The main module. -}\n\nimport Html\nimport Dict exposing (Dict)\n\n\nmain =\n    1\n", result)
+
+	src = "module Main exposing (main)\n\nimport Html\nimport Dict\n\n\nmain =\n    1\n"
+	result, err = editedHeader(t, src, HeaderChanges{Remove: []string{"Html", "Dict"}})
+	require.NoError(err)
+	require.Equal("module Main exposing (main)\n\n\nmain =\n    1\n", result)
+
+	result, err = editedHeader(t, src, HeaderChanges{Remove: []string{"Html", "Dict"}, Import: []Import{{Module: "Set"}}})
+	require.NoError(err)
+	require.Equal("module Main exposing (main)\n\nimport Set\n\n\nmain =\n    1\n", result)
+
+	result, err = editedHeader(t, src, HeaderChanges{Remove: []string{"Html"}})
+	require.NoError(err)
+	require.Equal("module Main exposing (main)\n\nimport Dict\n\n\nmain =\n    1\n", result)
+}
+
+func TestEditHeaderErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		changes HeaderChanges
+		err     string
+	}{
+		{"module name", HeaderChanges{Name: "main"}, `refactor: "main" is not a valid name for a module`},
+		{"exposed name", HeaderChanges{Expose: []string{"view("}}, `refactor: "view(" is not a valid exposed name`},
+		{"exposed and hidden", HeaderChanges{Expose: []string{"main"}, Hide: []string{"main"}}, "refactor: main is both exposed and hidden"},
+		{"hide all", HeaderChanges{Hide: []string{"main", "(+++)", "Shape"}}, "refactor: can't hide all the names of module Main, it must expose at least one"},
+		{"import", HeaderChanges{Import: []Import{{Module: "Html", Alias: "h"}}}, `refactor: "import Html as h" is not a valid import`},
+		{"imported and removed", HeaderChanges{Import: []Import{{Module: "Html"}}, Remove: []string{"Html"}}, "refactor: module Html is both imported and removed"},
+		{"comments", HeaderChanges{Remove: []string{"Extra"}}, "refactor: can't remove the imports of module Main, there are comments between them"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := editedHeader(t, headerSource, tt.changes)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+
+	_, err := editedHeader(t, "module Main exposing (..)\n\n\nmain =\n    1\n", HeaderChanges{Hide: []string{"main"}})
+	require.EqualError(t, err, "refactor: can't hide main, module Main exposes all of its names")
+}
@@ -0,0 +1,401 @@
+package refactor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// OrganizeImports returns the edit that organizes the imports of a resolved
+// module: they are sorted by the name of the module, the imports of the
+// same module with the same alias are merged into one, the imports that are
+// not used are removed, and the exposing lists are sorted without the names
+// that are repeated or not used. There are no edits if the imports are
+// already organized. It fails if there are comments between the imports,
+// which would be lost.
+func OrganizeImports(mod *ast.Module) ([]Edit, error) {
+	var imports []*ast.ImportDecl
+	for _, imp := range mod.Imports {
+		// the default imports have no position
+		if imp.Import != token.NoPos {
+			imports = append(imports, imp)
+		}
+	}
+
+	if len(imports) == 0 || mod.Scope == nil {
+		return nil, nil
+	}
+
+	start, end := imports[0].Pos(), importEnd(imports[len(imports)-1])
+	for _, c := range mod.Comments {
+		if c.Pos() >= start && c.End() <= end {
+			return nil, fmt.Errorf("refactor: can't organize the imports of module %s, there are comments between them", mod.Name)
+		}
+	}
+
+	used := usedObjects(mod)
+	groups := groupImports(imports)
+
+	var buf bytes.Buffer
+	for _, g := range groups {
+		if !g.organize(mod.Scope, used) {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		g.write(&buf)
+	}
+
+	original := make([]byte, 0, end-start)
+	for i, imp := range imports {
+		if i > 0 {
+			original = append(original, '\n')
+		}
+		original = append(original, importText(imp)...)
+	}
+
+	// the imports are written in the same way to know if they changed, so
+	// that only their order and content count, not their format
+	if bytes.Equal(original, buf.Bytes()) {
+		return nil, nil
+	}
+
+	return []Edit{{Path: mod.Path, Pos: start, End: end, Text: buf.String()}}, nil
+}
+
+// importEnd returns the position where the import ends, which includes the
+// parenthesis that closes its exposing list.
+func importEnd(imp *ast.ImportDecl) token.Pos {
+	if imp.Exposing != nil {
+		return imp.Exposing.End() + 1
+	}
+	return imp.End()
+}
+
+// importGroup are the imports of the same module with the same alias.
+type importGroup struct {
+	module string
+	alias  string
+	// open reports whether any of the imports exposes everything.
+	open bool
+	// exposed are the names exposed by the imports, and ctors the exposed
+	// constructors of the exposed unions, which are nil if the union exposes
+	// all of them.
+	exposed map[string]bool
+	ctors   map[string]map[string]bool
+	// unions are the names of the exposed unions with their constructors.
+	unions map[string]bool
+}
+
+func groupImports(imports []*ast.ImportDecl) []*importGroup {
+	var byKey = make(map[[2]string]*importGroup)
+	var groups []*importGroup
+	for _, imp := range imports {
+		var alias string
+		if imp.Alias != nil {
+			alias = imp.Alias.Name
+		}
+
+		key := [2]string{imp.ModuleName(), alias}
+		g, ok := byKey[key]
+		if !ok {
+			g = &importGroup{
+				module:  key[0],
+				alias:   alias,
+				exposed: make(map[string]bool),
+				ctors:   make(map[string]map[string]bool),
+				unions:  make(map[string]bool),
+			}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.add(imp.Exposing)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].module != groups[j].module {
+			return groups[i].module < groups[j].module
+		}
+		return groups[i].alias < groups[j].alias
+	})
+	return groups
+}
+
+func (g *importGroup) add(list ast.ExposedList) {
+	switch list := list.(type) {
+	case *ast.OpenList:
+		g.open = true
+	case *ast.ClosedList:
+		for _, exposed := range list.Exposed {
+			switch exposed := exposed.(type) {
+			case *ast.ExposedVar:
+				g.exposed[exposed.Name] = true
+			case *ast.ExposedUnion:
+				name := exposed.Type.Name
+				g.exposed[name] = true
+				if g.unions[name] && g.ctors[name] == nil {
+					continue
+				}
+				g.unions[name] = true
+
+				ctors, ok := exposed.Ctors.(*ast.ClosedList)
+				if !ok {
+					g.ctors[name] = nil
+					continue
+				}
+
+				if g.ctors[name] == nil {
+					g.ctors[name] = make(map[string]bool)
+				}
+				for _, ctor := range ctors.Exposed {
+					if v, ok := ctor.(*ast.ExposedVar); ok {
+						g.ctors[name][v.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// organize removes the exposed names that are not used, and reports
+// whether the module is still imported.
+func (g *importGroup) organize(scope *ast.ModuleScope, used map[*ast.Object]bool) bool {
+	var imported *ast.ModuleScope
+	if obj := scope.Modules[g.module]; obj != nil {
+		if m, ok := obj.Node.(*ast.Module); ok {
+			imported = m.Scope
+		}
+	}
+
+	// the native modules and the modules that could not be resolved are
+	// kept as they are
+	if imported == nil {
+		return true
+	}
+
+	if g.open {
+		return g.usedQualified(scope, used) || usesExposed(used, imported)
+	}
+
+	for name := range g.exposed {
+		if g.unions[name] {
+			if ctors := g.ctors[name]; ctors != nil {
+				for ctor := range ctors {
+					if !used[imported.LookupExposed(ctor, ast.Ctor)] {
+						delete(ctors, ctor)
+					}
+				}
+
+				if len(ctors) == 0 {
+					delete(g.ctors, name)
+					delete(g.unions, name)
+				}
+			} else if !usesCtors(used, imported, name) {
+				delete(g.unions, name)
+			}
+		}
+
+		if g.unions[name] {
+			continue
+		}
+
+		obj := imported.LookupExposed(name, ast.Var)
+		if obj == nil {
+			obj = imported.LookupExposed(name, ast.Typ)
+		}
+
+		if obj != nil && !used[obj] {
+			delete(g.exposed, name)
+		}
+	}
+	return len(g.exposed) > 0 || g.usedQualified(scope, used)
+}
+
+// usesCtors reports whether any of the constructors of the union with the
+// given name exposed by the module is used.
+func usesCtors(used map[*ast.Object]bool, scope *ast.ModuleScope, name string) bool {
+	obj := scope.LookupExposed(name, ast.Typ)
+	if obj == nil {
+		return false
+	}
+
+	union, ok := obj.Node.(*ast.UnionDecl)
+	if !ok {
+		return false
+	}
+
+	for _, ctor := range union.Ctors {
+		if used[scope.LookupExposed(ctor.Name.Name, ast.Ctor)] {
+			return true
+		}
+	}
+	return false
+}
+
+// usedQualified reports whether the module is used qualified, by its name
+// or its alias.
+func (g *importGroup) usedQualified(scope *ast.ModuleScope, used map[*ast.Object]bool) bool {
+	if g.alias != "" {
+		return used[scope.Modules[g.alias]]
+	}
+	return used[scope.Modules[g.module]]
+}
+
+func (g *importGroup) write(buf *bytes.Buffer) {
+	buf.WriteString("import " + g.module)
+	if g.alias != "" {
+		buf.WriteString(" as " + g.alias)
+	}
+
+	if g.open {
+		buf.WriteString(" exposing (..)")
+		return
+	} else if len(g.exposed) == 0 {
+		return
+	}
+
+	var names []string
+	for name := range g.exposed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf.WriteString(" exposing (")
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(exposedName(name))
+
+		switch {
+		case !g.unions[name]:
+		case g.ctors[name] == nil:
+			buf.WriteString("(..)")
+		default:
+			var ctors []string
+			for ctor := range g.ctors[name] {
+				ctors = append(ctors, ctor)
+			}
+			sort.Strings(ctors)
+
+			buf.WriteString("(")
+			for i, ctor := range ctors {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				buf.WriteString(ctor)
+			}
+			buf.WriteString(")")
+		}
+	}
+	buf.WriteString(")")
+}
+
+// importText returns the text of the import written the way the organized
+// imports are, keeping the order of its exposed names.
+func importText(imp *ast.ImportDecl) string {
+	var buf bytes.Buffer
+	buf.WriteString("import " + imp.ModuleName())
+	if imp.Alias != nil {
+		buf.WriteString(" as " + imp.Alias.Name)
+	}
+
+	if imp.Exposing != nil {
+		buf.WriteString(" exposing ")
+		writeList(&buf, imp.Exposing)
+	}
+	return buf.String()
+}
+
+func writeList(buf *bytes.Buffer, list ast.ExposedList) {
+	closed, ok := list.(*ast.ClosedList)
+	if !ok {
+		buf.WriteString("(..)")
+		return
+	}
+
+	buf.WriteString("(")
+	for i, exposed := range closed.Exposed {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+
+		switch exposed := exposed.(type) {
+		case *ast.ExposedVar:
+			buf.WriteString(exposedName(exposed.Name))
+		case *ast.ExposedUnion:
+			buf.WriteString(exposed.Type.Name)
+			writeList(buf, exposed.Ctors)
+		}
+	}
+	buf.WriteString(")")
+}
+
+// exposedName returns the name as it is written in an exposing list, where
+// the operators are between parenthesis.
+func exposedName(name string) string {
+	if scanner.IsIdentifier(name) {
+		return name
+	}
+	return "(" + name + ")"
+}
+
+// usedObjects returns the objects the identifiers of the module refer to,
+// except the names of the definitions and their annotations and the names
+// that are qualified, which do not need to be exposed. The modules of the
+// qualified names are used.
+func usedObjects(mod *ast.Module) map[*ast.Object]bool {
+	var names = make(map[*ast.Ident]bool)
+	for _, d := range mod.Decls {
+		ast.WalkFunc(d, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Definition:
+				names[n.Name] = true
+				if n.Annotation != nil {
+					names[n.Annotation.Name] = true
+				}
+			case *ast.SelectorExpr:
+				if id := qualifiedName(n); id != nil {
+					names[id] = true
+				}
+			}
+			return true
+		})
+	}
+
+	var used = make(map[*ast.Object]bool)
+	var visit = func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Obj != nil && !names[id] {
+			used[id.Obj] = true
+		}
+		return true
+	}
+
+	// the effect managers refer to their functions in the module
+	// declaration
+	if mod.Module != nil {
+		ast.WalkFunc(mod.Module, visit)
+	}
+
+	for _, d := range mod.Decls {
+		ast.WalkFunc(d, visit)
+	}
+	return used
+}
+
+// usesExposed reports whether any of the exposed objects of the module is
+// used.
+func usesExposed(used map[*ast.Object]bool, scope *ast.ModuleScope) bool {
+	for _, obj := range scope.Exposed {
+		if used[obj] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package refactor
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func organized(t *testing.T, mod *ast.Module) string {
+	content, err := ioutil.ReadFile(mod.Path)
+	require.NoError(t, err)
+
+	edits, err := OrganizeImports(mod)
+	require.NoError(t, err)
+
+	content, err = Apply(content, edits)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestOrganizeImports(t *testing.T) {
+	require := require.New(t)
+	pkg, err := parser.Parse("_testdata/project/src/Imports.elm", parser.FullParse|parser.ParseComments)
+	require.NoError(err)
+
+	require.Equal(`module Imports exposing (main)
+
+import Extra as E
+import Util exposing (Shape(..), identity)
+
+
+main : Int
+main =
+    Util.width (Square (identity E.one))
+`, organized(t, pkg.Modules["Imports"]))
+}
+
+func TestOrganizeImportsUnchanged(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	edits, err := OrganizeImports(pkg.Modules["Main"])
+	require.NoError(err)
+	require.Empty(edits)
+}
+
+func TestOrganizeImportsConstructors(t *testing.T) {
+	require := require.New(t)
+	mod := parseModuleSource(t, `module Main exposing (main)
+
+import Util exposing (Shape(Rect, Square), width)
+import Extra
+
+
+main =
+    Square
+`)
+	defer os.Remove(mod.Path)
+
+	require.Equal(`module Main exposing (main)
+
+import Util exposing (Shape(Square))
+
+
+main =
+    Square
+`, organized(t, mod))
+}
+
+func TestOrganizeImportsComments(t *testing.T) {
+	mod := parseModuleSource(t, `module Main exposing (main)
+
+import Util exposing (identity)
+-- the extra values
+import Extra exposing (one)
+
+
+main =
+    identity one
+`)
+	defer os.Remove(mod.Path)
+
+	_, err := OrganizeImports(mod)
+	require.EqualError(t, err, "refactor: can't organize the imports of module Main, there are comments between them")
+}
+
+// parseModuleSource parses the given source of a module of the fixture,
+// which is written to a temporary file in its directory that must be
+// removed.
+func parseModuleSource(t *testing.T, src string) *ast.Module {
+	f, err := ioutil.TempFile("_testdata/project/src", "Main")
+	require.NoError(t, err)
+	path := f.Name()
+	f.Close()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(src), 0644))
+	pkg, err := parser.Parse(path, parser.FullParse|parser.ParseComments)
+	require.NoError(t, err)
+	return pkg.Modules["Main"]
+}
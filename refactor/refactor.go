@@ -0,0 +1,71 @@
+// Package refactor implements the refactorings of the source code of Elm
+// packages, which compute the edits of the files of the modules that change
+// the code without changing what it does. The edits are computed from the
+// resolved modules, so every refactoring only changes the modules of the
+// package it is given, except the edits of the header of a module, which
+// only need it to be parsed.
+package refactor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Edit is the replacement of part of the content of a file.
+type Edit struct {
+	// Path is the path to the file of the module.
+	Path string
+	// Pos and End are the offsets where the replaced text starts and ends.
+	Pos token.Pos
+	End token.Pos
+	// Text replaces the text between Pos and End.
+	Text string
+}
+
+// Merge returns the edits of all the given lists sorted by their path and
+// position, without the repeated ones. The refactorings of a package that
+// is parsed from many modules give the same edits for the modules that are
+// imported by more than one of them.
+func Merge(lists ...[]Edit) []Edit {
+	var seen = make(map[Edit]bool)
+	var result []Edit
+	for _, edits := range lists {
+		for _, e := range edits {
+			if !seen[e] {
+				seen[e] = true
+				result = append(result, e)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Pos < result[j].Pos
+	})
+	return result
+}
+
+// Apply returns the content of a file with the given edits, which must all
+// be of that file. It fails if they overlap or are out of the content.
+func Apply(content []byte, edits []Edit) ([]byte, error) {
+	edits = Merge(edits)
+
+	var buf bytes.Buffer
+	var last token.Pos
+	for _, e := range edits {
+		if e.Pos < last || e.End < e.Pos || int(e.End) > len(content) {
+			return nil, fmt.Errorf("refactor: invalid edit of %s at offset %d", e.Path, e.Pos)
+		}
+
+		buf.Write(content[last:e.Pos])
+		buf.WriteString(e.Text)
+		last = e.End
+	}
+	buf.Write(content[last:])
+	return buf.Bytes(), nil
+}
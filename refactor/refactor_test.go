@@ -0,0 +1,32 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	a := Edit{Path: "A.elm", Pos: 5, End: 6, Text: "x"}
+	b := Edit{Path: "A.elm", Pos: 1, End: 2, Text: "y"}
+	c := Edit{Path: "B.elm", Pos: 0, End: 1, Text: "z"}
+	require.Equal(t, []Edit{b, a, c}, Merge([]Edit{c, a}, []Edit{a, b}))
+}
+
+func TestApply(t *testing.T) {
+	require := require.New(t)
+
+	content, err := Apply([]byte("foo bar foo"), []Edit{
+		{Pos: 8, End: 11, Text: "baz"},
+		{Pos: 0, End: 3, Text: "qux"},
+		{Pos: 3, End: 3, Text: "!"},
+	})
+	require.NoError(err)
+	require.Equal("qux! bar baz", string(content))
+
+	_, err = Apply([]byte("foo"), []Edit{{Pos: 0, End: 2}, {Pos: 1, End: 3}})
+	require.Error(err)
+
+	_, err = Apply([]byte("foo"), []Edit{{Pos: 2, End: 5}})
+	require.Error(err)
+}
@@ -0,0 +1,365 @@
+package refactor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// Lookup returns the object of the top-level declaration with the given
+// name of a module of the package, or nil if there is none.
+func Lookup(pkg *ast.Package, module, name string) *ast.Object {
+	mod := pkg.Modules[module]
+	if mod == nil || mod.Scope == nil {
+		return nil
+	}
+	return mod.Scope.Objects[name]
+}
+
+// Rename returns the edits that rename the object to the given name in all
+// the modules of the package: its declaration, its type annotation, all the
+// references to it, qualified or not, and the exposing lists and the @docs
+// of the module comment that have it. Only values, types and constructors
+// can be renamed. It fails if the name is not valid for the object, or if
+// it is already used in the modules where the object is visible, so it
+// would change what another name refers to.
+func Rename(pkg *ast.Package, obj *ast.Object, name string) ([]Edit, error) {
+	decl := nameOf(obj.Node)
+	switch {
+	case obj.Kind != ast.Var && obj.Kind != ast.Typ && obj.Kind != ast.Ctor:
+		return nil, fmt.Errorf("refactor: %s %s can't be renamed", obj.Kind, obj.Name)
+	case decl == nil || !scanner.IsIdentifier(obj.Name):
+		return nil, fmt.Errorf("refactor: %s can't be renamed", obj.Name)
+	case !scanner.IsIdentifier(name) || isUpper(name) != (obj.Kind != ast.Var):
+		return nil, fmt.Errorf("refactor: %q is not a valid name for a %s", name, obj.Kind)
+	case name == obj.Name:
+		return nil, nil
+	}
+
+	mod, scope := definition(pkg, obj)
+	if mod == nil {
+		return nil, fmt.Errorf("refactor: %s is not defined in the package", obj.Name)
+	}
+
+	r := &renamer{pkg: pkg, obj: obj, name: name, decl: decl, mod: mod, scope: scope}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range pkg.Order {
+		if m := pkg.Modules[name]; m != nil {
+			r.renameModule(m)
+		}
+	}
+	return Merge(r.edits), nil
+}
+
+// renamer computes the edits of a rename.
+type renamer struct {
+	pkg  *ast.Package
+	obj  *ast.Object
+	name string
+	// decl is the name of the declaration of the object.
+	decl *ast.Ident
+	// mod is the module that defines the object in the given scope.
+	mod   *ast.Module
+	scope *ast.NodeScope
+	edits []Edit
+}
+
+// topLevel reports whether the object is a top-level declaration, which is
+// visible from other modules.
+func (r *renamer) topLevel() bool {
+	return r.scope == r.mod.Scope.NodeScope
+}
+
+// check checks that the new name is not used where the object is visible.
+func (r *renamer) check() error {
+	if r.scope.Objects[r.name] != nil {
+		if r.topLevel() {
+			return fmt.Errorf("refactor: %s is already defined in module %s", r.name, r.mod.Name)
+		}
+		return fmt.Errorf("refactor: %s is already defined in the same scope", r.name)
+	}
+
+	if !r.topLevel() {
+		if err := r.checkShadowed(r.mod, r.scope, r.scope.Root); err != nil {
+			return err
+		}
+
+		// the uses of the name in the scope of the object would refer to it
+		outer := r.scope.Parent.Lookup(r.name, ast.Var)
+		if outer == nil {
+			return nil
+		}
+
+		for _, id := range references(r.mod, r.scope.Root, func(id *ast.Ident) bool { return id.Obj == outer }) {
+			if !id.qualified {
+				return fmt.Errorf("refactor: %s is already used in module %s, it would be shadowed", r.name, r.mod.Name)
+			}
+		}
+		return nil
+	}
+
+	for _, name := range r.pkg.Order {
+		m := r.pkg.Modules[name]
+		if m == nil || m.Scope == nil || m != r.mod && m.Scope.Imported[r.obj.Name] != r.obj {
+			continue
+		}
+
+		if m != r.mod && m.Scope.Objects[r.name] != nil {
+			return fmt.Errorf("refactor: %s is already defined in module %s", r.name, m.Name)
+		}
+
+		if imported := m.Scope.Imported[r.name]; imported != nil && imported != r.obj {
+			return fmt.Errorf("refactor: %s is already imported in module %s", r.name, m.Name)
+		}
+
+		if err := r.checkShadowed(m, m.Scope.NodeScope, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkShadowed checks that the scopes inside the given one, whose root is
+// the given node, do not define the new name where the object is used.
+func (r *renamer) checkShadowed(mod *ast.Module, scope *ast.NodeScope, root ast.Node) error {
+	for _, child := range scope.Children() {
+		if other := child.Objects[r.name]; other != nil && isUpper(other.Name) == isUpper(r.name) && child.Root != nil {
+			for _, id := range references(mod, child.Root, func(id *ast.Ident) bool { return id.Obj == r.obj }) {
+				if !id.qualified {
+					return fmt.Errorf("refactor: %s is already defined in module %s, %s would be shadowed", r.name, mod.Name, r.obj.Name)
+				}
+			}
+		}
+
+		if err := r.checkShadowed(mod, child, child.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *renamer) renameModule(mod *ast.Module) {
+	refs := references(mod, mod, func(id *ast.Ident) bool {
+		return id.Obj == r.obj || id == r.decl
+	})
+	for _, ref := range refs {
+		r.rename(mod, ref.Ident)
+	}
+
+	if mod == r.mod {
+		ast.WalkFunc(mod, func(n ast.Node) bool {
+			if def, ok := n.(*ast.Definition); ok && def.Name == r.decl && def.Annotation != nil {
+				r.rename(mod, def.Annotation.Name)
+			}
+			return n != nil
+		})
+	}
+
+	if !r.topLevel() {
+		return
+	}
+
+	if mod == r.mod {
+		if mod.Module != nil {
+			r.renameExposed(mod, mod.Module.Exposing)
+		}
+		r.renameDocs(mod)
+	}
+
+	for _, imp := range mod.Imports {
+		if imp.ModuleName() == r.mod.Name {
+			r.renameExposed(mod, imp.Exposing)
+		}
+	}
+}
+
+// renameExposed renames the object in an exposing list of the module that
+// defines it or of an import of it.
+func (r *renamer) renameExposed(mod *ast.Module, list ast.ExposedList) {
+	closed, ok := list.(*ast.ClosedList)
+	if !ok {
+		return
+	}
+
+	union := r.union()
+	for _, exposed := range closed.Exposed {
+		switch exposed := exposed.(type) {
+		case *ast.ExposedVar:
+			if r.obj.Kind != ast.Ctor && exposed.Name == r.obj.Name {
+				r.rename(mod, exposed.Ident)
+			}
+		case *ast.ExposedUnion:
+			if r.obj.Kind == ast.Typ && exposed.Type.Name == r.obj.Name {
+				r.rename(mod, exposed.Type)
+			}
+
+			ctors, ok := exposed.Ctors.(*ast.ClosedList)
+			if !ok || union == nil || exposed.Type.Name != union.Name.Name {
+				continue
+			}
+
+			for _, ctor := range ctors.Exposed {
+				if v, ok := ctor.(*ast.ExposedVar); ok && v.Name == r.obj.Name {
+					r.rename(mod, v.Ident)
+				}
+			}
+		}
+	}
+}
+
+// union returns the declaration of the union type of the object if it is
+// a constructor.
+func (r *renamer) union() *ast.UnionDecl {
+	if r.obj.Kind != ast.Ctor {
+		return nil
+	}
+
+	for _, decl := range r.mod.Decls {
+		if union, ok := decl.(*ast.UnionDecl); ok {
+			for _, ctor := range union.Ctors {
+				if ctor == r.obj.Node {
+					return union
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renameDocs renames the object in the @docs lines of the comments of the
+// module, which list the declarations that are documented.
+func (r *renamer) renameDocs(mod *ast.Module) {
+	for _, c := range mod.Comments {
+		var off int
+		for _, line := range strings.SplitAfter(c.Text, "\n") {
+			start := off
+			off += len(line)
+
+			i := strings.Index(line, "@docs ")
+			if i < 0 || strings.TrimSpace(line[:i]) != "" && strings.TrimSpace(line[:i]) != "{-|" {
+				continue
+			}
+
+			i += len("@docs ")
+			for _, name := range strings.Split(line[i:], ",") {
+				trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(name), "-}"))
+				if trimmed == r.obj.Name {
+					pos := c.Position + token.Pos(start+i+strings.Index(name, trimmed))
+					r.edits = append(r.edits, Edit{Path: mod.Path, Pos: pos, End: pos + token.Pos(len(trimmed)), Text: r.name})
+				}
+				i += len(name) + 1
+			}
+		}
+	}
+}
+
+func (r *renamer) rename(mod *ast.Module, id *ast.Ident) {
+	if id == nil || id.NamePos == token.NoPos {
+		return
+	}
+	r.edits = append(r.edits, Edit{Path: mod.Path, Pos: id.Pos(), End: id.End(), Text: r.name})
+}
+
+// reference is an identifier with an object, which is qualified if it is
+// the selector of a qualified name.
+type reference struct {
+	*ast.Ident
+	qualified bool
+}
+
+// references returns the identifiers of the module inside the given node
+// that match the given function.
+func references(mod *ast.Module, node ast.Node, match func(*ast.Ident) bool) []reference {
+	var qualified = make(map[*ast.Ident]bool)
+	var refs []reference
+	ast.WalkFunc(mod, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case nil:
+			return false
+		case *ast.SelectorExpr:
+			if id := qualifiedName(n); id != nil {
+				qualified[id] = true
+			}
+		case *ast.Ident:
+			inside := node == mod || n.Pos() >= node.Pos() && n.End() <= node.End()
+			if inside && match(n) {
+				refs = append(refs, reference{n, qualified[n]})
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// qualifiedName returns the name qualified by the module of the selector,
+// if it is the last one of a qualified name. The selectors of the
+// qualified names are the names of their modules, and their expressions
+// are the rest of the name.
+func qualifiedName(sel *ast.SelectorExpr) *ast.Ident {
+	if id, ok := sel.Expr.(*ast.Ident); ok && isUpper(sel.Selector.Name) {
+		return id
+	}
+	return nil
+}
+
+// definition returns the module that defines the object and the scope in
+// which it is defined.
+func definition(pkg *ast.Package, obj *ast.Object) (*ast.Module, *ast.NodeScope) {
+	for _, name := range pkg.Order {
+		mod := pkg.Modules[name]
+		if mod == nil || mod.Scope == nil {
+			continue
+		}
+
+		if scope := scopeOf(mod.Scope.NodeScope, obj); scope != nil {
+			return mod, scope
+		}
+	}
+	return nil, nil
+}
+
+func scopeOf(scope *ast.NodeScope, obj *ast.Object) *ast.NodeScope {
+	if scope.Objects[obj.Name] == obj {
+		return scope
+	}
+
+	for _, child := range scope.Children() {
+		if s := scopeOf(child, obj); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// nameOf returns the identifier of the name of the node that defines an
+// object.
+func nameOf(node ast.Node) *ast.Ident {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return n
+	case *ast.AliasDecl:
+		return n.Name
+	case *ast.UnionDecl:
+		return n.Name
+	case *ast.Constructor:
+		return n.Name
+	case *ast.VarPattern:
+		return n.Name
+	case *ast.AliasPattern:
+		return n.Name
+	}
+	return nil
+}
+
+func isUpper(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,161 @@
+package refactor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseFixture(t *testing.T) *ast.Package {
+	pkg, err := parser.Parse("_testdata/project/src/Main.elm", parser.FullParse|parser.ParseComments)
+	require.NoError(t, err)
+	return pkg
+}
+
+// renamed returns the modules of the fixture by their name with the edits
+// applied.
+func renamed(t *testing.T, edits []Edit) map[string]string {
+	var byPath = make(map[string][]Edit)
+	for _, e := range edits {
+		byPath[e.Path] = append(byPath[e.Path], e)
+	}
+
+	var result = make(map[string]string)
+	for _, name := range []string{"Main", "Util"} {
+		path := filepath.Join("_testdata", "project", "src", name+".elm")
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		var edits []Edit
+		for p, e := range byPath {
+			if filepath.Clean(p) == path {
+				edits = e
+			}
+		}
+
+		content, err = Apply(content, edits)
+		require.NoError(t, err)
+		result[name] = string(content)
+	}
+	return result
+}
+
+func TestRenameValue(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	edits, err := Rename(pkg, Lookup(pkg, "Util", "identity"), "same")
+	require.NoError(err)
+
+	files := renamed(t, edits)
+	require.Contains(files["Util"], "module Util exposing (Shape(..), same, width)")
+	require.Contains(files["Util"], "@docs Shape, same, width")
+	require.Contains(files["Util"], "same : a -> a\nsame x =\n")
+	require.Contains(files["Util"], "            same side\n")
+	require.NotContains(files["Util"], "identity")
+	require.Contains(files["Main"], "import Util exposing (Shape(Square), same)")
+	require.Contains(files["Main"], "            same 2\n")
+	require.NotContains(files["Main"], "identity")
+}
+
+func TestRenameQualified(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	edits, err := Rename(pkg, Lookup(pkg, "Util", "width"), "size")
+	require.NoError(err)
+
+	files := renamed(t, edits)
+	require.Contains(files["Util"], "module Util exposing (Shape(..), identity, size)")
+	require.Contains(files["Util"], "size : Shape -> Int\nsize shape =\n")
+	require.Contains(files["Main"], "        Util.size (Square size)\n")
+}
+
+func TestRenameType(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	edits, err := Rename(pkg, Lookup(pkg, "Util", "Shape"), "Figure")
+	require.NoError(err)
+
+	files := renamed(t, edits)
+	require.Contains(files["Util"], "module Util exposing (Figure(..), identity, width)")
+	require.Contains(files["Util"], "@docs Figure, identity, width")
+	require.Contains(files["Util"], "type Figure\n")
+	require.Contains(files["Util"], "width : Figure -> Int\n")
+	require.Contains(files["Main"], "import Util exposing (Figure(Square), identity)")
+}
+
+func TestRenameConstructor(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	edits, err := Rename(pkg, Lookup(pkg, "Util", "Square"), "Box")
+	require.NoError(err)
+
+	files := renamed(t, edits)
+	require.Contains(files["Util"], "    = Box Int\n")
+	require.Contains(files["Util"], "        Box side ->\n")
+	require.Contains(files["Util"], "exposing (Shape(..), identity, width)")
+	require.Contains(files["Main"], "import Util exposing (Shape(Box), identity)")
+	require.Contains(files["Main"], "Util.width (Box size)")
+}
+
+func TestRenameLocal(t *testing.T) {
+	require := require.New(t)
+	pkg := parseFixture(t)
+
+	var size *ast.Object
+	ast.WalkFunc(pkg.Modules["Main"], func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "size" && id.Obj != nil {
+			size = id.Obj
+		}
+		return n != nil
+	})
+	require.NotNil(size)
+
+	edits, err := Rename(pkg, size, "n")
+	require.NoError(err)
+	require.Len(edits, 2)
+
+	files := renamed(t, edits)
+	require.Contains(files["Main"], "        n =\n")
+	require.Contains(files["Main"], "Util.width (Square n)")
+
+	_, err = Rename(pkg, size, "identity")
+	require.EqualError(err, "refactor: identity is already used in module Main, it would be shadowed")
+}
+
+func TestRenameErrors(t *testing.T) {
+	pkg := parseFixture(t)
+
+	testCases := []struct {
+		module string
+		name   string
+		to     string
+		err    string
+	}{
+		{"Util", "identity", "Identity", `refactor: "Identity" is not a valid name for a variable`},
+		{"Util", "Shape", "shape", `refactor: "shape" is not a valid name for a type`},
+		{"Util", "identity", "let", `refactor: "let" is not a valid name for a variable`},
+		{"Util", "identity", "width", "refactor: width is already defined in module Util"},
+		{"Util", "identity", "main", "refactor: main is already defined in module Main"},
+		{"Util", "identity", "size", "refactor: size is already defined in module Main, identity would be shadowed"},
+		{"Util", "identity", "side", "refactor: side is already defined in module Util, identity would be shadowed"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name+" to "+tt.to, func(t *testing.T) {
+			_, err := Rename(pkg, Lookup(pkg, tt.module, tt.name), tt.to)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+
+	_, err := Rename(pkg, pkg.Modules["Main"].Scope.Modules["Util"], "Other")
+	require.EqualError(t, err, "refactor: module Util can't be renamed")
+}
@@ -2,6 +2,7 @@ package report
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -84,6 +85,12 @@ func (e *writerEmitter) emitReport(file string, d *Diagnostic) error {
 		}
 	}
 
+	for _, f := range d.Fixes {
+		if err := e.print("\nfix: %s\n", f.Message); err != nil {
+			return err
+		}
+	}
+
 	return e.print("\nat %s:%d:%d\n\n", file, d.Pos.Line, d.Pos.Col)
 }
 
@@ -138,5 +145,85 @@ func (e *writerEmitter) printRegion(typ ReportType, pos source.LinePos, region *
 
 // Stderr creates a new emitter that will report to stderr all diagnostics.
 func Stderr(warnings, colors bool) Emitter {
-	return &writerEmitter{os.Stderr, warnings, colors}
+	return Writer(os.Stderr, warnings, colors)
+}
+
+// Writer creates a new emitter that will report all diagnostics to the
+// given writer.
+func Writer(w io.Writer, warnings, colors bool) Emitter {
+	return &writerEmitter{w, warnings, colors}
+}
+
+// JSON creates a new emitter that writes every diagnostic to the given
+// writer as a JSON object in a line of its own. The objects have an "event"
+// field whose value is "diagnostic", so they can be part of a stream of
+// other events.
+func JSON(w io.Writer, warnings bool) Emitter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &jsonEmitter{enc, warnings}
+}
+
+type jsonEmitter struct {
+	enc      *json.Encoder
+	warnings bool
+}
+
+// jsonDiagnostic is the JSON encoding of a diagnostic.
+type jsonDiagnostic struct {
+	Event   string `json:"event"`
+	File    string `json:"file"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Line and Col are the position of the diagnostic, which are zero if
+	// it has no position.
+	Line    int          `json:"line"`
+	Col     int          `json:"col"`
+	Snippet *jsonSnippet `json:"snippet,omitempty"`
+	Fixes   []jsonFix    `json:"fixes,omitempty"`
+}
+
+// jsonFix is the JSON encoding of a fix of a diagnostic, which replaces
+// the code from line and col to end-line and end-col with the text.
+type jsonFix struct {
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	EndLine int    `json:"end-line"`
+	EndCol  int    `json:"end-col"`
+	Text    string `json:"text"`
+}
+
+// jsonSnippet is the JSON encoding of the code affected by a diagnostic.
+type jsonSnippet struct {
+	Start int      `json:"start"`
+	Lines []string `json:"lines"`
+}
+
+func (e *jsonEmitter) Emit(file string, diagnostics []*Diagnostic) error {
+	for _, d := range diagnostics {
+		if !e.warnings && d.Type == Warning {
+			continue
+		}
+
+		jd := jsonDiagnostic{
+			Event:   "diagnostic",
+			File:    file,
+			Type:    d.Type.String(),
+			Message: d.Message,
+			Line:    d.Pos.Line,
+			Col:     d.Pos.Col,
+		}
+		if d.Region != nil {
+			jd.Snippet = &jsonSnippet{d.Region.Start, d.Region.Lines}
+		}
+		for _, f := range d.Fixes {
+			jd.Fixes = append(jd.Fixes, jsonFix{f.Message, f.Start.Line, f.Start.Col, f.End.Line, f.End.Col, f.Text})
+		}
+
+		if err := e.enc.Encode(jd); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elm-tangram/tangram/source"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	diagnostics := []*Diagnostic{
+		{
+			Type:    TypeError,
+			Message: "I expected an Int",
+			Pos:     source.LinePos{Line: 3, Col: 5},
+			Region:  &source.Snippet{Start: 3, Lines: []string{"    x = \"a\""}},
+		},
+		{Type: Warning, Message: "unused import"},
+		{Type: SyntaxError, Message: "I could not find module \"Foo\""},
+	}
+
+	require.NoError(t, JSON(&buf, false).Emit("src/Main.elm", diagnostics))
+	require.Equal(t, `{"event":"diagnostic","file":"src/Main.elm","type":"type error","message":"I expected an Int","line":3,"col":5,"snippet":{"start":3,"lines":["    x = \"a\""]}}
+{"event":"diagnostic","file":"src/Main.elm","type":"syntax error","message":"I could not find module \"Foo\"","line":0,"col":0}
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, JSON(&buf, true).Emit("src/Main.elm", diagnostics[1:2]))
+	require.Equal(t, `{"event":"diagnostic","file":"src/Main.elm","type":"warning","message":"unused import","line":0,"col":0}
+`, buf.String())
+}
+
+func TestFixes(t *testing.T) {
+	diagnostics := []*Diagnostic{
+		{
+			Type:    NameError,
+			Message: `I could not find any definition for "identity".`,
+			Pos:     source.LinePos{Line: 5, Col: 8},
+			Fixes: []DiagnosticFix{{
+				Message: `Add "import Util exposing (identity)"`,
+				Start:   source.LinePos{Line: 3, Col: 12},
+				End:     source.LinePos{Line: 3, Col: 12},
+				Text:    "\nimport Util exposing (identity)",
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, JSON(&buf, false).Emit("src/Main.elm", diagnostics))
+	require.Equal(t, `{"event":"diagnostic","file":"src/Main.elm","type":"name error","message":"I could not find any definition for \"identity\".","line":5,"col":8,"fixes":[{"message":"Add \"import Util exposing (identity)\"","line":3,"col":12,"end-line":3,"end-col":12,"text":"\nimport Util exposing (identity)"}]}
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Writer(&buf, false, false).Emit("src/Main.elm", diagnostics))
+	require.Equal(t, `I found problems at file: src/Main.elm
+
+name error: I could not find any definition for "identity".
+fix: Add "import Util exposing (identity)"
+
+at src/Main.elm:5:8
+
+`, buf.String())
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/elm-tangram/tangram/ast"
 	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
 )
 
 // Name errors
@@ -15,9 +16,9 @@ type UndefinedError struct {
 	Name string
 }
 
-func NewUndefinedError(expr ast.Node, name *ast.Ident) *UndefinedError {
+func NewUndefinedError(file string, expr ast.Node, name *ast.Ident) *UndefinedError {
 	return &UndefinedError{
-		NewBaseReport(NameError, name.Pos(), "", RegionFromNode(expr)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(expr)),
 		name.Name,
 	}
 }
@@ -31,9 +32,9 @@ type UndefinedTypeVarError struct {
 	Name string
 }
 
-func NewUndefinedTypeVarError(expr ast.Node, varTyp *ast.VarType) *UndefinedTypeVarError {
+func NewUndefinedTypeVarError(file string, expr ast.Node, varTyp *ast.VarType) *UndefinedTypeVarError {
 	return &UndefinedTypeVarError{
-		NewBaseReport(NameError, varTyp.Pos(), "", RegionFromNode(expr)),
+		NewBaseReport(NameError, NodeLocation(file, varTyp), "", RegionFromNode(expr)),
 		varTyp.Name,
 	}
 }
@@ -44,13 +45,14 @@ func (e UndefinedTypeVarError) Message() string {
 
 type ModuleNotImportedError struct {
 	BaseReport
+	FixList
 	Module string
 }
 
-func NewModuleNotImportedError(expr ast.Node, name string) *ModuleNotImportedError {
+func NewModuleNotImportedError(file string, expr ast.Node, name string) *ModuleNotImportedError {
 	return &ModuleNotImportedError{
-		NewBaseReport(NameError, expr.Pos(), "", RegionFromNode(expr)),
-		name,
+		BaseReport: NewBaseReport(NameError, NodeLocation(file, expr), "", RegionFromNode(expr)),
+		Module:     name,
 	}
 }
 
@@ -58,29 +60,136 @@ func (e ModuleNotImportedError) Message() string {
 	return fmt.Sprintf("I could not find imported module %q.", e.Module)
 }
 
-type ImportError struct {
+type ModuleNotFoundError struct {
+	BaseReport
+	Module      string
+	Suggestions []string
+}
+
+// NewModuleNotFoundError creates the report of an imported module that is
+// not in the package nor in its dependencies. Suggestions are the modules
+// with similar names, if any.
+func NewModuleNotFoundError(file string, module ast.Node, name string, suggestions ...string) *ModuleNotFoundError {
+	return &ModuleNotFoundError{
+		NewBaseReport(SyntaxError, NodeLocation(file, module), "", RegionFromNode(module)),
+		name,
+		suggestions,
+	}
+}
+
+func (e *ModuleNotFoundError) Message() string {
+	msg := fmt.Sprintf("I could not find module %q in any of the package source directories or any of its dependencies.", e.Module)
+	if len(e.Suggestions) == 0 {
+		return msg + " Maybe you're missing a dependency?"
+	}
+	return msg + suggestionsMessage(e.Suggestions)
+}
+
+type ModuleCaseError struct {
 	BaseReport
 	Module string
-	Name   string
+	// Path is the path of the file of the module according to its name, and
+	// File the path of the file found, which only differs in case.
+	Path, File string
+}
+
+// NewModuleCaseError creates the report of an imported module whose file is
+// only found if the case of its path is ignored, which is found at found.
+func NewModuleCaseError(file string, module ast.Node, name, path, found string) *ModuleCaseError {
+	return &ModuleCaseError{
+		NewBaseReport(SyntaxError, NodeLocation(file, module), "", RegionFromNode(module)),
+		name,
+		path,
+		found,
+	}
+}
+
+func (e *ModuleCaseError) Message() string {
+	return fmt.Sprintf(
+		"I found the file of module %q, but the case of its path does not match the name of the module:\n\n    expected: %s\n    found:    %s\n\n"+
+			"The file is only found on case-insensitive file systems, such as the ones of macOS and Windows, and not on Linux. Rename the file or fix the import so they match.",
+		e.Module, e.Path, e.File,
+	)
+}
+
+type CaseConflictError struct {
+	BaseReport
+	Module string
+	Paths  []string
+}
+
+// NewCaseConflictError creates the report of an imported module with more
+// than one file whose paths only differ in case.
+func NewCaseConflictError(file string, module ast.Node, name string, paths []string) *CaseConflictError {
+	return &CaseConflictError{
+		NewBaseReport(SyntaxError, NodeLocation(file, module), "", RegionFromNode(module)),
+		name,
+		paths,
+	}
+}
+
+func (e *CaseConflictError) Message() string {
+	return fmt.Sprintf(
+		"I found more than one file for module %q, whose paths only differ in case:\n\n    %s\n\n"+
+			"Only one of them can exist on case-insensitive file systems, such as the ones of macOS and Windows. Rename or remove the others.",
+		e.Module, strings.Join(e.Paths, "\n    "),
+	)
+}
+
+type ExcludedModuleError struct {
+	BaseReport
+	Module string
+	Tag    string
+}
+
+// NewExcludedModuleError creates the report of an imported module that has
+// a tag in the manifest of its package, such as test, which is not built
+// with the module that imports it.
+func NewExcludedModuleError(file string, module ast.Node, name, tag string) *ExcludedModuleError {
+	return &ExcludedModuleError{
+		NewBaseReport(SyntaxError, NodeLocation(file, module), "", RegionFromNode(module)),
+		name,
+		tag,
+	}
 }
 
-func NewImportError(decl ast.Node, module string, name *ast.Ident) *ImportError {
+func (e *ExcludedModuleError) Message() string {
+	return fmt.Sprintf(
+		"I found module %q, but it is tagged %s in the module-tags of the manifest of its package, so it is only built with the modules tagged %s, such as the %ss, and not with this one.",
+		e.Module, e.Tag, e.Tag, e.Tag,
+	)
+}
+
+type ImportError struct {
+	BaseReport
+	Module      string
+	Name        string
+	Suggestions []string
+}
+
+func NewImportError(file string, decl ast.Node, module string, name *ast.Ident, suggestions ...string) *ImportError {
 	return &ImportError{
-		NewBaseReport(NameError, name.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		module,
 		name.Name,
+		suggestions,
 	}
 }
 
+func (e *ImportError) Message() string {
+	return fmt.Sprintf("Module %q does not expose %q.", e.Module, e.Name) +
+		suggestionsMessage(e.Suggestions)
+}
+
 type ExportError struct {
 	BaseReport
 	Module string
 	Name   string
 }
 
-func NewExportError(decl *ast.ModuleDecl, name *ast.Ident) *ExportError {
+func NewExportError(file string, decl *ast.ModuleDecl, name *ast.Ident) *ExportError {
 	return &ExportError{
-		NewBaseReport(NameError, name.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		decl.ModuleName(),
 		name.Name,
 	}
@@ -96,9 +205,12 @@ type ExpectedUnionError struct {
 	ActualKind ast.ObjKind
 }
 
-func NewExpectedUnionError(decl ast.Decl, obj *ast.Object) *ExpectedUnionError {
+// NewExpectedUnionError creates the report of a name exposed with its
+// constructors in the declaration that is not the one of a union type, but
+// of the given object, which may be declared in another module.
+func NewExpectedUnionError(file string, decl ast.Node, name *ast.Ident, obj *ast.Object) *ExpectedUnionError {
 	return &ExpectedUnionError{
-		NewBaseReport(NameError, obj.Node.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		obj.Name,
 		obj.Kind,
 	}
@@ -114,9 +226,12 @@ type ExpectedCtorError struct {
 	ActualKind ast.ObjKind
 }
 
-func NewExpectedCtorError(decl ast.Decl, obj *ast.Object) *ExpectedCtorError {
+// NewExpectedCtorError creates the report of a name exposed as a constructor
+// in the declaration that is not one, but the given object, which may be
+// declared in another module.
+func NewExpectedCtorError(file string, decl ast.Node, name *ast.Ident, obj *ast.Object) *ExpectedCtorError {
 	return &ExpectedCtorError{
-		NewBaseReport(NameError, obj.Node.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		obj.Name,
 		obj.Kind,
 	}
@@ -131,9 +246,9 @@ type RepeatedFieldError struct {
 	Field string
 }
 
-func NewRepeatedFieldError(record ast.Node, field *ast.Ident) *RepeatedFieldError {
+func NewRepeatedFieldError(file string, record ast.Node, field *ast.Ident) *RepeatedFieldError {
 	return &RepeatedFieldError{
-		NewBaseReport(NameError, field.Pos(), "", RegionFromNode(record)),
+		NewBaseReport(NameError, NodeLocation(file, field), "", RegionFromNode(record)),
 		field.Name,
 	}
 }
@@ -147,9 +262,9 @@ type AlreadyDeclaredError struct {
 	Name string
 }
 
-func NewAlreadyDeclaredError(decl ast.Decl, name *ast.Ident) *AlreadyDeclaredError {
+func NewAlreadyDeclaredError(file string, decl ast.Decl, name *ast.Ident) *AlreadyDeclaredError {
 	return &AlreadyDeclaredError{
-		NewBaseReport(NameError, name.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		name.Name,
 	}
 }
@@ -163,9 +278,9 @@ type RepeatedVarTypeError struct {
 	Var string
 }
 
-func NewRepeatedVarTypeError(decl ast.Decl, name *ast.Ident) *RepeatedVarTypeError {
+func NewRepeatedVarTypeError(file string, decl ast.Decl, name *ast.Ident) *RepeatedVarTypeError {
 	return &RepeatedVarTypeError{
-		NewBaseReport(NameError, name.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		name.Name,
 	}
 }
@@ -179,9 +294,9 @@ type RepeatedCtorError struct {
 	Ctor string
 }
 
-func NewRepeatedCtorError(decl ast.Decl, name *ast.Ident) *RepeatedCtorError {
+func NewRepeatedCtorError(file string, decl ast.Decl, name *ast.Ident) *RepeatedCtorError {
 	return &RepeatedCtorError{
-		NewBaseReport(NameError, name.Pos(), "", RegionFromNode(decl)),
+		NewBaseReport(NameError, NodeLocation(file, name), "", RegionFromNode(decl)),
 		name.Name,
 	}
 }
@@ -192,35 +307,180 @@ func (e RepeatedCtorError) Message() string {
 
 type UnresolvedNameError struct {
 	BaseReport
-	Name string
+	FixList
+	Name        string
+	Suggestions []string
 }
 
-func NewUnresolvedNameError(name string, node *ast.Ident) *UnresolvedNameError {
+func NewUnresolvedNameError(file string, name string, node *ast.Ident, suggestions ...string) *UnresolvedNameError {
 	return &UnresolvedNameError{
-		NewBaseReport(NameError, node.Pos(), "", nil),
-		name,
+		BaseReport:  NewBaseReport(NameError, NodeLocation(file, node), "", nil),
+		Name:        name,
+		Suggestions: suggestions,
 	}
 }
 
 func (e *UnresolvedNameError) Message() string {
-	return fmt.Sprintf("I could not find any definition for %q.", e.Name)
+	return fmt.Sprintf("I could not find any definition for %q.", e.Name) +
+		suggestionsMessage(e.Suggestions)
+}
+
+// Type errors
+
+type TypeMismatchError struct {
+	BaseReport
+	Expected    string
+	Actual      string
+	Reason      string
+	Suggestions []string
+}
+
+// NewTypeMismatchError creates a new report for a type mismatch found while
+// checking the given node. Suggestions are names that could be used instead
+// of the one in the node and would have the expected type.
+func NewTypeMismatchError(file string, node ast.Node, err *types.MismatchError, suggestions ...string) *TypeMismatchError {
+	types := types.TypeStrings(err.Expected, err.Actual)
+	return &TypeMismatchError{
+		NewBaseReport(TypeError, NodeLocation(file, node), "", RegionFromNode(node)),
+		types[0],
+		types[1],
+		err.Reason,
+		suggestions,
+	}
+}
+
+func (e *TypeMismatchError) Message() string {
+	msg := fmt.Sprintf(
+		"I was expecting this to be of type:\n\n    %s\n\nBut it is of type:\n\n    %s",
+		e.Expected,
+		e.Actual,
+	)
+
+	if e.Reason != "" {
+		msg += fmt.Sprintf("\n\nThe types do not match because %s.", e.Reason)
+	}
+
+	return msg + suggestionsMessage(e.Suggestions)
+}
+
+type InfiniteTypeError struct {
+	BaseReport
+	Name  string
+	Var   string
+	Bound string
+	Marks string
+	Hint  types.InfiniteTypeHint
+}
+
+// NewInfiniteTypeError creates a new report for an infinite type found while
+// checking the given node. Name is the name of the definition whose type is
+// infinite, if any.
+func NewInfiniteTypeError(file string, node ast.Node, name string, err *types.InfiniteTypeError) *InfiniteTypeError {
+	v, typ, marks := err.Highlight()
+	return &InfiniteTypeError{
+		NewBaseReport(TypeError, NodeLocation(file, node), "", RegionFromNode(node)),
+		name,
+		v,
+		typ,
+		marks,
+		err.Hint(),
+	}
+}
+
+const (
+	infiniteTypeMissingArgHint = `Hint: Infinite types are usually caused by a function that is missing an argument. Check that all the functions used here are given all their arguments.`
+	infiniteTypeFlippedHint    = `Hint: Infinite types are usually caused by passing the arguments of a function in the wrong order, e.g. "xs :: x" instead of "x :: xs".`
+)
+
+func (e *InfiniteTypeError) Message() string {
+	var subject = "this expression"
+	if e.Name != "" {
+		subject = fmt.Sprintf("%q", e.Name)
+	}
+
+	prefix := e.Var + " = "
+	msg := fmt.Sprintf(
+		"I am inferring a weird self-referential type for %s:\n\n    %s%s\n    %s%s\n\nThe type %s would need to contain itself, so it would be infinite.",
+		subject,
+		prefix,
+		e.Bound,
+		strings.Repeat(" ", len(prefix)),
+		e.Marks,
+		e.Var,
+	)
+
+	switch e.Hint {
+	case types.MissingArgument:
+		msg += "\n\n" + infiniteTypeMissingArgHint
+	case types.FlippedArguments:
+		msg += "\n\n" + infiniteTypeFlippedHint
+	}
+	return msg
+}
+
+// NewTypeError creates the report for an error found by the type checker,
+// in the file of its path. Mismatches found in names are reported with the
+// names of the candidates that are most similar to them as suggestions.
+func NewTypeError(err *types.Error) Report {
+	switch e := err.Err.(type) {
+	case *types.InfiniteTypeError:
+		return NewInfiniteTypeError(err.Path, err.Node, err.Name, e)
+	case *types.MismatchError:
+		var suggestions []string
+		for _, name := range err.Names {
+			for _, s := range Suggest(name, err.Candidates) {
+				if !contains(suggestions, s) {
+					suggestions = append(suggestions, s)
+				}
+			}
+		}
+		return NewTypeMismatchError(err.Path, err.Node, e, suggestions...)
+	case *types.MainError:
+		return NewBaseReport(
+			TypeError,
+			NodeLocation(err.Path, err.Node),
+			fmt.Sprintf(
+				"I cannot run a main value of type:\n\n    %s\n\nThe main value must be a Program, such as the ones created with Html.program, or an Html value.",
+				types.TypeString(e.Type),
+			),
+			RegionFromNode(err.Node),
+		)
+	case *types.IntRangeError:
+		return NewBaseReport(
+			Warning,
+			NodeLocation(err.Path, err.Node),
+			fmt.Sprintf(
+				"The number %s is too big to be represented exactly, so it may lose precision. Integers must be between -%d and %d.",
+				e.Value, e.Max, e.Max,
+			),
+			RegionFromNode(err.Node),
+		)
+	}
+
+	var typ = TypeError
+	if err.Soft {
+		typ = Warning
+	}
+
+	msg := strings.TrimPrefix(err.Error(), "types: ")
+	return NewBaseReport(typ, NodeLocation(err.Path, err.Node), msg, RegionFromNode(err.Node))
 }
 
 // Parse errors
 
-func NewExpectedTypeError(pos token.Pos, region *Region) Report {
+func NewExpectedTypeError(file string, pos token.Pos, region *Region) Report {
 	return NewBaseReport(
 		SyntaxError,
-		pos,
+		token.At(file, pos),
 		"I was expecting a type, but I encountered what looks like a declaration instead.",
 		region,
 	)
 }
 
-func NewUnexpectedEOFError(pos token.Pos, region *Region) Report {
+func NewUnexpectedEOFError(file string, pos token.Pos, region *Region) Report {
 	return NewBaseReport(
 		SyntaxError,
-		pos,
+		token.At(file, pos),
 		"Unexpected end of file.",
 		region,
 	)
@@ -232,9 +492,10 @@ type UnexpectedTokenError struct {
 	Expected []token.Type
 }
 
-func NewUnexpectedTokenError(tok *token.Token, region *Region, expected ...token.Type) *UnexpectedTokenError {
+func NewUnexpectedTokenError(file string, tok *token.Token, region *Region, expected ...token.Type) *UnexpectedTokenError {
+	loc := token.Location{File: file, Span: token.Span{Start: tok.Offset, End: tok.Offset + token.Pos(len(tok.Value))}}
 	return &UnexpectedTokenError{
-		NewBaseReport(SyntaxError, tok.Offset, "", region),
+		NewBaseReport(SyntaxError, loc, "", region),
 		tok,
 		expected,
 	}
@@ -51,35 +51,77 @@ func (t ReportType) Color() func(string, ...interface{}) string {
 type Report interface {
 	Type() ReportType
 	Message() string
-	Pos() token.Pos
+	// Location is the file and the code the report is about, which has no
+	// position if the report is about the whole file.
+	Location() token.Location
+	// Region is the code shown with the report, if any, which is around its
+	// location.
 	Region() *Region
 }
 
 type BaseReport struct {
 	typ    ReportType
-	pos    token.Pos
+	loc    token.Location
 	msg    string
 	region *Region
 }
 
-func NewBaseReport(typ ReportType, pos token.Pos, msg string, region *Region) BaseReport {
-	return BaseReport{typ, pos, msg, region}
+func NewBaseReport(typ ReportType, loc token.Location, msg string, region *Region) BaseReport {
+	return BaseReport{typ, loc, msg, region}
 }
 
-func (r BaseReport) Type() ReportType { return r.typ }
-func (r BaseReport) Message() string  { return r.msg }
-func (r BaseReport) Pos() token.Pos   { return r.pos }
-func (r BaseReport) Region() *Region  { return r.region }
+func (r BaseReport) Type() ReportType         { return r.typ }
+func (r BaseReport) Message() string          { return r.msg }
+func (r BaseReport) Location() token.Location { return r.loc }
+func (r BaseReport) Region() *Region          { return r.region }
 
 func AsError(report Report) error {
 	return errors.New(report.Message())
 }
 
+// Fix is a change of the source code that fixes the problem of a report:
+// the code between Pos and End is replaced with Text, so it is inserted if
+// they are the same position.
+type Fix struct {
+	Message string
+	Pos     token.Pos
+	End     token.Pos
+	Text    string
+}
+
+// Fixer is a report that knows how its problem can be fixed.
+type Fixer interface {
+	Fixes() []Fix
+}
+
+// FixList are the fixes of a report. The reports that embed it implement
+// the Fixer interface.
+type FixList struct {
+	fixes []Fix
+}
+
+// AddFix adds a fix to the list.
+func (l *FixList) AddFix(fix Fix) { l.fixes = append(l.fixes, fix) }
+
+// Fixes returns the fixes in the list.
+func (l *FixList) Fixes() []Fix { return l.fixes }
+
 type Diagnostic struct {
 	Type    ReportType
 	Message string
 	Pos     source.LinePos
 	Region  *source.Snippet
+	// Fixes are the changes of the code that fix the problem, if any.
+	Fixes []DiagnosticFix
+}
+
+// DiagnosticFix is a fix of a diagnostic: the code between Start and End
+// is replaced with Text.
+type DiagnosticFix struct {
+	Message string
+	Start   source.LinePos
+	End     source.LinePos
+	Text    string
 }
 
 type Region struct {
@@ -90,3 +132,8 @@ type Region struct {
 func RegionFromNode(node ast.Node) *Region {
 	return &Region{node.Pos(), node.End()}
 }
+
+// NodeLocation returns the location of the node in the given file.
+func NodeLocation(file string, node ast.Node) token.Location {
+	return token.Location{File: file, Span: token.Span{Start: node.Pos(), End: node.End()}}
+}
@@ -2,7 +2,6 @@ package report
 
 import (
 	"github.com/elm-tangram/tangram/source"
-	"github.com/elm-tangram/tangram/token"
 )
 
 // Reporter is in charge of reporting the diagnostics occurred during any of
@@ -23,6 +22,32 @@ func (r *Reporter) IsOK() bool {
 	return len(r.reports) == 0
 }
 
+// HasErrors returns true if any of the diagnostics so far is not a warning.
+func (r *Reporter) HasErrors() bool {
+	for _, reports := range r.reports {
+		for _, report := range reports {
+			if report.Type() != Warning {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Errors returns the number of the diagnostics so far that are not
+// warnings.
+func (r *Reporter) Errors() int {
+	var errors int
+	for _, reports := range r.reports {
+		for _, report := range reports {
+			if report.Type() != Warning {
+				errors++
+			}
+		}
+	}
+	return errors
+}
+
 func (r *Reporter) Reports(path string) []Report {
 	return r.reports[path]
 }
@@ -32,7 +57,7 @@ func (r *Reporter) Emit() error {
 	for file, reports := range r.reports {
 		var ds = make([]*Diagnostic, 0, len(reports))
 		for _, report := range reports {
-			d, err := r.makeDiagnostic(file, report)
+			d, err := r.makeDiagnostic(report)
 			if err != nil {
 				return err
 			}
@@ -47,23 +72,26 @@ func (r *Reporter) Emit() error {
 	return nil
 }
 
-// Report adds a new report occurred at some path.
-func (r *Reporter) Report(path string, report Report) {
-	r.reports[path] = append(r.reports[path], report)
+// Report adds a new report, which is emitted with the ones of the file of
+// its location.
+func (r *Reporter) Report(report Report) {
+	file := report.Location().File
+	r.reports[file] = append(r.reports[file], report)
 }
 
 // makeDiagnostic transforms a report into a diagnostic, with the affected
-// snippet of code, if there is any.
-func (r *Reporter) makeDiagnostic(path string, report Report) (*Diagnostic, error) {
-	if report.Pos() == token.NoPos {
+// snippet of code of the file of its location, if there is any.
+func (r *Reporter) makeDiagnostic(report Report) (*Diagnostic, error) {
+	loc := report.Location()
+	if !loc.IsValid() {
 		return &Diagnostic{
 			Type:    report.Type(),
 			Message: report.Message(),
 		}, nil
 	}
 
-	src := r.cm.Source(path)
-	pos, err := src.LinePos(report.Pos())
+	src := r.cm.Source(loc.File)
+	pos, err := src.LinePos(loc.Pos())
 	if err != nil {
 		return nil, err
 	}
@@ -77,10 +105,25 @@ func (r *Reporter) makeDiagnostic(path string, report Report) (*Diagnostic, erro
 		}
 	}
 
+	var fixes []DiagnosticFix
+	if fixer, ok := report.(Fixer); ok {
+		for _, f := range fixer.Fixes() {
+			fix := DiagnosticFix{Message: f.Message, Text: f.Text}
+			if fix.Start, err = src.LinePos(f.Pos); err != nil {
+				return nil, err
+			}
+			if fix.End, err = src.LinePos(f.End); err != nil {
+				return nil, err
+			}
+			fixes = append(fixes, fix)
+		}
+	}
+
 	return &Diagnostic{
 		Type:    report.Type(),
 		Message: report.Message(),
 		Pos:     pos,
 		Region:  snippet,
+		Fixes:   fixes,
 	}, nil
 }
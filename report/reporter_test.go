@@ -0,0 +1,47 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/token"
+
+	"github.com/stretchr/testify/require"
+)
+
+// emitted is an emitter that keeps the diagnostics of every file.
+type emitted map[string][]*Diagnostic
+
+func (e emitted) Emit(file string, diagnostics []*Diagnostic) error {
+	e[file] = append(e[file], diagnostics...)
+	return nil
+}
+
+func TestReporterLocations(t *testing.T) {
+	require := require.New(t)
+	loader := source.NewMemLoader()
+	loader.Add("A.elm", "module A exposing (..)\n\na =\n    b\n")
+	loader.Add("B.elm", "module B exposing (..)\nb =\n    1\n")
+
+	cm := source.NewCodeMap(loader)
+	defer cm.Close()
+	require.NoError(cm.Add("A.elm"))
+	require.NoError(cm.Add("B.elm"))
+
+	var e = make(emitted)
+	r := NewReporter(cm, e)
+	r.Report(NewBaseReport(NameError, token.At("A.elm", 32), "a", nil))
+	r.Report(NewBaseReport(TypeError, token.Location{File: "B.elm", Span: token.Span{Start: 23, End: 24}}, "b", &Region{23, 24}))
+	r.Report(NewBaseReport(SyntaxError, token.Location{File: "B.elm"}, "c", nil))
+	require.Len(r.Reports("A.elm"), 1)
+	require.Len(r.Reports("B.elm"), 2)
+	require.NoError(r.Emit())
+
+	require.Equal(emitted{
+		"A.elm": {{Type: NameError, Message: "a", Pos: source.LinePos{Line: 4, Col: 5}}},
+		"B.elm": {
+			{Type: TypeError, Message: "b", Pos: source.LinePos{Line: 2, Col: 1}, Region: &source.Snippet{Start: 2, Lines: []string{"b"}}},
+			{Type: SyntaxError, Message: "c"},
+		},
+	}, e)
+}
@@ -0,0 +1,163 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSuggestions is the maximum number of suggestions given for a name.
+const maxSuggestions = 3
+
+// Suggest returns the names in candidates that are similar enough to the
+// given name to be suggested as an alternative for it, sorted from the most
+// similar to the least similar. At most three names are returned. Operators
+// are neither suggested nor given suggestions, because a similar operator is
+// not what was meant.
+func Suggest(name string, candidates []string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	if isOperator(name) {
+		return nil
+	}
+
+	var threshold = len(name) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	var seen = make(map[string]struct{})
+	var suggestions []suggestion
+	for _, c := range candidates {
+		if _, ok := seen[c]; ok || c == name || isOperator(c) {
+			continue
+		}
+		seen[c] = struct{}{}
+
+		d := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if d <= threshold {
+			suggestions = append(suggestions, suggestion{c, d})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance == suggestions[j].distance {
+			return suggestions[i].name < suggestions[j].name
+		}
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	var result []string
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		result = append(result, suggestions[i].name)
+	}
+	return result
+}
+
+// SuggestModule returns the modules in candidates that may be the one meant
+// by the given module name, which are the ones with the same name and a
+// different qualifier, such as `Json.Decode` for `Decode`, followed by the
+// ones that are similar to it. At most three modules are returned.
+func SuggestModule(name string, candidates []string) []string {
+	var result []string
+	for _, c := range candidates {
+		if c != name && lastPart(c) == lastPart(name) && len(result) < maxSuggestions {
+			result = append(result, c)
+		}
+	}
+
+	for _, s := range Suggest(name, candidates) {
+		if len(result) == maxSuggestions {
+			break
+		}
+
+		if !contains(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// isOperator reports whether the name is the one of an operator, which does
+// not start with a letter.
+func isOperator(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return name != "" && !unicode.IsLetter(r) && r != '_'
+}
+
+// lastPart returns the last part of a qualified name.
+func lastPart(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	var d = make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func min(n int, ns ...int) int {
+	for _, x := range ns {
+		if x < n {
+			n = x
+		}
+	}
+	return n
+}
+
+// suggestionsMessage returns the part of a message offering the given
+// suggestions to the user, if there are any.
+func suggestionsMessage(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	var list = make([]string, len(suggestions))
+	for i, s := range suggestions {
+		list[i] = fmt.Sprintf("    %s", s)
+	}
+
+	return fmt.Sprintf(
+		"\n\nMaybe you want one of the following?\n\n%s",
+		strings.Join(list, "\n"),
+	)
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"maybeStr", "map", "foldl", "foldr", "filter", "fold", "Just", "length", "x", "+"}
+	cases := []struct {
+		name     string
+		expected []string
+	}{
+		{"mabyeStr", []string{"maybeStr"}},
+		{"fodl", []string{"fold", "foldl"}},
+		{"fodlr", []string{"foldr"}},
+		{"just", []string{"Just"}},
+		{"lenght", []string{"length"}},
+		{"zzzzzz", nil},
+		{"map", nil},
+		{"*", nil},
+		{"y", []string{"x"}},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, Suggest(c.name, candidates), c.name)
+	}
+}
+
+func TestSuggestModule(t *testing.T) {
+	candidates := []string{"Json.Decode", "Json.Encode", "Html", "Html.Attributes", "Html.Events", "Http", "Page.Decode"}
+	cases := []struct {
+		name     string
+		expected []string
+	}{
+		{"Decode", []string{"Json.Decode", "Page.Decode"}},
+		{"Json.Decod", []string{"Json.Decode", "Json.Encode"}},
+		{"Html.Attribute", []string{"Html.Attributes"}},
+		{"Htlm", []string{"Html"}},
+		{"Svg", nil},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, SuggestModule(c.name, candidates), c.name)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"foo", "foo", 0},
+		{"foo", "fo", 1},
+		{"foo", "fooo", 1},
+		{"foo", "bar", 3},
+		{"ab", "ba", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, editDistance(c.a, c.b), "%s -> %s", c.a, c.b)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/elm-tangram/tangram/token"
 )
@@ -51,6 +53,11 @@ type Scanner struct {
 	line    int
 	linePos int
 	word    []rune
+	// buf is the buffer where the words are encoded to be interned.
+	buf []byte
+	// names interns the words of the source, which are released with the
+	// scanner.
+	names *token.Interner
 
 	idx    int
 	tokens []*token.Token
@@ -63,6 +70,7 @@ func New(source string, input io.Reader) *Scanner {
 		reader: bufio.NewReader(input),
 		state:  lexExpr,
 		line:   1,
+		names:  token.NewInterner(),
 	}
 }
 
@@ -110,9 +118,25 @@ func (l *Scanner) peekWord() string {
 	return string(l.word)
 }
 
+// internWord returns the text of the current word, which is interned so
+// every identifier and operator is allocated only once.
+func (l *Scanner) internWord() string {
+	l.buf = l.buf[:0]
+	for _, r := range l.word {
+		l.buf = utf8.AppendRune(l.buf, r)
+	}
+	return l.names.InternBytes(l.buf).String()
+}
+
 // emit sends the token to the consumer.
 func (l *Scanner) emit(t token.Type) {
-	word := l.peekWord()
+	var word string
+	switch t {
+	case token.Identifier, token.Op, token.InfixOp:
+		word = l.internWord()
+	default:
+		word = l.peekWord()
+	}
 	l.word = nil
 	l.tokens = append(l.tokens, token.New(
 		t,
@@ -318,6 +342,25 @@ func (l *Scanner) Reset() {
 	l.idx = 0
 }
 
+// Clone returns a scanner with the tokens of this one, which starts from
+// the first one.
+func (l *Scanner) Clone() *Scanner {
+	return &Scanner{source: l.source, tokens: l.tokens}
+}
+
+// From returns a scanner with the tokens of this one, whose next token is
+// the given one, so part of the source can be parsed again without being
+// scanned again.
+func (l *Scanner) From(tok *token.Token) *Scanner {
+	idx := sort.Search(len(l.tokens), func(i int) bool {
+		return l.tokens[i].Offset >= tok.Offset
+	})
+	for idx < len(l.tokens) && l.tokens[idx] != tok {
+		idx++
+	}
+	return &Scanner{source: l.source, tokens: l.tokens, idx: idx}
+}
+
 // lexLeftParen scans the left paren, which is known to be present.
 func lexLeftParen(l *Scanner) (stateFunc, error) {
 	l.emit(token.LeftParen)
@@ -786,6 +829,9 @@ var keywords = map[string]token.Type{
 	"module":   token.Module,
 	"exposing": token.Exposing,
 	"import":   token.Import,
+	"effect":   token.Effect,
+	"where":    token.Where,
+	"port":     token.Port,
 	"True":     token.True,
 	"False":    token.False,
 }
@@ -794,3 +840,19 @@ func isKeyword(lit string) (token.Type, bool) {
 	typ, ok := keywords[lit]
 	return typ, ok
 }
+
+// IsIdentifier reports if the name is a valid identifier, that is, it
+// starts with a letter, the rest of it are letters, digits or underscores
+// and it is not a keyword.
+func IsIdentifier(name string) bool {
+	if _, ok := isKeyword(name); ok || name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		if i == 0 && !unicode.IsLetter(r) || !isAllowedInIdentifier(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -20,6 +20,16 @@ func TestIsAllowedInIdentifier(t *testing.T) {
 	}
 }
 
+func TestIsIdentifier(t *testing.T) {
+	for _, name := range []string{"foo", "Foo", "foo_bar1", "áb"} {
+		require.True(t, IsIdentifier(name), name)
+	}
+
+	for _, name := range []string{"", "1foo", "_foo", "foo-bar", "foo.bar", "let", "True", "+"} {
+		require.False(t, IsIdentifier(name), name)
+	}
+}
+
 func TestLexNumber(t *testing.T) {
 	require := require.New(t)
 
@@ -0,0 +1,155 @@
+package source
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/elm-tangram/tangram/scanner"
+)
+
+// Cache is a cache of the sources of the files, with their lines and their
+// tokens, shared by the code maps of the repeated parses of a package, such
+// as the ones of the language server or of the watch mode. The sources are
+// kept by their path and the hash of their content, so a file is only
+// scanned again once it changes.
+//
+// Every code map that has a source of the cache holds a reference to it
+// until it is closed, and the sources that are not referenced are removed
+// once there is a newer version of their file. It is safe to use a cache
+// from several goroutines.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	latest  map[string]cacheKey
+}
+
+type cacheKey struct {
+	path string
+	hash [sha256.Size]byte
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	content []byte
+	refs    int
+
+	once      sync.Once
+	lineIndex []lineInfo
+	scanner   *scanner.Scanner
+	err       error
+
+	mu     sync.Mutex
+	header interface{}
+}
+
+// NewCache creates a new empty cache.
+func NewCache() *Cache {
+	return &Cache{
+		entries: make(map[cacheKey]*cacheEntry),
+		latest:  make(map[string]cacheKey),
+	}
+}
+
+// Loader returns a loader that reads the files with the given loader, and
+// takes their sources from the cache.
+func (c *Cache) Loader(loader Loader) Loader {
+	return &cacheLoader{loader, c}
+}
+
+// Len returns the number of sources in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) acquire(path string, content []byte) *cachedFile {
+	key := cacheKey{path, sha256.Sum256(content)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{key: key, content: content}
+		c.entries[key] = e
+	}
+	e.refs++
+
+	if prev, ok := c.latest[path]; ok && prev != key && c.entries[prev].refs == 0 {
+		delete(c.entries, prev)
+	}
+	c.latest[path] = key
+	return &cachedFile{Reader: bytes.NewReader(e.content), cache: c, entry: e}
+}
+
+func (c *Cache) release(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.refs--
+	if e.refs == 0 && c.latest[e.key.path] != e.key {
+		delete(c.entries, e.key)
+	}
+}
+
+// cacheLoader is a loader whose files are in a cache.
+type cacheLoader struct {
+	Loader
+	cache *Cache
+}
+
+// Load reads the file with the other loader and returns its content in the
+// cache.
+func (l *cacheLoader) Load(path string) (io.ReadSeeker, error) {
+	src, err := l.Loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(src)
+	if c, ok := src.(io.Closer); ok {
+		c.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.cache.acquire(path, content), nil
+}
+
+// cachedFile is the content of a file in the cache, which releases its
+// source when it is closed.
+type cachedFile struct {
+	*bytes.Reader
+	cache  *Cache
+	entry  *cacheEntry
+	closed bool
+}
+
+func (f *cachedFile) Close() error {
+	if !f.closed {
+		f.closed = true
+		f.cache.release(f.entry)
+	}
+	return nil
+}
+
+// source returns the source with the given path of the file, whose lines
+// and tokens are only found the first time.
+func (f *cachedFile) source(path string) (*Source, error) {
+	e := f.entry
+	e.once.Do(func() {
+		s := &Source{Path: path, Src: bytes.NewReader(e.content)}
+		if e.err = s.makeLineIndex(); e.err != nil {
+			return
+		}
+		e.lineIndex = s.lineIndex
+		e.scanner = s.Scanner()
+	})
+
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &Source{path, f, e.lineIndex, e.scanner.Clone()}, nil
+}
@@ -0,0 +1,53 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	require := require.New(t)
+
+	mem := NewMemLoader()
+	mem.Add("Foo.elm", sourceFixture)
+	cache := NewCache()
+	loader := cache.Loader(mem)
+
+	cm := NewCodeMap(loader)
+	require.NoError(cm.Add("Foo.elm"))
+	src := cm.Source("Foo.elm")
+	require.Equal(1, cache.Len())
+	require.Nil(src.Header())
+	src.SetHeader("header")
+
+	other := NewCodeMap(loader)
+	require.NoError(other.Add("Foo.elm"))
+	require.Equal(1, cache.Len())
+	require.Equal("header", other.Source("Foo.elm").Header())
+	require.True(src.Scanner() != other.Source("Foo.elm").Scanner())
+	require.Equal(src.Scanner().Next(), other.Source("Foo.elm").Scanner().Next())
+
+	snippet, err := other.Source("Foo.elm").Region(src.Scanner().Next().Offset, 10)
+	require.NoError(err)
+	require.Equal([]string{"map : (a "}, snippet.Lines)
+
+	// the old version is kept until the code maps that use it are closed
+	mem.Add("Foo.elm", "module Foo exposing (..)\n")
+	changed := NewCodeMap(loader)
+	require.NoError(changed.Add("Foo.elm"))
+	require.Equal(2, cache.Len())
+	require.Nil(changed.Source("Foo.elm").Header())
+
+	require.NoError(cm.Close())
+	require.Equal(2, cache.Len())
+	require.NoError(other.Close())
+	require.Equal(1, cache.Len())
+	require.NoError(changed.Close())
+	require.Equal(1, cache.Len())
+
+	var plain = NewCodeMap(mem)
+	require.NoError(plain.Add("Foo.elm"))
+	plain.Source("Foo.elm").SetHeader("header")
+	require.Nil(plain.Source("Foo.elm").Header())
+}
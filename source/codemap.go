@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"io/ioutil"
 	"strings"
 	"unicode/utf8"
 
@@ -43,6 +44,11 @@ func (cm *CodeMap) Add(path string) error {
 	return nil
 }
 
+// Loader returns the loader of the files of the code map.
+func (cm *CodeMap) Loader() Loader {
+	return cm.loader
+}
+
 // Close closes all the source files that implement io.Closer.
 func (cm *CodeMap) Close() error {
 	for _, f := range cm.files {
@@ -86,6 +92,10 @@ func (li lineInfo) inLine(pos token.Pos) bool {
 }
 
 func NewSource(path string, src io.ReadSeeker) (*Source, error) {
+	if f, ok := src.(*cachedFile); ok {
+		return f.source(path)
+	}
+
 	s := &Source{path, src, nil, nil}
 	if err := s.makeLineIndex(); err != nil {
 		return nil, err
@@ -224,10 +234,56 @@ func (s *Source) Region(start, end token.Pos) (*Snippet, error) {
 	return &snippet, nil
 }
 
-// Scanner returns a scanner for this source with all the tokens parsed.
+// Line returns the line of the source code with the given position.
+func (s *Source) Line(pos token.Pos) (*Snippet, error) {
+	if len(s.lineIndex) == 0 {
+		return &Snippet{1, []string{""}}, nil
+	}
+
+	start, lineNo := s.findLineStart(pos)
+	if lineNo > len(s.lineIndex) {
+		return &Snippet{lineNo, []string{""}}, nil
+	}
+	return s.Region(start, s.lineIndex[lineNo-1].end)
+}
+
+// Header returns the value set with SetHeader by a previous parse of the
+// same content of the file, if the source is from a Cache.
+func (s *Source) Header() interface{} {
+	f, ok := s.Src.(*cachedFile)
+	if !ok {
+		return nil
+	}
+
+	f.entry.mu.Lock()
+	defer f.entry.mu.Unlock()
+	return f.entry.header
+}
+
+// SetHeader keeps a value for the next parses of the same content of the
+// file, such as its parsed header, if the source is from a Cache. The value
+// is shared by all of them, so it must not be modified.
+func (s *Source) SetHeader(header interface{}) {
+	if f, ok := s.Src.(*cachedFile); ok {
+		f.entry.mu.Lock()
+		f.entry.header = header
+		f.entry.mu.Unlock()
+	}
+}
+
+// Scanner returns a scanner for this source with all the tokens parsed. The
+// tokens of a literate module are the ones of its Elm code.
 func (s *Source) Scanner() *scanner.Scanner {
 	if s.scanner == nil {
-		s.scanner = scanner.New(s.Path, s.Src)
+		var src io.Reader = s.Src
+		if IsLiterate(s.Path) {
+			content, err := ioutil.ReadAll(s.Src)
+			s.Src.Seek(0, io.SeekStart)
+			if err == nil {
+				src = bytes.NewReader(Literate(content))
+			}
+		}
+		s.scanner = scanner.New(s.Path, src)
 		s.scanner.Run()
 	}
 
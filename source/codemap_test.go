@@ -1,6 +1,8 @@
 package source
 
 import (
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
@@ -117,4 +119,39 @@ func TestSource(t *testing.T) {
 			require.Equal(c.line, snippet.Start, c.name)
 		}
 	})
+
+	t.Run("Line", func(t *testing.T) {
+		require := require.New(t)
+
+		snippet, err := s.Line(60)
+		require.NoError(err)
+		require.Equal(&Snippet{4, []string{`  foldr (\x acc -> f x :: acc) [] xs`}}, snippet)
+
+		snippet, err = s.Line(140)
+		require.NoError(err)
+		require.Equal(&Snippet{8, []string{expectedWithTab}}, snippet)
+	})
+}
+
+func TestOverlayLoader(t *testing.T) {
+	require := require.New(t)
+	mem := NewMemLoader()
+	mem.Add("/src/A.elm", "module A exposing (..)")
+	mem.Add("/src/B.elm", "module B exposing (..)")
+	l := NewOverlayLoader(mem, Overlay{"/src/A.elm": "module A exposing (a)"})
+
+	for path, expected := range map[string]string{
+		"/src/A.elm":        "module A exposing (a)",
+		"/src/../src/A.elm": "module A exposing (a)",
+		"/src/B.elm":        "module B exposing (..)",
+	} {
+		src, err := l.Load(path)
+		require.NoError(err)
+		content, err := ioutil.ReadAll(src)
+		require.NoError(err)
+		require.Equal(expected, string(content), path)
+	}
+
+	_, err := l.Load("/src/C.elm")
+	require.Equal(os.ErrNotExist, err)
 }
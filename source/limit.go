@@ -0,0 +1,138 @@
+package source
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Limits are the limits of the files a loader reads, so the programs that
+// embed the compiler, such as playgrounds or CI bots, can not run out of
+// memory parsing whatever code they are given. A zero limit is no limit.
+type Limits struct {
+	// MaxFileSize is the maximum size of a file, and MaxSize the maximum
+	// size of all the files, in bytes.
+	MaxFileSize, MaxSize int
+	// MaxModules is the maximum number of files, which are the modules of
+	// the package being parsed.
+	MaxModules int
+}
+
+// Limit is one of the limits of Limits.
+type Limit byte
+
+const (
+	// FileSizeLimit is the limit of the size of a file.
+	FileSizeLimit Limit = iota
+	// SizeLimit is the limit of the size of all the files.
+	SizeLimit
+	// ModulesLimit is the limit of the number of files.
+	ModulesLimit
+)
+
+// LimitError is the error of loading a file that goes over one of the
+// limits of a loader.
+type LimitError struct {
+	// Path of the file.
+	Path string
+	// Limit is the limit the file goes over.
+	Limit Limit
+	// Value is the size or the number of files with the file, and Max the
+	// limit.
+	Value, Max int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case FileSizeLimit:
+		return fmt.Sprintf("the file %s has %d bytes, but the maximum size of a file is %d bytes", e.Path, e.Value, e.Max)
+	case SizeLimit:
+		return fmt.Sprintf("the files add up to %d bytes with %s, but the maximum size of all the files is %d bytes", e.Value, e.Path, e.Max)
+	default:
+		return fmt.Sprintf("%s is the module number %d, but the maximum number of modules is %d", e.Path, e.Value, e.Max)
+	}
+}
+
+// limitLoader is a loader that returns a LimitError instead of the files
+// that go over its limits.
+type limitLoader struct {
+	Loader
+	limits Limits
+
+	mu    sync.Mutex
+	sizes map[string]int
+	size  int
+}
+
+// NewLimitLoader returns a loader that reads the files with the given loader
+// as long as they are within the limits. The size of a file is found before
+// reading it, so a file over the limits is never read into memory. Loading
+// the same path again does not count twice.
+func NewLimitLoader(loader Loader, limits Limits) Loader {
+	return &limitLoader{Loader: loader, limits: limits, sizes: make(map[string]int)}
+}
+
+// Load retrieves the content of the given path from the other loader, or
+// returns a LimitError if it goes over the limits.
+func (l *limitLoader) Load(path string) (io.ReadSeeker, error) {
+	src, err := l.Loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := readerSize(src)
+	if err == nil {
+		err = l.add(path, size)
+	}
+
+	if err != nil {
+		if c, ok := src.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// add adds the file with the given path and size to the files loaded, if it
+// is within the limits.
+func (l *limitLoader) add(path string, size int) error {
+	if l.limits.MaxFileSize > 0 && size > l.limits.MaxFileSize {
+		return &LimitError{path, FileSizeLimit, size, l.limits.MaxFileSize}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prev, ok := l.sizes[path]
+	if !ok && l.limits.MaxModules > 0 && len(l.sizes) >= l.limits.MaxModules {
+		return &LimitError{path, ModulesLimit, len(l.sizes) + 1, l.limits.MaxModules}
+	}
+
+	total := l.size - prev + size
+	if l.limits.MaxSize > 0 && total > l.limits.MaxSize {
+		return &LimitError{path, SizeLimit, total, l.limits.MaxSize}
+	}
+
+	l.sizes[path] = size
+	l.size = total
+	return nil
+}
+
+// readerSize returns the size of the content of the reader from its current
+// offset, which is kept.
+func readerSize(r io.ReadSeeker) (int, error) {
+	cur, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := r.Seek(cur, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return int(end - cur), nil
+}
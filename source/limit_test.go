@@ -0,0 +1,49 @@
+package source
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitLoader(t *testing.T) {
+	require := require.New(t)
+
+	mem := NewMemLoader()
+	mem.Add("A.elm", "module A")
+	mem.Add("B.elm", "module B exposing (..)")
+	mem.Add("C.elm", "module C")
+	mem.Add("D.elm", "module D")
+
+	loader := NewLimitLoader(mem, Limits{MaxFileSize: 10, MaxSize: 20, MaxModules: 3})
+	src, err := loader.Load("A.elm")
+	require.NoError(err)
+	content, err := ioutil.ReadAll(src)
+	require.NoError(err)
+	require.Equal("module A", string(content))
+
+	_, err = loader.Load("B.elm")
+	require.Equal(&LimitError{"B.elm", FileSizeLimit, 22, 10}, err)
+	require.EqualError(err, "the file B.elm has 22 bytes, but the maximum size of a file is 10 bytes")
+
+	_, err = loader.Load("C.elm")
+	require.NoError(err)
+
+	// loading a file again does not count twice
+	_, err = loader.Load("A.elm")
+	require.NoError(err)
+
+	_, err = loader.Load("D.elm")
+	require.Equal(&LimitError{"D.elm", SizeLimit, 24, 20}, err)
+	require.EqualError(err, "the files add up to 24 bytes with D.elm, but the maximum size of all the files is 20 bytes")
+
+	mem.Add("D.elm", "D")
+	_, err = loader.Load("D.elm")
+	require.NoError(err)
+
+	mem.Add("E.elm", "E")
+	_, err = loader.Load("E.elm")
+	require.Equal(&LimitError{"E.elm", ModulesLimit, 4, 3}, err)
+	require.EqualError(err, "E.elm is the module number 4, but the maximum number of modules is 3")
+}
@@ -0,0 +1,121 @@
+package source
+
+import (
+	"bytes"
+	"path/filepath"
+)
+
+// LiterateExt is the extension of the literate modules, which are Markdown
+// files whose Elm code is in their fenced code blocks, such as guides or
+// documentation that is compiled to make sure it is up to date.
+const LiterateExt = ".md"
+
+// IsLiterate reports whether the file at the given path is a literate module.
+func IsLiterate(path string) bool {
+	return filepath.Ext(path) == LiterateExt
+}
+
+// Literate returns the Elm code of a literate module, which is the content of
+// its code blocks fenced with ``` or ~~~ and whose info string is elm, one
+// after the other. The rest of the file, the fences included, is replaced
+// by spaces, and the line breaks are kept, so every line and column of the
+// code is the same in the Markdown file and its positions need no mapping.
+// The code of a block is not unindented, so the fence of a block with
+// declarations must not be indented either.
+func Literate(content []byte) []byte {
+	var (
+		code  = make([]byte, len(content))
+		fence []byte
+		isElm bool
+	)
+
+	for start := 0; start < len(content); {
+		end := bytes.IndexByte(content[start:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += start + 1
+		}
+		line := content[start:end]
+
+		switch {
+		case fence == nil:
+			fence, isElm = openingFence(line)
+			blank(code[start:end], line)
+		case isClosingFence(line, fence):
+			fence, isElm = nil, false
+			blank(code[start:end], line)
+		case isElm:
+			copy(code[start:end], line)
+		default:
+			blank(code[start:end], line)
+		}
+		start = end
+	}
+	return code
+}
+
+// openingFence returns the fence that opens a code block in the line, which
+// is nil if there is none, and whether the code of the block is Elm.
+func openingFence(line []byte) ([]byte, bool) {
+	trimmed := bytes.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return nil, false
+	}
+
+	char := trimmed[0]
+	if char != '`' && char != '~' {
+		return nil, false
+	}
+
+	n := 0
+	for n < len(trimmed) && trimmed[n] == char {
+		n++
+	}
+	if n < 3 {
+		return nil, false
+	}
+
+	// the info string of a backtick fence can not have backticks, or the
+	// line is inline code
+	if char == '`' && bytes.IndexByte(trimmed[n:], '`') >= 0 {
+		return nil, false
+	}
+
+	info := bytes.Fields(trimmed[n:])
+	return trimmed[:n], len(info) > 0 && string(info[0]) == "elm"
+}
+
+// isClosingFence reports whether the line closes the code block opened with
+// the given fence, which it does with at least as many of its characters
+// and nothing else.
+func isClosingFence(line, fence []byte) bool {
+	trimmed := bytes.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+
+	trimmed = bytes.TrimRight(trimmed, " \t\r\n")
+	if len(trimmed) < len(fence) {
+		return false
+	}
+
+	for _, c := range trimmed {
+		if c != fence[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// blank writes the line to dst with every character replaced by a space but
+// the line breaks.
+func blank(dst, line []byte) {
+	for i, c := range line {
+		if c == '\n' || c == '\r' {
+			dst[i] = c
+		} else {
+			dst[i] = ' '
+		}
+	}
+}
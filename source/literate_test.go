@@ -0,0 +1,72 @@
+package source
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiterate(t *testing.T) {
+	require := require.New(t)
+
+	content := strings.Join([]string{
+		"# Guide",
+		"",
+		"```elm",
+		"module Guide exposing (..)",
+		"```",
+		"",
+		"Some `code` that is not Elm:",
+		"",
+		"````sh",
+		"```elm",
+		"elmo build Guide.md",
+		"````",
+		"",
+		"~~~ elm {.numbered}",
+		"answer =",
+		"    42",
+		"~~~~",
+		"```js",
+		"```",
+		"```elm",
+		"unclosed = 1",
+	}, "\n")
+
+	code := string(Literate([]byte(content)))
+	require.Len(code, len(content))
+
+	lines := strings.Split(code, "\n")
+	require.Len(lines, 21)
+	require.Equal("module Guide exposing (..)", lines[3])
+	require.Equal("answer =", lines[14])
+	require.Equal("    42", lines[15])
+	require.Equal("unclosed = 1", lines[20])
+
+	for _, i := range []int{0, 2, 4, 6, 8, 9, 10, 11, 13, 16, 17, 18, 19} {
+		require.Equal(strings.Repeat(" ", len(lines[i])), lines[i], "line %d", i+1)
+	}
+
+	require.True(IsLiterate("src/Guide.md"))
+	require.False(IsLiterate("src/Guide.elm"))
+}
+
+func TestLiterateScanner(t *testing.T) {
+	require := require.New(t)
+
+	loader := NewMemLoader()
+	loader.Add("Guide.md", "Intro\n\n```elm\nmodule Guide exposing (..)\n```\n")
+	cm := NewCodeMap(loader)
+	require.NoError(cm.Add("Guide.md"))
+
+	s := cm.Source("Guide.md").Scanner()
+	tok := s.Next()
+	require.Equal("module", tok.Value)
+	require.Equal(4, tok.Line)
+	require.Equal(1, tok.Column)
+
+	line, err := cm.Source("Guide.md").Line(tok.Offset)
+	require.NoError(err)
+	require.Equal(&Snippet{4, []string{"module Guide exposing (..)"}}, line)
+}
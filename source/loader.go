@@ -35,8 +35,17 @@ func (l *FsLoader) AbsPath(path string) string {
 	return filepath.Join(l.pkg.Root(), path)
 }
 
-// Load retrieves the source code of the file at the given module path.
+// Load retrieves the source code of the file at the given module path, or
+// the one of the synthetic module of the package at the path.
 func (l *FsLoader) Load(path string) (io.ReadSeeker, error) {
+	if l.pkg != nil {
+		if src, ok, err := l.pkg.SyntheticSource(path); err != nil {
+			return nil, err
+		} else if ok {
+			return bytes.NewReader(src), nil
+		}
+	}
+
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -74,3 +83,56 @@ func (l *MemLoader) Load(path string) (io.ReadSeeker, error) {
 
 	return nil, os.ErrNotExist
 }
+
+// PackageLoader is a loader that loads the synthetic modules of a package
+// with its providers, and the other files with another loader, such as a
+// MemLoader.
+type PackageLoader struct {
+	Loader
+	pkg *pkg.Package
+}
+
+// NewPackageLoader returns a new loader of the synthetic modules of the
+// package that loads the other files with the given loader.
+func NewPackageLoader(loader Loader, pkg *pkg.Package) *PackageLoader {
+	return &PackageLoader{loader, pkg}
+}
+
+// Load retrieves the source code of the synthetic module at the given
+// path, or the content of the file from the other loader if it is not the
+// path of one.
+func (l *PackageLoader) Load(path string) (io.ReadSeeker, error) {
+	if src, ok, err := l.pkg.SyntheticSource(path); err != nil {
+		return nil, err
+	} else if ok {
+		return bytes.NewReader(src), nil
+	}
+	return l.Loader.Load(path)
+}
+
+// Overlay is the source code of some files by their clean path, which
+// replaces the one in the file system, such as the unsaved buffers of an
+// editor.
+type Overlay map[string]string
+
+// OverlayLoader is a loader that reads the files in an overlay from it and
+// the rest with another loader.
+type OverlayLoader struct {
+	Loader
+	overlay Overlay
+}
+
+// NewOverlayLoader returns a new loader of the files in the overlay that
+// loads the other files with the given loader.
+func NewOverlayLoader(loader Loader, overlay Overlay) *OverlayLoader {
+	return &OverlayLoader{loader, overlay}
+}
+
+// Load retrieves the content of the given path from the overlay, or from
+// the other loader if it is not in it.
+func (l *OverlayLoader) Load(path string) (io.ReadSeeker, error) {
+	if s, ok := l.overlay[filepath.Clean(path)]; ok {
+		return bytes.NewReader([]byte(s)), nil
+	}
+	return l.Loader.Load(path)
+}
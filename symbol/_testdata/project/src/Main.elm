@@ -0,0 +1,21 @@
+module Main exposing (main)
+
+import Shapes exposing (Shape(..), area, (<+>))
+
+
+type alias Model a =
+    { shape : Shape
+    , tag : a
+    }
+
+
+main : Int
+main =
+    let
+        model =
+            { shape = Square Shapes.scale, tag = 1 }
+
+        double n =
+            n
+    in
+        double (area model.shape) <+> .tag model
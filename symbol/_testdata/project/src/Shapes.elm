@@ -0,0 +1,35 @@
+module Shapes exposing (Shape(..), area, scale, (<+>))
+
+{-| Shapes and their areas.
+-}
+
+
+type Shape
+    = Square Int
+    | Rect Int Int
+
+
+{-| The area of a shape.
+-}
+area : Shape -> Int
+area shape =
+    case shape of
+        Square side ->
+            side
+
+        Rect w _ ->
+            w
+
+
+scale =
+    2
+
+
+{-| Combines two areas.
+-}
+(<+>) : Int -> Int -> Int
+(<+>) a b =
+    a
+
+
+infixl 6 <+>
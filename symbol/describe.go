@@ -0,0 +1,271 @@
+package symbol
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/doc"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+)
+
+// Description is what is known about a name: how it is declared, with its
+// type if it was checked, its doc comment and the module that declares it.
+type Description struct {
+	// Code is the Elm code that declares the name, such as "module Util",
+	// "type alias Point" or "identity : a -> a".
+	Code string
+	// Doc is the doc comment of the declaration, without the delimiters,
+	// or empty if it has none.
+	Doc string
+	// Module is the name of the module that declares the name, which is
+	// empty for the modules themselves and the local names.
+	Module string
+	// Fixity is the fixity of an operator, such as "infixl 6", or empty if
+	// the name is not an operator with a fixity declaration.
+	Fixity string
+}
+
+// Markdown returns the description in markdown: the code in an Elm code
+// block, the fixity and the module it is declared in and the doc comment.
+func (d *Description) Markdown() string {
+	result := "```elm\n" + d.Code + "\n```"
+	switch {
+	case d.Fixity != "" && d.Module != "":
+		result += fmt.Sprintf("\n\n`%s`, defined in `%s`.", d.Fixity, d.Module)
+	case d.Module != "":
+		result += fmt.Sprintf("\n\nDefined in `%s`.", d.Module)
+	}
+
+	if doc := strings.TrimSpace(d.Doc); doc != "" {
+		result += "\n\n" + doc
+	}
+	return result
+}
+
+// Describe returns the description of what the identifier of the module
+// refers to or declares, or nil if it is not known. The package must be
+// the one the module was parsed in, and info the one it was checked with.
+func Describe(pkg *ast.Package, info *types.Info, mod *ast.Module, id *ast.Ident) *Description {
+	if obj := ObjectOf(pkg, mod, id); obj != nil {
+		return describe(pkg, info, mod, obj)
+	}
+
+	if t := info.TypeOf(id); t != nil {
+		return &Description{Code: id.Name + " : " + types.TypeString(t)}
+	}
+	return nil
+}
+
+// DescribeName returns the description of the top-level declaration with
+// the given name of a module of the package, or of the module itself if
+// the name is empty. It is nil if there is no such declaration.
+func DescribeName(pkg *ast.Package, info *types.Info, module, name string) *Description {
+	mod := pkg.Modules[module]
+	if mod == nil || mod.Scope == nil {
+		return nil
+	}
+
+	if name == "" {
+		return &Description{Code: "module " + mod.Name, Doc: docOf(mod, info, "")}
+	}
+
+	obj := mod.Scope.Objects[name]
+	if obj == nil {
+		return nil
+	}
+	return describe(pkg, info, mod, obj)
+}
+
+func describe(pkg *ast.Package, info *types.Info, mod *ast.Module, obj *ast.Object) *Description {
+	def := ModuleOf(pkg, mod, obj)
+	var d Description
+	switch obj.Kind {
+	case ast.Mod:
+		return &Description{Code: "module " + def.Name, Doc: docOf(def, info, "")}
+	case ast.Typ:
+		d.Code = "type " + obj.Name
+		if _, ok := obj.Node.(*ast.AliasDecl); ok {
+			d.Code = "type alias " + obj.Name
+		}
+	case ast.VarTyp:
+		return &Description{Code: obj.Name}
+	default:
+		d.Code = obj.Name
+		if t := typeOf(info, obj); t != nil {
+			d.Code += " : " + types.TypeString(t)
+		}
+	}
+
+	if def.Scope != nil && def.Scope.Objects[obj.Name] == obj {
+		d.Module = def.Name
+		d.Doc = docOf(def, info, obj.Name)
+		if op := pkg.Operator(def.Name, obj.Name); op != nil && op.Module == def.Name {
+			d.Fixity = op.Fixity()
+		}
+	}
+	return &d
+}
+
+// docOf returns the doc comment of the declaration of the module with the
+// given name, or of the module itself if the name is empty.
+func docOf(mod *ast.Module, info *types.Info, name string) string {
+	if len(mod.Comments) == 0 || mod.Module == nil {
+		return ""
+	}
+
+	d := doc.New(mod, info)
+	if name == "" {
+		return d.Comment
+	}
+
+	for _, a := range d.Aliases {
+		if a.Name == name {
+			return a.Comment
+		}
+	}
+
+	for _, u := range d.Types {
+		if u.Name == name {
+			return u.Comment
+		}
+	}
+
+	for _, v := range d.Values {
+		if v.Name == name {
+			return v.Comment
+		}
+	}
+	return ""
+}
+
+// typeOf returns the type of the object, or nil if it was not checked.
+func typeOf(info *types.Info, obj *ast.Object) types.Type {
+	if id := NameOf(obj.Node); id != nil {
+		if o := info.Defs[id]; o != nil {
+			return o.Type
+		}
+	}
+	return nil
+}
+
+// IdentAt returns the identifier of the module at the given offset, if
+// there is one.
+func IdentAt(mod *ast.Module, off token.Pos) *ast.Ident {
+	var result *ast.Ident
+	ast.WalkFunc(mod, func(n ast.Node) bool {
+		if n == nil || result != nil {
+			return false
+		}
+
+		if id, ok := n.(*ast.Ident); ok && id.NamePos != token.NoPos && off >= id.Pos() && off <= id.End() {
+			result = id
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+// NameOf returns the identifier of the name of the node defining an
+// object.
+func NameOf(node ast.Node) *ast.Ident {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return n
+	case *ast.AliasDecl:
+		return n.Name
+	case *ast.UnionDecl:
+		return n.Name
+	case *ast.Constructor:
+		return n.Name
+	case *ast.VarPattern:
+		return n.Name
+	case *ast.AliasPattern:
+		return n.Name
+	case *ast.Module:
+		if n.Module != nil {
+			if id, ok := n.Module.Name.(*ast.Ident); ok {
+				return id
+			}
+		}
+	}
+	return nil
+}
+
+// ObjectOf returns the object the identifier of the module refers to or
+// defines, if any.
+func ObjectOf(pkg *ast.Package, mod *ast.Module, id *ast.Ident) *ast.Object {
+	if id.Obj != nil {
+		return id.Obj
+	}
+
+	// the names of the definitions only are the nodes of their objects
+	var result *ast.Object
+	ast.WalkFunc(mod, func(n ast.Node) bool {
+		if result != nil {
+			return false
+		}
+
+		if scope := scopeOf(mod, n); scope != nil {
+			for _, obj := range scope.Objects {
+				if NameOf(obj.Node) == id {
+					result = obj
+					return false
+				}
+			}
+		}
+		return true
+	})
+
+	if result == nil && mod.Scope != nil {
+		for _, obj := range mod.Scope.Objects {
+			if NameOf(obj.Node) == id {
+				result = obj
+			}
+		}
+	}
+	return result
+}
+
+// scopeOf returns the scope whose root is the given node, if any.
+func scopeOf(mod *ast.Module, node ast.Node) *ast.NodeScope {
+	if mod.Scope == nil || node == nil {
+		return nil
+	}
+
+	var result *ast.NodeScope
+	var find func(*ast.NodeScope)
+	find = func(s *ast.NodeScope) {
+		for _, child := range s.Children() {
+			if result != nil {
+				return
+			}
+
+			if child.Root == node {
+				result = child
+				return
+			}
+			find(child)
+		}
+	}
+	find(mod.Scope.NodeScope)
+	return result
+}
+
+// ModuleOf returns the module of the package that defines the object,
+// which is the given one for the local objects.
+func ModuleOf(pkg *ast.Package, mod *ast.Module, obj *ast.Object) *ast.Module {
+	if m, ok := obj.Node.(*ast.Module); ok {
+		return m
+	}
+
+	for _, name := range pkg.Order {
+		m := pkg.Modules[name]
+		if m != nil && m.Scope != nil && m.Scope.Objects[obj.Name] == obj {
+			return m
+		}
+	}
+	return mod
+}
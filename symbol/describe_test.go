@@ -0,0 +1,74 @@
+package symbol
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/token"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+// checkedProject returns the checked package of the main module of the
+// test project and its source code.
+func checkedProject(t *testing.T) (*ast.Package, *types.Info, string) {
+	path := filepath.Join("_testdata", "project", "src", "Main.elm")
+	src, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	pkg, err := parser.Parse(path, parser.FullParse|parser.ParseComments)
+	require.NoError(t, err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(t, conf.Check(pkg, info))
+	return pkg, info, string(src)
+}
+
+func TestDescribe(t *testing.T) {
+	require := require.New(t)
+	pkg, info, src := checkedProject(t)
+	mod := pkg.Modules["Main"]
+
+	// describe describes the name the code starts with
+	describe := func(name string) *Description {
+		id := IdentAt(mod, token.Pos(strings.Index(src, name)))
+		require.NotNil(id, name)
+		return Describe(pkg, info, mod, id)
+	}
+
+	require.Equal(&Description{
+		Code:   "area : Shapes.Shape -> Int",
+		Doc:    " The area of a shape.\n",
+		Module: "Shapes",
+	}, describe("area model"))
+	require.Equal(&Description{Code: "module Shapes", Doc: " Shapes and their areas.\n"}, describe("Shapes.scale"))
+	require.Equal(&Description{Code: "n : a"}, describe("n =\n            n"))
+	require.Equal(&Description{Code: "type alias Model", Module: "Main"}, describe("Model a"))
+
+	require.Equal("```elm\narea : Shapes.Shape -> Int\n```\n\nDefined in `Shapes`.\n\nThe area of a shape.", describe("area model").Markdown())
+
+	require.Equal(&Description{
+		Code:   "<+> : Int -> Int -> Int",
+		Doc:    " Combines two areas.\n",
+		Module: "Shapes",
+		Fixity: "infixl 6",
+	}, describe("<+> .tag"))
+	require.Equal("```elm\n<+> : Int -> Int -> Int\n```\n\n`infixl 6`, defined in `Shapes`.\n\nCombines two areas.", describe("<+> .tag").Markdown())
+}
+
+func TestDescribeName(t *testing.T) {
+	require := require.New(t)
+	pkg, info, _ := checkedProject(t)
+
+	require.Equal(&Description{Code: "scale : number", Module: "Shapes"}, DescribeName(pkg, info, "Shapes", "scale"))
+	require.Equal(&Description{Code: "type Shape", Module: "Shapes"}, DescribeName(pkg, info, "Shapes", "Shape"))
+	require.Equal("module Shapes", DescribeName(pkg, info, "Shapes", "").Code)
+	require.Nil(DescribeName(pkg, info, "Shapes", "missing"))
+	require.Nil(DescribeName(pkg, info, "Missing", ""))
+}
@@ -0,0 +1,143 @@
+package symbol
+
+import (
+	"sort"
+	"unicode"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// Index is the set of symbols of many modules, which can be searched by
+// name. Only the symbols visible outside of the definitions are indexed:
+// the top-level declarations, the constructors and the fields.
+type Index struct {
+	// modules are the symbols of every module by the path to its file.
+	modules map[string][]*Symbol
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{modules: make(map[string][]*Symbol)}
+}
+
+// Add adds the symbols of the module to the index, replacing the ones of
+// the previous version of the module at the same path, if any.
+func (i *Index) Add(mod *ast.Module) {
+	var symbols []*Symbol
+	for _, sym := range Outline(mod) {
+		symbols = append(symbols, sym)
+		if sym.Kind == Type || sym.Kind == TypeAlias {
+			symbols = append(symbols, sym.Children...)
+		}
+	}
+	i.modules[mod.Path] = symbols
+}
+
+// Remove removes the symbols of the module at the given path.
+func (i *Index) Remove(path string) {
+	delete(i.modules, path)
+}
+
+// Search returns the symbols whose names match the query, from the best
+// match to the worst. A name matches if it has all the characters of the
+// query in the same order, ignoring the case, and the matches are better
+// the more of them are consecutive or at the start of the words of the
+// name, so "wid" matches "width" better than "rowId". The shorter names
+// go first among the equally good matches. Every symbol matches an empty
+// query.
+func (i *Index) Search(query string) []*Symbol {
+	var q = []rune(query)
+	var matches []match
+	for _, symbols := range i.modules {
+		for _, sym := range symbols {
+			if score, ok := fuzzy(q, []rune(sym.Name)); ok {
+				matches = append(matches, match{sym, score})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case len(a.Name) != len(b.Name):
+			return len(a.Name) < len(b.Name)
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.Path != b.Path:
+			return a.Path < b.Path
+		default:
+			return a.Node.Pos() < b.Node.Pos()
+		}
+	})
+
+	var result = make([]*Symbol, len(matches))
+	for i, m := range matches {
+		result[i] = m.Symbol
+	}
+	return result
+}
+
+type match struct {
+	*Symbol
+	score int
+}
+
+// The scores of the characters of a query that match a name.
+const (
+	scoreMatch       = 1
+	scoreSameCase    = 1
+	scoreConsecutive = 4
+	scoreWordStart   = 3
+	scoreExact       = 10
+)
+
+// fuzzy reports whether the name matches the query and how good the match
+// is. Every character of the query matches the first character of the name
+// after the previous match that is equal to it ignoring the case.
+func fuzzy(query, name []rune) (int, bool) {
+	var score int
+	var last = -1
+	for _, q := range query {
+		i := last + 1
+		for i < len(name) && unicode.ToLower(name[i]) != unicode.ToLower(q) {
+			i++
+		}
+
+		if i == len(name) {
+			return 0, false
+		}
+
+		score += scoreMatch
+		if name[i] == q {
+			score += scoreSameCase
+		}
+
+		if i == last+1 && last >= 0 {
+			score += scoreConsecutive
+		}
+
+		if wordStart(name, i) {
+			score += scoreWordStart
+		}
+		last = i
+	}
+
+	if len(query) == len(name) {
+		score += scoreExact
+	}
+	return score, true
+}
+
+// wordStart reports whether the character at i starts a word of the name,
+// which are separated by underscores or by changes of case.
+func wordStart(name []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	prev := name[i-1]
+	return prev == '_' || unicode.IsUpper(name[i]) && !unicode.IsUpper(prev) ||
+		unicode.IsLetter(name[i]) != unicode.IsLetter(prev)
+}
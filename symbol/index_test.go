@@ -0,0 +1,54 @@
+package symbol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func names(symbols []*Symbol) []string {
+	var result []string
+	for _, sym := range symbols {
+		result = append(result, fmt.Sprintf("%s.%s", sym.Container, sym.Name))
+	}
+	return result
+}
+
+func TestIndex(t *testing.T) {
+	require := require.New(t)
+
+	idx := NewIndex()
+	idx.Add(parseSource(t, "Shapes.elm", source))
+	idx.Add(parseSource(t, "Table.elm", `module Table exposing (..)
+
+
+type alias Row =
+    { rowId : Int }
+
+
+width : Int
+width =
+    80
+`))
+
+	require.Equal([]string{"Shapes.area"}, names(idx.Search("area")))
+	require.Equal([]string{"Table.width", "Row.rowId"}, names(idx.Search("wid")))
+	require.Equal([]string{"Shape.Square"}, names(idx.Search("sq")))
+	require.Equal([]string{"Shapes.shapes", "Shapes.Shape"}, names(idx.Search("sh")))
+	require.Empty(idx.Search("sides"))
+	require.Len(idx.Search(""), 13)
+
+	idx.Add(parseSource(t, "Table.elm", `module Table exposing (..)
+
+
+height : Int
+height =
+    80
+`))
+	require.Equal([]string{"Table.height"}, names(idx.Search("height")))
+	require.Empty(idx.Search("wid"))
+
+	idx.Remove("Table.elm")
+	require.Empty(idx.Search("height"))
+}
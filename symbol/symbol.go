@@ -0,0 +1,152 @@
+// Package symbol lists the declarations of the modules of Elm packages,
+// which editors show as the outline of a module and search by name to go to
+// any declaration of the package, and tells what the names of a module
+// refer to: the kind of declaration for highlighting and its description
+// on hover.
+package symbol
+
+import (
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// Kind is the kind of declaration of a symbol.
+type Kind byte
+
+const (
+	// Value is a definition without arguments.
+	Value Kind = iota
+	// Function is a definition with arguments.
+	Function
+	// Type is a union type.
+	Type
+	// TypeAlias is a type alias.
+	TypeAlias
+	// Constructor is a constructor of a union type.
+	Constructor
+	// Field is a field of the record of a type alias.
+	Field
+	// Port is a port declaration.
+	Port
+	// Operator is the infix declaration of an operator.
+	Operator
+)
+
+var kinds = [...]string{
+	Value:       "value",
+	Function:    "function",
+	Type:        "type",
+	TypeAlias:   "type alias",
+	Constructor: "constructor",
+	Field:       "field",
+	Port:        "port",
+	Operator:    "operator",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return "unknown"
+}
+
+// Symbol is a declaration of a module.
+type Symbol struct {
+	// Name is the name that is declared.
+	Name string
+	Kind Kind
+	// Container is the name of the declaration the symbol is declared in,
+	// or the name of the module for the top-level declarations.
+	Container string
+	// Path is the path to the file of the module.
+	Path string
+	// Node is the whole declaration, and Ident is the name in it.
+	Node  ast.Node
+	Ident *ast.Ident
+	// Children are the symbols declared inside the declaration: the
+	// constructors of a type, the fields of a type alias and the
+	// definitions of the let expressions of a definition.
+	Children []*Symbol
+}
+
+// Outline returns the symbols of the top-level declarations of the module,
+// in the order they are declared, with the symbols declared inside them as
+// their children.
+func Outline(mod *ast.Module) []*Symbol {
+	o := outline{mod: mod}
+	var symbols []*Symbol
+	for _, decl := range mod.Decls {
+		switch decl := decl.(type) {
+		case *ast.Definition:
+			sym := o.symbol(decl.Name, decl, definitionKind(decl), mod.Name)
+			sym.Children = o.locals(decl.Name.Name, decl.Body)
+			symbols = append(symbols, sym)
+		case *ast.AliasDecl:
+			sym := o.symbol(decl.Name, decl, TypeAlias, mod.Name)
+			if record, ok := decl.Type.(*ast.RecordType); ok {
+				for _, f := range record.Fields {
+					sym.Children = append(sym.Children, o.symbol(f.Name, f, Field, decl.Name.Name))
+				}
+			}
+			symbols = append(symbols, sym)
+		case *ast.UnionDecl:
+			sym := o.symbol(decl.Name, decl, Type, mod.Name)
+			for _, c := range decl.Ctors {
+				sym.Children = append(sym.Children, o.symbol(c.Name, c, Constructor, decl.Name.Name))
+			}
+			symbols = append(symbols, sym)
+		case *ast.PortDecl:
+			symbols = append(symbols, o.symbol(decl.Name, decl, Port, mod.Name))
+		case *ast.InfixDecl:
+			symbols = append(symbols, o.symbol(decl.Op, decl, Operator, mod.Name))
+		}
+	}
+	return symbols
+}
+
+// outline builds the symbols of a module.
+type outline struct {
+	mod *ast.Module
+}
+
+func (o outline) symbol(name *ast.Ident, node ast.Node, kind Kind, container string) *Symbol {
+	return &Symbol{
+		Name:      name.Name,
+		Kind:      kind,
+		Container: container,
+		Path:      o.mod.Path,
+		Node:      node,
+		Ident:     name,
+	}
+}
+
+// locals returns the symbols of the definitions of the let expressions in
+// the node that are not inside other definitions, which have their own.
+func (o outline) locals(container string, node ast.Node) []*Symbol {
+	var symbols []*Symbol
+	ast.WalkFunc(node, func(n ast.Node) bool {
+		let, ok := n.(*ast.LetExpr)
+		if !ok {
+			return n != nil
+		}
+
+		for _, decl := range let.Decls {
+			if def, ok := decl.(*ast.Definition); ok {
+				sym := o.symbol(def.Name, def, definitionKind(def), container)
+				sym.Children = o.locals(def.Name.Name, def.Body)
+				symbols = append(symbols, sym)
+			} else {
+				symbols = append(symbols, o.locals(container, decl)...)
+			}
+		}
+		symbols = append(symbols, o.locals(container, let.Body)...)
+		return false
+	})
+	return symbols
+}
+
+func definitionKind(def *ast.Definition) Kind {
+	if len(def.Args) > 0 {
+		return Function
+	}
+	return Value
+}
@@ -0,0 +1,102 @@
+package symbol
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+const source = `module Shapes exposing (..)
+
+
+type Shape
+    = Square Int
+    | Rect Int Int
+
+
+type alias Point =
+    { x : Int
+    , y : Int
+    }
+
+
+origin : Point
+origin =
+    Point 0 0
+
+
+area : Shape -> Int
+area shape =
+    let
+        sides =
+            case shape of
+                Square side ->
+                    let
+                        squared =
+                            side * side
+                    in
+                        ( side, squared )
+
+                Rect w h ->
+                    ( w, h )
+
+        multiply ( a, b ) =
+            a * b
+    in
+        multiply sides
+
+
+port shapes : (Shape -> msg) -> Sub msg
+
+
+infixl 7 <*>
+`
+
+func parseSource(t *testing.T, path, src string) *ast.Module {
+	mod, err := parser.ParseFrom(path, strings.NewReader(src), parser.FullParse)
+	require.NoError(t, err)
+	return mod
+}
+
+// tree returns the names, kinds and containers of the symbols, indented
+// by their depth.
+func tree(symbols []*Symbol, indent string) []string {
+	var result []string
+	for _, sym := range symbols {
+		result = append(result, fmt.Sprintf("%s%s %s %s", indent, sym.Name, sym.Kind, sym.Container))
+		result = append(result, tree(sym.Children, indent+"  ")...)
+	}
+	return result
+}
+
+func TestOutline(t *testing.T) {
+	require := require.New(t)
+	mod := parseSource(t, "Shapes.elm", source)
+
+	symbols := Outline(mod)
+	require.Equal([]string{
+		"Shape type Shapes",
+		"  Square constructor Shape",
+		"  Rect constructor Shape",
+		"Point type alias Shapes",
+		"  x field Point",
+		"  y field Point",
+		"origin value Shapes",
+		"area function Shapes",
+		"  sides value area",
+		"    squared value sides",
+		"  multiply function area",
+		"shapes port Shapes",
+		"<*> operator Shapes",
+	}, tree(symbols, ""))
+
+	area := symbols[3]
+	require.Equal("Shapes.elm", area.Path)
+	require.Equal(strings.Index(source, "area : Shape"), int(area.Node.Pos()))
+	require.Equal(strings.Index(source, "area shape"), int(area.Ident.Pos()))
+}
@@ -0,0 +1,310 @@
+package symbol
+
+import (
+	"sort"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/scanner"
+	"github.com/elm-tangram/tangram/token"
+)
+
+// TokenKind is the kind of what an identifier refers to.
+type TokenKind byte
+
+const (
+	// ModuleToken is the name of a module.
+	ModuleToken TokenKind = iota
+	// TypeToken is the name of a type or a type alias.
+	TypeToken
+	// TypeParameterToken is a type variable.
+	TypeParameterToken
+	// ConstructorToken is a constructor of a union type.
+	ConstructorToken
+	// FunctionToken is a definition with arguments or a port.
+	FunctionToken
+	// VariableToken is a definition without arguments or a name bound by
+	// a pattern that is not an argument.
+	VariableToken
+	// ParameterToken is a name bound by an argument of a definition or a
+	// lambda.
+	ParameterToken
+	// PropertyToken is the field of a record.
+	PropertyToken
+	// OperatorToken is an operator.
+	OperatorToken
+)
+
+var tokenKinds = [...]string{
+	ModuleToken:        "module",
+	TypeToken:          "type",
+	TypeParameterToken: "type parameter",
+	ConstructorToken:   "constructor",
+	FunctionToken:      "function",
+	VariableToken:      "variable",
+	ParameterToken:     "parameter",
+	PropertyToken:      "property",
+	OperatorToken:      "operator",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKinds) {
+		return tokenKinds[k]
+	}
+	return "unknown"
+}
+
+// Token is an identifier of a module classified by what it refers to.
+type Token struct {
+	// Pos and End are the offsets where the identifier starts and ends.
+	Pos, End token.Pos
+	Kind     TokenKind
+	// Declaration reports whether the identifier is the name that is
+	// declared, instead of a reference to it.
+	Declaration bool
+}
+
+// Tokens returns the tokens of the identifiers of the resolved module, in
+// the order they appear in the source code. They are classified by the
+// objects they were resolved to, so a name is a function or a parameter
+// because of where it is declared, even in the modules of the package that
+// declare the imported names. The names that could not be resolved only
+// have a token if their kind is clear from where they are, such as the
+// fields of the records.
+func Tokens(pkg *ast.Package, mod *ast.Module) []Token {
+	c := &classifier{
+		pkg:    pkg,
+		mod:    mod,
+		defs:   make(map[*ast.Ident]*ast.Definition),
+		params: make(map[ast.Node]bool),
+	}
+	c.collect()
+
+	var tokens []Token
+	var parents []ast.Node
+	ast.WalkFunc(mod, func(n ast.Node) bool {
+		if n == nil {
+			parents = parents[:len(parents)-1]
+			return false
+		}
+
+		if id, ok := n.(*ast.Ident); ok {
+			if id.NamePos != token.NoPos {
+				if kind, decl, ok := c.classify(id, parents); ok {
+					tokens = append(tokens, Token{id.Pos(), id.End(), kind, decl})
+				}
+			}
+			return false
+		}
+
+		parents = append(parents, n)
+		return true
+	})
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Pos < tokens[j].Pos
+	})
+	return tokens
+}
+
+// classifier classifies the identifiers of a module.
+type classifier struct {
+	pkg *ast.Package
+	mod *ast.Module
+	// defs are the definitions by their names: the top-level ones of all
+	// the modules of the package and the local ones of the module.
+	defs map[*ast.Ident]*ast.Definition
+	// params are the patterns that bind the arguments of the definitions
+	// and the lambdas of the module.
+	params map[ast.Node]bool
+}
+
+func (c *classifier) collect() {
+	for _, m := range c.pkg.Modules {
+		for _, decl := range m.Decls {
+			if def, ok := decl.(*ast.Definition); ok {
+				c.defs[def.Name] = def
+			}
+		}
+	}
+
+	ast.WalkFunc(c.mod, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Definition:
+			c.defs[n.Name] = n
+			c.addParams(n.Args)
+		case *ast.Lambda:
+			c.addParams(n.Args)
+		}
+		return n != nil
+	})
+}
+
+func (c *classifier) addParams(args []ast.Pattern) {
+	for _, arg := range args {
+		ast.WalkFunc(arg, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.VarPattern, *ast.AliasPattern:
+				c.params[n] = true
+			}
+			return n != nil
+		})
+	}
+}
+
+// classify returns the kind of the identifier with the given ancestors, and
+// whether it is the name of a declaration. The last result is false if the
+// kind is not known.
+func (c *classifier) classify(id *ast.Ident, parents []ast.Node) (TokenKind, bool, bool) {
+	parent := parents[len(parents)-1]
+
+	// the names of the modules are selectors of their parts
+	owner := parent
+	for i := len(parents) - 1; i > 0; i-- {
+		if _, ok := parents[i].(*ast.SelectorExpr); !ok {
+			break
+		}
+		owner = parents[i-1]
+	}
+
+	switch owner.(type) {
+	case *ast.ModuleDecl:
+		return ModuleToken, true, true
+	case *ast.ImportDecl:
+		return ModuleToken, false, true
+	}
+
+	switch p := parent.(type) {
+	case *ast.Definition:
+		if id == p.Name {
+			return c.definitionKind(p), true, true
+		}
+	case *ast.TypeAnnotation:
+		if def, ok := parents[len(parents)-2].(*ast.Definition); ok {
+			return c.definitionKind(def), false, true
+		}
+		return FunctionToken, false, true
+	case *ast.PortDecl:
+		return FunctionToken, true, true
+	case *ast.AliasDecl:
+		if id == p.Name {
+			return TypeToken, true, true
+		}
+		return TypeParameterToken, true, true
+	case *ast.UnionDecl:
+		if id == p.Name {
+			return TypeToken, true, true
+		}
+		return TypeParameterToken, true, true
+	case *ast.Constructor:
+		return ConstructorToken, true, true
+	case *ast.VarPattern, *ast.AliasPattern:
+		if c.params[p] {
+			return ParameterToken, true, true
+		}
+		return VariableToken, true, true
+	case *ast.InfixDecl:
+		return OperatorToken, false, true
+	case *ast.VarType:
+		return TypeParameterToken, false, true
+	case *ast.RecordField:
+		return PropertyToken, true, true
+	case *ast.FieldAssign:
+		if id == p.Field {
+			return PropertyToken, false, true
+		}
+	case *ast.AccessorExpr:
+		return PropertyToken, false, true
+	case *ast.ExposedUnion:
+		return TypeToken, false, true
+	case *ast.ExposedVar:
+		return c.exposedKind(p, parents)
+	}
+
+	if id.Obj != nil {
+		return c.objectKind(id.Obj)
+	}
+
+	// only the names of the modules and the values are resolved in the
+	// qualified names, so the rest are fields
+	if _, ok := parent.(*ast.SelectorExpr); ok {
+		return PropertyToken, false, true
+	}
+	return 0, false, false
+}
+
+// definitionKind returns the kind of the name of the definition.
+func (c *classifier) definitionKind(def *ast.Definition) TokenKind {
+	if len(def.Args) > 0 {
+		return FunctionToken
+	}
+	return VariableToken
+}
+
+// objectKind returns the kind of the identifiers that refer to the object.
+func (c *classifier) objectKind(obj *ast.Object) (TokenKind, bool, bool) {
+	switch obj.Kind {
+	case ast.Mod, ast.NativeMod:
+		return ModuleToken, false, true
+	case ast.Typ, ast.BuiltinTyp:
+		return TypeToken, false, true
+	case ast.VarTyp:
+		return TypeParameterToken, false, true
+	case ast.Ctor:
+		return ConstructorToken, false, true
+	case ast.Var:
+		if !scanner.IsIdentifier(obj.Name) {
+			return OperatorToken, false, true
+		}
+
+		switch node := obj.Node.(type) {
+		case *ast.Ident:
+			if def, ok := c.defs[node]; ok {
+				return c.definitionKind(def), false, true
+			}
+			return FunctionToken, false, true
+		case *ast.VarPattern, *ast.AliasPattern:
+			if c.params[node] {
+				return ParameterToken, false, true
+			}
+		}
+		return VariableToken, false, true
+	}
+	return 0, false, false
+}
+
+// exposedKind returns the kind of a name of an exposing list, which is
+// the kind of the declaration of the module that exposes it.
+func (c *classifier) exposedKind(exposed *ast.ExposedVar, parents []ast.Node) (TokenKind, bool, bool) {
+	if len(parents) > 2 {
+		if _, ok := parents[len(parents)-3].(*ast.ExposedUnion); ok {
+			return ConstructorToken, false, true
+		}
+	}
+
+	if exposed.Obj != nil {
+		return c.objectKind(exposed.Obj)
+	}
+
+	var scope *ast.ModuleScope
+	for i := len(parents) - 1; i >= 0 && scope == nil; i-- {
+		switch p := parents[i].(type) {
+		case *ast.ModuleDecl:
+			scope = c.mod.Scope
+		case *ast.ImportDecl:
+			if m := c.pkg.Modules[p.ModuleName()]; m != nil {
+				scope = m.Scope
+			}
+		}
+	}
+
+	if scope != nil {
+		if obj := scope.Objects[exposed.Name]; obj != nil {
+			return c.objectKind(obj)
+		}
+	}
+
+	if !scanner.IsIdentifier(exposed.Name) {
+		return OperatorToken, false, true
+	}
+	return 0, false, false
+}
@@ -0,0 +1,66 @@
+package symbol
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join("_testdata", "project", "src", "Main.elm")
+	src, err := ioutil.ReadFile(path)
+	require.NoError(err)
+
+	pkg, err := parser.Parse(path, parser.FullParse)
+	require.NoError(err)
+
+	var tokens []string
+	for _, tok := range Tokens(pkg, pkg.Modules["Main"]) {
+		s := fmt.Sprintf("%s %s", src[tok.Pos:tok.End], tok.Kind)
+		if tok.Declaration {
+			s += " declaration"
+		}
+		tokens = append(tokens, s)
+	}
+
+	require.Equal([]string{
+		"Main module declaration",
+		"main variable",
+		"Shapes module",
+		"Shape type",
+		"area function",
+		"<+> operator",
+		"Model type declaration",
+		"a type parameter declaration",
+		"shape property declaration",
+		"Shape type",
+		"tag property declaration",
+		"a type parameter",
+		"main variable",
+		"Int type",
+		"main variable declaration",
+		"model variable declaration",
+		"shape property",
+		"Square constructor",
+		"Shapes module",
+		"scale variable",
+		"tag property",
+		"double function declaration",
+		"n parameter declaration",
+		"n parameter",
+		"double function",
+		"area function",
+		"model variable",
+		"shape property",
+		"<+> operator",
+		"tag property",
+		"model variable",
+	}, tokens)
+}
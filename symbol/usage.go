@@ -0,0 +1,185 @@
+package symbol
+
+import (
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// Usage is how much a top-level declaration of a module, or a constructor
+// of one of its types, is used by the modules of a package.
+type Usage struct {
+	*Symbol
+	// Module is the name of the module of the declaration.
+	Module string
+	// Exposed reports whether the module exposes it, and Public whether it
+	// is part of the API of the package, which is what its exposed modules
+	// expose.
+	Exposed bool
+	Public  bool
+	// Uses is the number of references to it outside of its own
+	// declaration, and Modules the number of modules with any of them.
+	Uses    int
+	Modules int
+	// Dependents is the number of declarations that use it, directly or
+	// through other declarations.
+	Dependents int
+	// Reachable reports whether it is part of the API of the package or a
+	// main definition, or one of them uses it, directly or not. The ones
+	// that are not reachable are dead code.
+	Reachable bool
+}
+
+// Usages returns the usage of the declarations of the given modules of the
+// package, in the order of the modules and of their declarations, with the
+// constructors after their type. Only the uses by those modules, which must
+// have been resolved, are counted, and public are the exposed modules of
+// the package.
+func Usages(pkg *ast.Package, modules []string, public []string) []*Usage {
+	var isPublic = make(map[string]bool, len(public))
+	for _, m := range public {
+		isPublic[m] = true
+	}
+
+	var usages []*Usage
+	var byObj = make(map[*ast.Object]*Usage)
+	var owners = make(map[ast.Decl]*Usage)
+	// types are the types of the constructors
+	var types = make(map[*Usage]*Usage)
+	for _, name := range modules {
+		mod := pkg.Modules[name]
+		if mod == nil || mod.Scope == nil {
+			continue
+		}
+
+		var objects = make(map[*ast.Ident]*ast.Object, len(mod.Scope.Objects))
+		for _, obj := range mod.Scope.Objects {
+			if id := NameOf(obj.Node); id != nil {
+				objects[id] = obj
+			}
+		}
+
+		add := func(sym *Symbol) *Usage {
+			obj := objects[sym.Ident]
+			if obj == nil {
+				return nil
+			}
+
+			u := &Usage{Symbol: sym, Module: name}
+			u.Exposed = mod.Scope.Exposed[obj.Name] == obj
+			u.Public = u.Exposed && isPublic[name]
+			byObj[obj] = u
+			usages = append(usages, u)
+			return u
+		}
+
+		for _, sym := range Outline(mod) {
+			switch sym.Kind {
+			case Value, Function, Type, TypeAlias, Port:
+			default:
+				continue
+			}
+
+			u := add(sym)
+			if u == nil {
+				continue
+			}
+
+			if decl, ok := sym.Node.(ast.Decl); ok {
+				owners[decl] = u
+			}
+
+			if sym.Kind == Type {
+				for _, ctor := range sym.Children {
+					if c := add(ctor); c != nil {
+						types[c] = u
+					}
+				}
+			}
+		}
+	}
+
+	// uses are the declarations every declaration uses
+	var uses = make(map[*Usage][]*Usage)
+	for _, name := range modules {
+		mod := pkg.Modules[name]
+		if mod == nil {
+			continue
+		}
+
+		var inModule = make(map[*Usage]bool)
+		for _, decl := range mod.Decls {
+			owner := owners[decl]
+			if owner == nil {
+				continue
+			}
+
+			var used = make(map[*Usage]bool)
+			ast.WalkFunc(decl, func(n ast.Node) bool {
+				id, ok := n.(*ast.Ident)
+				if !ok || id.Obj == nil {
+					return n != nil
+				}
+
+				u := byObj[id.Obj]
+				if u == nil || u.Ident == id || u == owner || types[u] == owner {
+					return true
+				}
+
+				u.Uses++
+				inModule[u] = true
+				if !used[u] {
+					used[u] = true
+					uses[owner] = append(uses[owner], u)
+				}
+				return true
+			})
+		}
+
+		for u := range inModule {
+			u.Modules++
+		}
+	}
+
+	var dependents = make(map[*Usage]map[*Usage]bool)
+	for _, u := range usages {
+		for d := range reach(u, uses) {
+			if d != u {
+				if dependents[d] == nil {
+					dependents[d] = make(map[*Usage]bool)
+				}
+				dependents[d][u] = true
+			}
+		}
+	}
+
+	// the types of the reachable constructors are reachable too
+	for c, t := range types {
+		uses[c] = append(uses[c], t)
+	}
+
+	for _, u := range usages {
+		u.Dependents = len(dependents[u])
+		if u.Public || u.Name == "main" && (u.Kind == Value || u.Kind == Function) {
+			for r := range reach(u, uses) {
+				r.Reachable = true
+			}
+		}
+	}
+	return usages
+}
+
+// reach returns the usage and all the usages it uses, directly or not.
+func reach(u *Usage, uses map[*Usage][]*Usage) map[*Usage]bool {
+	var seen = map[*Usage]bool{u: true}
+	var stack = []*Usage{u}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, used := range uses[next] {
+			if !seen[used] {
+				seen[used] = true
+				stack = append(stack, used)
+			}
+		}
+	}
+	return seen
+}
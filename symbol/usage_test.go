@@ -0,0 +1,43 @@
+package symbol
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsages(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join("_testdata", "project", "src", "Main.elm")
+	pkg, err := parser.Parse(path, parser.FullParse)
+	require.NoError(err)
+
+	var result []string
+	for _, u := range Usages(pkg, []string{"Shapes", "Main"}, []string{"Main"}) {
+		result = append(result, fmt.Sprintf("%s.%s %s exposed=%v public=%v uses=%d modules=%d dependents=%d reachable=%v", u.Module, u.Name, u.Kind, u.Exposed, u.Public, u.Uses, u.Modules, u.Dependents, u.Reachable))
+	}
+	require.Equal([]string{
+		"Shapes.Shape type exposed=true public=false uses=2 modules=2 dependents=3 reachable=true",
+		"Shapes.Square constructor exposed=true public=false uses=2 modules=2 dependents=2 reachable=true",
+		"Shapes.Rect constructor exposed=true public=false uses=1 modules=1 dependents=2 reachable=true",
+		"Shapes.area function exposed=true public=false uses=1 modules=1 dependents=1 reachable=true",
+		"Shapes.scale value exposed=true public=false uses=1 modules=1 dependents=1 reachable=true",
+		"Shapes.<+> function exposed=true public=false uses=1 modules=1 dependents=1 reachable=true",
+		// the alias is not used by main
+		"Main.Model type alias exposed=false public=false uses=0 modules=0 dependents=0 reachable=false",
+		"Main.main value exposed=true public=true uses=0 modules=0 dependents=0 reachable=true",
+	}, result)
+
+	// only the uses by the given modules are counted
+	for _, u := range Usages(pkg, []string{"Shapes"}, []string{"Shapes"}) {
+		if u.Name == "scale" {
+			require.Equal(0, u.Uses)
+			require.True(u.Public)
+			require.True(u.Reachable)
+		}
+	}
+}
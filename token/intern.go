@@ -0,0 +1,84 @@
+package token
+
+import "sync"
+
+// Name is an interned string, such as an identifier or the name of a module.
+// All the names with the same text interned by the same Interner are the
+// same value, so comparing two names or looking one up in a map only
+// compares a pointer, no matter how long the text is. The zero value is the
+// name of the empty string.
+type Name struct {
+	text *string
+}
+
+// String returns the text of the name.
+func (n Name) String() string {
+	if n.text == nil {
+		return ""
+	}
+	return *n.text
+}
+
+// Interner is a table of interned names. It belongs to a parse, a scan or
+// the session of a program, and its names are freed with it, so the
+// programs that run for long and parse any code they are given, such as
+// the language server, do not keep every name they ever saw. The names of
+// different interners are never the same, even with the same text. It is
+// safe to use from several goroutines.
+type Interner struct {
+	mu    sync.RWMutex
+	names map[string]Name
+}
+
+// NewInterner returns a new interner without names.
+func NewInterner() *Interner {
+	return &Interner{names: make(map[string]Name)}
+}
+
+// Intern returns the name with the given text.
+func (in *Interner) Intern(s string) Name {
+	if s == "" {
+		return Name{}
+	}
+
+	in.mu.RLock()
+	n, ok := in.names[s]
+	in.mu.RUnlock()
+	if ok {
+		return n
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if n, ok := in.names[s]; ok {
+		return n
+	}
+
+	n = Name{&s}
+	in.names[s] = n
+	return n
+}
+
+// InternBytes returns the name with the text of the given bytes. It does not
+// allocate if the name was already interned, so the bytes can be a reused
+// buffer.
+func (in *Interner) InternBytes(b []byte) Name {
+	if len(b) == 0 {
+		return Name{}
+	}
+
+	in.mu.RLock()
+	n, ok := in.names[string(b)]
+	in.mu.RUnlock()
+	if ok {
+		return n
+	}
+	return in.Intern(string(b))
+}
+
+// Len returns the number of names of the interner.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.names)
+}
@@ -0,0 +1,31 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntern(t *testing.T) {
+	require := require.New(t)
+
+	in := NewInterner()
+	a := in.Intern("Html.Events")
+	b := in.InternBytes([]byte("Html.Events"))
+	require.Equal(a, b)
+	require.True(a == b)
+	require.Equal("Html.Events", b.String())
+	require.False(a == in.Intern("Html"))
+	require.Equal(2, in.Len())
+
+	require.Equal(Name{}, in.Intern(""))
+	require.Equal("", Name{}.String())
+
+	// the names of other interners are not the same
+	require.False(a == NewInterner().Intern("Html.Events"))
+
+	var buf = []byte("Html.Events")
+	require.Zero(testing.AllocsPerRun(10, func() {
+		in.InternBytes(buf)
+	}))
+}
@@ -0,0 +1,38 @@
+package token
+
+import "fmt"
+
+// Span is the code of a file between two positions, from Start to End.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Location is a span of the code of a file. The positions are offsets in
+// that file, so carrying the file with them means they are never taken for
+// positions of another one when the diagnostics of many files are reported.
+type Location struct {
+	// File is the path of the file.
+	File string
+	Span Span
+}
+
+// At returns the location of the position in the file, whose span is
+// empty.
+func At(file string, pos Pos) Location {
+	return Location{File: file, Span: Span{Start: pos, End: pos}}
+}
+
+// Pos returns the position where the location starts, which is NoPos if it
+// has no position.
+func (l Location) Pos() Pos { return l.Span.Start }
+
+// IsValid reports whether the location has a position in its file.
+func (l Location) IsValid() bool { return l.Span.Start != NoPos }
+
+func (l Location) String() string {
+	if !l.IsValid() {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d-%d", l.File, l.Span.Start, l.Span.End)
+}
@@ -126,4 +126,10 @@ const (
 	Import
 	// Backslash is the "\" character
 	Backslash
+	// Effect is the "effect" keyword
+	Effect
+	// Where is the "where" keyword
+	Where
+	// Port is the "port" keyword
+	Port
 )
@@ -84,6 +84,12 @@ func (t Type) String() string {
 		return "exposing"
 	case Import:
 		return "import"
+	case Effect:
+		return "effect"
+	case Where:
+		return "where"
+	case Port:
+		return "port"
 	default:
 		return "invalid token"
 	}
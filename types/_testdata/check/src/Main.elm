@@ -0,0 +1,78 @@
+module Main exposing (..)
+
+import Basics exposing (..)
+
+
+type Shape
+    = Circle Float
+    | Rect Float Float
+
+
+type alias Point =
+    { x : Int, y : Int }
+
+
+origin : Point
+origin =
+    { x = 0, y = 0 }
+
+
+move : Int -> Point -> Point
+move dx p =
+    { p | x = p.x + dx }
+
+
+area shape =
+    case shape of
+        Circle r ->
+            r + r
+
+        Rect w h ->
+            w + h
+
+
+pair =
+    ( identity 1, identity "a" )
+
+
+greeting name =
+    "Hello, " ++ name
+
+
+isOrigin p =
+    p == origin
+
+
+apply f x =
+    f x
+
+
+xs =
+    let
+        twice f x =
+            f (f x)
+    in
+        [ twice (\n -> n + 1) 1, 2 ]
+
+
+getX =
+    .x
+
+
+isTrue b =
+    case b of
+        True ->
+            1
+
+        False ->
+            0
+
+
+big : Int
+big =
+    9007199254740993
+
+
+ignored : String
+ignored =
+    (\_ -> "a") 1
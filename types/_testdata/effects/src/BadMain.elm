@@ -0,0 +1,7 @@
+module BadMain exposing (..)
+
+import BadTick
+
+
+main =
+    "hello"
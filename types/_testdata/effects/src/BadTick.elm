@@ -0,0 +1,22 @@
+effect module BadTick where { command = MyCmd, subscription = MySub } exposing (..)
+
+import Basics exposing (..)
+import Platform exposing (..)
+
+
+type MyCmd msg
+    = Tick msg
+
+
+type MySub
+    = Sub
+
+
+init : Task Never Int
+init =
+    succeed 0
+
+
+onEffects : Router msg Int -> List (MyCmd msg) -> Int -> Task Never Int
+onEffects router cmds state =
+    succeed state
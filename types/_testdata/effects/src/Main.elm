@@ -0,0 +1,18 @@
+module Main exposing (..)
+
+import Basics exposing (..)
+import Platform exposing (..)
+import Platform.Cmd as Cmd exposing (..)
+import Tick
+
+
+main : Program Never Int Int
+main =
+    program
+        { init = ( 0, Cmd.none )
+        , update = \msg model -> ( model + msg, Cmd.map identity Cmd.none )
+        }
+
+
+identity x =
+    x
@@ -0,0 +1,31 @@
+module Platform exposing (..)
+
+import Basics exposing (..)
+import Platform.Cmd exposing (..)
+import Native.Platform
+
+
+type Program flags model msg
+    = Program
+
+
+type Task err ok
+    = Task
+
+
+type Router appMsg selfMsg
+    = Router
+
+
+succeed : a -> Task x a
+succeed =
+    Native.Platform.succeed
+
+
+program :
+    { init : ( model, Cmd msg )
+    , update : msg -> model -> ( model, Cmd msg )
+    }
+    -> Program Never model msg
+program =
+    Native.Platform.program
@@ -0,0 +1,30 @@
+effect module Tick where { command = MyCmd } exposing (..)
+
+import Basics exposing (..)
+import Platform exposing (..)
+
+
+type MyCmd msg
+    = Tick msg
+
+
+cmdMap : (a -> b) -> MyCmd a -> MyCmd b
+cmdMap f cmd =
+    case cmd of
+        Tick msg ->
+            Tick (f msg)
+
+
+init : Task Never Int
+init =
+    succeed 0
+
+
+onEffects : Router msg Int -> List (MyCmd msg) -> Int -> Task Never Int
+onEffects router cmds state =
+    succeed state
+
+
+onSelfMsg : Router msg Int -> Int -> Int -> Task Never Int
+onSelfMsg router tick state =
+    succeed (state + tick)
@@ -0,0 +1,28 @@
+module Basics exposing (..)
+
+import Native.Basics
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Basics.add
+
+
+(++) : appendable -> appendable -> appendable
+(++) =
+    Native.Basics.append
+
+
+(==) : a -> a -> Bool
+(==) =
+    Native.Basics.eq
+
+
+infixl 6 +
+infixr 5 ++
+infix 4 ==
+
+
+identity : a -> a
+identity x =
+    x
@@ -0,0 +1,68 @@
+module Main exposing (..)
+
+import Basics exposing (..)
+
+
+count : Int
+count =
+    "one"
+
+
+self x =
+    x x
+
+
+wrong : a -> a
+wrong x =
+    x + 1
+
+
+title : String
+title =
+    "title"
+
+
+titles : Int
+titles =
+    2
+
+
+greet : String -> String
+greet n =
+    n
+
+
+label : String
+label =
+    greet titles
+
+
+type Color
+    = Red
+    | Green
+
+
+type Light
+    = Gren
+
+
+color : Color
+color =
+    Gren
+
+
+origin =
+    { x = 1, y = 2 }
+
+
+originX =
+    origin.xx
+
+
+moved =
+    { origin | yy = 3 }
+
+
+point : { x : Int, y : Int }
+point =
+    { x = 1, yy = 2 }
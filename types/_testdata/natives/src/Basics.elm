@@ -0,0 +1,16 @@
+module Basics exposing (..)
+
+import Native.Basics
+
+
+add =
+    Native.Basics.add
+
+
+wrong : String
+wrong =
+    Native.Basics.add
+
+
+missing =
+    Native.Basics.missing
@@ -0,0 +1,7 @@
+module Main exposing (..)
+
+import Basics exposing (..)
+
+
+answer =
+    add 1 2
@@ -0,0 +1,28 @@
+port module BadPorts exposing (..)
+
+import Maybe exposing (..)
+import Platform.Cmd exposing (..)
+import Platform.Sub exposing (..)
+import Plain
+
+
+type Shape
+    = Circle Float
+
+
+port callback : (Int -> String) -> Cmd msg
+
+
+port tags : { name : String, tags : List Char } -> Cmd msg
+
+
+port shape : Maybe ( Int, Shape ) -> Cmd msg
+
+
+port anything : (a -> msg) -> Sub msg
+
+
+port notAPort : Int -> Int
+
+
+port otherMsg : (String -> Int) -> Sub msg
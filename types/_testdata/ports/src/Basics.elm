@@ -0,0 +1,11 @@
+module Basics exposing (..)
+
+import Native.Platform
+
+
+(+) : number -> number -> number
+(+) =
+    Native.Platform.add
+
+
+infixl 6 +
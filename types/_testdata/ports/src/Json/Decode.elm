@@ -0,0 +1,7 @@
+module Json.Decode exposing (..)
+
+import Json.Encode
+
+
+type alias Value =
+    Json.Encode.Value
@@ -0,0 +1,5 @@
+module Json.Encode exposing (..)
+
+
+type Value
+    = Value
@@ -0,0 +1,6 @@
+module Plain exposing (..)
+
+import Platform.Cmd exposing (..)
+
+
+port send : String -> Cmd msg
@@ -0,0 +1,5 @@
+module Platform.Cmd exposing (..)
+
+
+type Cmd msg
+    = Cmd
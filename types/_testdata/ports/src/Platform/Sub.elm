@@ -0,0 +1,5 @@
+module Platform.Sub exposing (..)
+
+
+type Sub msg
+    = Sub
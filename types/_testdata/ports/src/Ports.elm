@@ -0,0 +1,29 @@
+port module Ports exposing (..)
+
+import Basics exposing (..)
+import Maybe exposing (..)
+import Platform.Cmd exposing (..)
+import Platform.Sub exposing (..)
+import Json.Decode as Decode
+
+
+type alias User =
+    { name : String
+    , age : Int
+    , emails : List String
+    , manager : Maybe ( String, Float )
+    }
+
+
+port saveUser : User -> Cmd msg
+
+
+port log : List ( Int, Bool ) -> Cmd msg
+
+
+port receive : (Decode.Value -> msg) -> Sub msg
+
+
+save : User -> Cmd msg
+save user =
+    saveUser { user | age = user.age + 1 }
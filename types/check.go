@@ -0,0 +1,1039 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/crash"
+	"github.com/elm-tangram/tangram/profile"
+)
+
+// maxAliasDepth is the maximum number of nested type aliases that will be
+// expanded, to avoid looping forever on recursive aliases.
+const maxAliasDepth = 100
+
+// Error is an error found while type checking a package.
+type Error struct {
+	// Module is the name of the module where the error was found.
+	Module string
+	// Path is the path to the file of the module.
+	Path string
+	// Node is the node where the error was found.
+	Node ast.Node
+	// Name is the name of the definition being checked when the error was
+	// found, if any.
+	Name string
+	// Err is the actual error. It is usually a *MismatchError or an
+	// *InfiniteTypeError.
+	Err error
+	// Soft errors are warnings. They do not make the check fail.
+	Soft bool
+	// Names are the names in the node that may be misspelled, and
+	// Candidates are the names that could be used instead of them. In the
+	// mismatches of records, they are the fields of the node that the other
+	// record does not have, and the fields of the other record. In the
+	// other mismatches found in identifiers, they are the identifier and
+	// the values and constructors visible at module level whose type would
+	// have been valid.
+	Names, Candidates []string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Config is the configuration of the type checker.
+type Config struct {
+	// Error is called with every error found during checking, if it is not
+	// nil.
+	Error func(err *Error)
+	// Main is the name of the module whose main value will be checked to
+	// have a type that can be run, if any.
+	Main string
+	// MaxInt is the greatest integer the target backend can represent
+	// exactly. Integer literals of type Int out of the range [-MaxInt,
+	// MaxInt] are reported as soft errors. If it is zero, MaxSafeInt is
+	// used.
+	MaxInt int64
+	// Profile measures the checking of every module, if it is not nil. The
+	// checks made once all the modules are checked are measured for the
+	// whole program.
+	Profile *profile.Profile
+	// Context makes the checking stop when it is done, before the next
+	// module is checked, if it is not nil. Check returns the error of the
+	// context then.
+	Context context.Context
+}
+
+// Check type checks all the modules in the given package, which must have
+// been resolved, in the package resolution order. The type information is
+// recorded in info, if it is not nil. The errors found are passed to the
+// Error function of the configuration and the first one that is not soft is
+// returned. Only the first error of every definition is reported. The lazy
+// bodies of the definitions are parsed first, and the problems in them are
+// returned without checking the package. A panic of the checker is returned
+// as a *crash.Error.
+func (conf *Config) Check(pkg *ast.Package, info *Info) (err error) {
+	if info == nil {
+		info = new(Info)
+	}
+
+	c := &checker{
+		conf:        conf,
+		info:        info,
+		objects:     make(map[ast.Node]*Object),
+		typeModules: make(map[ast.Node]string),
+		patterns:    make(map[*ast.DestructuringAssignment]Type),
+		natives:     pkg.Natives,
+	}
+	defer c.recover(&err)
+
+	for _, name := range pkg.Order {
+		if err := ast.ParseLazy(pkg.Modules[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range pkg.Order {
+		if conf.Context != nil && conf.Context.Err() != nil {
+			return conf.Context.Err()
+		}
+
+		measure := conf.Profile.Start()
+		c.checkModule(pkg.Modules[name])
+		measure.Stop(profile.Check, name)
+	}
+
+	measure := conf.Profile.Start()
+	if mod, ok := pkg.Modules[conf.Main]; ok {
+		c.checkMain(mod)
+	}
+
+	c.defaultLiterals()
+	c.checkLiterals()
+	c.resolveTypes()
+	measure.Stop(profile.Check, profile.Program)
+	if c.err != nil {
+		return c.err
+	}
+	return nil
+}
+
+type checker struct {
+	conf *Config
+	info *Info
+	mod  *ast.Module
+	// natives are the native modules of the package.
+	natives map[string]*ast.NativeModule
+	// node is the last expression inferred, where a panic is located.
+	node ast.Node
+
+	// objects contains the typed objects by the node that defines them.
+	objects map[ast.Node]*Object
+	// typeModules contains the module name of every union type by its
+	// declaration.
+	typeModules map[ast.Node]string
+	// patterns contains the types of the destructuring assignments that
+	// have been declared but not checked yet.
+	patterns map[*ast.DestructuringAssignment]Type
+	// literals are all the integer literals found.
+	literals []literal
+
+	// level is the current nesting level of definitions.
+	level int
+	// name is the name of the definition being checked.
+	name string
+	// failed reports whether an error has already been found in the
+	// definition being checked. Only the first error of every definition
+	// is reported, because the rest are usually caused by the first one.
+	failed     bool
+	aliasDepth int
+	err        *Error
+}
+
+// recover stores the panic of the checker, if any, in err as an internal
+// error located in the last expression inferred.
+func (c *checker) recover(err *error) {
+	if r := recover(); r != nil {
+		e := crash.New("type check", r)
+		if c.mod != nil {
+			e.Module, e.Path = c.mod.Name, c.mod.Path
+		}
+		if c.node != nil {
+			e.Pos = c.node.Pos()
+		}
+		*err = e
+	}
+}
+
+func (c *checker) checkModule(mod *ast.Module) {
+	c.mod = mod
+	for _, decl := range mod.Decls {
+		if decl, ok := decl.(*ast.UnionDecl); ok {
+			c.typeModules[decl] = mod.Name
+		}
+	}
+
+	for _, decl := range mod.Decls {
+		switch decl := decl.(type) {
+		case *ast.UnionDecl:
+			c.declareUnion(decl)
+		case *ast.AliasDecl:
+			c.declareAlias(decl)
+		}
+	}
+
+	c.checkDecls(mod.Decls)
+	if mod.Module.IsEffect() {
+		c.checkEffects(mod)
+	}
+}
+
+func (c *checker) checkDecls(decls []ast.Decl) {
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.Definition:
+			c.declareDef(decl)
+		case *ast.PortDecl:
+			c.declarePort(decl)
+		case *ast.DestructuringAssignment:
+			t := c.newVar("")
+			c.checkPattern(decl.Pattern, t)
+			c.patterns[decl] = t
+		}
+	}
+
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.Definition:
+			c.checkDef(decl)
+		case *ast.DestructuringAssignment:
+			c.check(decl.Expr, c.patterns[decl])
+			delete(c.patterns, decl)
+		}
+	}
+}
+
+func (c *checker) declareUnion(decl *ast.UnionDecl) {
+	var env = make(map[string]Type)
+	var args []Type
+	var generic []*Var
+	for _, arg := range decl.Args {
+		v := NewVar(arg.Name)
+		env[arg.Name] = v
+		args = append(args, v)
+		generic = append(generic, v)
+	}
+
+	typ := NewNamed(c.mod.Name, decl.Name.Name, args...)
+	c.define(decl.Name, &Object{decl.Name.Name, c.mod.Name, ast.Typ, decl, typ, generic})
+
+	for _, ctor := range decl.Ctors {
+		var types []Type
+		for _, arg := range ctor.Args {
+			types = append(types, c.convertType(arg, env))
+		}
+		types = append(types, typ)
+
+		c.define(ctor.Name, &Object{
+			ctor.Name.Name,
+			c.mod.Name,
+			ast.Ctor,
+			ctor,
+			NewFunc(types...),
+			generic,
+		})
+	}
+}
+
+func (c *checker) declareAlias(decl *ast.AliasDecl) {
+	var env = make(map[string]Type)
+	var generic []*Var
+	for _, arg := range decl.Args {
+		v := NewVar(arg.Name)
+		env[arg.Name] = v
+		generic = append(generic, v)
+	}
+
+	typ := c.convertType(decl.Type, env)
+	c.define(decl.Name, &Object{decl.Name.Name, c.mod.Name, ast.Typ, decl, typ, generic})
+}
+
+// declareDef defines the object of a definition before any definition is
+// checked, so definitions can refer to the ones defined after them. If the
+// definition is annotated, its type is the annotated one. Otherwise, the type
+// is a variable that will be bound when the definition is checked.
+func (c *checker) declareDef(def *ast.Definition) {
+	obj := &Object{
+		Name:   def.Name.Name,
+		Module: c.mod.Name,
+		Kind:   ast.Var,
+		Node:   def.Name,
+	}
+
+	if def.Annotation != nil {
+		var env = make(map[string]Type)
+		obj.Type = c.convertType(def.Annotation.Type, env)
+		for _, t := range env {
+			if v, ok := t.(*Var); ok {
+				obj.Generic = append(obj.Generic, v)
+			}
+		}
+		sort.Slice(obj.Generic, func(i, j int) bool {
+			return obj.Generic[i].ID < obj.Generic[j].ID
+		})
+	} else {
+		c.level++
+		obj.Type = c.newVar("")
+		c.level--
+	}
+
+	c.define(def.Name, obj)
+}
+
+func (c *checker) checkDef(def *ast.Definition) {
+	obj := c.objects[def.Name]
+	name, failed := c.name, c.failed
+	c.name, c.failed = def.Name.Name, false
+	c.level++
+
+	// The body of an annotated definition is checked against the annotation
+	// with rigid variables, so it is not more specific than the annotation.
+	var t = obj.Type
+	if def.Annotation != nil {
+		subst := make(map[*Var]Type, len(obj.Generic))
+		for _, v := range obj.Generic {
+			rigid := NewRigidVar(v.Name)
+			rigid.Constraint = v.Constraint
+			subst[v] = rigid
+		}
+		t = Substitute(obj.Type, subst)
+	}
+
+	for _, arg := range def.Args {
+		var param Type
+		param, t = c.matchFunc(arg, t)
+		c.checkPattern(arg, param)
+	}
+	c.check(def.Body, t)
+
+	c.level--
+	c.name, c.failed = name, failed || c.failed
+	if def.Annotation == nil {
+		obj.Generic = c.generalize(obj.Type)
+	}
+}
+
+// matchFunc returns the type of the argument and the result of the given
+// function type. If the type is not a function, the error will be reported
+// in the given node.
+func (c *checker) matchFunc(node ast.Node, t Type) (arg, result Type) {
+	if fn, ok := Prune(t).(*Func); ok {
+		return fn.Arg, fn.Result
+	}
+
+	arg, result = c.newVar(""), c.newVar("")
+	c.unify(node, t, &Func{arg, result})
+	return arg, result
+}
+
+// check infers the type of the expression and unifies it with the expected
+// type.
+func (c *checker) check(e ast.Expr, expected Type) {
+	c.unify(e, expected, c.infer(e))
+}
+
+// infer infers the type of an expression and records it.
+func (c *checker) infer(e ast.Expr) Type {
+	c.node = e
+	t := c.exprType(e)
+	if c.info.Types != nil {
+		c.info.Types[e] = t
+	}
+	return t
+}
+
+func (c *checker) exprType(e ast.Expr) Type {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return c.ident(e)
+	case *ast.SelectorExpr:
+		return c.selector(e)
+	case *ast.BasicLit:
+		return c.literal(e)
+	case *ast.TupleLit:
+		var elems = make([]Type, len(e.Elems))
+		for i, el := range e.Elems {
+			elems[i] = c.infer(el)
+		}
+		return NewTuple(elems...)
+	case *ast.ListLit:
+		elem := c.newVar("")
+		for _, el := range e.Elems {
+			c.check(el, elem)
+		}
+		return NewList(elem)
+	case *ast.RecordLit:
+		var fields = make(map[string]Type, len(e.Fields))
+		for _, f := range e.Fields {
+			fields[f.Field.Name] = c.infer(f.Expr)
+		}
+		return NewRecord(fields, nil)
+	case *ast.RecordUpdate:
+		record := c.infer(e.Record)
+		var fields = make(map[string]Type, len(e.Fields))
+		for _, f := range e.Fields {
+			fields[f.Field.Name] = c.infer(f.Expr)
+		}
+		c.unify(e, NewRecord(fields, c.newVar("")), record)
+		return record
+	case *ast.FuncApp:
+		t := c.infer(e.Func)
+		for _, arg := range e.Args {
+			t = c.apply(e.Func, t, arg)
+		}
+		return t
+	case *ast.BinaryOp:
+		t := c.infer(e.Op)
+		t = c.apply(e.Op, t, e.Lhs)
+		return c.apply(e.Op, t, e.Rhs)
+	case *ast.UnaryOp:
+		// the only unary operator is the negation
+		t := c.newVar("number")
+		c.check(e.Expr, t)
+		return t
+	case *ast.IfExpr:
+		c.check(e.Cond, Bool)
+		t := c.infer(e.ThenExpr)
+		c.check(e.ElseExpr, t)
+		return t
+	case *ast.CaseExpr:
+		subject := c.infer(e.Expr)
+		result := c.newVar("")
+		for _, b := range e.Branches {
+			c.checkPattern(b.Pattern, subject)
+			c.check(b.Expr, result)
+		}
+		return result
+	case *ast.LetExpr:
+		c.checkDecls(e.Decls)
+		return c.infer(e.Body)
+	case *ast.Lambda:
+		var types []Type
+		for _, arg := range e.Args {
+			t := c.newVar("")
+			c.checkPattern(arg, t)
+			types = append(types, t)
+		}
+		return NewFunc(append(types, c.infer(e.Expr))...)
+	case *ast.AccessorExpr:
+		field := c.newVar("")
+		record := NewRecord(map[string]Type{e.Field.Name: field}, c.newVar(""))
+		return NewFunc(record, field)
+	case *ast.TupleCtor:
+		var elems = make([]Type, e.Elems)
+		for i := range elems {
+			elems[i] = c.newVar("")
+		}
+		return NewFunc(append(elems, NewTuple(elems...))...)
+	case *ast.ParensExpr:
+		return c.infer(e.Expr)
+	}
+
+	return c.newVar("")
+}
+
+// apply returns the result type of applying a function of the given type to
+// the given argument.
+func (c *checker) apply(fn ast.Node, t Type, arg ast.Expr) Type {
+	if f, ok := Prune(t).(*Func); ok {
+		c.check(arg, f.Arg)
+		return f.Result
+	}
+
+	result := c.newVar("")
+	c.unify(fn, t, &Func{c.infer(arg), result})
+	return result
+}
+
+func (c *checker) literal(lit *ast.BasicLit) Type {
+	switch lit.Type {
+	case ast.Int:
+		v := c.newVar("number")
+		c.literals = append(c.literals, literal{lit, v, c.mod, c.name})
+		return v
+	case ast.Float:
+		return Float
+	case ast.String:
+		return String
+	case ast.Char:
+		return Char
+	case ast.Bool:
+		return Bool
+	}
+	return c.newVar("")
+}
+
+func (c *checker) ident(id *ast.Ident) Type {
+	if id.Obj == nil && (id.Name == "True" || id.Name == "False") {
+		return Bool
+	}
+
+	obj := c.use(id)
+	if obj == nil {
+		return c.newVar("")
+	}
+	return c.instantiate(obj)
+}
+
+// selector returns the type of a qualified name, a record field access or
+// both, such as `Foo.bar.baz`.
+func (c *checker) selector(e *ast.SelectorExpr) Type {
+	var idents []*ast.Ident
+	var expr ast.Expr = e
+	for expr != nil {
+		switch sel := expr.(type) {
+		case *ast.SelectorExpr:
+			idents = append(idents, sel.Selector)
+			expr = sel.Expr
+		case *ast.Ident:
+			idents = append(idents, sel)
+			expr = nil
+		default:
+			expr = nil
+		}
+	}
+
+	var i int
+	for i < len(idents)-1 && isUpper(idents[i].Name) {
+		if obj := idents[i].Obj; obj == nil {
+			// modules that could not be found have already been reported
+			return c.newVar("")
+		} else if obj.Kind == ast.NativeMod {
+			return c.native(obj, idents[i:])
+		}
+		i++
+	}
+
+	t := c.ident(idents[i])
+	for _, field := range idents[i+1:] {
+		ft := c.newVar("")
+		c.unify(field, NewRecord(map[string]Type{field.Name: ft}, c.newVar("")), t)
+		t = ft
+	}
+	return t
+}
+
+// native returns the type of the value of the native module with the given
+// object that is selected with the given identifiers, which is the type it
+// is declared with. The values of the native modules without declarations
+// have no types.
+func (c *checker) native(obj *ast.Object, idents []*ast.Ident) Type {
+	imp, ok := obj.Node.(*ast.ImportDecl)
+	if !ok {
+		return c.newVar("")
+	}
+
+	native := c.natives[imp.ModuleName()]
+	if native == nil || native.DeclPath == "" {
+		return c.newVar("")
+	}
+
+	for _, id := range idents {
+		if isUpper(id.Name) {
+			continue
+		}
+
+		decl := native.Lookup(id.Name)
+		if decl == nil {
+			c.error(id, fmt.Errorf("types: native module %s does not declare %s", native.Name, id.Name))
+			return c.newVar("")
+		}
+		return c.convertType(decl.Type, make(map[string]Type))
+	}
+	return c.newVar("")
+}
+
+func (c *checker) checkPattern(pattern ast.Pattern, t Type) {
+	switch p := pattern.(type) {
+	case *ast.VarPattern:
+		c.define(p.Name, &Object{p.Name.Name, c.mod.Name, ast.Var, p, t, nil})
+	case *ast.AliasPattern:
+		c.checkPattern(p.Pattern, t)
+		c.define(p.Name, &Object{p.Name.Name, c.mod.Name, ast.Var, p, t, nil})
+	case *ast.LiteralPattern:
+		c.unify(p, t, c.literal(p.Literal))
+	case *ast.CtorPattern:
+		var ctor Type
+		switch name := p.Ctor.(type) {
+		case *ast.Ident:
+			ctor = c.ident(name)
+		case *ast.SelectorExpr:
+			ctor = c.selector(name)
+		default:
+			ctor = c.newVar("")
+		}
+
+		for _, arg := range p.Args {
+			var param Type
+			param, ctor = c.matchFunc(arg, ctor)
+			c.checkPattern(arg, param)
+		}
+		c.unify(p, t, ctor)
+	case *ast.TuplePattern:
+		var elems = make([]Type, len(p.Elems))
+		for i, el := range p.Elems {
+			elems[i] = c.newVar("")
+			c.checkPattern(el, elems[i])
+		}
+		c.unify(p, t, NewTuple(elems...))
+	case *ast.RecordPattern:
+		var fields = make(map[string]Type, len(p.Fields))
+		for _, f := range p.Fields {
+			ft := c.newVar("")
+			c.checkPattern(f, ft)
+			if v, ok := f.(*ast.VarPattern); ok {
+				fields[v.Name.Name] = ft
+			}
+		}
+		c.unify(p, t, NewRecord(fields, c.newVar("")))
+	case *ast.ListPattern:
+		elem := c.newVar("")
+		for _, el := range p.Elems {
+			c.checkPattern(el, elem)
+		}
+		c.unify(p, t, NewList(elem))
+	}
+}
+
+// convertType returns the type represented by the given type node. env
+// contains the types of the type variables by name, and type variables not
+// in env are added to it.
+func (c *checker) convertType(typ ast.Type, env map[string]Type) Type {
+	switch typ := typ.(type) {
+	case *ast.NamedType:
+		var args = make([]Type, len(typ.Args))
+		for i, arg := range typ.Args {
+			args[i] = c.convertType(arg, env)
+		}
+		return c.namedType(typ, args)
+	case *ast.VarType:
+		if t, ok := env[typ.Name]; ok {
+			return t
+		}
+		v := NewVar(typ.Name)
+		env[typ.Name] = v
+		return v
+	case *ast.FuncType:
+		var types []Type
+		for _, arg := range typ.Args {
+			types = append(types, c.convertType(arg, env))
+		}
+		return NewFunc(append(types, c.convertType(typ.Return, env))...)
+	case *ast.RecordType:
+		var fields = make(map[string]Type, len(typ.Fields))
+		for _, f := range typ.Fields {
+			fields[f.Name.Name] = c.convertType(f.Type, env)
+		}
+		return NewRecord(fields, nil)
+	case *ast.TupleType:
+		var elems = make([]Type, len(typ.Elems))
+		for i, el := range typ.Elems {
+			elems[i] = c.convertType(el, env)
+		}
+		return NewTuple(elems...)
+	}
+	return c.newVar("")
+}
+
+func (c *checker) namedType(typ *ast.NamedType, args []Type) Type {
+	id := typeIdent(typ)
+	if id == nil || id.Obj == nil {
+		return c.newVar("")
+	}
+
+	switch id.Obj.Kind {
+	case ast.BuiltinTyp:
+		if id.Name == "List" {
+			if !c.checkArgs(typ, id.Name, 1, args) {
+				return c.newVar("")
+			}
+			return NewList(args[0])
+		}
+
+		if !c.checkArgs(typ, id.Name, 0, args) {
+			return c.newVar("")
+		}
+		return NewNamed("", id.Name)
+	case ast.Typ:
+		switch decl := id.Obj.Node.(type) {
+		case *ast.UnionDecl:
+			if !c.checkArgs(typ, id.Name, len(decl.Args), args) {
+				return c.newVar("")
+			}
+			return NewNamed(c.typeModules[decl], decl.Name.Name, args...)
+		case *ast.AliasDecl:
+			if !c.checkArgs(typ, id.Name, len(decl.Args), args) || c.aliasDepth >= maxAliasDepth {
+				return c.newVar("")
+			}
+
+			var env = make(map[string]Type, len(args))
+			for i, arg := range decl.Args {
+				env[arg.Name] = args[i]
+			}
+
+			c.aliasDepth++
+			t := c.convertType(decl.Type, env)
+			c.aliasDepth--
+			return t
+		}
+	}
+
+	return c.newVar("")
+}
+
+// typeIdent returns the identifier of the type name, skipping the module
+// path of qualified names.
+func typeIdent(typ *ast.NamedType) *ast.Ident {
+	switch name := typ.Name.(type) {
+	case *ast.Ident:
+		return name
+	case *ast.SelectorExpr:
+		expr := name.Expr
+		for {
+			switch e := expr.(type) {
+			case *ast.SelectorExpr:
+				expr = e.Expr
+			case *ast.Ident:
+				return e
+			default:
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkArgs(node ast.Node, name string, expected int, args []Type) bool {
+	if expected != len(args) {
+		c.error(node, fmt.Errorf(
+			"types: type %s expects %d arguments, but got %d",
+			name, expected, len(args),
+		))
+		return false
+	}
+	return true
+}
+
+func (c *checker) unify(node ast.Node, expected, actual Type) {
+	if err := Unify(expected, actual); err != nil {
+		c.error(node, err)
+	}
+}
+
+func (c *checker) error(node ast.Node, err error) {
+	if c.failed {
+		return
+	}
+	c.failed = c.name != ""
+
+	e := &Error{
+		Module: c.mod.Name,
+		Path:   c.mod.Path,
+		Node:   node,
+		Name:   c.name,
+		Err:    err,
+	}
+
+	if mismatch, ok := err.(*MismatchError); ok {
+		e.Names, e.Candidates = c.suggestions(node, mismatch)
+	}
+
+	if c.err == nil {
+		c.err = e
+	}
+
+	if c.conf.Error != nil {
+		c.conf.Error(e)
+	}
+}
+
+// warn reports a soft error. Unlike errors, warnings are always reported.
+func (c *checker) warn(mod *ast.Module, name string, node ast.Node, err error) {
+	if c.conf.Error != nil {
+		c.conf.Error(&Error{
+			Module: mod.Name,
+			Path:   mod.Path,
+			Node:   node,
+			Name:   name,
+			Err:    err,
+			Soft:   true,
+		})
+	}
+}
+
+// suggestions returns the names of the node of a mismatch that may be
+// misspelled and the names that could be used instead of them, as in the
+// Names and Candidates of Error.
+func (c *checker) suggestions(node ast.Node, err *MismatchError) ([]string, []string) {
+	var names []string
+	switch node := node.(type) {
+	case *ast.Ident:
+		names = []string{node.Name}
+	case *ast.RecordLit:
+		for _, f := range node.Fields {
+			names = append(names, f.Field.Name)
+		}
+	case *ast.RecordUpdate:
+		for _, f := range node.Fields {
+			names = append(names, f.Field.Name)
+		}
+	}
+
+	expected, ok := Prune(err.Expected).(*Record)
+	actual, isRecord := Prune(err.Actual).(*Record)
+	if ok && isRecord && len(names) > 0 {
+		// the record of the node is the one with all its names, such as
+		// the one of a field access or of a record literal, and the names
+		// it has that the other record does not have may be misspelled
+		own, other := recordFields(expected), recordFields(actual)
+		if hasFields(other, names) {
+			own, other = other, own
+		}
+
+		var missing, candidates []string
+		for _, name := range names {
+			if !other[name] {
+				missing = append(missing, name)
+			}
+		}
+
+		if hasFields(own, names) && len(missing) > 0 {
+			for field := range other {
+				if !contains(names, field) {
+					candidates = append(candidates, field)
+				}
+			}
+			sort.Strings(candidates)
+			return missing, candidates
+		}
+	}
+
+	if _, ok := node.(*ast.Ident); ok {
+		return names, c.candidates(err.Expected)
+	}
+	return nil, nil
+}
+
+// recordFields returns the names of the fields of a record, including the
+// ones of its extension.
+func recordFields(r *Record) map[string]bool {
+	var fields = make(map[string]bool)
+	for r != nil {
+		for name := range r.Fields {
+			fields[name] = true
+		}
+		r, _ = Prune(r.Ext).(*Record)
+	}
+	return fields
+}
+
+// hasFields reports whether all the names are in the fields.
+func hasFields(fields map[string]bool, names []string) bool {
+	for _, name := range names {
+		if !fields[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the names of the values and constructors visible at
+// module level whose type can be unified with the given type. Values whose
+// type is not known yet are left out.
+func (c *checker) candidates(t Type) []string {
+	if c.mod.Scope == nil {
+		return nil
+	}
+
+	var names []string
+	var add = func(objs map[string]*ast.Object) {
+		for name, o := range objs {
+			if o.Kind != ast.Var && o.Kind != ast.Ctor {
+				continue
+			}
+
+			obj := c.objects[o.Node]
+			if obj == nil {
+				continue
+			}
+
+			if _, ok := Prune(obj.Type).(*Var); ok {
+				continue
+			}
+
+			if Compatible(t, c.instantiate(obj)) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	add(c.mod.Scope.Objects)
+	add(c.mod.Scope.Imported)
+	sort.Strings(names)
+	return names
+}
+
+func (c *checker) define(id *ast.Ident, obj *Object) {
+	c.objects[obj.Node] = obj
+	if c.info.Defs != nil {
+		c.info.Defs[id] = obj
+	}
+}
+
+func (c *checker) use(id *ast.Ident) *Object {
+	if id.Obj == nil || id.Obj.Node == nil {
+		return nil
+	}
+
+	obj := c.objects[id.Obj.Node]
+	if obj != nil && c.info.Uses != nil {
+		c.info.Uses[id] = obj
+	}
+	return obj
+}
+
+func (c *checker) newVar(name string) *Var {
+	v := NewVar(name)
+	v.level = c.level
+	return v
+}
+
+// instantiate returns the type of the object with all its generic type
+// variables replaced with new ones.
+func (c *checker) instantiate(obj *Object) Type {
+	if len(obj.Generic) == 0 {
+		return obj.Type
+	}
+
+	var subst = make(map[*Var]Type, len(obj.Generic))
+	for _, v := range obj.Generic {
+		nv := c.newVar(v.Name)
+		nv.Constraint = v.Constraint
+		subst[v] = nv
+	}
+	return Substitute(obj.Type, subst)
+}
+
+// generalize returns the unbound variables of the type that were created
+// in a deeper level than the current one.
+func (c *checker) generalize(t Type) []*Var {
+	var vars []*Var
+	var seen = make(map[*Var]struct{})
+	walkVars(t, func(v *Var) {
+		if _, ok := seen[v]; ok || v.Rigid || v.level <= c.level {
+			return
+		}
+		seen[v] = struct{}{}
+		vars = append(vars, v)
+	})
+	return vars
+}
+
+// resolveTypes replaces all the bound variables of the recorded types, now
+// that all of them have been checked.
+func (c *checker) resolveTypes() {
+	for e, t := range c.info.Types {
+		c.info.Types[e] = Resolve(t)
+	}
+
+	for _, obj := range c.objects {
+		obj.Type = Resolve(obj.Type)
+	}
+}
+
+// Substitute returns a copy of the type with the variables in subst
+// replaced by their substitutes.
+func Substitute(t Type, subst map[*Var]Type) Type {
+	switch t := Prune(t).(type) {
+	case *Var:
+		if s, ok := subst[t]; ok {
+			return s
+		}
+		return t
+	case *Named:
+		if len(t.Args) == 0 {
+			return t
+		}
+
+		var args = make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = Substitute(arg, subst)
+		}
+		return NewNamed(t.Module, t.Name, args...)
+	case *Func:
+		return &Func{Substitute(t.Arg, subst), Substitute(t.Result, subst)}
+	case *Tuple:
+		var elems = make([]Type, len(t.Elems))
+		for i, el := range t.Elems {
+			elems[i] = Substitute(el, subst)
+		}
+		return NewTuple(elems...)
+	case *Record:
+		var fields = make(map[string]Type, len(t.Fields))
+		for n, f := range t.Fields {
+			fields[n] = Substitute(f, subst)
+		}
+
+		var ext Type
+		if t.Ext != nil {
+			ext = Substitute(t.Ext, subst)
+		}
+		return NewRecord(fields, ext)
+	}
+	return t
+}
+
+// walkVars calls fn with every unbound variable in the type.
+func walkVars(t Type, fn func(*Var)) {
+	switch t := Prune(t).(type) {
+	case *Var:
+		fn(t)
+	case *Named:
+		for _, arg := range t.Args {
+			walkVars(arg, fn)
+		}
+	case *Func:
+		walkVars(t.Arg, fn)
+		walkVars(t.Result, fn)
+	case *Tuple:
+		for _, el := range t.Elems {
+			walkVars(el, fn)
+		}
+	case *Record:
+		for _, f := range t.Fields {
+			walkVars(f, fn)
+		}
+		if t.Ext != nil {
+			walkVars(t.Ext, fn)
+		}
+	}
+}
+
+func isUpper(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,209 @@
+package types_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/crash"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/report"
+	"github.com/elm-tangram/tangram/source"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/check/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	info := types.NewInfo()
+	var warnings []*types.Error
+	conf := types.Config{
+		Error: func(err *types.Error) {
+			warnings = append(warnings, err)
+		},
+	}
+	require.NoError(conf.Check(pkg, info))
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"origin", "{ x : Int, y : Int }"},
+		{"move", "Int -> { x : Int, y : Int } -> { x : Int, y : Int }"},
+		{"area", "Main.Shape -> Float"},
+		{"pair", "(number, String)"},
+		{"greeting", "String -> String"},
+		{"isOrigin", "{ x : Int, y : Int } -> Bool"},
+		{"apply", "(a -> b) -> a -> b"},
+		{"xs", "List number"},
+		{"getX", "{ a | x : b } -> b"},
+		{"isTrue", "Bool -> number"},
+		{"big", "Int"},
+		{"ignored", "String"},
+	}
+
+	main := pkg.Modules["Main"]
+	for _, c := range cases {
+		def := findDef(main, c.name)
+		require.NotNil(def, c.name)
+
+		obj := info.ObjectOf(def.Name)
+		require.NotNil(obj, c.name)
+		require.Equal(ast.Var, obj.Kind)
+		require.Equal("Main", obj.Module)
+		require.Equal(c.expected, types.TypeString(obj.Type), c.name)
+	}
+
+	move := findDef(main, "move")
+	update := move.Body.(*ast.RecordUpdate)
+	require.Equal("Int", types.TypeString(info.TypeOf(update.Fields[0].Expr)))
+	field := update.Fields[0].Expr.(*ast.BinaryOp).Lhs
+	require.Equal("Int", types.TypeString(info.TypeOf(field)))
+
+	op := update.Fields[0].Expr.(*ast.BinaryOp).Op
+	obj := info.Uses[op]
+	require.NotNil(obj)
+	require.Equal("Basics", obj.Module)
+	require.Equal("number -> number -> number", types.TypeString(obj.Type))
+
+	area := findDef(main, "area")
+	ctor := area.Body.(*ast.CaseExpr).Branches[0].Pattern.(*ast.CtorPattern)
+	obj = info.ObjectOf(ctor.Ctor.(*ast.Ident))
+	require.NotNil(obj)
+	require.Equal(ast.Ctor, obj.Kind)
+	require.Equal("Float -> Main.Shape", types.TypeString(obj.Type))
+
+	ignored := findDef(main, "ignored")
+	arg := ignored.Body.(*ast.FuncApp).Args[0]
+	require.Equal("Int", types.TypeString(info.TypeOf(arg)), "literal should default to Int")
+
+	pair := findDef(main, "pair")
+	one := pair.Body.(*ast.TupleLit).Elems[0].(*ast.FuncApp).Args[0]
+	require.Equal("number", types.TypeString(info.TypeOf(one)), "generic literal should not default")
+
+	require.Len(warnings, 1)
+	require.True(warnings[0].Soft)
+	require.Equal("big", warnings[0].Name)
+	require.Equal(&types.IntRangeError{"9007199254740993", types.MaxSafeInt}, warnings[0].Err)
+	require.Equal(report.Warning, report.NewTypeError(warnings[0]).Type())
+
+	warnings = nil
+	conf.MaxInt = math.MaxInt64
+	require.NoError(conf.Check(pkg, nil))
+	require.Len(warnings, 0)
+}
+
+func TestCheckErrors(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/errors/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	var errors []*types.Error
+	conf := types.Config{
+		Error: func(err *types.Error) {
+			errors = append(errors, err)
+		},
+	}
+
+	err = conf.Check(pkg, nil)
+	require.Error(err)
+	require.Len(errors, 8)
+	require.Equal(errors[0], err)
+
+	require.Equal("count", errors[0].Name)
+	require.IsType(&types.MismatchError{}, errors[0].Err)
+	require.Equal("types: expected Int, found String", err.Error())
+
+	require.Equal("self", errors[1].Name)
+	require.IsType(&types.InfiniteTypeError{}, errors[1].Err)
+	require.IsType(&report.InfiniteTypeError{}, report.NewTypeError(errors[1]))
+
+	require.Equal("wrong", errors[2].Name)
+	require.IsType(&types.MismatchError{}, errors[2].Err)
+
+	require.Equal("label", errors[3].Name)
+	require.Equal([]string{"label", "title"}, errors[3].Candidates)
+	r := report.NewTypeError(errors[3])
+	require.IsType(&report.TypeMismatchError{}, r)
+	require.Equal([]string{"title"}, r.(*report.TypeMismatchError).Suggestions)
+
+	var suggestions = make(map[string][]string)
+	for _, e := range errors[4:] {
+		suggestions[e.Name] = report.NewTypeError(e).(*report.TypeMismatchError).Suggestions
+	}
+	require.Equal(map[string][]string{
+		"color":   {"Green"},
+		"originX": {"x"},
+		"moved":   {"y"},
+		"point":   {"y"},
+	}, suggestions)
+}
+
+func TestCheckNatives(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/natives/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	var errors []*types.Error
+	info := types.NewInfo()
+	conf := types.Config{
+		Error: func(err *types.Error) {
+			errors = append(errors, err)
+		},
+	}
+	require.Error(conf.Check(pkg, info))
+
+	require.Len(errors, 2)
+	require.Equal("wrong", errors[0].Name)
+	require.IsType(&types.MismatchError{}, errors[0].Err)
+	require.Equal("missing", errors[1].Name)
+	require.EqualError(errors[1].Err, "types: native module Native.Basics does not declare missing")
+
+	// add has the declared type, whose number is an Int in Main
+	add := findDef(pkg.Modules["Basics"], "add")
+	require.Equal("Int -> Int -> Int", types.TypeString(info.ObjectOf(add.Name).Type))
+}
+
+func TestCheckCrash(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/errors/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	conf := types.Config{
+		Error: func(err *types.Error) {
+			panic("boom")
+		},
+	}
+
+	err = conf.Check(pkg, nil)
+	require.IsType(&crash.Error{}, err)
+	e := err.(*crash.Error)
+	require.Equal("type check", e.Phase)
+	require.Equal("Main", e.Module)
+	require.Equal(pkg.Modules["Main"].Path, e.Path)
+	require.Equal("boom", e.Value)
+
+	cm := source.NewCodeMap(source.NewFsLoader(nil))
+	defer cm.Close()
+	e.Locate(cm)
+	require.NotZero(e.Line)
+	require.NotEmpty(e.Snippet)
+	require.Contains(err.Error(), "internal compiler error: boom")
+}
+
+func findDef(mod *ast.Module, name string) *ast.Definition {
+	for _, d := range mod.Decls {
+		if def, ok := d.(*ast.Definition); ok && def.Name.Name == name {
+			return def
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package types
+
+// Compatible reports whether the two given types can be unified. Unlike
+// Unify, none of the type variables in the given types are bound.
+func Compatible(a, b Type) bool {
+	var vars = make(map[*Var]*Var)
+	return Unify(copyType(a, vars), copyType(b, vars)) == nil
+}
+
+// copyType returns a copy of the given type in which all unbound type
+// variables are replaced with new variables. vars contains the variables
+// already replaced, so the same variable is replaced always with the same
+// copy.
+func copyType(t Type, vars map[*Var]*Var) Type {
+	switch t := Prune(t).(type) {
+	case *Var:
+		if v, ok := vars[t]; ok {
+			return v
+		}
+
+		v := NewVar(t.Name)
+		v.Constraint = t.Constraint
+		v.Rigid = t.Rigid
+		vars[t] = v
+		return v
+	case *Named:
+		var args = make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = copyType(arg, vars)
+		}
+		return NewNamed(t.Module, t.Name, args...)
+	case *Func:
+		return &Func{copyType(t.Arg, vars), copyType(t.Result, vars)}
+	case *Tuple:
+		var elems = make([]Type, len(t.Elems))
+		for i, el := range t.Elems {
+			elems[i] = copyType(el, vars)
+		}
+		return NewTuple(elems...)
+	case *Record:
+		var fields = make(map[string]Type, len(t.Fields))
+		for n, f := range t.Fields {
+			fields[n] = copyType(f, vars)
+		}
+
+		var ext Type
+		if t.Ext != nil {
+			ext = copyType(t.Ext, vars)
+		}
+		return NewRecord(fields, ext)
+	}
+	return t
+}
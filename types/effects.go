@@ -0,0 +1,157 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// never is the type `Never` declared in the Basics module of the core
+// package.
+var never = NewNamed("Basics", "Never")
+
+func newTask(err, ok Type) *Named     { return NewNamed("Platform", "Task", err, ok) }
+func newRouter(app, self Type) *Named { return NewNamed("Platform", "Router", app, self) }
+
+// MainError is returned when the main value of a program does not have a
+// type that can be run.
+type MainError struct {
+	// Type of the main value.
+	Type Type
+}
+
+func (e *MainError) Error() string {
+	return fmt.Sprintf("types: main has type %s, but it must be a Program or an Html value", TypeString(e.Type))
+}
+
+// isProgram reports whether the type is one of the types main can have:
+// `Platform.Program flags model msg` or `VirtualDom.Node msg`, which is the
+// type `Html msg` and `Svg msg` are aliases of.
+func isProgram(t Type) bool {
+	n, ok := Prune(t).(*Named)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case n.Module == "Platform" && n.Name == "Program":
+		return len(n.Args) == 3
+	case n.Module == "VirtualDom" && n.Name == "Node":
+		return len(n.Args) == 1
+	}
+	return false
+}
+
+// checkMain checks the type of the main value of the given module, if it
+// has one.
+func (c *checker) checkMain(mod *ast.Module) {
+	def := findDef(mod, "main")
+	if def == nil {
+		return
+	}
+
+	c.mod = mod
+	obj := c.objects[def.Name]
+	if !isProgram(obj.Type) {
+		c.name, c.failed = def.Name.Name, false
+		c.error(def.Name, &MainError{obj.Type})
+		c.name, c.failed = "", false
+	}
+}
+
+// checkEffects checks that an effect module has valid types for the
+// commands and subscriptions it manages and defines all the functions an
+// effect manager needs with the right types:
+//
+//	init : Task Never state
+//	onEffects : Router msg self -> List (MyCmd msg) -> List (MySub msg) -> state -> Task Never state
+//	onSelfMsg : Router msg self -> self -> state -> Task Never state
+//	cmdMap : (a -> b) -> MyCmd a -> MyCmd b
+//	subMap : (a -> b) -> MySub a -> MySub b
+//
+// onEffects only takes the list of commands or subscriptions if the module
+// manages them, and cmdMap and subMap are only required if the module
+// manages commands or subscriptions, respectively.
+func (c *checker) checkEffects(mod *ast.Module) {
+	var state, msg, self = c.newVar("state"), c.newVar("msg"), c.newVar("self")
+	var onEffects = []Type{newRouter(msg, self)}
+
+	var effects = []struct{ field, mapper string }{
+		{"command", "cmdMap"},
+		{"subscription", "subMap"},
+	}
+
+	var expected = make(map[string]Type)
+	for _, e := range effects {
+		expr := mod.Module.ManagedType(e.field)
+		if expr == nil {
+			continue
+		}
+
+		typ, ok := c.managedType(expr)
+		if !ok {
+			c.error(expr, fmt.Errorf(
+				"types: the %s type of an effect module must be an union type with one type argument",
+				e.field,
+			))
+			continue
+		}
+
+		onEffects = append(onEffects, NewList(NewNamed(typ.Module, typ.Name, msg)))
+		a, b := c.newVar("a"), c.newVar("b")
+		expected[e.mapper] = NewFunc(
+			NewFunc(a, b),
+			NewNamed(typ.Module, typ.Name, a),
+			NewNamed(typ.Module, typ.Name, b),
+		)
+	}
+
+	expected["init"] = newTask(never, state)
+	expected["onEffects"] = NewFunc(append(onEffects, state, newTask(never, state))...)
+	expected["onSelfMsg"] = NewFunc(newRouter(msg, self), self, state, newTask(never, state))
+
+	for _, name := range []string{"init", "onEffects", "onSelfMsg", "cmdMap", "subMap"} {
+		t, ok := expected[name]
+		if !ok {
+			continue
+		}
+
+		def := findDef(mod, name)
+		if def == nil {
+			c.error(mod.Module, fmt.Errorf(
+				"types: effect module %s must define %s : %s",
+				mod.Name, name, TypeString(t),
+			))
+			continue
+		}
+
+		c.name, c.failed = name, false
+		c.unify(def.Name, t, c.instantiate(c.objects[def.Name]))
+		c.name, c.failed = "", false
+	}
+}
+
+// managedType returns the union type named by the given expression if it
+// has exactly one type argument.
+func (c *checker) managedType(expr ast.Expr) (*Named, bool) {
+	id, ok := expr.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return nil, false
+	}
+
+	decl, ok := id.Obj.Node.(*ast.UnionDecl)
+	if !ok || len(decl.Args) != 1 {
+		return nil, false
+	}
+
+	return NewNamed(c.typeModules[decl], decl.Name.Name), true
+}
+
+func findDef(mod *ast.Module, name string) *ast.Definition {
+	for _, d := range mod.Decls {
+		if def, ok := d.(*ast.Definition); ok && def.Name.Name == name {
+			return def
+		}
+	}
+	return nil
+}
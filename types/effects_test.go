@@ -0,0 +1,42 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckEffects(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/effects/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	conf := types.Config{Main: "Main"}
+	require.NoError(conf.Check(pkg, nil))
+}
+
+func TestCheckEffectsErrors(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/effects/src/BadMain.elm", parser.FullParse)
+	require.NoError(err)
+
+	var errors []string
+	conf := types.Config{
+		Main: "BadMain",
+		Error: func(err *types.Error) {
+			errors = append(errors, err.Error())
+		},
+	}
+	require.Error(conf.Check(pkg, nil))
+	require.Equal([]string{
+		"types: the subscription type of an effect module must be an union type with one type argument",
+		"types: effect module BadTick must define onSelfMsg : Platform.Router msg Int -> Int -> Int -> Platform.Task Basics.Never Int",
+		"types: effect module BadTick must define cmdMap : (a -> b) -> BadTick.MyCmd a -> BadTick.MyCmd b",
+		"types: main has type String, but it must be a Program or an Html value",
+	}, errors)
+}
@@ -0,0 +1,72 @@
+package types
+
+import "github.com/elm-tangram/tangram/ast"
+
+// Object is a named entity with a type, such as a definition, a variable
+// bound by a pattern, an union type constructor or a type declaration.
+type Object struct {
+	// Name of the object.
+	Name string
+	// Module is the name of the module where the object is defined.
+	Module string
+	// Kind of the object. It can be Var, Ctor or Typ.
+	Kind ast.ObjKind
+	// Node is the node defining the object. It is the same node the
+	// ast.Object of the object points to.
+	Node ast.Node
+	// Type of the object.
+	Type Type
+	// Generic are the type variables of Type that are quantified, that is,
+	// the ones that are replaced by new variables every time the object is
+	// used.
+	Generic []*Var
+}
+
+// Info holds the type information of a checked package. Only the
+// information of the maps that are not nil is recorded, so clients can
+// decide what they need.
+type Info struct {
+	// Types maps every checked expression to its type.
+	Types map[ast.Expr]Type
+	// Defs maps the identifiers defining objects to the objects they
+	// define.
+	Defs map[*ast.Ident]*Object
+	// Uses maps the identifiers referring to objects to the objects they
+	// refer to.
+	Uses map[*ast.Ident]*Object
+}
+
+// NewInfo creates a new Info with all its maps initialized, so all the
+// type information is recorded.
+func NewInfo() *Info {
+	return &Info{
+		Types: make(map[ast.Expr]Type),
+		Defs:  make(map[*ast.Ident]*Object),
+		Uses:  make(map[*ast.Ident]*Object),
+	}
+}
+
+// TypeOf returns the type of the given expression, or nil if it was not
+// recorded. If the expression is an identifier that was not recorded in
+// Types, the type of the object it defines or uses is returned.
+func (info *Info) TypeOf(e ast.Expr) Type {
+	if t, ok := info.Types[e]; ok {
+		return t
+	}
+
+	if id, ok := e.(*ast.Ident); ok {
+		if obj := info.ObjectOf(id); obj != nil {
+			return obj.Type
+		}
+	}
+	return nil
+}
+
+// ObjectOf returns the object defined or used by the given identifier, or
+// nil if there is none.
+func (info *Info) ObjectOf(id *ast.Ident) *Object {
+	if obj := info.Defs[id]; obj != nil {
+		return obj
+	}
+	return info.Uses[id]
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// MaxSafeInt is the greatest integer that can be represented exactly by
+// JavaScript numbers.
+const MaxSafeInt = 1<<53 - 1
+
+// IntRangeError is a soft error reported for integer literals that cannot be
+// represented exactly by the target backend.
+type IntRangeError struct {
+	// Value of the literal.
+	Value string
+	// Max is the greatest integer the backend can represent exactly.
+	Max int64
+}
+
+func (e *IntRangeError) Error() string {
+	return fmt.Sprintf("types: integer %s is out of the safe range [-%d, %d]", e.Value, e.Max, e.Max)
+}
+
+// literal is an integer literal and the type variable it was given.
+type literal struct {
+	lit  *ast.BasicLit
+	v    *Var
+	mod  *ast.Module
+	name string
+}
+
+// defaultLiterals binds to Int the types of the integer literals that are
+// still `number` once all the modules are checked and are not generic in
+// any object, so they are not left without a concrete type. The types of
+// literals that are generic, such as the `1` in `inc x = x + 1`, are left
+// as they are, because they will take the type they are used with.
+func (c *checker) defaultLiterals() {
+	var generic = make(map[*Var]struct{})
+	for _, obj := range c.objects {
+		for _, v := range obj.Generic {
+			generic[v] = struct{}{}
+		}
+	}
+
+	for _, l := range c.literals {
+		v, ok := Prune(l.v).(*Var)
+		if !ok || v.Rigid || v.Constraint != Number {
+			continue
+		}
+
+		if _, ok := generic[v]; !ok {
+			v.Instance = Int
+		}
+	}
+}
+
+// checkLiterals reports the integer literals of type Int whose value is out
+// of the range the target backend can represent exactly.
+func (c *checker) checkLiterals() {
+	max := c.conf.MaxInt
+	if max <= 0 {
+		max = MaxSafeInt
+	}
+
+	limit := big.NewInt(max)
+	for _, l := range c.literals {
+		if !isBuiltin(Prune(l.v), "Int") {
+			continue
+		}
+
+		n, ok := new(big.Int).SetString(l.lit.Value, 10)
+		if !ok || n.CmpAbs(limit) > 0 {
+			c.warn(l.mod, l.name, l.lit, &IntRangeError{l.lit.Value, max})
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// PortError is returned when a port does not have the type of an outgoing
+// port, `a -> Cmd msg`, or an incoming port, `(a -> msg) -> Sub msg`.
+type PortError struct {
+	// Port is the name of the port.
+	Port string
+	// Type of the port.
+	Type Type
+}
+
+func (e *PortError) Error() string {
+	return fmt.Sprintf(
+		"types: port %s has type %s, but it must be a function `a -> Cmd msg` or `(a -> msg) -> Sub msg`",
+		e.Port, TypeString(e.Type),
+	)
+}
+
+// PortValueError is returned when a port sends or receives values of a type
+// that cannot be transferred between Elm and JavaScript.
+type PortValueError struct {
+	// Port is the name of the port.
+	Port string
+	// Type is the type that is not allowed. It may be nested inside the
+	// type of the values of the port.
+	Type Type
+}
+
+func (e *PortValueError) Error() string {
+	return fmt.Sprintf(
+		"types: port %s cannot send or receive values of type %s",
+		e.Port, TypeString(e.Type),
+	)
+}
+
+// portValueTypes are the types, other than the builtin ones, that can be
+// transferred through a port, by module and name, along with their number
+// of type arguments.
+var portValueTypes = map[[2]string]int{
+	{"", "List"}:             1,
+	{"Maybe", "Maybe"}:       1,
+	{"Array", "Array"}:       1,
+	{"Json.Encode", "Value"}: 0,
+}
+
+// declarePort defines the object of a port with the type it is annotated
+// with and checks that type.
+func (c *checker) declarePort(decl *ast.PortDecl) {
+	name, failed := c.name, c.failed
+	c.name, c.failed = decl.Name.Name, false
+
+	var env = make(map[string]Type)
+	obj := &Object{
+		Name:   decl.Name.Name,
+		Module: c.mod.Name,
+		Kind:   ast.Var,
+		Node:   decl.Name,
+		Type:   c.convertType(decl.Type, env),
+	}
+	for _, t := range env {
+		if v, ok := t.(*Var); ok {
+			obj.Generic = append(obj.Generic, v)
+		}
+	}
+	sort.Slice(obj.Generic, func(i, j int) bool {
+		return obj.Generic[i].ID < obj.Generic[j].ID
+	})
+	c.define(decl.Name, obj)
+
+	if !c.mod.Module.PortModule {
+		c.error(decl, fmt.Errorf(
+			"types: port %s can only be declared in a port module",
+			decl.Name.Name,
+		))
+	} else {
+		c.checkPort(decl, obj.Type)
+	}
+
+	c.name, c.failed = name, failed || c.failed
+}
+
+// checkPort checks that the port has the type of an outgoing or incoming
+// port and that the values it sends or receives can be transferred between
+// Elm and JavaScript.
+func (c *checker) checkPort(decl *ast.PortDecl, t Type) {
+	fn, ok := Prune(t).(*Func)
+	if !ok {
+		c.error(decl.Type, &PortError{decl.Name.Name, t})
+		return
+	}
+
+	arg := funcArg(decl.Type)
+	switch result := Prune(fn.Result); {
+	case isNamed(result, "Platform.Cmd", "Cmd", 1):
+		c.checkPortValue(arg, fn.Arg)
+	case isNamed(result, "Platform.Sub", "Sub", 1):
+		tagger, ok := Prune(fn.Arg).(*Func)
+		msg := Prune(result.(*Named).Args[0])
+		if !ok || Prune(tagger.Result) != msg {
+			c.error(decl.Type, &PortError{decl.Name.Name, t})
+			return
+		}
+		c.checkPortValue(funcArg(arg), tagger.Arg)
+	default:
+		c.error(decl.Type, &PortError{decl.Name.Name, t})
+	}
+}
+
+// checkPortValue checks that values of the given type can be transferred
+// through a port. The error is reported in the innermost node of the type
+// that is not allowed.
+func (c *checker) checkPortValue(node ast.Type, t Type) bool {
+	switch t := Prune(t).(type) {
+	case *Named:
+		if t.Module == "" {
+			switch t.Name {
+			case "Int", "Float", "Bool", "String":
+				return true
+			}
+		}
+
+		if n, ok := portValueTypes[[2]string{t.Module, t.Name}]; ok && n == len(t.Args) {
+			for i, arg := range t.Args {
+				if !c.checkPortValue(typeArg(node, i), arg) {
+					return false
+				}
+			}
+			return true
+		}
+	case *Tuple:
+		tuple, _ := node.(*ast.TupleType)
+		for i, el := range t.Elems {
+			var elNode = node
+			if tuple != nil && i < len(tuple.Elems) {
+				elNode = tuple.Elems[i]
+			}
+
+			if !c.checkPortValue(elNode, el) {
+				return false
+			}
+		}
+		return true
+	case *Record:
+		if t.Ext != nil {
+			break
+		}
+
+		record, _ := node.(*ast.RecordType)
+		for _, name := range t.FieldNames() {
+			var fieldNode = node
+			if record != nil {
+				for _, f := range record.Fields {
+					if f.Name.Name == name {
+						fieldNode = f.Type
+					}
+				}
+			}
+
+			if !c.checkPortValue(fieldNode, t.Fields[name]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	c.error(node, &PortValueError{c.name, t})
+	return false
+}
+
+// typeArg returns the node of the i-th argument of the given named type. If
+// the node is not a named type or it is an alias, whose arguments may not
+// match the ones of the type it is expanded to, the node itself is returned.
+func typeArg(node ast.Type, i int) ast.Type {
+	named, ok := node.(*ast.NamedType)
+	if !ok || i >= len(named.Args) {
+		return node
+	}
+
+	if id := typeIdent(named); id == nil || id.Obj == nil {
+		return node
+	} else if _, ok := id.Obj.Node.(*ast.AliasDecl); ok {
+		return node
+	}
+	return named.Args[i]
+}
+
+// funcArg returns the node of the first argument of the given function
+// type, or the node itself if it is not a function type.
+func funcArg(node ast.Type) ast.Type {
+	if fn, ok := node.(*ast.FuncType); ok && len(fn.Args) > 0 {
+		return fn.Args[0]
+	}
+	return node
+}
+
+func isNamed(t Type, module, name string, args int) bool {
+	n, ok := t.(*Named)
+	return ok && n.Module == module && n.Name == name && len(n.Args) == args
+}
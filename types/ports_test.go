@@ -0,0 +1,81 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/elm-tangram/tangram/ast"
+	"github.com/elm-tangram/tangram/parser"
+	"github.com/elm-tangram/tangram/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPorts(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/ports/src/Ports.elm", parser.FullParse)
+	require.NoError(err)
+
+	info := types.NewInfo()
+	var conf types.Config
+	require.NoError(conf.Check(pkg, info))
+
+	save := findDef(pkg.Modules["Ports"], "save")
+	obj := info.ObjectOf(save.Body.(*ast.FuncApp).Func.(*ast.Ident))
+	require.NotNil(obj)
+	require.Equal("saveUser", obj.Name)
+	require.Equal(
+		"{ age : Int, emails : List String, manager : Maybe.Maybe (String, Float), name : String } -> Platform.Cmd.Cmd msg",
+		types.TypeString(obj.Type),
+	)
+}
+
+func TestCheckPortsErrors(t *testing.T) {
+	require := require.New(t)
+
+	pkg, err := parser.Parse("_testdata/ports/src/BadPorts.elm", parser.FullParse)
+	require.NoError(err)
+
+	var errors []*types.Error
+	conf := types.Config{
+		Error: func(err *types.Error) {
+			errors = append(errors, err)
+		},
+	}
+	require.Error(conf.Check(pkg, nil))
+
+	cases := []struct {
+		module string
+		name   string
+		msg    string
+		node   string
+	}{
+		{"Plain", "send", "types: port send can only be declared in a port module", ""},
+		{"BadPorts", "callback", "types: port callback cannot send or receive values of type Int -> String", ""},
+		{"BadPorts", "tags", "types: port tags cannot send or receive values of type Char", "Char"},
+		{"BadPorts", "shape", "types: port shape cannot send or receive values of type BadPorts.Shape", "Shape"},
+		{"BadPorts", "anything", "types: port anything cannot send or receive values of type a", ""},
+		{
+			"BadPorts", "notAPort",
+			"types: port notAPort has type Int -> Int, but it must be a function `a -> Cmd msg` or `(a -> msg) -> Sub msg`",
+			"",
+		},
+		{
+			"BadPorts", "otherMsg",
+			"types: port otherMsg has type (String -> Int) -> Platform.Sub.Sub msg, but it must be a function `a -> Cmd msg` or `(a -> msg) -> Sub msg`",
+			"",
+		},
+	}
+
+	require.Len(errors, len(cases))
+	for i, c := range cases {
+		require.Equal(c.module, errors[i].Module, c.name)
+		require.Equal(c.name, errors[i].Name)
+		require.Equal(c.msg, errors[i].Error())
+		if c.node != "" {
+			named, ok := errors[i].Node.(*ast.NamedType)
+			require.True(ok, c.name)
+			require.Equal(c.node, named.Name.(*ast.Ident).Name)
+		}
+	}
+}
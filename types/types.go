@@ -0,0 +1,255 @@
+// Package types declares the data types used to represent Elm types,
+// implements the unification of those types and the type checking of
+// resolved packages.
+package types
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// Type is the representation of an Elm type.
+type Type interface {
+	// String returns the type as it would be written in Elm source code.
+	String() string
+	isType()
+}
+
+// Constraint is a special constraint a type variable can have, restricting
+// the types it can be unified with.
+type Constraint byte
+
+const (
+	// NoConstraint means the variable can be unified with anything.
+	NoConstraint Constraint = iota
+	// Number can only be unified with Int and Float.
+	Number
+	// Comparable can only be unified with Int, Float, Char, String and
+	// lists and tuples of comparable values.
+	Comparable
+	// Appendable can only be unified with String and lists.
+	Appendable
+	// CompAppend can only be unified with String and lists of comparable
+	// values.
+	CompAppend
+)
+
+var constraintNames = [...]string{"", "number", "comparable", "appendable", "compappend"}
+
+func (c Constraint) String() string {
+	if int(c) >= len(constraintNames) {
+		return ""
+	}
+	return constraintNames[c]
+}
+
+// ConstraintOf returns the constraint implied by the given type variable
+// name, e.g. `number` or `comparable2`.
+func ConstraintOf(name string) Constraint {
+	for c := Number; c <= CompAppend; c++ {
+		n := c.String()
+		if len(name) >= len(n) && name[:len(n)] == n {
+			if isDigits(name[len(n):]) {
+				return c
+			}
+		}
+	}
+	return NoConstraint
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var lastVarID int64
+
+// Var is a type variable. A type variable that has no instance can be
+// unified with any other type satisfying its constraint.
+type Var struct {
+	// ID is the unique identifier of the variable.
+	ID int64
+	// Name is the name the variable was given in the source code, if any.
+	Name string
+	// Constraint of the variable.
+	Constraint Constraint
+	// Instance is the type this variable has been bound to, if any.
+	Instance Type
+	// Rigid variables are the ones written in type annotations. They can
+	// only be unified with themselves or with other unbound variables.
+	Rigid bool
+
+	// level is the let nesting level at which the variable was created.
+	// Variables are only generalized when the checker leaves their level.
+	level int
+}
+
+// NewVar creates a new type variable with the given name, which can be
+// empty. The constraint of the variable is infered from its name.
+func NewVar(name string) *Var {
+	return &Var{
+		ID:         atomic.AddInt64(&lastVarID, 1),
+		Name:       name,
+		Constraint: ConstraintOf(name),
+	}
+}
+
+// NewRigidVar creates a new rigid type variable with the given name.
+func NewRigidVar(name string) *Var {
+	v := NewVar(name)
+	v.Rigid = true
+	return v
+}
+
+func (*Var) isType()          {}
+func (v *Var) String() string { return TypeString(v) }
+
+// Named is a type with a name and, optionally, some type arguments, such as
+// `Int` or `Maybe a`.
+type Named struct {
+	// Module is the module where the type is declared. Builtin types have
+	// no module.
+	Module string
+	// Name of the type.
+	Name string
+	// Args are the type arguments.
+	Args []Type
+}
+
+// NewNamed creates a new named type.
+func NewNamed(module, name string, args ...Type) *Named {
+	return &Named{module, name, args}
+}
+
+func (*Named) isType()          {}
+func (t *Named) String() string { return TypeString(t) }
+
+// Func is a function type. Functions with more than one argument are
+// represented as functions returning another function.
+type Func struct {
+	// Arg is the type of the function argument.
+	Arg Type
+	// Result is the type of the function result.
+	Result Type
+}
+
+// NewFunc creates a curried function type with the given argument types
+// and the result type, which is always the last type given.
+func NewFunc(types ...Type) Type {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return &Func{types[0], NewFunc(types[1:]...)}
+}
+
+func (*Func) isType()          {}
+func (t *Func) String() string { return TypeString(t) }
+
+// Tuple is a tuple type. A tuple with no elements is the unit type.
+type Tuple struct {
+	// Elems are the types of the tuple elements.
+	Elems []Type
+}
+
+// NewTuple creates a new tuple type.
+func NewTuple(elems ...Type) *Tuple {
+	return &Tuple{elems}
+}
+
+func (*Tuple) isType()          {}
+func (t *Tuple) String() string { return TypeString(t) }
+
+// Record is a record type. A record with an extension is an extensible
+// record, that is, a record that has at least the given fields.
+type Record struct {
+	// Fields of the record by name.
+	Fields map[string]Type
+	// Ext is the type of the rest of the fields of the record, if any.
+	// Ext is always a type variable or another record.
+	Ext Type
+}
+
+// NewRecord creates a new record type with the given fields and extension,
+// which can be nil.
+func NewRecord(fields map[string]Type, ext Type) *Record {
+	if fields == nil {
+		fields = make(map[string]Type)
+	}
+	return &Record{fields, ext}
+}
+
+func (*Record) isType()          {}
+func (t *Record) String() string { return TypeString(t) }
+
+// FieldNames returns the names of the record fields sorted alphabetically.
+func (t *Record) FieldNames() []string {
+	var names = make([]string, 0, len(t.Fields))
+	for n := range t.Fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Builtin types.
+var (
+	Int    = NewNamed("", "Int")
+	Float  = NewNamed("", "Float")
+	Bool   = NewNamed("", "Bool")
+	String = NewNamed("", "String")
+	Char   = NewNamed("", "Char")
+	Unit   = NewTuple()
+)
+
+// NewList creates a new list type with the given element type.
+func NewList(elem Type) *Named {
+	return NewNamed("", "List", elem)
+}
+
+// Prune returns the type a type variable is bound to, following all the
+// chain of bound variables. If the type is not a bound variable, it is
+// returned as is.
+func Prune(t Type) Type {
+	if v, ok := t.(*Var); ok && v.Instance != nil {
+		v.Instance = Prune(v.Instance)
+		return v.Instance
+	}
+	return t
+}
+
+// Resolve returns the given type with all its bound type variables replaced
+// by the types they are bound to, at any depth.
+func Resolve(t Type) Type {
+	switch t := Prune(t).(type) {
+	case *Named:
+		if len(t.Args) == 0 {
+			return t
+		}
+		args := make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = Resolve(arg)
+		}
+		return NewNamed(t.Module, t.Name, args...)
+	case *Func:
+		return &Func{Resolve(t.Arg), Resolve(t.Result)}
+	case *Tuple:
+		elems := make([]Type, len(t.Elems))
+		for i, el := range t.Elems {
+			elems[i] = Resolve(el)
+		}
+		return NewTuple(elems...)
+	case *Record:
+		fields, ext := flattenRecord(t)
+		for n, f := range fields.Fields {
+			fields.Fields[n] = Resolve(f)
+		}
+		fields.Ext = ext
+		return fields
+	default:
+		return t
+	}
+}
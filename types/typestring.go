@@ -0,0 +1,211 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// TypeString returns the string representation of the given type. Unnamed
+// type variables are given names in the order they appear.
+func TypeString(t Type) string {
+	var w = newTypeWriter()
+	w.write(t, false)
+	return w.buf.String()
+}
+
+// TypeStrings returns the string representation of several types, naming
+// the unnamed type variables consistently across all of them.
+func TypeStrings(types ...Type) []string {
+	var w = newTypeWriter()
+	var result = make([]string, len(types))
+	for i, t := range types {
+		w.buf.Reset()
+		w.write(t, false)
+		result[i] = w.buf.String()
+	}
+	return result
+}
+
+type span struct {
+	start, end int
+}
+
+type typeWriter struct {
+	buf   bytes.Buffer
+	names map[*Var]string
+	used  map[string]struct{}
+	next  int
+
+	// mark is a variable whose occurrences will be recorded in marks.
+	mark  *Var
+	marks []span
+	// visiting contains the variables whose instance is being written, to
+	// avoid looping forever on infinite types.
+	visiting map[*Var]struct{}
+}
+
+func newTypeWriter() *typeWriter {
+	return &typeWriter{
+		names:    make(map[*Var]string),
+		used:     make(map[string]struct{}),
+		visiting: make(map[*Var]struct{}),
+	}
+}
+
+func (w *typeWriter) varName(v *Var) string {
+	if n, ok := w.names[v]; ok {
+		return n
+	}
+
+	var name = v.Name
+	if name == "" {
+		if v.Constraint != NoConstraint {
+			name = v.Constraint.String()
+		} else {
+			name = w.nextName()
+		}
+	}
+
+	if _, ok := w.used[name]; ok {
+		base := name
+		for i := 1; ; i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+			if _, ok := w.used[name]; !ok {
+				break
+			}
+		}
+	}
+
+	w.names[v] = name
+	w.used[name] = struct{}{}
+	return name
+}
+
+func (w *typeWriter) nextName() string {
+	for {
+		n := w.next
+		w.next++
+		name := string(rune('a' + n%26))
+		if n >= 26 {
+			name = fmt.Sprintf("%s%d", name, n/26)
+		}
+
+		if _, ok := w.used[name]; !ok {
+			return name
+		}
+	}
+}
+
+// write writes the type to the buffer. If nested is true, types that would
+// be ambiguous as an argument of another type are wrapped with parenthesis.
+func (w *typeWriter) write(t Type, nested bool) {
+	switch t := t.(type) {
+	case *Var:
+		if t == w.mark {
+			start := w.buf.Len()
+			w.buf.WriteString(w.varName(t))
+			w.marks = append(w.marks, span{start, w.buf.Len()})
+			return
+		}
+
+		if t.Instance != nil {
+			if _, ok := w.visiting[t]; !ok {
+				w.visiting[t] = struct{}{}
+				w.write(t.Instance, nested)
+				delete(w.visiting, t)
+				return
+			}
+		}
+
+		w.buf.WriteString(w.varName(t))
+	case *Named:
+		if nested && len(t.Args) > 0 {
+			w.buf.WriteRune('(')
+			defer w.buf.WriteRune(')')
+		}
+
+		if t.Module != "" {
+			w.buf.WriteString(t.Module)
+			w.buf.WriteRune('.')
+		}
+		w.buf.WriteString(t.Name)
+		for _, arg := range t.Args {
+			w.buf.WriteRune(' ')
+			w.write(arg, true)
+		}
+	case *Func:
+		if nested {
+			w.buf.WriteRune('(')
+			defer w.buf.WriteRune(')')
+		}
+
+		_, isFunc := Prune(t.Arg).(*Func)
+		w.write(t.Arg, isFunc)
+		w.buf.WriteString(" -> ")
+		w.write(t.Result, false)
+	case *Tuple:
+		w.buf.WriteRune('(')
+		for i, el := range t.Elems {
+			if i > 0 {
+				w.buf.WriteString(", ")
+			}
+			w.write(el, false)
+		}
+		w.buf.WriteRune(')')
+	case *Record:
+		w.writeRecord(t)
+	default:
+		w.buf.WriteRune('?')
+	}
+}
+
+func (w *typeWriter) writeRecord(t *Record) {
+	fields, ext := flattenRecord(t)
+	if len(fields.Fields) == 0 && ext == nil {
+		w.buf.WriteString("{}")
+		return
+	}
+
+	w.buf.WriteString("{ ")
+	if ext != nil {
+		w.write(ext, false)
+		w.buf.WriteString(" | ")
+	}
+
+	for i, name := range fields.FieldNames() {
+		if i > 0 {
+			w.buf.WriteString(", ")
+		}
+		w.buf.WriteString(name)
+		w.buf.WriteString(" : ")
+		w.write(fields.Fields[name], false)
+	}
+	w.buf.WriteString(" }")
+}
+
+// flattenRecord merges the fields of a record with the fields of all its
+// record extensions, returning the resulting record and the type variable
+// of its extension, if it has not been bound to a record.
+func flattenRecord(t *Record) (*Record, Type) {
+	var fields = make(map[string]Type)
+	var ext Type = t
+	for ext != nil {
+		r, ok := Prune(ext).(*Record)
+		if !ok {
+			break
+		}
+
+		for n, f := range r.Fields {
+			if _, ok := fields[n]; !ok {
+				fields[n] = f
+			}
+		}
+		ext = r.Ext
+	}
+
+	if ext != nil {
+		ext = Prune(ext)
+	}
+
+	return NewRecord(fields, nil), ext
+}
@@ -0,0 +1,443 @@
+package types
+
+import "fmt"
+
+// MismatchError is returned when two types cannot be unified.
+type MismatchError struct {
+	// Expected is the type that was expected.
+	Expected Type
+	// Actual is the type that was found instead.
+	Actual Type
+	// Reason is an optional explanation of why the types do not match.
+	Reason string
+}
+
+func (e *MismatchError) Error() string {
+	types := TypeStrings(e.Expected, e.Actual)
+	msg := fmt.Sprintf("types: expected %s, found %s", types[0], types[1])
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// InfiniteTypeError is returned when a type variable would need to be bound
+// to a type containing the variable itself, which would create an infinite
+// type, e.g. `a = List a`.
+type InfiniteTypeError struct {
+	// Var is the variable that would be bound.
+	Var *Var
+	// Type is the type the variable would be bound to.
+	Type Type
+}
+
+func (e *InfiniteTypeError) Error() string {
+	name, typ, _ := e.Highlight()
+	return fmt.Sprintf("types: infinite type %s = %s", name, typ)
+}
+
+// Highlight returns the name of the variable, the representation of the type
+// the variable would be bound to and a line with a "^" under every
+// occurrence of the variable in that type.
+func (e *InfiniteTypeError) Highlight() (name, typ, marks string) {
+	w := newTypeWriter()
+	w.mark = e.Var
+	name = w.varName(e.Var)
+	w.write(e.Type, false)
+	typ = w.buf.String()
+
+	var line = make([]byte, len(typ))
+	for i := range line {
+		line[i] = ' '
+	}
+
+	var end int
+	for _, s := range w.marks {
+		for i := s.start; i < s.end; i++ {
+			line[i] = '^'
+		}
+		end = s.end
+	}
+
+	return name, typ, string(line[:end])
+}
+
+// InfiniteTypeHint is a hint about the probable cause of an infinite type.
+type InfiniteTypeHint byte
+
+const (
+	// NoHint means there is no clear cause of the infinite type.
+	NoHint InfiniteTypeHint = iota
+	// MissingArgument means the infinite type is probably the result of a
+	// function applied to fewer arguments than it needs.
+	MissingArgument
+	// FlippedArguments means the infinite type is probably the result of
+	// passing the arguments of a function in the wrong order.
+	FlippedArguments
+)
+
+// Hint returns the most probable cause of the infinite type. If the variable
+// would be bound to a function, an argument is probably missing somewhere. If the variable is nested inside another type, such as
+// in `a = List a`, it is probably because the arguments of a function have
+// been flipped, like in `xs :: x`.
+func (e *InfiniteTypeError) Hint() InfiniteTypeHint {
+	switch Prune(e.Type).(type) {
+	case *Func:
+		return MissingArgument
+	case *Named, *Tuple:
+		return FlippedArguments
+	}
+	return NoHint
+}
+
+// Unify unifies the two given types, binding the type variables in them
+// when it is needed. `a` is considered the expected type and `b` the actual
+// one for error reporting purposes.
+func Unify(a, b Type) error {
+	err := unify(a, b)
+	if e, ok := err.(*MismatchError); ok {
+		if e.Expected != a || e.Actual != b {
+			return &MismatchError{a, b, e.Reason}
+		}
+	}
+	return err
+}
+
+func unify(a, b Type) error {
+	a, b = Prune(a), Prune(b)
+	if a == b {
+		return nil
+	}
+
+	if v, ok := a.(*Var); ok && !v.Rigid {
+		return bindVar(v, b)
+	}
+
+	if v, ok := b.(*Var); ok && !v.Rigid {
+		return bindVar(v, a)
+	}
+
+	if v, ok := a.(*Var); ok {
+		return rigidMismatch(a, b, v)
+	}
+
+	if v, ok := b.(*Var); ok {
+		return rigidMismatch(a, b, v)
+	}
+
+	switch a := a.(type) {
+	case *Named:
+		b, ok := b.(*Named)
+		if !ok || a.Module != b.Module || a.Name != b.Name || len(a.Args) != len(b.Args) {
+			return &MismatchError{Expected: a, Actual: b}
+		}
+
+		for i := range a.Args {
+			if err := unify(a.Args[i], b.Args[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Func:
+		b, ok := b.(*Func)
+		if !ok {
+			return &MismatchError{Expected: a, Actual: b}
+		}
+
+		if err := unify(a.Arg, b.Arg); err != nil {
+			return err
+		}
+		return unify(a.Result, b.Result)
+	case *Tuple:
+		b, ok := b.(*Tuple)
+		if !ok || len(a.Elems) != len(b.Elems) {
+			return &MismatchError{Expected: a, Actual: b}
+		}
+
+		for i := range a.Elems {
+			if err := unify(a.Elems[i], b.Elems[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Record:
+		b, ok := b.(*Record)
+		if !ok {
+			return &MismatchError{Expected: a, Actual: b}
+		}
+		return unifyRecords(a, b)
+	}
+
+	return &MismatchError{Expected: a, Actual: b}
+}
+
+func unifyRecords(a, b *Record) error {
+	fa, ea := flattenRecord(a)
+	fb, eb := flattenRecord(b)
+
+	var onlyA, onlyB = make(map[string]Type), make(map[string]Type)
+	for n, t := range fa.Fields {
+		if tb, ok := fb.Fields[n]; ok {
+			if err := unify(t, tb); err != nil {
+				return err
+			}
+		} else {
+			onlyA[n] = t
+		}
+	}
+
+	for n, t := range fb.Fields {
+		if _, ok := fa.Fields[n]; !ok {
+			onlyB[n] = t
+		}
+	}
+
+	switch {
+	case ea == nil && eb == nil:
+		if len(onlyA) > 0 || len(onlyB) > 0 {
+			return missingFields(a, b, onlyA, onlyB)
+		}
+		return nil
+	case eb == nil:
+		if len(onlyA) > 0 {
+			return missingFields(a, b, onlyA, nil)
+		}
+		return unify(ea, NewRecord(onlyB, nil))
+	case ea == nil:
+		if len(onlyB) > 0 {
+			return missingFields(a, b, nil, onlyB)
+		}
+		return unify(eb, NewRecord(onlyA, nil))
+	case ea == eb:
+		if len(onlyA) > 0 || len(onlyB) > 0 {
+			return missingFields(a, b, onlyA, onlyB)
+		}
+		return nil
+	}
+
+	if len(onlyA) == 0 && len(onlyB) == 0 {
+		return unify(ea, eb)
+	}
+
+	rest := NewVar("")
+	if err := unify(ea, NewRecord(onlyB, rest)); err != nil {
+		return err
+	}
+	return unify(eb, NewRecord(onlyA, rest))
+}
+
+func missingFields(a, b *Record, onlyA, onlyB map[string]Type) error {
+	var field string
+	var missingIn = "expected"
+	for n := range onlyB {
+		if field == "" || n < field {
+			field = n
+		}
+	}
+
+	if field == "" {
+		missingIn = "actual"
+		for n := range onlyA {
+			if field == "" || n < field {
+				field = n
+			}
+		}
+	}
+
+	return &MismatchError{
+		Expected: a,
+		Actual:   b,
+		Reason:   fmt.Sprintf("field %q is missing in the %s record", field, missingIn),
+	}
+}
+
+func rigidMismatch(a, b Type, v *Var) error {
+	other := a
+	if other == v {
+		other = b
+	}
+
+	return &MismatchError{
+		Expected: a,
+		Actual:   b,
+		Reason:   rigidReason(v, TypeString(other)),
+	}
+}
+
+func rigidReason(v *Var, other string) string {
+	return fmt.Sprintf("%s comes from a type annotation and can be more general than %s", TypeString(v), other)
+}
+
+func bindVar(v *Var, t Type) error {
+	if other, ok := t.(*Var); ok {
+		c, ok := mergeConstraints(v.Constraint, other.Constraint)
+		if ok && other.Rigid && c != other.Constraint {
+			return &MismatchError{
+				Expected: v,
+				Actual:   other,
+				Reason:   rigidReason(other, c.String()),
+			}
+		}
+
+		if !ok {
+			return &MismatchError{
+				Expected: v,
+				Actual:   other,
+				Reason:   fmt.Sprintf("%s and %s are not compatible", v.Constraint, other.Constraint),
+			}
+		}
+
+		if !other.Rigid {
+			if other.Name == "" && v.Name != "" {
+				other.Name = v.Name
+			}
+			other.Constraint = c
+			if other.level > v.level {
+				other.level = v.level
+			}
+		}
+		v.Instance = other
+		return nil
+	}
+
+	if occurs(v, t) {
+		return &InfiniteTypeError{v, t}
+	}
+
+	if !satisfies(v.Constraint, t) {
+		return &MismatchError{
+			Expected: v,
+			Actual:   t,
+			Reason:   fmt.Sprintf("%s is not %s", TypeString(t), v.Constraint),
+		}
+	}
+
+	adjustLevels(t, v.level)
+	v.Instance = t
+	return nil
+}
+
+// adjustLevels lowers the level of all the unbound variables in the type to
+// the given one, so they are not generalized before the variable the type
+// is being bound to.
+func adjustLevels(t Type, level int) {
+	walkVars(t, func(v *Var) {
+		if v.level > level {
+			v.level = level
+		}
+	})
+}
+
+func occurs(v *Var, t Type) bool {
+	switch t := Prune(t).(type) {
+	case *Var:
+		return t == v
+	case *Named:
+		for _, arg := range t.Args {
+			if occurs(v, arg) {
+				return true
+			}
+		}
+	case *Func:
+		return occurs(v, t.Arg) || occurs(v, t.Result)
+	case *Tuple:
+		for _, el := range t.Elems {
+			if occurs(v, el) {
+				return true
+			}
+		}
+	case *Record:
+		for _, f := range t.Fields {
+			if occurs(v, f) {
+				return true
+			}
+		}
+		return t.Ext != nil && occurs(v, t.Ext)
+	}
+	return false
+}
+
+func mergeConstraints(a, b Constraint) (Constraint, bool) {
+	if a == b || b == NoConstraint {
+		return a, true
+	}
+
+	if a == NoConstraint {
+		return b, true
+	}
+
+	if a > b {
+		a, b = b, a
+	}
+
+	switch {
+	case a == Number && b == Comparable:
+		return Number, true
+	case a == Comparable && (b == Appendable || b == CompAppend):
+		return CompAppend, true
+	case a == Appendable && b == CompAppend:
+		return CompAppend, true
+	}
+
+	return NoConstraint, false
+}
+
+// satisfies reports whether the type satisfies the constraint. Type
+// variables found inside the type may be constrained further for the type
+// to satisfy the constraint.
+func satisfies(c Constraint, t Type) bool {
+	t = Prune(t)
+	if v, ok := t.(*Var); ok {
+		merged, ok := mergeConstraints(c, v.Constraint)
+		if v.Rigid {
+			return ok && merged == v.Constraint
+		}
+
+		if ok {
+			v.Constraint = merged
+		}
+		return ok
+	}
+
+	switch c {
+	case NoConstraint:
+		return true
+	case Number:
+		return isBuiltin(t, "Int") || isBuiltin(t, "Float")
+	case Comparable:
+		switch t := t.(type) {
+		case *Named:
+			switch {
+			case isBuiltin(t, "Int"), isBuiltin(t, "Float"),
+				isBuiltin(t, "Char"), isBuiltin(t, "String"):
+				return true
+			case isBuiltin(t, "List"):
+				return satisfies(Comparable, t.Args[0])
+			}
+		case *Tuple:
+			for _, el := range t.Elems {
+				if !satisfies(Comparable, el) {
+					return false
+				}
+			}
+			return len(t.Elems) > 0
+		}
+		return false
+	case Appendable:
+		return isBuiltin(t, "String") || isBuiltin(t, "List")
+	case CompAppend:
+		if isBuiltin(t, "String") {
+			return true
+		}
+
+		if l, ok := t.(*Named); ok && isBuiltin(l, "List") {
+			return satisfies(Comparable, l.Args[0])
+		}
+	}
+	return false
+}
+
+func isBuiltin(t Type, name string) bool {
+	n, ok := t.(*Named)
+	return ok && n.Module == "" && n.Name == name
+}
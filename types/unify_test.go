@@ -0,0 +1,166 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnify(t *testing.T) {
+	require := require.New(t)
+
+	a := NewVar("a")
+	require.NoError(Unify(NewList(a), NewList(Int)))
+	require.Equal(Int, Prune(a))
+
+	b, c := NewVar(""), NewVar("")
+	require.NoError(Unify(NewFunc(b, c), NewFunc(String, NewList(b))))
+	require.Equal("String -> List String", TypeString(NewFunc(b, c)))
+
+	err := Unify(NewList(Int), NewList(String))
+	require.IsType(&MismatchError{}, err)
+	require.Equal("types: expected List Int, found List String", err.Error())
+
+	require.Error(Unify(NewTuple(Int, Int), NewTuple(Int)))
+	require.Error(Unify(NewFunc(Int, Int), Int))
+}
+
+func TestUnifyConstraints(t *testing.T) {
+	cases := []struct {
+		name string
+		typ  Type
+		ok   bool
+	}{
+		{"number", Int, true},
+		{"number", Float, true},
+		{"number", String, false},
+		{"comparable", Char, true},
+		{"comparable", NewList(String), true},
+		{"comparable", NewTuple(Int, Float), true},
+		{"comparable", NewFunc(Int, Int), false},
+		{"appendable", NewList(NewFunc(Int, Int)), true},
+		{"appendable", Int, false},
+		{"compappend", NewList(Int), true},
+		{"compappend", NewList(NewFunc(Int, Int)), false},
+	}
+
+	for _, c := range cases {
+		err := Unify(NewVar(c.name), c.typ)
+		if c.ok {
+			require.NoError(t, err, "%s with %s", c.name, c.typ)
+		} else {
+			require.Error(t, err, "%s with %s", c.name, c.typ)
+		}
+	}
+
+	n, cmp := NewVar("number"), NewVar("comparable")
+	require.NoError(t, Unify(n, cmp))
+	require.Equal(t, Number, Prune(n).(*Var).Constraint)
+	require.Error(t, Unify(NewVar("number"), NewVar("appendable")))
+}
+
+func TestUnifyRigid(t *testing.T) {
+	require := require.New(t)
+
+	a := NewRigidVar("a")
+	err := Unify(a, Int)
+	require.IsType(&MismatchError{}, err)
+	require.Contains(err.Error(), "a comes from a type annotation")
+	require.Error(Unify(a, NewRigidVar("b")))
+	require.Error(Unify(NewVar("number"), a))
+
+	v := NewVar("")
+	require.NoError(Unify(NewList(v), NewList(a)))
+	require.Equal(a, Prune(v))
+	require.Equal("List a", TypeString(Resolve(NewList(v))))
+
+	n := NewRigidVar("number")
+	require.NoError(Unify(NewVar("comparable"), n))
+	require.Equal(Number, n.Constraint)
+}
+
+func TestUnifyRecords(t *testing.T) {
+	require := require.New(t)
+
+	closed := func(fields map[string]Type) *Record { return NewRecord(fields, nil) }
+
+	require.NoError(Unify(
+		closed(map[string]Type{"x": Int, "y": Int}),
+		closed(map[string]Type{"y": Int, "x": Int}),
+	))
+
+	err := Unify(
+		closed(map[string]Type{"x": Int}),
+		closed(map[string]Type{"x": Int, "y": Int}),
+	)
+	require.Error(err)
+	require.Contains(err.Error(), `field "y" is missing in the expected record`)
+
+	ext := NewVar("a")
+	open := NewRecord(map[string]Type{"x": NewVar("")}, ext)
+	target := closed(map[string]Type{"x": Float, "name": String})
+	require.NoError(Unify(open, target))
+	require.Equal("{ name : String, x : Float }", TypeString(open))
+
+	r1 := NewRecord(map[string]Type{"x": Int}, NewVar(""))
+	r2 := NewRecord(map[string]Type{"y": Int}, NewVar(""))
+	require.NoError(Unify(r1, r2))
+	require.Equal("{ a | x : Int, y : Int }", TypeString(r1))
+}
+
+func TestInfiniteType(t *testing.T) {
+	require := require.New(t)
+
+	a := NewVar("")
+	err := Unify(a, NewList(a))
+	require.IsType(&InfiniteTypeError{}, err)
+
+	e := err.(*InfiniteTypeError)
+	name, typ, marks := e.Highlight()
+	require.Equal("a", name)
+	require.Equal("List a", typ)
+	require.Equal("     ^", marks)
+	require.Equal(FlippedArguments, e.Hint())
+	require.Equal("types: infinite type a = List a", e.Error())
+
+	b := NewVar("b")
+	err = Unify(NewFunc(Int, b), NewFunc(Int, NewFunc(b, b)))
+	require.IsType(&InfiniteTypeError{}, err)
+	e = err.(*InfiniteTypeError)
+	name, typ, marks = e.Highlight()
+	require.Equal("b", name)
+	require.Equal("b -> b", typ)
+	require.Equal("^    ^", marks)
+	require.Equal(MissingArgument, e.Hint())
+}
+
+func TestTypeString(t *testing.T) {
+	a, b := NewVar(""), NewVar("")
+	cases := []struct {
+		typ      Type
+		expected string
+	}{
+		{NewFunc(NewFunc(a, b), NewList(a), NewList(b)), "(a -> b) -> List a -> List b"},
+		{NewNamed("Maybe", "Maybe", NewList(Int)), "Maybe.Maybe (List Int)"},
+		{NewTuple(Int, NewVar("number")), "(Int, number)"},
+		{Unit, "()"},
+		{NewRecord(nil, nil), "{}"},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, TypeString(c.typ))
+	}
+
+	require.Equal(t, []string{"a", "List b", "a -> b"}, TypeStrings(a, NewList(b), NewFunc(a, b)))
+}
+
+func TestCompatible(t *testing.T) {
+	require := require.New(t)
+
+	a := NewVar("")
+	require.True(Compatible(NewList(a), NewList(Int)))
+	require.Nil(a.Instance, "variables must not be bound")
+	require.True(Compatible(NewFunc(a, a), NewFunc(String, String)))
+	require.False(Compatible(NewFunc(a, a), NewFunc(String, Int)))
+	require.False(Compatible(NewVar("number"), String))
+}
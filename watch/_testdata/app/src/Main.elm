@@ -0,0 +1,9 @@
+module Main exposing (..)
+
+import Model
+import View
+
+
+main : Int
+main =
+    View.view
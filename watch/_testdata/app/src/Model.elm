@@ -0,0 +1,6 @@
+module Model exposing (..)
+
+
+initial : Int
+initial =
+    1
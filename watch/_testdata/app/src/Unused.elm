@@ -0,0 +1,6 @@
+module Unused exposing (..)
+
+
+unused : Int
+unused =
+    0
@@ -0,0 +1,8 @@
+module View exposing (..)
+
+import Model
+
+
+view : Int
+view =
+    Model.initial
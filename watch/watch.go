@@ -0,0 +1,160 @@
+// Package watch implements the rebuilding of programs when their source code
+// changes. A Watcher polls the Elm files of some directories and reports the
+// ones that were added, modified or removed, and Affected uses the imports
+// of the modules of the last build to tell which of them must be compiled
+// again: the modules of the changed files and every module that imports
+// them, directly or not.
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/elm-tangram/tangram/ast"
+)
+
+// DefaultInterval is the default time between two polls of the files.
+const DefaultInterval = 500 * time.Millisecond
+
+// stamp identifies a version of a file.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls the Elm files in some directories, looking for them
+// recursively, except in the elm-stuff directories.
+type Watcher struct {
+	// Interval is the time between two polls of the files.
+	Interval time.Duration
+	dirs     []string
+	files    map[string]stamp
+}
+
+// New creates a new watcher of the given directories, with the current
+// state of their files as the one the changes are reported from. The
+// directories that do not exist are watched in case they are created.
+func New(dirs ...string) (*Watcher, error) {
+	w := &Watcher{Interval: DefaultInterval, dirs: dirs}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	w.files = files
+	return w, nil
+}
+
+// scan returns the current state of the watched files.
+func (w *Watcher) scan() (map[string]stamp, error) {
+	var files = make(map[string]stamp)
+	for _, dir := range w.dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if info.IsDir() && info.Name() == "elm-stuff" {
+				return filepath.SkipDir
+			}
+
+			if !info.IsDir() && filepath.Ext(path) == ".elm" {
+				files[path] = stamp{info.ModTime(), info.Size()}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Changes returns the files that were added, modified or removed since the
+// watcher was created or the last time the changes were returned, sorted.
+func (w *Watcher) Changes() ([]string, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for path, s := range files {
+		if prev, ok := w.files[path]; !ok || prev != s {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	w.files = files
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Wait polls the files until some of them change and returns them. It
+// returns no files if done is closed before that.
+func (w *Watcher) Wait(done <-chan struct{}) ([]string, error) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil, nil
+		case <-ticker.C:
+			changed, err := w.Changes()
+			if err != nil || len(changed) > 0 {
+				return changed, err
+			}
+		}
+	}
+}
+
+// Affected returns the modules of the given package that must be compiled
+// again after the given files changed, in the order they are resolved in.
+// They are the modules of the files and the ones that import any affected
+// module.
+func Affected(pkg *ast.Package, changed []string) []string {
+	var paths = make(map[string]bool)
+	for _, path := range changed {
+		paths[absPath(path)] = true
+	}
+
+	var affected = make(map[string]bool)
+	var result []string
+	for _, name := range pkg.Order {
+		mod, ok := pkg.Modules[name]
+		if !ok {
+			continue
+		}
+
+		isAffected := paths[absPath(mod.Path)]
+		for _, imp := range mod.Imports {
+			isAffected = isAffected || affected[imp.ModuleName()]
+		}
+
+		if isAffected {
+			affected[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// absPath returns the absolute path of the given path, or the path itself
+// if it cannot be made absolute.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elm-tangram/tangram/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestChanges(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "watch")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	main := filepath.Join(dir, "Main.elm")
+	view := filepath.Join(dir, "Page", "View.elm")
+	writeFile(t, main, "module Main exposing (..)\n")
+	writeFile(t, view, "module Page.View exposing (..)\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not elm")
+
+	w, err := New(dir, filepath.Join(dir, "missing"))
+	require.NoError(err)
+
+	changed, err := w.Changes()
+	require.NoError(err)
+	require.Empty(changed)
+
+	added := filepath.Join(dir, "Added.elm")
+	writeFile(t, main, "module Main exposing (main)\n")
+	writeFile(t, added, "module Added exposing (..)\n")
+	writeFile(t, filepath.Join(dir, "elm-stuff", "Stuff.elm"), "module Stuff exposing (..)\n")
+	require.NoError(os.Remove(view))
+
+	changed, err = w.Changes()
+	require.NoError(err)
+	require.Equal([]string{added, main, view}, changed)
+
+	changed, err = w.Changes()
+	require.NoError(err)
+	require.Empty(changed)
+}
+
+func TestWait(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "watch")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	w, err := New(dir)
+	require.NoError(err)
+	w.Interval = 10 * time.Millisecond
+
+	main := filepath.Join(dir, "Main.elm")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		writeFile(t, main, "module Main exposing (..)\n")
+	}()
+
+	changed, err := w.Wait(nil)
+	require.NoError(err)
+	require.Equal([]string{main}, changed)
+
+	done := make(chan struct{})
+	close(done)
+	changed, err = w.Wait(done)
+	require.NoError(err)
+	require.Empty(changed)
+}
+
+func TestAffected(t *testing.T) {
+	require := require.New(t)
+	pkg, err := parser.Parse("_testdata/app/src/Main.elm", parser.FullParse)
+	require.NoError(err)
+
+	var cases = []struct {
+		changed  []string
+		affected []string
+	}{
+		{[]string{"_testdata/app/src/Model.elm"}, []string{"Model", "View", "Main"}},
+		{[]string{"_testdata/app/src/View.elm"}, []string{"View", "Main"}},
+		{[]string{"_testdata/app/src/Main.elm"}, []string{"Main"}},
+		{[]string{"_testdata/app/src/Unused.elm"}, nil},
+	}
+
+	for _, c := range cases {
+		var changed []string
+		for _, path := range c.changed {
+			abs, err := filepath.Abs(path)
+			require.NoError(err)
+			changed = append(changed, abs)
+		}
+		require.Equal(c.affected, Affected(pkg, changed), "changed %v", c.changed)
+	}
+}